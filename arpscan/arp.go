@@ -0,0 +1,68 @@
+package arpscan
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// ARP (RFC 826) maps IPv4 addresses to MAC addresses on the local segment. it sits directly on Ethernet, with no IP
+// header at all: to find out who has 192.168.1.7, you broadcast a request to ff:ff:ff:ff:ff:ff and the owner answers
+// you directly.
+//
+//	Ethernet: destination MAC (6) | source MAC (6) | EtherType 0x0806 (2)
+//	ARP:      hardware type (2) | protocol type (2) | hardware len (1) | protocol len (1) | operation (2)
+//	          sender MAC (6) | sender IP (4) | target MAC (6) | target IP (4)
+//
+// since IP routing doesn't cross into ARP, a scan only ever sees the local segment: routers answer for themselves,
+// never for the hosts behind them.
+
+const (
+	etherTypeARP = 0x0806
+	arpRequest   = 1
+	arpReply     = 2
+)
+
+// minFrame is the shortest Ethernet frame (without the FCS the hardware adds); shorter ones get padded.
+const minFrame = 60
+
+var broadcast = net.HardwareAddr{0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+
+// arpRequestFrame builds a broadcast "who has target? tell srcIP" frame.
+func arpRequestFrame(srcMAC net.HardwareAddr, srcIP, target netip.Addr) []byte {
+	b := make([]byte, 0, minFrame)
+	b = append(b, broadcast...)
+	b = append(b, srcMAC...)
+	b = binary.BigEndian.AppendUint16(b, etherTypeARP)
+
+	b = binary.BigEndian.AppendUint16(b, 1)      // hardware type: Ethernet
+	b = binary.BigEndian.AppendUint16(b, 0x0800) // protocol type: IPv4
+	b = append(b, 6, 4)
+	b = binary.BigEndian.AppendUint16(b, arpRequest)
+	b = append(b, srcMAC...)
+	b = append(b, srcIP.AsSlice()...)
+	b = append(b, make([]byte, 6)...) // target MAC: unknown, that's the question
+	b = append(b, target.AsSlice()...)
+	return append(b, make([]byte, minFrame-len(b))...)
+}
+
+// parseARPReply extracts the sender's addresses from an ARP reply frame.
+func parseARPReply(frame []byte) (neighbor, error) {
+	if len(frame) < 14+28 {
+		return neighbor{}, fmt.Errorf("short ARP frame: %d bytes", len(frame))
+	}
+	if binary.BigEndian.Uint16(frame[12:14]) != etherTypeARP {
+		return neighbor{}, fmt.Errorf("not an ARP frame")
+	}
+	arp := frame[14:]
+	if !bytes.Equal(arp[:6], []byte{0, 1, 0x08, 0x00, 6, 4}) {
+		return neighbor{}, fmt.Errorf("not Ethernet/IPv4 ARP")
+	}
+	if op := binary.BigEndian.Uint16(arp[6:8]); op != arpReply {
+		return neighbor{}, fmt.Errorf("ARP operation %d is not a reply", op)
+	}
+	ip, _ := netip.AddrFromSlice(arp[14:18])
+	return neighbor{IP: ip, MAC: net.HardwareAddr(bytes.Clone(arp[8:14]))}, nil
+}
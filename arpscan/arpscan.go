@@ -0,0 +1,311 @@
+// Package arpscan is the arpscan tool: it finds the hosts on a subnet with ARP (IPv4) or neighbor discovery (IPv6).
+package arpscan
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/netip"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+// Command is the arpscan tool.
+var Command = &cli.Command{
+	Name:    "arpscan",
+	Summary: "find the hosts on a subnet with ARP or IPv6 neighbor discovery",
+	Args:    "<CIDR or IP>",
+	JSON:    true,
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		var o options
+		fs.StringVar(&o.iface, "i", "", "interface to scan from (default: the one whose subnet contains the target)")
+		fs.DurationVar(&o.timeout, "timeout", 2*time.Second, "how long to wait for replies after the last request")
+		fs.DurationVar(&o.interval, "interval", 2*time.Millisecond, "pause between requests, so a big scan doesn't flood the segment")
+		fs.StringVar(&o.oui, "oui", "", "vendor database to load: Wireshark's manuf file or IEEE's oui.txt")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			return run(ctx, env, o, args)
+		}
+	},
+}
+
+type options struct {
+	iface, oui        string
+	timeout, interval time.Duration
+}
+
+func run(ctx context.Context, env *cli.Env, o options, args []string) error {
+	if len(args) != 1 {
+		return cli.Usagef("expected one CIDR prefix or IP to scan")
+	}
+	prefix, err := parsePrefix(args[0])
+	if err != nil {
+		return cli.Usagef("%v", err)
+	}
+	if o.oui != "" {
+		if err := loadOUI(o.oui); err != nil {
+			return err
+		}
+	}
+
+	targets, err := hosts(prefix)
+	if err != nil {
+		return err
+	}
+	iface, src, err := pickInterface(o.iface, prefix)
+	if err != nil {
+		return err
+	}
+
+	open, proto := newARPScanner, "ARP"
+	if prefix.Addr().Is6() {
+		open, proto = newNDPScanner, "NDP"
+	}
+	sc, err := open(iface, src)
+	if err != nil {
+		return err
+	}
+	defer sc.close()
+
+	slog.InfoContext(ctx, "main", "message", fmt.Sprintf("scanning %d addresses with %s", len(targets), proto), "interface", iface.Name, "source", src.String())
+	start := time.Now()
+	found, err := scan(ctx, sc, targets, src, o.interval, o.timeout)
+	if err != nil {
+		return err
+	}
+
+	if env.JSON {
+		type host struct {
+			IP        string `json:"ip"`
+			MAC       string `json:"mac"`
+			Vendor    string `json:"vendor"`
+			Duplicate bool   `json:"duplicate,omitempty"`
+		}
+		out := []host{}
+		for _, nb := range found {
+			out = append(out, host{IP: nb.IP.String(), MAC: nb.MAC.String(), Vendor: vendor(nb.MAC), Duplicate: nb.Dup})
+		}
+		return env.PrintJSON(out)
+	}
+
+	w := tabwriter.NewWriter(env.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "IP\tMAC\tVENDOR")
+	for _, nb := range found {
+		v := vendor(nb.MAC)
+		if nb.Dup {
+			v += " (DUPLICATE: another MAC answered for this IP)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", nb.IP, nb.MAC, v)
+	}
+	w.Flush()
+	fmt.Fprintf(env.Stdout, "\n%d of %d addresses answered in %s\n", countIPs(found), len(targets), time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// neighbor is one answer: an IP and the MAC that claimed it.
+type neighbor struct {
+	IP  netip.Addr
+	MAC net.HardwareAddr
+	Dup bool // another MAC also answered for IP: an address conflict, or someone spoofing
+}
+
+// scanner is ARP for IPv4, NDP for IPv6.
+type scanner interface {
+	send(target netip.Addr) error
+	// recv waits a short while for a reply; it returns errNoReply if none came.
+	recv() (neighbor, error)
+	close() error
+}
+
+var errNoReply = errors.New("no reply")
+
+// scan sends a request to every target, then keeps listening until timeout has passed since the last one.
+// it returns the answers sorted by IP. only answers for addresses we asked about count.
+func scan(ctx context.Context, sc scanner, targets []netip.Addr, self netip.Addr, interval, timeout time.Duration) ([]neighbor, error) {
+	wanted := make(map[netip.Addr]bool, len(targets))
+	for _, t := range targets {
+		wanted[t] = true
+	}
+
+	// replies arrive while we're still sending, so listen in the background from the start.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	replies := make(chan neighbor)
+	recvErr := make(chan error, 1)
+	go func() {
+		for ctx.Err() == nil {
+			nb, err := sc.recv()
+			if errors.Is(err, errNoReply) {
+				continue
+			}
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case replies <- nb:
+			case <-ctx.Done():
+			}
+		}
+	}()
+
+	var found []neighbor
+	seen := map[string]bool{}
+	collect := func(nb neighbor) {
+		if !wanted[nb.IP] || seen[nb.IP.String()+nb.MAC.String()] {
+			return // answers to someone else's question, or a retransmission
+		}
+		seen[nb.IP.String()+nb.MAC.String()] = true
+		for i := range found {
+			if found[i].IP == nb.IP {
+				found[i].Dup, nb.Dup = true, true
+			}
+		}
+		found = append(found, nb)
+	}
+
+	tick := time.NewTicker(max(interval, time.Microsecond))
+	defer tick.Stop()
+	for i := 0; i < len(targets); {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case err := <-recvErr:
+			return nil, err
+		case nb := <-replies:
+			collect(nb)
+		case <-tick.C:
+			if targets[i] != self { // we can't ask the segment about ourselves
+				if err := sc.send(targets[i]); err != nil {
+					return nil, fmt.Errorf("sending to %s: %w", targets[i], err)
+				}
+			}
+			i++
+		}
+	}
+
+	deadline := time.After(timeout)
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case err := <-recvErr:
+			return nil, err
+		case nb := <-replies:
+			collect(nb)
+		case <-deadline:
+			slices.SortFunc(found, func(a, b neighbor) int { return a.IP.Compare(b.IP) })
+			return found, nil
+		}
+	}
+}
+
+func countIPs(found []neighbor) int {
+	ips := map[netip.Addr]bool{}
+	for _, nb := range found {
+		ips[nb.IP] = true
+	}
+	return len(ips)
+}
+
+// parsePrefix accepts a CIDR prefix or a single address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if p, err := netip.ParsePrefix(s); err == nil {
+		return p.Masked(), nil
+	}
+	a, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid target %q: want a CIDR like 192.168.1.0/24 or a single address", s)
+	}
+	return netip.PrefixFrom(a, a.BitLen()), nil
+}
+
+// maxHosts caps a scan. a /64 is 2^64 addresses: you don't scan IPv6 subnets, you narrow them down first.
+const maxHosts = 1 << 16
+
+// hosts lists the addresses in p. for IPv4 the network and broadcast addresses are skipped, except in /31 and /32,
+// which have no room for them (RFC 3021).
+func hosts(p netip.Prefix) ([]netip.Addr, error) {
+	if bits := p.Addr().BitLen() - p.Bits(); bits > 16 {
+		return nil, fmt.Errorf("%s has 2^%d addresses; scan at most %d (a /%d)", p, bits, maxHosts, p.Addr().BitLen()-16)
+	}
+	var out []netip.Addr
+	for a := p.Addr(); p.Contains(a); a = a.Next() {
+		out = append(out, a)
+		if !a.Next().IsValid() {
+			break // the very last address
+		}
+	}
+	if p.Addr().Is4() && p.Bits() < 31 {
+		out = out[1 : len(out)-1]
+	}
+	return out, nil
+}
+
+// pickInterface finds the interface to scan from and our address on it. ARP and NDP don't cross routers,
+// so with no -i the target has to be on a directly attached subnet.
+func pickInterface(name string, target netip.Prefix) (*net.Interface, netip.Addr, error) {
+	var ifaces []net.Interface
+	if name != "" {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, netip.Addr{}, err
+		}
+		ifaces = []net.Interface{*iface}
+	} else {
+		var err error
+		if ifaces, err = net.Interfaces(); err != nil {
+			return nil, netip.Addr{}, err
+		}
+	}
+
+	for i := range ifaces {
+		iface := &ifaces[i]
+		if iface.Flags&net.FlagUp == 0 || len(iface.HardwareAddr) != 6 {
+			continue // down, loopback, or not Ethernet-like
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		var fallback netip.Addr
+		for _, a := range addrs {
+			ipnet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip, _ := netip.AddrFromSlice(ipnet.IP)
+			ip = ip.Unmap()
+			if ip.Is4() != target.Addr().Is4() {
+				continue
+			}
+			ones, _ := ipnet.Mask.Size()
+			if netip.PrefixFrom(ip, ones).Masked().Overlaps(target) {
+				return iface, ip, nil
+			}
+			if !fallback.IsValid() || (fallback.IsLinkLocalUnicast() && !ip.IsLinkLocalUnicast()) {
+				fallback = ip
+			}
+		}
+		// -i was given explicitly: trust it even if the target isn't on its subnet.
+		if name != "" && fallback.IsValid() {
+			return iface, fallback, nil
+		}
+	}
+	if name != "" {
+		return nil, netip.Addr{}, fmt.Errorf("%s has no usable %s address", name, family(target))
+	}
+	return nil, netip.Addr{}, fmt.Errorf("no interface is attached to %s; pick one with -i", target)
+}
+
+func family(p netip.Prefix) string {
+	if p.Addr().Is4() {
+		return "IPv4"
+	}
+	return "IPv6"
+}
@@ -0,0 +1,117 @@
+package arpscan
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestARP(t *testing.T) {
+	mac := net.HardwareAddr{0x02, 0, 0, 0, 0, 0x01}
+	req := arpRequestFrame(mac, netip.MustParseAddr("192.168.1.10"), netip.MustParseAddr("192.168.1.1"))
+	if len(req) != minFrame {
+		t.Errorf("request is %d bytes, want %d", len(req), minFrame)
+	}
+	if !bytes.Equal(req[:6], broadcast) {
+		t.Errorf("request destination = %x, want broadcast", req[:6])
+	}
+	if _, err := parseARPReply(req); err == nil {
+		t.Errorf("parseARPReply(request) succeeded; want error")
+	}
+
+	// turn the request into the reply 192.168.1.1 would send back.
+	theirs := net.HardwareAddr{0xb8, 0x27, 0xeb, 0x12, 0x34, 0x56}
+	reply := bytes.Clone(req)
+	reply[14+7] = arpReply
+	copy(reply[14+8:], theirs)
+	copy(reply[14+14:], []byte{192, 168, 1, 1})
+	nb, err := parseARPReply(reply)
+	if err != nil {
+		t.Fatalf("parseARPReply() error: %v", err)
+	}
+	if nb.IP != netip.MustParseAddr("192.168.1.1") || nb.MAC.String() != theirs.String() {
+		t.Errorf("parseARPReply() = %s at %s, want 192.168.1.1 at %s", nb.IP, nb.MAC, theirs)
+	}
+	if got := vendor(nb.MAC); got != "Raspberry Pi Foundation" {
+		t.Errorf("vendor(%s) = %q", nb.MAC, got)
+	}
+}
+
+func TestNDP(t *testing.T) {
+	target := netip.MustParseAddr("2001:db8::1:2:3")
+	if got, want := solicitedNode(target), netip.MustParseAddr("ff02::1:ff02:3"); got != want {
+		t.Errorf("solicitedNode(%s) = %s, want %s", target, got, want)
+	}
+
+	ns := neighborSolicitation(net.HardwareAddr{2, 0, 0, 0, 0, 1}, target)
+	if len(ns) != 32 || ns[0] != icmpv6NeighborSolicitation || ns[24] != optSourceLinkAddr {
+		t.Errorf("neighborSolicitation() = %x", ns)
+	}
+
+	mac := net.HardwareAddr{0x52, 0x54, 0x00, 0xaa, 0xbb, 0xcc}
+	na := append([]byte{icmpv6NeighborAdvertisement, 0, 0, 0, 0x60, 0, 0, 0}, target.AsSlice()...)
+	na = append(na, optTargetLinkAddr, 1)
+	na = append(na, mac...)
+	nb, err := parseNeighborAdvertisement(na)
+	if err != nil || nb.IP != target || nb.MAC.String() != mac.String() {
+		t.Errorf("parseNeighborAdvertisement() = %s at %s, %v; want %s at %s", nb.IP, nb.MAC, err, target, mac)
+	}
+	if _, err := parseNeighborAdvertisement(na[:24]); err == nil {
+		t.Errorf("parseNeighborAdvertisement(no options) succeeded; want error")
+	}
+}
+
+func TestHosts(t *testing.T) {
+	for _, tt := range []struct {
+		prefix      string
+		n           int
+		first, last string
+	}{
+		{"192.168.1.0/24", 254, "192.168.1.1", "192.168.1.254"},
+		{"10.0.0.0/31", 2, "10.0.0.0", "10.0.0.1"},
+		{"10.0.0.7/32", 1, "10.0.0.7", "10.0.0.7"},
+		{"255.255.255.252/30", 2, "255.255.255.253", "255.255.255.254"},
+		{"fd00::/125", 8, "fd00::", "fd00::7"},
+	} {
+		p, err := parsePrefix(tt.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := hosts(p)
+		if err != nil || len(got) != tt.n || got[0].String() != tt.first || got[len(got)-1].String() != tt.last {
+			t.Errorf("hosts(%s) = %d addresses, %v; want %d from %s to %s", tt.prefix, len(got), err, tt.n, tt.first, tt.last)
+		}
+	}
+	if _, err := hosts(netip.MustParsePrefix("fd00::/64")); err == nil {
+		t.Errorf("hosts(/64) succeeded; want error")
+	}
+}
+
+func TestLoadOUI(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manuf")
+	data := "# comment\n" +
+		"AC:DE:48\tPrivate\tPrivate Vendor\n" +
+		"00-00-5E   (hex)\t\tICANN, IANA Department\n" +
+		"00005E     (base 16)\t\tICANN, IANA Department\n" +
+		"00:1B:C5:00:00:00/36\tSomeone\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadOUI(path); err != nil {
+		t.Fatal(err)
+	}
+	for mac, want := range map[string]string{
+		"ac:de:48:00:00:01": "Private Vendor",
+		"00:00:5e:00:53:01": "ICANN, IANA Department",
+		"02:42:ac:11:00:02": "(locally administered)",
+		"00:1b:c5:00:00:01": "",
+	} {
+		hw, _ := net.ParseMAC(mac)
+		if got := vendor(hw); got != want {
+			t.Errorf("vendor(%s) = %q, want %q", mac, got, want)
+		}
+	}
+}
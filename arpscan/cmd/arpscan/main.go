@@ -0,0 +1,9 @@
+// Command arpscan scans a subnet for neighbors; see package arpscan.
+package main
+
+import (
+	"github.com/ekediala/arpscan"
+	"github.com/ekediala/cli"
+)
+
+func main() { cli.Main(arpscan.Command) }
@@ -0,0 +1,7 @@
+module github.com/ekediala/arpscan
+
+go 1.23.1
+
+require github.com/ekediala/cli v0.0.0
+
+replace github.com/ekediala/cli => ../cli
@@ -0,0 +1,68 @@
+package arpscan
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// IPv6 has no ARP. neighbor discovery (RFC 4861) does the same job with ICMPv6 messages, which ride inside ordinary
+// IPv6 packets. instead of broadcasting, a neighbor solicitation goes to the target's solicited-node multicast group
+// (ff02::1:ffXX:XXXX, from the address's last 24 bits), so only hosts sharing those bits are woken up.
+//
+//	solicitation:  type 135 | code 0 | checksum (2) | reserved (4) | target address (16) | options
+//	advertisement: type 136 | code 0 | checksum (2) | R S O flags + reserved (4) | target address (16) | options
+//
+// the MAC rides along as an option: "source link-layer address" (type 1) in solicitations, "target link-layer
+// address" (type 2) in advertisements. the kernel fills in the checksum for us; it's computed over a pseudo-header
+// that includes the source address, which we don't pick.
+//
+// both messages must be sent with a hop limit of 255. a receiver drops anything lower: it must have crossed a
+// router, so it can't be from the local link, which is what stops off-link hosts spoofing neighbors.
+
+const (
+	icmpv6NeighborSolicitation  = 135
+	icmpv6NeighborAdvertisement = 136
+
+	optSourceLinkAddr = 1
+	optTargetLinkAddr = 2
+)
+
+// solicitedNode returns the solicited-node multicast address for target.
+func solicitedNode(target netip.Addr) netip.Addr {
+	t := target.As16()
+	return netip.AddrFrom16([16]byte{0xff, 0x02, 11: 0x01, 12: 0xff, 13: t[13], 14: t[14], 15: t[15]})
+}
+
+// neighborSolicitation builds the ICMPv6 message asking who has target (checksum left zero for the kernel).
+func neighborSolicitation(srcMAC net.HardwareAddr, target netip.Addr) []byte {
+	b := []byte{icmpv6NeighborSolicitation, 0, 0, 0, 0, 0, 0, 0}
+	b = append(b, target.AsSlice()...)
+	// options are measured in units of 8 bytes: type, length, then a 6-byte MAC.
+	b = append(b, optSourceLinkAddr, 1)
+	return append(b, srcMAC...)
+}
+
+// parseNeighborAdvertisement extracts the target address and MAC from an ICMPv6 neighbor advertisement.
+func parseNeighborAdvertisement(msg []byte) (neighbor, error) {
+	if len(msg) < 24 {
+		return neighbor{}, fmt.Errorf("short ICMPv6 message: %d bytes", len(msg))
+	}
+	if msg[0] != icmpv6NeighborAdvertisement || msg[1] != 0 {
+		return neighbor{}, fmt.Errorf("ICMPv6 type %d is not a neighbor advertisement", msg[0])
+	}
+	ip, _ := netip.AddrFromSlice(msg[8:24])
+	for opts := msg[24:]; len(opts) >= 2; {
+		size := int(opts[1]) * 8
+		if size == 0 || size > len(opts) {
+			return neighbor{}, fmt.Errorf("malformed option")
+		}
+		if opts[0] == optTargetLinkAddr && size >= 8 {
+			return neighbor{IP: ip, MAC: net.HardwareAddr(bytes.Clone(opts[2:8]))}, nil
+		}
+		opts = opts[size:]
+	}
+	// allowed when replying to a unicast solicitation, but we only send multicast ones.
+	return neighbor{}, fmt.Errorf("advertisement for %s has no link-layer address", ip)
+}
@@ -0,0 +1,82 @@
+package arpscan
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// the first three bytes of a MAC address are an OUI (organizationally unique identifier) that IEEE assigns to
+// the manufacturer. the full registry has tens of thousands of entries; we carry a handful of common ones and can load
+// the rest from Wireshark's "manuf" file or IEEE's oui.txt with -oui.
+var vendors = map[[3]byte]string{
+	{0x00, 0x00, 0x0c}: "Cisco",
+	{0x00, 0x05, 0x69}: "VMware",
+	{0x00, 0x0c, 0x29}: "VMware",
+	{0x00, 0x50, 0x56}: "VMware",
+	{0x00, 0x15, 0x5d}: "Microsoft (Hyper-V)",
+	{0x00, 0x16, 0x3e}: "Xen",
+	{0x00, 0x1b, 0x63}: "Apple",
+	{0x08, 0x00, 0x27}: "PCS Systemtechnik (VirtualBox)",
+	{0x52, 0x54, 0x00}: "QEMU/KVM",
+	{0xb8, 0x27, 0xeb}: "Raspberry Pi Foundation",
+	{0xdc, 0xa6, 0x32}: "Raspberry Pi Trading",
+	{0xe4, 0x5f, 0x01}: "Raspberry Pi Trading",
+}
+
+// vendor names the manufacturer of mac, if known.
+func vendor(mac net.HardwareAddr) string {
+	if len(mac) < 3 {
+		return ""
+	}
+	if v, ok := vendors[[3]byte(mac[:3])]; ok {
+		return v
+	}
+	// the second-lowest bit of the first byte marks an address the owner made up instead of taking from an OUI:
+	// virtual machines, containers, and phones randomizing their MAC for privacy.
+	if mac[0]&0x02 != 0 {
+		return "(locally administered)"
+	}
+	return ""
+}
+
+// loadOUI adds the vendors in path to the table. it accepts the two common formats:
+//
+//	00:1B:63	Apple	Apple, Inc.           (Wireshark's manuf: tab-separated, short name then long name)
+//	00-1B-63   (hex)		Apple, Inc.       (IEEE's oui.txt)
+func loadOUI(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		prefix, rest, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		prefix = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(prefix), "(hex)"))
+		mac, err := net.ParseMAC(strings.ReplaceAll(prefix, "-", ":") + ":00:00:00")
+		if err != nil || len(prefix) != len("00:00:00") {
+			continue // longer (/28, /36) assignments and header lines
+		}
+		fields := strings.Split(strings.TrimSpace(rest), "\t")
+		name := fields[len(fields)-1]
+		if name == "" {
+			continue
+		}
+		vendors[[3]byte(mac[:3])] = name
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
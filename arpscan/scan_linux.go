@@ -0,0 +1,135 @@
+//go:build linux
+
+package arpscan
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+const ethPARP = 0x0806
+
+// pollInterval bounds how long a receive blocks, so the scan notices its deadline.
+const pollInterval = 100 * time.Millisecond
+
+// arpScanner sends and receives raw Ethernet frames through an AF_PACKET socket bound to one interface.
+// only ARP frames reach it: the socket is opened for the ARP EtherType.
+type arpScanner struct {
+	fd    int
+	iface *net.Interface
+	src   netip.Addr
+	buf   []byte
+}
+
+func newARPScanner(iface *net.Interface, src netip.Addr) (scanner, error) {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(ethPARP)))
+	if err != nil {
+		return nil, fmt.Errorf("opening packet socket (needs root or CAP_NET_RAW): %w", err)
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(ethPARP), Ifindex: iface.Index}); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("binding to %s: %w", iface.Name, err)
+	}
+	if err := setRecvTimeout(fd); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &arpScanner{fd: fd, iface: iface, src: src, buf: make([]byte, 1500)}, nil
+}
+
+func (s *arpScanner) send(target netip.Addr) error {
+	frame := arpRequestFrame(s.iface.HardwareAddr, s.src, target)
+	to := &syscall.SockaddrLinklayer{Protocol: htons(ethPARP), Ifindex: s.iface.Index, Halen: 6}
+	copy(to.Addr[:], broadcast)
+	return syscall.Sendto(s.fd, frame, 0, to)
+}
+
+func (s *arpScanner) recv() (neighbor, error) {
+	for {
+		n, _, err := syscall.Recvfrom(s.fd, s.buf, 0)
+		if err != nil {
+			return neighbor{}, recvErr(err)
+		}
+		if nb, err := parseARPReply(s.buf[:n]); err == nil {
+			return nb, nil
+		}
+		// our own requests (which the socket sees going out) and other hosts' chatter.
+	}
+}
+
+func (s *arpScanner) close() error { return syscall.Close(s.fd) }
+
+// ndpScanner sends neighbor solicitations through a raw ICMPv6 socket; the kernel builds the IPv6 header.
+type ndpScanner struct {
+	fd    int
+	iface *net.Interface
+	buf   []byte
+}
+
+func newNDPScanner(iface *net.Interface, _ netip.Addr) (scanner, error) {
+	fd, err := syscall.Socket(syscall.AF_INET6, syscall.SOCK_RAW, syscall.IPPROTO_ICMPV6)
+	if err != nil {
+		return nil, fmt.Errorf("opening ICMPv6 socket (needs root or CAP_NET_RAW): %w", err)
+	}
+	for _, opt := range []int{syscall.IPV6_MULTICAST_HOPS, syscall.IPV6_UNICAST_HOPS} {
+		if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, opt, 255); err != nil {
+			syscall.Close(fd)
+			return nil, fmt.Errorf("setting hop limit: %w", err)
+		}
+	}
+	if err := syscall.SetsockoptInt(fd, syscall.IPPROTO_IPV6, syscall.IPV6_MULTICAST_IF, iface.Index); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("selecting %s for multicast: %w", iface.Name, err)
+	}
+	// a raw ICMPv6 socket sees every ICMPv6 packet; we only want advertisements, and only on this interface.
+	if err := syscall.BindToDevice(fd, iface.Name); err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("binding to %s: %w", iface.Name, err)
+	}
+	if err := setRecvTimeout(fd); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	return &ndpScanner{fd: fd, iface: iface, buf: make([]byte, 1500)}, nil
+}
+
+func (s *ndpScanner) send(target netip.Addr) error {
+	to := &syscall.SockaddrInet6{Addr: solicitedNode(target).As16(), ZoneId: uint32(s.iface.Index)}
+	return syscall.Sendto(s.fd, neighborSolicitation(s.iface.HardwareAddr, target), 0, to)
+}
+
+func (s *ndpScanner) recv() (neighbor, error) {
+	for {
+		n, _, err := syscall.Recvfrom(s.fd, s.buf, 0)
+		if err != nil {
+			return neighbor{}, recvErr(err)
+		}
+		if nb, err := parseNeighborAdvertisement(s.buf[:n]); err == nil {
+			return nb, nil
+		}
+	}
+}
+
+func (s *ndpScanner) close() error { return syscall.Close(s.fd) }
+
+func setRecvTimeout(fd int) error {
+	tv := syscall.NsecToTimeval(int64(pollInterval))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return fmt.Errorf("setting receive timeout: %w", err)
+	}
+	return nil
+}
+
+func recvErr(err error) error {
+	if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+		return errNoReply
+	}
+	return fmt.Errorf("receiving: %w", err)
+}
+
+// htons converts to network byte order, which is what AF_PACKET wants for the protocol number.
+func htons(v uint16) uint16 { return v<<8 | v>>8 }
@@ -0,0 +1,16 @@
+//go:build !linux
+
+package arpscan
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+)
+
+// sending raw Ethernet frames needs AF_PACKET, which is Linux-only; the BSDs and macOS would use /dev/bpf instead.
+var errUnsupported = errors.New("arpscan only runs on Linux")
+
+func newARPScanner(iface *net.Interface, src netip.Addr) (scanner, error) { return nil, errUnsupported }
+
+func newNDPScanner(iface *net.Interface, src netip.Addr) (scanner, error) { return nil, errUnsupported }
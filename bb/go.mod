@@ -0,0 +1,46 @@
+module github.com/ekediala/bb
+
+go 1.23.1
+
+require (
+	github.com/ekediala/arpscan v0.0.0
+	github.com/ekediala/cli v0.0.0
+	github.com/ekediala/conformance v0.0.0
+	github.com/ekediala/dhcpdemo v0.0.0
+	github.com/ekediala/ftpget v0.0.0
+	github.com/ekediala/nslookup v0.0.0
+	github.com/ekediala/sendreq v0.0.0
+	github.com/ekediala/sniff v0.0.0
+	github.com/ekediala/socksd v0.0.0
+	github.com/ekediala/syslog v0.0.0
+	github.com/ekediala/tcpupperecho v0.0.0
+	github.com/ekediala/telnet v0.0.0
+	github.com/ekediala/writetcp v0.0.0
+)
+
+require (
+	github.com/ekediala/httpwire v0.0.0 // indirect
+	github.com/ekediala/metrics v0.0.0 // indirect
+	github.com/ekediala/pool v0.0.0 // indirect
+	github.com/ekediala/retry v0.0.0 // indirect
+)
+
+replace (
+	github.com/ekediala/arpscan => ../arpscan
+	github.com/ekediala/cli => ../cli
+	github.com/ekediala/conformance => ../conformance
+	github.com/ekediala/dhcpdemo => ../dhcpdemo
+	github.com/ekediala/ftpget => ../ftpget
+	github.com/ekediala/httpwire => ../httpwire
+	github.com/ekediala/metrics => ../metrics
+	github.com/ekediala/nslookup => ../dns
+	github.com/ekediala/pool => ../pool
+	github.com/ekediala/retry => ../retry
+	github.com/ekediala/sendreq => ../sendreq
+	github.com/ekediala/sniff => ../sniff
+	github.com/ekediala/socksd => ../socksd
+	github.com/ekediala/syslog => ../syslog
+	github.com/ekediala/tcpupperecho => ../tcpupperecho
+	github.com/ekediala/telnet => ../telnet
+	github.com/ekediala/writetcp => ../write_tcp
+)
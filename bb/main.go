@@ -0,0 +1,36 @@
+// Command bb runs every tool in the repository from one binary: bb dns https://example.com, bb sendreq -host
+// example.com -port 80, and so on. each tool's own main package runs the same code; see package cli.
+package main
+
+import (
+	"github.com/ekediala/arpscan"
+	"github.com/ekediala/cli"
+	"github.com/ekediala/conformance"
+	"github.com/ekediala/dhcpdemo"
+	"github.com/ekediala/ftpget"
+	"github.com/ekediala/nslookup"
+	"github.com/ekediala/sendreq"
+	"github.com/ekediala/sniff"
+	"github.com/ekediala/socksd"
+	"github.com/ekediala/syslog"
+	"github.com/ekediala/tcpupperecho"
+	"github.com/ekediala/telnet"
+	"github.com/ekediala/writetcp"
+)
+
+func main() {
+	cli.Dispatch("bb", []*cli.Command{
+		sendreq.Command,
+		nslookup.Command,
+		tcpupperecho.Command,
+		writetcp.Command,
+		ftpget.Command,
+		telnet.Command,
+		socksd.Command,
+		conformance.Command,
+		sniff.Command,
+		arpscan.Command,
+		dhcpdemo.Command,
+		syslog.Command,
+	})
+}
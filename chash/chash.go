@@ -0,0 +1,127 @@
+// Package chash implements a consistent hash ring.
+//
+// with naive sharding (hash(key) % len(nodes)) adding or removing a node changes the owner of almost every key.
+// a consistent hash ring places both nodes and keys on the same circle of hash values and gives each key to the
+// first node clockwise from it, so a membership change only moves the keys between the changed node and its neighbour:
+// about 1/N of them.
+//
+// each node is placed on the ring many times ("virtual nodes"); otherwise a handful of nodes would split the circle
+// into very uneven arcs and some would own far more keys than others.
+package chash
+
+import (
+	"hash/crc32"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// DefaultReplicas is the number of virtual nodes per node used when New is given zero.
+const DefaultReplicas = 100
+
+// Hash maps data to a point on the ring.
+type Hash func(data []byte) uint32
+
+// Ring is a consistent hash ring. It is safe for concurrent use.
+type Ring struct {
+	replicas int
+	hash     Hash
+
+	mu     sync.RWMutex
+	points []uint32          // sorted virtual node positions
+	owners map[uint32]string // virtual node position -> node
+	nodes  map[string]bool
+}
+
+// New returns an empty ring that places each node replicas times, hashing with hash.
+// zero replicas means DefaultReplicas; a nil hash means CRC-32 (IEEE).
+func New(replicas int, hash Hash) *Ring {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	if hash == nil {
+		hash = crc32.ChecksumIEEE
+	}
+	return &Ring{replicas: replicas, hash: hash, owners: make(map[uint32]string), nodes: make(map[string]bool)}
+}
+
+// Add places nodes on the ring. adding a node that's already present does nothing.
+func (r *Ring) Add(nodes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, node := range nodes {
+		if r.nodes[node] {
+			continue
+		}
+		r.nodes[node] = true
+		for i := range r.replicas {
+			r.place(r.hash([]byte(strconv.Itoa(i)+node)), node)
+		}
+	}
+	slices.Sort(r.points)
+}
+
+// place puts one of node's virtual nodes at p. if two nodes hash to the same point, the one that sorts first gets
+// it, whichever was added first, so the ring depends only on which nodes are on it. r.points has to be sorted after.
+func (r *Ring) place(p uint32, node string) {
+	owner, taken := r.owners[p]
+	if !taken {
+		r.points = append(r.points, p)
+	}
+	if !taken || node < owner {
+		r.owners[p] = node
+	}
+}
+
+// Remove takes nodes off the ring; their keys fall to the next node clockwise.
+func (r *Ring) Remove(nodes ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	removed := false
+	for _, node := range nodes {
+		if r.nodes[node] {
+			delete(r.nodes, node)
+			removed = true
+		}
+	}
+	if !removed {
+		return
+	}
+	// a removed node's point may have been another's too, which lost the collision: placing the nodes that are
+	// left from scratch gives it back, as if the removed node had never been added.
+	r.points, r.owners = r.points[:0], make(map[uint32]string, len(r.owners))
+	for node := range r.nodes {
+		for i := range r.replicas {
+			r.place(r.hash([]byte(strconv.Itoa(i)+node)), node)
+		}
+	}
+	slices.Sort(r.points)
+}
+
+// Locate returns the node that owns key, or false if the ring is empty.
+func (r *Ring) Locate(key string) (node string, ok bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.points) == 0 {
+		return "", false
+	}
+	h := r.hash([]byte(key))
+	// the first point at or after h; past the last point we wrap around to the first.
+	i, _ := slices.BinarySearch(r.points, h)
+	if i == len(r.points) {
+		i = 0
+	}
+	return r.owners[r.points[i]], true
+}
+
+// Nodes returns the nodes on the ring in sorted order.
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	nodes := make([]string, 0, len(r.nodes))
+	for node := range r.nodes {
+		nodes = append(nodes, node)
+	}
+	slices.Sort(nodes)
+	return nodes
+}
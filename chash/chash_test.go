@@ -0,0 +1,114 @@
+package chash
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+)
+
+func TestLocateEmpty(t *testing.T) {
+	if node, ok := New(0, nil).Locate("key"); ok {
+		t.Errorf("Locate() on empty ring = %q, want no node", node)
+	}
+}
+
+func TestLocateStable(t *testing.T) {
+	r := New(0, nil)
+	r.Add("a", "b", "c")
+	for i := range 100 {
+		key := fmt.Sprint("key-", i)
+		first, _ := r.Locate(key)
+		if again, _ := r.Locate(key); again != first {
+			t.Fatalf("Locate(%q) = %q then %q; want the same node", key, first, again)
+		}
+	}
+}
+
+func TestMinimalMovement(t *testing.T) {
+	const keys = 10_000
+	r := New(0, nil)
+	r.Add("a", "b", "c", "d")
+
+	before := make(map[string]string, keys)
+	for i := range keys {
+		key := fmt.Sprint("key-", i)
+		before[key], _ = r.Locate(key)
+	}
+
+	r.Add("e")
+	moved := 0
+	for key, old := range before {
+		now, _ := r.Locate(key)
+		if now != old {
+			moved++
+			if now != "e" {
+				t.Fatalf("key %q moved from %q to %q; keys should only move to the new node", key, old, now)
+			}
+		}
+	}
+	// ideally 1/5 of the keys move; allow generous slack for hash unevenness.
+	if moved == 0 || moved > keys/3 {
+		t.Errorf("adding a 5th node moved %d/%d keys, want roughly %d", moved, keys, keys/5)
+	}
+
+	r.Remove("e")
+	for key, old := range before {
+		if now, _ := r.Locate(key); now != old {
+			t.Fatalf("after removing the new node, key %q is on %q, want %q", key, now, old)
+		}
+	}
+}
+
+func TestBalance(t *testing.T) {
+	const keys = 10_000
+	r := New(0, nil)
+	nodes := []string{"10.0.0.1:7000", "10.0.0.2:7000", "10.0.0.3:7000"}
+	r.Add(nodes...)
+
+	counts := make(map[string]int)
+	for i := range keys {
+		node, _ := r.Locate(fmt.Sprint("key-", i))
+		counts[node]++
+	}
+	for _, node := range nodes {
+		if share := float64(counts[node]) / keys; share < 0.2 || share > 0.5 {
+			t.Errorf("node %s owns %.0f%% of keys; want roughly a third", node, share*100)
+		}
+	}
+}
+
+func TestNodes(t *testing.T) {
+	r := New(10, nil)
+	r.Add("b", "a", "a")
+	r.Remove("missing")
+	if got := r.Nodes(); fmt.Sprint(got) != "[a b]" {
+		t.Errorf("Nodes() = %v, want [a b]", got)
+	}
+}
+
+func TestCollisions(t *testing.T) {
+	// a hash of just the replica number puts every node's virtual nodes on the same points.
+	collide := func(data []byte) uint32 { return uint32(data[0]) }
+	ring := func(build func(r *Ring)) *Ring {
+		r := New(3, collide)
+		build(r)
+		return r
+	}
+	want := ring(func(r *Ring) { r.Add("a") })
+	for name, got := range map[string]*Ring{
+		"Add(a), Add(b), Remove(b)":  ring(func(r *Ring) { r.Add("a"); r.Add("b"); r.Remove("b") }),
+		"Add(b), Add(a), Remove(b)":  ring(func(r *Ring) { r.Add("b"); r.Add("a"); r.Remove("b") }),
+		"Add(b, a, c), Remove(c, b)": ring(func(r *Ring) { r.Add("b", "a", "c"); r.Remove("c", "b") }),
+	} {
+		if !slices.Equal(got.points, want.points) || !maps.Equal(got.owners, want.owners) {
+			t.Errorf("%s = points %v, owners %v; want %v, %v, as for Add(a)", name, got.points, got.owners, want.points, want.owners)
+		}
+	}
+
+	// which node gets a contested point doesn't depend on the order they were added in.
+	ab, ba := ring(func(r *Ring) { r.Add("a", "b") }), ring(func(r *Ring) { r.Add("b"); r.Add("a") })
+	if !maps.Equal(ab.owners, ba.owners) {
+		t.Errorf("Add(a, b) owners = %v, Add(b), Add(a) = %v; want the same", ab.owners, ba.owners)
+	}
+}
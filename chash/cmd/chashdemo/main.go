@@ -0,0 +1,266 @@
+// chashdemo starts a handful of tiny key-value nodes on localhost, shards keys across them with a consistent hash ring,
+// then adds and removes a node to show how few keys have to move compared to naive modulo sharding.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"hash/crc32"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+
+	"github.com/ekediala/chash"
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer done()
+
+	const name = "chashdemo"
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	log = log.With("app", name)
+	slog.SetDefault(log)
+
+	numNodes := flag.Int("nodes", 3, "number of kv nodes to start")
+	numKeys := flag.Int("keys", 1000, "number of keys to store")
+	replicas := flag.Int("vnodes", chash.DefaultReplicas, "virtual nodes per node")
+	flag.Parse()
+
+	var nodes []*node
+	start := func() *node {
+		n, err := startNode(ctx)
+		if err != nil {
+			slog.ErrorContext(ctx, "main", "error", err.Error())
+			os.Exit(1)
+		}
+		nodes = append(nodes, n)
+		return n
+	}
+	for range *numNodes {
+		start()
+	}
+	defer func() {
+		for _, n := range nodes {
+			n.Close()
+		}
+	}()
+
+	ring := chash.New(*replicas, nil)
+	for _, n := range nodes {
+		ring.Add(n.addr)
+	}
+	client := &client{ring: ring}
+
+	keys := make([]string, *numKeys)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key-%d", i)
+		if err := client.Set(keys[i], fmt.Sprint(i)); err != nil {
+			slog.ErrorContext(ctx, "main", "error", err.Error())
+			os.Exit(1)
+		}
+	}
+	report(ctx, "initial placement", nodes)
+
+	// grow the cluster by one node and move the keys it now owns.
+	added := start()
+	changeMembership(ctx, client, keys, len(nodes)-1, len(nodes), func() { ring.Add(added.addr) })
+	report(ctx, "after adding "+added.addr, nodes)
+
+	// and shrink it again: the node's keys fall to its ring neighbours.
+	removed := nodes[0]
+	changeMembership(ctx, client, keys, len(nodes), len(nodes)-1, func() { ring.Remove(removed.addr) })
+	removed.Close()
+	report(ctx, "after removing "+removed.addr, nodes)
+
+	for _, key := range keys {
+		if _, err := client.Get(key); err != nil {
+			slog.ErrorContext(ctx, "main", "key", key, "error", err.Error())
+			os.Exit(1)
+		}
+	}
+	slog.InfoContext(ctx, "main", "message", "every key is still reachable through the ring", "keys", len(keys))
+}
+
+// changeMembership applies change to the ring, migrates every key whose owner changed, and logs how many keys moved
+// next to how many would have moved with hash(key) % nodes sharding going from oldN to newN nodes.
+func changeMembership(ctx context.Context, c *client, keys []string, oldN, newN int, change func()) {
+	owners := make(map[string]string, len(keys))
+	modMoved := 0
+	for _, key := range keys {
+		owners[key], _ = c.ring.Locate(key)
+		h := crc32.ChecksumIEEE([]byte(key))
+		if h%uint32(oldN) != h%uint32(newN) {
+			modMoved++
+		}
+	}
+
+	change()
+
+	moved := 0
+	for _, key := range keys {
+		from, to := owners[key], c.locate(key)
+		if from == to {
+			continue
+		}
+		moved++
+		// a real system would stream these in the background; the demo just copies them over.
+		val, err := call(from, "GET "+key)
+		if err == nil {
+			_, err = call(to, "SET "+key+" "+val)
+		}
+		if err == nil {
+			_, err = call(from, "DEL "+key)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, "migrate", "key", key, "from", from, "to", to, "error", err.Error())
+		}
+	}
+	slog.InfoContext(ctx, "membership change", "nodes before", oldN, "nodes after", newN,
+		"keys moved (ring)", fmt.Sprintf("%d/%d (%.1f%%)", moved, len(keys), 100*float64(moved)/float64(len(keys))),
+		"keys moved (mod N)", fmt.Sprintf("%d/%d (%.1f%%)", modMoved, len(keys), 100*float64(modMoved)/float64(len(keys))))
+}
+
+// report logs how many keys each live node holds.
+func report(ctx context.Context, stage string, nodes []*node) {
+	for _, n := range nodes {
+		if n.closed() {
+			continue
+		}
+		slog.InfoContext(ctx, stage, "node", n.addr, "keys", n.Len())
+	}
+}
+
+// client is the "thin client": it knows the ring and talks to whichever node owns a key.
+type client struct {
+	ring *chash.Ring
+}
+
+func (c *client) locate(key string) string {
+	addr, _ := c.ring.Locate(key)
+	return addr
+}
+
+func (c *client) Set(key, val string) error {
+	_, err := call(c.locate(key), "SET "+key+" "+val)
+	return err
+}
+
+func (c *client) Get(key string) (string, error) {
+	return call(c.locate(key), "GET "+key)
+}
+
+// call sends a single command line to addr and returns the reply line.
+// the protocol is deliberately tiny: "SET <key> <value>" and "DEL <key>" reply "OK",
+// "GET <key>" replies with the value, and failures reply with an "ERR ..." line.
+func call(addr, cmd string) (string, error) {
+	if addr == "" {
+		return "", errors.New("no nodes on the ring")
+	}
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	if _, err := fmt.Fprintf(conn, "%s\n", cmd); err != nil {
+		return "", err
+	}
+	reply, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	reply = strings.TrimSuffix(reply, "\n")
+	if msg, ok := strings.CutPrefix(reply, "ERR "); ok {
+		return "", fmt.Errorf("%s: %s", addr, msg)
+	}
+	return reply, nil
+}
+
+// node is a minimal in-memory key-value server listening on a random localhost port.
+type node struct {
+	addr     string
+	listener net.Listener
+
+	mu   sync.Mutex
+	data map[string]string
+	done bool
+}
+
+func startNode(ctx context.Context) (*node, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("starting node: %w", err)
+	}
+	n := &node{addr: l.Addr().String(), listener: l, data: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					slog.ErrorContext(ctx, "node", "addr", n.addr, "error", err.Error())
+				}
+				return
+			}
+			go n.serve(conn)
+		}
+	}()
+	return n, nil
+}
+
+func (n *node) serve(conn net.Conn) {
+	defer conn.Close()
+	for scanner := bufio.NewScanner(conn); scanner.Scan(); {
+		fmt.Fprintln(conn, n.handle(scanner.Text()))
+	}
+}
+
+func (n *node) handle(line string) string {
+	cmd, rest, _ := strings.Cut(line, " ")
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	switch cmd {
+	case "SET":
+		key, val, ok := strings.Cut(rest, " ")
+		if !ok {
+			return "ERR usage: SET <key> <value>"
+		}
+		n.data[key] = val
+		return "OK"
+	case "GET":
+		val, ok := n.data[rest]
+		if !ok {
+			return "ERR not found: " + rest
+		}
+		return val
+	case "DEL":
+		delete(n.data, rest)
+		return "OK"
+	default:
+		return "ERR unknown command: " + cmd
+	}
+}
+
+func (n *node) Len() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return len(n.data)
+}
+
+func (n *node) Close() error {
+	n.mu.Lock()
+	n.done = true
+	n.mu.Unlock()
+	return n.listener.Close()
+}
+
+func (n *node) closed() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.done
+}
@@ -0,0 +1,3 @@
+module github.com/ekediala/chash
+
+go 1.23.1
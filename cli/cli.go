@@ -0,0 +1,232 @@
+// Package cli is the plumbing every tool's command line shares: flag parsing, the common flags (log level and format,
+// an overall timeout, JSON output, a config file), logger setup, and exit codes. A tool describes itself as a
+// Command; its own main package is a one-line wrapper around Main, and the bb binary runs the same Commands as
+// subcommands, so a tool behaves the same whichever way it's started.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+)
+
+// Exit codes, the same for every tool.
+const (
+	ExitOK          = 0
+	ExitError       = 1   // the command failed
+	ExitUsage       = 2   // bad flags or arguments (flag's own convention)
+	ExitTimeout     = 124 // -timeout ran out (what timeout(1) returns)
+	ExitInterrupted = 130 // stopped with ^C: 128 + SIGINT, what shells report
+)
+
+// Command is one tool.
+type Command struct {
+	Name      string // subcommand name; also the "app" attribute in logs and the config file section
+	Summary   string // one line for bb's command list
+	Args      string // synopsis of the positional arguments for the usage line, like "<URL>"
+	LogFormat string // default log format, "text" or "json"; empty means text
+	JSON      bool   // the command can print its results as JSON, so it gets a -json flag
+
+	// Setup defines the command's flags on fs and returns the function that runs it once they're parsed.
+	Setup func(fs *flag.FlagSet) Runner
+}
+
+// Runner runs a command with its flags parsed. args are the positional arguments left over. a returned error is
+// logged and turned into an exit code; wrap it with Usagef for bad arguments.
+type Runner func(ctx context.Context, env *Env, args []string) error
+
+// Env is what a running command gets besides its own flags.
+type Env struct {
+	Name           string
+	Stdout, Stderr io.Writer
+
+	// Level is the log level; a command can change it (e.g. for its own -v flag).
+	Level *slog.LevelVar
+	// Handler is the configured stderr handler, without the app attribute. commands that send logs elsewhere too
+	// (like -syslog) wrap it and set a new default logger.
+	Handler slog.Handler
+
+	Timeout time.Duration // from -timeout; already applied to the context
+	JSON    bool          // from -json
+}
+
+// PrintJSON writes v to stdout as indented JSON.
+func (e *Env) PrintJSON(v any) error {
+	enc := json.NewEncoder(e.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// usageError marks an error as the user's fault, so it exits with ExitUsage and prints the usage line.
+type usageError struct{ err error }
+
+func (e *usageError) Error() string { return e.err.Error() }
+func (e *usageError) Unwrap() error { return e.err }
+
+// Usagef returns an error for bad arguments.
+func Usagef(format string, args ...any) error {
+	return &usageError{fmt.Errorf(format, args...)}
+}
+
+// exitError is an error with an exit code of its own.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+// WithExitCode returns err, to exit with code instead of ExitError, for a command whose exit codes say more than
+// that it failed, like sendreq's -fail. 3 to 99 are free for a command's own use: the codes above, and 126 and up,
+// already mean something to every tool, or to the shell. ^C and -timeout still exit with their own codes.
+func WithExitCode(code int, err error) error {
+	return &exitError{code, err}
+}
+
+// shared holds the flags every command gets.
+type shared struct {
+	logLevel  string
+	logFormat string
+	timeout   time.Duration
+	json      bool
+	config    string
+}
+
+// register adds the shared flags to fs, skipping any the command defines itself (ftpget's -timeout, say, means
+// something narrower and wins). the current values are the defaults, so flags already parsed by bb before the
+// subcommand name carry over.
+func (s *shared) register(fs *flag.FlagSet, cmd *Command) {
+	if fs.Lookup("log-level") == nil {
+		fs.StringVar(&s.logLevel, "log-level", s.logLevel, "log level: debug, info, warn, or error")
+	}
+	if fs.Lookup("log-format") == nil {
+		fs.StringVar(&s.logFormat, "log-format", s.logFormat, "log format: text or json")
+	}
+	if fs.Lookup("timeout") == nil {
+		fs.DurationVar(&s.timeout, "timeout", s.timeout, "stop the command after this long (0 means no limit)")
+	}
+	if (cmd == nil || cmd.JSON) && fs.Lookup("json") == nil {
+		fs.BoolVar(&s.json, "json", s.json, "print results as JSON")
+	}
+	if fs.Lookup("config") == nil {
+		fs.StringVar(&s.config, "config", s.config, "config file (default $BB_CONFIG, or bb/config in the user config directory)")
+	}
+}
+
+// Main runs cmd as a standalone program with os.Args and exits.
+func Main(cmd *Command) {
+	os.Exit(run(cmd, cmd.Name, os.Args[1:], &shared{logLevel: "info"}, nil))
+}
+
+// run parses args for cmd, runs it, and returns the exit code. prog is the program name for the usage line ("bb dns"
+// or just "dns"), and set holds flags already given on the command line before the subcommand.
+func run(cmd *Command, prog string, args []string, opts *shared, set map[string]bool) int {
+	if opts.logFormat == "" {
+		opts.logFormat = cmd.LogFormat
+		if opts.logFormat == "" {
+			opts.logFormat = "text"
+		}
+	}
+	fs := flag.NewFlagSet(prog, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	runner := cmd.Setup(fs)
+	opts.register(fs, cmd)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: %s [flags] %s\n", prog, cmd.Args)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage // flag already printed the error and the usage.
+	}
+
+	// a flag given on the command line beats the config file.
+	if set == nil {
+		set = map[string]bool{}
+	}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if err := applyConfig(fs, cmd.Name, opts.config, set); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", prog, err)
+		return ExitUsage
+	}
+
+	level := new(slog.LevelVar)
+	if err := level.UnmarshalText([]byte(opts.logLevel)); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: invalid -log-level %q\n", prog, opts.logLevel)
+		return ExitUsage
+	}
+	var h slog.Handler
+	switch strings.ToLower(opts.logFormat) {
+	case "text":
+		h = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	case "json":
+		h = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	default:
+		fmt.Fprintf(os.Stderr, "%s: invalid -log-format %q (want text or json)\n", prog, opts.logFormat)
+		return ExitUsage
+	}
+	slog.SetDefault(slog.New(h).With("app", cmd.Name))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	interrupted := ctx
+	if opts.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.timeout)
+		defer cancel()
+	}
+
+	env := &Env{
+		Name:    cmd.Name,
+		Stdout:  os.Stdout,
+		Stderr:  os.Stderr,
+		Level:   level,
+		Handler: h,
+		Timeout: opts.timeout,
+		JSON:    opts.json,
+	}
+	err := runner(ctx, env, fs.Args())
+	if err != nil {
+		slog.ErrorContext(ctx, "main", "error", err.Error())
+	}
+
+	// the exit code says why the command stopped. a server that shuts down cleanly on ^C still exits with
+	// ExitInterrupted, like any program a shell sees killed by SIGINT.
+	var usage *usageError
+	var exit *exitError
+	switch {
+	case errors.As(err, &usage):
+		fs.Usage()
+		return ExitUsage
+	case interrupted.Err() != nil:
+		return ExitInterrupted
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return ExitTimeout
+	case errors.As(err, &exit):
+		return exit.code
+	case err != nil:
+		return ExitError
+	default:
+		return ExitOK
+	}
+}
+
+// Stop exits right away with the code for why ctx ended: ExitInterrupted for ^C, ExitTimeout for -timeout. it's for
+// commands stuck in something the context can't interrupt, like a read from stdin.
+func Stop(ctx context.Context) {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		os.Exit(ExitTimeout)
+	}
+	os.Exit(ExitInterrupted)
+}
@@ -0,0 +1,161 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCommand records the flag values and args it ran with and returns err.
+func testCommand(err error) (*Command, *string, *int, *[]string) {
+	host, port := new(string), new(int)
+	args := new([]string)
+	return &Command{
+		Name: "test",
+		Setup: func(fs *flag.FlagSet) Runner {
+			fs.StringVar(host, "host", "localhost", "")
+			fs.IntVar(port, "port", 8080, "")
+			return func(ctx context.Context, env *Env, a []string) error {
+				*args = a
+				if err == context.DeadlineExceeded {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+				return err
+			}
+		},
+	}, host, port, args
+}
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+# comment
+host = global.example
+port = 1
+
+[test]
+port = 2
+
+[other]
+port = 3
+`)
+	cmd, host, port, args := testCommand(nil)
+	if code := run(cmd, "test", []string{"-config", path, "a", "b"}, &shared{logLevel: "info"}, nil); code != ExitOK {
+		t.Fatalf("run() = %d, want %d", code, ExitOK)
+	}
+	if *host != "global.example" || *port != 2 {
+		t.Errorf("host, port = %q, %d; want %q, %d (top level, then the command's section)", *host, *port, "global.example", 2)
+	}
+	if len(*args) != 2 {
+		t.Errorf("args = %q, want [a b]", *args)
+	}
+
+	cmd, _, port, _ = testCommand(nil)
+	run(cmd, "test", []string{"-config", path, "-port", "9"}, &shared{logLevel: "info"}, nil)
+	if *port != 9 {
+		t.Errorf("port = %d, want 9: the command line should beat the config file", *port)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown flag in section": "[test]\nnope = 1\n",
+		"bad value":               "port = eighty\n",
+		"bad line":                "just words\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			cmd, _, _, _ := testCommand(nil)
+			if code := run(cmd, "test", []string{"-config", writeConfig(t, content)}, &shared{logLevel: "info"}, nil); code != ExitUsage {
+				t.Errorf("run() = %d, want %d", code, ExitUsage)
+			}
+		})
+	}
+
+	cmd, _, _, _ := testCommand(nil)
+	missing := filepath.Join(t.TempDir(), "missing")
+	if code := run(cmd, "test", []string{"-config", missing}, &shared{logLevel: "info"}, nil); code != ExitUsage {
+		t.Errorf("run() with a missing -config file = %d, want %d", code, ExitUsage)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	t.Setenv("BB_CONFIG", "") // don't pick up the user's real config
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	for _, tc := range []struct {
+		name string
+		err  error
+		args []string
+		want int
+	}{
+		{"ok", nil, nil, ExitOK},
+		{"error", errors.New("boom"), nil, ExitError},
+		{"usage", Usagef("missing URL"), nil, ExitUsage},
+		{"bad flag", nil, []string{"-nope"}, ExitUsage},
+		{"help", nil, []string{"-h"}, ExitOK},
+		{"bad log level", nil, []string{"-log-level", "loud"}, ExitUsage},
+		{"timeout", context.DeadlineExceeded, []string{"-timeout", "10ms"}, ExitTimeout},
+		{"own code", WithExitCode(5, errors.New("server error")), nil, 5},
+		{"own code, wrapped", fmt.Errorf("fetching: %w", WithExitCode(4, errors.New("not found"))), nil, 4},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, _, _, _ := testCommand(tc.err)
+			if code := run(cmd, "test", tc.args, &shared{logLevel: "info"}, nil); code != tc.want {
+				t.Errorf("run() = %d, want %d", code, tc.want)
+			}
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	t.Setenv("BB_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	cmd, host, _, _ := testCommand(nil)
+	cmds := []*Command{cmd}
+
+	var ran time.Duration
+	timed := &Command{Name: "timed", Setup: func(fs *flag.FlagSet) Runner {
+		return func(ctx context.Context, env *Env, args []string) error {
+			ran = env.Timeout
+			return nil
+		}
+	}}
+	cmds = append(cmds, timed)
+
+	for _, tc := range []struct {
+		args []string
+		want int
+	}{
+		{nil, ExitUsage},
+		{[]string{"help"}, ExitOK},
+		{[]string{"help", "test"}, ExitOK},
+		{[]string{"nope"}, ExitUsage},
+		{[]string{"test", "-host", "example.com"}, ExitOK},
+	} {
+		if code := dispatch("bb", cmds, tc.args, io.Discard); code != tc.want {
+			t.Errorf("dispatch(%q) = %d, want %d", tc.args, code, tc.want)
+		}
+	}
+	if *host != "example.com" {
+		t.Errorf("host = %q, want example.com", *host)
+	}
+
+	// shared flags work before the command name too.
+	if code := dispatch("bb", cmds, []string{"-timeout", "5s", "timed"}, io.Discard); code != ExitOK || ran != 5*time.Second {
+		t.Errorf("dispatch(-timeout 5s timed) = %d with timeout %v; want %d with 5s", code, ran, ExitOK)
+	}
+}
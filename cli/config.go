@@ -0,0 +1,132 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// the config file holds flag defaults, so the options you always pass don't have to be typed every time. keys are
+// flag names without the dash; keys before any section apply to every command that has that flag, and a [name]
+// section applies to one command only:
+//
+//	# ~/.config/bb/config
+//	log-level = debug
+//
+//	[sendreq]
+//	host = example.com
+//	port = 80
+//
+// precedence, highest first: the command line, the command's section, the top of the file, the built-in default.
+
+// entry is one key = value line.
+type entry struct {
+	key, value string
+	line       int
+}
+
+// config is a parsed config file.
+type config struct {
+	path     string
+	global   []entry
+	sections map[string][]entry
+}
+
+// configPath picks the file to read: -config if given, else $BB_CONFIG, else bb/config in the user config directory.
+// the second result reports whether the file was asked for explicitly, in which case it must exist.
+func configPath(flagValue string) (string, bool) {
+	if flagValue != "" {
+		return flagValue, true
+	}
+	if env := os.Getenv("BB_CONFIG"); env != "" {
+		return env, true
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", false
+	}
+	return filepath.Join(dir, "bb", "config"), false
+}
+
+func loadConfig(path string) (*config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	c := &config{path: path, sections: map[string][]entry{}}
+	section := ""
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+			continue
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value or [section]", path, n)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if uq, err := strconv.Unquote(value); err == nil {
+			value = uq // "quoted" values can keep leading and trailing spaces
+		}
+		e := entry{key: strings.TrimPrefix(key, "-"), value: value, line: n}
+		if section == "" {
+			c.global = append(c.global, e)
+		} else {
+			c.sections[section] = append(c.sections[section], e)
+		}
+	}
+	return c, scanner.Err()
+}
+
+// applyConfig sets the flags that weren't given on the command line from the config file.
+func applyConfig(flags *flag.FlagSet, section, flagValue string, set map[string]bool) error {
+	path, explicit := configPath(flagValue)
+	if path == "" {
+		return nil
+	}
+	c, err := loadConfig(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return nil // no config file is fine unless one was asked for.
+	}
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	return c.apply(flags, section, set)
+}
+
+func (c *config) apply(flags *flag.FlagSet, section string, set map[string]bool) error {
+	for _, e := range c.global {
+		// top-level keys are for whichever commands have the flag; the rest don't care.
+		if flags.Lookup(e.key) == nil || set[e.key] {
+			continue
+		}
+		if err := flags.Set(e.key, e.value); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", c.path, e.line, e.key, err)
+		}
+	}
+	for _, e := range c.sections[section] {
+		if flags.Lookup(e.key) == nil {
+			return fmt.Errorf("%s:%d: %s has no flag -%s", c.path, e.line, section, e.key)
+		}
+		if set[e.key] {
+			continue
+		}
+		if err := flags.Set(e.key, e.value); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", c.path, e.line, e.key, err)
+		}
+	}
+	return nil
+}
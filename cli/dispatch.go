@@ -0,0 +1,61 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Dispatch runs one of cmds as a subcommand of a multi-tool binary and exits:
+//
+//	name [shared flags] <command> [command flags] [args]
+//
+// shared flags can go before the command name or after it.
+func Dispatch(name string, cmds []*Command) {
+	os.Exit(dispatch(name, cmds, os.Args[1:], os.Stderr))
+}
+
+func dispatch(name string, cmds []*Command, args []string, stderr io.Writer) int {
+	opts := &shared{logLevel: "info"}
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	opts.register(fs, nil)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: %s [flags] <command> [command flags] [args]\n\ncommands:\n", name)
+		for _, c := range cmds {
+			fmt.Fprintf(stderr, "  %-14s %s\n", c.Name, c.Summary)
+		}
+		fmt.Fprintf(stderr, "\nrun '%s <command> -h' for a command's flags.\n\nflags:\n", name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return ExitUsage
+	}
+
+	sub, rest := fs.Arg(0), fs.Args()[1:]
+	if sub == "help" {
+		if len(rest) == 0 {
+			fs.Usage()
+			return ExitOK
+		}
+		sub, rest = rest[0], []string{"-h"}
+	}
+	for _, c := range cmds {
+		if c.Name == sub {
+			set := map[string]bool{}
+			fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+			return run(c, name+" "+c.Name, rest, opts, set)
+		}
+	}
+	fmt.Fprintf(stderr, "%s: unknown command %q\n", name, sub)
+	fs.Usage()
+	return ExitUsage
+}
@@ -0,0 +1,3 @@
+module github.com/ekediala/cli
+
+go 1.23.1
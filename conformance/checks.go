@@ -0,0 +1,250 @@
+package conformance
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// level says how serious a failed check is, after the RFC 2119 keyword behind it.
+type level int
+
+const (
+	must   level = iota // a failure is a protocol violation
+	should              // a failure is allowed, but worth knowing about
+)
+
+type check struct {
+	name  string
+	level level
+	// run returns nil if the server passed, or a description of what it did instead.
+	run func(t target) error
+}
+
+// target is the server under test.
+type target struct {
+	addr    string // host:port to dial
+	host    string // value for the Host header
+	path    string
+	timeout time.Duration
+}
+
+// get builds a GET request; extra lines are added after Host.
+func (t target) get(extra ...string) string {
+	return t.request("GET", t.path, extra...)
+}
+
+func (t target) request(method, path string, extra ...string) string {
+	lines := append([]string{method + " " + path + " HTTP/1.1", "Host: " + t.host}, extra...)
+	return strings.Join(lines, "\r\n") + "\r\n\r\n"
+}
+
+func (t target) send(raw string, methods ...string) exchange {
+	return send(t.addr, t.timeout, raw, methods...)
+}
+
+// one sends raw and expects exactly one response.
+func (t target) one(raw string) (*response, error) {
+	ex := t.send(raw, "GET")
+	if ex.Err != nil {
+		return nil, ex.Err
+	}
+	if len(ex.Responses) == 0 {
+		return nil, fmt.Errorf("connection closed without a response")
+	}
+	return ex.Responses[0], nil
+}
+
+// rejects passes if the server answers raw with one of the given status codes, or hangs up without answering
+// (dropping a malformed request is a legitimate, if unfriendly, way to reject it).
+func (t target) rejects(raw string, codes ...int) error {
+	ex := t.send(raw, "GET")
+	if len(ex.Responses) == 0 {
+		if ex.Err != nil && !ex.Closed {
+			return ex.Err
+		}
+		return nil
+	}
+	resp := ex.Responses[0]
+	for _, c := range codes {
+		if resp.StatusCode == c {
+			return nil
+		}
+	}
+	return fmt.Errorf("got %d %s, want one of %v", resp.StatusCode, resp.Reason, codes)
+}
+
+var checks = []check{
+	{"basic GET gets a well-formed HTTP/1.1 response", must, func(t target) error {
+		resp, err := t.one(t.get())
+		if err != nil {
+			return err
+		}
+		if resp.Proto != "HTTP/1.1" {
+			return fmt.Errorf("response version %s to an HTTP/1.1 request", resp.Proto)
+		}
+		return nil
+	}},
+
+	{"header names are case-insensitive", must, func(t target) error {
+		base, err := t.one(t.get())
+		if err != nil {
+			return err
+		}
+		// RFC 9110 section 5.1: field names are case-insensitive. "hOST" is as good a Host header as any.
+		raw := strings.Join([]string{"GET " + t.path + " HTTP/1.1", "hOST: " + t.host, "user-AGENT: conformance"}, "\r\n") + "\r\n\r\n"
+		resp, err := t.one(raw)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode != base.StatusCode {
+			return fmt.Errorf("got %d with oddly-cased headers, %d without", resp.StatusCode, base.StatusCode)
+		}
+		return nil
+	}},
+
+	{"missing Host header is rejected with 400", must, func(t target) error {
+		// RFC 9112 section 3.2: a server MUST respond with 400 to an HTTP/1.1 request that lacks a Host header.
+		return t.rejects("GET "+t.path+" HTTP/1.1\r\nUser-Agent: conformance\r\n\r\n", 400)
+	}},
+
+	{"duplicate Host headers are rejected with 400", must, func(t target) error {
+		return t.rejects(t.get("Host: "+t.host), 400)
+	}},
+
+	{"chunked request body is accepted", must, func(t target) error {
+		// every HTTP/1.1 recipient MUST be able to parse chunked (RFC 9112 section 7.1). the method might not be
+		// allowed on this path, so any status is fine except the ones that mean "I can't read that".
+		raw := t.request("POST", t.path, "Transfer-Encoding: chunked", "Content-Type: text/plain")
+		raw += "5\r\nhello\r\n7;ext=1\r\n, world\r\n0\r\n\r\n"
+		ex := t.send(raw+t.get(), "POST", "GET")
+		if len(ex.Responses) == 0 {
+			return fmt.Errorf("no response: %v", ex.Err)
+		}
+		switch c := ex.Responses[0].StatusCode; c {
+		case 400, 411, 501:
+			return fmt.Errorf("got %d %s", c, ex.Responses[0].Reason)
+		}
+		// if the body wasn't consumed correctly, the follow-up request would come out garbled.
+		if len(ex.Responses) == 2 && ex.Responses[1].StatusCode == 400 {
+			return fmt.Errorf("request after the chunked one got 400: the body wasn't framed correctly")
+		}
+		return nil
+	}},
+
+	{"chunked responses are well-formed", must, func(t target) error {
+		// we can't make a server chunk, but if it does, readResponse has already checked the framing.
+		_, err := t.one(t.get())
+		return err
+	}},
+
+	{"conflicting Content-Length headers are rejected", must, func(t target) error {
+		// RFC 9112 section 6.3: differing Content-Length values make the framing ambiguous (a request smuggling vector); MUST be 400.
+		return t.rejects(t.request("POST", t.path, "Content-Length: 5", "Content-Length: 6")+"hello!", 400)
+	}},
+
+	{"Content-Length and Transfer-Encoding together don't desync the connection", must, func(t target) error {
+		// RFC 9112 section 6.1: the server may reject this, or must ignore Content-Length and use the chunked framing.
+		// if it used Content-Length instead, the "0\r\n\r\n" left behind would be glued onto the front of the next request.
+		raw := t.request("POST", t.path, "Content-Length: 3", "Transfer-Encoding: chunked") + "0\r\n\r\n" + t.get()
+		ex := t.send(raw, "POST", "GET")
+		if len(ex.Responses) == 2 && ex.Responses[0].StatusCode != 400 && ex.Responses[1].StatusCode == 400 {
+			return fmt.Errorf("request after the ambiguous one got 400: the server framed the body by Content-Length")
+		}
+		if len(ex.Responses) < 2 && !ex.Closed {
+			return fmt.Errorf("got %d of 2 responses: %v", len(ex.Responses), ex.Err)
+		}
+		return nil
+	}},
+
+	{"malformed request line is rejected with 400", must, func(t target) error {
+		return t.rejects("THIS IS NOT HTTP\r\n\r\n", 400)
+	}},
+
+	{"whitespace before a header colon is rejected with 400", must, func(t target) error {
+		// RFC 9112 section 5.1: a server MUST reject a request with whitespace between the field name and colon.
+		return t.rejects(t.get("X-Bad : yes"), 400)
+	}},
+
+	{"obsolete line folding is rejected or unfolded", must, func(t target) error {
+		// RFC 9112 section 5.2: a server MUST either reject obs-fold with 400 or replace it with spaces. either way it answers.
+		resp, err := t.one(t.get("X-Folded: one", " two"))
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode >= 500 {
+			return fmt.Errorf("got %d %s", resp.StatusCode, resp.Reason)
+		}
+		return nil
+	}},
+
+	{"oversized headers are rejected", should, func(t target) error {
+		// no RFC sets a limit, but accepting megabytes of headers is a cheap way to let clients eat memory. 431 is
+		// the dedicated status (RFC 6585); 400 and hanging up are common too.
+		return t.rejects(t.get("X-Big: "+strings.Repeat("a", 2<<20)), 431, 400, 413, 494)
+	}},
+
+	{"unknown HTTP version gets 505", should, func(t target) error {
+		return t.rejects("GET "+t.path+" HTTP/9.9\r\nHost: "+t.host+"\r\n\r\n", 505, 400)
+	}},
+
+	{"unknown method gets 501 or 405", should, func(t target) error {
+		return t.rejects(t.request("FROBNICATE", t.path), 501, 405)
+	}},
+
+	{"connections persist by default", should, func(t target) error {
+		// HTTP/1.1 connections are persistent unless either side says "Connection: close" (RFC 9112 section 9.3).
+		ex := t.send(t.get(), "GET")
+		if ex.Err != nil {
+			return ex.Err
+		}
+		if len(ex.Responses) == 1 && ex.Closed && !ex.Responses[0].closes() {
+			return fmt.Errorf("server closed the connection without sending Connection: close")
+		}
+		if len(ex.Responses) == 1 && ex.Responses[0].closes() {
+			return fmt.Errorf("server sent Connection: close to a keep-alive request")
+		}
+		return nil
+	}},
+
+	{"Connection: close is honored", must, func(t target) error {
+		ex := t.send(t.get("Connection: close"), "GET")
+		if ex.Err != nil {
+			return ex.Err
+		}
+		if !ex.Closed {
+			return fmt.Errorf("connection still open after a response to Connection: close")
+		}
+		return nil
+	}},
+
+	{"HTTP/1.0 requests without keep-alive are closed", must, func(t target) error {
+		ex := t.send("GET "+t.path+" HTTP/1.0\r\nHost: "+t.host+"\r\n\r\n", "GET")
+		if ex.Err != nil {
+			return ex.Err
+		}
+		if len(ex.Responses) == 1 && ex.Responses[0].Chunked {
+			return fmt.Errorf("chunked response to an HTTP/1.0 client, which can't decode it")
+		}
+		if !ex.Closed {
+			return fmt.Errorf("connection still open after responding to HTTP/1.0 without keep-alive")
+		}
+		return nil
+	}},
+
+	{"pipelined requests get responses in order", must, func(t target) error {
+		// three requests in one write. HEAD in the middle has a distinguishable (empty) body, so out-of-order
+		// responses would break the framing. the server may close early, but must say so.
+		ex := t.send(t.get()+t.request("HEAD", t.path)+t.get(), "GET", "HEAD", "GET")
+		if ex.Err != nil {
+			return ex.Err
+		}
+		if n := len(ex.Responses); n < 3 {
+			if n > 0 && ex.Responses[n-1].closes() {
+				return nil
+			}
+			return fmt.Errorf("got %d of 3 responses", n)
+		}
+		return nil
+	}},
+}
@@ -0,0 +1,9 @@
+// Command conformance tests an HTTP/1.1 server; see package conformance.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/conformance"
+)
+
+func main() { cli.Main(conformance.Command) }
@@ -0,0 +1,138 @@
+// Package conformance is the conformance tool: it checks an HTTP/1.1 server's wire-level behaviour against the RFCs.
+package conformance
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+// Command is the conformance tool.
+var Command = &cli.Command{
+	Name:    "conformance",
+	Summary: "test an HTTP/1.1 server's wire-level behaviour against the RFCs",
+	Args:    "<HOST:PORT>",
+	JSON:    true,
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		path := fs.String("path", "/", "path to request; pick one that returns 200 to a GET")
+		host := fs.String("host", "", "Host header to send (default: the address)")
+		timeout := fs.Duration("timeout", 5*time.Second, "timeout for each check's connection")
+		only := fs.String("run", "", "only run checks whose name matches this regular expression")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			if len(args) != 1 {
+				return cli.Usagef("expected one address to test")
+			}
+			addr := args[0]
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				return cli.Usagef("invalid address %q: %v", addr, err)
+			}
+			if *host == "" {
+				*host = addr
+			}
+			filter, err := regexp.Compile(*only)
+			if err != nil {
+				return cli.Usagef("invalid -run: %v", err)
+			}
+
+			// make sure there's something there, so a typo doesn't show up as a wall of failed checks.
+			conn, err := net.DialTimeout("tcp", addr, *timeout)
+			if err != nil {
+				return err
+			}
+			conn.Close()
+
+			t := target{addr: addr, host: *host, path: *path, timeout: *timeout}
+			var failed int
+			if env.JSON {
+				failed, err = reportJSON(ctx, env, t, filter)
+				if err != nil {
+					return err
+				}
+			} else {
+				failed = report(ctx, env.Stdout, t, filter)
+			}
+			if failed > 0 {
+				return fmt.Errorf("%d checks failed", failed)
+			}
+			return nil
+		}
+	},
+}
+
+// result is one check's outcome.
+type result struct {
+	Check  string `json:"check"`
+	Status string `json:"status"` // PASS, WARN, or FAIL
+	Error  string `json:"error,omitempty"`
+}
+
+// runChecks runs the checks against t that match filter. a failed SHOULD is a WARN.
+func runChecks(ctx context.Context, t target, filter *regexp.Regexp) []result {
+	var results []result
+	for _, c := range checks {
+		if ctx.Err() != nil {
+			break
+		}
+		if !filter.MatchString(c.name) {
+			continue
+		}
+		r := result{Check: c.name, Status: "PASS"}
+		if err := c.run(t); err != nil {
+			r.Status, r.Error = "FAIL", err.Error()
+			if c.level == should {
+				r.Status = "WARN"
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+func count(results []result) (passed, failed, warned int) {
+	for _, r := range results {
+		switch r.Status {
+		case "PASS":
+			passed++
+		case "FAIL":
+			failed++
+		default:
+			warned++
+		}
+	}
+	return passed, failed, warned
+}
+
+// report runs the checks against t, writes one line per check and a summary to w, and returns the number of failures.
+// a failed SHOULD is reported as WARN and doesn't count.
+func report(ctx context.Context, w io.Writer, t target, filter *regexp.Regexp) (failed int) {
+	results := runChecks(ctx, t, filter)
+	for _, r := range results {
+		if r.Error == "" {
+			fmt.Fprintf(w, "%-4s  %s\n", r.Status, r.Check)
+		} else {
+			fmt.Fprintf(w, "%-4s  %s: %s\n", r.Status, r.Check, r.Error)
+		}
+	}
+	passed, failed, warned := count(results)
+	fmt.Fprintf(w, "\n%d passed, %d failed, %d warnings\n", passed, failed, warned)
+	return failed
+}
+
+// reportJSON is report for -json: the results and the totals as one JSON document.
+func reportJSON(ctx context.Context, env *cli.Env, t target, filter *regexp.Regexp) (failed int, err error) {
+	results := runChecks(ctx, t, filter)
+	passed, failed, warned := count(results)
+	err = env.PrintJSON(struct {
+		Results  []result `json:"results"`
+		Passed   int      `json:"passed"`
+		Failed   int      `json:"failed"`
+		Warnings int      `json:"warnings"`
+	}{results, passed, failed, warned})
+	return failed, err
+}
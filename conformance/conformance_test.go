@@ -0,0 +1,65 @@
+package conformance
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadResponse(t *testing.T) {
+	raw := "HTTP/1.1 100 Continue\r\n\r\n" +
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\ncontent-type: text/plain\r\nTrailer: X-Checksum\r\n\r\n" +
+		"5\r\nhello\r\n6;name=value\r\n world\r\n0\r\nX-Checksum: yes\r\n\r\n" +
+		"HTTP/1.1 404 \r\nContent-Length: 3\r\n\r\nnope" // trailing byte belongs to whatever comes next
+	r := bufio.NewReader(strings.NewReader(raw))
+
+	resp, err := readResponse(r, "GET")
+	if err != nil {
+		t.Fatalf("readResponse() error: %v", err)
+	}
+	if resp.StatusCode != 200 || !resp.Chunked || string(resp.Body) != "hello world" {
+		t.Errorf("readResponse() = %d chunked=%v %q; want 200 chunked=true %q", resp.StatusCode, resp.Chunked, resp.Body, "hello world")
+	}
+	if got := resp.Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Get(Content-Type) = %q, want %q", got, "text/plain")
+	}
+
+	resp, err = readResponse(r, "GET")
+	if err != nil || resp.StatusCode != 404 || string(resp.Body) != "nop" {
+		t.Errorf("readResponse() = %+v, %v; want 404 with body %q", resp, err, "nop")
+	}
+
+	for _, bad := range []string{
+		"HTTP/1.1 OK\r\n\r\n",
+		"HTTP/1.1 200 OK\r\nBad Header: x\r\n\r\n",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\n",
+		"HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nshort",
+		"HTTP/1.1 200 OK\nContent-Length: 0\n\n",                                              // bare LFs: a recipient may take them, a checker shouldn't
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n0\r\nContent-Length: 5\r\n\r\n", // framing in a trailer
+	} {
+		if _, err := readResponse(bufio.NewReader(strings.NewReader(bad)), "GET"); err == nil {
+			t.Errorf("readResponse(%q) succeeded; want error", bad)
+		}
+	}
+}
+
+// net/http's server is about as conformant as servers get; every MUST check should pass against it.
+func TestChecksAgainstNetHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	addr := strings.TrimPrefix(srv.URL, "http://")
+	var out bytes.Buffer
+	tgt := target{addr: addr, host: addr, path: "/", timeout: 5 * time.Second}
+	if failed := report(context.Background(), &out, tgt, regexp.MustCompile("")); failed > 0 {
+		t.Errorf("%d checks failed against net/http:\n%s", failed, out.String())
+	}
+}
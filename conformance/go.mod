@@ -0,0 +1,13 @@
+module github.com/ekediala/conformance
+
+go 1.23.1
+
+require (
+	github.com/ekediala/cli v0.0.0
+	github.com/ekediala/httpwire v0.0.0
+)
+
+replace (
+	github.com/ekediala/cli => ../cli
+	github.com/ekediala/httpwire => ../httpwire
+)
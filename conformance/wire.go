@@ -0,0 +1,106 @@
+package conformance
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/ekediala/httpwire"
+)
+
+// the checks talk to the server over raw TCP: net/http would quietly fix up exactly the things we want to see
+// (framing, connection reuse), and would refuse to send malformed requests at all. responses are read with httpwire,
+// strictly, since what's being checked is the server: a response the RFCs only let a recipient tolerate, like one
+// with bare-LF line endings, is an error here.
+
+// response is one parsed HTTP/1.x response, with its body read.
+type response struct {
+	*httpwire.Response
+	Chunked bool // the body came in chunks
+}
+
+// Get returns the first value of the header named key, compared case-insensitively as RFC 9110 requires.
+func (r *response) Get(key string) string { return r.Headers.Get(key) }
+
+// closes reports whether the server announced it will close the connection after this response.
+func (r *response) closes() bool {
+	if r.Proto == "HTTP/1.0" {
+		return !r.Headers.HasToken("Connection", "keep-alive")
+	}
+	return r.Headers.HasToken("Connection", "close")
+}
+
+// maxLine bounds a status or header line, so a misbehaving server can't make us buffer forever.
+const maxLine = 64 << 10
+
+// wire is how responses are read.
+var wire = httpwire.ParseOptions{MaxLineLength: maxLine, Strict: true}
+
+// readResponse reads one response from r. interim 1xx responses are skipped. method is the request's method:
+// a response to HEAD has no body whatever its headers say.
+func readResponse(r *bufio.Reader, method string) (*response, error) {
+	for {
+		resp, err := wire.ReadResponse(r, method)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= 100 && resp.StatusCode < 200 && resp.StatusCode != 101 {
+			continue
+		}
+		// an HTTP/1.0 response can't be chunked; a strict read has refused one that says it is.
+		return &response{Response: resp, Chunked: resp.Headers.Chunked()}, nil
+	}
+}
+
+// exchange is the result of sending raw bytes on a fresh connection and reading what came back.
+type exchange struct {
+	Responses []*response
+	// Closed reports that the server closed the connection (rather than us timing out waiting for more).
+	Closed bool
+	// Err is the error that stopped reading, if it wasn't a clean close or the expected number of responses.
+	Err error
+}
+
+// send dials addr, writes raw, and reads up to want responses (methods[i] is the method of the i-th request,
+// so HEAD responses are framed correctly). after the last one it waits briefly to see whether the server hangs up.
+func send(addr string, timeout time.Duration, raw string, methods ...string) exchange {
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return exchange{Err: err}
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+	if _, err := io.WriteString(conn, raw); err != nil {
+		return exchange{Err: err}
+	}
+
+	var ex exchange
+	r := bufio.NewReader(conn)
+	for _, m := range methods {
+		resp, err := readResponse(r, m)
+		if resp != nil {
+			ex.Responses = append(ex.Responses, resp)
+		}
+		if err != nil {
+			ex.Closed, ex.Err = isClosed(err), err
+			if ex.Closed && resp == nil {
+				ex.Err = nil
+			}
+			return ex
+		}
+	}
+
+	// anything left? a well-behaved server either waits for the next request or closes.
+	conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	if _, err := r.Peek(1); err != nil {
+		ex.Closed = isClosed(err)
+	}
+	return ex
+}
+
+func isClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) || errors.Is(err, syscall.ECONNRESET)
+}
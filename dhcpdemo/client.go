@@ -0,0 +1,191 @@
+package dhcpdemo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"time"
+)
+
+// client runs one DHCP conversation. every message in it carries the same transaction ID (xid), which is how we
+// pick our replies out of everything else broadcast on the segment.
+type client struct {
+	conn    net.PacketConn
+	server  net.Addr // where requests go: 255.255.255.255:67, or a server's unicast address
+	mac     net.HardwareAddr
+	xid     uint32
+	timeout time.Duration // first retransmission timeout; doubles on every retry, as RFC 2131 suggests
+	retries int
+	out     io.Writer
+
+	start time.Time
+}
+
+// newMessage starts a client message of the given type with the options every message carries.
+func (c *client) newMessage(typ byte) *message {
+	m := &message{
+		Op:     opRequest,
+		XID:    c.xid,
+		Secs:   uint16(time.Since(c.start).Seconds()),
+		Flags:  flagBroadcast,
+		CHAddr: c.mac,
+	}
+	m.add(optMessageType, typ)
+	// client identifier: hardware type 1 (Ethernet) and the MAC. servers key leases on it.
+	m.add(optClientID, append([]byte{1}, c.mac...)...)
+	return m
+}
+
+// params is what we ask the server to include in its reply.
+var params = []byte{optSubnetMask, optRouter, optDNS, optDomainName, optBroadcastAddr, optNTP, optMTU, optLeaseTime, optRenewalTime, optRebindingTime}
+
+func (c *client) send(m *message, to net.Addr) error {
+	fmt.Fprintf(c.out, ">>> sent to %s\n%s\n", to, m)
+	_, err := c.conn.WriteTo(m.marshal(), to)
+	return err
+}
+
+// roundTrip sends m and waits for a reply of one of the wanted types, retransmitting with a doubling timeout.
+func (c *client) roundTrip(ctx context.Context, m *message, want ...byte) (*message, error) {
+	timeout := c.timeout
+	for attempt := 0; attempt <= c.retries; attempt++ {
+		m.Secs = uint16(time.Since(c.start).Seconds()) // servers may use "seconds since we started" to prioritize
+		if err := c.send(m, c.server); err != nil {
+			return nil, err
+		}
+		reply, err := c.await(ctx, timeout, want...)
+		if err == nil || !errors.Is(err, os.ErrDeadlineExceeded) {
+			return reply, err
+		}
+		fmt.Fprintf(c.out, "... no reply after %s\n", timeout)
+		timeout *= 2
+	}
+	return nil, fmt.Errorf("no reply to %s after %d attempts", msgTypeNames[m.Type()], c.retries+1)
+}
+
+// await reads until a reply with our xid and one of the wanted types arrives, or timeout passes.
+func (c *client) await(ctx context.Context, timeout time.Duration, want ...byte) (*message, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.conn.SetReadDeadline(deadline)
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := c.conn.ReadFrom(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		m, err := unmarshal(buf[:n])
+		if err != nil || m.Op != opReply || m.XID != c.xid || m.CHAddr.String() != c.mac.String() {
+			continue // someone else's conversation
+		}
+		for _, w := range want {
+			if m.Type() == w {
+				fmt.Fprintf(c.out, "<<< received from %s\n%s\n", from, m)
+				return m, nil
+			}
+		}
+		fmt.Fprintf(c.out, "... ignoring %s from %s\n", msgTypeNames[m.Type()], from)
+	}
+}
+
+// lease runs the full DISCOVER, OFFER, REQUEST, ACK exchange and returns the ACK.
+// with release set it gives the address straight back, since the demo never configures it.
+func (c *client) lease(ctx context.Context, release bool) (*message, error) {
+	// 1. DISCOVER: "is there a DHCP server out there?" broadcast, since we have no address and don't know the server's.
+	discover := c.newMessage(msgDiscover)
+	discover.add(optParamRequest, params...)
+	offer, err := c.roundTrip(ctx, discover, msgOffer)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. OFFER: a server proposes yiaddr. several servers may answer; we take the first.
+	// 3. REQUEST: still broadcast, so every server that made an offer learns which one we picked (option 54)
+	// and the others can take their offers back.
+	serverID := offer.ip(optServerID)
+	if !serverID.IsValid() {
+		return nil, fmt.Errorf("offer of %s has no server identifier", offer.YIAddr)
+	}
+	request := c.newMessage(msgRequest)
+	request.add(optRequestedIP, offer.YIAddr.AsSlice()...)
+	request.add(optServerID, serverID.AsSlice()...)
+	request.add(optParamRequest, params...)
+	ack, err := c.roundTrip(ctx, request, msgAck, msgNak)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. ACK: the lease is ours. or NAK: the offer went stale (someone else got the address, or we're on the wrong
+	// network); a real client would start over from DISCOVER.
+	if ack.Type() == msgNak {
+		text, _ := ack.get(optMessage)
+		return nil, fmt.Errorf("server %s refused the request (DHCPNAK): %q", serverID, text)
+	}
+
+	if release {
+		// RELEASE goes straight to the server, from the leased address; no reply is expected.
+		rel := c.newMessage(msgRelease)
+		rel.Flags = 0
+		rel.CIAddr = ack.YIAddr
+		rel.add(optServerID, serverID.AsSlice()...)
+		to := &net.UDPAddr{IP: serverID.AsSlice(), Port: c.server.(*net.UDPAddr).Port}
+		if err := c.send(rel, to); err != nil {
+			return ack, fmt.Errorf("releasing %s: %w", ack.YIAddr, err)
+		}
+	}
+	return ack, nil
+}
+
+// inform asks for configuration only (DHCPINFORM, RFC 2131 section 3.4): the client already has addr, configured
+// by hand or some other way, and wants DNS servers, routers, and the like. no address is leased, so there's nothing
+// to clean up.
+func (c *client) inform(ctx context.Context, addr netip.Addr) (*message, error) {
+	m := c.newMessage(msgInform)
+	m.Flags = 0 // we have an address; the server answers it directly
+	m.CIAddr = addr
+	m.add(optParamRequest, params...)
+	return c.roundTrip(ctx, m, msgAck)
+}
+
+// summary describes the configuration in an ACK in a few lines.
+func summary(ack *message, leased bool) string {
+	s := ""
+	if leased {
+		mask := ack.ip(optSubnetMask)
+		addr := ack.YIAddr.String()
+		if mask.IsValid() {
+			ones, _ := net.IPMask(mask.AsSlice()).Size()
+			addr = fmt.Sprintf("%s/%d", ack.YIAddr, ones)
+		}
+		s += fmt.Sprintf("address:   %s\n", addr)
+		for _, t := range []struct {
+			label string
+			code  byte
+		}{{"lease:     ", optLeaseTime}, {"renew at:  ", optRenewalTime}, {"rebind at: ", optRebindingTime}} {
+			if v, ok := ack.get(t.code); ok {
+				s += t.label + formatOption(option{t.code, v}) + "\n"
+			}
+		}
+	}
+	for _, t := range []struct {
+		label string
+		code  byte
+	}{{"router:    ", optRouter}, {"dns:       ", optDNS}, {"domain:    ", optDomainName}, {"ntp:       ", optNTP}, {"mtu:       ", optMTU}} {
+		if v, ok := ack.get(t.code); ok {
+			s += t.label + formatOption(option{t.code, v}) + "\n"
+		}
+	}
+	if s == "" {
+		return "the server sent no configuration\n"
+	}
+	return s
+}
@@ -0,0 +1,9 @@
+// Command dhcpdemo runs a DHCP exchange and prints it; see package dhcpdemo.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/dhcpdemo"
+)
+
+func main() { cli.Main(dhcpdemo.Command) }
@@ -0,0 +1,309 @@
+package dhcpdemo
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+	"time"
+)
+
+// a DHCP message (RFC 2131) is a BOOTP packet from 1985 with a list of options bolted onto the end.
+// the fixed part is 236 bytes, most of it unused nowadays:
+//
+//	op (1) | htype (1) | hlen (1) | hops (1) | xid (4) | secs (2) | flags (2)
+//	ciaddr (4)  client's current address, if it has one
+//	yiaddr (4)  "your" address: the one the server is offering
+//	siaddr (4)  next server (for network boot)
+//	giaddr (4)  relay agent
+//	chaddr (16) client hardware address
+//	sname (64) | file (128)   boot server name and boot file: legacy
+//	magic cookie 99.130.83.99, then options: code (1) | length (1) | value, ending with 255
+//
+// everything interesting, starting with which of DISCOVER, OFFER, REQUEST, ACK... a message is, lives in options.
+
+const (
+	opRequest = 1 // client to server
+	opReply   = 2 // server to client
+
+	flagBroadcast = 0x8000 // "I have no address yet, broadcast your reply"
+
+	fixedLen = 236
+)
+
+var magicCookie = []byte{99, 130, 83, 99}
+
+// message types (option 53)
+const (
+	msgDiscover = 1
+	msgOffer    = 2
+	msgRequest  = 3
+	msgDecline  = 4
+	msgAck      = 5
+	msgNak      = 6
+	msgRelease  = 7
+	msgInform   = 8
+)
+
+var msgTypeNames = map[byte]string{
+	msgDiscover: "DHCPDISCOVER", msgOffer: "DHCPOFFER", msgRequest: "DHCPREQUEST", msgDecline: "DHCPDECLINE",
+	msgAck: "DHCPACK", msgNak: "DHCPNAK", msgRelease: "DHCPRELEASE", msgInform: "DHCPINFORM",
+}
+
+// option codes (RFC 2132)
+const (
+	optPad           = 0
+	optSubnetMask    = 1
+	optRouter        = 3
+	optDNS           = 6
+	optHostName      = 12
+	optDomainName    = 15
+	optMTU           = 26
+	optBroadcastAddr = 28
+	optNTP           = 42
+	optRequestedIP   = 50
+	optLeaseTime     = 51
+	optMessageType   = 53
+	optServerID      = 54
+	optParamRequest  = 55
+	optMessage       = 56
+	optRenewalTime   = 58
+	optRebindingTime = 59
+	optClientID      = 61
+	optEnd           = 255
+)
+
+// the option formats we know how to print; anything else is shown as hex.
+type optKind int
+
+const (
+	kindBytes optKind = iota
+	kindIPs
+	kindString
+	kindDuration
+	kindUint16
+	kindMsgType
+	kindCodes
+)
+
+var optionInfo = map[byte]struct {
+	name string
+	kind optKind
+}{
+	optSubnetMask:    {"subnet mask", kindIPs},
+	optRouter:        {"router", kindIPs},
+	optDNS:           {"DNS servers", kindIPs},
+	optHostName:      {"host name", kindString},
+	optDomainName:    {"domain name", kindString},
+	optMTU:           {"interface MTU", kindUint16},
+	optBroadcastAddr: {"broadcast address", kindIPs},
+	optNTP:           {"NTP servers", kindIPs},
+	optRequestedIP:   {"requested IP", kindIPs},
+	optLeaseTime:     {"lease time", kindDuration},
+	optMessageType:   {"message type", kindMsgType},
+	optServerID:      {"server identifier", kindIPs},
+	optParamRequest:  {"parameter request list", kindCodes},
+	optMessage:       {"message", kindString},
+	optRenewalTime:   {"renewal (T1) time", kindDuration},
+	optRebindingTime: {"rebinding (T2) time", kindDuration},
+	optClientID:      {"client identifier", kindBytes},
+}
+
+// message is a decoded DHCP message. options keep their wire order.
+type message struct {
+	Op      byte
+	XID     uint32
+	Secs    uint16
+	Flags   uint16
+	CIAddr  netip.Addr
+	YIAddr  netip.Addr
+	SIAddr  netip.Addr
+	GIAddr  netip.Addr
+	CHAddr  net.HardwareAddr
+	Options []option
+}
+
+type option struct {
+	Code  byte
+	Value []byte
+}
+
+func (m *message) add(code byte, value ...byte) *message {
+	m.Options = append(m.Options, option{code, value})
+	return m
+}
+
+// get returns the value of the first option with the given code.
+func (m *message) get(code byte) ([]byte, bool) {
+	for _, o := range m.Options {
+		if o.Code == code {
+			return o.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Type returns the message type from option 53, or 0 for a plain BOOTP message.
+func (m *message) Type() byte {
+	if v, ok := m.get(optMessageType); ok && len(v) == 1 {
+		return v[0]
+	}
+	return 0
+}
+
+// ip returns an address-valued option.
+func (m *message) ip(code byte) netip.Addr {
+	v, _ := m.get(code)
+	if len(v) != 4 {
+		return netip.Addr{}
+	}
+	a, _ := netip.AddrFromSlice(v)
+	return a
+}
+
+func (m *message) marshal() []byte {
+	b := make([]byte, fixedLen, 300)
+	b[0] = m.Op
+	b[1], b[2] = 1, 6 // Ethernet, 6-byte addresses
+	binary.BigEndian.PutUint32(b[4:], m.XID)
+	binary.BigEndian.PutUint16(b[8:], m.Secs)
+	binary.BigEndian.PutUint16(b[10:], m.Flags)
+	for i, a := range []netip.Addr{m.CIAddr, m.YIAddr, m.SIAddr, m.GIAddr} {
+		if a.IsValid() {
+			copy(b[12+4*i:], a.AsSlice())
+		}
+	}
+	copy(b[28:44], m.CHAddr)
+
+	b = append(b, magicCookie...)
+	for _, o := range m.Options {
+		b = append(b, o.Code, byte(len(o.Value)))
+		b = append(b, o.Value...)
+	}
+	b = append(b, optEnd)
+	// some old BOOTP relays drop anything shorter than the original 300-byte BOOTP packet.
+	for len(b) < 300 {
+		b = append(b, optPad)
+	}
+	return b
+}
+
+var errNotDHCP = errors.New("not a DHCP message")
+
+func unmarshal(b []byte) (*message, error) {
+	if len(b) < fixedLen+len(magicCookie) || string(b[fixedLen:fixedLen+4]) != string(magicCookie) {
+		return nil, errNotDHCP
+	}
+	m := &message{
+		Op:    b[0],
+		XID:   binary.BigEndian.Uint32(b[4:]),
+		Secs:  binary.BigEndian.Uint16(b[8:]),
+		Flags: binary.BigEndian.Uint16(b[10:]),
+	}
+	for i, dst := range []*netip.Addr{&m.CIAddr, &m.YIAddr, &m.SIAddr, &m.GIAddr} {
+		*dst, _ = netip.AddrFromSlice(b[12+4*i : 16+4*i])
+	}
+	hlen := min(int(b[2]), 16)
+	m.CHAddr = net.HardwareAddr(append([]byte(nil), b[28:28+hlen]...))
+
+	opts := b[fixedLen+4:]
+	for len(opts) > 0 {
+		code := opts[0]
+		switch code {
+		case optPad:
+			opts = opts[1:]
+			continue
+		case optEnd:
+			return m, nil
+		}
+		if len(opts) < 2 || len(opts) < 2+int(opts[1]) {
+			return nil, fmt.Errorf("option %d runs past the end of the message", code)
+		}
+		m.Options = append(m.Options, option{code, append([]byte(nil), opts[2:2+int(opts[1])]...)})
+		opts = opts[2+int(opts[1]):]
+	}
+	return nil, fmt.Errorf("options not terminated by an end option")
+}
+
+// String prints the message the way a DHCP walkthrough would: header fields, then one option per line.
+func (m *message) String() string {
+	var b strings.Builder
+	name := msgTypeNames[m.Type()]
+	if name == "" {
+		name = fmt.Sprintf("type %d", m.Type())
+	}
+	fmt.Fprintf(&b, "%s  xid=0x%08x  chaddr=%s", name, m.XID, m.CHAddr)
+	if m.Flags&flagBroadcast != 0 {
+		b.WriteString("  flags=broadcast")
+	}
+	b.WriteString("\n")
+	for _, f := range []struct {
+		name string
+		a    netip.Addr
+	}{{"ciaddr (client)", m.CIAddr}, {"yiaddr (offered)", m.YIAddr}, {"siaddr (next server)", m.SIAddr}, {"giaddr (relay)", m.GIAddr}} {
+		if f.a.IsValid() && !f.a.IsUnspecified() {
+			fmt.Fprintf(&b, "  %-30s %s\n", f.name, f.a)
+		}
+	}
+	for _, o := range m.Options {
+		fmt.Fprintf(&b, "  %-30s %s\n", optionName(o.Code), formatOption(o))
+	}
+	return b.String()
+}
+
+func optionName(code byte) string {
+	if info, ok := optionInfo[code]; ok {
+		return fmt.Sprintf("(%d) %s", code, info.name)
+	}
+	return fmt.Sprintf("(%d)", code)
+}
+
+func formatOption(o option) string {
+	switch optionInfo[o.Code].kind {
+	case kindIPs:
+		if len(o.Value)%4 != 0 {
+			break
+		}
+		var ips []string
+		for i := 0; i < len(o.Value); i += 4 {
+			a, _ := netip.AddrFromSlice(o.Value[i : i+4])
+			ips = append(ips, a.String())
+		}
+		return strings.Join(ips, ", ")
+	case kindString:
+		return fmt.Sprintf("%q", o.Value)
+	case kindDuration:
+		if len(o.Value) != 4 {
+			break
+		}
+		secs := binary.BigEndian.Uint32(o.Value)
+		if secs == 0xffffffff {
+			return "infinite"
+		}
+		return (time.Duration(secs) * time.Second).String()
+	case kindUint16:
+		if len(o.Value) == 2 {
+			return fmt.Sprint(binary.BigEndian.Uint16(o.Value))
+		}
+	case kindMsgType:
+		if len(o.Value) == 1 && msgTypeNames[o.Value[0]] != "" {
+			return msgTypeNames[o.Value[0]]
+		}
+	case kindCodes:
+		codes := append([]byte(nil), o.Value...)
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+		var names []string
+		for _, c := range codes {
+			if info, ok := optionInfo[c]; ok {
+				names = append(names, info.name)
+			} else {
+				names = append(names, fmt.Sprint(c))
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("% x", o.Value)
+}
@@ -0,0 +1,151 @@
+package dhcpdemo
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMarshalRoundTrip(t *testing.T) {
+	m := &message{
+		Op:     opReply,
+		XID:    0xdeadbeef,
+		Flags:  flagBroadcast,
+		YIAddr: netip.MustParseAddr("192.168.1.50"),
+		CHAddr: net.HardwareAddr{2, 0, 0, 0, 0, 1},
+	}
+	m.add(optMessageType, msgOffer)
+	m.add(optServerID, 192, 168, 1, 1)
+	m.add(optLeaseTime, 0, 0, 0x0e, 0x10)
+	m.add(optDNS, 1, 1, 1, 1, 8, 8, 8, 8)
+
+	b := m.marshal()
+	if len(b) < 300 {
+		t.Errorf("marshal() = %d bytes, want at least 300", len(b))
+	}
+	got, err := unmarshal(b)
+	if err != nil {
+		t.Fatalf("unmarshal() error: %v", err)
+	}
+	if got.XID != m.XID || got.YIAddr != m.YIAddr || got.CHAddr.String() != m.CHAddr.String() || got.Type() != msgOffer {
+		t.Errorf("unmarshal() = %+v, want %+v", got, m)
+	}
+	if got.ip(optServerID) != netip.MustParseAddr("192.168.1.1") {
+		t.Errorf("server identifier = %s", got.ip(optServerID))
+	}
+
+	s := got.String()
+	for _, want := range []string{"DHCPOFFER", "192.168.1.50", "lease time", "1h0m0s", "1.1.1.1, 8.8.8.8"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("String() is missing %q:\n%s", want, s)
+		}
+	}
+
+	if _, err := unmarshal(b[:200]); err == nil {
+		t.Errorf("unmarshal(short) succeeded; want error")
+	}
+	bad := bytes.Clone(b)
+	bad[fixedLen+4+1] = 200 // first option's length runs off the end
+	if _, err := unmarshal(bad); err == nil {
+		t.Errorf("unmarshal(bad option length) succeeded; want error")
+	}
+}
+
+// fakeServer answers DISCOVER with an OFFER, REQUEST with an ACK, and INFORM with an ACK, ignoring the first
+// message it sees so the client has to retransmit.
+func fakeServer(t *testing.T, conn net.PacketConn, got chan<- byte) {
+	buf := make([]byte, 1500)
+	dropped := false
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			return
+		}
+		req, err := unmarshal(buf[:n])
+		if err != nil {
+			t.Errorf("server: unmarshal: %v", err)
+			return
+		}
+		got <- req.Type()
+		if !dropped {
+			dropped = true
+			continue
+		}
+
+		reply := &message{Op: opReply, XID: req.XID, Flags: req.Flags, CHAddr: req.CHAddr}
+		switch req.Type() {
+		case msgDiscover:
+			reply.YIAddr = netip.MustParseAddr("10.0.0.50")
+			reply.add(optMessageType, msgOffer)
+		case msgRequest, msgInform:
+			if req.Type() == msgRequest {
+				reply.YIAddr = netip.MustParseAddr("10.0.0.50")
+				reply.add(optLeaseTime, 0, 0, 0x0e, 0x10)
+			}
+			reply.add(optMessageType, msgAck)
+		default:
+			continue
+		}
+		reply.add(optServerID, 127, 0, 0, 1) // so the RELEASE, sent straight to the server, reaches us
+		reply.add(optSubnetMask, 255, 255, 255, 0)
+		reply.add(optRouter, 10, 0, 0, 1)
+		// a reply to someone else's transaction first: the client must skip it.
+		other := *reply
+		other.XID++
+		conn.WriteTo(other.marshal(), from)
+		conn.WriteTo(reply.marshal(), from)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	srv, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+	types := make(chan byte, 10)
+	go fakeServer(t, srv, types)
+
+	newClient := func() *client {
+		conn, err := net.ListenPacket("udp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { conn.Close() })
+		var out bytes.Buffer
+		return &client{conn: conn, server: srv.LocalAddr(), mac: net.HardwareAddr{2, 0, 0, 0, 0, 9}, xid: 42,
+			timeout: 50 * time.Millisecond, retries: 2, out: &out, start: time.Now()}
+	}
+
+	ack, err := newClient().lease(context.Background(), true)
+	if err != nil {
+		t.Fatalf("lease() error: %v", err)
+	}
+	if ack.YIAddr != netip.MustParseAddr("10.0.0.50") {
+		t.Errorf("leased %s, want 10.0.0.50", ack.YIAddr)
+	}
+	if s := summary(ack, true); !strings.Contains(s, "10.0.0.50/24") || !strings.Contains(s, "router:") {
+		t.Errorf("summary() =\n%s", s)
+	}
+	// the dropped DISCOVER, its retransmission, the REQUEST, and the RELEASE.
+	for _, want := range []byte{msgDiscover, msgDiscover, msgRequest, msgRelease} {
+		if got := <-types; got != want {
+			t.Errorf("server got %s, want %s", msgTypeNames[got], msgTypeNames[want])
+		}
+	}
+
+	ack, err = newClient().inform(context.Background(), netip.MustParseAddr("10.0.0.7"))
+	if err != nil {
+		t.Fatalf("inform() error: %v", err)
+	}
+	if _, leased := ack.get(optLeaseTime); leased {
+		t.Errorf("inform() ACK carries a lease time")
+	}
+	if got := <-types; got != msgInform {
+		t.Errorf("server got %s, want DHCPINFORM", msgTypeNames[got])
+	}
+}
@@ -0,0 +1,137 @@
+// Package dhcpdemo is the dhcpdemo tool: it walks through a DHCP exchange (RFC 2131) and prints every message.
+package dhcpdemo
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/netip"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+const (
+	serverPort = 67
+	clientPort = 68
+)
+
+// Command is the dhcpdemo tool.
+var Command = &cli.Command{
+	Name:    "dhcpdemo",
+	Summary: "walk through a DHCP exchange and print every message",
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		var o options
+		fs.StringVar(&o.iface, "i", "", "interface to run DHCP on (default: the first one that's up and has a MAC)")
+		fs.BoolVar(&o.informOnly, "inform-only", false, "only ask for configuration (DHCPINFORM) for the interface's current address; leases nothing")
+		fs.StringVar(&o.server, "server", "255.255.255.255", "where to send requests: broadcast, or a specific server's address")
+		fs.DurationVar(&o.timeout, "timeout", 4*time.Second, "time to wait for the first reply; doubles on every retransmission")
+		fs.IntVar(&o.retries, "retries", 2, "retransmissions before giving up")
+		fs.BoolVar(&o.release, "release", true, "release the leased address right after getting it")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			return run(ctx, env, o)
+		}
+	},
+}
+
+type options struct {
+	iface, server       string
+	informOnly, release bool
+	timeout             time.Duration
+	retries             int
+}
+
+func run(ctx context.Context, env *cli.Env, o options) error {
+	iface, err := pickInterface(o.iface)
+	if err != nil {
+		return err
+	}
+	serverIP, err := netip.ParseAddr(o.server)
+	if err != nil || !serverIP.Is4() {
+		return cli.Usagef("invalid -server %q: want an IPv4 address", o.server)
+	}
+
+	conn, err := listen(ctx, iface.Name, clientPort)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	go func() {
+		<-ctx.Done()
+		conn.Close()
+	}()
+
+	c := &client{
+		conn:    conn,
+		server:  &net.UDPAddr{IP: serverIP.AsSlice(), Port: serverPort},
+		mac:     iface.HardwareAddr,
+		xid:     rand.Uint32(),
+		timeout: o.timeout,
+		retries: o.retries,
+		out:     env.Stdout,
+		start:   time.Now(),
+	}
+	slog.InfoContext(ctx, "main", "message", "starting", "interface", iface.Name, "mac", iface.HardwareAddr.String(), "xid", fmt.Sprintf("0x%08x", c.xid))
+
+	// nothing here ever touches the interface's configuration: we print what a real client would apply.
+	if o.informOnly {
+		addr, err := ipv4Addr(iface)
+		if err != nil {
+			return err
+		}
+		ack, err := c.inform(ctx, addr)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(env.Stdout, "configuration for ", addr, ":\n", summary(ack, false))
+		return nil
+	}
+
+	ack, err := c.lease(ctx, o.release)
+	if ack != nil {
+		fmt.Fprint(env.Stdout, "lease:\n", summary(ack, true))
+	}
+	return err
+}
+
+func pickInterface(name string) (*net.Interface, error) {
+	if name != "" {
+		iface, err := net.InterfaceByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if len(iface.HardwareAddr) != 6 {
+			return nil, fmt.Errorf("%s has no Ethernet address", name)
+		}
+		return iface, nil
+	}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	for i := range ifaces {
+		if ifaces[i].Flags&net.FlagUp != 0 && ifaces[i].Flags&net.FlagLoopback == 0 && len(ifaces[i].HardwareAddr) == 6 {
+			return &ifaces[i], nil
+		}
+	}
+	return nil, errors.New("no interface is up with an Ethernet address; pick one with -i")
+}
+
+func ipv4Addr(iface *net.Interface) (netip.Addr, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok {
+			if ip, ok := netip.AddrFromSlice(ipnet.IP); ok && ip.Unmap().Is4() {
+				return ip.Unmap(), nil
+			}
+		}
+	}
+	return netip.Addr{}, fmt.Errorf("-inform-only needs an IPv4 address on %s, and it has none", iface.Name)
+}
@@ -0,0 +1,39 @@
+//go:build linux
+
+package dhcpdemo
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"syscall"
+)
+
+// listen binds the DHCP client port on every address, the only way to hear replies broadcast to a host with no address
+// yet. SO_BINDTODEVICE restricts it to one interface, so we only talk to that interface's segment; SO_BROADCAST
+// allows sending to 255.255.255.255 at all.
+func listen(ctx context.Context, iface string, port int) (net.PacketConn, error) {
+	lc := net.ListenConfig{Control: func(network, address string, c syscall.RawConn) error {
+		var serr error
+		err := c.Control(func(fd uintptr) {
+			if serr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_BROADCAST, 1); serr != nil {
+				return
+			}
+			if serr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1); serr != nil {
+				return
+			}
+			if iface != "" {
+				serr = syscall.BindToDevice(int(fd), iface)
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return serr
+	}}
+	conn, err := lc.ListenPacket(ctx, "udp4", fmt.Sprintf("0.0.0.0:%d", port))
+	if err != nil {
+		return nil, fmt.Errorf("listening on port %d (needs root or CAP_NET_BIND_SERVICE, and no other DHCP client on it): %w", port, err)
+	}
+	return conn, nil
+}
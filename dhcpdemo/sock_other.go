@@ -0,0 +1,15 @@
+//go:build !linux
+
+package dhcpdemo
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// pinning the socket to one interface needs SO_BINDTODEVICE, which is Linux-only; without it replies from the
+// wrong network could be mistaken for ours.
+func listen(ctx context.Context, iface string, port int) (net.PacketConn, error) {
+	return nil, errors.New("dhcpdemo only runs on Linux")
+}
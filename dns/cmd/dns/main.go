@@ -0,0 +1,9 @@
+// Command dns resolves a URL's host; see package nslookup.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/nslookup"
+)
+
+func main() { cli.Main(nslookup.Command) }
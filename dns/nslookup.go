@@ -0,0 +1,93 @@
+// Package nslookup is the dns tool: it resolves a URL's host to its IPv4 and IPv6 addresses.
+package nslookup
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/ekediala/cli"
+	"github.com/ekediala/retry"
+)
+
+// Command is the dns tool.
+var Command = &cli.Command{
+	Name:      "dns",
+	Summary:   "resolve a URL's host to its IPv4 and IPv6 addresses",
+	Args:      "<URL>",
+	LogFormat: "json",
+	JSON:      true,
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		return run
+	},
+}
+
+// result is what -json prints.
+type result struct {
+	Host string   `json:"host"`
+	IPv4 []string `json:"ipv4"`
+	IPv6 []string `json:"ipv6"`
+}
+
+func run(ctx context.Context, env *cli.Env, args []string) error {
+	if len(args) != 1 {
+		return cli.Usagef("expected exactly one argument; got %d", len(args))
+	}
+
+	u, err := url.Parse(args[0])
+	if err != nil {
+		return err
+	}
+
+	// DNS runs over UDP, so lost packets and slow resolvers are routine; retry lookups that failed for a temporary reason.
+	policy := retry.DefaultPolicy
+	policy.Retryable = retry.IsNetRetryable
+	policy.OnRetry = func(attempt int, err error, delay time.Duration) {
+		slog.WarnContext(ctx, "main", "host", u.Host, "attempt", attempt, "error", err.Error(), "retrying in", delay.String())
+	}
+
+	var ips []net.IP
+	err = policy.Do(ctx, func(ctx context.Context) (err error) {
+		ips, err = net.DefaultResolver.LookupIP(ctx, "ip", u.Host)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("looking up %s: %w", u.Host, err)
+	}
+
+	if len(ips) == 0 {
+		return fmt.Errorf("no ips found for %s", u.Host)
+	}
+
+	if env.JSON {
+		r := result{Host: u.Host, IPv4: []string{}, IPv6: []string{}}
+		for _, ip := range ips {
+			if ip.To4() != nil {
+				r.IPv4 = append(r.IPv4, ip.String())
+			} else {
+				r.IPv6 = append(r.IPv6, ip.String())
+			}
+		}
+		return env.PrintJSON(r)
+	}
+
+	for _, ip := range ips {
+		if ip.To4() != nil {
+			slog.InfoContext(ctx, "ipv4", "ip", ip.String())
+			goto IPV6
+		}
+	}
+
+IPV6:
+	for _, ip := range ips {
+		if ip.To4() == nil {
+			slog.InfoContext(ctx, "ipv6", "ip", ip.String())
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+// election runs a node of a Raft-style leader election over TCP and logs elections and leader changes.
+// start one per peer, or use -local to run a whole cluster in one process.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ekediala/election"
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer done()
+
+	const name = "election"
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	log = log.With("app", name)
+	slog.SetDefault(log)
+
+	addr := flag.String("addr", "localhost:7001", "address to listen on; also the node's ID, so use the address peers will dial")
+	peers := flag.String("peers", "", "comma-separated addresses of the other nodes")
+	local := flag.Int("local", 0, "run this many nodes in one process on localhost instead of a single node")
+	timeout := flag.Duration("election-timeout", 300*time.Millisecond, "minimum election timeout")
+	heartbeat := flag.Duration("heartbeat", 100*time.Millisecond, "leader heartbeat interval")
+	flag.Parse()
+
+	var configs []election.Config
+	if *local > 0 {
+		addrs, err := localAddrs(*local)
+		if err != nil {
+			slog.ErrorContext(ctx, "main", "error", err.Error())
+			os.Exit(1)
+		}
+		for _, a := range addrs {
+			configs = append(configs, election.Config{Addr: a, Peers: without(addrs, a)})
+		}
+	} else {
+		var peerList []string
+		if *peers != "" {
+			peerList = strings.Split(*peers, ",")
+		}
+		configs = append(configs, election.Config{Addr: *addr, Peers: peerList})
+	}
+
+	var wg sync.WaitGroup
+	for _, cfg := range configs {
+		cfg.ElectionTimeout, cfg.HeartbeatInterval = *timeout, *heartbeat
+		node, err := election.New(cfg)
+		if err != nil {
+			slog.ErrorContext(ctx, "main", "error", err.Error())
+			os.Exit(1)
+		}
+		slog.InfoContext(ctx, "main", "message", "node started", "node", node.ID(), "peers", strings.Join(cfg.Peers, ","))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := node.Run(ctx); err != nil {
+				slog.ErrorContext(ctx, "main", "node", node.ID(), "error", err.Error())
+			}
+		}()
+	}
+	wg.Wait()
+	slog.InfoContext(ctx, "main", "message", "shutdown signal received")
+}
+
+// localAddrs picks n free localhost ports.
+func localAddrs(n int) ([]string, error) {
+	addrs := make([]string, n)
+	for i := range addrs {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return nil, fmt.Errorf("finding a free port: %w", err)
+		}
+		addrs[i] = l.Addr().String()
+		l.Close()
+	}
+	return addrs, nil
+}
+
+func without(addrs []string, addr string) []string {
+	var out []string
+	for _, a := range addrs {
+		if a != addr {
+			out = append(out, a)
+		}
+	}
+	return out
+}
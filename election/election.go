@@ -0,0 +1,360 @@
+// Package election implements the leader election half of Raft over TCP.
+//
+// every node starts as a follower. a follower that hears nothing from a leader for a randomized election timeout
+// becomes a candidate: it bumps the term, votes for itself, and asks every peer for a vote. a candidate that collects
+// votes from a majority becomes leader and sends heartbeats to keep everyone else from starting elections of their own.
+// any message carrying a newer term makes the receiver step down to follower in that term.
+//
+// the randomized timeouts are what make this work: they make it unlikely that two nodes start an election at the
+// same moment and split the vote, and when they do, the next round is likely to break the tie.
+//
+// this is "Raft-lite": there is no log, so votes are granted first-come-first-served within a term instead of
+// depending on how up-to-date the candidate's log is. that's the part to add when replicating data on top.
+// see "In Search of an Understandable Consensus Algorithm" (Ongaro, Ousterhout, 2014), section 5.2.
+package election
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+)
+
+// Role is the part a node currently plays in the election protocol.
+type Role int
+
+const (
+	Follower Role = iota
+	Candidate
+	Leader
+)
+
+func (r Role) String() string {
+	switch r {
+	case Follower:
+		return "follower"
+	case Candidate:
+		return "candidate"
+	case Leader:
+		return "leader"
+	default:
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+}
+
+// Config configures a Node.
+type Config struct {
+	Addr  string   // TCP address to listen on; it doubles as the node's ID, so it must be the address peers dial
+	Peers []string // addresses of every other node in the cluster
+
+	ElectionTimeout   time.Duration // minimum election timeout; each wait is randomized in [ElectionTimeout, 2*ElectionTimeout). defaults to 300ms
+	HeartbeatInterval time.Duration // how often a leader sends heartbeats; defaults to 100ms. must be well below ElectionTimeout
+
+	Logger *slog.Logger // defaults to slog.Default()
+}
+
+// Node is one participant in leader elections.
+type Node struct {
+	cfg      Config
+	listener net.Listener
+	log      *slog.Logger
+
+	mu       sync.Mutex
+	term     uint64
+	votedFor string // who we voted for in term; empty if nobody yet
+	role     Role
+	leader   string
+
+	heard chan struct{} // signalled whenever something resets the election timer
+}
+
+// New creates a node listening on cfg.Addr. Call Run to take part in elections.
+func New(cfg Config) (*Node, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("election: missing listen address")
+	}
+	if cfg.ElectionTimeout == 0 {
+		cfg.ElectionTimeout = 300 * time.Millisecond
+	}
+	if cfg.HeartbeatInterval == 0 {
+		cfg.HeartbeatInterval = 100 * time.Millisecond
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+	l, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("election: %w", err)
+	}
+	return &Node{
+		cfg:      cfg,
+		listener: l,
+		log:      cfg.Logger.With("node", cfg.Addr),
+		heard:    make(chan struct{}, 1),
+	}, nil
+}
+
+// ID returns the node's ID, which is its listen address.
+func (n *Node) ID() string { return n.cfg.Addr }
+
+// Status returns the node's current term, role, and the leader it knows of (empty if none).
+func (n *Node) Status() (term uint64, role Role, leader string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.term, n.role, n.leader
+}
+
+// Run serves peers and takes part in elections until ctx is done.
+func (n *Node) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		n.listener.Close()
+	}()
+	go n.loop(ctx)
+
+	for {
+		conn, err := n.listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("election: accepting: %w", err)
+		}
+		go n.serve(ctx, conn)
+	}
+}
+
+// loop drives the node's role: followers and candidates wait out election timeouts, leaders send heartbeats.
+func (n *Node) loop(ctx context.Context) {
+	for ctx.Err() == nil {
+		_, role, _ := n.Status()
+		if role == Leader {
+			n.heartbeat(ctx)
+			sleep(ctx, n.cfg.HeartbeatInterval)
+			continue
+		}
+
+		timeout := n.cfg.ElectionTimeout + rand.N(n.cfg.ElectionTimeout)
+		t := time.NewTimer(timeout)
+		select {
+		case <-ctx.Done():
+		case <-n.heard:
+		case <-t.C:
+			n.elect(ctx)
+		}
+		t.Stop()
+	}
+}
+
+// resetTimer restarts the election timeout; it never blocks.
+func (n *Node) resetTimer() {
+	select {
+	case n.heard <- struct{}{}:
+	default:
+	}
+}
+
+// stepDown moves to follower in a newer term. n.mu must be held.
+func (n *Node) stepDown(ctx context.Context, term uint64) {
+	if n.role != Follower {
+		n.log.InfoContext(ctx, "election", "message", "stepping down", "term", term, "was", n.role.String())
+	}
+	n.term, n.votedFor, n.role, n.leader = term, "", Follower, ""
+}
+
+// elect runs one election as a candidate.
+func (n *Node) elect(ctx context.Context) {
+	n.mu.Lock()
+	n.term++
+	n.role, n.votedFor, n.leader = Candidate, n.cfg.Addr, ""
+	term := n.term
+	n.mu.Unlock()
+	n.log.InfoContext(ctx, "election", "message", "starting election", "term", term)
+
+	votes := 1 // our own
+	majority := (len(n.cfg.Peers)+1)/2 + 1
+	if votes >= majority { // a cluster of one
+		n.win(ctx, term, votes)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.ElectionTimeout)
+	defer cancel()
+	replies := n.broadcast(ctx, message{Type: msgRequestVote, Term: term, From: n.cfg.Addr})
+	for r := range replies {
+		n.mu.Lock()
+		if r.Term > n.term {
+			n.stepDown(ctx, r.Term)
+			n.mu.Unlock()
+			return
+		}
+		if n.role != Candidate || n.term != term {
+			n.mu.Unlock()
+			return // someone else won (or a newer term started) while we were counting.
+		}
+		if r.OK {
+			votes++
+		}
+		n.mu.Unlock()
+		if votes >= majority {
+			n.win(ctx, term, votes)
+			n.heartbeat(ctx) // assert leadership straight away
+			return
+		}
+	}
+	n.log.InfoContext(ctx, "election", "message", "election failed", "term", term, "votes", votes)
+}
+
+// win makes the node leader of term, unless the term moved on in the meantime.
+func (n *Node) win(ctx context.Context, term uint64, votes int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.term != term || n.role != Candidate {
+		return
+	}
+	n.role, n.leader = Leader, n.cfg.Addr
+	n.log.InfoContext(ctx, "election", "message", "won election", "term", term, "votes", votes)
+}
+
+// heartbeat sends one round of heartbeats, stepping down if any peer reports a newer term.
+func (n *Node) heartbeat(ctx context.Context) {
+	n.mu.Lock()
+	term := n.term
+	n.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.HeartbeatInterval)
+	defer cancel()
+	for r := range n.broadcast(ctx, message{Type: msgHeartbeat, Term: term, From: n.cfg.Addr}) {
+		n.mu.Lock()
+		if r.Term > n.term {
+			n.stepDown(ctx, r.Term)
+		}
+		n.mu.Unlock()
+	}
+}
+
+// broadcast sends msg to every peer concurrently and returns a channel of their replies, closed once all have answered or failed.
+func (n *Node) broadcast(ctx context.Context, msg message) <-chan reply {
+	replies := make(chan reply, len(n.cfg.Peers))
+	var wg sync.WaitGroup
+	for _, peer := range n.cfg.Peers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := call(ctx, peer, msg)
+			if err != nil {
+				n.log.DebugContext(ctx, "election", "peer", peer, "error", err.Error())
+				return
+			}
+			replies <- r
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(replies)
+	}()
+	return replies
+}
+
+// serve answers a single RPC on conn.
+func (n *Node) serve(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(n.cfg.ElectionTimeout))
+
+	var msg message
+	if err := json.NewDecoder(conn).Decode(&msg); err != nil {
+		n.log.WarnContext(ctx, "election", "from", conn.RemoteAddr().String(), "error", fmt.Sprintf("malformed message: %v", err))
+		return
+	}
+	json.NewEncoder(conn).Encode(n.handle(ctx, msg))
+}
+
+// handle applies the Raft rules for an incoming RequestVote or heartbeat.
+func (n *Node) handle(ctx context.Context, msg message) reply {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if msg.Term < n.term {
+		return reply{Term: n.term} // a stale candidate or deposed leader; our term in the reply tells it to step down.
+	}
+	if msg.Term > n.term {
+		n.stepDown(ctx, msg.Term)
+	}
+
+	switch msg.Type {
+	case msgRequestVote:
+		if n.votedFor != "" && n.votedFor != msg.From {
+			return reply{Term: n.term}
+		}
+		n.votedFor = msg.From
+		n.resetTimer()
+		n.log.InfoContext(ctx, "election", "message", "granted vote", "term", n.term, "candidate", msg.From)
+		return reply{Term: n.term, OK: true}
+	case msgHeartbeat:
+		// a candidate that hears from a leader of its own term lost the race.
+		n.role = Follower
+		if n.leader != msg.From {
+			n.leader = msg.From
+			n.log.InfoContext(ctx, "election", "message", "new leader", "term", n.term, "leader", msg.From)
+		}
+		n.resetTimer()
+		return reply{Term: n.term, OK: true}
+	default:
+		return reply{Term: n.term}
+	}
+}
+
+// message types
+const (
+	msgRequestVote = "request-vote"
+	msgHeartbeat   = "heartbeat"
+)
+
+// message is the request half of both RPCs: a RequestVote from a candidate or a heartbeat (an empty AppendEntries) from a leader.
+type message struct {
+	Type string `json:"type"`
+	Term uint64 `json:"term"`
+	From string `json:"from"`
+}
+
+// reply answers either RPC: OK is "vote granted" or "heartbeat accepted", and Term lets a stale sender catch up.
+type reply struct {
+	Term uint64 `json:"term"`
+	OK   bool   `json:"ok"`
+}
+
+// call dials addr, sends msg as a line of JSON, and reads the reply. Each RPC uses its own short-lived connection.
+func call(ctx context.Context, addr string, msg message) (reply, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return reply{}, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if err := json.NewEncoder(conn).Encode(msg); err != nil {
+		return reply{}, err
+	}
+	var r reply
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&r); err != nil {
+		return reply{}, err
+	}
+	return r, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+	case <-t.C:
+	}
+}
@@ -0,0 +1,114 @@
+package election
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+	"time"
+)
+
+// freeAddrs reserves n localhost addresses by listening and immediately closing.
+func freeAddrs(t *testing.T, n int) []string {
+	t.Helper()
+	addrs := make([]string, n)
+	for i := range addrs {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs[i] = l.Addr().String()
+		l.Close()
+	}
+	return addrs
+}
+
+func startCluster(t *testing.T, size int) ([]*Node, []context.CancelFunc) {
+	t.Helper()
+	addrs := freeAddrs(t, size)
+	nodes := make([]*Node, size)
+	stops := make([]context.CancelFunc, size)
+	for i, addr := range addrs {
+		var peers []string
+		for _, peer := range addrs {
+			if peer != addr {
+				peers = append(peers, peer)
+			}
+		}
+		n, err := New(Config{
+			Addr:              addr,
+			Peers:             peers,
+			ElectionTimeout:   100 * time.Millisecond,
+			HeartbeatInterval: 20 * time.Millisecond,
+			Logger:            slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		go n.Run(ctx)
+		nodes[i], stops[i] = n, cancel
+	}
+	return nodes, stops
+}
+
+// waitForLeader waits until every node in nodes agrees on one leader, and returns its index and term.
+func waitForLeader(t *testing.T, nodes []*Node) (int, uint64) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		leaders := 0
+		leader, agreed := -1, true
+		var leaderTerm uint64
+		for i, n := range nodes {
+			term, role, known := n.Status()
+			if role == Leader {
+				leaders++
+				leader, leaderTerm = i, term
+			}
+			if known == "" || (leader >= 0 && known != nodes[leader].ID()) {
+				agreed = false
+			}
+		}
+		if leaders == 1 && agreed {
+			return leader, leaderTerm
+		}
+	}
+	t.Fatal("nodes never agreed on a single leader")
+	return -1, 0
+}
+
+func TestElection(t *testing.T) {
+	nodes, stops := startCluster(t, 3)
+	leader, term := waitForLeader(t, nodes)
+
+	// kill the leader: the remaining two still form a majority and must elect a new one in a later term.
+	stops[leader]()
+	survivors := append(append([]*Node{}, nodes[:leader]...), nodes[leader+1:]...)
+	newLeader, newTerm := waitForLeader(t, survivors)
+	if newTerm <= term {
+		t.Errorf("new leader %s elected in term %d, want a term after %d", survivors[newLeader].ID(), newTerm, term)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	n := &Node{cfg: Config{Addr: "a"}, log: slog.New(slog.NewTextHandler(io.Discard, nil)), heard: make(chan struct{}, 1), term: 2}
+	ctx := context.Background()
+
+	if r := n.handle(ctx, message{Type: msgRequestVote, Term: 1, From: "b"}); r.OK || r.Term != 2 {
+		t.Errorf("vote request from stale term: got %+v, want denied with term 2", r)
+	}
+	if r := n.handle(ctx, message{Type: msgRequestVote, Term: 3, From: "b"}); !r.OK {
+		t.Errorf("vote request from newer term: got %+v, want granted", r)
+	}
+	if r := n.handle(ctx, message{Type: msgRequestVote, Term: 3, From: "c"}); r.OK {
+		t.Errorf("second vote request in the same term: got %+v, want denied", r)
+	}
+	if r := n.handle(ctx, message{Type: msgHeartbeat, Term: 3, From: "b"}); !r.OK {
+		t.Errorf("heartbeat from current leader: got %+v, want accepted", r)
+	}
+	if _, _, leader := n.Status(); leader != "b" {
+		t.Errorf("leader = %q, want %q", leader, "b")
+	}
+}
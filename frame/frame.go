@@ -0,0 +1,177 @@
+// Package frame splits a byte stream into discrete messages by prefixing each one with its length.
+//
+// TCP delivers a stream of bytes, not messages: two writes can arrive as one read, and one write can arrive as
+// several. newline-delimited protocols solve this by reserving a byte, which breaks down for binary payloads.
+// length-prefixing doesn't care what's in the payload:
+//
+//	+----------------+-------------------+--------------------------+
+//	| length header  | payload           | CRC-32C (optional)       |
+//	| 4 bytes/varint | length bytes      | 4 bytes, big-endian      |
+//	+----------------+-------------------+--------------------------+
+//
+// the reader knows exactly how many bytes to wait for, and MaxFrameSize stops a broken or hostile peer from making it
+// allocate gigabytes because of a garbage length. both ends must agree on the Options.
+package frame
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net"
+	"sync"
+)
+
+// DefaultMaxFrameSize is used when Options.MaxFrameSize is zero.
+const DefaultMaxFrameSize = 16 << 20
+
+// Header selects how the payload length is encoded.
+type Header int
+
+const (
+	// Fixed32 is a 4-byte big-endian length: simple to read in a hex dump, 4 bytes of overhead per frame.
+	Fixed32 Header = iota
+	// Varint is an unsigned LEB128 varint (as in encoding/binary and protobuf): 1 byte for frames under 128 bytes.
+	Varint
+)
+
+var (
+	// ErrFrameTooLarge is returned when a frame exceeds MaxFrameSize, on either the reading or the writing side.
+	ErrFrameTooLarge = errors.New("frame: frame too large")
+	// ErrChecksum is returned by ReadFrame when a frame's checksum doesn't match its payload.
+	ErrChecksum = errors.New("frame: checksum mismatch")
+)
+
+// Options configures framing. The zero value uses Fixed32 headers, DefaultMaxFrameSize, and no checksum.
+type Options struct {
+	Header       Header
+	MaxFrameSize int  // zero means DefaultMaxFrameSize
+	Checksum     bool // append a CRC-32C of the payload to every frame
+}
+
+func (o Options) maxSize() int {
+	if o.MaxFrameSize <= 0 {
+		return DefaultMaxFrameSize
+	}
+	return o.MaxFrameSize
+}
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Writer writes frames to an underlying writer. It is safe for concurrent use: each frame goes out in a single Write,
+// so frames from different goroutines never interleave.
+type Writer struct {
+	w    io.Writer
+	opts Options
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+// NewWriter returns a Writer that frames onto w.
+func NewWriter(w io.Writer, opts Options) *Writer {
+	return &Writer{w: w, opts: opts}
+}
+
+// WriteFrame writes p as one frame.
+func (w *Writer) WriteFrame(p []byte) error {
+	if len(p) > w.opts.maxSize() {
+		return fmt.Errorf("%w: %d bytes, max %d", ErrFrameTooLarge, len(p), w.opts.maxSize())
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// assemble header, payload, and checksum into one buffer: one syscall, and no interleaving between goroutines.
+	b := w.buf[:0]
+	switch w.opts.Header {
+	case Varint:
+		b = binary.AppendUvarint(b, uint64(len(p)))
+	default:
+		b = binary.BigEndian.AppendUint32(b, uint32(len(p)))
+	}
+	b = append(b, p...)
+	if w.opts.Checksum {
+		b = binary.BigEndian.AppendUint32(b, crc32.Checksum(p, castagnoli))
+	}
+	w.buf = b
+
+	_, err := w.w.Write(b)
+	return err
+}
+
+// Reader reads frames from an underlying reader. It is not safe for concurrent use.
+type Reader struct {
+	r    *bufio.Reader
+	opts Options
+}
+
+// NewReader returns a Reader that reads frames from r.
+func NewReader(r io.Reader, opts Options) *Reader {
+	return &Reader{r: bufio.NewReader(r), opts: opts}
+}
+
+// ReadFrame reads the next frame and returns its payload in a newly allocated slice.
+// it returns io.EOF if the stream ends cleanly between frames, and io.ErrUnexpectedEOF if it ends mid-frame.
+func (r *Reader) ReadFrame() ([]byte, error) {
+	size, err := r.readHeader()
+	if err != nil {
+		return nil, err
+	}
+	if size > uint64(r.opts.maxSize()) {
+		return nil, fmt.Errorf("%w: %d bytes, max %d", ErrFrameTooLarge, size, r.opts.maxSize())
+	}
+
+	p := make([]byte, size)
+	if _, err := io.ReadFull(r.r, p); err != nil {
+		return nil, unexpected(err)
+	}
+	if r.opts.Checksum {
+		var sum [4]byte
+		if _, err := io.ReadFull(r.r, sum[:]); err != nil {
+			return nil, unexpected(err)
+		}
+		if binary.BigEndian.Uint32(sum[:]) != crc32.Checksum(p, castagnoli) {
+			return nil, ErrChecksum
+		}
+	}
+	return p, nil
+}
+
+func (r *Reader) readHeader() (uint64, error) {
+	if r.opts.Header == Varint {
+		size, err := binary.ReadUvarint(r.r)
+		if err != nil && err != io.EOF {
+			return 0, unexpected(err) // ReadUvarint returns io.ErrUnexpectedEOF itself for a cut-off varint.
+		}
+		return size, err
+	}
+
+	var hdr [4]byte
+	if _, err := io.ReadFull(r.r, hdr[:]); err != nil {
+		return 0, err // io.EOF if nothing was read, io.ErrUnexpectedEOF if part of it was.
+	}
+	return uint64(binary.BigEndian.Uint32(hdr[:])), nil
+}
+
+// unexpected turns an EOF in the middle of a frame into io.ErrUnexpectedEOF.
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Conn reads and writes frames over a connection.
+type Conn struct {
+	net.Conn
+	*Reader
+	*Writer
+}
+
+// NewConn wraps conn so whole frames can be read from and written to it.
+func NewConn(conn net.Conn, opts Options) *Conn {
+	return &Conn{Conn: conn, Reader: NewReader(conn, opts), Writer: NewWriter(conn, opts)}
+}
@@ -0,0 +1,96 @@
+package frame
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	payloads := [][]byte{
+		[]byte("hello"),
+		{},
+		[]byte("line one\nline two\x00binary"),
+		bytes.Repeat([]byte{0xff}, 300), // bigger than a one-byte varint
+	}
+	for name, opts := range map[string]Options{
+		"fixed32":          {},
+		"varint":           {Header: Varint},
+		"fixed32+checksum": {Checksum: true},
+		"varint+checksum":  {Header: Varint, Checksum: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf, opts)
+			for _, p := range payloads {
+				if err := w.WriteFrame(p); err != nil {
+					t.Fatalf("WriteFrame(%q) returned error: %v", p, err)
+				}
+			}
+
+			r := NewReader(&buf, opts)
+			for _, want := range payloads {
+				got, err := r.ReadFrame()
+				if err != nil {
+					t.Fatalf("ReadFrame() returned error: %v", err)
+				}
+				if !bytes.Equal(got, want) {
+					t.Errorf("ReadFrame() = %q, want %q", got, want)
+				}
+			}
+			if _, err := r.ReadFrame(); err != io.EOF {
+				t.Errorf("ReadFrame() at end of stream: error = %v, want io.EOF", err)
+			}
+		})
+	}
+}
+
+func TestVarintHeaderSize(t *testing.T) {
+	var buf bytes.Buffer
+	NewWriter(&buf, Options{Header: Varint}).WriteFrame([]byte("hi"))
+	if want := []byte{2, 'h', 'i'}; !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("varint frame = %v, want %v", buf.Bytes(), want)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	small := Options{MaxFrameSize: 4, Checksum: true}
+
+	if err := NewWriter(io.Discard, small).WriteFrame([]byte("too long")); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("WriteFrame(oversized) error = %v, want %v", err, ErrFrameTooLarge)
+	}
+
+	var buf bytes.Buffer
+	NewWriter(&buf, Options{Checksum: true}).WriteFrame([]byte("too long"))
+	if _, err := NewReader(bytes.NewReader(buf.Bytes()), small).ReadFrame(); !errors.Is(err, ErrFrameTooLarge) {
+		t.Errorf("ReadFrame(oversized) error = %v, want %v", err, ErrFrameTooLarge)
+	}
+
+	corrupt := bytes.Clone(buf.Bytes())
+	corrupt[5] ^= 0xff // flip a payload byte
+	if _, err := NewReader(bytes.NewReader(corrupt), Options{Checksum: true}).ReadFrame(); !errors.Is(err, ErrChecksum) {
+		t.Errorf("ReadFrame(corrupt) error = %v, want %v", err, ErrChecksum)
+	}
+
+	for _, cut := range []int{2, 6, len(buf.Bytes()) - 1} {
+		truncated := buf.Bytes()[:cut]
+		if _, err := NewReader(bytes.NewReader(truncated), Options{Checksum: true}).ReadFrame(); err != io.ErrUnexpectedEOF {
+			t.Errorf("ReadFrame(truncated to %d bytes) error = %v, want io.ErrUnexpectedEOF", cut, err)
+		}
+	}
+}
+
+func TestConn(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	defer b.Close()
+	ca, cb := NewConn(a, Options{Header: Varint}), NewConn(b, Options{Header: Varint})
+
+	go ca.WriteFrame([]byte("ping"))
+	got, err := cb.ReadFrame()
+	if err != nil || string(got) != "ping" {
+		t.Errorf("ReadFrame() = %q, %v; want %q, nil", got, err, "ping")
+	}
+}
@@ -0,0 +1,9 @@
+// Command ftpget is an FTP client; see package ftpget.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/ftpget"
+)
+
+func main() { cli.Main(ftpget.Command) }
@@ -0,0 +1,352 @@
+// Package ftpget is the ftpget tool: a passive-mode FTP client for downloads, uploads, and directory listings.
+package ftpget
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+// FTP is a two-connection protocol. the control connection carries text commands ("RETR file.txt") and numeric
+// replies ("150 Opening data connection"), much like SMTP or HTTP/1.x; but the file contents travel over a
+// separate data connection, opened fresh for every transfer. in passive mode (PASV/EPSV) the server picks a port
+// and tells us to connect to it; the transfer ends when the server closes the data connection,
+// and a final reply on the control connection says whether it worked.
+//
+// compare that with HTTP, where a single connection carries both the "command" and the data,
+// and the end of the body is marked by Content-Length or chunking instead of by closing a connection.
+
+// Command is the ftpget tool.
+var Command = &cli.Command{
+	Name:    "ftpget",
+	Summary: "download, upload, or list files over FTP in passive mode",
+	Args:    "ftp://[user[:pass]@]host[:port]/path",
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		var o options
+		fs.StringVar(&o.user, "user", "anonymous", "user name; overridden by credentials in the URL")
+		fs.StringVar(&o.pass, "pass", "anonymous@", "password; overridden by credentials in the URL")
+		fs.BoolVar(&o.list, "list", false, "list the directory at the URL's path instead of downloading")
+		fs.StringVar(&o.stor, "stor", "", "upload this local file to the URL's path instead of downloading")
+		fs.StringVar(&o.output, "o", "", "write the downloaded file here instead of stdout")
+		fs.BoolVar(&o.epsv, "epsv", false, "use EPSV (extended passive mode, needed for IPv6) instead of PASV")
+		fs.DurationVar(&o.timeout, "timeout", 30*time.Second, "timeout for connecting and for each reply")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			return run(ctx, env, o, args)
+		}
+	},
+}
+
+type options struct {
+	user, pass, stor, output string
+	list, epsv               bool
+	timeout                  time.Duration
+}
+
+func run(ctx context.Context, env *cli.Env, o options, args []string) error {
+	if len(args) != 1 {
+		return cli.Usagef("expected one ftp:// URL; got %d arguments", len(args))
+	}
+	u, err := url.Parse(args[0])
+	if err != nil || u.Scheme != "ftp" || u.Hostname() == "" {
+		return cli.Usagef("expected an ftp:// URL; got %q", args[0])
+	}
+	if u.User != nil {
+		o.user = u.User.Username()
+		if p, ok := u.User.Password(); ok {
+			o.pass = p
+		}
+	}
+	port := u.Port()
+	if port == "" {
+		port = "21"
+	}
+
+	c, err := dial(ctx, net.JoinHostPort(u.Hostname(), port), o.timeout)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	c.epsv = o.epsv
+
+	go func() {
+		<-ctx.Done()
+		c.Close()
+	}()
+
+	if err := c.login(o.user, o.pass); err != nil {
+		return err
+	}
+
+	switch {
+	case o.list:
+		err = c.transfer(ctx, "TYPE A", "LIST "+u.Path, func(data net.Conn) error {
+			_, err := io.Copy(env.Stdout, data)
+			return err
+		})
+	case o.stor != "":
+		var f *os.File
+		if f, err = os.Open(o.stor); err != nil {
+			return err
+		}
+		defer f.Close()
+		err = c.transfer(ctx, "TYPE I", "STOR "+u.Path, func(data net.Conn) error {
+			n, err := io.Copy(data, f)
+			slog.InfoContext(ctx, "data", "bytes sent", n)
+			return err
+		})
+	default:
+		w := env.Stdout
+		if o.output != "" {
+			f, err := os.Create(o.output)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		err = c.transfer(ctx, "TYPE I", "RETR "+u.Path, func(data net.Conn) error {
+			n, err := io.Copy(w, data)
+			slog.InfoContext(ctx, "data", "bytes received", n)
+			return err
+		})
+	}
+	if err != nil {
+		return err
+	}
+
+	c.cmd("QUIT") // best effort: we're done either way.
+	return nil
+}
+
+// client is an FTP control connection.
+type client struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	epsv    bool
+}
+
+// dial connects the control channel and reads the server's greeting.
+func dial(ctx context.Context, addr string, timeout time.Duration) (*client, error) {
+	d := net.Dialer{Timeout: timeout}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to %s: %w", addr, err)
+	}
+	slog.InfoContext(ctx, "control", "message", fmt.Sprintf("connected to %s", conn.RemoteAddr()))
+	c := &client{conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+	if _, err := c.expect(220); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *client) Close() error { return c.conn.Close() }
+
+// reply is a parsed server reply: a 3-digit code and its text.
+type reply struct {
+	Code int
+	Text string
+}
+
+// readReply reads one reply. multi-line replies start with "123-" and end with a line starting "123 " (same code, then a space);
+// everything in between is text.
+func (c *client) readReply() (reply, error) {
+	c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	line, err := c.readLine()
+	if err != nil {
+		return reply{}, err
+	}
+	if len(line) < 4 {
+		return reply{}, fmt.Errorf("malformed reply: %q", line)
+	}
+	code, err := strconv.Atoi(line[:3])
+	if err != nil {
+		return reply{}, fmt.Errorf("malformed reply code: %q", line)
+	}
+	text := line[4:]
+	if line[3] == '-' {
+		end := line[:3] + " "
+		for {
+			next, err := c.readLine()
+			if err != nil {
+				return reply{}, err
+			}
+			text += "\n" + strings.TrimPrefix(next, end)
+			if strings.HasPrefix(next, end) {
+				break
+			}
+		}
+	}
+	slog.Info("control", "<", fmt.Sprintf("%d %s", code, text))
+	return reply{Code: code, Text: text}, nil
+}
+
+func (c *client) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("reading reply: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// expect reads a reply and checks its code is one of want.
+func (c *client) expect(want ...int) (reply, error) {
+	r, err := c.readReply()
+	if err != nil {
+		return r, err
+	}
+	for _, code := range want {
+		if r.Code == code {
+			return r, nil
+		}
+	}
+	return r, fmt.Errorf("unexpected reply: %d %s", r.Code, r.Text)
+}
+
+// cmd sends one command line and reads the reply.
+func (c *client) cmd(format string, args ...any) (reply, error) {
+	line := fmt.Sprintf(format, args...)
+	logged := line
+	if strings.HasPrefix(line, "PASS ") {
+		logged = "PASS ****"
+	}
+	slog.Info("control", ">", logged)
+	if _, err := fmt.Fprintf(c.conn, "%s\r\n", line); err != nil {
+		return reply{}, fmt.Errorf("sending %s: %w", strings.Fields(logged)[0], err)
+	}
+	return c.readReply()
+}
+
+func (c *client) login(user, pass string) error {
+	r, err := c.cmd("USER %s", user)
+	if err != nil {
+		return err
+	}
+	switch r.Code {
+	case 230: // logged in without a password
+		return nil
+	case 331: // need password
+	default:
+		return fmt.Errorf("login rejected: %d %s", r.Code, r.Text)
+	}
+	if r, err = c.cmd("PASS %s", pass); err != nil {
+		return err
+	}
+	if r.Code != 230 && r.Code != 202 {
+		return fmt.Errorf("login rejected: %d %s", r.Code, r.Text)
+	}
+	return nil
+}
+
+// transfer sets the transfer type, opens a passive data connection, issues command on the control connection,
+// lets fn read or write the data connection, and then waits for the server to confirm the transfer completed.
+func (c *client) transfer(ctx context.Context, typ, command string, fn func(data net.Conn) error) error {
+	if r, err := c.cmd("%s", typ); err != nil {
+		return err
+	} else if r.Code != 200 {
+		return fmt.Errorf("%s rejected: %d %s", typ, r.Code, r.Text)
+	}
+
+	addr, err := c.passive()
+	if err != nil {
+		return err
+	}
+	d := net.Dialer{Timeout: c.timeout}
+	data, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("opening data connection to %s: %w", addr, err)
+	}
+	defer data.Close()
+	slog.InfoContext(ctx, "data", "message", fmt.Sprintf("data connection open to %s", addr))
+
+	// 150 "about to open data connection" or 125 "data connection already open; transfer starting".
+	if r, err := c.cmd("%s", command); err != nil {
+		return err
+	} else if r.Code != 150 && r.Code != 125 {
+		return fmt.Errorf("%s rejected: %d %s", strings.Fields(command)[0], r.Code, r.Text)
+	}
+
+	if err := fn(data); err != nil {
+		return fmt.Errorf("transferring data: %w", err)
+	}
+	// closing our end is how an upload signals end-of-file; for downloads the server has already closed its end.
+	data.Close()
+
+	// the transfer isn't done until the control connection says so: 226 (closing data connection) or 250 (file action okay).
+	_, err = c.expect(226, 250)
+	return err
+}
+
+// passive asks the server to listen for a data connection and returns the address to dial.
+func (c *client) passive() (string, error) {
+	host, _, _ := net.SplitHostPort(c.conn.RemoteAddr().String())
+	if c.epsv {
+		// 229 Entering Extended Passive Mode (|||6446|): only the port; the host is the one we're already talking to.
+		r, err := c.cmd("EPSV")
+		if err != nil {
+			return "", err
+		}
+		if r.Code != 229 {
+			return "", fmt.Errorf("EPSV rejected: %d %s", r.Code, r.Text)
+		}
+		start, end := strings.Index(r.Text, "(|||"), strings.LastIndex(r.Text, "|)")
+		if start < 0 || end < start+4 {
+			return "", fmt.Errorf("malformed EPSV reply: %q", r.Text)
+		}
+		port := r.Text[start+4 : end]
+		if _, err := strconv.Atoi(port); err != nil {
+			return "", fmt.Errorf("malformed EPSV port: %q", r.Text)
+		}
+		return net.JoinHostPort(host, port), nil
+	}
+
+	// 227 Entering Passive Mode (h1,h2,h3,h4,p1,p2): an IPv4 address and a port as two bytes, high byte first.
+	r, err := c.cmd("PASV")
+	if err != nil {
+		return "", err
+	}
+	if r.Code != 227 {
+		return "", fmt.Errorf("PASV rejected: %d %s", r.Code, r.Text)
+	}
+	start, end := strings.Index(r.Text, "("), strings.Index(r.Text, ")")
+	if start < 0 || end < start {
+		return "", fmt.Errorf("malformed PASV reply: %q", r.Text)
+	}
+	parts := strings.Split(r.Text[start+1:end], ",")
+	if len(parts) != 6 {
+		return "", fmt.Errorf("malformed PASV reply: %q", r.Text)
+	}
+	nums := make([]int, 6)
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil || n < 0 || n > 255 {
+			return "", fmt.Errorf("malformed PASV reply: %q", r.Text)
+		}
+		nums[i] = n
+	}
+	ip := fmt.Sprintf("%d.%d.%d.%d", nums[0], nums[1], nums[2], nums[3])
+	// servers behind NAT often advertise a private address; like most clients, prefer the host we dialed
+	// when the advertised one is unroutable.
+	if parsed := net.ParseIP(ip); parsed == nil || parsed.IsUnspecified() || (parsed.IsPrivate() && !isPrivate(host)) {
+		ip = host
+	}
+	return net.JoinHostPort(ip, strconv.Itoa(nums[4]<<8|nums[5])), nil
+}
+
+func isPrivate(host string) bool {
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsPrivate() || ip.IsLoopback())
+}
@@ -0,0 +1,107 @@
+// gossipdemo launches a cluster of gossip nodes on localhost, prints each node's view of the cluster once a second as
+// the views converge, and optionally stops a node part-way through to show the failure being detected and spread.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ekediala/gossip"
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer done()
+
+	const name = "gossipdemo"
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	log = log.With("app", name)
+	slog.SetDefault(log)
+
+	numNodes := flag.Int("n", 5, "number of nodes to launch")
+	killAfter := flag.Duration("kill-after", 5*time.Second, "stop the last node after this long; 0 disables")
+	duration := flag.Duration("duration", 15*time.Second, "how long to run the demo")
+	verbose := flag.Bool("v", false, "log every membership change on every node")
+	flag.Parse()
+
+	if *numNodes < 2 {
+		slog.ErrorContext(ctx, "main", "error", "need at least 2 nodes")
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	nodeLog := slog.New(slog.NewTextHandler(io.Discard, nil))
+	if *verbose {
+		nodeLog = log
+	}
+
+	nodes := make([]*gossip.Node, *numNodes)
+	stops := make([]context.CancelFunc, *numNodes)
+	for i := range nodes {
+		node, err := gossip.New(gossip.Config{Name: fmt.Sprintf("node-%d", i), Logger: nodeLog})
+		if err != nil {
+			slog.ErrorContext(ctx, "main", "error", err.Error())
+			os.Exit(1)
+		}
+		nodeCtx, stop := context.WithCancel(ctx)
+		go func() {
+			if err := node.Run(nodeCtx); err != nil {
+				slog.ErrorContext(ctx, "main", "node", node.Name(), "error", err.Error())
+			}
+		}()
+		// everyone joins through node-0; gossip takes it from there.
+		if i > 0 {
+			if err := node.Join(nodes[0].Addr()); err != nil {
+				slog.ErrorContext(ctx, "main", "node", node.Name(), "error", err.Error())
+				os.Exit(1)
+			}
+		}
+		nodes[i], stops[i] = node, stop
+		slog.InfoContext(ctx, "main", "message", "node started", "node", node.Name(), "addr", node.Addr())
+	}
+
+	var kill <-chan time.Time
+	if *killAfter > 0 {
+		kill = time.After(*killAfter)
+	}
+	stopped := -1
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-kill:
+			stopped = len(nodes) - 1
+			stops[stopped]()
+			slog.InfoContext(ctx, "main", "message", "stopped node", "node", nodes[stopped].Name())
+		case <-ticker.C:
+			for i, node := range nodes {
+				if i == stopped {
+					continue
+				}
+				fmt.Printf("%-8s sees %s\n", node.Name(), view(node.Members()))
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// view renders members compactly: "node-0:alive node-1:suspect ...".
+func view(members []gossip.Member) string {
+	parts := make([]string, len(members))
+	for i, m := range members {
+		parts[i] = fmt.Sprintf("%s:%s", m.Name, m.State)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,3 @@
+module github.com/ekediala/gossip
+
+go 1.23.1
@@ -0,0 +1,457 @@
+// Package gossip implements cluster membership with a simplified version of the SWIM protocol over UDP.
+//
+// every ProbeInterval each node pings one other member. if no ack comes back within ProbeTimeout it asks
+// IndirectChecks other members to ping the target on its behalf (a "ping-req"), which tells a dead node apart from
+// a lossy link between two live ones. if nobody gets an ack through, the target becomes suspect; if it doesn't refute
+// the suspicion within SuspicionTimeout, it's declared dead.
+//
+// there is no broadcast: membership changes are piggybacked on the pings and acks that are flowing anyway,
+// each update being retransmitted a few (log N) times, which is enough for it to reach everyone with high probability.
+//
+// see "SWIM: Scalable Weakly-consistent Infection-style Process Group Membership Protocol" (Das, Gupta, Motivala, 2002).
+package gossip
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Config configures a Node. Only Name is required.
+type Config struct {
+	Name     string // unique name of this node in the cluster
+	BindAddr string // UDP address to listen on; defaults to "127.0.0.1:0"
+
+	ProbeInterval    time.Duration // how often to probe a member; defaults to 500ms
+	ProbeTimeout     time.Duration // how long to wait for a direct ack; defaults to 200ms
+	IndirectChecks   int           // how many members to ask for an indirect ping; defaults to 3
+	SuspicionTimeout time.Duration // how long a suspect has to refute before it's declared dead; defaults to 2s
+	MaxPiggyback     int           // maximum updates carried by one message; defaults to 8
+
+	Logger *slog.Logger // defaults to slog.Default()
+}
+
+// Node is a member of a gossip cluster.
+type Node struct {
+	cfg  Config
+	conn *net.UDPConn
+	log  *slog.Logger
+
+	mu         sync.Mutex
+	self       Member
+	members    map[string]*Member // everyone but us, keyed by name
+	suspectAt  map[string]time.Time
+	queue      []*broadcast // pending membership updates to piggyback
+	seq        uint64
+	acks       map[uint64]chan struct{} // probes waiting for an ack, by sequence number
+	probeOrder []string                 // round-robin probe list; reshuffled each time it's exhausted
+}
+
+// broadcast is a membership update waiting to be piggybacked, with the number of times it's been sent so far.
+type broadcast struct {
+	m     Member
+	sends int
+}
+
+// message types
+const (
+	msgPing    = "ping"
+	msgPingReq = "ping-req"
+	msgAck     = "ack"
+	msgJoin    = "join"
+)
+
+// message is the single datagram format used by the protocol; it's JSON because it's easy to read in a packet capture.
+type message struct {
+	Type    string   `json:"type"`
+	Seq     uint64   `json:"seq"`
+	From    Member   `json:"from"`
+	Target  string   `json:"target,omitempty"` // address to ping, for ping-req
+	Updates []Member `json:"updates,omitempty"`
+}
+
+// New creates a node and binds its UDP socket. Call Run to start participating and Join to find the cluster.
+func New(cfg Config) (*Node, error) {
+	if cfg.Name == "" {
+		return nil, errors.New("gossip: missing node name")
+	}
+	if cfg.BindAddr == "" {
+		cfg.BindAddr = "127.0.0.1:0"
+	}
+	if cfg.ProbeInterval == 0 {
+		cfg.ProbeInterval = 500 * time.Millisecond
+	}
+	if cfg.ProbeTimeout == 0 {
+		cfg.ProbeTimeout = 200 * time.Millisecond
+	}
+	if cfg.IndirectChecks == 0 {
+		cfg.IndirectChecks = 3
+	}
+	if cfg.SuspicionTimeout == 0 {
+		cfg.SuspicionTimeout = 2 * time.Second
+	}
+	if cfg.MaxPiggyback == 0 {
+		cfg.MaxPiggyback = 8
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", cfg.BindAddr)
+	if err != nil {
+		return nil, fmt.Errorf("gossip: resolving %s: %w", cfg.BindAddr, err)
+	}
+	conn, err := net.ListenUDP("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("gossip: listening on %s: %w", cfg.BindAddr, err)
+	}
+
+	return &Node{
+		cfg:       cfg,
+		conn:      conn,
+		log:       cfg.Logger.With("node", cfg.Name),
+		self:      Member{Name: cfg.Name, Addr: conn.LocalAddr().String(), State: Alive},
+		members:   make(map[string]*Member),
+		suspectAt: make(map[string]time.Time),
+		acks:      make(map[uint64]chan struct{}),
+	}, nil
+}
+
+// Name returns the node's name.
+func (n *Node) Name() string { return n.cfg.Name }
+
+// Addr returns the UDP address the node listens on; give it to other nodes as a seed.
+func (n *Node) Addr() string { return n.self.Addr }
+
+// Members returns this node's current view of the cluster, including itself, sorted by name.
+func (n *Node) Members() []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	members := []Member{n.self}
+	for _, m := range n.members {
+		members = append(members, *m)
+	}
+	slices.SortFunc(members, func(a, b Member) int {
+		switch {
+		case a.Name < b.Name:
+			return -1
+		case a.Name > b.Name:
+			return 1
+		}
+		return 0
+	})
+	return members
+}
+
+// Join announces this node to the seed addresses. The seeds answer with their view of the cluster and spread the news.
+// it returns an error only if no seed could be sent to.
+func (n *Node) Join(seeds ...string) error {
+	var errs []error
+	for _, seed := range seeds {
+		if err := n.send(seed, message{Type: msgJoin}); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) == len(seeds) {
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+// Run receives messages and probes members until ctx is done, then closes the node's socket.
+func (n *Node) Run(ctx context.Context) error {
+	go func() {
+		<-ctx.Done()
+		n.conn.Close()
+	}()
+	go n.probeLoop(ctx)
+
+	buf := make([]byte, 64<<10)
+	for {
+		size, from, err := n.conn.ReadFromUDP(buf)
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return fmt.Errorf("gossip: reading: %w", err)
+		}
+		var msg message
+		if err := json.Unmarshal(buf[:size], &msg); err != nil {
+			n.log.WarnContext(ctx, "gossip", "from", from.String(), "error", fmt.Sprintf("malformed message: %v", err))
+			continue
+		}
+		n.handle(ctx, msg)
+	}
+}
+
+// handle processes one incoming message.
+func (n *Node) handle(ctx context.Context, msg message) {
+	// the sender is evidently alive, and whatever it piggybacked is news.
+	n.apply(ctx, msg.From)
+	for _, u := range msg.Updates {
+		n.apply(ctx, u)
+	}
+
+	switch msg.Type {
+	case msgPing:
+		n.send(msg.From.Addr, message{Type: msgAck, Seq: msg.Seq})
+	case msgJoin:
+		// answer with everything we know so the newcomer has a full view straight away.
+		n.mu.Lock()
+		updates := []Member{n.self}
+		for _, m := range n.members {
+			updates = append(updates, *m)
+		}
+		n.mu.Unlock()
+		n.sendRaw(msg.From.Addr, message{Type: msgAck, Seq: msg.Seq, From: n.selfMember(), Updates: updates})
+	case msgPingReq:
+		// ping the target on the requester's behalf and relay the ack back with the requester's sequence number.
+		origin, seq := msg.From.Addr, msg.Seq
+		go func() {
+			if n.ping(ctx, msg.Target, n.cfg.ProbeTimeout) {
+				n.send(origin, message{Type: msgAck, Seq: seq})
+			}
+		}()
+	case msgAck:
+		n.mu.Lock()
+		ch, ok := n.acks[msg.Seq]
+		delete(n.acks, msg.Seq)
+		n.mu.Unlock()
+		if ok {
+			close(ch)
+		}
+	}
+}
+
+// apply merges a membership update into our view, queueing it for dissemination if it changed anything.
+func (n *Node) apply(ctx context.Context, u Member) {
+	if u.Name == "" {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if u.Name == n.self.Name {
+		// somebody thinks we're suspect or dead: refute by outbidding their incarnation.
+		if u.State != Alive && u.Incarnation >= n.self.Incarnation {
+			n.self.Incarnation = u.Incarnation + 1
+			n.enqueue(n.self)
+			n.log.InfoContext(ctx, "gossip", "message", "refuting suspicion", "incarnation", n.self.Incarnation)
+		}
+		return
+	}
+
+	cur, known := n.members[u.Name]
+	switch {
+	case !known && u.State == Dead:
+		return // no point learning about a member only to bury it.
+	case !known:
+		m := u
+		n.members[u.Name] = &m
+	case supersedes(u, *cur):
+		*cur = u
+	default:
+		return
+	}
+
+	if u.State == Suspect {
+		n.suspectAt[u.Name] = time.Now()
+	} else {
+		delete(n.suspectAt, u.Name)
+	}
+	n.enqueue(u)
+	n.log.InfoContext(ctx, "gossip", "member", u.Name, "state", u.State.String(), "incarnation", u.Incarnation)
+}
+
+// enqueue adds an update to the dissemination queue, replacing any older update about the same member. n.mu must be held.
+func (n *Node) enqueue(m Member) {
+	n.queue = slices.DeleteFunc(n.queue, func(b *broadcast) bool { return b.m.Name == m.Name })
+	n.queue = append(n.queue, &broadcast{m: m})
+}
+
+// piggyback picks the least-sent queued updates for an outgoing message and retires those sent often enough. n.mu must be held.
+func (n *Node) piggyback() []Member {
+	// each update is sent about 3*log(N) times: enough to infect the whole cluster with high probability.
+	limit := 3 * int(math.Ceil(math.Log10(float64(len(n.members)+2))))
+	slices.SortStableFunc(n.queue, func(a, b *broadcast) int { return a.sends - b.sends })
+
+	var updates []Member
+	for _, b := range n.queue[:min(len(n.queue), n.cfg.MaxPiggyback)] {
+		updates = append(updates, b.m)
+		b.sends++
+	}
+	n.queue = slices.DeleteFunc(n.queue, func(b *broadcast) bool { return b.sends >= limit })
+	return updates
+}
+
+func (n *Node) selfMember() Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.self
+}
+
+// send fills in our identity and piggybacked updates, then sends msg to addr.
+func (n *Node) send(addr string, msg message) error {
+	n.mu.Lock()
+	msg.From = n.self
+	msg.Updates = append(msg.Updates, n.piggyback()...)
+	n.mu.Unlock()
+	return n.sendRaw(addr, msg)
+}
+
+func (n *Node) sendRaw(addr string, msg message) error {
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	to, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return fmt.Errorf("gossip: resolving %s: %w", addr, err)
+	}
+	if _, err := n.conn.WriteToUDP(b, to); err != nil {
+		return fmt.Errorf("gossip: sending to %s: %w", addr, err)
+	}
+	return nil
+}
+
+// expectAck registers a new sequence number and returns a channel that's closed when its ack arrives.
+func (n *Node) expectAck() (uint64, chan struct{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.seq++
+	ch := make(chan struct{})
+	n.acks[n.seq] = ch
+	return n.seq, ch
+}
+
+func (n *Node) forgetAck(seq uint64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.acks, seq)
+}
+
+// ping sends a direct ping to addr and reports whether it was acked within timeout.
+func (n *Node) ping(ctx context.Context, addr string, timeout time.Duration) bool {
+	seq, acked := n.expectAck()
+	defer n.forgetAck(seq)
+	if err := n.send(addr, message{Type: msgPing, Seq: seq}); err != nil {
+		return false
+	}
+	return wait(ctx, acked, timeout)
+}
+
+func wait(ctx context.Context, ch <-chan struct{}, timeout time.Duration) bool {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+	select {
+	case <-ch:
+		return true
+	case <-t.C:
+	case <-ctx.Done():
+	}
+	return false
+}
+
+// probeLoop runs one protocol period every ProbeInterval.
+func (n *Node) probeLoop(ctx context.Context) {
+	ticker := time.NewTicker(n.cfg.ProbeInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		n.reapSuspects(ctx)
+		if target, ok := n.nextTarget(); ok {
+			n.probe(ctx, target)
+		}
+	}
+}
+
+// nextTarget picks the next live member in a shuffled round-robin order, which bounds how long a failure can go unnoticed.
+func (n *Node) nextTarget() (Member, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for {
+		if len(n.probeOrder) == 0 {
+			for name, m := range n.members {
+				if m.State != Dead {
+					n.probeOrder = append(n.probeOrder, name)
+				}
+			}
+			if len(n.probeOrder) == 0 {
+				return Member{}, false
+			}
+			rand.Shuffle(len(n.probeOrder), func(i, j int) {
+				n.probeOrder[i], n.probeOrder[j] = n.probeOrder[j], n.probeOrder[i]
+			})
+		}
+		name := n.probeOrder[0]
+		n.probeOrder = n.probeOrder[1:]
+		if m, ok := n.members[name]; ok && m.State != Dead {
+			return *m, true
+		}
+	}
+}
+
+// probe checks target directly, then indirectly, and marks it suspect if neither gets an ack.
+func (n *Node) probe(ctx context.Context, target Member) {
+	if n.ping(ctx, target.Addr, n.cfg.ProbeTimeout) {
+		return
+	}
+
+	// no direct ack: ask some other members to try. any ack relayed back under our seq counts.
+	seq, acked := n.expectAck()
+	defer n.forgetAck(seq)
+	for _, helper := range n.randomMembers(n.cfg.IndirectChecks, target.Name) {
+		n.send(helper.Addr, message{Type: msgPingReq, Seq: seq, Target: target.Addr})
+	}
+	if wait(ctx, acked, n.cfg.ProbeInterval-n.cfg.ProbeTimeout) {
+		return
+	}
+
+	n.log.InfoContext(ctx, "gossip", "message", "probe failed", "member", target.Name)
+	n.apply(ctx, Member{Name: target.Name, Addr: target.Addr, State: Suspect, Incarnation: target.Incarnation})
+}
+
+// randomMembers returns up to k random live members, excluding the named one.
+func (n *Node) randomMembers(k int, exclude string) []Member {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var candidates []Member
+	for name, m := range n.members {
+		if name != exclude && m.State == Alive {
+			candidates = append(candidates, *m)
+		}
+	}
+	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+	return candidates[:min(k, len(candidates))]
+}
+
+// reapSuspects declares dead every member that has been suspect for longer than SuspicionTimeout.
+func (n *Node) reapSuspects(ctx context.Context) {
+	n.mu.Lock()
+	var expired []Member
+	for name, since := range n.suspectAt {
+		if time.Since(since) > n.cfg.SuspicionTimeout {
+			if m, ok := n.members[name]; ok && m.State == Suspect {
+				expired = append(expired, *m)
+			}
+		}
+	}
+	n.mu.Unlock()
+
+	for _, m := range expired {
+		m.State = Dead
+		n.apply(ctx, m)
+	}
+}
@@ -0,0 +1,109 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestSupersedes(t *testing.T) {
+	for _, tt := range []struct {
+		update, cur Member
+		want        bool
+	}{
+		{Member{State: Alive, Incarnation: 1}, Member{State: Alive, Incarnation: 0}, true},
+		{Member{State: Alive, Incarnation: 1}, Member{State: Alive, Incarnation: 1}, false},
+		{Member{State: Alive, Incarnation: 2}, Member{State: Suspect, Incarnation: 1}, true},
+		{Member{State: Alive, Incarnation: 1}, Member{State: Suspect, Incarnation: 1}, false},
+		{Member{State: Suspect, Incarnation: 1}, Member{State: Alive, Incarnation: 1}, true},
+		{Member{State: Suspect, Incarnation: 0}, Member{State: Alive, Incarnation: 1}, false},
+		{Member{State: Suspect, Incarnation: 1}, Member{State: Suspect, Incarnation: 1}, false},
+		{Member{State: Dead, Incarnation: 1}, Member{State: Suspect, Incarnation: 1}, true},
+		{Member{State: Dead, Incarnation: 0}, Member{State: Alive, Incarnation: 1}, false},
+		{Member{State: Alive, Incarnation: 2}, Member{State: Dead, Incarnation: 1}, true},
+	} {
+		if got := supersedes(tt.update, tt.cur); got != tt.want {
+			t.Errorf("supersedes(%+v, %+v) = %v, want %v", tt.update, tt.cur, got, tt.want)
+		}
+	}
+}
+
+// cluster starts n fast-probing nodes joined through the first one, and a cancel func per node.
+func cluster(t *testing.T, n int) ([]*Node, []context.CancelFunc) {
+	t.Helper()
+	var nodes []*Node
+	var stops []context.CancelFunc
+	for i := range n {
+		node, err := New(Config{
+			Name:             fmt.Sprintf("node-%d", i),
+			ProbeInterval:    50 * time.Millisecond,
+			ProbeTimeout:     20 * time.Millisecond,
+			SuspicionTimeout: 200 * time.Millisecond,
+			Logger:           slog.New(slog.NewTextHandler(io.Discard, nil)),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		t.Cleanup(cancel)
+		go node.Run(ctx)
+		if i > 0 {
+			if err := node.Join(nodes[0].Addr()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		nodes = append(nodes, node)
+		stops = append(stops, cancel)
+	}
+	return nodes, stops
+}
+
+// eventually polls cond until it's true or the timeout passes.
+func eventually(t *testing.T, timeout time.Duration, cond func() bool, msg string) {
+	t.Helper()
+	for deadline := time.Now().Add(timeout); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if cond() {
+			return
+		}
+	}
+	t.Fatal(msg)
+}
+
+func count(members []Member, state State) int {
+	n := 0
+	for _, m := range members {
+		if m.State == state {
+			n++
+		}
+	}
+	return n
+}
+
+func TestConvergence(t *testing.T) {
+	const size = 4
+	nodes, stops := cluster(t, size)
+
+	eventually(t, 5*time.Second, func() bool {
+		for _, n := range nodes {
+			if count(n.Members(), Alive) != size {
+				return false
+			}
+		}
+		return true
+	}, "cluster never converged on every node being alive")
+
+	// stop the last node; the others should notice, suspect it, and finally declare it dead.
+	stops[size-1]()
+	eventually(t, 5*time.Second, func() bool {
+		for _, n := range nodes[:size-1] {
+			members := n.Members()
+			if count(members, Alive) != size-1 || count(members, Dead) != 1 {
+				return false
+			}
+		}
+		return true
+	}, "surviving nodes never agreed the stopped node is dead")
+}
@@ -0,0 +1,59 @@
+package gossip
+
+import "fmt"
+
+// State is what a node believes about another member.
+type State int
+
+const (
+	// Alive members answer pings.
+	Alive State = iota
+	// Suspect members missed a direct and an indirect ping; they have SuspicionTimeout to refute it before they're declared dead.
+	Suspect
+	// Dead members are considered gone until they rejoin with a higher incarnation.
+	Dead
+)
+
+func (s State) String() string {
+	switch s {
+	case Alive:
+		return "alive"
+	case Suspect:
+		return "suspect"
+	case Dead:
+		return "dead"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// Member is one node's view of a cluster member.
+type Member struct {
+	Name string `json:"name"`
+	Addr string `json:"addr"`
+	// State is only ever changed by an update carrying at least the current Incarnation.
+	State State `json:"state"`
+	// Incarnation is bumped by the member itself whenever it refutes a suspicion about it,
+	// which lets its "I'm alive" win over older "it's suspect" rumours.
+	Incarnation uint64 `json:"incarnation"`
+}
+
+// supersedes reports whether the update u should replace what we currently know, cur.
+// these are the SWIM precedence rules:
+//   - alive wins only with a strictly newer incarnation (that's how a member refutes suspicion, or rejoins after being declared dead);
+//   - suspect wins over alive at the same or a newer incarnation, and over suspect only when newer;
+//   - dead wins over everything at the same or a newer incarnation.
+func supersedes(u, cur Member) bool {
+	switch u.State {
+	case Alive:
+		return u.Incarnation > cur.Incarnation
+	case Suspect:
+		if cur.State == Alive {
+			return u.Incarnation >= cur.Incarnation
+		}
+		return cur.State == Suspect && u.Incarnation > cur.Incarnation
+	case Dead:
+		return cur.State != Dead && u.Incarnation >= cur.Incarnation
+	}
+	return false
+}
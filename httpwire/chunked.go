@@ -0,0 +1,236 @@
+package httpwire
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/* design note: a chunked body (RFC 9112, section 7.1) is how a message says where its body ends when the sender
+   doesn't know how long it'll be until it's done, so can't send a Content-Length up front:
+
+	<size in hex>[;extension]\r\n
+	<size bytes of data>\r\n
+	...
+	0\r\n
+	[trailer fields]\r\n
+	\r\n
+
+   a chunk of size zero is the last, so there's no way to send an empty chunk: ChunkedWriter skips empty writes.
+   chunk extensions are name=value pairs after the size, meant for things like per-chunk signatures; nothing common
+   uses them, and a reader has to skip any it doesn't understand, so ChunkedReader keeps the last chunk's for anyone
+   who's interested and otherwise ignores them. trailer fields are headers that come after the body, for things the
+   sender only knows once it's sent it, like a checksum.
+
+   both sides stream: ChunkedWriter writes each Write as a chunk as it comes, and ChunkedReader hands out the data a
+   chunk at a time, so a big body never needs to be held in memory.
+
+   the size lines and the trailer section are lines like the head's, and just as unbounded if they're read naively:
+   a peer could send a gigabyte of chunk extension, or a million trailers, and get round the limits on the head by
+   putting it after the body instead. so ChunkedReader keeps to ParseOptions' limits too: MaxLineLength for every
+   line, and MaxHeaderBytes and MaxHeaderCount for the trailer section, as if it were a second head, and goes over
+   with the same *LimitError.
+*/
+
+// lineReader is what ChunkedReader reads from: a *bufio.Reader, or anything else that can read a line at a time,
+// a buffer's worth at a time, without reading past it.
+type lineReader interface {
+	io.Reader
+	ReadSlice(delim byte) ([]byte, error)
+}
+
+// ChunkedReader reads the data out of a chunked body, stopping after the last chunk and the trailer section.
+type ChunkedReader struct {
+	o        ParseOptions
+	r        lineReader
+	size     int64 // of the current chunk
+	left     int64 // bytes of it not yet read
+	started  bool  // the first chunk's size line has been read
+	ext      string
+	trailers Headers
+	bytes    int   // of the trailer section so far
+	err      error // sticky; io.EOF after the last chunk and the trailer section
+}
+
+// NewChunkedReader returns a reader for the chunked body at the start of r, within the default ParseOptions' limits.
+// if r can't read a line at a time, like a *bufio.Reader can, it's wrapped in one, which may read past the end of
+// the body.
+func NewChunkedReader(r io.Reader) *ChunkedReader {
+	return ParseOptions{}.NewChunkedReader(r)
+}
+
+// NewChunkedReader returns a reader for the chunked body at the start of r, within o's limits.
+func (o ParseOptions) NewChunkedReader(r io.Reader) *ChunkedReader {
+	lr, ok := r.(lineReader)
+	if !ok {
+		lr = bufio.NewReader(r)
+	}
+	return &ChunkedReader{o: o, r: lr}
+}
+
+// Read reads the body's data, without the framing. it returns io.EOF once the trailer section has been read.
+func (c *ChunkedReader) Read(p []byte) (int, error) {
+	for c.err == nil && c.left == 0 {
+		c.err = c.next()
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	if int64(len(p)) > c.left {
+		p = p[:c.left]
+	}
+	n, err := c.r.Read(p)
+	c.left -= int64(n)
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF // there's always at least a CRLF and the last chunk to come.
+	}
+	c.err = err
+	return n, err
+}
+
+// Extensions returns the extensions on the size line of the chunk being read, like "sig=abc;final", or "".
+func (c *ChunkedReader) Extensions() string { return c.ext }
+
+// Trailers returns the trailer fields, once Read has returned io.EOF.
+func (c *ChunkedReader) Trailers() Headers { return c.trailers }
+
+// next reads up to the next chunk's data: the CRLF that ends the chunk before it, if there was one, and the size
+// line. the last chunk has no data; for that it reads the trailer section too, and returns io.EOF.
+func (c *ChunkedReader) next() error {
+	if c.started {
+		if crlf, err := c.readLine(); err != nil {
+			return err
+		} else if crlf != "" {
+			return fmt.Errorf("malformed chunk: %d bytes of data followed by %q instead of CRLF", c.size, crlf)
+		}
+	}
+	c.started = true
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	sizeField, ext, _ := strings.Cut(line, ";")
+	sizeField = strings.TrimSpace(sizeField)
+	size, err := strconv.ParseInt(sizeField, 16, 64)
+	// ParseInt takes a sign, and a 0x, and _ between digits; a chunk size is hex digits, and nothing else.
+	if err != nil || strings.ContainsAny(sizeField, "+-xX_") {
+		return fmt.Errorf("malformed chunk size line %q", line)
+	}
+	c.ext = strings.TrimSpace(ext)
+	if size > 0 {
+		c.size, c.left = size, size
+		return nil
+	}
+	for { // the trailer section ends with an empty line.
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if line == "" {
+			return io.EOF
+		}
+		if c.bytes += len(line) + 2; c.bytes > c.o.maxHeaderBytes() {
+			return &LimitError{Limit: "MaxHeaderBytes", Max: c.o.maxHeaderBytes()}
+		}
+		if len(c.trailers) >= c.o.maxHeaderCount() {
+			return &LimitError{Limit: "MaxHeaderCount", Max: c.o.maxHeaderCount()}
+		}
+		k, v, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(k) == "" {
+			return fmt.Errorf("malformed trailer field %q", line)
+		}
+		c.trailers = append(c.trailers, Header{AsTitle(strings.TrimSpace(k)), strings.TrimSpace(v)})
+	}
+}
+
+// ChunkedWriter writes a chunked body: each Write is a chunk, and Close writes the last chunk and the trailers.
+// it doesn't close the writer underneath.
+type ChunkedWriter struct {
+	// Trailers are written after the last chunk. they should be declared up front, in the message's Trailer header.
+	Trailers Headers
+
+	w      io.Writer
+	closed bool
+}
+
+// NewChunkedWriter returns a writer that writes a chunked body to w.
+func NewChunkedWriter(w io.Writer) *ChunkedWriter {
+	return &ChunkedWriter{w: w}
+}
+
+// Write writes p as one chunk. it returns the number of bytes of p written, not counting the framing.
+func (c *ChunkedWriter) Write(p []byte) (int, error) {
+	if c.closed {
+		return 0, errors.New("httpwire: write after ChunkedWriter.Close")
+	}
+	if len(p) == 0 {
+		return 0, nil // a zero-size chunk would end the body early.
+	}
+	if _, err := fmt.Fprintf(c.w, "%x\r\n", len(p)); err != nil {
+		return 0, err
+	}
+	n, err := c.w.Write(p)
+	if err != nil {
+		return n, err
+	}
+	_, err = io.WriteString(c.w, "\r\n")
+	return n, err
+}
+
+// Close ends the body with the last, empty chunk and the trailer section. closing twice does nothing.
+func (c *ChunkedWriter) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	var end strings.Builder
+	end.WriteString("0\r\n")
+	for _, h := range c.Trailers {
+		end.WriteString(h.Key + ": " + h.Value + "\r\n")
+	}
+	end.WriteString("\r\n")
+	_, err := io.WriteString(c.w, end.String())
+	return err
+}
+
+// readChunked reads a whole chunked body from r, within o's limits, and returns it reassembled, and its trailers.
+func readChunked(o ParseOptions, r *bufio.Reader) (body []byte, trailers Headers, err error) {
+	c := o.NewChunkedReader(r)
+	body, err = io.ReadAll(c)
+	return body, c.Trailers(), err
+}
+
+// readLine reads one line, no longer than MaxLineLength, and strips its line ending. running out of input mid-line
+// is io.ErrUnexpectedEOF: every line we read this way is followed by more of the message.
+func (c *ChunkedReader) readLine() (string, error) {
+	max := c.o.maxLineLength()
+	var line []byte
+	for {
+		frag, err := c.r.ReadSlice('\n')
+		line = append(line, frag...)
+		if len(line) > max+2 { // stop reading a line that's too long: it could go on forever.
+			return "", &LimitError{Limit: "MaxLineLength", Max: max}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil {
+			return string(line), unexpected(err)
+		}
+		if s := strings.TrimRight(string(line), "\r\n"); len(s) <= max {
+			return s, nil
+		}
+		return "", &LimitError{Limit: "MaxLineLength", Max: max}
+	}
+}
+
+// unexpected turns io.EOF into io.ErrUnexpectedEOF, for when the message isn't over yet.
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
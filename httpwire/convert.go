@@ -0,0 +1,175 @@
+package httpwire
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/* design note: Request and Response are the wire as written: headers in order, duplicates kept, the body a string.
+   net/http's types are the wire as understood: headers in a map, Host and Content-Length pulled out into fields,
+   the body a stream. the converters below go between the two, so a test can hand a Request to an http.Handler, or
+   send it to an httptest server and check the *http.Response that comes back with code written for Response, and
+   code can move from one set of types to the other a piece at a time.
+
+   something is lost each way. http.Header is a map, so headers coming from net/http are sorted by name (a name's
+   values keep their order). going to net/http, Host becomes the request's Host field and Content-Length its
+   ContentLength, since net/http writes those itself, from the fields, and a BodyReader is streamed. reading a body from net/http reads all of it,
+   and closes it.
+*/
+
+// ToStdRequest converts r to an *http.Request, a client's, for the Host header's server over plain HTTP, so it can
+// be sent with an http.Client or handed to an http.Handler.
+func ToStdRequest(r *Request) (*http.Request, error) {
+	host := r.Headers.Get("Host")
+	if host == "" {
+		return nil, errors.New("request has no Host header")
+	}
+	u, err := url.ParseRequestURI("http://" + host + r.Path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid host or path: %w", err)
+	}
+	req, err := http.NewRequest(r.Method, u.String(), r.body())
+	if err != nil {
+		return nil, err
+	}
+	if r.BodyReader != nil {
+		req.ContentLength = streamLength(r.ContentLength)
+	}
+	req.Header = toHTTPHeader(r.Headers)
+	req.Header.Del("Host")
+	req.Header.Del("Content-Length")
+	req.Host = host
+	if len(r.Trailers) > 0 {
+		req.Trailer = toHTTPHeader(r.Trailers)
+	}
+	return req, nil
+}
+
+// FromStdRequest converts req, a client's or a server's, to a Request, reading and closing its body, and then
+// taking its trailers. the Host header comes first, then the rest, sorted by name.
+func FromStdRequest(req *http.Request) (*Request, error) {
+	body, err := readAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading request body: %w", err)
+	}
+	host := req.Host
+	if host == "" && req.URL != nil {
+		host = req.URL.Host
+	}
+	if host == "" {
+		return nil, errors.New("request has no host")
+	}
+	path := "/"
+	if req.URL != nil {
+		path = req.URL.RequestURI()
+	}
+	headers := append(Headers{{"Host", host}}, fromHTTPHeader(req.Header)...)
+	if body != "" && headers.Get("Content-Length") == "" && headers.Get("Transfer-Encoding") == "" {
+		headers = append(headers, Header{"Content-Length", strconv.Itoa(len(body))})
+	}
+	return &Request{Method: req.Method, Path: path, Headers: headers, Body: body, Trailers: fromHTTPHeader(req.Trailer)}, nil
+}
+
+// ToStdResponse converts resp to an *http.Response, as if it had come over HTTP/1.1, or its Proto.
+func ToStdResponse(resp *Response) (*http.Response, error) {
+	if resp.StatusCode < 100 || resp.StatusCode > 599 {
+		return nil, fmt.Errorf("invalid status code %d", resp.StatusCode)
+	}
+	proto := cmp.Or(resp.Proto, "HTTP/1.1")
+	major, minor, ok := http.ParseHTTPVersion(proto)
+	if !ok {
+		return nil, fmt.Errorf("invalid protocol version %q", proto)
+	}
+	out := &http.Response{
+		Status:        fmt.Sprintf("%d %s", resp.StatusCode, cmp.Or(resp.Reason, http.StatusText(resp.StatusCode))),
+		StatusCode:    resp.StatusCode,
+		Proto:         proto,
+		ProtoMajor:    major,
+		ProtoMinor:    minor,
+		Header:        toHTTPHeader(resp.Headers),
+		Body:          io.NopCloser(resp.body()),
+		ContentLength: int64(len(resp.Body)),
+	}
+	if resp.BodyReader != nil {
+		out.ContentLength = streamLength(resp.ContentLength)
+	}
+	if resp.Headers.Chunked() {
+		out.Header.Del("Transfer-Encoding")
+		out.TransferEncoding = []string{"chunked"}
+		out.ContentLength = -1
+		if len(resp.Trailers) > 0 {
+			out.Trailer = toHTTPHeader(resp.Trailers)
+		}
+	}
+	return out, nil
+}
+
+// FromStdResponse converts resp to a Response, reading and closing its body, and then taking its trailers. the
+// headers are sorted by name.
+func FromStdResponse(resp *http.Response) (*Response, error) {
+	body, err := readAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	_, reason, _ := strings.Cut(resp.Status, " ") // Status is "200 OK": the code again, then the reason.
+	out := &Response{
+		Proto: resp.Proto, StatusCode: resp.StatusCode, Reason: reason, Headers: fromHTTPHeader(resp.Header), Body: body, Trailers: fromHTTPHeader(resp.Trailer),
+	}
+	// net/http takes Transfer-Encoding out of the headers; without it, WriteTo wouldn't know to write chunks.
+	if len(resp.TransferEncoding) > 0 && out.Headers.Get("Transfer-Encoding") == "" {
+		out.Headers = append(out.Headers, Header{"Transfer-Encoding", strings.Join(resp.TransferEncoding, ", ")})
+	}
+	return out, nil
+}
+
+// streamLength is a BodyReader's ContentLength as net/http has it, where -1 is unknown.
+func streamLength(n int64) int64 {
+	if n <= 0 {
+		return -1
+	}
+	return n
+}
+
+// toHTTPHeader converts headers to an http.Header.
+func toHTTPHeader(headers Headers) http.Header {
+	h := make(http.Header, len(headers))
+	for _, kv := range headers {
+		h.Add(kv.Key, kv.Value)
+	}
+	return h
+}
+
+// fromHTTPHeader converts h to headers, sorted by name, each name's values in order.
+func fromHTTPHeader(h http.Header) Headers {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var headers Headers
+	for _, k := range keys {
+		for _, v := range h[k] {
+			headers = append(headers, Header{AsTitle(k), v})
+		}
+	}
+	return headers
+}
+
+// readAll reads and closes body, which may be nil.
+func readAll(body io.ReadCloser) (string, error) {
+	if body == nil || body == http.NoBody {
+		return "", nil
+	}
+	defer body.Close()
+	var b bytes.Buffer
+	_, err := b.ReadFrom(body)
+	return b.String(), err
+}
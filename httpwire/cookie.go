@@ -0,0 +1,222 @@
+package httpwire
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* design note: cookies come in two headers that look alike and aren't (RFC 6265). a server sets one with a
+   Set-Cookie header per cookie: its name and value, then attributes saying where and for how long it applies:
+
+	Set-Cookie: session=abc123; Path=/; Max-Age=3600; Secure; HttpOnly; SameSite=Lax
+
+   a client sends back the ones that apply, all in one Cookie header, as bare name=value pairs separated by "; ":
+
+	Cookie: session=abc123; theme=dark
+
+   so there are two types: SetCookie, with the attributes, and Cookie, without. parsing is lenient, as RFC 6265's
+   algorithm is, since browsers are and servers rely on it: an attribute that isn't known is ignored, and so is one
+   whose value doesn't parse, like an Expires in a date format nobody uses. only a cookie with no name, or no = in
+   its name=value pair, is an error. a value may be quoted, and the quotes aren't part of it.
+
+   Max-Age and Expires both say when a cookie ends; Max-Age wins when both are there. Max-Age is an int here, as in
+   net/http: 0 means there's no Max-Age, and a negative one is written as Max-Age=0, which deletes the cookie.
+*/
+
+// Cookie is a cookie as a client sends it back, in a Cookie header.
+type Cookie struct {
+	Name, Value string
+}
+
+// String returns c as it's written in a Cookie header: name=value.
+func (c Cookie) String() string {
+	return c.Name + "=" + quoteCookieValue(c.Value)
+}
+
+// SameSite is a cookie's SameSite attribute: whether it's sent with requests from other sites.
+type SameSite string
+
+const (
+	SameSiteStrict SameSite = "Strict"
+	SameSiteLax    SameSite = "Lax"
+	SameSiteNone   SameSite = "None"
+)
+
+// SetCookie is a cookie as a server sets it, in a Set-Cookie header, with its attributes.
+type SetCookie struct {
+	Name, Value string
+	Path        string
+	Domain      string
+	Expires     time.Time // zero if there's no Expires
+	MaxAge      int       // in seconds; 0 if there's no Max-Age, and negative for Max-Age=0
+	Secure      bool
+	HttpOnly    bool
+	SameSite    SameSite // "" if there's no SameSite
+}
+
+// Cookie returns c as a client sends it back.
+func (c SetCookie) Cookie() Cookie {
+	return Cookie{Name: c.Name, Value: c.Value}
+}
+
+// ExpiresAt returns when c ends, as the client receiving it at now would work it out: Max-Age after now, or else
+// Expires. it's the zero time for a session cookie, which lasts until the client closes.
+func (c SetCookie) ExpiresAt(now time.Time) time.Time {
+	switch {
+	case c.MaxAge < 0:
+		return time.Unix(0, 0)
+	case c.MaxAge > 0:
+		return now.Add(time.Duration(c.MaxAge) * time.Second)
+	}
+	return c.Expires
+}
+
+// String returns c as it's written in a Set-Cookie header.
+func (c SetCookie) String() string {
+	var b strings.Builder
+	b.WriteString(c.Cookie().String())
+	if c.Path != "" {
+		b.WriteString("; Path=" + c.Path)
+	}
+	if c.Domain != "" {
+		b.WriteString("; Domain=" + c.Domain)
+	}
+	if !c.Expires.IsZero() {
+		b.WriteString("; Expires=" + FormatDate(c.Expires))
+	}
+	switch {
+	case c.MaxAge > 0:
+		b.WriteString("; Max-Age=" + strconv.Itoa(c.MaxAge))
+	case c.MaxAge < 0:
+		b.WriteString("; Max-Age=0")
+	}
+	if c.Secure {
+		b.WriteString("; Secure")
+	}
+	if c.HttpOnly {
+		b.WriteString("; HttpOnly")
+	}
+	if c.SameSite != "" {
+		b.WriteString("; SameSite=" + string(c.SameSite))
+	}
+	return b.String()
+}
+
+// ParseSetCookie parses the value of a Set-Cookie header.
+func ParseSetCookie(v string) (SetCookie, error) {
+	parts := strings.Split(v, ";")
+	name, value, err := parseCookiePair(parts[0])
+	if err != nil {
+		return SetCookie{}, fmt.Errorf("Set-Cookie %q: %w", v, err)
+	}
+	c := SetCookie{Name: name, Value: value}
+	for _, attr := range parts[1:] {
+		k, v, _ := strings.Cut(strings.TrimSpace(attr), "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		switch strings.ToLower(k) {
+		case "path":
+			if strings.HasPrefix(v, "/") {
+				c.Path = v
+			}
+		case "domain":
+			c.Domain = strings.ToLower(strings.TrimPrefix(v, "."))
+		case "expires":
+			if t, err := ParseDate(v); err == nil {
+				c.Expires = t
+			} else if t, err := time.Parse(netscapeTimeFormat, v); err == nil {
+				c.Expires = t
+			}
+		case "max-age":
+			n, err := strconv.Atoi(v)
+			switch {
+			case err != nil || v == "" || v[0] == '+':
+			case n <= 0:
+				c.MaxAge = -1
+			default:
+				c.MaxAge = n
+			}
+		case "secure":
+			c.Secure = true
+		case "httponly":
+			c.HttpOnly = true
+		case "samesite":
+			for _, s := range []SameSite{SameSiteStrict, SameSiteLax, SameSiteNone} {
+				if strings.EqualFold(v, string(s)) {
+					c.SameSite = s
+				}
+			}
+		}
+	}
+	return c, nil
+}
+
+// netscapeTimeFormat is the Expires format of Netscape's cookie spec, with dashes in the date, which servers still
+// send. any HTTP date is fine too.
+const netscapeTimeFormat = "Mon, 02-Jan-2006 15:04:05 GMT"
+
+// ParseCookies parses the value of a Cookie header. a pair that's malformed is skipped, as RFC 6265 says a server
+// should, since it can't tell what the client meant.
+func ParseCookies(v string) []Cookie {
+	var cookies []Cookie
+	for _, pair := range strings.Split(v, ";") {
+		if name, value, err := parseCookiePair(pair); err == nil {
+			cookies = append(cookies, Cookie{Name: name, Value: value})
+		}
+	}
+	return cookies
+}
+
+// parseCookiePair parses a cookie's name=value, unquoting the value.
+func parseCookiePair(pair string) (name, value string, err error) {
+	name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+	name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+	switch {
+	case !ok:
+		return "", "", fmt.Errorf("%q should be of form name=value", pair)
+	case name == "":
+		return "", "", fmt.Errorf("%q has no name", pair)
+	}
+	if len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"' {
+		value = value[1 : len(value)-1]
+	}
+	return name, value, nil
+}
+
+// quoteCookieValue quotes v if it has a space or comma in it, which a cookie value can only have inside quotes.
+func quoteCookieValue(v string) string {
+	if strings.ContainsAny(v, " ,") {
+		return `"` + v + `"`
+	}
+	return v
+}
+
+// CookieHeader returns the value of a Cookie header sending cookies.
+func CookieHeader(cookies []Cookie) string {
+	pairs := make([]string, len(cookies))
+	for i, c := range cookies {
+		pairs[i] = c.String()
+	}
+	return strings.Join(pairs, "; ")
+}
+
+// Cookies returns the cookies in every Cookie header, in order.
+func (h Headers) Cookies() []Cookie {
+	var cookies []Cookie
+	for _, v := range h.Values("Cookie") {
+		cookies = append(cookies, ParseCookies(v)...)
+	}
+	return cookies
+}
+
+// SetCookies returns the cookies set by the Set-Cookie headers, in order, skipping any that don't parse.
+func (h Headers) SetCookies() []SetCookie {
+	var cookies []SetCookie
+	for _, v := range h.Values("Set-Cookie") {
+		if c, err := ParseSetCookie(v); err == nil {
+			cookies = append(cookies, c)
+		}
+	}
+	return cookies
+}
@@ -0,0 +1,73 @@
+package httpwire
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+/* design note: HTTP dates, in Date, Expires, Last-Modified, If-Modified-Since, and Retry-After, are written one way
+   and read three (RFC 9110, section 5.6.7). a sender must use IMF-fixdate, always in GMT:
+
+	Sun, 06 Nov 1994 08:49:37 GMT
+
+   but a recipient has to accept the two obsolete formats older software sent, and a little still does:
+
+	Sunday, 06-Nov-94 08:49:37 GMT   (RFC 850: a full day name, dashes, and a two-digit year)
+	Sun Nov  6 08:49:37 1994         (C's asctime: no zone, which means GMT, and the day padded with a space)
+
+   so FormatDate writes the first, and ParseDate reads all three, and always returns the time in UTC. RFC 850's
+   two-digit year is taken to be within 50 years of now, as RFC 9110 says, rather than Go's fixed 1969 cutoff, so
+   "06-Nov-94" is 1994 and "06-Nov-30" is 2030. the day name isn't checked against the date: a wrong one is
+   the sender's typo, and the date still says when.
+*/
+
+// TimeFormat is the layout of an IMF-fixdate, the format HTTP dates are written in, for time.Format. the time has to
+// be in UTC; FormatDate sees to that.
+const TimeFormat = "Mon, 02 Jan 2006 15:04:05 GMT"
+
+// the obsolete formats ParseDate reads as well.
+const (
+	rfc850Format  = "Monday, 02-Jan-06 15:04:05 GMT"
+	asctimeFormat = "Mon Jan _2 15:04:05 2006"
+)
+
+// FormatDate returns t as an HTTP date, like "Sun, 06 Nov 1994 08:49:37 GMT", for Date, Expires, or Last-Modified.
+func FormatDate(t time.Time) string {
+	return t.UTC().Format(TimeFormat)
+}
+
+// ParseDate parses an HTTP date in any of the three formats: IMF-fixdate, RFC 850, or asctime, and returns it in
+// UTC. the "GMT" in the layouts is matched as text, not read as a zone, so time.Parse gives UTC already; UTC() makes
+// sure of it, whatever the layouts come to be.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if t, err := time.Parse(TimeFormat, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(asctimeFormat, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(rfc850Format, s); err == nil {
+		return within50Years(t, time.Now()).UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("%q isn't an HTTP date", s)
+}
+
+// within50Years moves t, parsed from a two-digit year, by a century if need be, so it's no more than 50 years after
+// now.
+func within50Years(t, now time.Time) time.Time {
+	for t.Year() > now.Year()+50 {
+		t = t.AddDate(-100, 0, 0)
+	}
+	for t.Year() <= now.Year()-50 {
+		t = t.AddDate(100, 0, 0)
+	}
+	return t
+}
+
+// Date parses the first header named key as an HTTP date. ok is false if there isn't one, or it doesn't parse.
+func (h Headers) Date(key string) (t time.Time, ok bool) {
+	t, err := ParseDate(h.Get(key))
+	return t, err == nil
+}
@@ -0,0 +1,125 @@
+package httpwire
+
+import "strings"
+
+// Headers is a message's header fields, in the order they were sent. a name can appear more than once, as
+// Set-Cookie often does, so it's a list rather than a map. its methods match names case-insensitively, since
+// "content-type" and "Content-Type" are the same header.
+type Headers []Header
+
+// Get returns the value of the first header named key, or "" if there isn't one.
+func (h Headers) Get(key string) string {
+	for _, kv := range h {
+		if strings.EqualFold(kv.Key, key) {
+			return kv.Value
+		}
+	}
+	return ""
+}
+
+// Values returns the values of every header named key, in order, or nil if there aren't any.
+func (h Headers) Values(key string) []string {
+	var vs []string
+	for _, kv := range h {
+		if strings.EqualFold(kv.Key, key) {
+			vs = append(vs, kv.Value)
+		}
+	}
+	return vs
+}
+
+// List returns the elements of every header named key as one comma-separated list (RFC 9110, section 5.6.1):
+// "Accept: a, b" and "Accept: a" then "Accept: b" are the same thing, and both come back as [a b]. a sender may
+// split a list across headers however it likes, so this is the way to read one; Get only sees the first header.
+//
+// Set-Cookie is the exception: a cookie's Expires attribute has a comma in it, so each Set-Cookie is sent as a
+// header of its own and must be read with Values.
+func (h Headers) List(key string) []string {
+	var list []string
+	for _, v := range h.Values(key) {
+		list = append(list, SplitList(v)...)
+	}
+	return list
+}
+
+// HasToken reports whether the list in the headers named key has token in it, in any case, like "close" in
+// "Connection: keep-alive, Close".
+func (h Headers) HasToken(key, token string) bool {
+	for _, t := range h.List(key) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// Chunked reports whether the body is chunked: the last transfer coding is chunked. any before it (say, "gzip,
+// chunked") are applied to the data inside the chunks.
+func (h Headers) Chunked() bool {
+	codings := h.List("Transfer-Encoding")
+	return len(codings) > 0 && strings.EqualFold(codings[len(codings)-1], "chunked")
+}
+
+// Add adds a header named key, with its key in title case, after any others of that name.
+func (h *Headers) Add(key, value string) {
+	*h = append(*h, Header{AsTitle(key), value})
+}
+
+// Set sets the header named key to value. the first header of that name is changed where it is, and any others are
+// removed; if there's none, it's added at the end, with its key in title case.
+func (h *Headers) Set(key, value string) {
+	out, found := (*h)[:0], false
+	for _, kv := range *h {
+		switch {
+		case !strings.EqualFold(kv.Key, key):
+			out = append(out, kv)
+		case !found:
+			out, found = append(out, Header{kv.Key, value}), true
+		}
+	}
+	if !found {
+		out = append(out, Header{AsTitle(key), value})
+	}
+	*h = out
+}
+
+// Del removes every header named key.
+func (h *Headers) Del(key string) {
+	out := (*h)[:0]
+	for _, kv := range *h {
+		if !strings.EqualFold(kv.Key, key) {
+			out = append(out, kv)
+		}
+	}
+	clear((*h)[len(out):])
+	*h = out
+}
+
+// SplitList splits a comma-separated header value into its elements, trimming the whitespace around each, and
+// dropping empty ones, so "a, , b," is [a b]. commas inside a quoted string, like the one in `"x, y"`, don't split.
+func SplitList(v string) []string {
+	var list []string
+	start, quoted := 0, false
+	for i := 0; i < len(v); i++ {
+		switch c := v[i]; {
+		case quoted && c == '\\':
+			i++ // an escaped character, maybe a quote, inside a quoted string.
+		case c == '"':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			if e := strings.TrimSpace(v[start:i]); e != "" {
+				list = append(list, e)
+			}
+			start = i + 1
+		}
+	}
+	if e := strings.TrimSpace(v[start:]); e != "" {
+		list = append(list, e)
+	}
+	return list
+}
+
+// JoinList joins elements into a comma-separated header value, the way SplitList splits one.
+func JoinList(elems []string) string {
+	return strings.Join(elems, ", ")
+}
@@ -0,0 +1,265 @@
+// Package httpwire is HTTP/1.1 messages as they're written on the wire: a Request or Response is its start line,
+// its headers in the order they were sent, duplicates and all, and its body, and WriteTo writes it back out byte for
+// byte. it's what sendreq builds its requests on, pulled out so other tools can make and parse HTTP messages too.
+//
+// it's small on purpose, for reading rather than for production: net/http does all of this and far more, but it
+// hides the wire format behind maps and streams, and the point here is to see it.
+package httpwire
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Header is one header field. the key is in title case, like "Content-Type", when it was set by WithHeader or
+// parsed; see AsTitle.
+type Header struct {
+	Key, Value string
+}
+
+// Response is an HTTP/1.1 response: a status code and reason phrase, headers, a body, and, for a chunked body,
+// trailers.
+type Response struct {
+	Headers    Headers
+	Body       string
+	StatusCode int
+	// Reason is the status line's reason phrase: a parsed one as it came, which can be empty. WriteTo writes it, or
+	// the standard one for the code, like "Not Found", if it's "".
+	Reason string
+	// BodyReader, if it isn't nil, is the body instead of Body, streamed by WriteTo rather than held in memory; see
+	// ReadResponseHead. ContentLength is how long it is: 0 or less means it isn't known, so it runs to io.EOF.
+	BodyReader    io.Reader
+	ContentLength int64
+	// Proto is the protocol version a parsed response came in: "HTTP/1.1", "HTTP/1.0", or "HTTP/0.9", which is
+	// just a body. WriteTo writes it in that version; "" is HTTP/1.1.
+	Proto string
+	// Trailers are header fields sent after the body. only a chunked body can have them: WriteTo drops them
+	// otherwise.
+	Trailers Headers
+	// Warnings are what a lenient parse let slide, like bare-LF line endings; see ParseOptions.Strict.
+	Warnings []string
+}
+
+// WithHeader adds a header, with its key in title case, and returns resp, so calls can be chained.
+func (resp *Response) WithHeader(key, value string) *Response {
+	resp.Headers = append(resp.Headers, Header{AsTitle(key), value})
+	return resp
+}
+
+// WithReason sets the reason phrase, and returns resp.
+func (resp *Response) WithReason(reason string) *Response {
+	resp.Reason = reason
+	return resp
+}
+
+// WithTrailer adds a trailer field, with its key in title case, and returns resp.
+func (resp *Response) WithTrailer(key, value string) *Response {
+	resp.Trailers = append(resp.Trailers, Header{AsTitle(key), value})
+	return resp
+}
+
+// BodyString returns the body: Body, or, if there's a BodyReader, what's left of it, which is read into Body, so
+// it's there next time. it's a convenience for small bodies, which are easier to handle whole.
+func (resp *Response) BodyString() (string, error) {
+	if resp.BodyReader == nil {
+		return resp.Body, nil
+	}
+	var err error
+	resp.Body, err = readBody(resp.body())
+	resp.BodyReader = nil
+	return resp.Body, err
+}
+
+// body returns resp's body to write: BodyReader, as far as ContentLength, or Body.
+func (resp *Response) body() io.Reader { return body(resp.Body, resp.BodyReader, resp.ContentLength) }
+
+// WriteTo writes resp to w as HTTP/1.1, or as its Proto. if the headers say Transfer-Encoding: chunked, the body
+// goes as one chunk, or, from a BodyReader, a chunk for each read, followed by the trailers; otherwise it's written
+// as it is. it returns the first error writing to w, if any, and the number of bytes written before it.
+func (resp *Response) WriteTo(w io.Writer) (n int64, err error) {
+	return WriteOptions{}.WriteResponse(w, resp)
+}
+
+// write writes resp to w, which keeps the first error.
+func (resp *Response) write(w *errWriter) {
+	switch resp.Proto {
+	case "HTTP/0.9":
+		w.body(resp.body(), false, nil)
+		return
+	default:
+		w.printf("%s %d %s\r\n", cmp.Or(resp.Proto, "HTTP/1.1"), resp.StatusCode, cmp.Or(resp.Reason, http.StatusText(resp.StatusCode)))
+	}
+	for _, h := range resp.Headers {
+		w.printf("%s: %s\r\n", h.Key, h.Value)
+	}
+	w.printf("\r\n")
+	// the header promises chunks, so send the body as chunks (if there is one) and the last, empty chunk. a
+	// streamed body goes as it comes, and so does one framed by its Content-Length, without the CRLF after it: that's
+	// for reading a message by eye, and after a framed body it'd be read as the start of the next response.
+	if chunked := resp.Headers.Chunked() && resp.Proto != "HTTP/1.0"; chunked || resp.BodyReader != nil {
+		w.body(resp.body(), chunked, resp.Trailers)
+		return
+	}
+	if resp.Headers.Get("Content-Length") != "" {
+		w.printf("%s", resp.Body)
+		return
+	}
+	w.printf("%s\r\n", resp.Body)
+}
+
+// String returns resp as WriteTo would write it.
+func (resp *Response) String() string {
+	b := new(strings.Builder)
+	resp.WriteTo(b)
+	return b.String()
+}
+
+func (resp *Response) MarshalText() ([]byte, error) {
+	b := new(bytes.Buffer)
+	resp.WriteTo(b)
+	return b.Bytes(), nil
+}
+
+// Request is an HTTP/1.1 request: a method, a path (the request target, query and all), headers, a body, and, for
+// a chunked body, trailers.
+type Request struct {
+	Headers            Headers
+	Method, Path, Body string
+	// BodyReader and ContentLength are a streamed body, as for a Response.
+	BodyReader    io.Reader
+	ContentLength int64
+	// Trailers are header fields sent after a chunked body, as for a Response.
+	Trailers Headers
+	// Warnings are what a lenient parse let slide, like bare-LF line endings; see ParseOptions.Strict.
+	Warnings []string
+}
+
+// WithHeader adds a header, with its key in title case, and returns r, so calls can be chained.
+func (r *Request) WithHeader(key, value string) *Request {
+	r.Headers = append(r.Headers, Header{AsTitle(key), value})
+	return r
+}
+
+// WithTrailer adds a trailer field, with its key in title case, and returns r.
+func (r *Request) WithTrailer(key, value string) *Request {
+	r.Trailers = append(r.Trailers, Header{AsTitle(key), value})
+	return r
+}
+
+// BodyString returns the body, as for a Response.
+func (r *Request) BodyString() (string, error) {
+	if r.BodyReader == nil {
+		return r.Body, nil
+	}
+	var err error
+	r.Body, err = readBody(r.body())
+	r.BodyReader = nil
+	return r.Body, err
+}
+
+// body returns r's body to write: BodyReader, as far as ContentLength, or Body.
+func (r *Request) body() io.Reader { return body(r.Body, r.BodyReader, r.ContentLength) }
+
+// WriteTo writes r to w as HTTP/1.1. if the headers say Transfer-Encoding: chunked, the body goes as chunks,
+// followed by the trailers, as for a Response. it returns the first error writing to w, as for a Response.
+func (r *Request) WriteTo(w io.Writer) (n int64, err error) {
+	return WriteOptions{}.WriteRequest(w, r)
+}
+
+// write writes r to w, which keeps the first error.
+func (r *Request) write(w *errWriter) {
+	// remember, a HTTP request looks like this:
+	// <METHOD>  <PATH>  <PROTOCOL/VERSION>
+	// <HEADER>: <VALUE>
+	// <HEADER>: <VALUE>
+	//
+	// <REQUEST BODY>
+
+	// write the request line: like "GET /index.html HTTP/1.1"
+	w.printf("%s %s HTTP/1.1\r\n", r.Method, r.Path)
+
+	// write the headers. we don't do anything to order them or combine/merge duplicate headers; this is just an example.
+	for _, h := range r.Headers {
+		w.printf("%s: %s\r\n", h.Key, h.Value)
+	}
+	w.printf("\r\n") // write the empty line that separates the headers from the body
+	if chunked := r.Headers.Chunked(); chunked || r.BodyReader != nil {
+		w.body(r.body(), chunked, r.Trailers)
+		return
+	}
+	w.printf("%s\r\n", r.Body) // write the body and terminate with a newline
+}
+
+// String returns r as WriteTo would write it.
+func (r *Request) String() string {
+	b := new(strings.Builder)
+	r.WriteTo(b)
+	return b.String()
+}
+
+func (r *Request) MarshalText() ([]byte, error) {
+	b := new(bytes.Buffer)
+	r.WriteTo(b)
+	return b.Bytes(), nil
+}
+
+// NewRequest returns a request for path on host, with a Host header, and a Content-Length if there's a body.
+func NewRequest(method, path, host, body string) (*Request, error) {
+	switch {
+	case method == "":
+		return nil, errors.New("missing required argument: method")
+	case path == "":
+		return nil, errors.New("missing required argument: path")
+	case !strings.HasPrefix(path, "/"):
+		return nil, errors.New("path must start with /")
+	case host == "":
+		return nil, errors.New("missing required argument: host")
+	default:
+		headers := Headers{{"Host", host}}
+		if body != "" {
+			headers = append(headers, Header{"Content-Length", fmt.Sprintf("%d", len(body))})
+		}
+		return &Request{Method: method, Path: path, Headers: headers, Body: body}, nil
+	}
+}
+
+// NewResponse returns a response with status and a Content-Length; an empty body is replaced by the status text.
+func NewResponse(status int, body string) (*Response, error) {
+	switch {
+	case status < 100 || status > 599:
+		return nil, errors.New("invalid status code")
+	default:
+		if body == "" {
+			body = http.StatusText(status)
+		}
+		headers := Headers{{"Content-Length", fmt.Sprintf("%d", len(body))}}
+		return &Response{
+			StatusCode: status,
+			Headers:    headers,
+			Body:       body,
+		}, nil
+	}
+}
+
+// body returns the body to write, for a message with s, r, and n as its Body, BodyReader, and ContentLength.
+func body(s string, r io.Reader, n int64) io.Reader {
+	switch {
+	case r == nil:
+		return strings.NewReader(s)
+	case n > 0:
+		return &exactReader{r: r, n: n}
+	}
+	return r
+}
+
+// readBody reads all of body, for BodyString.
+func readBody(body io.Reader) (string, error) {
+	var b strings.Builder
+	_, err := io.Copy(&b, body)
+	return b.String(), err
+}
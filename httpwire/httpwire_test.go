@@ -0,0 +1,1376 @@
+package httpwire
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTitleCaseKey(t *testing.T) {
+	for input, want := range map[string]string{
+		"foo-bar":      "Foo-Bar",
+		"cONTEnt-tYPE": "Content-Type",
+		"host":         "Host",
+		"host-":        "Host-",
+		"ha22-o3st":    "Ha22-O3st",
+	} {
+		if got := AsTitle(input); got != want {
+			t.Errorf("TitleCaseKey(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestHTTPResponse(t *testing.T) {
+	for name, tt := range map[string]struct {
+		input string
+		want  *Response
+	}{
+		"200 OK (no body)": {
+			input: "HTTP/1.1 200 OK\r\nContent-Length: 0\r\n\r\n",
+			want: &Response{
+				Proto:      "HTTP/1.1",
+				StatusCode: 200,
+				Reason:     "OK",
+				Headers: []Header{
+					{"Content-Length", "0"},
+				},
+			},
+		},
+		"404 Not Found (w/ body)": {
+			input: "HTTP/1.1 404 Not Found\r\nContent-Length: 11\r\n\r\nHello World\r\n",
+			want: &Response{
+				Proto:      "HTTP/1.1",
+				StatusCode: 404,
+				Reason:     "Not Found",
+				Headers: []Header{
+					{"Content-Length", "11"},
+				},
+				Body: "Hello World",
+			},
+		},
+		"200 OK (chunked)": {
+			input: "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: Expires\r\n\r\n5\r\nHello\r\n6;ext=1\r\n World\r\n0\r\nExpires: never\r\n\r\n",
+			want: &Response{
+				Proto:      "HTTP/1.1",
+				StatusCode: 200,
+				Reason:     "OK",
+				Headers: []Header{
+					{"Transfer-Encoding", "chunked"},
+					{"Trailer", "Expires"},
+				},
+				Body:     "Hello World",
+				Trailers: []Header{{"Expires", "never"}},
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseResponse(tt.input)
+			if err != nil {
+				t.Errorf("ParseResponse(%q) returned error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseResponse(%q) = %#+v, want %#+v", tt.input, got, tt.want)
+			}
+
+			if got2, err := ParseResponse(got.String()); err != nil {
+				t.Errorf("ParseResponse(%q) returned error: %v", got.String(), err)
+			} else if !reflect.DeepEqual(got2, got) {
+				t.Errorf("ParseResponse(%q) = %#+v, want %#+v", got.String(), got2, got)
+			}
+
+		})
+	}
+}
+
+func TestHTTPRequest(t *testing.T) {
+	for name, tt := range map[string]struct {
+		input string
+		want  Request
+	}{
+		"GET (no body)": {
+			input: "GET / HTTP/1.1\r\nHost: www.example.com\r\n\r\n",
+			want: Request{
+				Method: "GET",
+				Path:   "/",
+				Headers: []Header{
+					{"Host", "www.example.com"},
+				},
+			},
+		},
+		"POST (w/ body)": {
+			input: "POST / HTTP/1.1\r\nHost: www.example.com\r\nContent-Length: 11\r\n\r\nHello World\r\n",
+			want: Request{
+				Method: "POST",
+				Path:   "/",
+				Headers: []Header{
+					{"Host", "www.example.com"},
+					{"Content-Length", "11"},
+				},
+				Body: "Hello World",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := ParseRequest(tt.input)
+			if err != nil {
+				t.Errorf("ParseRequest(%q) returned error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseRequest(%q) = %#+v, want %#+v", tt.input, got, tt.want)
+			}
+			// test that the request can be written to a string and parsed back into the same request.
+			got2, err := ParseRequest(got.String())
+			if err != nil {
+				t.Errorf("ParseRequest(%q) returned error: %v", got.String(), err)
+			}
+			if !reflect.DeepEqual(got, got2) {
+				t.Errorf("ParseRequest(%q) = %+v, want %+v", got.String(), got2, got)
+			}
+
+		})
+	}
+}
+
+func TestConvertStd(t *testing.T) {
+	r := &Request{Method: "POST", Path: "/items?sort=name", Body: `{"name": "widget"}`}
+	r.WithHeader("Host", "example.com:8080").WithHeader("Content-Type", "application/json").WithHeader("X-Tag", "a").WithHeader("X-Tag", "b").WithHeader("Content-Length", "18")
+	req, err := ToStdRequest(r)
+	if err != nil {
+		t.Fatalf("ToStdRequest() returned error: %v", err)
+	}
+	if req.Host != "example.com:8080" || req.URL.String() != "http://example.com:8080/items?sort=name" || req.ContentLength != 18 {
+		t.Errorf("ToStdRequest() = host %q, URL %s, length %d", req.Host, req.URL, req.ContentLength)
+	}
+	if got := req.Header.Values("X-Tag"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("X-Tag = %q, want both values in order", got)
+	}
+	back, err := FromStdRequest(req)
+	if err != nil {
+		t.Fatalf("FromStdRequest() returned error: %v", err)
+	}
+	want := &Request{Method: "POST", Path: "/items?sort=name", Body: r.Body, Headers: []Header{
+		{"Host", "example.com:8080"}, {"Content-Type", "application/json"}, {"X-Tag", "a"}, {"X-Tag", "b"}, {"Content-Length", "18"},
+	}}
+	if !reflect.DeepEqual(back, want) {
+		t.Errorf("FromStdRequest(ToStdRequest()) = %+v, want %+v", back, want)
+	}
+
+	// a chunked response, with a trailer, through net/http's own parser and back.
+	resp := (&Response{Proto: "HTTP/1.1", StatusCode: 200, Reason: "OK", Body: "hello"}).WithHeader("Transfer-Encoding", "chunked").WithTrailer("X-Checksum", "42")
+	hr, err := http.ReadResponse(bufio.NewReader(strings.NewReader(resp.String())), nil)
+	if err != nil {
+		t.Fatalf("http.ReadResponse() returned error: %v", err)
+	}
+	got, err := FromStdResponse(hr)
+	if err != nil {
+		t.Fatalf("FromStdResponse() returned error: %v", err)
+	}
+	if !reflect.DeepEqual(got, resp) {
+		t.Errorf("FromStdResponse() = %+v, want %+v", got, resp)
+	}
+	hr, err = ToStdResponse(got)
+	if err != nil {
+		t.Fatalf("ToStdResponse() returned error: %v", err)
+	}
+	var b strings.Builder
+	if err := hr.Write(&b); err != nil {
+		t.Fatalf("writing the converted response: %v", err)
+	}
+	if want := "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Checksum\r\n\r\n5\r\nhello\r\n0\r\nX-Checksum: 42\r\n\r\n"; b.String() != want {
+		t.Errorf("the converted response writes as %q, want %q", b.String(), want)
+	}
+}
+
+func TestConvertStdServer(t *testing.T) {
+	// a handler that sees the request as a Request, and answers with a Response.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, hr *http.Request) {
+		req, err := FromStdRequest(hr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		resp := (&Response{StatusCode: http.StatusCreated, Body: req.Method + " " + req.Path + " " + req.Body}).
+			WithHeader("X-Tag", req.Headers.Get("X-Tag"))
+		for _, h := range resp.Headers {
+			w.Header().Add(h.Key, h.Value)
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.WriteString(w, resp.Body)
+	}))
+	defer srv.Close()
+
+	r := &Request{Method: "PUT", Path: "/items/1?full=1", Body: "widget"}
+	r.WithHeader("Host", strings.TrimPrefix(srv.URL, "http://")).WithHeader("X-Tag", "a")
+	req, err := ToStdRequest(r)
+	if err != nil {
+		t.Fatalf("ToStdRequest() returned error: %v", err)
+	}
+	hr, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatalf("sending the converted request: %v", err)
+	}
+	resp, err := FromStdResponse(hr)
+	if err != nil {
+		t.Fatalf("FromStdResponse() returned error: %v", err)
+	}
+	if resp.StatusCode != http.StatusCreated || resp.Body != "PUT /items/1?full=1 widget" || resp.Headers.Get("X-Tag") != "a" {
+		t.Errorf("FromStdResponse() = %+v; want 201, with the request echoed", resp)
+	}
+}
+
+func TestReadResponse(t *testing.T) {
+	// three responses back to back, as on a kept-alive connection; the last one's body runs to EOF.
+	r := bufio.NewReader(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello" +
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Sum\r\n\r\n3\r\nabc\r\n0\r\nX-Sum: 1\r\n\r\n" +
+		"HTTP/1.0 404 Not Found\r\n\r\nnot here"))
+	for _, want := range []*Response{
+		{Proto: "HTTP/1.1", StatusCode: 200, Reason: "OK", Headers: []Header{{"Content-Length", "5"}}, Body: "hello"},
+		{Proto: "HTTP/1.1", StatusCode: 200, Reason: "OK", Headers: []Header{{"Transfer-Encoding", "chunked"}, {"Trailer", "X-Sum"}}, Body: "abc", Trailers: []Header{{"X-Sum", "1"}}},
+		{Proto: "HTTP/1.0", StatusCode: 404, Reason: "Not Found", Body: "not here"},
+	} {
+		got, err := ReadResponse(r)
+		if err != nil {
+			t.Fatalf("ReadResponse returned error: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ReadResponse = %+v, want %+v", got, want)
+		}
+	}
+	if _, err := ReadResponse(r); err != io.EOF {
+		t.Errorf("ReadResponse at the end = %v, want io.EOF", err)
+	}
+
+	// a HEAD response's Content-Length describes the body a GET would have had, so don't wait for it.
+	r = bufio.NewReader(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Length: 100\r\n\r\nHTTP/1.1 204 No Content\r\n\r\n"))
+	if got, err := ReadResponseTo(r, "HEAD"); err != nil || got.Body != "" {
+		t.Errorf("ReadResponseTo(HEAD) = %+v, %v; want no body", got, err)
+	}
+	if got, err := ReadResponse(r); err != nil || got.StatusCode != 204 {
+		t.Errorf("ReadResponse after a HEAD response = %+v, %v; want the 204", got, err)
+	}
+
+	// responses written back to back read back as they were. a CRLF after the first one's body used to make the
+	// second look like an HTTP/0.9 body, so a 404 came back as a 200.
+	var conn bytes.Buffer
+	for _, resp := range []*Response{
+		{StatusCode: 200, Headers: Headers{{"Content-Length", "5"}}, Body: "hello"},
+		{StatusCode: 404, Headers: Headers{{"Content-Length", "4"}}, Body: "nope"},
+	} {
+		resp.WriteTo(&conn)
+	}
+	r = bufio.NewReader(&conn)
+	for _, want := range []struct {
+		code int
+		body string
+	}{{200, "hello"}, {404, "nope"}} {
+		if got, err := ReadResponse(r); err != nil || got.StatusCode != want.code || got.Body != want.body {
+			t.Errorf("ReadResponse of a written response = %+v, %v; want %d %q", got, err, want.code, want.body)
+		}
+	}
+	if _, err := ReadResponse(r); err != io.EOF {
+		t.Errorf("ReadResponse after the written responses = %v, want io.EOF", err)
+	}
+
+	// empty lines a sloppy server leaves before a status line are skipped, with a warning, or refused when strict.
+	const blank = "\r\n\nHTTP/1.1 204 No Content\r\n\r\n"
+	if got, err := ReadResponse(bufio.NewReader(strings.NewReader(blank))); err != nil || got.StatusCode != 204 || len(got.Warnings) != 1 {
+		t.Errorf("ReadResponse(%q) = %+v, %v; want the 204, with a warning", blank, got, err)
+	}
+	if got, err := ParseResponse(blank); err != nil || got.StatusCode != 204 {
+		t.Errorf("ParseResponse(%q) = %+v, %v; want the 204", blank, got, err)
+	}
+	if _, err := (ParseOptions{Strict: true}).ReadResponse(bufio.NewReader(strings.NewReader(blank)), ""); err == nil {
+		t.Errorf("strict ReadResponse(%q) returned no error", blank)
+	}
+	var limit *LimitError
+	endless := strings.Repeat("\r\n", 100) + "HTTP/1.1 204 No Content\r\n\r\n"
+	if _, err := (ParseOptions{MaxHeaderBytes: 64}).ReadResponse(bufio.NewReader(strings.NewReader(endless)), ""); !errors.As(err, &limit) {
+		t.Errorf("ReadResponse of 100 empty lines = %v, want a LimitError", err)
+	}
+
+	// a failed read, like a read deadline passing, is returned, and not lost to bufio, which reports it only once.
+	errTimeout := errors.New("i/o timeout")
+	r = bufio.NewReader(&errOnceReader{strings.NewReader("HTTP/1.1 204 No Content\r\n\r\n"), errTimeout})
+	if _, err := ReadResponse(r); !errors.Is(err, errTimeout) {
+		t.Errorf("ReadResponse over a failing read = %v, want %v", err, errTimeout)
+	}
+	if got, err := ReadResponse(r); err != nil || got.StatusCode != 204 {
+		t.Errorf("ReadResponse after the failure = %+v, %v; want the 204", got, err)
+	}
+
+	for _, raw := range []string{
+		"HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nshort",
+		"HTTP/1.1 200 OK\r\nContent-Length: ten\r\n\r\n",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhel",
+		"HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n",
+		"HTT",
+	} {
+		if _, err := ReadResponse(bufio.NewReader(strings.NewReader(raw))); err == nil {
+			t.Errorf("ReadResponse(%q) returned no error", raw)
+		}
+	}
+}
+
+func TestChunked(t *testing.T) {
+	var b strings.Builder
+	w := NewChunkedWriter(&b)
+	w.Trailers = []Header{{"X-Checksum", "42"}}
+	for _, s := range []string{"Hello", "", ", World"} {
+		if _, err := io.WriteString(w, s); err != nil {
+			t.Fatalf("Write(%q) returned error: %v", s, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+	// the empty write is skipped: a zero-size chunk would end the body.
+	if want := "5\r\nHello\r\n7\r\n, World\r\n0\r\nX-Checksum: 42\r\n\r\n"; b.String() != want {
+		t.Errorf("ChunkedWriter wrote %q, want %q", b.String(), want)
+	}
+	if _, err := w.Write([]byte("late")); err == nil {
+		t.Error("Write after Close returned no error")
+	}
+
+	// extensions are kept for the chunk being read, and what follows the body is left in the reader.
+	br := bufio.NewReader(strings.NewReader("5;sig=abc\r\nHello\r\n7\r\n, World\r\n0\r\nx-checksum: 42\r\n\r\nnext"))
+	r := NewChunkedReader(br)
+	p := make([]byte, 5)
+	if _, err := io.ReadFull(r, p); err != nil || r.Extensions() != "sig=abc" {
+		t.Errorf("first chunk = %q, extensions %q, %v; want Hello, sig=abc", p, r.Extensions(), err)
+	}
+	rest, err := io.ReadAll(r)
+	if err != nil || string(rest) != ", World" {
+		t.Errorf("the rest = %q, %v; want \", World\"", rest, err)
+	}
+	if want := (Headers{{"X-Checksum", "42"}}); !reflect.DeepEqual(r.Trailers(), want) {
+		t.Errorf("Trailers() = %v, want %v", r.Trailers(), want)
+	}
+	if next, _ := io.ReadAll(br); string(next) != "next" {
+		t.Errorf("left in the reader: %q, want \"next\"", next)
+	}
+
+	for _, raw := range []string{"5\r\nHel", "5\r\nHelloX\r\n0\r\n\r\n", "zz\r\n", "0\r\nno colon\r\n\r\n"} {
+		if _, err := io.ReadAll(NewChunkedReader(strings.NewReader(raw))); err == nil {
+			t.Errorf("ChunkedReader(%q) returned no error", raw)
+		}
+	}
+
+	// a chunked request goes out as chunks, and parses back to the same body.
+	req := (&Request{Method: "POST", Path: "/", Body: "hello"}).WithHeader("Host", "example.com").WithHeader("Transfer-Encoding", "chunked")
+	if want := "POST / HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n0\r\n\r\n"; req.String() != want {
+		t.Errorf("chunked request = %q, want %q", req.String(), want)
+	}
+	if got, err := ParseRequest(req.String()); err != nil || got.Body != "hello" {
+		t.Errorf("ParseRequest(%q) = %+v, %v; want the body back", req.String(), got, err)
+	}
+}
+
+func TestHeaders(t *testing.T) {
+	h := Headers{{"Content-Type", "text/plain"}, {"Set-Cookie", "a=1"}, {"X-Tag", "x"}, {"Set-Cookie", "b=2"}}
+	if got := h.Get("content-type"); got != "text/plain" {
+		t.Errorf("Get(content-type) = %q, want text/plain", got)
+	}
+	if got := h.Get("Accept"); got != "" {
+		t.Errorf("Get(Accept) = %q, want nothing", got)
+	}
+	if got := h.Values("SET-COOKIE"); !reflect.DeepEqual(got, []string{"a=1", "b=2"}) {
+		t.Errorf("Values(SET-COOKIE) = %q, want both cookies in order", got)
+	}
+
+	// Set replaces the first in place and drops the rest; a new one goes at the end, title-cased.
+	h.Set("set-cookie", "c=3")
+	h.Set("accept", "*/*")
+	want := Headers{{"Content-Type", "text/plain"}, {"Set-Cookie", "c=3"}, {"X-Tag", "x"}, {"Accept", "*/*"}}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("after Set, headers = %v, want %v", h, want)
+	}
+	h.Del("x-tag")
+	h.Del("Nope")
+	want = Headers{{"Content-Type", "text/plain"}, {"Set-Cookie", "c=3"}, {"Accept", "*/*"}}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("after Del, headers = %v, want %v", h, want)
+	}
+}
+
+func TestLists(t *testing.T) {
+	for v, want := range map[string][]string{
+		"a, b":                 {"a", "b"},
+		" a ,, b ,":            {"a", "b"},
+		`W/"x, y", "z\"," , w`: {`W/"x, y"`, `"z\","`, "w"},
+		"":                     nil,
+	} {
+		if got := SplitList(v); !reflect.DeepEqual(got, want) {
+			t.Errorf("SplitList(%q) = %q, want %q", v, got, want)
+		}
+	}
+	if got := JoinList([]string{"gzip", "chunked"}); got != "gzip, chunked" {
+		t.Errorf("JoinList = %q, want \"gzip, chunked\"", got)
+	}
+
+	// a list can be split across headers any way the sender likes.
+	h := Headers{{"Accept", "text/html, application/json"}, {"Connection", "keep-alive"}, {"Accept", "*/*"}, {"Connection", "Upgrade"}}
+	if got := h.List("accept"); !reflect.DeepEqual(got, []string{"text/html", "application/json", "*/*"}) {
+		t.Errorf("List(accept) = %q, want all three types in order", got)
+	}
+	if !h.HasToken("Connection", "upgrade") || h.HasToken("Connection", "close") {
+		t.Error("HasToken(Connection) should find upgrade in the second header, and not close")
+	}
+	h.Add("transfer-encoding", "gzip")
+	h.Add("Transfer-Encoding", "chunked")
+	if !h.Chunked() || (Headers{{"Transfer-Encoding", "chunked, gzip"}}).Chunked() {
+		t.Error("Chunked() should look at the last coding across every Transfer-Encoding header")
+	}
+}
+
+func TestParseLimits(t *testing.T) {
+	o := ParseOptions{MaxLineLength: 64, MaxHeaderBytes: 100, MaxHeaderCount: 3}
+	long := strings.Repeat("a", 65)
+	for name, tt := range map[string]struct {
+		raw    string
+		limit  string
+		status int
+	}{
+		"long request line": {"GET /" + long + " HTTP/1.1\r\nHost: x\r\n\r\n", "MaxLineLength", 414},
+		"long header line":  {"GET / HTTP/1.1\r\nHost: x\r\nX-Long: " + long + "\r\n\r\n", "MaxLineLength", 431},
+		"too many headers":  {"GET / HTTP/1.1\r\nHost: x\r\nA: 1\r\nB: 2\r\nC: 3\r\n\r\n", "MaxHeaderCount", 431},
+		"too many bytes": {"GET / HTTP/1.1\r\nHost: x\r\nA: " + strings.Repeat("b", 50) + "\r\nB: " + strings.Repeat("c", 50) + "\r\n\r\n",
+			"MaxHeaderBytes", 431},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := o.ParseRequest(tt.raw)
+			var le *LimitError
+			if !errors.As(err, &le) || le.Limit != tt.limit || le.StatusCode() != tt.status {
+				t.Fatalf("ParseRequest = %v, want a %s LimitError with status %d", err, tt.limit, tt.status)
+			}
+			// a response's head is held to the same limits, whether it's parsed or read.
+			resp := "HTTP/1.1 200 OK" + tt.raw[strings.Index(tt.raw, "\r\n"):]
+			if tt.status == 414 {
+				resp = "HTTP/1.1 200 " + long + "\r\n\r\n"
+			}
+			if _, err := o.ParseResponse(resp); !errors.As(err, &le) || le.Limit != tt.limit || le.StatusCode() != 431 {
+				t.Errorf("ParseResponse = %v, want a %s LimitError", err, tt.limit)
+			}
+			if _, err := o.ReadResponse(bufio.NewReader(strings.NewReader(resp)), ""); !errors.As(err, &le) || le.Limit != tt.limit {
+				t.Errorf("ReadResponse = %v, want a %s LimitError", err, tt.limit)
+			}
+		})
+	}
+
+	// an endless line is cut off once it's past the limit, not read to the end.
+	r := bufio.NewReaderSize(io.MultiReader(strings.NewReader("HTTP/1.1 200 OK\r\nX: "), endless{}), 16)
+	if _, err := o.ReadResponse(r, ""); !errors.As(err, new(*LimitError)) {
+		t.Errorf("ReadResponse of an endless header = %v, want a LimitError", err)
+	}
+	if _, err := ParseRequest("GET / HTTP/1.1\r\nHost: x\r\nA: " + long + "\r\n\r\n"); err != nil {
+		t.Errorf("with the defaults, ParseRequest returned error: %v", err)
+	}
+}
+
+// endless is an endless stream of x's.
+type endless struct{}
+
+func (endless) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'x'
+	}
+	return len(p), nil
+}
+
+func TestStrict(t *testing.T) {
+	for name, raw := range map[string]string{
+		"bare LF":              "GET / HTTP/1.1\nHost: x\n\n",
+		"space before colon":   "GET / HTTP/1.1\r\nHost: x\r\nContent-Length : 0\r\n\r\n",
+		"header name":          "GET / HTTP/1.1\r\nHost: x\r\nX(Bad): 1\r\n\r\n",
+		"method":               "G@T / HTTP/1.1\r\nHost: x\r\n\r\n",
+		"double spaces":        "GET  / HTTP/1.1\r\nHost: x\r\n\r\n",
+		"version":              "GET / HTTP/1\r\nHost: x\r\n\r\n",
+		"control in the value": "GET / HTTP/1.1\r\nHost: x\r\nX: a\x01b\r\n\r\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := (ParseOptions{Strict: true}).ParseRequest(raw); err == nil {
+				t.Errorf("strict ParseRequest(%q) returned no error", raw)
+			}
+			r, err := ParseRequest(raw)
+			if err != nil || len(r.Warnings) == 0 {
+				t.Errorf("lenient ParseRequest(%q) = warnings %q, %v; want it parsed, with a warning", raw, r.Warnings, err)
+			}
+			if err == nil && r.Headers.Get("Host") != "x" {
+				t.Errorf("lenient ParseRequest(%q) lost the Host header: %v", raw, r.Headers)
+			}
+		})
+	}
+
+	// some things aren't safe to let slide, strict or not.
+	for _, raw := range []string{
+		"GET / HTTP/1.1\r\nHost: x\r\nno colon\r\n\r\n",
+		"GET / HTTP/1.1\r\nHost: x\r\n: empty name\r\n\r\n",
+		"GET / HTTP/1.1\r\nHost: x\r\nX: a\rb\r\n\r\n",
+	} {
+		if _, err := ParseRequest(raw); err == nil {
+			t.Errorf("lenient ParseRequest(%q) returned no error", raw)
+		}
+	}
+
+	// a value needn't have a space before it, and the space around it isn't part of it.
+	r, err := (ParseOptions{Strict: true}).ParseRequest("GET / HTTP/1.1\r\nHost:x\r\nX-Tag: \t a b \r\n\r\n")
+	if err != nil || r.Headers.Get("Host") != "x" || r.Headers.Get("X-Tag") != "a b" || r.Warnings != nil {
+		t.Errorf("strict ParseRequest = %+v, %v; want Host x and X-Tag \"a b\", with no warnings", r, err)
+	}
+
+	resp, err := ParseOptions{}.ReadResponse(bufio.NewReader(strings.NewReader("HTTP/1.1 204 No Content\nServer : old\n\n")), "")
+	if err != nil {
+		t.Fatalf("ReadResponse returned error: %v", err)
+	}
+	if len(resp.Warnings) != 4 || resp.Headers.Get("Server") != "old" {
+		t.Errorf("ReadResponse warnings = %q; want the Server header, with a warning for each bare LF and the space", resp.Warnings)
+	}
+}
+
+func TestObsFold(t *testing.T) {
+	raw := "HTTP/1.1 200 OK\r\nX-Long: first,\r\n  second,\r\n\tthird\r\nX-Empty:\r\n more\r\nContent-Length: 2\r\n\r\nok"
+	resp, err := ReadResponse(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil {
+		t.Fatalf("ReadResponse returned error: %v", err)
+	}
+	want := Headers{{"X-Long", "first, second, third"}, {"X-Empty", "more"}, {"Content-Length", "2"}}
+	if !reflect.DeepEqual(resp.Headers, want) || resp.Body != "ok" || len(resp.Warnings) != 3 {
+		t.Errorf("ReadResponse = headers %v, body %q, warnings %q; want %v unfolded, with a warning for each fold", resp.Headers, resp.Body, resp.Warnings, want)
+	}
+	// a strict server may refuse a folded request, and does; a client has to take a folded response.
+	req := "GET / HTTP/1.1\r\nHost: x\r\nX-Long: a\r\n b\r\n\r\n"
+	if _, err := (ParseOptions{Strict: true}).ParseRequest(req); err == nil {
+		t.Error("strict ParseRequest accepted a folded header")
+	}
+	if r, err := ParseRequest(req); err != nil || r.Headers.Get("X-Long") != "a b" {
+		t.Errorf("ParseRequest = %v, %v; want X-Long unfolded to \"a b\"", r.Headers, err)
+	}
+	if r, err := (ParseOptions{Strict: true}).ParseResponse(raw); err != nil || r.Headers.Get("X-Long") != "first, second, third" {
+		t.Errorf("strict ParseResponse = %v, %v; want X-Long unfolded", r, err)
+	}
+	if _, err := ParseRequest("GET / HTTP/1.1\r\n Host: x\r\n\r\n"); err == nil {
+		t.Error("ParseRequest accepted a fold with no header before it")
+	}
+}
+
+func TestTarget(t *testing.T) {
+	for _, tc := range []struct{ in, path string }{
+		{"/", "/"},
+		{"/a/b", "/a/b"},
+		{"/a/./b/../c%20d", "/a/c d"},
+		{"/a/b/..", "/a/"},
+		{"/a/.", "/a/"},
+		{"/../../etc/passwd", "/etc/passwd"},
+		{"/static/%2e%2e/%2E%2e/secret", "/secret"},
+		{"/a%2Fb/c?x=1", "/a/b/c"},
+		{"//a//b/", "//a//b/"},
+	} {
+		target, err := ParseTarget(tc.in)
+		if err != nil || target.Path != tc.path {
+			t.Errorf("ParseTarget(%q) = %+v, %v; want path %q", tc.in, target, err, tc.path)
+		}
+	}
+	if target, _ := ParseTarget("/a%2Fb/c?x=1"); target.RawPath != "/a%2Fb/c" || target.RawQuery != "x=1" {
+		t.Errorf("ParseTarget = %+v; want the raw path and query as they were sent", target)
+	}
+	for _, in := range []string{"", "a/b", "http://x/", "/a%2", "/%zz"} {
+		if _, err := ParseTarget(in); err == nil {
+			t.Errorf("ParseTarget(%q) returned no error", in)
+		}
+	}
+
+	r, err := ParseRequest("GET /search?q=go+lang&tag=a&tag=b%26c&empty=&flag&bad=%z HTTP/1.1\r\nHost: x\r\n\r\n")
+	if err != nil {
+		t.Fatalf("ParseRequest returned error: %v", err)
+	}
+	want := map[string][]string{"q": {"go lang"}, "tag": {"a", "b&c"}, "empty": {""}, "flag": {""}}
+	if got := r.Query(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Query() = %v; want %v", got, want)
+	}
+	if q, err := ParseQuery("a=1&b=%z&c=3"); err == nil || !reflect.DeepEqual(q, map[string][]string{"a": {"1"}, "c": {"3"}}) {
+		t.Errorf("ParseQuery = %v, %v; want the good pairs, and an error for the bad one", q, err)
+	}
+	if s, err := Unescape("a+b%20c"); err != nil || s != "a+b c" {
+		t.Errorf("Unescape = %q, %v; want \"a+b c\": + is only a space in a query", s, err)
+	}
+}
+
+func TestCookies(t *testing.T) {
+	c, err := ParseSetCookie(`session="abc 123"; path=/app; Domain=.Example.com; Expires=Wed, 21-Oct-2015 07:28:00 GMT; Max-Age=3600; Secure; HttpOnly; SameSite=lax; Priority=High`)
+	want := SetCookie{
+		Name: "session", Value: "abc 123", Path: "/app", Domain: "example.com",
+		Expires: time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC), MaxAge: 3600,
+		Secure: true, HttpOnly: true, SameSite: SameSiteLax,
+	}
+	if err != nil || c != want {
+		t.Errorf("ParseSetCookie = %+v, %v; want %+v", c, err, want)
+	}
+	if s := want.String(); s != `session="abc 123"; Path=/app; Domain=example.com; Expires=Wed, 21 Oct 2015 07:28:00 GMT; Max-Age=3600; Secure; HttpOnly; SameSite=Lax` {
+		t.Errorf("String() = %s", s)
+	}
+	if back, err := ParseSetCookie(want.String()); err != nil || back != want {
+		t.Errorf("ParseSetCookie(String()) = %+v, %v; want it back as it was", back, err)
+	}
+
+	// attributes that don't parse are ignored; Max-Age=0 deletes, and wins over Expires.
+	c, err = ParseSetCookie("id=1; Expires=tomorrow; Max-Age=0; SameSite=sometimes; Path=relative")
+	if err != nil || c != (SetCookie{Name: "id", Value: "1", MaxAge: -1}) || !c.ExpiresAt(time.Now()).Before(time.Now()) {
+		t.Errorf("ParseSetCookie = %+v, %v; want id=1, expired, and nothing else", c, err)
+	}
+	if s := c.String(); s != "id=1; Max-Age=0" {
+		t.Errorf("String() = %q; want a negative MaxAge written as Max-Age=0", s)
+	}
+	for _, bad := range []string{"", "novalue", "=nameless; Path=/"} {
+		if _, err := ParseSetCookie(bad); err == nil {
+			t.Errorf("ParseSetCookie(%q) returned no error", bad)
+		}
+	}
+
+	h := Headers{{"Cookie", `a=1; b="two"; junk; =x`}, {"Cookie", "c=3"}, {"Set-Cookie", "x=y; HttpOnly"}, {"Set-Cookie", "bad"}}
+	cookies := h.Cookies()
+	if want := []Cookie{{"a", "1"}, {"b", "two"}, {"c", "3"}}; !reflect.DeepEqual(cookies, want) {
+		t.Errorf("Cookies() = %v; want %v", cookies, want)
+	}
+	if s := CookieHeader(cookies); s != "a=1; b=two; c=3" {
+		t.Errorf("CookieHeader = %q", s)
+	}
+	if set := h.SetCookies(); len(set) != 1 || set[0] != (SetCookie{Name: "x", Value: "y", HttpOnly: true}) {
+		t.Errorf("SetCookies() = %+v; want x=y, skipping the one that doesn't parse", set)
+	}
+}
+
+func TestDates(t *testing.T) {
+	want := time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)
+	for _, s := range []string{"Sun, 06 Nov 1994 08:49:37 GMT", "Sunday, 06-Nov-94 08:49:37 GMT", "Sun Nov  6 08:49:37 1994", " Sun, 06 Nov 1994 08:49:37 GMT "} {
+		if got, err := ParseDate(s); err != nil || !got.Equal(want) || got.Location() != time.UTC {
+			t.Errorf("ParseDate(%q) = %v (in %v), %v; want %v, in UTC", s, got, got.Location(), err, want)
+		}
+	}
+	for _, s := range []string{"", "yesterday", "2024-01-02T03:04:05Z", "Sun, 06 Nov 1994 08:49:37 PST"} {
+		if _, err := ParseDate(s); err == nil {
+			t.Errorf("ParseDate(%q) returned no error", s)
+		}
+	}
+	if s := FormatDate(want.In(time.FixedZone("EST", -5*60*60))); s != "Sun, 06 Nov 1994 08:49:37 GMT" {
+		t.Errorf("FormatDate = %q; want it in GMT", s)
+	}
+
+	// a two-digit year is within 50 years of now.
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for year, want := range map[int]int{1994: 1994, 2030: 2030, 1970: 2070, 2075: 2075, 2077: 1977} {
+		if got := within50Years(time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC), now).Year(); got != want {
+			t.Errorf("within50Years(%d) = %d; want %d", year, got, want)
+		}
+	}
+	if d, ok := (Headers{{"Last-Modified", "Sun Nov  6 08:49:37 1994"}}).Date("last-modified"); !ok || !d.Equal(want) {
+		t.Errorf("Date() = %v, %v; want %v", d, ok, want)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	h := Headers{
+		{"Accept", "text/html;level=1, application/json;q=0.9, text/*;q=0.5, image/png;q=0"},
+		{"Accept", "*/*;q=0.1"},
+		{"Accept-Encoding", "gzip;q=0.8, br, *;q=0"},
+		{"Accept-Language", "en-GB, en;q=0.7, fr;q=0.3"},
+	}
+	for _, tc := range []struct {
+		key    string
+		offers []string
+		want   string
+	}{
+		{"Accept", []string{"application/json", "text/html"}, "text/html"},
+		{"Accept", []string{"text/plain", "application/json"}, "application/json"},
+		{"Accept", []string{"text/plain; charset=utf-8", "application/xml"}, "text/plain; charset=utf-8"},
+		{"Accept", []string{"image/png", "application/xml"}, "application/xml"}, // */* is less specific than image/png's q=0.
+		{"Accept", []string{"image/png"}, ""},
+		{"accept-encoding", []string{"gzip", "br"}, "br"},
+		{"Accept-Encoding", []string{"deflate", "identity"}, ""}, // *;q=0 refuses identity too.
+		{"Accept-Language", []string{"fr", "en-US", "en-gb"}, "en-gb"},
+		{"Accept-Language", []string{"fr", "en-US"}, "en-US"},
+		{"Accept-Language", []string{"de", "fr-CA"}, "fr-CA"},
+		{"Accept-Language", []string{"de"}, ""},
+		{"Accept-Charset", []string{"utf-8", "latin1"}, "utf-8"}, // no header: anything goes.
+		{"Accept", nil, ""},
+	} {
+		if got := h.Negotiate(tc.key, tc.offers...); got != tc.want {
+			t.Errorf("Negotiate(%s, %q) = %q; want %q", tc.key, tc.offers, got, tc.want)
+		}
+	}
+	// identity is acceptable unless it's refused, even when the list doesn't mention it.
+	if got := (Headers{{"Accept-Encoding", "gzip;q=0.5"}}).Negotiate("Accept-Encoding", "br", "identity", "gzip"); got != "identity" {
+		t.Errorf("Negotiate = %q; want identity", got)
+	}
+	qs := (Headers{{"Accept", "a;q=0.5, b, c;q=bad, d;q=0.5;x=y, e;Q=1.5"}}).Qualities("Accept")
+	if want := []Quality{{"b", 1}, {"a", 0.5}, {"d", 0.5}, {"c", 0}, {"e", 0}}; !reflect.DeepEqual(qs, want) {
+		t.Errorf("Qualities() = %v; want %v", qs, want)
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	for _, tc := range []struct{ data, want string }{
+		{"", "text/plain; charset=utf-8"},
+		{"hello, world\n", "text/plain; charset=utf-8"},
+		{"\n  <!doctype html><title>x</title>", "text/html; charset=utf-8"},
+		{"<HTML>", "text/html; charset=utf-8"},
+		{"<p class=x>", "text/html; charset=utf-8"},
+		{"<pre>not one of the tags", "text/plain; charset=utf-8"},
+		{"plain text, then <script>", "text/plain; charset=utf-8"},
+		{"<?xml version=\"1.0\"?><a/>", "text/xml; charset=utf-8"},
+		{"\xEF\xBB\xBFbom", "text/plain; charset=utf-8"},
+		{"\xFF\xFEh\x00i\x00", "text/plain; charset=utf-16le"},
+		{"%PDF-1.7", "application/pdf"},
+		{"\x89PNG\r\n\x1A\n\x00\x00\x00\rIHDR", "image/png"},
+		{"GIF89a", "image/gif"},
+		{"\xFF\xD8\xFF\xE0", "image/jpeg"},
+		{"RIFF\x24\x00\x00\x00WEBPVP8 ", "image/webp"},
+		{"RIFF\x24\x00\x00\x00WAVEfmt ", "audio/wave"},
+		{"JUNK\x24\x00\x00\x00WAVEfmt ", "application/octet-stream"},
+		{"\x00\x00\x00\x18ftypmp42", "video/mp4"},
+		{"\x00\x00\x00\x18ftypisom", "video/mp4"},
+		{"\x1F\x8B\x08\x00", "application/gzip"},
+		{"PK\x03\x04", "application/zip"},
+		{"\x00asm\x01\x00\x00\x00", "application/wasm"},
+		{"text\x00with a NUL", "application/octet-stream"},
+		{"escape \x1B[1m is fine", "text/plain; charset=utf-8"},
+	} {
+		if got := DetectContentType([]byte(tc.data)); got != tc.want {
+			t.Errorf("DetectContentType(%q) = %q; want %q", tc.data, got, tc.want)
+		}
+	}
+	// only the first 512 bytes count.
+	if got := DetectContentType([]byte(strings.Repeat("a", 512) + "\x00")); got != "text/plain; charset=utf-8" {
+		t.Errorf("DetectContentType looked past 512 bytes: %q", got)
+	}
+
+	for ext, want := range map[string]string{".html": "text/html; charset=utf-8", "PNG": "image/png", ".JS": "text/javascript; charset=utf-8", ".woff2": "font/woff2", ".exe": "", "": ""} {
+		if got := TypeByExtension(ext); got != want {
+			t.Errorf("TypeByExtension(%q) = %q; want %q", ext, got, want)
+		}
+	}
+}
+
+// FuzzParseRequest checks that ParseRequest doesn't panic on any input, and that a request it accepts is written by
+// WriteTo in a form it reads back the same.
+func FuzzParseRequest(f *testing.F) {
+	for _, seed := range []string{
+		"GET / HTTP/1.1\r\nHost: example.com\r\n\r\n",
+		"POST /items?a=1 HTTP/1.1\r\nHost: x\r\nContent-Length: 5\r\n\r\nhello",
+		"POST / HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\n\r\n5;ext\r\nhello\r\n0\r\nX-Sum: 1\r\n\r\n",
+		"GET / HTTP/1.1\nHost: x\nX-Long: a\n b\n\n",
+		"GET  /  HTTP/1.1\r\nHost : x\r\n\r\n",
+		"GET / HTTP/1.1\r\n\r\n",
+		"GET / HTTP/1.1\r\nHost: x",
+		"\r\n",
+		"",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		r, err := ParseRequest(raw)
+		if err != nil {
+			return
+		}
+		if r.Method == "" || !strings.HasPrefix(r.Path, "/") || r.Headers.Get("Host") == "" {
+			t.Fatalf("ParseRequest(%q) = %+v; want a method, a path, and a Host", raw, r)
+		}
+		again, err := ParseRequest(r.String())
+		if err != nil {
+			t.Fatalf("ParseRequest(%q) = %+v, which writes as %q, which doesn't parse: %v", raw, r, r.String(), err)
+		}
+		if again.Method != r.Method || again.Path != r.Path || !reflect.DeepEqual(again.Headers, r.Headers) || again.Body != r.Body {
+			t.Fatalf("ParseRequest(%q) = %+v, which writes as %q, which parses as %+v", raw, r, r.String(), again)
+		}
+	})
+}
+
+// FuzzParseResponse checks that ParseResponse and ReadResponse don't panic on any input, and that a response
+// ParseResponse accepts is written by WriteTo in a form it reads back the same.
+func FuzzParseResponse(f *testing.F) {
+	for _, seed := range []string{
+		"HTTP/1.1 200 OK\r\nContent-Length: 2\r\n\r\nok",
+		"HTTP/1.1 204 No Content\r\n\r\n",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n2\r\nok\r\n0\r\nX-Sum: 1\r\n\r\n",
+		"HTTP/1.1 200\r\nX-Long: a,\r\n\tb\r\n\r\n",
+		"HTTP/1.0 404 Not Found\n\nnope",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\nffffffffffffffff\r\n",
+		"HTTP/1.1 2000 OK\r\n\r\n",
+		"",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, raw string) {
+		ReadResponse(bufio.NewReader(strings.NewReader(raw)))
+		r, err := ParseResponse(raw)
+		if err != nil {
+			return
+		}
+		if r.StatusCode < 100 || r.StatusCode > 999 {
+			t.Fatalf("ParseResponse(%q) has status %d", raw, r.StatusCode)
+		}
+		again, err := ParseResponse(r.String())
+		if err != nil {
+			t.Fatalf("ParseResponse(%q) = %+v, which writes as %q, which doesn't parse: %v", raw, r, r.String(), err)
+		}
+		if again.StatusCode != r.StatusCode || !reflect.DeepEqual(again.Headers, r.Headers) || again.Body != r.Body {
+			t.Fatalf("ParseResponse(%q) = %+v, which writes as %q, which parses as %+v", raw, r, r.String(), again)
+		}
+	})
+}
+
+// TestParseRegressions are inputs the fuzzers found, or that are close kin of them: numbers strconv takes and HTTP
+// doesn't.
+func TestParseRegressions(t *testing.T) {
+	for _, raw := range []string{
+		"HTTP/ 000 00\n\n",
+		"HTTP/1.1 099 Early\r\n\r\n",
+		"HTTP/1.1 -20 OK\r\n\r\n",
+		"HTTP/1.1 +20 OK\r\n\r\n",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n+5\r\nhello\r\n0\r\n\r\n",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n0x5\r\nhello\r\n0\r\n\r\n",
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n-0\r\n\r\n",
+	} {
+		if r, err := ParseResponse(raw); err == nil {
+			t.Errorf("ParseResponse(%q) = %+v; want an error", raw, r)
+		}
+	}
+	if r, err := ReadResponse(bufio.NewReader(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Length: +2\r\n\r\nok"))); err == nil {
+		t.Errorf("ReadResponse accepted Content-Length +2: %+v", r)
+	}
+}
+
+func TestParseAllocs(t *testing.T) {
+	raw := []byte(benchRequest)
+	if r, err := ParseRequestBytes(raw); err != nil || r.Path != "/search?q=httpwire&page=2" || len(r.Headers) != 7 {
+		t.Fatalf("ParseRequestBytes = %+v, %v", r, err)
+	}
+	for name, want := range map[string]float64{"ParseRequest": 1, "ParseRequestBytes": 2, "ParseResponse": 2} {
+		allocs := testing.AllocsPerRun(100, func() {
+			switch name {
+			case "ParseRequest":
+				ParseRequest(benchRequest)
+			case "ParseRequestBytes":
+				ParseRequestBytes(raw)
+			case "ParseResponse":
+				ParseResponse(benchResponse)
+			}
+		})
+		if allocs > want {
+			t.Errorf("%s allocates %v times; want %v", name, allocs, want)
+		}
+	}
+}
+
+// benchRequest and benchResponse are messages of a typical size, with the headers a browser or a server sends.
+const (
+	benchRequest = "GET /search?q=httpwire&page=2 HTTP/1.1\r\nHost: example.com\r\nUser-Agent: Mozilla/5.0 (X11; Linux x86_64)\r\n" +
+		"Accept: text/html,application/xhtml+xml;q=0.9,*/*;q=0.8\r\nAccept-Language: en-GB,en;q=0.5\r\n" +
+		"Accept-Encoding: gzip, deflate, br\r\nConnection: keep-alive\r\nCookie: session=abc123; theme=dark\r\n\r\n"
+	benchResponse = "HTTP/1.1 200 OK\r\nDate: Sun, 06 Nov 1994 08:49:37 GMT\r\nServer: httpwire\r\n" +
+		"Content-Type: text/html; charset=utf-8\r\nCache-Control: max-age=60\r\nEtag: \"abc\"\r\nContent-Length: 13\r\n\r\n<p>hello</p>\n"
+)
+
+func BenchmarkParseRequest(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchRequest)))
+	for range b.N {
+		if _, err := ParseRequest(benchRequest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseRequestBytes(b *testing.B) {
+	raw := []byte(benchRequest)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw)))
+	for range b.N {
+		if _, err := ParseRequestBytes(raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseResponse(b *testing.B) {
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchResponse)))
+	for range b.N {
+		if _, err := ParseResponse(benchResponse); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadResponse(b *testing.B) {
+	r := strings.NewReader(benchResponse)
+	br := bufio.NewReader(r)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(benchResponse)))
+	for range b.N {
+		r.Reset(benchResponse)
+		br.Reset(r)
+		if _, err := ReadResponse(br); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct{ method, target string }{
+		{"GET", "/"},
+		{"GET", "/a%20b?q=1"},
+		{"GET", "http://example.com/a?b"},
+		{"GET", "https://example.com"},
+		{"OPTIONS", "*"},
+		{"CONNECT", "example.com:443"},
+		{"CONNECT", "[::1]:8080"},
+		{"PURGE", "/cache"},
+	} {
+		r := &Request{Method: tc.method, Path: tc.target, Headers: Headers{{"Host", "example.com"}}}
+		if err := r.Validate(); err != nil {
+			t.Errorf("Validate(%s %s) = %v; want nil", tc.method, tc.target, err)
+		}
+	}
+
+	for _, tc := range []struct{ method, target string }{
+		{"GET", ""},
+		{"GET", "/a b"},
+		{"GET", "/a\r\nX-Injected: 1"},
+		{"GET", "/a#frag"},
+		{"GET", "/%zz"},
+		{"GET", "*"},
+		{"GET", "example.com/"},
+		{"GET", "http:///path"},
+		{"GET", "1http://example.com/"},
+		{"CONNECT", "/"},
+		{"CONNECT", "example.com"},
+		{"CONNECT", "example.com:https"},
+	} {
+		r := &Request{Method: tc.method, Path: tc.target, Headers: Headers{{"Host", "example.com"}}}
+		var te *TargetError
+		if err := r.Validate(); !errors.As(err, &te) || te.Target != tc.target {
+			t.Errorf("Validate(%s %q) = %v; want a *TargetError", tc.method, tc.target, err)
+		}
+	}
+
+	r := &Request{Method: "GE T", Path: "/", Headers: Headers{{"X Bad", "1"}, {"X-Split", "a\r\nX-Injected: 1"}, {"X-Tab", "a\tb"}}}
+	err := r.Validate()
+	var me *MethodError
+	if !errors.As(err, &me) || me.Method != "GE T" {
+		t.Errorf("Validate() = %v; want a *MethodError", err)
+	}
+	var keys []string
+	for _, e := range err.(interface{ Unwrap() []error }).Unwrap() {
+		if he := (*HeaderError)(nil); errors.As(e, &he) {
+			keys = append(keys, he.Key)
+		}
+	}
+	if want := []string{"X Bad", "X-Split", "Host"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("Validate() header errors are for %q; want %q", keys, want)
+	}
+}
+
+func TestAsTitleCommon(t *testing.T) {
+	for _, name := range []string{"content-type", "CONTENT-LENGTH", "x-forwarded-for", "www-authenticate", "x-custom-thing", strings.Repeat("a-", 40)} {
+		if got, want := AsTitle(name), newTitleCase(name); got != want {
+			t.Errorf("AsTitle(%q) = %q; want %q", name, got, want)
+		}
+	}
+	for name := range commonHeaders {
+		if !isTitleCase(name) {
+			t.Errorf("common header %q isn't in title case, so AsTitle would never return it", name)
+		}
+	}
+	if allocs := testing.AllocsPerRun(100, func() { AsTitle("content-type") }); allocs != 0 {
+		t.Errorf("AsTitle allocates %v times for a common header; want none", allocs)
+	}
+}
+
+// benchKeys are header names as clients write them: mostly title case already, some not.
+var benchKeys = []string{"Host", "user-agent", "Accept", "accept-encoding", "CONTENT-TYPE", "Content-Length", "x-request-id", "cookie"}
+
+func BenchmarkAsTitle(b *testing.B) {
+	b.ReportAllocs()
+	for range b.N {
+		for _, k := range benchKeys {
+			AsTitle(k)
+		}
+	}
+}
+
+func BenchmarkParseRequestLowercase(b *testing.B) {
+	// the headers as an HTTP/2 gateway might pass them on, all in lowercase; the request line as it was.
+	raw := strings.ToLower(benchRequest)
+	raw = strings.NewReplacer("get /", "GET /", "http/1.1", "HTTP/1.1").Replace(raw)
+	b.ReportAllocs()
+	b.SetBytes(int64(len(raw)))
+	for range b.N {
+		if _, err := ParseRequest(raw); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTrailers(t *testing.T) {
+	raw := "POST /upload HTTP/1.1\r\nHost: x\r\nTransfer-Encoding: chunked\r\nTrailer: X-Checksum, X-Count\r\n\r\n" +
+		"5\r\nhello\r\n0\r\nX-Checksum: abc\r\nx-count: 1\r\nContent-Length: 999\r\nX-Surprise: 1\r\n\r\n"
+	r, err := ParseRequest(raw)
+	if err != nil {
+		t.Fatalf("ParseRequest returned error: %v", err)
+	}
+	// Content-Length can't be a trailer, so it's dropped, and it's not merged into the headers either.
+	want := Headers{{"X-Checksum", "abc"}, {"X-Count", "1"}, {"X-Surprise", "1"}}
+	if !reflect.DeepEqual(r.Trailers, want) || r.Body != "hello" || r.Headers.Get("Content-Length") != "" || len(r.Warnings) != 2 {
+		t.Errorf("ParseRequest = trailers %v, body %q, warnings %q; want %v, and a warning each for Content-Length and X-Surprise", r.Trailers, r.Body, r.Warnings, want)
+	}
+	if _, err := (ParseOptions{Strict: true}).ParseRequest(raw); err == nil {
+		t.Error("strict ParseRequest accepted a forbidden trailer and an undeclared one")
+	}
+
+	// a request's trailers are written after its body, and read back.
+	again, err := ParseRequest(r.String())
+	if err != nil || !reflect.DeepEqual(again.Trailers, r.Trailers) {
+		t.Errorf("ParseRequest(String()) = %v, %v; want the trailers back", again.Trailers, err)
+	}
+
+	resp, err := (ParseOptions{Strict: true}).ReadResponse(bufio.NewReader(strings.NewReader(
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: Server-Timing\r\n\r\n0\r\nServer-Timing: db;dur=53\r\n\r\n")), "")
+	if err != nil || resp.Trailers.Get("Server-Timing") != "db;dur=53" || resp.Warnings != nil {
+		t.Errorf("strict ReadResponse = %+v, %v; want the declared trailer, with no warnings", resp, err)
+	}
+}
+
+func TestOldVersions(t *testing.T) {
+	// an HTTP/1.0 response can't be chunked: its body runs to the close, chunk sizes and all.
+	raw := "HTTP/1.0 200 OK\r\nTransfer-Encoding: chunked\r\nContent-Length: 1\r\n\r\n2\r\nok\r\n0\r\n\r\n"
+	resp, err := ReadResponse(bufio.NewReader(strings.NewReader(raw)))
+	if err != nil || resp.Proto != "HTTP/1.0" || resp.Body != "2\r\nok\r\n0\r\n\r\n" || len(resp.Warnings) != 1 {
+		t.Errorf("ReadResponse = %+v, %v; want the whole body, unchunked, with a warning", resp, err)
+	}
+	if r, err := ParseResponse(raw); err != nil || r.Body != "2\r\nok\r\n0" {
+		t.Errorf("ParseResponse = %+v, %v; want the body as it came", r, err)
+	}
+	if _, err := (ParseOptions{Strict: true}).ReadResponse(bufio.NewReader(strings.NewReader(raw)), ""); err == nil {
+		t.Error("strict ReadResponse accepted an HTTP/1.0 response with a Transfer-Encoding")
+	}
+	if r, err := ReadResponse(bufio.NewReader(strings.NewReader("HTTP/1.0 200 OK\r\nContent-Length: 2\r\n\r\nok, and more"))); err != nil || r.Body != "ok" {
+		t.Errorf("ReadResponse = %+v, %v; want a 1.0 response framed by its Content-Length", r, err)
+	}
+
+	// an HTTP/0.9 response is just the body, read as one only when asked to.
+	for _, read := range []func(string, ParseOptions) (*Response, error){
+		func(raw string, o ParseOptions) (*Response, error) { return o.ParseResponse(raw) },
+		func(raw string, o ParseOptions) (*Response, error) {
+			return o.ReadResponse(bufio.NewReader(strings.NewReader(raw)), "")
+		},
+	} {
+		body := "<html>hello</html>\n"
+		if resp, err := read(body, ParseOptions{}); err == nil {
+			t.Errorf("reading an HTTP/0.9 response without HTTP09 = %+v; want an error", resp)
+		}
+		resp, err := read(body, ParseOptions{HTTP09: true})
+		if err != nil || resp.Proto != "HTTP/0.9" || resp.StatusCode != 200 || resp.Body != body || resp.Headers != nil || len(resp.Warnings) != 1 {
+			t.Errorf("reading an HTTP/0.9 response = %+v, %v; want a 200 with the body", resp, err)
+			continue
+		}
+		if resp.String() != body {
+			t.Errorf("an HTTP/0.9 response writes as %q; want just the body", resp.String())
+		}
+		if _, err := read("SSH-2.0-OpenSSH_9.6\r\n", ParseOptions{Strict: true, HTTP09: true}); err == nil {
+			t.Error("a strict parse accepted an HTTP/0.9 response")
+		}
+	}
+}
+
+func TestStreamBody(t *testing.T) {
+	// each response's BodyReader stops where its framing says, so the next one is still on the reader after it.
+	r := bufio.NewReader(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello" +
+		"HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Count\r\n\r\n3\r\nabc\r\n3\r\ndef\r\n0\r\nX-Count: 2\r\n\r\n" +
+		"HTTP/1.1 204 No Content\r\n\r\n" +
+		"HTTP/1.1 200 OK\r\n\r\nto the end"))
+	for _, want := range []struct {
+		body, trailer string
+		length        int64
+	}{{"hello", "", 5}, {"abcdef", "2", -1}, {"", "", 0}, {"to the end", "", -1}} {
+		resp, err := ReadResponseHead(r, "")
+		if err != nil {
+			t.Fatalf("ReadResponseHead returned error: %v", err)
+		}
+		if resp.Body != "" || resp.ContentLength != want.length {
+			t.Errorf("ReadResponseHead = body %q, length %d; want no Body yet, and length %d", resp.Body, resp.ContentLength, want.length)
+		}
+		body, err := io.ReadAll(resp.BodyReader)
+		if err != nil || string(body) != want.body || resp.Trailers.Get("X-Count") != want.trailer {
+			t.Errorf("BodyReader = %q, %v, trailer %q; want %q, trailer %q", body, err, resp.Trailers.Get("X-Count"), want.body, want.trailer)
+		}
+	}
+
+	// a body cut short is an error, not the end of it.
+	resp, err := ReadResponseHead(bufio.NewReader(strings.NewReader("HTTP/1.1 200 OK\r\nContent-Length: 10\r\n\r\nhello")), "")
+	if err != nil {
+		t.Fatalf("ReadResponseHead returned error: %v", err)
+	}
+	if _, err := resp.BodyString(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("BodyString of a short body = %v; want io.ErrUnexpectedEOF", err)
+	}
+
+	// BodyString reads a BodyReader, as far as ContentLength, into Body, once.
+	req := &Request{Method: "PUT", Path: "/f", BodyReader: strings.NewReader("hello, world"), ContentLength: 5}
+	for range 2 {
+		if s, err := req.BodyString(); s != "hello" || err != nil || req.BodyReader != nil {
+			t.Errorf("BodyString = %q, %v; want %q, and no BodyReader after", s, err, "hello")
+		}
+	}
+
+	// WriteTo streams a BodyReader: as it is, or a chunk for each read, followed by the trailers.
+	req = &Request{Method: "PUT", Path: "/f", Headers: Headers{{"Content-Length", "5"}}, BodyReader: strings.NewReader("hello, world"), ContentLength: 5}
+	if got, want := req.String(), "PUT /f HTTP/1.1\r\nContent-Length: 5\r\n\r\nhello"; got != want {
+		t.Errorf("WriteTo = %q; want %q", got, want)
+	}
+	chunked := &Response{
+		StatusCode: 200, Headers: Headers{{"Transfer-Encoding", "chunked"}, {"Trailer", "X-Count"}}, Trailers: Headers{{"X-Count", "2"}},
+		BodyReader: io.MultiReader(strings.NewReader("abc"), strings.NewReader("def")),
+	}
+	if got, want := chunked.String(), "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\nTrailer: X-Count\r\n\r\n3\r\nabc\r\n3\r\ndef\r\n0\r\nX-Count: 2\r\n\r\n"; got != want {
+		t.Errorf("WriteTo = %q; want %q", got, want)
+	}
+}
+
+// errOnceReader fails its first read with err, and then reads r.
+type errOnceReader struct {
+	r   io.Reader
+	err error
+}
+
+func (e *errOnceReader) Read(p []byte) (int, error) {
+	if err := e.err; err != nil {
+		e.err = nil
+		return 0, err
+	}
+	return e.r.Read(p)
+}
+
+// failWriter takes limit bytes, and then fails, like a connection that's reset partway through a message.
+type failWriter struct {
+	limit, n, writes int
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (w *failWriter) Write(p []byte) (int, error) {
+	w.writes++
+	if w.n+len(p) > w.limit {
+		m := w.limit - w.n
+		w.n = w.limit
+		return m, errWriteFailed
+	}
+	w.n += len(p)
+	return len(p), nil
+}
+
+func TestWriteErrors(t *testing.T) {
+	chunked := &Response{StatusCode: 200, Headers: Headers{{"Transfer-Encoding", "chunked"}}, Body: "hello", Trailers: Headers{{"X-Count", "1"}}}
+	messages := []interface {
+		String() string
+	}{
+		&Request{Method: "POST", Path: "/", Headers: Headers{{"Host", "x"}, {"Content-Length", "5"}}, Body: "hello"},
+		&Request{Method: "POST", Path: "/", Headers: Headers{{"Transfer-Encoding", "chunked"}}, Body: "hello"},
+		&Response{StatusCode: 200, Headers: Headers{{"Content-Length", "5"}}, Body: "hello"},
+		chunked,
+		&Response{Proto: "HTTP/0.9", Body: "hello"},
+	}
+	for _, m := range messages {
+		full := m.String()
+		for _, o := range []WriteOptions{{}, {Buffered: true}} {
+			// cut off at every byte, the error comes back, with the count of what got through.
+			for limit := range len(full) {
+				w := &failWriter{limit: limit}
+				var n int64
+				var err error
+				switch m := m.(type) {
+				case *Request:
+					n, err = o.WriteRequest(w, m)
+				case *Response:
+					n, err = o.WriteResponse(w, m)
+				}
+				if !errors.Is(err, errWriteFailed) || n != int64(limit) {
+					t.Errorf("%+v: writing %q cut off after %d bytes = %d, %v; want %d, %v", o, full, limit, n, err, limit, errWriteFailed)
+				}
+			}
+		}
+	}
+
+	// buffered, a message that fits in the buffer goes in one write.
+	w := &failWriter{limit: 1 << 20}
+	if n, err := (WriteOptions{Buffered: true}).WriteResponse(w, chunked); err != nil || n != int64(len(chunked.String())) || w.writes != 1 {
+		t.Errorf("buffered WriteResponse = %d, %v, in %d writes; want %d, nil, in 1", n, err, w.writes, len(chunked.String()))
+	}
+	w = &failWriter{limit: 1 << 20}
+	if chunked.WriteTo(w); w.writes == 1 {
+		t.Error("WriteTo wrote a chunked response in one write; want it unbuffered")
+	}
+}
+
+func TestRequestBuilder(t *testing.T) {
+	r, err := NewRequestBuilder("POST", "http://api.example.com/items?dry_run=1").
+		Header("authorization", "Bearer xyz").
+		Query("tag", "a&b c").
+		JSONBody(map[string]int{"count": 2}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	want := &Request{
+		Method: "POST", Path: "/items?dry_run=1&tag=a%26b+c", Body: `{"count":2}`,
+		Headers: Headers{{"Host", "api.example.com"}, {"Authorization", "Bearer xyz"}, {"Content-Type", "application/json"}, {"Content-Length", "11"}},
+	}
+	if !reflect.DeepEqual(r, want) {
+		t.Errorf("Build = %+v; want %+v", r, want)
+	}
+
+	// a path needs a Host of its own; a Content-Type given replaces the body's.
+	r, err = NewRequestBuilder("PUT", "/form").
+		Header("Host", "x").
+		Header("Content-Type", "application/x-www-form-urlencoded; charset=utf-8").
+		FormBody(map[string][]string{"b": {"2"}, "a": {"1 2"}}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+	if got, want := r.String(), "PUT /form HTTP/1.1\r\nHost: x\r\nContent-Type: application/x-www-form-urlencoded; charset=utf-8\r\nContent-Length: 9\r\n\r\na=1+2&b=2\r\n"; got != want {
+		t.Errorf("Build = %q; want %q", got, want)
+	}
+
+	// a Content-Length given is replaced by the body's, not added to.
+	r, err = NewRequestBuilder("POST", "http://x/").Header("Content-Length", "100").Header("content-length", "3").JSONBody("hi").Build()
+	if err != nil || !reflect.DeepEqual(r.Headers.Values("Content-Length"), []string{"4"}) {
+		t.Errorf("Build = %+v, %v; want one Content-Length: 4", r, err)
+	}
+
+	// errors wait for Build.
+	for _, b := range []*RequestBuilder{
+		NewRequestBuilder("GET", "http://%zz/"),
+		NewRequestBuilder("GET", "/no-host"),
+		NewRequestBuilder("GET /", "http://x/"),
+		NewRequestBuilder("POST", "http://x/").JSONBody(func() {}),
+	} {
+		if r, err := b.Build(); err == nil {
+			t.Errorf("Build = %v; want an error", r)
+		}
+	}
+
+	// NewRequest adds only the headers it means to.
+	r, err = NewRequest("GET", "/", "example.com", "")
+	if err != nil || !reflect.DeepEqual(r.Headers, Headers{{"Host", "example.com"}}) {
+		t.Errorf("NewRequest = %+v, %v; want just a Host header", r, err)
+	}
+}
+
+func TestReasonPhrase(t *testing.T) {
+	// the reason phrase is kept as it came, even empty, or not the standard one, and with no warning.
+	for raw, want := range map[string]string{
+		"HTTP/1.1 200 OK\r\n\r\n":             "OK",
+		"HTTP/1.1 200 \r\n\r\n":               "",
+		"HTTP/1.1 404 Nothing Here\r\n\r\n":   "Nothing Here",
+		"HTTP/1.1 299 Fine, thanks  \r\n\r\n": "Fine, thanks  ",
+	} {
+		resp, err := (ParseOptions{Strict: true}).ParseResponse(raw)
+		if err != nil || resp.Reason != want || resp.Warnings != nil {
+			t.Errorf("ParseResponse(%q) = %+v, %v; want reason %q", raw, resp, err, want)
+		}
+	}
+	for _, raw := range []string{"HTTP/1.1 600 Too Far\r\n\r\n", "HTTP/1.1 200 O\x00K\r\n\r\n"} {
+		if resp, err := ParseResponse(raw); err != nil || len(resp.Warnings) != 1 {
+			t.Errorf("ParseResponse(%q) = %+v, %v; want a warning", raw, resp, err)
+		}
+		if _, err := (ParseOptions{Strict: true}).ParseResponse(raw); err == nil {
+			t.Errorf("strict ParseResponse(%q) succeeded; want an error", raw)
+		}
+	}
+
+	// WriteTo writes a reason of its own, or the standard one.
+	if got, want := (&Response{StatusCode: 200}).WithReason("Fine").String(), "HTTP/1.1 200 Fine\r\n\r\n\r\n"; got != want {
+		t.Errorf("WriteTo = %q; want %q", got, want)
+	}
+	if got, want := (&Response{StatusCode: 404}).String(), "HTTP/1.1 404 Not Found\r\n\r\n\r\n"; got != want {
+		t.Errorf("WriteTo = %q; want %q", got, want)
+	}
+	hr, err := ToStdResponse((&Response{StatusCode: 200}).WithReason("Fine"))
+	if err != nil || hr.Status != "200 Fine" {
+		t.Errorf("ToStdResponse = %v, %v; want status %q", hr, err, "200 Fine")
+	}
+
+	// Validate checks the status line and the headers.
+	if err := (&Response{StatusCode: 200, Reason: "Fine", Headers: Headers{{"X-Ok", "1"}}}).Validate(); err != nil {
+		t.Errorf("Validate = %v; want nil", err)
+	}
+	for _, resp := range []*Response{{StatusCode: 99}, {StatusCode: 1000}, {StatusCode: 200, Reason: "OK\r\nX-Injected: 1"}} {
+		var se *StatusError
+		if err := resp.Validate(); !errors.As(err, &se) {
+			t.Errorf("Validate(%d %q) = %v; want a *StatusError", resp.StatusCode, resp.Reason, err)
+		}
+	}
+	var he *HeaderError
+	if err := (&Response{StatusCode: 200, Headers: Headers{{"Bad Key", "1"}}}).Validate(); !errors.As(err, &he) {
+		t.Errorf("Validate = %v; want a *HeaderError", err)
+	}
+}
+
+func TestChunkedLimits(t *testing.T) {
+	o := ParseOptions{MaxLineLength: 64, MaxHeaderBytes: 200, MaxHeaderCount: 4}
+	trailers := func(n int) string {
+		var b strings.Builder
+		for i := range n {
+			fmt.Fprintf(&b, "X-T%d: 1\r\n", i)
+		}
+		return b.String()
+	}
+	for _, tt := range []struct {
+		body, limit string
+	}{
+		{"5;" + strings.Repeat("x", 100) + "\r\nhello\r\n0\r\n\r\n", "MaxLineLength"},            // a size line's extension
+		{"5\r\nhello\r\n0\r\nX-Long: " + strings.Repeat("x", 100) + "\r\n\r\n", "MaxLineLength"}, // a trailer
+		{"0\r\n" + trailers(5) + "\r\n", "MaxHeaderCount"},
+		{"0\r\n" + strings.Repeat("X-Big: "+strings.Repeat("x", 50)+"\r\n", 4) + "\r\n", "MaxHeaderBytes"},
+	} {
+		_, err := io.ReadAll(o.NewChunkedReader(strings.NewReader(tt.body)))
+		var le *LimitError
+		if !errors.As(err, &le) || le.Limit != tt.limit {
+			t.Errorf("reading %.40q... = %v; want a *LimitError for %s", tt.body, err, tt.limit)
+		}
+		raw := "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n" + tt.body
+		if _, err := o.ReadResponse(bufio.NewReader(strings.NewReader(raw)), ""); !errors.As(err, &le) {
+			t.Errorf("ReadResponse(%.40q...) = %v; want a *LimitError", raw, err)
+		}
+		if _, err := o.ParseResponse(raw); !errors.As(err, &le) {
+			t.Errorf("ParseResponse(%.40q...) = %v; want a *LimitError", raw, err)
+		}
+	}
+
+	// up to the limits is fine; a size line that never ends stops at the limit rather than reading on.
+	if b, err := io.ReadAll(o.NewChunkedReader(strings.NewReader("5\r\nhello\r\n0\r\n" + trailers(4) + "\r\n"))); err != nil || string(b) != "hello" {
+		t.Errorf("reading a body at the limits = %q, %v; want %q", b, err, "hello")
+	}
+	endless := io.MultiReader(strings.NewReader("5;"), neverEnding('x'))
+	var le *LimitError
+	if _, err := io.ReadAll(o.NewChunkedReader(endless)); !errors.As(err, &le) {
+		t.Errorf("reading an endless size line = %v; want a *LimitError", err)
+	}
+}
+
+// neverEnding is an endless stream of one byte.
+type neverEnding byte
+
+func (b neverEnding) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = byte(b)
+	}
+	return len(p), nil
+}
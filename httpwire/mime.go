@@ -0,0 +1,139 @@
+package httpwire
+
+import (
+	"bytes"
+	"strings"
+)
+
+/* design note: a response needs a Content-Type, and a server handing out files has two ways to find one: the file's
+   name, and its first few bytes. TypeByExtension looks the extension up in a table of the types a web server
+   actually serves; unlike the mime package's, it doesn't read /etc/mime.types, so it says the same thing on every
+   machine. DetectContentType looks at the bytes, by a subset of the WHATWG MIME sniffing algorithm
+   (https://mimesniff.spec.whatwg.org), the one browsers use:
+
+	1. HTML, by its first tag, like <!DOCTYPE html or <html, in any case, after any whitespace, and followed by a
+	   space or a >. then XML, by <?xml.
+	2. a byte order mark: it's text, in the encoding it marks.
+	3. a file format's magic number: PDF, PostScript, the common image, audio, and video formats, fonts, archives,
+	   and WebAssembly.
+	4. otherwise, text if there's no byte that's never in text (a control character other than tab, newline,
+	   form feed, carriage return, and escape), and application/octet-stream if there is.
+
+   only the first 512 bytes are looked at, which is what the algorithm reads, and enough for any of the patterns.
+   it never says a type that would let a browser run script the server didn't mean to serve as HTML: text that
+   merely mentions <script further in is text/plain.
+*/
+
+// sniffLen is how many bytes DetectContentType looks at.
+const sniffLen = 512
+
+// DetectContentType returns the Content-Type of data, by its first 512 bytes: "application/octet-stream" if it
+// can't tell, and "text/plain; charset=utf-8" for text.
+func DetectContentType(data []byte) string {
+	if len(data) > sniffLen {
+		data = data[:sniffLen]
+	}
+	if ct := sniffMarkup(data); ct != "" {
+		return ct
+	}
+	for _, sig := range signatures {
+		if len(data) >= sig.offset+len(sig.magic) && bytes.HasPrefix(data[sig.offset:], sig.magic) &&
+			(sig.also == nil || bytes.HasPrefix(data, sig.also)) {
+			return sig.contentType
+		}
+	}
+	for _, c := range data {
+		if c <= 0x08 || c == 0x0B || 0x0E <= c && c <= 0x1A || 0x1C <= c && c <= 0x1F {
+			return "application/octet-stream"
+		}
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// htmlTags are the tags that start an HTML document, as the sniffing algorithm has them.
+var htmlTags = []string{
+	"<!DOCTYPE HTML", "<HTML", "<HEAD", "<SCRIPT", "<IFRAME", "<H1", "<DIV", "<FONT", "<TABLE", "<A", "<STYLE",
+	"<TITLE", "<B", "<BODY", "<BR", "<P", "<!--",
+}
+
+// sniffMarkup returns the type of data if it starts, after whitespace, like HTML or XML, or else "".
+func sniffMarkup(data []byte) string {
+	data = bytes.TrimLeft(data, "\t\n\x0C\r ")
+	for _, tag := range htmlTags {
+		if len(data) > len(tag) && strings.EqualFold(string(data[:len(tag)]), tag) {
+			if c := data[len(tag)]; c == ' ' || c == '>' {
+				return "text/html; charset=utf-8"
+			}
+		}
+	}
+	if bytes.HasPrefix(data, []byte("<?xml")) {
+		return "text/xml; charset=utf-8"
+	}
+	return ""
+}
+
+// signature is a file format's magic number: magic at offset, and also at the start, if there's more to it.
+type signature struct {
+	magic       []byte
+	offset      int
+	also        []byte
+	contentType string
+}
+
+// signatures are the magic numbers DetectContentType knows, checked in order.
+var signatures = []signature{
+	{magic: []byte("\xFE\xFF"), contentType: "text/plain; charset=utf-16be"},
+	{magic: []byte("\xFF\xFE"), contentType: "text/plain; charset=utf-16le"},
+	{magic: []byte("\xEF\xBB\xBF"), contentType: "text/plain; charset=utf-8"},
+	{magic: []byte("%PDF-"), contentType: "application/pdf"},
+	{magic: []byte("%!PS-Adobe-"), contentType: "application/postscript"},
+	{magic: []byte("GIF87a"), contentType: "image/gif"},
+	{magic: []byte("GIF89a"), contentType: "image/gif"},
+	{magic: []byte("\x89PNG\r\n\x1A\n"), contentType: "image/png"},
+	{magic: []byte("\xFF\xD8\xFF"), contentType: "image/jpeg"},
+	{magic: []byte("BM"), contentType: "image/bmp"},
+	{magic: []byte("\x00\x00\x01\x00"), contentType: "image/x-icon"},
+	{magic: []byte("WEBPVP"), offset: 8, also: []byte("RIFF"), contentType: "image/webp"},
+	{magic: []byte("WAVE"), offset: 8, also: []byte("RIFF"), contentType: "audio/wave"},
+	{magic: []byte("AVI "), offset: 8, also: []byte("RIFF"), contentType: "video/avi"},
+	{magic: []byte("ID3"), contentType: "audio/mpeg"},
+	{magic: []byte("OggS\x00"), contentType: "application/ogg"},
+	{magic: []byte("fLaC"), contentType: "audio/flac"},
+	{magic: []byte("ftypmp4"), offset: 4, contentType: "video/mp4"},
+	{magic: []byte("ftypisom"), offset: 4, contentType: "video/mp4"},
+	{magic: []byte("\x1A\x45\xDF\xA3"), contentType: "video/webm"},
+	{magic: []byte("wOFF"), contentType: "font/woff"},
+	{magic: []byte("wOF2"), contentType: "font/woff2"},
+	{magic: []byte("\x00\x01\x00\x00"), contentType: "font/ttf"},
+	{magic: []byte("OTTO"), contentType: "font/otf"},
+	{magic: []byte("\x1F\x8B\x08"), contentType: "application/gzip"},
+	{magic: []byte("PK\x03\x04"), contentType: "application/zip"},
+	{magic: []byte("Rar!\x1A\x07"), contentType: "application/vnd.rar"},
+	{magic: []byte("\x00asm"), contentType: "application/wasm"},
+}
+
+// TypeByExtension returns the Content-Type of a file named with ext, like ".html" or "png", in any case, or "" if
+// it isn't one it knows.
+func TypeByExtension(ext string) string {
+	return extensionTypes[strings.ToLower(strings.TrimPrefix(ext, "."))]
+}
+
+// extensionTypes are the types of the files a web server usually serves, by extension. text is UTF-8.
+var extensionTypes = map[string]string{
+	"html": "text/html; charset=utf-8", "htm": "text/html; charset=utf-8", "css": "text/css; charset=utf-8",
+	"js": "text/javascript; charset=utf-8", "mjs": "text/javascript; charset=utf-8",
+	"json": "application/json", "map": "application/json", "xml": "text/xml; charset=utf-8",
+	"txt": "text/plain; charset=utf-8", "md": "text/markdown; charset=utf-8", "csv": "text/csv; charset=utf-8",
+	"webmanifest": "application/manifest+json", "wasm": "application/wasm",
+
+	"png": "image/png", "jpg": "image/jpeg", "jpeg": "image/jpeg", "gif": "image/gif", "webp": "image/webp",
+	"avif": "image/avif", "svg": "image/svg+xml", "ico": "image/x-icon", "bmp": "image/bmp",
+
+	"woff": "font/woff", "woff2": "font/woff2", "ttf": "font/ttf", "otf": "font/otf",
+
+	"mp3": "audio/mpeg", "wav": "audio/wave", "ogg": "application/ogg", "flac": "audio/flac",
+	"mp4": "video/mp4", "webm": "video/webm", "avi": "video/avi",
+
+	"pdf": "application/pdf", "ps": "application/postscript", "zip": "application/zip", "gz": "application/gzip",
+	"tar": "application/x-tar", "rar": "application/vnd.rar",
+}
@@ -0,0 +1,148 @@
+package httpwire
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/* design note: Accept, Accept-Encoding, and Accept-Language are lists of what the client can take, each with a
+   q-value, a weight from 0 to 1, saying how much it would like it (RFC 9110, section 12.4.2):
+
+	Accept: text/html, application/json;q=0.9, text/*;q=0.1
+
+   the server has a list of its own, of what it can send, in the order it prefers them. Negotiate picks from the
+   server's list: each offer gets the q-value of the most specific element in the client's list that matches it, and
+   the offer with the highest wins, the server's order breaking ties. an offer nothing matches, or whose best match
+   has q=0, isn't acceptable at all. how specific a match is depends on the header:
+
+	Accept           text/html beats text/*, which beats any type; parameters other than q are ignored
+	Accept-Encoding  gzip beats *; identity is acceptable unless it's refused, by name or with *;q=0
+	Accept-Language  en-GB beats en beats *: a range matches a tag that starts with it, then a -
+
+   no header at all means anything will do, so the first offer wins. if nothing is acceptable, Negotiate returns "",
+   and the server can send its default anyway or answer 406 Not Acceptable, whichever suits.
+*/
+
+// Quality is an element of an Accept-style list, and its q-value: "gzip;q=0.8" is {"gzip", 0.8}.
+type Quality struct {
+	Value string
+	Q     float64
+}
+
+// Qualities returns the elements of the list in the headers named key, with their q-values, highest first, and
+// in the order they were sent where they're equal. an element without a q is 1, and one with a q that doesn't
+// parse is 0, since it's not clear the client wants it.
+func (h Headers) Qualities(key string) []Quality {
+	var qs []Quality
+	for _, e := range h.List(key) {
+		value, params, _ := strings.Cut(e, ";")
+		q := Quality{Value: strings.TrimSpace(value), Q: 1}
+		for _, p := range strings.Split(params, ";") {
+			k, v, _ := strings.Cut(p, "=")
+			if strings.EqualFold(strings.TrimSpace(k), "q") {
+				q.Q = parseQ(strings.TrimSpace(v))
+				break
+			}
+		}
+		qs = append(qs, q)
+	}
+	slices.SortStableFunc(qs, func(a, b Quality) int {
+		switch {
+		case a.Q > b.Q:
+			return -1
+		case a.Q < b.Q:
+			return 1
+		}
+		return 0
+	})
+	return qs
+}
+
+// parseQ parses a q-value, which is between 0 and 1, with at most three decimal places. it's 0 if it isn't one.
+func parseQ(v string) float64 {
+	q, err := strconv.ParseFloat(v, 64)
+	if err != nil || q < 0 || q > 1 || len(v) > 5 {
+		return 0
+	}
+	return q
+}
+
+// Negotiate returns the best of offers for the client, by the list in the headers named key: Accept,
+// Accept-Encoding, or Accept-Language. offers are in the server's order of preference, which breaks ties. it's ""
+// if none of them is acceptable, and offers[0] if there's no such header.
+func (h Headers) Negotiate(key string, offers ...string) string {
+	if len(offers) == 0 {
+		return ""
+	}
+	if h.Values(key) == nil {
+		return offers[0]
+	}
+	accepted := h.Qualities(key)
+	match := matchMediaRange
+	switch {
+	case strings.EqualFold(key, "Accept-Encoding"):
+		match = matchCoding
+	case strings.EqualFold(key, "Accept-Language"):
+		match = matchLanguage
+	}
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, a := range accepted {
+			if s := match(a.Value, offer); s > specificity {
+				q, specificity = a.Q, s
+			}
+		}
+		if specificity < 0 && strings.EqualFold(key, "Accept-Encoding") && strings.EqualFold(offer, "identity") {
+			q = 1 // identity is acceptable unless the client says otherwise.
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// matchMediaRange says how specifically the media range r, like text/*, matches the media type offer: 2 for
+// type/subtype, 1 for type/*, 0 for */*, and -1 if it doesn't.
+func matchMediaRange(r, offer string) int {
+	offer, _, _ = strings.Cut(offer, ";")
+	rType, rSub, _ := strings.Cut(r, "/")
+	oType, oSub, _ := strings.Cut(strings.TrimSpace(offer), "/")
+	switch {
+	case rType == "*" && rSub == "*":
+		return 0
+	case !strings.EqualFold(rType, oType):
+		return -1
+	case rSub == "*":
+		return 1
+	case strings.EqualFold(rSub, oSub):
+		return 2
+	}
+	return -1
+}
+
+// matchCoding says how specifically the content coding c matches offer: 1 for the same coding, 0 for *, and -1
+// if it doesn't.
+func matchCoding(c, offer string) int {
+	switch {
+	case strings.EqualFold(c, offer):
+		return 1
+	case c == "*":
+		return 0
+	}
+	return -1
+}
+
+// matchLanguage says how specifically the language range r matches the tag offer, which is how many subtags r has,
+// so en-GB is 2, en is 1, and * is 0. it's -1 if it doesn't match.
+func matchLanguage(r, offer string) int {
+	switch {
+	case r == "*":
+		return 0
+	case strings.EqualFold(r, offer), len(offer) > len(r) && offer[len(r)] == '-' && strings.EqualFold(r, offer[:len(r)]):
+		return strings.Count(r, "-") + 1
+	}
+	return -1
+}
@@ -0,0 +1,607 @@
+package httpwire
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+/* design note: a message's head has no length up front: it ends at the first empty line, however long that takes.
+   a parser that keeps reading until then can be made to hold as much as a peer cares to send, by a single endless
+   line or a million short headers. so the head is read against limits, and a message over one is refused as soon
+   as it's over, having read no more than the limit, rather than once it's all been read.
+
+   the defaults are generous for real traffic and stingy for abuse: browsers keep request lines to a few KB, and
+   cookies are the only headers that get big. Apache allows 8KB lines and 100 headers; net/http allows 1MB for the
+   whole head. a server answers a LimitError with its StatusCode, 431 Request Header Fields Too Large, or 414 URI
+   Too Long when the request line is what's too long, and closes the connection, since it can't know where the
+   rest of the message ends.
+*/
+
+/* design note: the RFCs are strict about what a sender may send, and leave a recipient some room in what it
+   accepts. lines end in CRLF, but a recipient MAY take a bare LF (RFC 9112, section 2.2); a header name is a token,
+   with nothing between it and the colon (section 5.1). real peers get these wrong, so by default the parsers let
+   them slide, and say so: each thing they let slide is a line in the message's Warnings, which is a good way to see
+   how sloppy a client or server is.
+
+   ParseOptions.Strict refuses them instead. that's not pedantry: whitespace before the colon is how request
+   smuggling gets started, when a proxy and the server behind it disagree about whether "Content-Length : 5" is a
+   Content-Length, and RFC 9112 says a server MUST reject it. a server talking to the open internet wants Strict.
+   a few things are refused either way, because no reading of them is safe: a header line with no colon, an empty
+   name, and a NUL or a lone CR in a value.
+*/
+
+/* design note: parsing a head used to split it into a []string of lines, then each header line into a key and a
+   value, allocating the slice of lines, the Fields of the request line, and the Headers as they grew, which is
+   most of what it cost. now the parser walks the message by index instead: a line is a substring of the input,
+   and so is everything cut from it, so the strings in a Request or Response share the input's memory rather than
+   copying it. a first pass counts the header lines, so the Headers are allocated once, at the right size. a
+   message in a []byte is copied into a string once, whole, by ParseRequestBytes, rather than field by field.
+
+   what's left is what has to be: the Headers, the Response (ParseResponse returns a pointer), and, reading from a
+   connection, a copy of each line, since the bufio.Reader's buffer is reused. for the browser-sized messages in
+   the benchmarks (go test -bench . -benchmem):
+
+	                  before        after
+	ParseRequest      10 allocs     1 alloc
+	ParseRequestBytes               2 allocs
+	ParseResponse      9 allocs     2 allocs
+	ReadResponse      25 allocs    10 allocs
+
+   a title-case header name costs nothing; another is copied by AsTitle. a warning costs a string, and a chunked
+   body is reassembled into a new one.
+*/
+
+// the limits ParseOptions uses when its own are zero.
+const (
+	DefaultMaxLineLength  = 8 << 10
+	DefaultMaxHeaderBytes = 1 << 20
+	DefaultMaxHeaderCount = 100
+)
+
+// ParseOptions configures parsing. the zero value uses the default limits, and is lenient.
+type ParseOptions struct {
+	MaxLineLength  int // the longest start line or header line, without its CRLF
+	MaxHeaderBytes int // the most bytes of header lines in all, CRLFs included
+	MaxHeaderCount int // the most header lines
+
+	// HTTP09 reads a response that doesn't start with a status line as HTTP/0.9: nothing but a body, to the end of
+	// the input. without it, that's an error, since it's more likely garbage, or a response that isn't framed right.
+	HTTP09 bool
+
+	// Strict rejects messages the RFCs say a recipient may tolerate: bare-LF line endings, whitespace before a
+	// header's colon, header names and methods that aren't tokens, and start lines with extra whitespace. without
+	// it, they're accepted, and noted in the message's Warnings.
+	Strict bool
+}
+
+// LimitError is returned when a message's head is bigger than one of ParseOptions' limits allows.
+type LimitError struct {
+	Limit string // the ParseOptions field: "MaxLineLength", "MaxHeaderBytes", or "MaxHeaderCount"
+	Max   int
+
+	requestLine bool // it's the request line that's too long
+}
+
+func (e *LimitError) Error() string {
+	if e.requestLine {
+		return fmt.Sprintf("request line longer than %s (%d bytes)", e.Limit, e.Max)
+	}
+	return fmt.Sprintf("message head exceeds %s (%d)", e.Limit, e.Max)
+}
+
+// StatusCode returns the status to answer a request that's over the limit with.
+func (e *LimitError) StatusCode() int {
+	if e.requestLine {
+		return 414 // URI Too Long
+	}
+	return 431 // Request Header Fields Too Large
+}
+
+func (o ParseOptions) maxLineLength() int  { return or(o.MaxLineLength, DefaultMaxLineLength) }
+func (o ParseOptions) maxHeaderBytes() int { return or(o.MaxHeaderBytes, DefaultMaxHeaderBytes) }
+func (o ParseOptions) maxHeaderCount() int { return or(o.MaxHeaderCount, DefaultMaxHeaderCount) }
+
+func or(n, def int) int {
+	if n > 0 {
+		return n
+	}
+	return def
+}
+
+// ParseRequest parses raw, a whole HTTP/1.1 request, which must have a Host header, with the default ParseOptions.
+// header keys are put in title case.
+func ParseRequest(raw string) (Request, error) {
+	return ParseOptions{}.ParseRequest(raw)
+}
+
+// ParseRequest parses raw, a whole HTTP/1.1 request, as o says.
+func (o ParseOptions) ParseRequest(raw string) (r Request, err error) {
+	// request has three parts:
+	// 1. Request line
+	// 2. Headers
+	// 3. Body (optional)
+	p := &parser{o: o, kind: "request"}
+	start, rest, err := p.startLine(raw)
+	if err != nil {
+		return Request{}, err
+	}
+	if r.Method, r.Path, err = p.requestLine(start); err != nil {
+		return Request{}, err
+	}
+	var body string
+	if r.Headers, body, err = p.headers(rest); err != nil {
+		return Request{}, err
+	}
+	if r.Headers.Get("Host") == "" {
+		return Request{}, fmt.Errorf("malformed request: missing Host header")
+	}
+
+	if r.Headers.Chunked() {
+		b, trailers, err := readChunked(p.o, bufio.NewReader(strings.NewReader(body)))
+		if err != nil {
+			return Request{}, fmt.Errorf("malformed request: chunked body: %w", err)
+		}
+		if r.Trailers, err = p.trailers(r.Headers, trailers); err != nil {
+			return Request{}, err
+		}
+		body = string(b)
+	} else {
+		body = strings.TrimSuffix(body, "\r\n") // WriteTo ends the body with one.
+	}
+	r.Body, r.Warnings = body, p.warnings
+	return r, nil
+}
+
+// ParseRequestBytes is ParseRequest for a message in a []byte, like one read off a connection. it copies raw once,
+// into a string the request's fields are all slices of, rather than each field on its own, so it's safe to reuse
+// raw when it returns.
+func ParseRequestBytes(raw []byte) (Request, error) {
+	return ParseOptions{}.ParseRequest(string(raw))
+}
+
+// ParseResponseBytes is ParseResponse for a message in a []byte; see ParseRequestBytes.
+func ParseResponseBytes(raw []byte) (*Response, error) {
+	return ParseOptions{}.ParseResponse(string(raw))
+}
+
+// ParseResponse parses the given HTTP/1.1 response string into the Response, with the default ParseOptions. It
+// returns an error if the Response is invalid,
+// - not a valid integer
+// - invalid status code
+// - missing status text
+// - invalid headers
+// it doesn't handle html-encoding, etc.
+func ParseResponse(raw string) (*Response, error) {
+	return ParseOptions{}.ParseResponse(raw)
+}
+
+// ParseResponse parses raw, a whole HTTP/1.1 response, as o says.
+func (o ParseOptions) ParseResponse(raw string) (r *Response, err error) {
+	p := &parser{o: o, kind: "response"}
+	for n := emptyLine(raw); n > 0; n = emptyLine(raw) {
+		if err := p.skipEmpty(n); err != nil {
+			return nil, err
+		}
+		raw = raw[n:]
+	}
+	if o.HTTP09 && raw != "" && !strings.HasPrefix(raw, "HTTP/") {
+		return p.http09(raw)
+	}
+	start, rest, err := p.startLine(raw)
+	if err != nil {
+		return nil, err
+	}
+	r = new(Response)
+	if r.Proto, r.StatusCode, r.Reason, err = p.statusLine(start); err != nil {
+		return nil, err
+	}
+	var body string
+	if r.Headers, body, err = p.headers(rest); err != nil {
+		return nil, err
+	}
+	if err := p.http10Framing(r); err != nil {
+		return nil, err
+	}
+	if r.Headers.Chunked() && r.Proto != "HTTP/1.0" {
+		// the chunk sizes and CRLFs are framing, not content: take them off to get the body that was sent.
+		b, trailers, err := readChunked(p.o, bufio.NewReader(strings.NewReader(body)))
+		if err != nil {
+			return nil, fmt.Errorf("malformed response: chunked body: %w", err)
+		}
+		if r.Trailers, err = p.trailers(r.Headers, trailers); err != nil {
+			return nil, err
+		}
+		r.Body = string(b)
+	} else {
+		r.Body = strings.TrimSpace(body)
+	}
+	r.Warnings = p.warnings
+	return r, nil
+}
+
+// http09 returns body as an HTTP/0.9 response, which is nothing but a body: no status line, and no headers. it's
+// a 200, since a server that had anything else to say would have had to say it in HTML.
+func (p *parser) http09(body string) (*Response, error) {
+	if err := p.lax("no status line: an HTTP/0.9 response, just a body"); err != nil {
+		return nil, err
+	}
+	return &Response{Proto: "HTTP/0.9", StatusCode: 200, Body: body, Warnings: p.warnings}, nil
+}
+
+// emptyLine returns the length of the empty line at the start of s, a CRLF or a bare LF, or 0 if there isn't one.
+func emptyLine(s string) int {
+	switch {
+	case strings.HasPrefix(s, "\r\n"):
+		return 2
+	case strings.HasPrefix(s, "\n"):
+		return 1
+	}
+	return 0
+}
+
+// skipEmpty skips an empty line of n bytes before the status line: it's let slide, with a warning, or refused when
+// strict, and counted against MaxHeaderBytes, so an endless run of them is refused too.
+func (p *parser) skipEmpty(n int) error {
+	if p.bytes == 0 {
+		if err := p.lax("empty lines before the status line"); err != nil {
+			return err
+		}
+	}
+	if p.bytes += n; p.bytes > p.o.maxHeaderBytes() {
+		return &LimitError{Limit: "MaxHeaderBytes", Max: p.o.maxHeaderBytes()}
+	}
+	return nil
+}
+
+// http10Framing checks r's framing if it's an HTTP/1.0 response. chunked encoding is HTTP/1.1's, so a 1.0 response
+// with a Transfer-Encoding can't be framed by it, or trusted to have a right Content-Length either (RFC 9112, section
+// 6.1): its body is read until the connection closes, and it's let slide with a warning, or refused when strict.
+func (p *parser) http10Framing(r *Response) error {
+	if r.Proto != "HTTP/1.0" || r.Headers.Values("Transfer-Encoding") == nil {
+		return nil
+	}
+	return p.lax("an HTTP/1.0 response has a Transfer-Encoding, so its body runs until the connection closes")
+}
+
+// parser reads a message's head, holding it to o's limits, and to the RFCs as strictly as o says.
+type parser struct {
+	o            ParseOptions
+	kind         string // "request" or "response", for errors
+	lines, bytes int    // of the head so far
+	warnings     []string
+}
+
+// lax is for something the RFCs let a recipient tolerate: with Strict, it's an error; otherwise it's a warning.
+func (p *parser) lax(format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	if p.o.Strict {
+		return fmt.Errorf("malformed %s: %s", p.kind, msg)
+	}
+	p.warnings = append(p.warnings, msg)
+	return nil
+}
+
+// line takes the line ending off raw, the next line of the head, and counts it against the limits. an empty line
+// ends the head.
+func (p *parser) line(raw string) (string, error) {
+	line, ok := strings.CutSuffix(raw, "\r\n")
+	if !ok {
+		line = strings.TrimSuffix(raw, "\n")
+		if err := p.lax("line %d ends in a bare LF, not CRLF", p.lines+1); err != nil {
+			return "", err
+		}
+	}
+	p.lines++
+	if len(line) > p.o.maxLineLength() {
+		return "", &LimitError{Limit: "MaxLineLength", Max: p.o.maxLineLength(), requestLine: p.kind == "request" && p.lines == 1}
+	}
+	if p.lines == 1 || line == "" {
+		return line, nil // the start line and the empty line that ends the head aren't headers.
+	}
+	if p.bytes += len(line) + 2; p.bytes > p.o.maxHeaderBytes() {
+		return "", &LimitError{Limit: "MaxHeaderBytes", Max: p.o.maxHeaderBytes()}
+	}
+	if p.lines-1 > p.o.maxHeaderCount() {
+		return "", &LimitError{Limit: "MaxHeaderCount", Max: p.o.maxHeaderCount()}
+	}
+	return line, nil
+}
+
+// nextLine cuts the next line of the head off rest, counting it against the limits. the line is a substring of
+// rest, so this doesn't allocate; nor does the rest of parsing a head from a string, but for the Headers.
+func (p *parser) nextLine(rest string) (line, after string, err error) {
+	i := strings.IndexByte(rest, '\n')
+	if i < 0 {
+		if len(rest) > p.o.maxLineLength() {
+			_, err := p.line(rest + "\r\n")
+			return "", "", err
+		}
+		return "", "", fmt.Errorf("malformed %s: the head should end with an empty line", p.kind)
+	}
+	line, err = p.line(rest[:i+1])
+	return line, rest[i+1:], err
+}
+
+// startLine cuts the start line off raw, a whole message.
+func (p *parser) startLine(raw string) (line, rest string, err error) {
+	if line, rest, err = p.nextLine(raw); err != nil {
+		return "", "", err
+	}
+	if line == "" {
+		return "", "", fmt.Errorf("malformed %s: should start with a %s line", p.kind, p.kind)
+	}
+	return line, rest, nil
+}
+
+// headers parses the header lines at the start of rest, up to the empty line that ends the head, and returns them
+// and the body after it. a first pass counts the lines, which is cheap, so the Headers are allocated once, at
+// the right size, rather than grown as they're found.
+func (p *parser) headers(rest string) (headers Headers, body string, err error) {
+	n := 0
+	for s := rest; n <= p.o.maxHeaderCount(); n++ {
+		i := strings.IndexByte(s, '\n')
+		if i <= 0 || i == 1 && s[0] == '\r' {
+			break
+		}
+		s = s[i+1:]
+	}
+	if n > 0 {
+		headers = make(Headers, 0, n)
+	}
+	for {
+		var line string
+		if line, rest, err = p.nextLine(rest); err != nil {
+			return nil, "", err
+		}
+		if line == "" {
+			return headers, rest, nil
+		}
+		if err := p.addHeader(&headers, line); err != nil {
+			return nil, "", err
+		}
+	}
+}
+
+// readLine reads the next line of the head from r, reading no more than the line length limit allows, plus a bit
+// for the CRLF: a line too long is an error before it's all been read.
+func (p *parser) readLine(r *bufio.Reader) (string, error) {
+	var line []byte
+	for {
+		frag, err := r.ReadSlice('\n')
+		if line == nil && err == nil && len(frag) <= p.o.maxLineLength()+2 {
+			switch string(frag) { // the compiler doesn't copy frag to compare it.
+			case "\r\n":
+				return p.line("\r\n") // the empty line at the end of the head: nothing to copy.
+			case "\n":
+				return p.line("\n")
+			}
+			return p.line(string(frag)) // the whole line was in the buffer: copy it once, not twice.
+		}
+		line = append(line, frag...)
+		if max := p.o.maxLineLength(); len(line) > max+2 {
+			_, err := p.line(string(line[:max+1]) + "\r\n")
+			return "", err
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil {
+			return string(line), err
+		}
+		return p.line(string(line))
+	}
+}
+
+// requestLine parses a request line, like "GET /index.html HTTP/1.1".
+func (p *parser) requestLine(line string) (method, path string, err error) {
+	method, path, protocol, ok := splitRequestLine(line)
+	if !ok {
+		// not three plain fields separated by single spaces: see if it's three fields at all, spaced some other way.
+		first := strings.Fields(line)
+		if len(first) != 3 {
+			return "", "", fmt.Errorf("malformed request line %q: should be a method, a path, and an HTTP version", line)
+		}
+		if strings.Join(first, " ") != line {
+			if err := p.lax("request line %q should be separated by single spaces", line); err != nil {
+				return "", "", err
+			}
+		}
+		method, path, protocol = first[0], first[1], first[2]
+	}
+	if !isToken(method) {
+		if err := p.lax("method %q isn't a token", method); err != nil {
+			return "", "", err
+		}
+	}
+	if !strings.HasPrefix(path, "/") {
+		return "", "", fmt.Errorf("malformed request: path should start with /")
+	}
+	if !isVersion(protocol) {
+		if !strings.Contains(protocol, "HTTP") {
+			return "", "", fmt.Errorf("malformed request: first line should contain HTTP version")
+		}
+		if err := p.lax("%q isn't an HTTP version like HTTP/1.1", protocol); err != nil {
+			return "", "", err
+		}
+	}
+	return method, path, nil
+}
+
+// splitRequestLine splits line into its three fields, if they're separated by single spaces, and there's no other
+// whitespace in it, as in any request line that's well formed. it's strings.Fields without the allocation.
+func splitRequestLine(line string) (method, path, protocol string, ok bool) {
+	for i := 0; i < len(line); i++ {
+		if c := line[i]; c == '\t' || c == '\v' || c == '\f' || c == '\r' || c >= 0x80 {
+			return "", "", "", false
+		}
+	}
+	method, rest, _ := strings.Cut(line, " ")
+	path, protocol, _ = strings.Cut(rest, " ")
+	if method == "" || path == "" || protocol == "" || strings.IndexByte(protocol, ' ') >= 0 {
+		return "", "", "", false
+	}
+	return method, path, protocol, true
+}
+
+// statusLine parses a status line, like "HTTP/1.1 200 OK": the version, the code, and a reason phrase, which may
+// be empty, or anything else: it's for people, and a client shouldn't care what it says.
+func (p *parser) statusLine(line string) (version string, code int, reason string, err error) {
+	version, rest, _ := strings.Cut(line, " ")
+	codeField, reason, hasReason := strings.Cut(rest, " ")
+	code, err = strconv.Atoi(codeField)
+	// Atoi takes a sign, so "+20" and "-20" are numbers; a status code is three digits, and 000 to 099 aren't one.
+	if !strings.HasPrefix(version, "HTTP/") || len(codeField) != 3 || err != nil || !isDigit(codeField[0]) || code < 100 {
+		return "", 0, "", fmt.Errorf("malformed status line %q", line)
+	}
+	if code > 599 {
+		if err := p.lax("status code %d is out of range: they go from 100 to 599", code); err != nil {
+			return "", 0, "", err
+		}
+	}
+	if hasControl(reason) {
+		if err := p.lax("reason phrase %q has a control character in it", reason); err != nil {
+			return "", 0, "", err
+		}
+	}
+	if !isVersion(version) {
+		if err := p.lax("%q isn't an HTTP version like HTTP/1.1", version); err != nil {
+			return "", 0, "", err
+		}
+	}
+	if !hasReason {
+		if err := p.lax("status line %q has no space after the code", line); err != nil {
+			return "", 0, "", err
+		}
+	}
+	return version, code, reason, nil
+}
+
+// addHeader parses line, the next header line, and adds it to headers: as a header of its own, or, if it's folded,
+// to the value of the one before.
+func (p *parser) addHeader(headers *Headers, line string) error {
+	if line[0] != ' ' && line[0] != '\t' {
+		h, err := p.header(line)
+		if err != nil {
+			return err
+		}
+		*headers = append(*headers, h)
+		return nil
+	}
+	// obs-fold (RFC 9112, section 5.2): a line starting with whitespace continues the header before it, from the
+	// days when long values were wrapped like email headers. it's obsolete, but old servers still send it. the fold
+	// is replaced by a space; a server may refuse it instead, so a strict one does.
+	last := len(*headers) - 1
+	if last < 0 {
+		return fmt.Errorf("malformed %s: the first header line %q starts with whitespace", p.kind, line)
+	}
+	h := &(*headers)[last]
+	if p.kind == "request" && p.o.Strict {
+		return fmt.Errorf("malformed request: header %s is folded onto the next line (obs-fold)", h.Key)
+	}
+	p.warnings = append(p.warnings, fmt.Sprintf("header %s is folded onto the next line (obs-fold)", h.Key))
+	v := strings.Trim(line, " \t")
+	if strings.ContainsAny(v, "\r\x00") {
+		return fmt.Errorf("malformed %s: header %s has a CR or NUL in its value", p.kind, h.Key)
+	}
+	if h.Value == "" {
+		h.Value = v
+	} else if v != "" {
+		h.Value += " " + v
+	}
+	return nil
+}
+
+// trailers checks the trailer fields that came after a chunked body against the message's headers, and returns the
+// ones to keep (RFC 9110, section 6.5). a field the Trailer header didn't declare is let slide, or refused by a
+// strict parse. a field that can't be a trailer, because it's about framing, routing, or how to read the body,
+// like Content-Length, is dropped: it's too late for it to mean what it says, and merging it into the headers is
+// how trailers get used to smuggle. a strict parse refuses the message instead.
+func (p *parser) trailers(headers, trailers Headers) (Headers, error) {
+	declared := headers.List("Trailer")
+	var kept Headers
+	for _, t := range trailers {
+		if _, bad := forbiddenTrailers[AsTitle(t.Key)]; bad {
+			if err := p.lax("trailer %s isn't allowed after the body, so it's dropped", t.Key); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !isToken(t.Key) {
+			if err := p.lax("trailer name %q isn't a token", t.Key); err != nil {
+				return nil, err
+			}
+		}
+		if !slices.ContainsFunc(declared, func(d string) bool { return strings.EqualFold(d, t.Key) }) {
+			if err := p.lax("trailer %s wasn't declared in the Trailer header", t.Key); err != nil {
+				return nil, err
+			}
+		}
+		kept = append(kept, t)
+	}
+	return kept, nil
+}
+
+// forbiddenTrailers are the fields that can't be sent as trailers: net/http's list, of the framing, routing,
+// authentication, and content fields a recipient needs before the body.
+var forbiddenTrailers = map[string]struct{}{
+	"Authorization": {}, "Cache-Control": {}, "Connection": {}, "Content-Encoding": {}, "Content-Length": {},
+	"Content-Range": {}, "Content-Type": {}, "Expect": {}, "Host": {}, "Keep-Alive": {}, "Max-Forwards": {},
+	"Pragma": {}, "Proxy-Authenticate": {}, "Proxy-Authorization": {}, "Proxy-Connection": {}, "Range": {},
+	"Realm": {}, "Te": {}, "Trailer": {}, "Transfer-Encoding": {}, "Www-Authenticate": {},
+}
+
+// header parses a header line, "Key: Value", putting the key in title case. the whitespace around the value isn't
+// part of it.
+func (p *parser) header(line string) (Header, error) {
+	k, v, ok := strings.Cut(line, ":")
+	if !ok || strings.TrimSpace(k) == "" {
+		return Header{}, fmt.Errorf("malformed %s: header %q should be of form 'key: value'", p.kind, line)
+	}
+	if name := strings.TrimRight(k, " \t"); name != k {
+		if err := p.lax("whitespace between header name %q and the colon", name); err != nil {
+			return Header{}, err
+		}
+		k = name
+	}
+	if !isToken(k) {
+		if err := p.lax("header name %q isn't a token", k); err != nil {
+			return Header{}, err
+		}
+	}
+	v = strings.Trim(v, " \t")
+	if strings.ContainsAny(v, "\r\x00") {
+		return Header{}, fmt.Errorf("malformed %s: header %s has a CR or NUL in its value", p.kind, k)
+	}
+	if strings.ContainsFunc(v, func(c rune) bool { return c < ' ' && c != '\t' || c == 0x7f }) {
+		if err := p.lax("header %s has a control character in its value", k); err != nil {
+			return Header{}, err
+		}
+	}
+	return Header{AsTitle(k), v}, nil
+}
+
+// isToken reports whether s is a token (RFC 9110, section 5.6.2): one or more visible ASCII characters other than
+// the separators. header names and methods are both tokens.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// isVersion reports whether s is an HTTP version, like HTTP/1.1: "HTTP/", a digit, a dot, and a digit.
+func isVersion(s string) bool {
+	return len(s) == 8 && strings.HasPrefix(s, "HTTP/") && isDigit(s[5]) && s[6] == '.' && isDigit(s[7])
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
@@ -0,0 +1,278 @@
+package httpwire
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/* design note: ParseResponse needs the whole response in a string first, which means the caller has to know where
+   it ends, and that's the hard part. on a connection, the end of a response isn't the end of the input: a kept-alive
+   server goes quiet and waits for the next request, so reading until EOF hangs. the headers say where the body ends,
+   in order of precedence (RFC 9112, section 6.3):
+
+	1. a 1xx, 204, or 304 response has no body, whatever the headers say.
+	2. Transfer-Encoding: chunked ends with the zero-length chunk (and the trailers after it).
+	3. Content-Length ends after that many bytes.
+	4. otherwise the body runs until the server closes the connection.
+
+   ReadResponse reads exactly one response and stops, so the next one, on a kept-alive connection, is still in the
+   bufio.Reader for the next call. it can't know the request's method, so the body of a response to HEAD, which has
+   the headers a GET's would, Content-Length and all, but not the body, has to be read by ReadResponseTo.
+
+   older servers, and embedded ones, still speak older versions. an HTTP/1.0 response's body is framed by its
+   Content-Length, or runs until the server closes the connection, which it does after every response unless
+   both sides asked for keep-alive; chunked encoding came with 1.1. Response.Proto says which version it was, so a
+   caller can tell, and, say, not wait for a 1.0 server to keep the connection alive.
+
+   an HTTP/0.9 response is nothing but the body, with no status line or headers at all. reading anything that
+   doesn't start with "HTTP/" as one turned a stray CRLF after one response's body into a 0.9 "200" that swallowed
+   the next response, a 404, say, up to the close. so 0.9 is only read with ParseOptions.HTTP09, for a caller that
+   knows it might be talking to a server that old, and then only with a warning (a strict parse refuses it, since
+   it's more likely a server speaking some other protocol). empty lines before a status line, which sloppy servers
+   leave after a body, are skipped with a warning, or refused when strict.
+
+   ReadResponse reads the body into a string, which is the easy thing to work with, and fine for an API's JSON, but
+   not for a download of a few gigabytes. ReadResponseHead reads only the head, and leaves the body on the reader,
+   behind Response.BodyReader, which stops where the framing says it ends, so the next response is still there
+   after it. WriteTo streams a BodyReader in turn, so a response can go from one connection to another, as a proxy
+   does, without ever being in memory whole.
+*/
+
+// ReadResponse reads one response from r, reading its body as far as its framing says it goes, with the default
+// ParseOptions.
+func ReadResponse(r *bufio.Reader) (*Response, error) {
+	return ParseOptions{}.ReadResponse(r, "")
+}
+
+// ReadResponseTo reads one response to a method request from r. a response to HEAD has no body.
+func ReadResponseTo(r *bufio.Reader, method string) (*Response, error) {
+	return ParseOptions{}.ReadResponse(r, method)
+}
+
+// ReadResponseHead reads the head of one response to a method request from r, leaving its body to be streamed
+// from BodyReader, with the default ParseOptions.
+func ReadResponseHead(r *bufio.Reader, method string) (*Response, error) {
+	return ParseOptions{}.ReadResponseHead(r, method)
+}
+
+// ReadResponse reads one response to a method request from r, within o's limits. method can be "" if it isn't
+// HEAD.
+func (o ParseOptions) ReadResponse(r *bufio.Reader, method string) (*Response, error) {
+	p, resp, body, n, err := o.readHead(r, method)
+	if err != nil {
+		return nil, err
+	}
+	switch body {
+	case chunkedBody:
+		body, trailers, err := readChunked(p.o, r)
+		if err != nil {
+			return nil, fmt.Errorf("malformed response: chunked body: %w", err)
+		}
+		if resp.Trailers, err = p.trailers(resp.Headers, trailers); err != nil {
+			return nil, err
+		}
+		resp.Body = string(body)
+	case lengthBody:
+		if n <= int64(r.Buffered()) {
+			b, _ := r.Peek(int(n)) // the body's all in the buffer already: copy it straight out.
+			resp.Body = string(b)
+			r.Discard(int(n))
+			break
+		}
+		var b strings.Builder
+		if _, err := io.CopyN(&b, r, n); err != nil {
+			return nil, fmt.Errorf("reading body: %w", unexpected(err))
+		}
+		resp.Body = b.String()
+	case closeBody:
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading body: %w", err)
+		}
+		resp.Body = string(b)
+	}
+	resp.Warnings = p.warnings
+	return resp, nil
+}
+
+// ReadResponseHead reads the head of one response to a method request from r, within o's limits, and leaves the
+// body to be streamed: it's the response's BodyReader, which reads it from r as far as its framing says it goes,
+// and no further, and its ContentLength is how long it is, or -1 if that isn't known until it's over. a chunked
+// body's trailers are checked, and set on the response, when BodyReader returns io.EOF. the body has to be read to
+// the end before the next response on r.
+func (o ParseOptions) ReadResponseHead(r *bufio.Reader, method string) (*Response, error) {
+	p, resp, body, n, err := o.readHead(r, method)
+	if err != nil {
+		return nil, err
+	}
+	resp.Warnings = p.warnings
+	switch body {
+	case noBody:
+		resp.BodyReader = strings.NewReader("")
+	case chunkedBody:
+		resp.BodyReader, resp.ContentLength = &trailerReader{ChunkedReader: p.o.NewChunkedReader(r), p: p, resp: resp}, -1
+	case lengthBody:
+		resp.BodyReader, resp.ContentLength = &exactReader{r: r, n: n}, n
+	case closeBody:
+		resp.BodyReader, resp.ContentLength = r, -1
+	}
+	return resp, nil
+}
+
+// bodyFraming is how a response's body ends; see the design note.
+type bodyFraming int
+
+const (
+	noBody      bodyFraming = iota
+	chunkedBody             // at the zero-length chunk
+	lengthBody              // after Content-Length bytes
+	closeBody               // when the connection closes
+)
+
+// readHead reads a response's head from r, and works out how its body is framed: for lengthBody, n is how long.
+func (o ParseOptions) readHead(r *bufio.Reader, method string) (p *parser, resp *Response, body bodyFraming, n int64, err error) {
+	p = &parser{o: o, kind: "response"}
+	start, err := p.peekStart(r)
+	if err != nil {
+		return nil, nil, 0, 0, err
+	}
+	if o.HTTP09 && !bytes.HasPrefix([]byte("HTTP/"), start) {
+		resp, err := p.http09("")
+		return p, resp, closeBody, 0, err
+	}
+	line, err := p.readLine(r)
+	if err != nil {
+		if line == "" && errors.Is(err, io.EOF) {
+			return nil, nil, 0, 0, io.EOF // nothing at all: the connection closed between responses.
+		}
+		return nil, nil, 0, 0, fmt.Errorf("reading status line: %w", unexpected(err))
+	}
+	resp = new(Response)
+	if resp.Proto, resp.StatusCode, resp.Reason, err = p.statusLine(line); err != nil {
+		return nil, nil, 0, 0, err
+	}
+	if n := bufferedLines(r); n > 0 {
+		resp.Headers = make(Headers, 0, min(n, p.o.maxHeaderCount()))
+	}
+	for {
+		line, err := p.readLine(r)
+		if err != nil {
+			return nil, nil, 0, 0, fmt.Errorf("reading headers: %w", unexpected(err))
+		}
+		if line == "" {
+			break
+		}
+		if err := p.addHeader(&resp.Headers, line); err != nil {
+			return nil, nil, 0, 0, err
+		}
+	}
+	if err := p.http10Framing(resp); err != nil {
+		return nil, nil, 0, 0, err
+	}
+	// an HTTP/1.0 response with a Transfer-Encoding runs until the close, whatever else it says; see http10Framing.
+	framed := resp.Proto != "HTTP/1.0" || resp.Headers.Values("Transfer-Encoding") == nil
+
+	switch cl := resp.Headers.Get("Content-Length"); {
+	case method == "HEAD" || resp.StatusCode < 200 || resp.StatusCode == 204 || resp.StatusCode == 304:
+		return p, resp, noBody, 0, nil
+	case framed && resp.Headers.Chunked():
+		return p, resp, chunkedBody, 0, nil
+	case framed && cl != "":
+		n, err := strconv.ParseInt(cl, 10, 64)
+		if err != nil || !isDigit(cl[0]) { // ParseInt takes a sign, which a length can't have.
+			return nil, nil, 0, 0, fmt.Errorf("malformed response: bad Content-Length %q", cl)
+		}
+		return p, resp, lengthBody, n, nil
+	}
+	return p, resp, closeBody, 0, nil
+}
+
+// peekStart reads past any empty lines where the status line should be, and peeks at the first few bytes after
+// them: enough to tell whether they're a status line, for HTTP/0.9. it's io.EOF if there's nothing at all.
+func (p *parser) peekStart(r *bufio.Reader) ([]byte, error) {
+	for {
+		// a Peek's error is only returned once: bufio doesn't keep it for the next read, so it has to be dealt with
+		// here. a short message that ends at EOF is still read as far as it goes.
+		b, err := r.Peek(len("HTTP/"))
+		switch {
+		case err != nil && !errors.Is(err, io.EOF):
+			return nil, fmt.Errorf("reading status line: %w", err)
+		case len(b) == 0 && p.bytes == 0:
+			return nil, io.EOF // nothing at all: the connection closed between responses.
+		case len(b) == 0:
+			return nil, fmt.Errorf("reading status line: %w", io.ErrUnexpectedEOF)
+		}
+		n := emptyLine(string(b))
+		if n == 0 {
+			return b, nil
+		}
+		if err := p.skipEmpty(n); err != nil {
+			return nil, err
+		}
+		r.Discard(n)
+	}
+}
+
+// trailerReader is a streamed chunked body, which checks the trailers after it, and sets them on resp, at the end.
+type trailerReader struct {
+	*ChunkedReader
+	p    *parser
+	resp *Response
+	done bool
+}
+
+func (t *trailerReader) Read(b []byte) (int, error) {
+	n, err := t.ChunkedReader.Read(b)
+	if err == io.EOF && !t.done {
+		t.done = true
+		var terr error
+		if t.resp.Trailers, terr = t.p.trailers(t.resp.Headers, t.Trailers()); terr != nil {
+			return n, terr
+		}
+		t.resp.Warnings = t.p.warnings
+	}
+	return n, err
+}
+
+// exactReader reads n bytes from r: an io.LimitReader that says io.ErrUnexpectedEOF, not io.EOF, if r ends first,
+// so a body cut short isn't taken for a whole one.
+type exactReader struct {
+	r io.Reader
+	n int64
+}
+
+func (e *exactReader) Read(b []byte) (int, error) {
+	if e.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(b)) > e.n {
+		b = b[:e.n]
+	}
+	n, err := e.r.Read(b)
+	e.n -= int64(n)
+	if err == io.EOF && e.n > 0 {
+		err = io.ErrUnexpectedEOF
+	} else if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// bufferedLines counts the lines in r's buffer up to the first empty one, or as many as there are if there isn't
+// one, without reading any, as a guess at how many headers are coming.
+func bufferedLines(r *bufio.Reader) int {
+	b, _ := r.Peek(r.Buffered())
+	n := 0
+	for {
+		i := bytes.IndexByte(b, '\n')
+		if i <= 0 || i == 1 && b[0] == '\r' {
+			return n
+		}
+		b, n = b[i+1:], n+1
+	}
+}
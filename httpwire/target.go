@@ -0,0 +1,157 @@
+package httpwire
+
+import (
+	"fmt"
+	"strings"
+)
+
+/* design note: the request line's target is the path and query as the client wrote them: "/docs/../a%20b?q=1". a
+   server routing on that string would see three spellings of the same resource as three resources, and, worse, a
+   path that only looks like it stays under /static. Target takes it apart the way a server needs it:
+
+	1. the query is cut off at the first ?, and kept encoded: & and = in it only mean something before decoding.
+	2. the path is split into segments at each /, and each segment is percent-decoded on its own. an escaped slash,
+	   %2F, is part of a segment's name, not a separator, which is what RawPath is for if a router cares.
+	3. . and .. segments are resolved (RFC 3986, section 5.2.4), after decoding, so %2e%2e is a .. too, and can't
+	   climb out of the root: /../../etc/passwd is /etc/passwd.
+
+   a query is decoded the way HTML forms encode it: pairs split at &, keys from values at the first =, and + is a
+   space, as well as %20. a key can come more than once, so its values are a list.
+*/
+
+// Target is an origin-form request target, like "/search?q=go", taken apart.
+type Target struct {
+	Path     string // percent-decoded, with . and .. segments resolved: "/a/./b/../c%20d" is "/a/c d"
+	RawPath  string // the path as it was sent
+	RawQuery string // what came after the ?, still encoded
+}
+
+// ParseTarget parses s, an origin-form request target: an absolute path, and maybe a query.
+func ParseTarget(s string) (Target, error) {
+	if !strings.HasPrefix(s, "/") {
+		return Target{}, fmt.Errorf("request target %q should start with /", s)
+	}
+	path, query, _ := strings.Cut(s, "?")
+	t := Target{RawPath: path, RawQuery: query}
+	var segments []string
+	raw := strings.Split(path[1:], "/")
+	for i, seg := range raw {
+		seg, err := Unescape(seg)
+		if err != nil {
+			return Target{}, fmt.Errorf("request target %q: %w", s, err)
+		}
+		last := i == len(raw)-1
+		switch seg {
+		case ".":
+			if last {
+				segments = append(segments, "") // "/a/." is "/a/": a directory, like "/a/./".
+			}
+		case "..":
+			if len(segments) > 0 {
+				segments = segments[:len(segments)-1]
+			}
+			if last {
+				segments = append(segments, "")
+			}
+		default:
+			segments = append(segments, seg)
+		}
+	}
+	t.Path = "/" + strings.Join(segments, "/")
+	return t, nil
+}
+
+// Query parses the query, ignoring malformed pairs.
+func (t Target) Query() map[string][]string {
+	q, _ := ParseQuery(t.RawQuery)
+	return q
+}
+
+// Target parses r's path; see ParseTarget.
+func (r *Request) Target() (Target, error) {
+	return ParseTarget(r.Path)
+}
+
+// Query parses the query in r's path, ignoring malformed pairs, like a bad percent-escape. it's empty if there
+// isn't one.
+func (r *Request) Query() map[string][]string {
+	_, query, _ := strings.Cut(r.Path, "?")
+	q, _ := ParseQuery(query)
+	return q
+}
+
+// ParseQuery parses a query string, like "a=1&b=2&a=3", into each key's values, in order. a pair that can't be
+// decoded is skipped, and the first such error is returned, along with the pairs that could be.
+func ParseQuery(query string) (map[string][]string, error) {
+	q := map[string][]string{}
+	var first error
+	for _, pair := range strings.Split(query, "&") {
+		if pair == "" {
+			continue
+		}
+		k, v, _ := strings.Cut(pair, "=")
+		k, err := unescape(k, true)
+		if err == nil {
+			v, err = unescape(v, true)
+		}
+		if err != nil {
+			if first == nil {
+				first = fmt.Errorf("query pair %q: %w", pair, err)
+			}
+			continue
+		}
+		q[k] = append(q[k], v)
+	}
+	return q, first
+}
+
+// Unescape decodes the percent-escapes in s, like %20 for a space.
+func Unescape(s string) (string, error) {
+	return unescape(s, false)
+}
+
+// unescape decodes the percent-escapes in s, and with plus, turns + into a space, as a query does.
+func unescape(s string, plus bool) (string, error) {
+	if !strings.ContainsAny(s, "%+") {
+		return s, nil // the usual case: nothing to decode, and nothing to allocate.
+	}
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '%':
+			if i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+				return "", errBadEscape(s[i:])
+			}
+			b.WriteByte(unhex(s[i+1])<<4 | unhex(s[i+2]))
+			i += 2
+		case c == '+' && plus:
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+// errBadEscape is the error for a % that isn't followed by two hex digits, at the start of rest.
+func errBadEscape(rest string) error {
+	if len(rest) > 3 {
+		rest = rest[:3]
+	}
+	return fmt.Errorf("invalid percent-escape %q", rest)
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	}
+	return c - '0'
+}
@@ -0,0 +1,122 @@
+package httpwire
+
+import "strings"
+
+// AsTitle returns the given header key as title case; e.g. "content-type" -> "Content-Type"
+// It will panic if the key is empty.
+func AsTitle(key string) string {
+	/* design note --- an empty string could be considered 'in title case',
+	   but in practice it's probably programmer error. rather than guess, we'll panic.
+	*/
+	if key == "" {
+		panic("empty header key")
+	}
+
+	if isTitleCase(key) {
+		return key
+	}
+
+	/* ---- design note: allocation is very expensive, while iteration through strings is very cheap.
+	   in general, better to check twice rather than allocate once. ----
+	*/
+	if len(key) <= len(titleBuf{}) {
+		// put it in title case on the stack, and see if it's one of the common names: if it is, that's the answer,
+		// and there's nothing to allocate. this is textproto's trick: the compiler doesn't copy the bytes to look
+		// them up in a map, only to keep them as a string.
+		var buf titleBuf
+		b := toTitle(buf[:len(key)], key)
+		if name, ok := commonHeaders[string(b)]; ok {
+			return name
+		}
+		return string(b)
+	}
+	return newTitleCase(key)
+}
+
+// titleBuf is room on the stack for a header name to be put in title case; longer ones are rare.
+type titleBuf [64]byte
+
+// toTitle writes key in title case into b, which is as long as key, and returns b.
+func toTitle(b []byte, key string) []byte {
+	for i := range key {
+		if i == 0 || key[i-1] == '-' {
+			b[i] = upper(key[i])
+		} else {
+			b[i] = lower(key[i])
+		}
+	}
+	return b
+}
+
+// commonHeaders are the header names seen most, in title case, so AsTitle can return one without allocating. it's
+// what makes lowercase headers, as HTTP/2 sends them, cheap to parse: BenchmarkParseRequestLowercase went from 8
+// allocations to 1, the Headers, and BenchmarkAsTitle from 5 to none, and half the time.
+var commonHeaders = map[string]string{}
+
+func init() {
+	for _, name := range []string{
+		"Accept", "Accept-Charset", "Accept-Encoding", "Accept-Language", "Accept-Ranges", "Access-Control-Allow-Origin",
+		"Age", "Allow", "Authorization", "Cache-Control", "Connection", "Content-Disposition", "Content-Encoding",
+		"Content-Language", "Content-Length", "Content-Location", "Content-Range", "Content-Security-Policy",
+		"Content-Type", "Cookie", "Date", "Etag", "Expect", "Expires", "Forwarded", "From", "Host", "If-Match",
+		"If-Modified-Since", "If-None-Match", "If-Range", "If-Unmodified-Since", "Keep-Alive", "Last-Modified", "Link",
+		"Location", "Max-Forwards", "Origin", "Pragma", "Proxy-Authenticate", "Proxy-Authorization", "Range",
+		"Referer", "Retry-After", "Sec-Websocket-Accept", "Sec-Websocket-Key", "Sec-Websocket-Version", "Server",
+		"Set-Cookie", "Strict-Transport-Security", "Te", "Trailer", "Transfer-Encoding", "Upgrade",
+		"Upgrade-Insecure-Requests", "User-Agent", "Vary", "Via", "Warning", "Www-Authenticate", "X-Content-Type-Options",
+		"X-Forwarded-For", "X-Forwarded-Host", "X-Forwarded-Proto", "X-Frame-Options", "X-Request-Id",
+	} {
+		commonHeaders[name] = name
+	}
+}
+
+// newTitleCase returns the given header key as title case; e.g. "content-type" -> "Content-Type";
+// it always allocates a new string.
+func newTitleCase(key string) string {
+	var b strings.Builder
+	b.Grow(len(key))
+	for i := range key {
+
+		if i == 0 || key[i-1] == '-' {
+			b.WriteByte(upper(key[i]))
+		} else {
+			b.WriteByte(lower(key[i]))
+		}
+	}
+	return b.String()
+}
+
+// straight from K&R C, 2nd edition, page 43. some classics never go out of style.
+func lower(c byte) byte {
+	/* if you're having trouble understanding this:
+	   the idea is as follows: A..=Z are 65..=90, and a..=z are 97..=122.
+	   so upper-case letters are 32 less than their lower-case counterparts (or 'a'-'A' == 32).
+	   rather than using the 'magic' number 32, we use 'a'-'A' to get the same result.
+	*/
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c + 'A' - 'a'
+	}
+	return c
+}
+
+// isTitleCase returns true if the given header key is already title case; i.e, it is of the form "Content-Type" or "Content-Length", "Some-Odd-Header", etc.
+func isTitleCase(key string) bool {
+	// check if this is already title case.
+	for i := range key {
+		if i == 0 || key[i-1] == '-' {
+			if key[i] >= 'a' && key[i] <= 'z' {
+				return false
+			}
+		} else if key[i] >= 'A' && key[i] <= 'Z' {
+			return false
+		}
+	}
+	return true
+}
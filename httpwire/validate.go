@@ -0,0 +1,185 @@
+package httpwire
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/* design note: a parser has to be lenient about what it reads, but a Request built in code, or passed along by a
+   proxy, should be checked before it's written: a method with a space in it, or a header value with a CRLF in it,
+   writes a different message from the one that was meant, which is how header injection works. Validate checks
+   what RFC 9112 says a request must be, and returns an error of its own type for each thing wrong, joined, so
+   errors.As finds any of them:
+
+	*MethodError  the method isn't a token.
+	*TargetError  the target has a space or a control character in it, isn't the form the method calls for
+	              (section 3.2), or has a bad percent-escape. CONNECT takes a host:port (authority-form);
+	              OPTIONS can take * (asterisk-form); any method can take a path, or, to a proxy, a whole URL
+	              (origin-form and absolute-form).
+	*HeaderError  a header's name isn't a token, or its value has a control character other than a tab in it,
+	              or there's no Host header, which HTTP/1.1 requires.
+
+   Response.Validate does the same for a response: a *StatusError if the code isn't three digits from 100 to 599,
+   or the reason phrase has a control character in it (it's the rest of the line, so a CRLF would end it early),
+   and a *HeaderError for each bad header, as for a request, except that a response has no Host to miss.
+*/
+
+// MethodError is a request method that isn't a token, like "GET /" or "".
+type MethodError struct {
+	Method string
+}
+
+func (e *MethodError) Error() string {
+	return fmt.Sprintf("invalid method %q: should be a token", e.Method)
+}
+
+// TargetError is a request target that's malformed, or the wrong form for the method.
+type TargetError struct {
+	Method, Target string
+	Reason         string
+}
+
+func (e *TargetError) Error() string {
+	return fmt.Sprintf("invalid target %q for %s: %s", e.Target, e.Method, e.Reason)
+}
+
+// HeaderError is a header that can't be written as it is, or a missing Host.
+type HeaderError struct {
+	Key    string
+	Reason string
+}
+
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("invalid header %q: %s", e.Key, e.Reason)
+}
+
+// StatusError is a response's status line that can't be written as it is: a code out of range, or a reason phrase
+// with a control character in it.
+type StatusError struct {
+	Code   int
+	Phrase string // the reason phrase
+	Reason string // what's wrong
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("invalid status line %d %q: %s", e.Code, e.Phrase, e.Reason)
+}
+
+// Validate checks that r can be written as a well-formed HTTP/1.1 request, returning a *MethodError, *TargetError,
+// or *HeaderError for each way it can't, joined together, or nil if there's none.
+func (r *Request) Validate() error {
+	var errs []error
+	if !isToken(r.Method) {
+		errs = append(errs, &MethodError{r.Method})
+	}
+	if reason := targetProblem(r.Method, r.Path); reason != "" {
+		errs = append(errs, &TargetError{Method: r.Method, Target: r.Path, Reason: reason})
+	}
+	errs = append(errs, headerErrors(r.Headers)...)
+	if !slices.ContainsFunc(r.Headers, func(h Header) bool { return strings.EqualFold(h.Key, "Host") }) {
+		errs = append(errs, &HeaderError{Key: "Host", Reason: "HTTP/1.1 requires one"})
+	}
+	return errors.Join(errs...)
+}
+
+// Validate checks that resp can be written as a well-formed HTTP/1.1 response, returning a *StatusError or
+// *HeaderError for each way it can't, joined together, or nil if there's none.
+func (resp *Response) Validate() error {
+	var errs []error
+	switch {
+	case resp.StatusCode < 100 || resp.StatusCode > 599:
+		errs = append(errs, &StatusError{Code: resp.StatusCode, Phrase: resp.Reason, Reason: "the code should be from 100 to 599"})
+	case hasControl(resp.Reason):
+		errs = append(errs, &StatusError{Code: resp.StatusCode, Phrase: resp.Reason, Reason: "the reason phrase has a control character in it"})
+	}
+	errs = append(errs, headerErrors(resp.Headers)...)
+	return errors.Join(errs...)
+}
+
+// headerErrors returns a *HeaderError for each header in headers that can't be written as it is.
+func headerErrors(headers Headers) []error {
+	var errs []error
+	for _, h := range headers {
+		switch {
+		case !isToken(h.Key):
+			errs = append(errs, &HeaderError{Key: h.Key, Reason: "the name should be a token"})
+		case hasControl(h.Value):
+			errs = append(errs, &HeaderError{Key: h.Key, Reason: "the value has a control character in it"})
+		}
+	}
+	return errs
+}
+
+// hasControl reports whether s has a control character other than a tab in it, which a header value or a reason
+// phrase can't.
+func hasControl(s string) bool {
+	return strings.ContainsFunc(s, func(c rune) bool { return c < ' ' && c != '\t' || c == 0x7f })
+}
+
+// targetProblem says what's wrong with target as method's request target, or "" if nothing is.
+func targetProblem(method, target string) string {
+	switch {
+	case target == "":
+		return "it's empty"
+	case strings.ContainsFunc(target, func(c rune) bool { return c <= ' ' || c == 0x7f }):
+		return "it has a space or a control character in it"
+	case strings.Contains(target, "#"):
+		return "a fragment isn't sent to the server"
+	case method == "CONNECT":
+		if !isAuthority(target) {
+			return "CONNECT takes a host:port (authority-form)"
+		}
+		return ""
+	case target == "*":
+		if method != "OPTIONS" {
+			return "only OPTIONS takes * (asterisk-form)"
+		}
+		return ""
+	case strings.HasPrefix(target, "/"):
+		if _, err := ParseTarget(target); err != nil {
+			return errors.Unwrap(err).Error()
+		}
+		return ""
+	}
+	scheme, rest, ok := strings.Cut(target, "://")
+	if !ok || !isScheme(scheme) {
+		return "should be a path (origin-form), or a whole URL (absolute-form)"
+	}
+	authority, path, _ := strings.Cut(rest, "/")
+	if authority == "" {
+		return "a URL needs a host"
+	}
+	if _, err := ParseTarget("/" + path); err != nil {
+		return errors.Unwrap(err).Error()
+	}
+	return ""
+}
+
+// isAuthority reports whether s is a host and port, like "example.com:443" or "[::1]:8080".
+func isAuthority(s string) bool {
+	i := strings.LastIndexByte(s, ':')
+	if i <= 0 || i == len(s)-1 || strings.ContainsAny(s[:i], "/?@") {
+		return false
+	}
+	for _, c := range []byte(s[i+1:]) {
+		if !isDigit(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// isScheme reports whether s is a URI scheme, like http: a letter, then letters, digits, +, -, and . (RFC 3986,
+// section 3.1).
+func isScheme(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		letter := 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+		if !letter && (i == 0 || !isDigit(c) && c != '+' && c != '-' && c != '.') {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,92 @@
+package httpwire
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+/* design note: a message is written a line at a time, and any of those writes can fail: the connection resets, the
+   disk fills. each one used to be checked by hand, and the odd one that wasn't, like the blank line after the
+   headers, lost its error, so a message that never got there could look sent. now the pieces go through an
+   errWriter, which keeps the first error and skips every write after it, so there's one check, at the end, that
+   can't be forgotten, and the count says how far it got.
+
+   written straight to a connection, a message is a write per line: a syscall, and, with Nagle off, maybe a packet,
+   for each header. WriteOptions.Buffered puts a bufio.Writer in between, flushed once at the end, so a message that
+   fits in the buffer goes in a single write. it's off by default, since a caller writing to a bufio.Writer, or a
+   bytes.Buffer, already has one, and a second only copies everything twice.
+*/
+
+// WriteOptions configures writing. the zero value writes straight to the writer, as WriteTo does.
+type WriteOptions struct {
+	// Buffered writes through a bufio.Writer, flushed at the end, so a message goes in as few writes as it fits in.
+	Buffered bool
+}
+
+// WriteRequest writes r to w as o says, and returns the number of bytes written, and the first error, if any.
+func (o WriteOptions) WriteRequest(w io.Writer, r *Request) (int64, error) {
+	return o.write(w, r.write)
+}
+
+// WriteResponse writes resp to w as o says, and returns the number of bytes written, and the first error, if any.
+func (o WriteOptions) WriteResponse(w io.Writer, resp *Response) (int64, error) {
+	return o.write(w, resp.write)
+}
+
+// write calls write to write a message to w, through a buffer if o says so. the count is what got through to w.
+func (o WriteOptions) write(w io.Writer, write func(*errWriter)) (int64, error) {
+	cw := &countWriter{w: w}
+	ew := &errWriter{w: cw}
+	var bw *bufio.Writer
+	if o.Buffered {
+		bw = bufio.NewWriter(cw)
+		ew.w = bw
+	}
+	write(ew)
+	if bw != nil && ew.err == nil {
+		ew.err = bw.Flush()
+	}
+	return cw.n, ew.err
+}
+
+// errWriter writes to w until a write fails, and then keeps the error, and writes nothing more.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (w *errWriter) printf(format string, args ...any) {
+	if w.err == nil {
+		_, w.err = fmt.Fprintf(w.w, format, args...)
+	}
+}
+
+// body writes body, as it is, or as a chunked body followed by trailers. a chunked body is a chunk for each read of
+// body: a string is one.
+func (w *errWriter) body(body io.Reader, chunked bool, trailers Headers) {
+	if w.err != nil {
+		return
+	}
+	if !chunked {
+		_, w.err = io.Copy(w.w, body)
+		return
+	}
+	c := NewChunkedWriter(w.w)
+	c.Trailers = trailers
+	if _, w.err = io.Copy(c, body); w.err == nil {
+		w.err = c.Close()
+	}
+}
+
+// countWriter counts the bytes written through it.
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
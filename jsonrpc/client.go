@@ -0,0 +1,237 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// ErrClientClosed is returned by calls on a closed client, and by calls still waiting when the connection drops.
+var ErrClientClosed = errors.New("jsonrpc: client closed")
+
+// Client makes calls over a single connection. It is safe for concurrent use; concurrent calls are multiplexed
+// on the connection and their responses matched up by id.
+type Client struct {
+	conn io.ReadWriteCloser
+
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextID  uint64
+	pending map[string]chan *Response
+	err     error // set once the read loop stops
+}
+
+// Dial connects to a JSON-RPC server over TCP.
+func Dial(ctx context.Context, addr string) (*Client, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), nil
+}
+
+// NewClient starts a client on an existing connection. The client owns conn and closes it on Close.
+func NewClient(conn io.ReadWriteCloser) *Client {
+	c := &Client{conn: conn, pending: make(map[string]chan *Response)}
+	go c.readLoop()
+	return c
+}
+
+// Close closes the connection; calls in flight fail with ErrClientClosed.
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// readLoop delivers each response to the call waiting for its id.
+func (c *Client) readLoop() {
+	scanner := bufio.NewScanner(c.conn)
+	scanner.Buffer(make([]byte, 0, 4096), MaxLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		var responses []*Response
+		if len(line) > 0 && line[0] == '[' {
+			if err := json.Unmarshal(line, &responses); err != nil {
+				continue
+			}
+		} else {
+			var resp Response
+			if err := json.Unmarshal(line, &resp); err != nil {
+				continue
+			}
+			responses = []*Response{&resp}
+		}
+		for _, resp := range responses {
+			c.deliver(resp)
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.err = ErrClientClosed
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+}
+
+func (c *Client) deliver(resp *Response) {
+	c.mu.Lock()
+	ch, ok := c.pending[string(resp.ID)]
+	delete(c.pending, string(resp.ID))
+	c.mu.Unlock()
+	if ok {
+		ch <- resp
+	}
+}
+
+// register allocates an id and a channel its response will be delivered on.
+func (c *Client) register() (json.RawMessage, chan *Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, nil, c.err
+	}
+	c.nextID++
+	id := json.RawMessage(strconv.FormatUint(c.nextID, 10))
+	ch := make(chan *Response, 1)
+	c.pending[string(id)] = ch
+	return id, ch, nil
+}
+
+func (c *Client) unregister(id json.RawMessage) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, string(id))
+}
+
+func (c *Client) writeLine(v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.conn.Write(append(b, '\n'))
+	return err
+}
+
+func newRequest(method string, params any, id json.RawMessage) (*Request, error) {
+	req := &Request{JSONRPC: Version, Method: method, ID: id}
+	if params != nil {
+		b, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("jsonrpc: marshalling params for %s: %w", method, err)
+		}
+		req.Params = b
+	}
+	return req, nil
+}
+
+// Call invokes method with params and unmarshals the result into result (which may be nil to discard it).
+// a server-side error is returned as an *Error.
+func (c *Client) Call(ctx context.Context, method string, params, result any) error {
+	id, ch, err := c.register()
+	if err != nil {
+		return err
+	}
+	defer c.unregister(id)
+
+	req, err := newRequest(method, params, id)
+	if err != nil {
+		return err
+	}
+	if err := c.writeLine(req); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case resp, ok := <-ch:
+		if !ok {
+			return ErrClientClosed
+		}
+		return decodeResult(resp, result)
+	}
+}
+
+func decodeResult(resp *Response, result any) error {
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(resp.Result, result)
+}
+
+// Notify sends a notification: the server runs method but sends nothing back, so there's no way to know if it succeeded.
+func (c *Client) Notify(ctx context.Context, method string, params any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	req, err := newRequest(method, params, nil)
+	if err != nil {
+		return err
+	}
+	return c.writeLine(req)
+}
+
+// BatchCall is one element of a batch. Leave Notify false to get a response back in Result/Error.
+type BatchCall struct {
+	Method string
+	Params any
+	Notify bool
+
+	Result any   // if non-nil, the result is unmarshalled into it
+	Error  error // set after Batch returns: the call's own error, if any
+}
+
+// Batch sends calls as a single JSON array and waits for all their responses. Each call's outcome is recorded in its
+// Error field; Batch itself only returns an error if the batch couldn't be sent or the wait was cut short.
+func (c *Client) Batch(ctx context.Context, calls []*BatchCall) error {
+	batch := make([]*Request, len(calls))
+	waits := make(map[*BatchCall]chan *Response)
+	for i, call := range calls {
+		var id json.RawMessage
+		if !call.Notify {
+			var ch chan *Response
+			var err error
+			if id, ch, err = c.register(); err != nil {
+				return err
+			}
+			defer c.unregister(id)
+			waits[call] = ch
+		}
+		req, err := newRequest(call.Method, call.Params, id)
+		if err != nil {
+			return err
+		}
+		batch[i] = req
+	}
+	if err := c.writeLine(batch); err != nil {
+		return err
+	}
+
+	for call, ch := range waits {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case resp, ok := <-ch:
+			if !ok {
+				return ErrClientClosed
+			}
+			call.Error = decodeResult(resp, call.Result)
+		}
+	}
+	return nil
+}
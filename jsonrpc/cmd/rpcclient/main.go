@@ -0,0 +1,92 @@
+// rpcclient calls methods on a JSON-RPC 2.0 server over TCP and prints the results.
+//
+//	rpcclient arith.add '[1, 2, 3]'
+//	rpcclient -notify log '"hello"'
+//	rpcclient -batch arith.add '[1, 2]' arith.divide '{"a": 1, "b": 0}' echo '"hi"'
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/ekediala/jsonrpc"
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer done()
+
+	const name = "rpcclient"
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	log = log.With("app", name)
+	slog.SetDefault(log)
+
+	addr := flag.String("addr", "localhost:9000", "server address")
+	notify := flag.Bool("notify", false, "send as a notification (no response)")
+	batch := flag.Bool("batch", false, "send every method/params pair in one batch")
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		slog.ErrorContext(ctx, "main", "error", "usage: rpcclient [-addr host:port] [-notify] [-batch] method [params] [method params ...]")
+		os.Exit(1)
+	}
+
+	// params are given as raw JSON and passed through untouched.
+	var calls []*jsonrpc.BatchCall
+	for i := 0; i < len(args); i += 2 {
+		call := &jsonrpc.BatchCall{Method: args[i], Notify: *notify, Result: new(json.RawMessage)}
+		if i+1 < len(args) {
+			if !json.Valid([]byte(args[i+1])) {
+				slog.ErrorContext(ctx, "main", "error", fmt.Sprintf("params for %s are not valid JSON: %s", args[i], args[i+1]))
+				os.Exit(1)
+			}
+			call.Params = json.RawMessage(args[i+1])
+		}
+		calls = append(calls, call)
+	}
+	if len(calls) > 1 && !*batch {
+		slog.ErrorContext(ctx, "main", "error", "several calls given; pass -batch to send them together")
+		os.Exit(1)
+	}
+
+	client, err := jsonrpc.Dial(ctx, *addr)
+	if err != nil {
+		slog.ErrorContext(ctx, "main", "error", err.Error())
+		os.Exit(1)
+	}
+	defer client.Close()
+
+	if *batch {
+		err = client.Batch(ctx, calls)
+	} else if *notify {
+		err = client.Notify(ctx, calls[0].Method, calls[0].Params)
+	} else {
+		calls[0].Error = client.Call(ctx, calls[0].Method, calls[0].Params, calls[0].Result)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "main", "error", err.Error())
+		os.Exit(1)
+	}
+
+	failed := false
+	for _, call := range calls {
+		switch {
+		case call.Notify:
+			fmt.Printf("%s: sent as notification\n", call.Method)
+		case call.Error != nil:
+			failed = true
+			fmt.Printf("%s: error: %v\n", call.Method, call.Error)
+		default:
+			fmt.Printf("%s: %s\n", call.Method, *call.Result.(*json.RawMessage))
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
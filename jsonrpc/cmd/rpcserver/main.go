@@ -0,0 +1,99 @@
+// rpcserver serves a small demo JSON-RPC 2.0 service over TCP:
+//
+//	arith.add      [numbers...]         -> sum
+//	arith.divide   {"a": x, "b": y}     -> a / b, or an invalid-params error when b is 0
+//	echo           any                  -> the params, unchanged
+//	sleep          "duration"           -> waits, then returns the duration (handy for showing out-of-order replies)
+//	log            any                  -> logs the params server-side; meant to be sent as a notification
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log/slog"
+	"net"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/ekediala/jsonrpc"
+)
+
+func main() {
+	ctx, done := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer done()
+
+	const name = "rpcserver"
+	log := slog.New(slog.NewTextHandler(os.Stderr, nil))
+	log = log.With("app", name)
+	slog.SetDefault(log)
+
+	port := flag.Int("p", 9000, "port to listen on")
+	flag.Parse()
+
+	server := jsonrpc.NewServer()
+	server.Register("arith.add", add)
+	server.Register("arith.divide", divide)
+	server.Register("echo", func(_ context.Context, params json.RawMessage) (any, error) {
+		return params, nil
+	})
+	server.Register("sleep", sleep)
+	server.Register("log", func(ctx context.Context, params json.RawMessage) (any, error) {
+		slog.InfoContext(ctx, "log", "params", string(params))
+		return nil, nil
+	})
+
+	listener, err := net.Listen("tcp", ":"+strconv.Itoa(*port))
+	if err != nil {
+		slog.ErrorContext(ctx, "main", "error", err.Error())
+		os.Exit(1)
+	}
+	slog.InfoContext(ctx, "main", "message", "listening for connections", "port", *port)
+	if err := server.Serve(ctx, listener); err != nil {
+		slog.ErrorContext(ctx, "main", "error", err.Error())
+		os.Exit(1)
+	}
+	slog.InfoContext(ctx, "main", "message", "received shutdown signal")
+}
+
+func add(_ context.Context, params json.RawMessage) (any, error) {
+	var nums []float64
+	if err := json.Unmarshal(params, &nums); err != nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "arith.add takes an array of numbers")
+	}
+	var sum float64
+	for _, n := range nums {
+		sum += n
+	}
+	return sum, nil
+}
+
+func divide(_ context.Context, params json.RawMessage) (any, error) {
+	var args struct{ A, B float64 }
+	if err := json.Unmarshal(params, &args); err != nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, `arith.divide takes {"a": number, "b": number}`)
+	}
+	if args.B == 0 {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "division by zero")
+	}
+	return args.A / args.B, nil
+}
+
+func sleep(ctx context.Context, params json.RawMessage) (any, error) {
+	var s string
+	if err := json.Unmarshal(params, &s); err != nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, `sleep takes a duration string like "500ms"`)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, jsonrpc.Errorf(jsonrpc.CodeInvalidParams, "%v", err)
+	}
+	select {
+	case <-time.After(d):
+		return s, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
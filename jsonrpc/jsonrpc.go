@@ -0,0 +1,69 @@
+// Package jsonrpc implements JSON-RPC 2.0 over a stream connection, one JSON value per line.
+//
+// JSON-RPC is a thin layer over the raw byte stream: a request names a method and carries params and an id;
+// the response carries the same id and either a result or an error object. the id is what lets a client have many
+// calls in flight on one connection and match up replies that come back out of order. a request without an id is a
+// notification and gets no response at all, and an array of requests is a batch, answered with an array of responses.
+//
+// the spec doesn't define framing; we use newline-delimited JSON, which works because encoding/json never emits a raw newline.
+// see https://www.jsonrpc.org/specification
+package jsonrpc
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Version is the only protocol version this package speaks.
+const Version = "2.0"
+
+// Request is a call or, when ID is empty, a notification.
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	// ID is a JSON string or number chosen by the client. it's left raw so the server can echo it back untouched.
+	ID json.RawMessage `json:"id,omitempty"`
+}
+
+// IsNotification reports whether r expects no response.
+func (r *Request) IsNotification() bool { return len(r.ID) == 0 }
+
+// Response answers a Request with the same ID. Exactly one of Result and Error is set.
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+	ID      json.RawMessage `json:"id"` // null when the request's id couldn't be read
+}
+
+// Standard error codes.
+const (
+	CodeParseError     = -32700 // the line isn't valid JSON
+	CodeInvalidRequest = -32600 // valid JSON, but not a valid request object
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// Error is a JSON-RPC error object. Handlers can return one to control the code the caller sees;
+// any other error is reported as CodeInternalError.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Data) > 0 {
+		return fmt.Sprintf("jsonrpc: %s (%d): %s", e.Message, e.Code, e.Data)
+	}
+	return fmt.Sprintf("jsonrpc: %s (%d)", e.Message, e.Code)
+}
+
+// Errorf returns an *Error with the given code and a formatted message.
+func Errorf(code int, format string, args ...any) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+var nullID = json.RawMessage("null")
@@ -0,0 +1,154 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testServer() *Server {
+	s := NewServer()
+	s.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	s.Register("add", func(_ context.Context, params json.RawMessage) (any, error) {
+		var nums []int
+		if err := json.Unmarshal(params, &nums); err != nil {
+			return nil, Errorf(CodeInvalidParams, "want an array of numbers")
+		}
+		sum := 0
+		for _, n := range nums {
+			sum += n
+		}
+		return sum, nil
+	})
+	s.Register("sleep", func(ctx context.Context, params json.RawMessage) (any, error) {
+		var d string
+		json.Unmarshal(params, &d)
+		dur, _ := time.ParseDuration(d)
+		time.Sleep(dur)
+		return d, nil
+	})
+	s.Register("fail", func(context.Context, json.RawMessage) (any, error) {
+		return nil, errors.New("boom")
+	})
+	return s
+}
+
+// pipe connects a client to testServer over an in-memory connection.
+func pipe(t *testing.T) *Client {
+	t.Helper()
+	server, client := net.Pipe()
+	go testServer().ServeConn(context.Background(), server)
+	c := NewClient(client)
+	t.Cleanup(func() { c.Close(); server.Close() })
+	return c
+}
+
+func TestCall(t *testing.T) {
+	c := pipe(t)
+	ctx := context.Background()
+
+	var sum int
+	if err := c.Call(ctx, "add", []int{1, 2, 3}, &sum); err != nil || sum != 6 {
+		t.Errorf("Call(add, [1 2 3]) = %d, %v; want 6, nil", sum, err)
+	}
+
+	for method, wantCode := range map[string]int{
+		"missing": CodeMethodNotFound,
+		"fail":    CodeInternalError,
+	} {
+		var rpcErr *Error
+		if err := c.Call(ctx, method, nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != wantCode {
+			t.Errorf("Call(%s) error = %v, want code %d", method, err, wantCode)
+		}
+	}
+
+	var rpcErr *Error
+	if err := c.Call(ctx, "add", "not numbers", nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Errorf("Call(add, bad params) error = %v, want code %d", err, CodeInvalidParams)
+	}
+}
+
+func TestConcurrentCallsOutOfOrder(t *testing.T) {
+	c := pipe(t)
+	ctx := context.Background()
+
+	// the slow call is sent first but answered last; id correlation must still route each reply correctly.
+	results := make(chan string, 2)
+	for _, d := range []string{"50ms", "1ms"} {
+		go func() {
+			var got string
+			if err := c.Call(ctx, "sleep", d, &got); err != nil {
+				t.Error(err)
+			}
+			if got != d {
+				t.Errorf("Call(sleep, %s) = %s", d, got)
+			}
+			results <- got
+		}()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if first := <-results; first != "1ms" {
+		t.Errorf("first reply was for %s; want the fast call to finish first", first)
+	}
+	<-results
+}
+
+func TestBatch(t *testing.T) {
+	c := pipe(t)
+	var a, b int
+	calls := []*BatchCall{
+		{Method: "add", Params: []int{1, 1}, Result: &a},
+		{Method: "add", Params: []int{2, 2}, Notify: true},
+		{Method: "add", Params: []int{3, 3}, Result: &b},
+		{Method: "missing"},
+	}
+	if err := c.Batch(context.Background(), calls); err != nil {
+		t.Fatal(err)
+	}
+	if a != 2 || b != 6 {
+		t.Errorf("batch results = %d, %d; want 2, 6", a, b)
+	}
+	if calls[0].Error != nil || calls[2].Error != nil || calls[3].Error == nil {
+		t.Errorf("batch errors = %v, %v, %v; want only the missing method to fail", calls[0].Error, calls[2].Error, calls[3].Error)
+	}
+}
+
+// TestWire checks the raw line protocol, including the cases that must produce no response at all.
+func TestWire(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	go testServer().ServeConn(context.Background(), server)
+	lines := bufio.NewScanner(client)
+
+	for _, tt := range []struct {
+		send, want string // want "" means no response is expected
+	}{
+		{`{"jsonrpc":"2.0","method":"add","params":[1,2]}`, ""},
+		{`[{"jsonrpc":"2.0","method":"add","params":[1,2]}]`, ""},
+		{`{"jsonrpc":"2.0","method":"add","params":[1,2],"id":"abc"}`, `{"jsonrpc":"2.0","result":3,"id":"abc"}`},
+		{`{not json`, `"code":-32700`},
+		{`[]`, `"code":-32600`},
+		{`{"jsonrpc":"1.0","method":"add","id":1}`, `"code":-32600`},
+		{`[1]`, `[{"jsonrpc":"2.0","error":{"code":-32600`},
+	} {
+		if _, err := io.WriteString(client, tt.send+"\n"); err != nil {
+			t.Fatal(err)
+		}
+		if tt.want == "" {
+			continue
+		}
+		if !lines.Scan() {
+			t.Fatalf("no response to %s", tt.send)
+		}
+		if got := lines.Text(); !strings.Contains(got, tt.want) {
+			t.Errorf("response to %s = %s, want it to contain %s", tt.send, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"sync"
+)
+
+// Handler implements one method. params is the raw params value (nil if the request had none);
+// the returned result is marshalled into the response. returning an *Error controls the error code.
+type Handler func(ctx context.Context, params json.RawMessage) (result any, err error)
+
+// MaxLineSize bounds a single request line (or batch).
+const MaxLineSize = 1 << 20
+
+// Server dispatches JSON-RPC requests to registered handlers.
+type Server struct {
+	Logger *slog.Logger // defaults to slog.Default()
+
+	mu      sync.RWMutex
+	methods map[string]Handler
+}
+
+// NewServer returns a server with no methods registered.
+func NewServer() *Server {
+	return &Server{methods: make(map[string]Handler)}
+}
+
+// Register makes h answer calls to method, replacing any previous handler.
+func (s *Server) Register(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.methods[method] = h
+}
+
+func (s *Server) log() *slog.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return slog.Default()
+}
+
+// Serve accepts connections on l and serves each in its own goroutine until ctx is done or l fails.
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			s.log().InfoContext(ctx, "jsonrpc", "message", "client connected", "remote", conn.RemoteAddr().String())
+			s.ServeConn(ctx, conn)
+			s.log().InfoContext(ctx, "jsonrpc", "message", "client disconnected", "remote", conn.RemoteAddr().String())
+		}()
+	}
+}
+
+// ServeConn reads requests from rw, one per line, until EOF or ctx is done.
+// each line is handled in its own goroutine, so a slow call doesn't hold up the ones behind it;
+// that's why responses can come back in a different order than the requests, and why ids matter.
+func (s *Server) ServeConn(ctx context.Context, rw io.ReadWriter) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		writeMu sync.Mutex
+		wg      sync.WaitGroup
+	)
+	write := func(v any) {
+		b, err := json.Marshal(v)
+		if err != nil {
+			s.log().ErrorContext(ctx, "jsonrpc", "error", err.Error())
+			return
+		}
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		if _, err := rw.Write(append(b, '\n')); err != nil {
+			s.log().ErrorContext(ctx, "jsonrpc", "error", err.Error())
+		}
+	}
+
+	scanner := bufio.NewScanner(rw)
+	scanner.Buffer(make([]byte, 0, 4096), MaxLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		line = bytes.Clone(line) // the scanner reuses its buffer
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if resp := s.handleLine(ctx, line); resp != nil {
+				write(resp)
+			}
+		}()
+	}
+	if err := scanner.Err(); err != nil {
+		s.log().ErrorContext(ctx, "jsonrpc", "error", err.Error())
+	}
+	wg.Wait()
+}
+
+// handleLine handles a single request or a batch, returning what to send back: a *Response, a []*Response, or nil for nothing.
+func (s *Server) handleLine(ctx context.Context, line []byte) any {
+	if line[0] != '[' {
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			return errorResponse(nullID, Errorf(CodeParseError, "parse error: %v", err))
+		}
+		if resp := s.handle(ctx, &req); resp != nil {
+			return resp
+		}
+		return nil // not the nil *Response, which would be a non-nil any.
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(line, &batch); err != nil {
+		return errorResponse(nullID, Errorf(CodeParseError, "parse error: %v", err))
+	}
+	if len(batch) == 0 {
+		return errorResponse(nullID, Errorf(CodeInvalidRequest, "empty batch"))
+	}
+
+	// batch members are independent, so run them concurrently too; responses go back in request order for readability.
+	responses := make([]*Response, len(batch))
+	var wg sync.WaitGroup
+	for i, raw := range batch {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var req Request
+			if err := json.Unmarshal(raw, &req); err != nil {
+				responses[i] = errorResponse(nullID, Errorf(CodeInvalidRequest, "invalid request: %v", err))
+				return
+			}
+			responses[i] = s.handle(ctx, &req)
+		}()
+	}
+	wg.Wait()
+
+	var out []*Response
+	for _, resp := range responses {
+		if resp != nil {
+			out = append(out, resp)
+		}
+	}
+	if len(out) == 0 {
+		return nil // a batch of notifications gets no response at all.
+	}
+	return out
+}
+
+// handle runs one request and returns its response, or nil for a notification.
+func (s *Server) handle(ctx context.Context, req *Request) *Response {
+	id := req.ID
+	if req.IsNotification() {
+		id = nullID
+	}
+	if req.JSONRPC != Version || req.Method == "" {
+		return errorResponse(id, Errorf(CodeInvalidRequest, "invalid request: want jsonrpc %q and a method", Version))
+	}
+
+	s.mu.RLock()
+	h, ok := s.methods[req.Method]
+	s.mu.RUnlock()
+
+	var (
+		result any
+		err    error
+	)
+	if !ok {
+		err = Errorf(CodeMethodNotFound, "method not found: %s", req.Method)
+	} else {
+		result, err = h(ctx, req.Params)
+	}
+
+	if req.IsNotification() {
+		if err != nil {
+			s.log().WarnContext(ctx, "jsonrpc", "method", req.Method, "notification error", err.Error())
+		}
+		return nil
+	}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		return errorResponse(id, rpcErr)
+	}
+
+	b, err := json.Marshal(result)
+	if err != nil {
+		return errorResponse(id, Errorf(CodeInternalError, "marshalling result: %v", err))
+	}
+	return &Response{JSONRPC: Version, Result: b, ID: id}
+}
+
+func errorResponse(id json.RawMessage, err *Error) *Response {
+	return &Response{JSONRPC: Version, Error: err, ID: id}
+}
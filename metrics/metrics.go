@@ -0,0 +1,399 @@
+// Package metrics collects counters, gauges, and timers in-process and flushes them to a StatsD or Graphite
+// endpoint over UDP.
+//
+// StatsD is the classic "fire and forget" metrics protocol: one text line per measurement, sent over UDP so a dead
+// collector never slows the application down.
+//
+//	requests:1|c|#route:home      counter: add 1
+//	conns.active:12|g             gauge: the value is now 12
+//	latency:3.2|ms                timer: one sample, in milliseconds
+//
+// sending a datagram for every increment is wasteful, though, so the Registry aggregates between flushes: a
+// thousand Inc calls become one "requests:1000|c" line, and lines are packed into datagrams that fit in an ethernet
+// frame. Graphite's plaintext protocol ("name value timestamp") is supported too; it has no metric types, so timers
+// are summarized (count, mean, min, max, percentiles) before they're sent.
+//
+// tags are key/value pairs. StatsD has no standard for them; this uses the DogStatsD "|#k:v" extension that most
+// servers (Datadog, Telegraf, statsd_exporter) understand. Graphite gets its own tagged form, "name;k=v".
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Format selects the wire protocol.
+type Format int
+
+const (
+	// StatsD sends typed lines ("name:value|type") and leaves percentile math to the server.
+	StatsD Format = iota
+	// Graphite sends plaintext "name value timestamp" lines; the Registry does all the aggregation itself.
+	Graphite
+)
+
+func (f Format) String() string {
+	if f == Graphite {
+		return "graphite"
+	}
+	return "statsd"
+}
+
+// DefaultMaxPacketSize keeps a datagram inside a 1500-byte ethernet MTU once IP and UDP headers are added, so it
+// never gets fragmented. StatsD's docs suggest 1432 for the internet and up to 8932 on a LAN with jumbo frames.
+const DefaultMaxPacketSize = 1432
+
+// DefaultMaxSamples bounds how many timer samples are kept per flush interval.
+const DefaultMaxSamples = 1024
+
+// Config configures a Registry.
+type Config struct {
+	Addr          string        // UDP host:port of the collector
+	Format        Format        // StatsD or Graphite
+	Prefix        string        // prepended to every name, like "tcpupperecho."
+	Tags          []string      // key/value pairs added to every metric
+	FlushInterval time.Duration // defaults to 10s
+	MaxPacketSize int           // defaults to DefaultMaxPacketSize
+	MaxSamples    int           // timer samples kept per interval; defaults to DefaultMaxSamples
+
+	Logger *slog.Logger // for flush errors; defaults to slog.Default()
+}
+
+// Registry holds metrics and flushes them periodically. It is safe for concurrent use.
+//
+// a nil Registry hands out nil metrics, and nil metrics ignore updates, so a program can keep its instrumentation
+// in place and simply not create a Registry when metrics are turned off.
+type Registry struct {
+	cfg  Config
+	conn net.Conn
+	log  *slog.Logger
+	now  func() time.Time
+
+	mu     sync.Mutex
+	byKey  map[string]metric
+	series []metric // in registration order, so output is stable
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// metric is one series: a name plus tags, and the aggregate since the last flush.
+type metric interface {
+	// collect appends the lines for this interval and resets the aggregate.
+	collect(lines []string, f Format, ts int64) []string
+}
+
+type series struct {
+	name string
+	tags []string // sorted "k", "v" pairs, sanitized
+}
+
+// New dials the collector and starts flushing every cfg.FlushInterval. Close flushes one last time and stops.
+func New(cfg Config) (*Registry, error) {
+	if cfg.Addr == "" {
+		return nil, errors.New("metrics: missing collector address")
+	}
+	if len(cfg.Tags)%2 != 0 {
+		return nil, fmt.Errorf("metrics: tags must be key/value pairs; got %q", cfg.Tags)
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = 10 * time.Second
+	}
+	if cfg.MaxPacketSize == 0 {
+		cfg.MaxPacketSize = DefaultMaxPacketSize
+	}
+	if cfg.MaxSamples == 0 {
+		cfg.MaxSamples = DefaultMaxSamples
+	}
+	if cfg.Logger == nil {
+		cfg.Logger = slog.Default()
+	}
+
+	// "dialing" UDP only picks the destination; nothing is sent, so this succeeds even if no collector is listening.
+	conn, err := net.Dial("udp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics: %w", err)
+	}
+	r := &Registry{
+		cfg:   cfg,
+		conn:  conn,
+		log:   cfg.Logger,
+		now:   time.Now,
+		byKey: map[string]metric{},
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	go r.loop()
+	return r, nil
+}
+
+func (r *Registry) loop() {
+	defer close(r.done)
+	t := time.NewTicker(r.cfg.FlushInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-t.C:
+			if err := r.Flush(); err != nil {
+				r.log.Warn("metrics", "error", err.Error())
+			}
+		}
+	}
+}
+
+// Close stops the flush loop, sends whatever has accumulated since the last flush, and closes the connection.
+func (r *Registry) Close() error {
+	if r == nil {
+		return nil
+	}
+	close(r.stop)
+	<-r.done
+	err := r.Flush()
+	return errors.Join(err, r.conn.Close())
+}
+
+// Counter returns the counter with this name and tags, creating it on first use. tags are key/value pairs.
+func (r *Registry) Counter(name string, tags ...string) *Counter {
+	return register(r, "c", name, tags, func(s series) *Counter { return &Counter{series: s} })
+}
+
+// Gauge returns the gauge with this name and tags, creating it on first use.
+func (r *Registry) Gauge(name string, tags ...string) *Gauge {
+	return register(r, "g", name, tags, func(s series) *Gauge { return &Gauge{series: s} })
+}
+
+// Timer returns the timer with this name and tags, creating it on first use.
+func (r *Registry) Timer(name string, tags ...string) *Timer {
+	return register(r, "ms", name, tags, func(s series) *Timer { return &Timer{series: s, limit: r.cfg.MaxSamples} })
+}
+
+// register finds or creates a metric. the same name and tags (in any order) always give back the same metric; asking
+// for it as a different type panics, since the two would clobber each other at the collector.
+func register[M metric](r *Registry, kind, name string, tags []string, create func(series) M) M {
+	var zero M
+	if r == nil {
+		return zero
+	}
+	if len(tags)%2 != 0 {
+		panic(fmt.Sprintf("metrics: tags for %q must be key/value pairs; got %q", name, tags))
+	}
+	s := series{name: sanitize(r.cfg.Prefix + name), tags: sortTags(append(append([]string{}, r.cfg.Tags...), tags...))}
+	key := s.name + "|" + strings.Join(s.tags, "\x00")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if m, ok := r.byKey[key]; ok {
+		typed, ok := m.(M)
+		if !ok {
+			panic(fmt.Sprintf("metrics: %q already registered as a different type than %q", name, kind))
+		}
+		return typed
+	}
+	m := create(s)
+	r.byKey[key] = m
+	r.series = append(r.series, m)
+	return m
+}
+
+// Flush sends everything aggregated since the last flush, packed into as few datagrams as fit.
+func (r *Registry) Flush() error {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	ts := r.now().Unix()
+	var lines []string
+	for _, m := range r.series {
+		lines = m.collect(lines, r.cfg.Format, ts)
+	}
+	r.mu.Unlock()
+
+	var errs []error
+	for _, p := range pack(lines, r.cfg.MaxPacketSize) {
+		// a refused datagram (nothing listening, reported back via ICMP) shows up as an error on a later write;
+		// keep sending the rest either way.
+		if _, err := r.conn.Write(p); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("metrics: sending to %s: %w", r.cfg.Addr, errs[0])
+	}
+	return nil
+}
+
+// Counter counts events. Only the increase since the last flush is sent; the collector adds it up.
+type Counter struct {
+	series
+	n atomic.Int64
+}
+
+// Add adds n to the counter.
+func (c *Counter) Add(n int64) {
+	if c != nil {
+		c.n.Add(n)
+	}
+}
+
+// Inc adds one.
+func (c *Counter) Inc() { c.Add(1) }
+
+func (c *Counter) collect(lines []string, f Format, ts int64) []string {
+	n := c.n.Swap(0)
+	if f == StatsD {
+		if n == 0 {
+			return lines // nothing happened; StatsD treats a missing counter as zero anyway.
+		}
+		return append(lines, statsdLine(&c.series, "", formatInt(n), "c"))
+	}
+	// Graphite stores whatever value arrives, so send zeros too or the graph shows a gap instead of a flat line.
+	return append(lines, graphiteLine(&c.series, "", formatInt(n), ts))
+}
+
+// Gauge is a value that goes up and down, like open connections. The latest value is sent on every flush.
+type Gauge struct {
+	series
+	bits atomic.Uint64 // math.Float64bits of the value
+}
+
+// Set sets the gauge.
+func (g *Gauge) Set(v float64) {
+	if g != nil {
+		g.bits.Store(math.Float64bits(v))
+	}
+}
+
+// Add adds delta (which can be negative) to the gauge.
+func (g *Gauge) Add(delta float64) {
+	for g != nil {
+		old := g.bits.Load()
+		if g.bits.CompareAndSwap(old, math.Float64bits(math.Float64frombits(old)+delta)) {
+			return
+		}
+	}
+}
+
+// Value returns the gauge's current value.
+func (g *Gauge) Value() float64 {
+	if g == nil {
+		return 0
+	}
+	return math.Float64frombits(g.bits.Load())
+}
+
+func (g *Gauge) collect(lines []string, f Format, ts int64) []string {
+	v := g.Value()
+	if f == Graphite {
+		return append(lines, graphiteLine(&g.series, "", formatFloat(v), ts))
+	}
+	// a StatsD gauge with a leading sign is a *change*: "conns:-1|g" means one fewer, not minus one. to set a
+	// negative value the protocol's own workaround is to zero the gauge first, in the same packet.
+	if v < 0 {
+		lines = append(lines, statsdLine(&g.series, "", "0", "g"))
+	}
+	return append(lines, statsdLine(&g.series, "", formatFloat(v), "g"))
+}
+
+// Timer records durations. StatsD gets the raw samples and computes percentiles itself; Graphite gets a summary.
+type Timer struct {
+	series
+	limit int // samples kept per interval
+
+	mu      sync.Mutex
+	count   int64
+	sum     time.Duration
+	min     time.Duration
+	maxSeen time.Duration
+	samples []time.Duration
+	seen    uint64 // random state for reservoir sampling
+}
+
+// Observe records one duration.
+func (t *Timer) Observe(d time.Duration) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.count++
+	t.sum += d
+	if t.count == 1 || d < t.min {
+		t.min = d
+	}
+	if d > t.maxSeen {
+		t.maxSeen = d
+	}
+	// keep at most limit samples with reservoir sampling: once full, the i-th sample replaces a random slot with
+	// probability limit/i, so the kept ones stay a uniform sample of the whole interval and memory stays bounded.
+	if len(t.samples) < t.limit {
+		t.samples = append(t.samples, d)
+		return
+	}
+	t.seen = t.seen*6364136223846793005 + 1442695040888963407 // a tiny LCG is plenty for picking slots
+	if j := (t.seen >> 33) % uint64(t.count); j < uint64(t.limit) {
+		t.samples[j] = d
+	}
+}
+
+// Since records the time elapsed since start; handy as defer t.Since(time.Now()).
+func (t *Timer) Since(start time.Time) {
+	if t != nil {
+		t.Observe(time.Since(start))
+	}
+}
+
+func (t *Timer) collect(lines []string, f Format, ts int64) []string {
+	t.mu.Lock()
+	count, sum, lo, hi, samples := t.count, t.sum, t.min, t.maxSeen, t.samples
+	t.count, t.sum, t.min, t.maxSeen, t.samples = 0, 0, 0, 0, nil
+	t.mu.Unlock()
+	if count == 0 {
+		return lines
+	}
+
+	if f == StatsD {
+		// when samples were dropped, the sample rate tells the server each kept one stands for 1/rate events,
+		// so its counts stay right.
+		typ := "ms"
+		if int64(len(samples)) < count {
+			typ += "|@" + formatFloat(float64(len(samples))/float64(count))
+		}
+		for _, d := range samples {
+			lines = append(lines, statsdLine(&t.series, "", formatFloat(millis(d)), typ))
+		}
+		return lines
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	lines = append(lines,
+		graphiteLine(&t.series, ".count", formatInt(count), ts),
+		graphiteLine(&t.series, ".mean", formatFloat(millis(sum)/float64(count)), ts),
+		graphiteLine(&t.series, ".min", formatFloat(millis(lo)), ts),
+		graphiteLine(&t.series, ".max", formatFloat(millis(hi)), ts),
+	)
+	for _, p := range []int{50, 90, 99} {
+		lines = append(lines, graphiteLine(&t.series, fmt.Sprintf(".p%d", p), formatFloat(millis(percentile(samples, p))), ts))
+	}
+	return lines
+}
+
+// percentile picks the nearest-rank percentile from sorted samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100 // ceil(n*p/100), 1-based
+	if i < 1 {
+		i = 1
+	}
+	return sorted[i-1]
+}
+
+func millis(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
@@ -0,0 +1,189 @@
+package metrics
+
+import (
+	"io"
+	"log/slog"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// collector listens on a loopback UDP port and returns a Registry pointed at it, plus a func that flushes and
+// returns the lines received.
+func collector(t *testing.T, cfg Config) (*Registry, func() []string) {
+	t.Helper()
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	cfg.Addr = conn.LocalAddr().String()
+	cfg.FlushInterval = time.Hour // tests flush by hand
+	cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	r, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	r.now = func() time.Time { return time.Unix(1700000000, 0) }
+
+	return r, func() []string {
+		t.Helper()
+		if err := r.Flush(); err != nil {
+			t.Fatalf("Flush() returned error: %v", err)
+		}
+		var lines []string
+		buf := make([]byte, 65536)
+		for {
+			conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return lines
+			}
+			lines = append(lines, strings.Split(string(buf[:n]), "\n")...)
+		}
+	}
+}
+
+func TestStatsD(t *testing.T) {
+	r, flush := collector(t, Config{Prefix: "app.", Tags: []string{"host", "a"}})
+
+	hits := r.Counter("hits", "route", "home")
+	for range 1000 {
+		hits.Inc()
+	}
+	r.Counter("hits", "route", "home").Add(5) // same series
+	r.Gauge("temp").Set(-3.5)
+	r.Gauge("conns", "zone", "x", "env", "dev").Set(12)
+	r.Timer("latency").Observe(1500 * time.Microsecond)
+
+	want := []string{
+		"app.hits:1005|c|#host:a,route:home",
+		"app.temp:0|g|#host:a",
+		"app.temp:-3.5|g|#host:a",
+		"app.conns:12|g|#env:dev,host:a,zone:x",
+		"app.latency:1.5|ms|#host:a",
+	}
+	if got := flush(); !reflect.DeepEqual(got, want) {
+		t.Errorf("first flush:\n got %q\nwant %q", got, want)
+	}
+
+	// counters and timers start over; gauges keep their value.
+	want = []string{
+		"app.temp:0|g|#host:a",
+		"app.temp:-3.5|g|#host:a",
+		"app.conns:12|g|#env:dev,host:a,zone:x",
+	}
+	if got := flush(); !reflect.DeepEqual(got, want) {
+		t.Errorf("second flush:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestGraphite(t *testing.T) {
+	r, flush := collector(t, Config{Format: Graphite})
+
+	r.Counter("hits", "route", "home").Add(3)
+	r.Counter("idle").Add(0)
+	latency := r.Timer("latency")
+	for i := 1; i <= 100; i++ {
+		latency.Observe(time.Duration(i) * time.Millisecond)
+	}
+
+	want := []string{
+		"hits;route=home 3 1700000000",
+		"idle 0 1700000000",
+		"latency.count 100 1700000000",
+		"latency.mean 50.5 1700000000",
+		"latency.min 1 1700000000",
+		"latency.max 100 1700000000",
+		"latency.p50 50 1700000000",
+		"latency.p90 90 1700000000",
+		"latency.p99 99 1700000000",
+	}
+	if got := flush(); !reflect.DeepEqual(got, want) {
+		t.Errorf("flush:\n got %q\nwant %q", got, want)
+	}
+}
+
+func TestTimerSampling(t *testing.T) {
+	r, flush := collector(t, Config{MaxSamples: 10})
+
+	latency := r.Timer("latency")
+	for range 1000 {
+		latency.Observe(time.Millisecond)
+	}
+	got := flush()
+	if len(got) != 10 {
+		t.Fatalf("flush sent %d samples, want 10: %q", len(got), got)
+	}
+	for _, line := range got {
+		if line != "latency:1|ms|@0.01" {
+			t.Errorf("sample line = %q, want %q", line, "latency:1|ms|@0.01")
+		}
+	}
+}
+
+func TestPack(t *testing.T) {
+	lines := []string{"aaaa", "bbbb", "cccc", strings.Repeat("x", 20), "dd"}
+	var got []string
+	for _, p := range pack(lines, 10) {
+		got = append(got, string(p))
+	}
+	want := []string{"aaaa\nbbbb", "cccc", strings.Repeat("x", 20), "dd"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pack() = %q, want %q", got, want)
+	}
+}
+
+func TestSanitize(t *testing.T) {
+	r, flush := collector(t, Config{})
+	r.Counter("bad name:x|y", "k#", "v,w").Inc()
+	if got, want := flush(), []string{"bad_name_x_y:1|c|#k_:v_w"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("flush = %q, want %q", got, want)
+	}
+}
+
+func TestRegisterTypeMismatch(t *testing.T) {
+	r, _ := collector(t, Config{})
+	r.Counter("x")
+	defer func() {
+		if recover() == nil {
+			t.Error("Gauge() with a counter's name didn't panic")
+		}
+	}()
+	r.Gauge("x")
+}
+
+func TestParseAddr(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		format Format
+		addr   string
+	}{
+		{"localhost", StatsD, "localhost:8125"},
+		{"statsd://10.0.0.1:9125", StatsD, "10.0.0.1:9125"},
+		{"graphite://carbon", Graphite, "carbon:2003"},
+		{"[::1]:8125", StatsD, "[::1]:8125"},
+	} {
+		f, addr, err := ParseAddr(tc.in)
+		if err != nil || f != tc.format || addr != tc.addr {
+			t.Errorf("ParseAddr(%q) = %v, %q, %v; want %v, %q, nil", tc.in, f, addr, err, tc.format, tc.addr)
+		}
+	}
+	if _, _, err := ParseAddr("http://localhost"); err == nil {
+		t.Error("ParseAddr(http://localhost) returned no error")
+	}
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+	r.Counter("hits").Inc()
+	r.Gauge("conns").Add(1)
+	r.Timer("latency").Since(time.Now())
+	if err := r.Close(); err != nil {
+		t.Errorf("Close() on a nil Registry returned error: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// statsdLine formats one StatsD line, with tags in the DogStatsD style:
+//
+//	name:value|type|#k1:v1,k2:v2
+func statsdLine(s *series, suffix, value, typ string) string {
+	var b strings.Builder
+	b.WriteString(s.name + suffix + ":" + value + "|" + typ)
+	for i := 0; i < len(s.tags); i += 2 {
+		if i == 0 {
+			b.WriteString("|#")
+		} else {
+			b.WriteByte(',')
+		}
+		b.WriteString(s.tags[i] + ":" + s.tags[i+1])
+	}
+	return b.String()
+}
+
+// graphiteLine formats one line of Graphite's plaintext protocol, with tags in its 1.1+ tagged-series form:
+//
+//	name;k1=v1;k2=v2 value unix-timestamp
+func graphiteLine(s *series, suffix, value string, ts int64) string {
+	var b strings.Builder
+	b.WriteString(s.name + suffix)
+	for i := 0; i < len(s.tags); i += 2 {
+		b.WriteString(";" + s.tags[i] + "=" + s.tags[i+1])
+	}
+	fmt.Fprintf(&b, " %s %d", value, ts)
+	return b.String()
+}
+
+// pack joins lines with newlines into packets of at most size bytes. StatsD and carbon both accept several
+// newline-separated lines in one datagram. a line longer than size goes out alone rather than being dropped.
+func pack(lines []string, size int) [][]byte {
+	var packets [][]byte
+	var cur []byte
+	for _, line := range lines {
+		if len(cur) > 0 && len(cur)+1+len(line) > size {
+			packets = append(packets, cur)
+			cur = nil
+		}
+		if len(cur) > 0 {
+			cur = append(cur, '\n')
+		}
+		cur = append(cur, line...)
+	}
+	if len(cur) > 0 {
+		packets = append(packets, cur)
+	}
+	return packets
+}
+
+// sanitize replaces the characters that are part of either protocol's syntax (':', '|', '@', '#', ',', ';', '=',
+// and whitespace) with '_', so a stray one in a name or tag can't corrupt the line.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ':', '|', '@', '#', ',', ';', '=', ' ', '\t', '\n', '\r':
+			return '_'
+		}
+		return r
+	}, s)
+}
+
+// sortTags sanitizes key/value pairs and sorts them by key, so the same tags in any order make the same series.
+// a later value for a key replaces an earlier one, which lets a metric override a common tag from Config.
+func sortTags(pairs []string) []string {
+	values := map[string]string{}
+	for i := 0; i < len(pairs); i += 2 {
+		values[sanitize(pairs[i])] = sanitize(pairs[i+1])
+	}
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	tags := make([]string, 0, 2*len(keys))
+	for _, k := range keys {
+		tags = append(tags, k, values[k])
+	}
+	return tags
+}
+
+func formatInt(n int64) string { return strconv.FormatInt(n, 10) }
+
+// formatFloat writes the shortest form that round-trips, without an exponent: "12", "0.5", "3.25".
+func formatFloat(f float64) string { return strconv.FormatFloat(f, 'f', -1, 64) }
+
+// ParseAddr splits a collector address like "statsd://localhost:8125" or "graphite://10.0.0.5:2003" into a format
+// and a host:port for Config. a bare host:port means StatsD, and a missing port means the format's usual one.
+func ParseAddr(s string) (Format, string, error) {
+	scheme := "statsd"
+	if sch, rest, ok := strings.Cut(s, "://"); ok {
+		scheme, s = sch, rest
+	}
+	var f Format
+	port := "8125"
+	switch scheme {
+	case "statsd":
+	case "graphite":
+		f, port = Graphite, "2003"
+	default:
+		return 0, "", fmt.Errorf("metrics: unsupported scheme %q (want statsd or graphite)", scheme)
+	}
+	if _, _, err := net.SplitHostPort(s); err != nil {
+		s = net.JoinHostPort(s, port)
+	}
+	return f, s, nil
+}
@@ -0,0 +1,289 @@
+// Package pool keeps dialed connections around so they can be reused instead of paying for a new
+// TCP (and maybe TLS) handshake on every request.
+//
+// connections are pooled per address. a Pool bounds how many connections to an address can be checked out
+// at once (MaxActive) and how many are kept idle for later (MaxIdle); idle connections older than IdleTimeout,
+// or that fail the HealthCheck, are closed rather than handed out.
+package pool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned by Get after the pool has been closed.
+var ErrClosed = errors.New("pool: closed")
+
+// DefaultMaxIdle is used when Config.MaxIdle is zero.
+const DefaultMaxIdle = 2
+
+// Config tunes a Pool. The zero value dials TCP, keeps DefaultMaxIdle idle connections per address,
+// and places no limit on active connections or on how long a connection can sit idle.
+type Config struct {
+	// Dial opens a new connection to addr. nil means a plain net.Dialer over TCP.
+	Dial func(ctx context.Context, addr string) (net.Conn, error)
+
+	// MaxIdle is the number of idle connections kept per address. zero means DefaultMaxIdle; negative means none.
+	MaxIdle int
+
+	// MaxActive limits how many connections to one address may be checked out at once.
+	// when the limit is reached, Get blocks until a connection is returned or its context is done. zero means no limit.
+	MaxActive int
+
+	// IdleTimeout closes idle connections that have been unused for longer than this. zero means never.
+	IdleTimeout time.Duration
+
+	// HealthCheck, if non-nil, is run on an idle connection before Get hands it out; connections that fail it are closed.
+	// ProbeClosed is a reasonable choice for protocols where the server never speaks first.
+	HealthCheck func(net.Conn) error
+}
+
+// Pool is a set of reusable connections keyed by address. It is safe for concurrent use.
+type Pool struct {
+	cfg Config
+
+	mu     sync.Mutex
+	hosts  map[string]*host
+	closed bool
+}
+
+// host holds the per-address state.
+type host struct {
+	idle []idleConn    // most recently returned last
+	sem  chan struct{} // one token per checked-out connection; nil if MaxActive is unlimited
+}
+
+type idleConn struct {
+	conn  net.Conn
+	since time.Time
+}
+
+// New returns a Pool configured by cfg.
+func New(cfg Config) *Pool {
+	if cfg.Dial == nil {
+		var d net.Dialer
+		cfg.Dial = func(ctx context.Context, addr string) (net.Conn, error) {
+			return d.DialContext(ctx, "tcp", addr)
+		}
+	}
+	if cfg.MaxIdle == 0 {
+		cfg.MaxIdle = DefaultMaxIdle
+	}
+	return &Pool{cfg: cfg, hosts: make(map[string]*host)}
+}
+
+// host returns the state for addr, creating it if needed. p.mu must be held.
+func (p *Pool) host(addr string) *host {
+	h, ok := p.hosts[addr]
+	if !ok {
+		h = new(host)
+		if p.cfg.MaxActive > 0 {
+			h.sem = make(chan struct{}, p.cfg.MaxActive)
+		}
+		p.hosts[addr] = h
+	}
+	return h
+}
+
+// Get returns a connection to addr: a healthy idle one if there is one, otherwise a freshly dialed one.
+// it blocks while MaxActive connections to addr are checked out, until one is released or ctx is done.
+// the caller must hand the connection back with Put, or Close it if it's no longer usable.
+func (p *Pool) Get(ctx context.Context, addr string) (*Conn, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, ErrClosed
+	}
+	h := p.host(addr)
+	p.mu.Unlock()
+
+	if h.sem != nil {
+		select {
+		case h.sem <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	for {
+		ic, ok, err := p.popIdle(h)
+		if err != nil {
+			h.release()
+			return nil, err
+		}
+		if !ok {
+			break
+		}
+		if p.cfg.IdleTimeout > 0 && time.Since(ic.since) > p.cfg.IdleTimeout {
+			ic.conn.Close()
+			continue
+		}
+		if p.cfg.HealthCheck != nil && p.cfg.HealthCheck(ic.conn) != nil {
+			ic.conn.Close()
+			continue
+		}
+		return &Conn{Conn: ic.conn, pool: p, addr: addr, h: h}, nil
+	}
+
+	conn, err := p.cfg.Dial(ctx, addr)
+	if err != nil {
+		h.release()
+		return nil, err
+	}
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		// Close ran while we were dialing; don't hand out a connection it can't know about.
+		conn.Close()
+		h.release()
+		return nil, ErrClosed
+	}
+	return &Conn{Conn: conn, pool: p, addr: addr, h: h}, nil
+}
+
+// popIdle takes the most recently returned idle connection, if any. it fails with ErrClosed once the pool is
+// closed, so Get never hands out a connection Close is closing.
+func (p *Pool) popIdle(h *host) (idleConn, bool, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return idleConn{}, false, ErrClosed
+	}
+	if len(h.idle) == 0 {
+		return idleConn{}, false, nil
+	}
+	ic := h.idle[len(h.idle)-1]
+	h.idle = h.idle[:len(h.idle)-1]
+	return ic, true, nil
+}
+
+// Put returns c to the pool so a later Get can reuse it. if the pool already holds MaxIdle idle connections
+// for the address, or has been closed, the oldest connection is closed instead.
+// only return connections that are in a clean state, e.g. after a response has been fully read.
+func (p *Pool) Put(c *Conn) {
+	if !c.release() {
+		return
+	}
+
+	p.mu.Lock()
+	if p.closed || p.cfg.MaxIdle < 0 {
+		p.mu.Unlock()
+		c.Conn.Close()
+		return
+	}
+	h := c.h
+	h.idle = append(h.idle, idleConn{conn: c.Conn, since: time.Now()})
+	var evicted []idleConn
+	if n := len(h.idle) - p.cfg.MaxIdle; n > 0 {
+		evicted = append(evicted, h.idle[:n]...)
+		h.idle = append(h.idle[:0], h.idle[n:]...)
+	}
+	p.mu.Unlock()
+
+	for _, ic := range evicted {
+		ic.conn.Close()
+	}
+}
+
+// Stats describes the connections to one address.
+type Stats struct {
+	Idle, Active int
+}
+
+// Stats reports idle and checked-out connection counts for addr. Active is only tracked when MaxActive is set.
+func (p *Pool) Stats(addr string) Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	h, ok := p.hosts[addr]
+	if !ok {
+		return Stats{}
+	}
+	return Stats{Idle: len(h.idle), Active: len(h.sem)}
+}
+
+// Close closes every idle connection and makes Get fail with ErrClosed, from now on, and in any call still waiting
+// for a connection.
+// connections that are checked out are closed when they're Put back.
+func (p *Pool) Close() error {
+	// take the idle connections while holding the lock, so a Get can't pop one we're about to close, and close
+	// them after letting go of it.
+	p.mu.Lock()
+	p.closed = true
+	var idle []idleConn
+	for _, h := range p.hosts {
+		idle = append(idle, h.idle...)
+		h.idle = nil
+	}
+	p.hosts = make(map[string]*host)
+	p.mu.Unlock()
+
+	var errs []error
+	for _, ic := range idle {
+		errs = append(errs, ic.conn.Close())
+	}
+	return errors.Join(errs...)
+}
+
+func (h *host) release() {
+	if h.sem != nil {
+		<-h.sem
+	}
+}
+
+// Conn is a connection checked out of a Pool. Closing it closes the underlying connection and frees its slot;
+// use Pool.Put instead to keep it for reuse.
+type Conn struct {
+	net.Conn
+	pool *Pool
+	addr string
+	h    *host
+
+	once sync.Once
+}
+
+// Addr is the address the connection was requested for.
+func (c *Conn) Addr() string { return c.addr }
+
+// Close closes the underlying connection and releases its slot in the pool.
+// calling Close after Put (or twice) does nothing.
+func (c *Conn) Close() error {
+	if !c.release() {
+		return nil
+	}
+	return c.Conn.Close()
+}
+
+// release frees the connection's MaxActive slot the first time it's called, and reports whether it did.
+func (c *Conn) release() (first bool) {
+	c.once.Do(func() {
+		c.h.release()
+		first = true
+	})
+	return first
+}
+
+// ProbeClosed is a HealthCheck for idle connections to servers that never send unprompted data (like HTTP/1.1).
+// it peeks at the connection with a tiny read deadline: a timeout means the connection is quiet and healthy;
+// EOF means the peer hung up; and unexpected data means the connection is out of sync. the deadline is cleared afterwards.
+func ProbeClosed(conn net.Conn) error {
+	if err := conn.SetReadDeadline(time.Now().Add(time.Millisecond)); err != nil {
+		return err
+	}
+	defer conn.SetReadDeadline(time.Time{})
+
+	var b [1]byte
+	_, err := conn.Read(b[:])
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return nil
+	case err != nil:
+		return err
+	default:
+		return errors.New("pool: unexpected data on idle connection")
+	}
+}
@@ -0,0 +1,249 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// listen starts a TCP server that accepts connections and holds them open until the test ends.
+func listen(t *testing.T) (addr string, accepted *atomic.Int32) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	accepted = new(atomic.Int32)
+	conns := make(chan net.Conn, 64)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted.Add(1)
+			conns <- conn
+		}
+	}()
+	t.Cleanup(func() {
+		l.Close()
+		for len(conns) > 0 {
+			(<-conns).Close()
+		}
+	})
+	return l.Addr().String(), accepted
+}
+
+func TestReuse(t *testing.T) {
+	addr, accepted := listen(t)
+	p := New(Config{})
+	defer p.Close()
+	ctx := context.Background()
+
+	c1, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local := c1.LocalAddr().String()
+	p.Put(c1)
+
+	c2, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if got := c2.LocalAddr().String(); got != local {
+		t.Errorf("second Get returned a new connection (%s); want the pooled one (%s)", got, local)
+	}
+	if n := accepted.Load(); n > 1 {
+		t.Errorf("server accepted %d connections, want 1", n)
+	}
+}
+
+func TestMaxIdle(t *testing.T) {
+	addr, _ := listen(t)
+	p := New(Config{MaxIdle: 1})
+	defer p.Close()
+	ctx := context.Background()
+
+	c1, _ := p.Get(ctx, addr)
+	c2, _ := p.Get(ctx, addr)
+	p.Put(c1)
+	p.Put(c2)
+	if got := p.Stats(addr).Idle; got != 1 {
+		t.Errorf("Idle = %d, want 1", got)
+	}
+}
+
+func TestIdleTimeout(t *testing.T) {
+	addr, _ := listen(t)
+	p := New(Config{IdleTimeout: time.Millisecond})
+	defer p.Close()
+	ctx := context.Background()
+
+	c1, _ := p.Get(ctx, addr)
+	local := c1.LocalAddr().String()
+	p.Put(c1)
+	time.Sleep(5 * time.Millisecond)
+
+	c2, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if c2.LocalAddr().String() == local {
+		t.Errorf("Get returned a connection that had been idle past IdleTimeout")
+	}
+}
+
+func TestHealthCheck(t *testing.T) {
+	addr, _ := listen(t)
+	p := New(Config{HealthCheck: func(net.Conn) error { return errors.New("unhealthy") }})
+	defer p.Close()
+	ctx := context.Background()
+
+	c1, _ := p.Get(ctx, addr)
+	local := c1.LocalAddr().String()
+	p.Put(c1)
+
+	c2, err := p.Get(ctx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if c2.LocalAddr().String() == local {
+		t.Errorf("Get returned a connection that failed its health check")
+	}
+}
+
+func TestMaxActive(t *testing.T) {
+	addr, _ := listen(t)
+	p := New(Config{MaxActive: 1})
+	defer p.Close()
+
+	c1, err := p.Get(context.Background(), addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.Get(ctx, addr); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get() with MaxActive reached: error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	c1.Close()
+	c2, err := p.Get(context.Background(), addr)
+	if err != nil {
+		t.Fatalf("Get() after Close freed a slot: %v", err)
+	}
+	c2.Close()
+}
+
+func TestProbeClosed(t *testing.T) {
+	server, client := net.Pipe()
+	defer client.Close()
+	if err := ProbeClosed(client); err != nil {
+		t.Errorf("ProbeClosed(open conn) = %v, want nil", err)
+	}
+	server.Close()
+	if err := ProbeClosed(client); err == nil {
+		t.Errorf("ProbeClosed(closed conn) = nil, want error")
+	}
+}
+
+func TestClosed(t *testing.T) {
+	p := New(Config{})
+	p.Close()
+	if _, err := p.Get(context.Background(), "127.0.0.1:1"); !errors.Is(err, ErrClosed) {
+		t.Errorf("Get() after Close: error = %v, want %v", err, ErrClosed)
+	}
+}
+
+// trackedConn is a connection that remembers whether it's been closed.
+type trackedConn struct {
+	net.Conn
+	closed atomic.Bool
+}
+
+func (c *trackedConn) Close() error {
+	c.closed.Store(true)
+	return c.Conn.Close()
+}
+
+// TestGetCloseRace runs Get and Close at the same time: meant for -race, and checks that Get never hands out a
+// connection that Close closed.
+func TestGetCloseRace(t *testing.T) {
+	dial := func(context.Context, string) (net.Conn, error) {
+		client, server := net.Pipe()
+		server.Close()
+		return &trackedConn{Conn: client}, nil
+	}
+
+	// a Get that got in before Close, and is waiting for a MaxActive slot, fails when it gets one, rather than
+	// dialing a connection for a closed pool.
+	p := New(Config{MaxActive: 1, Dial: dial})
+	c, err := p.Get(context.Background(), "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waiting := make(chan error)
+	go func() {
+		_, err := p.Get(context.Background(), "a")
+		waiting <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Close()
+	p.Put(c)
+	if err := <-waiting; !errors.Is(err, ErrClosed) {
+		t.Errorf("waiting Get() after Close: error = %v, want %v", err, ErrClosed)
+	}
+
+	for range 50 {
+		p := New(Config{MaxIdle: 8, Dial: dial})
+		ctx := context.Background()
+		var idle []*Conn
+		for range 8 {
+			c, err := p.Get(ctx, "a")
+			if err != nil {
+				t.Fatal(err)
+			}
+			idle = append(idle, c)
+		}
+		for _, c := range idle {
+			p.Put(c)
+		}
+
+		// each worker takes a connection, holds it for a moment, checks nobody closed it, and puts it back, until
+		// the pool's closed under it.
+		var wg sync.WaitGroup
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for {
+					c, err := p.Get(ctx, "a")
+					if err != nil {
+						if !errors.Is(err, ErrClosed) {
+							t.Errorf("Get() error = %v, want nil or %v", err, ErrClosed)
+						}
+						return
+					}
+					runtime.Gosched()
+					if c.Conn.(*trackedConn).closed.Load() {
+						t.Error("Get returned a connection that Close closed")
+					}
+					p.Put(c)
+				}
+			}()
+		}
+		time.Sleep(time.Millisecond)
+		p.Close()
+		wg.Wait()
+	}
+}
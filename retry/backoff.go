@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff computes how long to wait before retry number attempt (1-based: the wait after the first failure is Delay(1, 0)).
+// prev is the delay that was returned for the previous attempt, which lets stateful-looking policies like
+// decorrelated jitter stay stateless and safe to share between goroutines.
+type Backoff interface {
+	Delay(attempt int, prev time.Duration) time.Duration
+}
+
+// Constant waits the same Interval between every attempt.
+type Constant struct {
+	Interval time.Duration
+}
+
+func (c Constant) Delay(int, time.Duration) time.Duration { return c.Interval }
+
+// Exponential waits Base * Multiplier^(attempt-1), capped at Max.
+// Jitter in [0, 1] randomly shaves up to that fraction off each delay, so a crowd of clients that failed together
+// don't all come back at the same instant: 0 is no jitter, 1 is "full jitter".
+type Exponential struct {
+	Base, Max  time.Duration
+	Multiplier float64 // zero means 2
+	Jitter     float64
+}
+
+func (e Exponential) Delay(attempt int, _ time.Duration) time.Duration {
+	mult := e.Multiplier
+	if mult == 0 {
+		mult = 2
+	}
+	if e.Base <= 0 {
+		return 0 // and not 0 * +Inf, which is NaN.
+	}
+	d := float64(e.Base) * math.Pow(mult, float64(attempt-1))
+	if e.Max > 0 && d > float64(e.Max) {
+		d = float64(e.Max)
+	}
+	// with no Max, enough attempts overflow to +Inf, and converting that, or anything from MaxInt64 up, to a
+	// Duration is undefined (it comes out negative in practice), so the longest Duration will have to do.
+	d = min(d, math.MaxInt64)
+	if j := min(max(e.Jitter, 0), 1); j > 0 {
+		d -= d * j * rand.Float64()
+	}
+	if d >= math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return time.Duration(d)
+}
+
+// DecorrelatedJitter implements the "decorrelated jitter" policy from the AWS architecture blog:
+//
+//	sleep = min(Max, random_between(Base, prev * 3))
+//
+// it grows roughly exponentially but each delay depends on the last one rather than on the attempt count,
+// which spreads retries out better than Exponential with jitter.
+type DecorrelatedJitter struct {
+	Base, Max time.Duration
+}
+
+func (d DecorrelatedJitter) Delay(_ int, prev time.Duration) time.Duration {
+	if prev < d.Base {
+		prev = d.Base
+	}
+	hi := prev * 3
+	next := d.Base
+	if hi > d.Base {
+		next += rand.N(hi - d.Base)
+	}
+	if d.Max > 0 && next > d.Max {
+		next = d.Max
+	}
+	return next
+}
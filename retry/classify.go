@@ -0,0 +1,38 @@
+package retry
+
+import (
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
+
+// IsNetRetryable is a classifier for Policy.Retryable that accepts the network errors that are usually transient:
+// timeouts, temporary DNS failures, refused/reset/aborted connections, and a peer hanging up mid-read.
+// anything else (a malformed address, an unknown host, ...) is treated as permanent.
+func IsNetRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,3 @@
+module github.com/ekediala/retry
+
+go 1.23.1
@@ -0,0 +1,160 @@
+// Package retry runs an operation until it succeeds, its error is classified as permanent,
+// the attempt budget is spent, or the context is done.
+//
+// the moving parts are small on purpose:
+//   - a Backoff decides how long to wait before the next attempt,
+//   - a classifier (Policy.Retryable) decides whether an error is worth another attempt,
+//   - Policy.Do glues them together and respects ctx cancellation while sleeping.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// DefaultMaxAttempts is used when Policy.MaxAttempts is zero.
+const DefaultMaxAttempts = 5
+
+// DefaultPolicy retries up to DefaultMaxAttempts times with jittered exponential backoff starting at 100ms.
+var DefaultPolicy = Policy{
+	MaxAttempts: DefaultMaxAttempts,
+	Backoff:     Exponential{Base: 100 * time.Millisecond, Max: 5 * time.Second, Jitter: 0.5},
+}
+
+// Policy describes how an operation is retried. The zero value is usable:
+// it makes DefaultMaxAttempts attempts with no delay between them and retries every non-permanent error.
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first one. zero means DefaultMaxAttempts;
+	// a negative value means retry until the context is done.
+	MaxAttempts int
+
+	// Backoff computes the delay before each retry. nil means retry immediately.
+	Backoff Backoff
+
+	// Retryable reports whether err is worth another attempt. nil means every error is retryable
+	// unless it was wrapped with Permanent. Permanent errors are never retried, whatever Retryable says.
+	Retryable func(err error) bool
+
+	// OnRetry, if non-nil, is called after a failed attempt and before sleeping for delay.
+	// it's the natural place to log.
+	OnRetry func(attempt int, err error, delay time.Duration)
+}
+
+// Do calls fn using DefaultPolicy.
+func Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return DefaultPolicy.Do(ctx, fn)
+}
+
+// Do calls fn until it returns nil, returns a non-retryable error, the attempts run out, or ctx is done.
+// a permanent error is returned unwrapped; running out of attempts returns the last error wrapped in an *Error.
+func (p Policy) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	maxAttempts := p.MaxAttempts
+	if maxAttempts == 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+
+	var delay time.Duration // the Backoff's last delay, which an After's doesn't replace
+	for attempt := 1; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+
+		var perm *permanentError
+		if errors.As(err, &perm) {
+			return perm.err
+		}
+		if ctx.Err() != nil || (p.Retryable != nil && !p.Retryable(err)) {
+			return err
+		}
+		if maxAttempts > 0 && attempt >= maxAttempts {
+			return &Error{Attempts: attempt, Err: err}
+		}
+
+		if p.Backoff != nil {
+			delay = p.Backoff.Delay(attempt, delay)
+		}
+		wait := delay
+		var after *afterError
+		if errors.As(err, &after) {
+			wait = after.d
+		}
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, err, wait)
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// sleep waits for d or until ctx is done, whichever comes first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Error is returned by Policy.Do when every attempt failed.
+type Error struct {
+	Attempts int
+	Err      error // the error from the last attempt
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("retry: giving up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Permanent wraps err so that Policy.Do stops immediately and returns err as-is.
+// Permanent(nil) returns nil.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err}
+}
+
+// IsPermanent reports whether err (or anything it wraps) was marked with Permanent.
+func IsPermanent(err error) bool {
+	var perm *permanentError
+	return errors.As(err, &perm)
+}
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// After wraps err so that Policy.Do waits d before the next attempt instead of what the Backoff says, for an error
+// that comes with its own idea of when to try again, like an HTTP response's Retry-After. it doesn't make err
+// retryable: Policy.Retryable still decides that. After(nil, d) returns nil.
+func After(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &afterError{err, d}
+}
+
+type afterError struct {
+	err error
+	d   time.Duration
+}
+
+func (e *afterError) Error() string { return e.err.Error() }
+func (e *afterError) Unwrap() error { return e.err }
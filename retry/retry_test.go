@@ -0,0 +1,149 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestDo(t *testing.T) {
+	errBoom := errors.New("boom")
+	for name, tt := range map[string]struct {
+		policy    Policy
+		failures  int   // number of times fn fails before succeeding
+		err       error // the error fn fails with
+		wantCalls int
+		wantErr   bool
+	}{
+		"succeeds first time":     {policy: Policy{MaxAttempts: 3}, failures: 0, err: errBoom, wantCalls: 1},
+		"succeeds after retries":  {policy: Policy{MaxAttempts: 3}, failures: 2, err: errBoom, wantCalls: 3},
+		"runs out of attempts":    {policy: Policy{MaxAttempts: 3}, failures: 5, err: errBoom, wantCalls: 3, wantErr: true},
+		"zero value uses default": {policy: Policy{}, failures: 10, err: errBoom, wantCalls: DefaultMaxAttempts, wantErr: true},
+		"permanent stops":         {policy: Policy{MaxAttempts: 3}, failures: 5, err: Permanent(errBoom), wantCalls: 1, wantErr: true},
+		"not retryable stops": {
+			policy:   Policy{MaxAttempts: 3, Retryable: func(error) bool { return false }},
+			failures: 5, err: errBoom, wantCalls: 1, wantErr: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			calls := 0
+			err := tt.policy.Do(context.Background(), func(context.Context) error {
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, errBoom) {
+				t.Errorf("Do() error = %v, want it to wrap %v", err, errBoom)
+			}
+			if IsPermanent(err) {
+				t.Errorf("Do() returned a permanent wrapper; want it unwrapped")
+			}
+		})
+	}
+}
+
+func TestDoContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	p := Policy{MaxAttempts: -1, Backoff: Constant{time.Hour}, OnRetry: func(int, error, time.Duration) { cancel() }}
+	err := p.Do(ctx, func(context.Context) error { return errors.New("boom") })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestAfter(t *testing.T) {
+	// the error's own delay replaces the backoff's, for that retry only.
+	var delays []time.Duration
+	p := Policy{MaxAttempts: 3, Backoff: Constant{time.Millisecond}, OnRetry: func(_ int, _ error, d time.Duration) { delays = append(delays, d) }}
+	calls := 0
+	errBoom := errors.New("boom")
+	err := p.Do(context.Background(), func(context.Context) error {
+		calls++
+		if calls == 1 {
+			return After(errBoom, 2*time.Millisecond)
+		}
+		return errBoom
+	})
+	if !errors.Is(err, errBoom) || calls != 3 {
+		t.Fatalf("Do() = %v after %d calls, want %v after 3", err, calls, errBoom)
+	}
+	if want := []time.Duration{2 * time.Millisecond, time.Millisecond}; fmt.Sprint(delays) != fmt.Sprint(want) {
+		t.Errorf("delays = %v, want %v", delays, want)
+	}
+	if After(nil, time.Second) != nil {
+		t.Error("After(nil) != nil")
+	}
+}
+
+func TestExponential(t *testing.T) {
+	b := Exponential{Base: 10 * time.Millisecond, Max: 50 * time.Millisecond}
+	for attempt, want := range map[int]time.Duration{
+		1: 10 * time.Millisecond,
+		2: 20 * time.Millisecond,
+		3: 40 * time.Millisecond,
+		4: 50 * time.Millisecond,
+		9: 50 * time.Millisecond,
+	} {
+		if got := b.Delay(attempt, 0); got != want {
+			t.Errorf("Delay(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+
+	b.Jitter = 1
+	for attempt := 1; attempt < 10; attempt++ {
+		if got := b.Delay(attempt, 0); got < 0 || got > b.Max {
+			t.Errorf("jittered Delay(%d) = %v, want in [0, %v]", attempt, got, b.Max)
+		}
+	}
+
+	// with no Max, a delay too long to be a Duration is the longest one, not an overflow.
+	for _, b := range []Exponential{{Base: time.Second}, {Base: time.Second, Jitter: 0.5}} {
+		for _, attempt := range []int{64, 2000, math.MaxInt} {
+			if got := b.Delay(attempt, 0); got <= 0 || b.Jitter == 0 && got != math.MaxInt64 {
+				t.Errorf("%+v.Delay(%d) = %v, want a positive delay, the longest Duration without jitter", b, attempt, got)
+			}
+		}
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	b := DecorrelatedJitter{Base: 10 * time.Millisecond, Max: time.Second}
+	var prev time.Duration
+	for attempt := 1; attempt < 50; attempt++ {
+		got := b.Delay(attempt, prev)
+		if got < b.Base || got > b.Max {
+			t.Fatalf("Delay(%d, %v) = %v, want in [%v, %v]", attempt, prev, got, b.Base, b.Max)
+		}
+		if prev > 0 && got > 3*prev {
+			t.Fatalf("Delay(%d, %v) = %v, want at most 3x the previous delay", attempt, prev, got)
+		}
+		prev = got
+	}
+}
+
+func TestIsNetRetryable(t *testing.T) {
+	for err, want := range map[error]bool{
+		errors.New("boom"):                                   false,
+		syscall.ECONNREFUSED:                                 true,
+		fmt.Errorf("dial: %w", syscall.ECONNRESET):           true,
+		&net.DNSError{Err: "no such host", IsNotFound: true}: false,
+		&net.DNSError{Err: "timeout", IsTimeout: true}:       true,
+	} {
+		if got := IsNetRetryable(err); got != want {
+			t.Errorf("IsNetRetryable(%v) = %v, want %v", err, got, want)
+		}
+	}
+}
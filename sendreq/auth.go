@@ -0,0 +1,66 @@
+package sendreq
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// basicAuth is the Authorization header value for -u's user:password.
+func basicAuth(userinfo string) string {
+	/* design note: basic auth (RFC 7617) is just "user:password" in base64. base64 isn't encryption: anyone who sees
+	   the request can read the password back out, so it's only as private as the connection. use it with -tls.
+	*/
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(userinfo))
+}
+
+// promptPassword asks for user's password on the terminal, like curl does for -u with no password. the terminal's
+// echo is turned off while it's typed, so it doesn't show.
+func promptPassword(ctx context.Context, user string) (string, error) {
+	// the terminal itself, not stdin: stdin may be the request body, or a pipe.
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("-u %s has no password, and there's no terminal to ask for one: %w", user, err)
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "Enter host password for user '%s': ", user)
+	// stty is the portable way to switch echo off without reaching for syscalls; if it isn't there, the password
+	// just shows as it's typed.
+	if stty(tty, "-echo") == nil {
+		defer stty(tty, "echo")
+	}
+	defer fmt.Fprintln(tty) // the Enter that ended the password wasn't echoed either.
+
+	line := make(chan string, 1)
+	errc := make(chan error, 1)
+	go func() {
+		s, err := bufio.NewReader(tty).ReadString('\n')
+		if err != nil {
+			errc <- err
+			return
+		}
+		line <- s
+	}()
+	// ^C doesn't kill us while the read waits (the context has the signal), so watch for it here, and put echo back
+	// on the way out.
+	select {
+	case s := <-line:
+		return strings.TrimRight(s, "\r\n"), nil
+	case err := <-errc:
+		return "", fmt.Errorf("reading password: %w", err)
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// stty runs stty(1) with args against tty.
+func stty(tty *os.File, args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	return cmd.Run()
+}
@@ -0,0 +1,217 @@
+package sendreq
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+/* design note: -requests runs a file of requests, one after another, like a little smoke test suite. the format is
+   the one editor plugins like VS Code's REST Client use for .http files, so the same file works in both:
+
+	# comments start with # or //
+	GET /health
+
+	###
+	POST /items
+	Content-Type: application/json
+
+	{"name": "widget"}
+
+   "###" lines separate the requests. each is a request line (a method and a target, with an optional HTTP version
+   that's ignored), header lines, and, after an empty line, a body, which runs to the next "###". the target can be
+   a path, taken against the URL on the command line, or a whole URL. -var's placeholders are filled in across the
+   whole file first, so the same suite can run against staging and production.
+
+   the requests go over the client's kept-alive connections like any others, so a suite against one host makes one
+   connection (-max-idle 0 gives each request its own). a request that fails, or gets a 4xx or 5xx, doesn't stop the
+   rest: the report at the end says which ones did. -expect-* checks each response too, and -expect-status decides
+   which statuses are a failure instead.
+*/
+
+// stanza is one request from a -requests file.
+type stanza struct {
+	line    int // where its request line is, for messages
+	method  string
+	target  string
+	headers headerFlags
+	body    string
+}
+
+// loadRequests reads and parses a -requests file, filling in vars' placeholders first, if there are any.
+func loadRequests(path string, vars varFlags) ([]stanza, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	text := string(b)
+	if len(vars) > 0 {
+		if text, err = vars.expand(text); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	stanzas, err := parseRequests(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(stanzas) == 0 {
+		return nil, fmt.Errorf("%s: no requests", path)
+	}
+	return stanzas, nil
+}
+
+// parseRequests parses the requests in text.
+func parseRequests(text string) ([]stanza, error) {
+	var stanzas []stanza
+	var s *stanza
+	inBody := false
+	var body []string
+	end := func() {
+		if s != nil {
+			// the empty lines before the next "###" are there to read it more easily, not part of the body.
+			for len(body) > 0 && strings.TrimSpace(body[len(body)-1]) == "" {
+				body = body[:len(body)-1]
+			}
+			s.body = strings.Join(body, "\n")
+			stanzas = append(stanzas, *s)
+		}
+		s, inBody, body = nil, false, nil
+	}
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		switch {
+		case strings.HasPrefix(line, "###"):
+			end()
+		case inBody:
+			body = append(body, line)
+		case s == nil:
+			trimmed := strings.TrimSpace(line)
+			if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "//") {
+				continue
+			}
+			f := strings.Fields(trimmed)
+			if len(f) < 2 || len(f) > 3 || !isToken(f[0]) || (len(f) == 3 && !strings.HasPrefix(f[2], "HTTP/")) {
+				return nil, fmt.Errorf("line %d: %q should be a request line, like \"GET /path\"", i+1, line)
+			}
+			s = &stanza{line: i + 1, method: f[0], target: f[1]}
+		case line == "":
+			inBody = true
+		case strings.HasPrefix(strings.TrimSpace(line), "#"), strings.HasPrefix(strings.TrimSpace(line), "//"):
+		default:
+			if err := s.headers.Set(line); err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+		}
+	}
+	end()
+	return stanzas, nil
+}
+
+// fromStanza returns the options for s's request: o's, with s's method and target, its headers merged over -H's,
+// and its body, if it has one.
+func (o options) fromStanza(s stanza) (options, error) {
+	r := o
+	ref, err := url.Parse(s.target)
+	if err != nil {
+		return o, fmt.Errorf("line %d: bad target %q: %w", s.line, s.target, err)
+	}
+	if err := r.target([]string{o.url().ResolveReference(ref).String()}, nil); err != nil {
+		return o, fmt.Errorf("line %d: bad target %q: %w", s.line, s.target, err)
+	}
+	r.method = s.method
+	r.headers = slices.Clone(o.headers)
+	for _, h := range s.headers {
+		if i := indexHeader(r.headers, h.Key); i >= 0 {
+			r.headers[i] = h
+		} else {
+			r.headers = append(r.headers, h)
+		}
+	}
+	r.data, r.hasBody = s.body, s.body != ""
+	if r.hasBody && (indexHeader(r.headers, "Content-Length") >= 0 || indexHeader(r.headers, "Transfer-Encoding") >= 0) {
+		return o, fmt.Errorf("line %d: a request with a body can't set Content-Length or Transfer-Encoding; sendreq frames the body itself", s.line)
+	}
+	return r, nil
+}
+
+// batchResult is how one request from a -requests file went.
+type batchResult struct {
+	request string // "GET /path"
+	status  int    // 0 if it failed
+	err     error
+	took    time.Duration
+	checked bool // -expect-status said which statuses are fine, so a 4xx or 5xx can be
+}
+
+func (r batchResult) failed() bool { return r.err != nil || r.status >= 400 && !r.checked }
+
+// batch runs the -requests file's requests in order, printing each response as usual, and then a report of them
+// all on stderr. it's an error if any failed.
+func batch(ctx context.Context, env *cli.Env, o options) error {
+	w := bufio.NewWriter(env.Stdout)
+	defer w.Flush()
+	c := newClient(o)
+	defer c.close()
+	c.progress = env.Stderr
+
+	var results []batchResult
+	for _, s := range o.batch {
+		if ctx.Err() != nil {
+			break
+		}
+		res := batchResult{request: s.method + " " + s.target, checked: o.expect != nil && len(o.expect.status) > 0}
+		start := time.Now()
+		r, err := o.fromStanza(s)
+		if err == nil {
+			var resp *rawResponse
+			if resp, err = fetch(ctx, c, w, env.Stderr, r); resp != nil {
+				res.status = resp.status
+			}
+		}
+		res.err, res.took = err, time.Since(start)
+		results = append(results, res)
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	writeBatch(env.Stderr, results)
+	failed := 0
+	for _, r := range results {
+		if r.failed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d requests failed", failed, len(results))
+	}
+	return ctx.Err()
+}
+
+// writeBatch prints the report: each request, what came back, and how long it took.
+func writeBatch(w io.Writer, results []batchResult) {
+	fmt.Fprintf(w, "%d requests\n", len(results))
+	fmt.Fprintf(w, "  %3s  %-4s  %-40s %-6s %10s\n", "#", "", "request", "status", "time")
+	for i, r := range results {
+		status := fmt.Sprint(r.status)
+		if r.err != nil && r.status == 0 {
+			status = "error"
+		}
+		result := "ok"
+		if r.failed() {
+			result = "FAIL"
+		}
+		fmt.Fprintf(w, "  %3d  %-4s  %-40s %-6s %10s", i+1, result, r.request, status, round(r.took))
+		if r.err != nil {
+			fmt.Fprintf(w, "  %v", r.err)
+		}
+		fmt.Fprintln(w)
+	}
+}
@@ -0,0 +1,190 @@
+package sendreq
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+/* design note: -bench is a small load generator, like ab or hey. -c workers share one client, so they share its
+   pool: each worker keeps a connection busy, and hands it back for the next request when the response is done. that
+   measures what a server does with steady keep-alive traffic. there's no retrying and no redirects: a failed request
+   counts as an error, and a 3xx is just another status code.
+
+   latencies are summarized with percentiles rather than the mean alone: a server that answers most requests in 1ms
+   and one in a hundred in 2s has a fine mean and an awful p99, and the p99 is what some user is waiting on.
+*/
+
+// sample is one request's outcome.
+type sample struct {
+	latency time.Duration
+	status  int
+	err     error
+}
+
+// benchResult is the report; it's what -json prints.
+type benchResult struct {
+	Requests    int            `json:"requests"`
+	Errors      int            `json:"errors"`
+	Concurrency int            `json:"concurrency"`
+	Seconds     float64        `json:"seconds"`
+	PerSecond   float64        `json:"requests_per_second"`
+	Latency     benchLatency   `json:"latency_ms"` // of the requests that got a response
+	Statuses    map[int]int    `json:"status_codes"`
+	ErrorCounts map[string]int `json:"error_counts"` // by message
+}
+
+type benchLatency struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	Max  float64 `json:"max"`
+}
+
+// bench sends o.count requests from o.concurrency workers, cycling through requests and pacing them for -rate and
+// -duration, and reports how it went. ^C or -timeout stops it early, with a report on what was done.
+func bench(ctx context.Context, env *cli.Env, o options, requests []options) error {
+	c := newClient(o)
+	defer c.close()
+
+	jobs := make(chan options)
+	go func() {
+		defer close(jobs)
+		// with -rate, a request waits here when every worker is busy, so the rate can fall short if the server can't
+		// keep up with -c requests at a time; the report says what was achieved.
+		p := o.newPacer(o.count)
+		for i := 0; p.next(ctx); i++ {
+			select {
+			case jobs <- requests[i%len(requests)]:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	samples := make(chan sample, o.concurrency)
+	var wg sync.WaitGroup
+	for range o.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				start := time.Now()
+				resp, err := c.do(ctx, r)
+				s := sample{latency: time.Since(start), err: err}
+				if resp != nil {
+					s.status = resp.status
+				}
+				samples <- s
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(samples)
+	}()
+
+	start := time.Now()
+	var all []sample
+	for s := range samples {
+		if s.err != nil && ctx.Err() != nil {
+			continue // called off by ^C or -timeout, not a failure of the server's.
+		}
+		all = append(all, s)
+	}
+	r := summarize(all, time.Since(start))
+	r.Concurrency = o.concurrency
+	if o.json {
+		return env.PrintJSON(r)
+	}
+	r.write(env.Stdout)
+	return nil
+}
+
+// summarize tallies samples from a run that took elapsed.
+func summarize(samples []sample, elapsed time.Duration) benchResult {
+	r := benchResult{
+		Requests:    len(samples),
+		Seconds:     elapsed.Seconds(),
+		Statuses:    map[int]int{},
+		ErrorCounts: map[string]int{},
+	}
+	if elapsed > 0 {
+		r.PerSecond = float64(len(samples)) / elapsed.Seconds()
+	}
+	var latencies []time.Duration
+	var sum time.Duration
+	for _, s := range samples {
+		if s.err != nil {
+			r.Errors++
+			r.ErrorCounts[s.err.Error()]++
+			continue
+		}
+		r.Statuses[s.status]++
+		latencies = append(latencies, s.latency)
+		sum += s.latency
+	}
+	if len(latencies) > 0 {
+		slices.Sort(latencies)
+		r.Latency = benchLatency{
+			Min:  ms(latencies[0]),
+			Mean: ms(sum / time.Duration(len(latencies))),
+			P50:  ms(percentile(latencies, 50)),
+			P95:  ms(percentile(latencies, 95)),
+			P99:  ms(percentile(latencies, 99)),
+			Max:  ms(latencies[len(latencies)-1]),
+		}
+	}
+	return r
+}
+
+// percentile picks the nearest-rank percentile from sorted samples: the smallest one that at least p% of them are
+// less than or equal to.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	i := (len(sorted)*p + 99) / 100 // ceil(n*p/100), 1-based
+	if i < 1 {
+		i = 1
+	}
+	return sorted[i-1]
+}
+
+// write prints the report as a table.
+func (r benchResult) write(w io.Writer) {
+	fmt.Fprintf(w, "requests:     %d (%d errors), %d at a time\n", r.Requests, r.Errors, r.Concurrency)
+	fmt.Fprintf(w, "time:         %.3fs\n", r.Seconds)
+	fmt.Fprintf(w, "throughput:   %.1f requests/s\n", r.PerSecond)
+	if r.Requests > r.Errors {
+		l := r.Latency
+		fmt.Fprintf(w, "latency (ms): min %.3f  mean %.3f  p50 %.3f  p95 %.3f  p99 %.3f  max %.3f\n", l.Min, l.Mean, l.P50, l.P95, l.P99, l.Max)
+	}
+	if len(r.Statuses) > 0 {
+		fmt.Fprintln(w, "status codes:")
+		codes := make([]int, 0, len(r.Statuses))
+		for code := range r.Statuses {
+			codes = append(codes, code)
+		}
+		slices.Sort(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "  %d  %d\n", code, r.Statuses[code])
+		}
+	}
+	if len(r.ErrorCounts) > 0 {
+		fmt.Fprintln(w, "errors:")
+		msgs := make([]string, 0, len(r.ErrorCounts))
+		for msg := range r.ErrorCounts {
+			msgs = append(msgs, msg)
+		}
+		// most frequent first.
+		slices.SortFunc(msgs, func(a, b string) int { return r.ErrorCounts[b] - r.ErrorCounts[a] })
+		for _, msg := range msgs {
+			fmt.Fprintf(w, "  %6d  %s\n", r.ErrorCounts[msg], msg)
+		}
+	}
+}
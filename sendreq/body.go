@@ -0,0 +1,82 @@
+package sendreq
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/ekediala/httpwire"
+)
+
+// openBody opens the request body from -d, -data-file, -data-stdin, -F, or -form. size is -1 when the length isn't known
+// before reading it all, as with a pipe; the request is then sent chunked instead of with a Content-Length.
+func (o options) openBody() (body io.ReadCloser, size int64, err error) {
+	switch {
+	case len(o.form) > 0:
+		return o.form.open(o.boundary)
+	case len(o.values) > 0:
+		body := o.values.encode()
+		return io.NopCloser(strings.NewReader(body)), int64(len(body)), nil
+	case o.dataStdin:
+		return io.NopCloser(os.Stdin), fileSize(os.Stdin), nil
+	case o.dataFile != "":
+		f, err := os.Open(o.dataFile)
+		if err != nil {
+			return nil, 0, err
+		}
+		return f, fileSize(f), nil
+	default:
+		return io.NopCloser(strings.NewReader(o.data)), int64(len(o.data)), nil
+	}
+}
+
+// fileSize is f's size if it's a regular file, or -1 for things like pipes and terminals that have no size until
+// they're read to the end.
+func fileSize(f *os.File) int64 {
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		return -1
+	}
+	return fi.Size()
+}
+
+// writeBody copies a request body of size bytes from r to w: chunked, with trailers after it, if size is -1, or else
+// exactly what Content-Length promised, even if a file grew since. it returns the number of body bytes copied.
+func writeBody(w io.Writer, r io.Reader, size int64, trailers []Header) (int64, error) {
+	if size < 0 {
+		return writeChunked(w, r, trailers)
+	}
+	return io.CopyN(w, r, size)
+}
+
+// writeChunked copies r to w as a chunked body: one chunk per read from r, then the last, empty chunk and the trailer
+// fields. it returns the number of body bytes copied, not counting the framing.
+func writeChunked(w io.Writer, r io.Reader, trailers []Header) (n int64, err error) {
+	c := httpwire.NewChunkedWriter(w)
+	c.Trailers = trailers
+	if n, err = io.Copy(c, r); err != nil {
+		return n, err
+	}
+	return n, c.Close()
+}
+
+// formValues collects repeated -form key=value flags as an application/x-www-form-urlencoded body, the way an HTML
+// form without files is sent: key=value pairs joined with &, each side percent-encoded, with spaces as +.
+type formValues []string
+
+func (f *formValues) String() string { return f.encode() }
+
+func (f *formValues) Set(s string) error {
+	k, v, ok := strings.Cut(s, "=")
+	if !ok || k == "" {
+		return fmt.Errorf("%q should be of form key=value", s)
+	}
+	// url.Values would sort the keys; a form sends its fields in order, so this keeps them that way.
+	*f = append(*f, url.QueryEscape(k)+"="+url.QueryEscape(v))
+	return nil
+}
+
+// encode returns the body.
+func (f formValues) encode() string { return strings.Join(f, "&") }
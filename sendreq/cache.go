@@ -0,0 +1,123 @@
+package sendreq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+/* design note: a validator is what a server hands out so a client can ask later "has this changed?" without
+   downloading it again. there are two kinds: an ETag, an opaque tag for this version of the resource, and
+   Last-Modified, a date. the client sends them back as If-None-Match and If-Modified-Since (RFC 9110, section 13),
+   and if nothing changed the server answers 304 Not Modified, with no body.
+
+   a browser keeps the body too, and shows it for the 304. sendreq keeps only the validators, so a 304 just says the
+   resource is the same as last time; with -O, the file from last time is still good. that's enough to check that a
+   server's conditional requests work, and to poll something big without fetching it every time.
+*/
+
+// validators are a response's ETag and Last-Modified, as the server sent them.
+type validators struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// validatorCache is -cache's file: validators by URL. it's not safe for concurrent use.
+type validatorCache struct {
+	path    string
+	entries map[string]validators
+	changed bool
+}
+
+// defaultCachePath is sendreq/validators.json in the user cache directory, like ~/.cache/sendreq/validators.json.
+func defaultCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "sendreq", "validators.json"), nil
+}
+
+// loadCache reads the cache at path. a missing file is an empty cache.
+func loadCache(path string) (*validatorCache, error) {
+	c := &validatorCache{path: path, entries: map[string]validators{}}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.entries); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+// conditional reports whether a request with method can be made conditional: only GET and HEAD, since a 304 says
+// nothing about what a POST or a PUT did.
+func conditional(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// get returns the validators to send with a request for url, if it's one that can be conditional.
+func (c *validatorCache) get(url, method string) validators {
+	if !conditional(method) {
+		return validators{}
+	}
+	return c.entries[url]
+}
+
+// update records what resp says about the validators for url. a 200 replaces them, and one without any forgets
+// them; a 304 can refresh them.
+func (c *validatorCache) update(url string, resp *rawResponse) {
+	v := validators{resp.headers.Get("Etag"), resp.headers.Get("Last-Modified")}
+	switch {
+	case resp.status == http.StatusOK && v == validators{}:
+		if _, ok := c.entries[url]; ok {
+			delete(c.entries, url)
+			c.changed = true
+		}
+	case resp.status == http.StatusOK, resp.status == http.StatusNotModified && v != validators{}:
+		if c.entries[url] != v {
+			c.entries[url] = v
+			c.changed = true
+		}
+	}
+}
+
+// save writes the cache back, if it changed. the new file is written next to the old one and renamed over it, so
+// a run that's cut short never leaves half a file.
+func (c *validatorCache) save() error {
+	if !c.changed {
+		return nil
+	}
+	b, err := json.MarshalIndent(c.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append(b, '\n'))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	c.changed = false
+	return nil
+}
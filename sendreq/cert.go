@@ -0,0 +1,93 @@
+package sendreq
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+)
+
+/* design note: in ordinary TLS only the server proves who it is. with mutual TLS (mTLS) the client does too: the
+   server sends a CertificateRequest in the handshake, naming the CAs it trusts, and the client answers with a
+   certificate and a signature made with the certificate's private key, which shows it holds the key and isn't just
+   replaying someone's certificate. services use it instead of passwords or tokens, mostly between machines.
+
+   the server decides whether to ask, so -cert's certificate only goes out when it does; otherwise the handshake
+   never mentions it. a server that asks but doesn't get one can refuse the handshake, or let it through and refuse
+   the request. in TLS 1.3 the client's certificate is checked after the client considers the handshake done, so a
+   refusal can show up as an alert on the first read, "tls: certificate required" or "tls: unknown certificate
+   authority", instead of a failed handshake. either way, the log says whether the server asked, and what it got.
+*/
+
+/* design note: the server's certificate is only worth something if someone we trust vouches for it: it has to chain
+   up to a root CA, and by default the roots are the system's, the public CAs a browser trusts. a dev server's
+   certificate is usually self-signed, or issued by a company's own CA, which no system trusts out of the box.
+
+   -cacert names the CAs to trust instead, from a PEM bundle: the dev server's own certificate, if it's self-signed,
+   or the private CA that signed it. the name still has to match, and the dates still have to be right, so it's
+   still real verification, just with a different root. -insecure (-k) skips verification altogether: the traffic is
+   still encrypted, but to whoever answered, so anyone in the middle can read and change it. it's for a quick look
+   at a server whose certificate can't be had, never for anything that matters, and it says so every time.
+*/
+
+// loadCABundle reads -cacert's PEM bundle into a pool of roots to trust instead of the system's.
+func loadCABundle(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(b) {
+		return nil, fmt.Errorf("%s: no PEM certificates in it", path)
+	}
+	return roots, nil
+}
+
+// verifyHint adds a pointer to -cacert and -insecure to a handshake error from a certificate no trusted CA vouches
+// for, since that's what a dev server's usually is.
+func verifyHint(err error) error {
+	var unknown x509.UnknownAuthorityError
+	if errors.As(err, &unknown) {
+		return fmt.Errorf("%w (trust its CA with -cacert, or, for a quick look, skip verifying it with -insecure)", err)
+	}
+	return err
+}
+
+// loadClientCert loads -cert's certificate chain and -key's private key, which must go together. with no -key, the
+// key is looked for in the -cert file, where it's often kept alongside the certificate.
+func loadClientCert(certFile, keyFile string) (*tls.Certificate, error) {
+	if keyFile == "" {
+		keyFile = certFile
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &cert, nil
+}
+
+// clientCertNote says how the client certificate part of a handshake went: whether the server asked for one, and
+// which one went, if any.
+func clientCertNote(asked bool, cert *tls.Certificate) string {
+	switch {
+	case asked && cert != nil:
+		return fmt.Sprintf("the server asked for a client certificate; sent %s", describeCert(cert.Leaf))
+	case asked:
+		return "the server asked for a client certificate, but there's none to send (give one with -cert)"
+	case cert != nil:
+		return "the server didn't ask for a client certificate, so -cert's wasn't sent"
+	default:
+		return ""
+	}
+}
+
+// describeCert names a certificate by its subject and issuer, and when it expires.
+func describeCert(c *x509.Certificate) string {
+	return fmt.Sprintf("subject %q, issuer %q, valid until %s", c.Subject.CommonName, c.Issuer.CommonName, c.NotAfter.Format("2006-01-02"))
+}
@@ -0,0 +1,351 @@
+package sendreq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ekediala/pool"
+)
+
+// client sends requests, keeping connections open between them while the server allows it. HTTP/1.1 connections
+// are persistent by default: a response's framing says where it ends, so the next request can go out on the same
+// connection without another TCP (and TLS) handshake. idle connections wait in a pool, keyed by scheme, host, and
+// port, until a request for the same place picks one up. a client is safe for concurrent use.
+type client struct {
+	o        options // for the dial settings; the target comes from each request
+	pool     *pool.Pool
+	progress io.Writer // where -O reports on downloads
+	out      io.Writer // where responses are printed as they arrive (see stream.go); nil to read them whole
+
+	mu      sync.Mutex
+	digests map[string]*digestChallenge // by scheme://host:port, the digest challenges servers have sent
+}
+
+func newClient(o options) *client {
+	c := &client{o: o}
+	c.pool = pool.New(pool.Config{
+		Dial:        c.dial,
+		MaxIdle:     o.maxIdle,
+		IdleTimeout: o.idleTimeout,
+		// a server can close an idle connection whenever it likes; checking first is cheaper than a failed request.
+		HealthCheck: pool.ProbeClosed,
+	})
+	return c
+}
+
+// close closes the idle connections.
+func (c *client) close() error { return c.pool.Close() }
+
+// bufConn is a connection with the reader its responses are read through, which goes into the pool along with it.
+// the reader is always empty by then (see do), so reading the connection directly, as the pool's health check does,
+// skips nothing.
+type bufConn struct {
+	net.Conn
+	r      *bufio.Reader
+	rec    *recorder            // under r, for -o raw
+	used   bool                 // it has carried a request before
+	dialed dialTiming           // how long it took to connect
+	tls    *tls.ConnectionState // what the TLS handshake settled on, for a TLS connection
+}
+
+// poolKey is where o's request goes: scheme://host:port.
+func poolKey(o options) string {
+	return o.url().Scheme + "://" + net.JoinHostPort(o.host, strconv.Itoa(o.port))
+}
+
+// dial is the pool's Dial: it connects to a poolKey address.
+func (c *client) dial(ctx context.Context, addr string) (net.Conn, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	o := c.o
+	o.host, o.tls = u.Hostname(), u.Scheme == "https"
+	if o.port, err = strconv.Atoi(u.Port()); err != nil {
+		return nil, err
+	}
+	conn, t, err := dial(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "main", "message", fmt.Sprintf("connected to %s (@ %s)", o.host, conn.RemoteAddr()), "took", (t.dns + t.connect + t.tls).String())
+	var r io.Reader = conn
+	if o.readTimeout > 0 {
+		r = timeoutReader{conn, o.readTimeout}
+	}
+	rec := &recorder{r: r}
+	return &bufConn{Conn: conn, r: bufio.NewReader(rec), rec: rec, dialed: t, tls: tlsState(conn)}, nil
+}
+
+// do sends o's request on a pooled connection to the same place, or a new one, and reads the response.
+func (c *client) do(ctx context.Context, o options) (*rawResponse, error) {
+	if o.http2 {
+		return c.doHTTP2(ctx, o)
+	}
+	start := time.Now()
+	addr := poolKey(o)
+	pc, err := c.pool.Get(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	reused := pc.Conn.(*bufConn).used
+	if reused && o.trace != nil {
+		o.trace.note("reusing the connection to %s", pc.Conn.RemoteAddr())
+	}
+
+	resp, err := c.roundTrip(ctx, pc, o, start)
+	if err == nil && o.expectContinue && resp.status == http.StatusExpectationFailed {
+		// the server (or a proxy) doesn't do Expect: 100-continue. RFC 9110 says to send the request again without it.
+		slog.InfoContext(ctx, "main", "message", "server refused Expect: 100-continue; resending without it")
+		pc.Close()
+		o.expectContinue = false
+		if pc, err = c.pool.Get(ctx, addr); err != nil {
+			return nil, err
+		}
+		resp, err = c.roundTrip(ctx, pc, o, start)
+	}
+	if err != nil && reused && errors.Is(err, errNoResponse) && !o.dataStdin {
+		// the connection passed the health check but died before the request reached the server. nothing came
+		// back, so the request wasn't handled: try it once more on a fresh connection. (stdin can't be read twice,
+		// so a request with a -data-stdin body can't be.)
+		slog.InfoContext(ctx, "main", "message", "server closed the idle connection; reconnecting", "error", err.Error())
+		pc.Close()
+		if pc, err = c.pool.Get(ctx, addr); err != nil {
+			return nil, err
+		}
+		resp, err = c.roundTrip(ctx, pc, o, start)
+	}
+	if err != nil {
+		pc.Close()
+		return nil, err
+	}
+	resp.timing.total = time.Since(start)
+	// only a connection that's between responses can go back: anything already buffered belongs to no request. and
+	// an HTTP/1.0 request gets a connection of its own unless the server offers keep-alive, whatever version it
+	// answers in.
+	if !resp.keepAlive() || o.http10 && !resp.offersKeepAlive() || pc.Conn.(*bufConn).r.Buffered() > 0 {
+		slog.DebugContext(ctx, "main", "message", "server won't reuse the connection; closing it")
+		pc.Close()
+		return resp, nil
+	}
+	c.pool.Put(pc)
+	return resp, nil
+}
+
+// roundTrip writes o's request to conn and reads the response. start is when the request began, for the timings.
+func (c *client) roundTrip(ctx context.Context, conn *pool.Conn, o options, start time.Time) (*rawResponse, error) {
+	bc := conn.Conn.(*bufConn)
+	t := timing{reused: bc.used}
+	if !t.reused {
+		t.dialTiming = bc.dialed
+	}
+	bc.used = true
+
+	request, body, size, err := o.request()
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		defer body.Close()
+	}
+
+	// closing the connection on ^C ends the read below.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	// for -o raw (and -i's show), everything read is recorded, from before a 100 Continue to the end of the response.
+	r := bc.r
+	record := o.output == "raw" || o.interactive
+	if record {
+		bc.rec.start(r)
+	}
+
+	bw := bufio.NewWriter(conn)
+	bw.WriteString(request)
+	// send writes the body, if there is one, and sends whatever's buffered.
+	send := func() error {
+		if body != nil {
+			n, err := writeBody(bw, body, size, o.trailers)
+			if err != nil {
+				return fmt.Errorf("sending request body: %w", err)
+			}
+			slog.DebugContext(ctx, "main", "message", "sent request body", "bytes", n)
+		}
+		if err := bw.Flush(); err != nil {
+			// a write to a connection the server already closed can fail outright; that's no response too.
+			return fmt.Errorf("%w: %w", errNoResponse, err)
+		}
+		return nil
+	}
+	var early *rawResponse // the head of a final response that came before the body went out
+	if body != nil && o.expectContinue {
+		if err = bw.Flush(); err != nil {
+			return nil, fmt.Errorf("%w: %w", errNoResponse, err)
+		}
+		early, err = expectContinue(ctx, r, o.method, o.expectTimeout, send)
+	} else {
+		err = send()
+	}
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "main", "info", fmt.Sprintf("sent request:\n%s", request))
+
+	if early == nil {
+		// the time to the first byte of the response is mostly the server's.
+		err = awaitResponse(r)
+	}
+	t.ttfb = time.Since(start)
+
+	var resp *rawResponse
+	switch {
+	case err != nil: // no response in time, or none at all
+	case early != nil:
+		resp, err = early, early.readBody()
+		resp.unsent = true
+	case o.download != "":
+		resp, err = c.download(r, o)
+	case c.out != nil:
+		resp, err = c.stream(r, o)
+	default:
+		resp, err = readResponse(r, o.method)
+	}
+	if record {
+		if raw := bc.rec.stop(r); resp != nil {
+			resp.raw = raw
+		}
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+		return nil, fmt.Errorf("no data from the server for %s (-read-timeout): %w", o.readTimeout, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	resp.timing, resp.tls = t, bc.tls
+	return resp, nil
+}
+
+// request returns o's request head, from the request line to the empty line after the headers, and opens its body,
+// if it has one; otherwise body is nil. size is the body's length, or -1 to send it chunked.
+func (o options) request() (head string, body io.ReadCloser, size int64, err error) {
+	target, headers := o.requestHeaders()
+	if o.signer != nil {
+		if headers, err = o.signer.sign(o, headers); err != nil {
+			return "", nil, 0, err
+		}
+	}
+	if o.hasBody {
+		if body, size, err = o.openBody(); err != nil {
+			return "", nil, 0, fmt.Errorf("opening request body: %w", err)
+		}
+		if size < 0 && o.http10 {
+			// HTTP/1.0 has no chunked encoding, and a request body has to say how long it is, so read it all first.
+			b, err := io.ReadAll(body)
+			body.Close()
+			if err != nil {
+				return "", nil, 0, fmt.Errorf("reading request body: %w", err)
+			}
+			body, size = io.NopCloser(bytes.NewReader(b)), int64(len(b))
+		}
+		if len(o.trailers) > 0 {
+			size = -1 // trailers can only follow a chunked body, however long it is.
+		}
+		if size >= 0 {
+			headers = append(headers, Header{Key: "Content-Length", Value: strconv.FormatInt(size, 10)})
+		} else {
+			headers = append(headers, Header{Key: "Transfer-Encoding", Value: "chunked"})
+		}
+	}
+	version := "HTTP/1.1"
+	if o.http10 {
+		version = "HTTP/1.0"
+	}
+	reqFields := []string{fmt.Sprintf("%s %s %s", o.method, target, version)} // request line
+	for _, h := range headers {
+		reqFields = append(reqFields, h.Key+": "+h.Value)
+	}
+	reqFields = append(reqFields, "") // empty line to terminate the headers
+	return strings.Join(reqFields, "\r\n") + "\r\n", body, size, nil
+}
+
+/* design note: one server, one IP address, often serves many sites: the Host header is how it tells which one a
+   request is for, and so how a load balancer or a reverse proxy routes it. it's normally the URL's host, and its
+   port unless that's the scheme's default, which is what sendreq sends. -host-header sends another, while still
+   connecting to the URL's host, to ask a server reached by IP, or a staging box, for a site by name before DNS
+   points there. it's the Host header only: TLS's server name is -sni's business, and a redirect to another host
+   gets that host's own.
+*/
+
+// authority is the Host header's value for o's request: -host-header's name, or the URL's host and port.
+func (o options) authority() string {
+	if o.hostHeader.name != "" && o.hostHeader.host == o.host {
+		return o.hostHeader.name
+	}
+	return o.url().Host
+}
+
+// validAuthority reports whether s is a host, with an optional port, and nothing else: what a Host header holds.
+func validAuthority(s string) bool {
+	u, err := url.Parse("http://" + s)
+	return err == nil && u.Host == s && u.Hostname() != ""
+}
+
+// requestHeaders returns the request target for o's request line, and its headers: the defaults, with -H's merged
+// in. the body's framing headers aren't among them.
+func (o options) requestHeaders() (target string, headers Headers) {
+	// Host is the host and port the URL names (so an IPv6 address is bracketed), without the port if it's the default.
+	defaults := []Header{{Key: "Host", Value: o.authority()}, {Key: "User-Agent", Value: "httpget"}}
+	if o.user != "" {
+		defaults = append(defaults, Header{Key: "Authorization", Value: basicAuth(o.user)})
+	}
+	if o.compressed {
+		defaults = append(defaults, Header{Key: "Accept-Encoding", Value: acceptEncoding})
+	}
+	if o.expectContinue && o.hasBody {
+		defaults = append(defaults, Header{Key: "Expect", Value: "100-continue"})
+	}
+	if o.hasBody && len(o.trailers) > 0 {
+		// the Trailer header says which fields to expect after the body, so the server can be ready for them.
+		names := make([]string, len(o.trailers))
+		for i, h := range o.trailers {
+			names[i] = h.Key
+		}
+		defaults = append(defaults, Header{Key: "Trailer", Value: strings.Join(names, ", ")})
+	}
+	if o.validators.ETag != "" {
+		defaults = append(defaults, Header{Key: "If-None-Match", Value: o.validators.ETag})
+	}
+	if o.validators.LastModified != "" {
+		defaults = append(defaults, Header{Key: "If-Modified-Since", Value: o.validators.LastModified})
+	}
+	switch {
+	case o.hasBody && len(o.form) > 0:
+		defaults = append(defaults, Header{Key: "Content-Type", Value: "multipart/form-data; boundary=" + o.boundary})
+	case o.hasBody && len(o.values) > 0:
+		defaults = append(defaults, Header{Key: "Content-Type", Value: "application/x-www-form-urlencoded"})
+	}
+	// through a proxy, plain http requests are the proxy's to forward: the request line has the whole URL, and the
+	// proxy's credentials go along. (https requests went into a CONNECT tunnel, and are the target's alone.)
+	target = o.path
+	if o.proxy != nil && !o.tls {
+		target = o.url().String()
+		if auth := proxyAuth(o.proxy); auth != "" {
+			defaults = append(defaults, Header{Key: "Proxy-Authorization", Value: auth})
+		}
+	}
+	return target, mergeHeaders(defaults, o.headers)
+}
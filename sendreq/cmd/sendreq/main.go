@@ -0,0 +1,9 @@
+// Command sendreq sends a raw HTTP request; see package sendreq.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/sendreq"
+)
+
+func main() { cli.Main(sendreq.Command) }
@@ -0,0 +1,68 @@
+package sendreq
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"log/slog"
+	"time"
+)
+
+/* design note: "Expect: 100-continue" (RFC 9110, section 10.1.1) lets a client ask before it uploads. it sends the
+   request head alone and waits; the server answers "HTTP/1.1 100 Continue" to say go ahead, and the final response
+   comes after the body as usual. or the server can answer with the final response straight away, a 401 or a 413
+   say, and the client has saved itself sending a gigabyte to be thrown away.
+
+   a server that doesn't know the expectation never says 100, so the wait has to be short, and the body goes anyway
+   when it's up. the server may say 100 after all, late; that interim response comes ahead of the final one, so
+   readFinalHead skips it.
+
+   the client can't read the response while it's deciding whether to write the body, and reading the head is the only
+   way to see an answer arrive. so a goroutine peeks at the connection for the first byte of one, and the wait is a
+   select between it and a timer. whichever way it goes, nothing else touches the reader until the peek is done.
+*/
+
+// expectContinue waits, up to timeout, for the server to answer the head of a method request sent with Expect:
+// 100-continue on the connection read through r. if it says 100 Continue, or nothing in time, send sends the body
+// and expectContinue returns nil; the response is still to be read. if it gives a final answer instead, the body
+// isn't sent, and expectContinue returns the answer's head.
+func expectContinue(ctx context.Context, r *bufio.Reader, method string, timeout time.Duration, send func() error) (*rawResponse, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		var peekErr error
+		peeked := make(chan struct{})
+		go func() {
+			peekErr = awaitResponse(r)
+			close(peeked)
+		}()
+		select {
+		case <-timer.C:
+			slog.DebugContext(ctx, "main", "message", "no 100 Continue in time; sending the body anyway", "waited", timeout.String())
+			err := send()
+			<-peeked // the peek ends when the response comes, or the connection fails.
+			return nil, cmp.Or(err, peekErr)
+		case <-ctx.Done():
+			<-peeked // roundTrip closes the connection on ^C, which ends it.
+			return nil, ctx.Err()
+		case <-peeked:
+		}
+		if peekErr != nil {
+			return nil, peekErr // bufio won't say it again, so readHead would wait all over again.
+		}
+		head, err := readHead(r, method)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case head.status == 100:
+			slog.DebugContext(ctx, "main", "message", "got 100 Continue; sending the body")
+			return nil, send()
+		case head.status < 200 && head.status != 101:
+			continue // some other interim response, like 103 Early Hints: keep waiting.
+		default:
+			slog.InfoContext(ctx, "main", "message", "the server answered before the body was sent; not sending it", "status", head.status)
+			return head, nil
+		}
+	}
+}
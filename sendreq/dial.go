@@ -0,0 +1,210 @@
+package sendreq
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"net"
+	"time"
+)
+
+// dial connects to the server, wrapping the connection in TLS for -tls, and times each step. errors come back as
+// *dialError.
+func dial(ctx context.Context, o options) (net.Conn, dialTiming, error) {
+	var t dialTiming
+	// -connect-timeout bounds the lookup, the TCP handshake, and the TLS handshake together.
+	if o.connectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.connectTimeout)
+		defer cancel()
+	}
+	// the connection the request (or the TLS handshake) goes over: straight to the server, or through a proxy.
+	var conn net.Conn
+	var err error
+	switch {
+	case o.proxy != nil:
+		conn, err = dialProxy(ctx, o, &t)
+	case o.socks != nil:
+		conn, err = dialSOCKS(ctx, o, &t)
+	case o.unix != "":
+		conn, err = dialUnix(ctx, o.unix, &t)
+	default:
+		conn, err = dialTCP(ctx, o, o.host, o.port, &t)
+	}
+	if err != nil {
+		return nil, t, &dialError{err}
+	}
+	if o.trace != nil {
+		o.trace.note("connected to %s (%s)", o.host, conn.RemoteAddr())
+		if o.sock.local != nil {
+			o.trace.note("from %s, for -local-addr", conn.LocalAddr())
+		}
+		if o.sock.nagle && o.unix == "" {
+			o.trace.note("Nagle's algorithm is on (-nodelay=false)")
+		}
+	}
+	if !o.tls {
+		return o.traced(conn), t, nil
+	}
+
+	start := time.Now()
+	var alpn []string
+	if o.http2 {
+		alpn = []string{"h2"}
+	}
+	tc, asked, err := o.handshake(ctx, conn, alpn)
+	if err != nil {
+		conn.Close()
+		return nil, t, &dialError{err}
+	}
+	t.tls = time.Since(start)
+	if o.http2 && tc.ConnectionState().NegotiatedProtocol != "h2" {
+		tc.Close()
+		return nil, t, &dialError{fmt.Errorf("%s doesn't speak HTTP/2: it didn't pick h2 in the TLS handshake", o.host)}
+	}
+	if o.trace != nil {
+		if name := o.serverName(); name != o.host {
+			o.trace.note("sent %s as the server name (SNI), from -sni", name)
+		}
+		o.trace.traceHandshake(tc.ConnectionState())
+		if o.insecure {
+			o.trace.note("the server's certificate was NOT verified (-insecure)")
+		}
+		if note := clientCertNote(asked, o.cert); note != "" {
+			o.trace.note("%s", note)
+		}
+	}
+	return o.traced(tc), t, nil
+}
+
+/* design note: SNI (server name indication, RFC 6066) is the host name the client puts in its first handshake
+   message, in the clear, so a server with many sites behind one address knows which certificate to answer with,
+   and a CDN or load balancer knows where to route the connection, before anything is decrypted. it's normally the
+   host from the URL, the same name as the Host header. -sni sends another: to ask a CDN edge or an ingress for a
+   site by name while connecting to it by address, or to see which certificate a virtual host gets. the
+   certificate has to be valid for the SNI name, since that's the site we asked for; the Host header doesn't change.
+   (to send the request to a particular address as well, there's -resolve.)
+*/
+
+// hostOverride is a name to use for host, the URL's host, in place of its own, like -sni's server name. other hosts,
+// like a redirect's, get their own.
+type hostOverride struct{ host, name string }
+
+// serverName is the name to send as SNI and check the certificate against.
+func (o options) serverName() string {
+	if o.sni.name != "" && o.sni.host == o.host {
+		return o.sni.name
+	}
+	return o.host
+}
+
+// traced wraps conn to trace it for -v, if that's on.
+func (o options) traced(conn net.Conn) net.Conn {
+	if o.trace == nil {
+		return conn
+	}
+	return &traceConn{Conn: conn, t: o.trace}
+}
+
+// dialTCP opens a TCP connection to host:port, looking host up as o says, and adds the time the lookup and the
+// handshake took to t.
+func dialTCP(ctx context.Context, o options, host string, port int, t *dialTiming) (net.Conn, error) {
+	start := time.Now()
+	ips, err := o.resolve(ctx, host, port)
+	if err != nil {
+		return nil, fmt.Errorf("resolving tcp address: %w", err)
+	}
+	t.dns += time.Since(start)
+	if ips = o.sock.families(ips); len(ips) == 0 {
+		return nil, fmt.Errorf("%s has no address that -local-addr %s can reach", host, o.sock.local.IP)
+	}
+
+	start = time.Now()
+	conn, err := race(ctx, o.sock.dialer(), ips, port)
+	if err != nil {
+		return nil, fmt.Errorf("dialing tcp address: %w", err)
+	}
+	t.connect += time.Since(start)
+	if err := o.sock.apply(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dialUnix connects to the unix socket at path, adding the time it took to t. there's no lookup: the path is the
+// address. daemons like Docker serve HTTP this way, so only local users with permission on the file can reach it.
+func dialUnix(ctx context.Context, path string, t *dialTiming) (net.Conn, error) {
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("dialing unix socket: %w", err)
+	}
+	t.connect += time.Since(start)
+	return conn, nil
+}
+
+// dialTiming is how long each step of connecting took. through a proxy, dns and connect are for the proxy, and
+// connect includes setting up the tunnel.
+type dialTiming struct {
+	dns     time.Duration // looking up the host's addresses
+	connect time.Duration // the TCP handshake
+	tls     time.Duration // the TLS handshake, for -tls
+}
+
+// dialError is an error from connecting. the request never left, so trying again can't repeat it.
+type dialError struct{ err error }
+
+func (e *dialError) Error() string { return e.err.Error() }
+func (e *dialError) Unwrap() error { return e.err }
+
+// timeoutReader sets a read deadline before every read, so a server that goes quiet for longer than d fails the read
+// with a timeout instead of hanging it forever. it sits under the response reader's buffer, where the reads that
+// actually wait on the network happen.
+type timeoutReader struct {
+	conn net.Conn
+	d    time.Duration
+}
+
+func (t timeoutReader) Read(p []byte) (int, error) {
+	if err := t.conn.SetReadDeadline(time.Now().Add(t.d)); err != nil {
+		return 0, err
+	}
+	return t.conn.Read(p)
+}
+
+// handshake runs the TLS client handshake over conn. the server's certificate is checked against the system roots, or
+// -cacert's (see cert.go), unless it's -insecure, and must be valid for the server name: o.host, or -sni's name for
+// it. the name is also sent as SNI, so a server hosting several sites knows which certificate to present. (SNI is
+// only for names; Go leaves it out when the host is an IP address.) alpn is the application protocols to offer, like "h2"; the server picks one,
+// or none. -cert's certificate goes if the server asks for one; asked says whether it did.
+func (o options) handshake(ctx context.Context, conn net.Conn, alpn []string) (tc *tls.Conn, asked bool, err error) {
+	config := &tls.Config{ServerName: o.serverName(), NextProtos: alpn, RootCAs: o.roots, InsecureSkipVerify: o.insecure}
+	// only called if the server sends a CertificateRequest. an empty certificate means there's none to send.
+	config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+		asked = true
+		if o.cert == nil {
+			return &tls.Certificate{}, nil
+		}
+		return o.cert, nil
+	}
+	tc = tls.Client(conn, config)
+	if err := tc.HandshakeContext(ctx); err != nil {
+		if asked {
+			err = fmt.Errorf("%s: %w", clientCertNote(asked, o.cert), err)
+		}
+		return nil, asked, fmt.Errorf("tls handshake with %s: %w", config.ServerName, verifyHint(err))
+	}
+	state := tc.ConnectionState()
+	slog.InfoContext(ctx, "main", "message", "tls handshake done", "version", tls.VersionName(state.Version),
+		"cipher", tls.CipherSuiteName(state.CipherSuite), "server", state.PeerCertificates[0].Subject.CommonName)
+	if o.insecure {
+		slog.WarnContext(ctx, "main", "message", "-insecure: the server's certificate wasn't verified, so this connection could be to anyone")
+	}
+	if note := clientCertNote(asked, o.cert); note != "" {
+		slog.InfoContext(ctx, "main", "message", note)
+	}
+	return tc, asked, nil
+}
@@ -0,0 +1,212 @@
+package sendreq
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ekediala/httpwire"
+)
+
+/* design note: digest auth (RFC 7616) is basic auth's answer to sending the password in the clear. the server's 401
+   carries a challenge, "WWW-Authenticate: Digest realm=..., nonce=..., qop=auth", and the client proves it knows the
+   password by hashing it together with the nonce, the method, and the path:
+
+	HA1      = H(user:realm:password)
+	HA2      = H(method:uri)
+	response = H(HA1:nonce:nc:cnonce:qop:HA2)
+
+   nc counts the requests made with the nonce, and cnonce is the client's own random nonce, so a response can't be
+   replayed, and the server doesn't get to pick everything that's hashed. H is MD5 or SHA-256, as the challenge's
+   algorithm says; "-sess" algorithms hash the nonces into HA1 too. a server can offer both in separate challenges,
+   and SHA-256 wins. only qop=auth is done, not auth-int, which hashes the body into HA2 and which few servers ask
+   for.
+
+   -u sends basic auth up front, as it always has. a 401 with a digest challenge makes us send the request again
+   with a digest answer, and remember the challenge, so later requests to the same server answer it straight away,
+   with nc counting up, instead of being turned away first. the server says "stale=true" when its nonce has expired
+   but the answer was otherwise right: the request goes again with the new one. a second 401 otherwise means the
+   credentials are wrong, and comes back as it is.
+*/
+
+// digestChallenge is a server's Digest challenge, and how many requests have answered it.
+type digestChallenge struct {
+	realm, nonce, opaque, algorithm, qop string
+	stale                                bool
+
+	mu sync.Mutex
+	nc int
+}
+
+// parseDigest returns the best Digest challenge in headers' WWW-Authenticate, if there is one.
+func parseDigest(headers []Header) (*digestChallenge, bool) {
+	var best *digestChallenge
+	for _, h := range headers {
+		if h.Key != httpwire.AsTitle("WWW-Authenticate") {
+			continue
+		}
+		scheme, rest, _ := strings.Cut(strings.TrimSpace(h.Value), " ")
+		if !strings.EqualFold(scheme, "Digest") {
+			continue
+		}
+		params := authParams(rest)
+		c := &digestChallenge{realm: params["realm"], nonce: params["nonce"], opaque: params["opaque"], algorithm: params["algorithm"],
+			stale: strings.EqualFold(params["stale"], "true")}
+		if c.algorithm == "" {
+			c.algorithm = "MD5"
+		}
+		if digestHash(c.algorithm) == nil || c.nonce == "" {
+			continue
+		}
+		if qop, ok := params["qop"]; ok {
+			// a list of what the server will take; only auth is done here.
+			for _, q := range strings.Split(qop, ",") {
+				if strings.TrimSpace(q) == "auth" {
+					c.qop = "auth"
+				}
+			}
+			if c.qop == "" {
+				continue
+			}
+		}
+		if best == nil || strings.HasPrefix(strings.ToUpper(c.algorithm), "SHA-256") && !strings.HasPrefix(strings.ToUpper(best.algorithm), "SHA-256") {
+			best = c
+		}
+	}
+	return best, best != nil
+}
+
+// authParams parses a challenge's comma-separated key=value parameters, where a value can be a quoted string.
+func authParams(s string) map[string]string {
+	params := map[string]string{}
+	for {
+		s = strings.TrimLeft(s, " \t,")
+		k, rest, ok := strings.Cut(s, "=")
+		if !ok {
+			return params
+		}
+		k, rest = strings.ToLower(strings.TrimSpace(k)), strings.TrimLeft(rest, " \t")
+		var v strings.Builder
+		if strings.HasPrefix(rest, `"`) {
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				v.WriteByte(rest[i])
+			}
+			s = rest[min(i+1, len(rest)):]
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end < 0 {
+				end = len(rest)
+			}
+			v.WriteString(strings.TrimSpace(rest[:end]))
+			s = rest[end:]
+		}
+		params[k] = v.String()
+	}
+}
+
+// digestHash returns the hash function for a challenge's algorithm, or nil if it's one we don't do.
+func digestHash(algorithm string) func() hash.Hash {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		return md5.New
+	case "SHA-256":
+		return sha256.New
+	}
+	return nil
+}
+
+// authorization answers c for user:password's request of method for uri, with the next nc and a fresh cnonce.
+func (c *digestChallenge) authorization(userinfo, method, uri string) string {
+	c.mu.Lock()
+	c.nc++
+	nc := fmt.Sprintf("%08x", c.nc)
+	c.mu.Unlock()
+	b := make([]byte, 16)
+	rand.Read(b)
+	return c.answer(userinfo, method, uri, nc, hex.EncodeToString(b))
+}
+
+// answer is the Authorization header value for the given nc and cnonce.
+func (c *digestChallenge) answer(userinfo, method, uri, nc, cnonce string) string {
+	newHash := digestHash(c.algorithm)
+	h := func(s string) string {
+		hh := newHash()
+		hh.Write([]byte(s))
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+	user, password, _ := strings.Cut(userinfo, ":")
+	ha1 := h(user + ":" + c.realm + ":" + password)
+	if strings.HasSuffix(strings.ToUpper(c.algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + c.nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username=%q, realm=%q, nonce=%q, uri=%q, algorithm=%s`, user, c.realm, c.nonce, uri, c.algorithm)
+	if c.qop == "" {
+		// RFC 2069's original digest, for a server that offers no qop.
+		fmt.Fprintf(&b, `, response="%s"`, h(ha1+":"+c.nonce+":"+ha2))
+	} else {
+		fmt.Fprintf(&b, `, response="%s", qop=%s, nc=%s, cnonce=%q`, h(ha1+":"+c.nonce+":"+nc+":"+cnonce+":"+c.qop+":"+ha2), c.qop, nc, cnonce)
+	}
+	if c.opaque != "" {
+		fmt.Fprintf(&b, `, opaque=%q`, c.opaque)
+	}
+	return b.String()
+}
+
+// withDigest returns o with an Authorization header answering c, in place of -u's basic auth.
+func (o options) withDigest(c *digestChallenge) options {
+	// clipped, so the append can't write into an array o.headers shares with other requests.
+	o.headers = append(slices.Clip(o.headers), Header{Key: "Authorization", Value: c.authorization(o.user, o.method, o.path)})
+	return o
+}
+
+// doAuth sends o's request with doRetry, answering a digest challenge if there is one and there's a -u to answer it
+// with.
+func (c *client) doAuth(ctx context.Context, o options) (*rawResponse, error) {
+	if o.user == "" {
+		return c.doRetry(ctx, o)
+	}
+	space := o.url().Scheme + "://" + o.url().Host // the "protection space" a challenge is good for
+	c.mu.Lock()
+	known := c.digests[space]
+	c.mu.Unlock()
+	sent := o
+	if known != nil {
+		sent = o.withDigest(known)
+	}
+	resp, err := c.doRetry(ctx, sent)
+	if err != nil || resp.status != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge, ok := parseDigest(resp.headers)
+	switch {
+	case !ok:
+		return resp, nil
+	case known != nil && !challenge.stale:
+		return resp, nil // the answer was wrong, not just late: the credentials are.
+	case o.hasBody && o.dataStdin:
+		return resp, nil // stdin's been read, and can't be sent again.
+	}
+	slog.InfoContext(ctx, "main", "message", "server wants digest auth; sending the request again with it", "realm", challenge.realm, "algorithm", challenge.algorithm)
+	c.mu.Lock()
+	if c.digests == nil {
+		c.digests = map[string]*digestChallenge{}
+	}
+	c.digests[space] = challenge
+	c.mu.Unlock()
+	return c.doRetry(ctx, o.withDigest(challenge))
+}
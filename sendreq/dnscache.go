@@ -0,0 +1,83 @@
+package sendreq
+
+import (
+	"context"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+/* design note: every new connection starts with a lookup, and a run of many requests makes many connections: -n
+   against a server that closes each one, -bench's workers, -sse and -ws reconnecting. asking DNS the same question
+   each time adds a round trip to every connection, which in a benchmark is measuring the resolver, not the server.
+   so a lookup's answer is kept, and reused for -dns-ttl. lookups for the same host at the same time, like -bench's
+   workers all starting at once, share one query instead of each sending their own.
+
+   Go's resolver doesn't say what TTL the records came with, so the cache can't honor it exactly; -dns-ttl is a
+   stand-in, short enough to pick up a change within a run. a failed lookup isn't kept: the next connection asks
+   again. -no-dns-cache looks the host up for every connection, for testing DNS failover, or a round-robin name
+   whose answers should rotate between connections.
+*/
+
+// defaultDNSTTL is how long a lookup's answer is kept, unless -dns-ttl says otherwise.
+const defaultDNSTTL = 30 * time.Second
+
+// dnsCache keeps the answers to lookups for ttl. it's safe for concurrent use.
+type dnsCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]*dnsEntry // by lowercase host name
+}
+
+// dnsEntry is a lookup, in flight until done is closed.
+type dnsEntry struct {
+	done    chan struct{}
+	ips     []net.IP
+	err     error
+	expires time.Time
+}
+
+func newDNSCache(ttl time.Duration) *dnsCache {
+	return &dnsCache{ttl: ttl, entries: map[string]*dnsEntry{}}
+}
+
+// lookup returns host's addresses: from the cache if they're there and fresh, or from a lookup already under way,
+// or else from calling lookUp, and keeping what it says. cached says whether the answer was someone else's.
+func (c *dnsCache) lookup(ctx context.Context, host string, lookUp func(context.Context) ([]net.IP, error)) (ips []net.IP, cached bool, err error) {
+	key := strings.ToLower(host)
+	for {
+		c.mu.Lock()
+		e := c.entries[key]
+		if e == nil || e.stale() {
+			e = &dnsEntry{done: make(chan struct{})}
+			c.entries[key] = e
+			c.mu.Unlock()
+			e.ips, e.err = lookUp(ctx)
+			e.expires = time.Now().Add(c.ttl)
+			close(e.done)
+			return slices.Clone(e.ips), false, e.err
+		}
+		c.mu.Unlock()
+		select {
+		case <-e.done:
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+		if e.err == nil {
+			return slices.Clone(e.ips), true, nil
+		}
+		// the lookup we waited for failed, maybe for reasons of its own, like its context ending: try one ourselves.
+	}
+}
+
+// stale reports whether e's lookup is over, and its answer no use: it failed, or it's expired.
+func (e *dnsEntry) stale() bool {
+	select {
+	case <-e.done:
+		return e.err != nil || time.Now().After(e.expires)
+	default:
+		return false // still in flight
+	}
+}
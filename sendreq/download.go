@@ -0,0 +1,140 @@
+package sendreq
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+/* design note: -O is for bodies too big to print, or to hold: a disk image, a database dump. everything else in
+   sendreq reads a response whole and then prints it, but -O copies the body to the file as it comes off the
+   connection, a buffer at a time, so a 10GB download takes no more memory than a 10 byte one. the framing still
+   decides where the body ends, so the connection can carry the next request after.
+
+   the progress report counts bytes as they arrive, before any -compressed decoding, so the percentage is out of the
+   Content-Length, which is the size on the wire.
+*/
+
+// progressInterval is how often the progress line is redrawn.
+const progressInterval = 200 * time.Millisecond
+
+// download reads a response for -O: a final response's body goes to the file, while a redirect that -L will
+// follow is read as usual.
+func (c *client) download(r *bufio.Reader, o options) (*rawResponse, error) {
+	resp, err := readFinalHead(r, o.method)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case o.follow && isRedirect(resp.status) && resp.headers.Get("Location") != "":
+		return resp, resp.readBody()
+	case bodiless(o.method, resp.status):
+		// nothing to save, and a 304 from -cache means the file from last time is still good.
+		return resp, resp.readBody()
+	}
+	return resp, save(o, resp, c.progress)
+}
+
+// save reads resp's body into -O's file, decoding it for -compressed, with a progress report to w. the file is
+// created (or truncated) only now, so a retried request starts it over.
+func save(o options, resp *rawResponse, w io.Writer) error {
+	body, size := resp.bodyReader()
+	f, err := os.Create(o.download)
+	if err != nil {
+		return err
+	}
+	p := newProgress(w, size)
+	wire := io.TeeReader(body, p)
+	src := wire
+	if o.compressed && !o.compressedRaw {
+		if src, err = decoder(resp, wire); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	_, err = io.Copy(f, src)
+	if err == nil {
+		// whatever follows the end of a compressed stream is still part of the response, and has to be read off the
+		// connection before it can carry another one.
+		_, err = io.Copy(io.Discard, wire)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	p.done(o.download)
+	if err != nil {
+		return fmt.Errorf("saving response body to %s: %w", o.download, err)
+	}
+	return nil
+}
+
+// progress counts the bytes written to it and reports on them: a line redrawn as they arrive if the report goes to
+// a terminal, and a last line when they're all in.
+type progress struct {
+	w     io.Writer
+	live  bool  // w is a terminal, so the line can be redrawn in place
+	n     int64 // bytes so far
+	size  int64 // -1 if unknown
+	start time.Time
+	drawn time.Time
+}
+
+func newProgress(w io.Writer, size int64) *progress {
+	now := time.Now()
+	return &progress{w: w, live: isTerminal(w), size: size, start: now, drawn: now}
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.n += int64(len(b))
+	if p.live && time.Since(p.drawn) >= progressInterval {
+		// \r goes back to the start of the line, and \x1b[K clears what's left of the last one.
+		fmt.Fprintf(p.w, "\r%s\x1b[K", p.line())
+		p.drawn = time.Now()
+	}
+	return len(b), nil
+}
+
+// done prints the last line, for the file at path.
+func (p *progress) done(path string) {
+	if p.live {
+		fmt.Fprint(p.w, "\r\x1b[K")
+	}
+	fmt.Fprintf(p.w, "%s: %s in %s\n", path, p.line(), time.Since(p.start).Round(time.Millisecond))
+}
+
+// line is the report so far, like "3.1 MiB / 10.0 MiB (31%), 1.5 MiB/s".
+func (p *progress) line() string {
+	s := byteSize(p.n)
+	if p.size > 0 {
+		s += fmt.Sprintf(" / %s (%d%%)", byteSize(p.size), p.n*100/p.size)
+	}
+	if elapsed := time.Since(p.start).Seconds(); elapsed > 0 {
+		s += fmt.Sprintf(", %s/s", byteSize(int64(float64(p.n)/elapsed)))
+	}
+	return s
+}
+
+// byteSize formats n bytes in binary units: 512 B, 1.5 KiB, 3.0 GiB.
+func byteSize(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	f, unit := float64(n)/1024, 0
+	for f >= 1024 && unit < len("KMGTPE")-1 {
+		f /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %ciB", f, "KMGTPE"[unit])
+}
+
+// isTerminal reports whether w is a terminal (or some other character device, which is near enough).
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,73 @@
+package sendreq
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// acceptEncoding is the Accept-Encoding -compressed sends: the codings decodeBody knows.
+const acceptEncoding = "gzip, deflate"
+
+/* design note: Content-Encoding and Transfer-Encoding look alike but live at different layers. chunked is a
+   transfer coding: framing for this one hop, which readResponse always takes off. gzip in Content-Encoding is part of
+   the representation itself; the server stored or sent the body compressed, and would hand a cache the same bytes.
+   that's why a client only gets it by asking with Accept-Encoding, and why undoing it is optional (-compressed-raw).
+*/
+
+// decodeBody undoes the response's Content-Encoding, replacing resp.body with the decoded bytes.
+func decodeBody(resp *rawResponse) error {
+	if len(resp.body) == 0 {
+		return nil // HEAD, 204, 304: nothing was encoded.
+	}
+	body, err := decoder(resp, bytes.NewReader(resp.body))
+	if err != nil {
+		return err
+	}
+	if resp.body, err = io.ReadAll(body); err != nil {
+		return fmt.Errorf("decoding response body: %w", err)
+	}
+	return nil
+}
+
+// decoder wraps body, the response's encoded body, in a reader that undoes its Content-Encoding. codings are listed
+// in the order they were applied, so they come off in reverse.
+func decoder(resp *rawResponse, body io.Reader) (io.Reader, error) {
+	var codings []string
+	for _, c := range resp.headers.List("Content-Encoding") {
+		if c = strings.ToLower(c); c != "identity" {
+			codings = append(codings, c)
+		}
+	}
+	for i := len(codings) - 1; i >= 0; i-- {
+		var err error
+		if body, err = decode(codings[i], body); err != nil {
+			return nil, fmt.Errorf("decoding %s response body: %w", codings[i], err)
+		}
+	}
+	return body, nil
+}
+
+// decode returns a reader that undoes one content coding of r.
+func decode(coding string, r io.Reader) (io.Reader, error) {
+	switch coding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(r)
+	case "deflate":
+		// "deflate" in HTTP means zlib-wrapped deflate (RFC 1950), but some servers have always sent the bare
+		// stream (RFC 1951) instead. a zlib stream starts with a two-byte header: deflate's method number (8) in the
+		// low bits of the first, and a check that makes the pair a multiple of 31, which anything else rarely is.
+		br := bufio.NewReader(r)
+		if h, err := br.Peek(2); err == nil && h[0]&0x0f == 8 && (uint16(h[0])<<8|uint16(h[1]))%31 == 0 {
+			return zlib.NewReader(br)
+		}
+		return flate.NewReader(br), nil
+	default:
+		return nil, fmt.Errorf("unsupported content coding %q", coding)
+	}
+}
@@ -0,0 +1,138 @@
+package sendreq
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/* design note: -expect-status, -expect-body-contains, and -expect-header turn sendreq into a one-line smoke test for
+   a CI script: each response (the last one, with -L) is checked against them, and if it falls short, what was
+   expected and what came back go to stderr, diff style, and sendreq exits 1:
+
+	expectations not met by GET http://localhost:8080/health
+	  - status 200
+	  + status 503
+	  - Content-Type: application/json
+	  + Content-Type: text/html
+
+   the response is still printed first, as usual, so the log shows what the server actually said. the checks are
+   deliberately loose where servers vary for no reason: -expect-status takes a list, and classes like 2xx;
+   -expect-header's value only has to appear in the header's (so "application/json" matches
+   "application/json; charset=utf-8"), and with no value the header just has to be there. the body is checked after
+   -compressed has decoded it. with -requests, each request is checked, and one that falls short is a FAIL in the
+   report.
+*/
+
+// expectations are the -expect-* flags' checks on each response.
+type expectations struct {
+	status       []string    // codes like "200", or classes like "2xx"
+	bodyContains stringFlags // -expect-body-contains
+	headers      headerFlags // -expect-header
+}
+
+// stringFlags is a repeatable string flag.
+type stringFlags []string
+
+func (s *stringFlags) String() string { return strings.Join(*s, ", ") }
+
+func (s *stringFlags) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// parseStatuses parses -expect-status's comma-separated list of codes and classes, like "200,204" or "2xx,304".
+func parseStatuses(s string) ([]string, error) {
+	var statuses []string
+	for _, code := range strings.Split(s, ",") {
+		code = strings.TrimSpace(code)
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.ToLower(code), "xx"))
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("%q isn't a status code or a class like 2xx", code)
+		case strings.HasSuffix(strings.ToLower(code), "xx") && (n < 1 || n > 5):
+			return nil, fmt.Errorf("%q: status classes run from 1xx to 5xx", code)
+		case !strings.HasSuffix(strings.ToLower(code), "xx") && (n < 100 || n > 599):
+			return nil, fmt.Errorf("%q: status codes run from 100 to 599", code)
+		}
+		statuses = append(statuses, strings.ToLower(code))
+	}
+	return statuses, nil
+}
+
+// check checks resp, o's response, against e, and if it falls short, prints what was expected and what came back
+// to stderr, and says how many checks failed. a nil e expects nothing.
+func (e *expectations) check(stderr io.Writer, o options, resp *rawResponse) error {
+	if e == nil {
+		return nil
+	}
+	var diff []string // "- expected" and "+ got" lines
+	failed := 0
+	if len(e.status) > 0 && !statusMatches(e.status, resp.status) {
+		failed++
+		diff = append(diff, "- status "+strings.Join(e.status, " or "), fmt.Sprintf("+ status %d", resp.status))
+	}
+	for _, want := range e.bodyContains {
+		if !strings.Contains(string(resp.body), want) {
+			failed++
+			diff = append(diff, fmt.Sprintf("- body containing %q", want), "+ body "+quoteBody(resp.body))
+		}
+	}
+	for _, want := range e.headers {
+		var got []string
+		ok := false
+		for _, h := range resp.headers {
+			if h.Key == want.Key {
+				got = append(got, h.Value)
+				ok = ok || strings.Contains(h.Value, want.Value)
+			}
+		}
+		if ok {
+			continue
+		}
+		failed++
+		if want.Value == "" {
+			diff = append(diff, "- "+want.Key+" header present")
+		} else {
+			diff = append(diff, "- "+want.Key+": "+want.Value)
+		}
+		if len(got) == 0 {
+			diff = append(diff, "+ no "+want.Key+" header")
+		}
+		for _, v := range got {
+			diff = append(diff, "+ "+want.Key+": "+v)
+		}
+	}
+	if failed == 0 {
+		return nil
+	}
+	fmt.Fprintf(stderr, "expectations not met by %s %s\n", o.method, o.url())
+	for _, line := range diff {
+		fmt.Fprintf(stderr, "  %s\n", line)
+	}
+	if failed == 1 {
+		return errors.New("1 expectation not met")
+	}
+	return fmt.Errorf("%d expectations not met", failed)
+}
+
+// statusMatches reports whether status is one of statuses' codes, or in one of its classes.
+func statusMatches(statuses []string, status int) bool {
+	for _, s := range statuses {
+		if s == strconv.Itoa(status) || strings.HasSuffix(s, "xx") && s[:1] == strconv.Itoa(status/100) {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteBody quotes body for a diff line, cut short if it's long.
+func quoteBody(body []byte) string {
+	const max = 200
+	if len(body) <= max {
+		return strconv.Quote(string(body))
+	}
+	return fmt.Sprintf("%s... (%d bytes)", strconv.Quote(string(body[:max])), len(body))
+}
@@ -0,0 +1,103 @@
+package sendreq
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"strconv"
+	"time"
+)
+
+/* design note: a host with both IPv4 and IPv6 addresses is a gamble. IPv6 is preferred, but plenty of networks
+   have an IPv6 route that goes nowhere, and a client that tries one address at a time waits out a whole TCP timeout
+   on it before falling back. "happy eyeballs" (RFC 8305) races them instead: start with the first address, and if
+   it hasn't connected within a short delay, start the next one too, alternating families, and keep whichever
+   connects first. a working IPv6 network costs nothing, and a broken one costs 250ms instead of a minute.
+*/
+
+// attemptDelay is how long a connection attempt gets before the next one starts alongside it, as RFC 8305
+// recommends.
+const attemptDelay = 250 * time.Millisecond
+
+// interleave orders ips to alternate between IPv6 and IPv4, starting with the family of the first one, which the
+// resolver sorted to be the best bet (RFC 6724). otherwise each family keeps its order.
+func interleave(ips []net.IP) []net.IP {
+	var first, second []net.IP
+	for _, ip := range ips {
+		if (ip.To4() == nil) == (ips[0].To4() == nil) {
+			first = append(first, ip)
+		} else {
+			second = append(second, ip)
+		}
+	}
+	out := make([]net.IP, 0, len(ips))
+	for i := 0; i < len(first) || i < len(second); i++ {
+		if i < len(first) {
+			out = append(out, first[i])
+		}
+		if i < len(second) {
+			out = append(out, second[i])
+		}
+	}
+	return out
+}
+
+// race connects to one of ips on port, happy-eyeballs style: attempts start attemptDelay apart, or as soon as the
+// one before fails, and the first to connect wins. the others are called off. if every attempt fails, the error is
+// the first one's. every attempt uses d.
+func race(ctx context.Context, d *net.Dialer, ips []net.IP, port int) (net.Conn, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // calls off the losers.
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, len(ips)) // room for every attempt, so none is left blocked sending.
+	started, pending := 0, 0
+	next := func() {
+		addr := net.JoinHostPort(ips[started].String(), strconv.Itoa(port))
+		started++
+		pending++
+		go func() {
+			conn, err := d.DialContext(ctx, "tcp", addr)
+			results <- result{conn, err}
+		}()
+	}
+
+	next()
+	timer := time.NewTimer(attemptDelay)
+	defer timer.Stop()
+	var firstErr error
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				slog.DebugContext(ctx, "main", "message", "connected", "addr", r.conn.RemoteAddr().String(), "attempts", started)
+				// an attempt that connects just as it's called off still has to be closed.
+				go func(n int) {
+					for ; n > 0; n-- {
+						if r := <-results; r.conn != nil {
+							r.conn.Close()
+						}
+					}
+				}(pending)
+				return r.conn, nil
+			}
+			if firstErr == nil {
+				firstErr = r.err
+			}
+			if started < len(ips) {
+				next()
+				timer.Reset(attemptDelay)
+			}
+		case <-timer.C:
+			if started < len(ips) {
+				next()
+				timer.Reset(attemptDelay)
+			}
+		}
+	}
+	return nil, firstErr
+}
@@ -0,0 +1,297 @@
+package sendreq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ekediala/cli"
+	"github.com/ekediala/httpwire"
+)
+
+/* design note: -record saves each exchange to a directory as a fixture: a file of its own, numbered in the order
+   the requests were made, with the request as it was sent and the response as it came off the wire, written by
+   Request.WriteTo and Response.WriteTo:
+
+	fixtures/001-get-health.fixture
+	fixtures/002-post-items.fixture
+
+   the file is plain HTTP/1.1, so it reads like -v's trace, diffs well in a code review, and can be fixed up by hand.
+   it's read back by the same framing rules as a connection: the request's head, then its body, which always has a
+   Content-Length (a chunked request body is written whole), then the response's head, and its body, framed by its
+   own headers. the response body is kept as it came, still compressed if it was.
+
+   -replay sends each fixture's request again, in order, to the URL on the command line, so fixtures recorded
+   against staging can check production, and compares the response with the recorded one: status, headers, and body.
+   that's a golden-file test: a difference is shown as - and + lines, as -expect-* does, and sendreq exits 1.
+   headers that change with every response, like Date, would always differ, so they aren't compared, and nor are
+   -replay-ignore's. to update the fixtures, delete them and record again; recording into a directory that has some
+   already adds to them, numbered after the last.
+*/
+
+// unreplayed are the headers -replay doesn't compare, because they change with every response.
+var unreplayed = []string{"Date"}
+
+// fixtureDir is a -record directory, which each exchange is saved to, as the next numbered file.
+type fixtureDir struct {
+	path string
+	n    int // the last file's number
+}
+
+// newFixtureDir makes the -record directory, if it doesn't exist, and finds where the numbering is up to.
+func newFixtureDir(path string) (*fixtureDir, error) {
+	if err := os.MkdirAll(path, 0o755); err != nil {
+		return nil, err
+	}
+	names, err := filepath.Glob(filepath.Join(path, "*.fixture"))
+	if err != nil {
+		return nil, err
+	}
+	d := &fixtureDir{path: path}
+	for _, name := range names {
+		prefix, _, _ := strings.Cut(filepath.Base(name), "-")
+		if n, err := strconv.Atoi(strings.TrimSuffix(prefix, ".fixture")); err == nil && n > d.n {
+			d.n = n
+		}
+	}
+	return d, nil
+}
+
+// save writes o's request and resp, its response, with the body as it came over the wire, to the next file.
+func (d *fixtureDir) save(o options, resp *rawResponse) error {
+	req, err := o.fixtureRequest()
+	if err != nil {
+		return err
+	}
+	var b bytes.Buffer
+	req.WriteTo(&b)
+	(&Response{StatusCode: resp.status, Headers: resp.headers, Body: string(resp.body), Trailers: resp.trailers}).WriteTo(&b)
+	d.n++
+	name := fmt.Sprintf("%03d-%s%s.fixture", d.n, strings.ToLower(o.method), slug(o.path))
+	return os.WriteFile(filepath.Join(d.path, name), b.Bytes(), 0o644)
+}
+
+// fixtureRequest is o's request as it was sent, with its body whole, and a Content-Length saying how long it is.
+func (o options) fixtureRequest() (*Request, error) {
+	head, body, _, err := o.request()
+	if err != nil {
+		return nil, err
+	}
+	var b []byte
+	if body != nil {
+		b, err = io.ReadAll(body)
+		body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("reading request body: %w", err)
+		}
+	}
+	// the request line's target is the whole URL through a proxy; a fixture's is the path, which is what's replayed.
+	r := &Request{Method: o.method, Path: o.path, Body: string(b)}
+	lines := strings.Split(strings.TrimSuffix(head, "\r\n\r\n"), "\r\n")
+	for _, line := range lines[1:] {
+		k, v, _ := strings.Cut(line, ": ")
+		if k != "Content-Length" && k != "Transfer-Encoding" {
+			r.Headers = append(r.Headers, Header{Key: k, Value: v})
+		}
+	}
+	if len(b) > 0 {
+		r.Headers = append(r.Headers, Header{Key: "Content-Length", Value: strconv.Itoa(len(b))})
+	}
+	return r, nil
+}
+
+// slug turns a request path into something for a file name: "/items/42?full=1" is "-items-42".
+func slug(path string) string {
+	path, _, _ = strings.Cut(path, "?")
+	var b strings.Builder
+	dash := false
+	for _, c := range strings.ToLower(path) {
+		switch {
+		case c >= 'a' && c <= 'z' || c >= '0' && c <= '9':
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(c)
+			dash = false
+		default:
+			dash = true
+		}
+		if b.Len() >= 40 {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "-" + b.String()
+}
+
+// readFixture reads a fixture's request, and the response recorded for it.
+func readFixture(path string) (*Request, *rawResponse, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+
+	line, err := r.ReadString('\n')
+	fields := strings.Fields(line)
+	if err != nil || len(fields) != 3 {
+		return nil, nil, fmt.Errorf("malformed fixture: %q should be a request line", strings.TrimSpace(line))
+	}
+	req := &Request{Method: fields[0], Path: fields[1]}
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, nil, fmt.Errorf("malformed fixture: request headers: %w", err)
+		}
+		if line == "\r\n" {
+			break
+		}
+		k, v, ok := strings.Cut(strings.TrimSuffix(line, "\r\n"), ": ")
+		if !ok {
+			return nil, nil, fmt.Errorf("malformed fixture: header %q should be of form 'key: value'", strings.TrimSpace(line))
+		}
+		req.Headers = append(req.Headers, Header{Key: httpwire.AsTitle(k), Value: v})
+	}
+	n := 0
+	if cl := req.Headers.Get("Content-Length"); cl != "" {
+		if n, err = strconv.Atoi(cl); err != nil || n < 0 {
+			return nil, nil, fmt.Errorf("malformed fixture: bad request Content-Length %q", cl)
+		}
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, nil, fmt.Errorf("malformed fixture: request body: %w", err)
+	}
+	req.Body = string(body)
+	// Request.WriteTo ends the body with a CRLF of its own.
+	if crlf, _ := r.ReadString('\n'); crlf != "\r\n" {
+		return nil, nil, fmt.Errorf("malformed fixture: want a CRLF after the request body, got %q", crlf)
+	}
+
+	resp, err := readFinalHead(r, req.Method)
+	if err != nil {
+		return nil, nil, fmt.Errorf("malformed fixture: response: %w", err)
+	}
+	if err := resp.readBody(); err != nil {
+		return nil, nil, fmt.Errorf("malformed fixture: %w", err)
+	}
+	if resp.toEOF {
+		resp.body = bytes.TrimSuffix(resp.body, []byte("\r\n")) // Response.WriteTo's, as for the request's.
+	}
+	return req, resp, nil
+}
+
+// fromFixture returns the options for sending req, a fixture's request, to o's URL: its method, path, headers, with
+// -H's over them, and body. Host and the framing headers are o's own.
+func (o options) fromFixture(req *Request) options {
+	r := o
+	r.method, r.path = req.Method, req.Path
+	r.headers = slices.DeleteFunc(headerFlags(slices.Clone(req.Headers)), func(h Header) bool {
+		return h.Key == "Host" || h.Key == "Content-Length" || h.Key == "Transfer-Encoding"
+	})
+	for _, h := range o.headers {
+		if i := indexHeader(r.headers, h.Key); i >= 0 {
+			r.headers[i] = h
+		} else {
+			r.headers = append(r.headers, h)
+		}
+	}
+	r.data, r.hasBody = req.Body, req.Body != ""
+	return r
+}
+
+// diffResponses compares got with want, the recorded response, and returns how they differ, as "- want" and
+// "+ got" lines, leaving out the headers in ignore. no lines means they match.
+func diffResponses(want, got *rawResponse, ignore []string) []string {
+	var diff []string
+	if want.status != got.status {
+		diff = append(diff, fmt.Sprintf("- status %d", want.status), fmt.Sprintf("+ status %d", got.status))
+	}
+	var keys []string
+	for _, h := range append(slices.Clone(want.headers), got.headers...) {
+		if !slices.Contains(keys, h.Key) && !slices.ContainsFunc(ignore, func(k string) bool { return httpwire.AsTitle(k) == h.Key }) {
+			keys = append(keys, h.Key)
+		}
+	}
+	for _, key := range keys {
+		w, g := want.headers.Values(key), got.headers.Values(key)
+		if slices.Equal(w, g) {
+			continue
+		}
+		if len(w) == 0 {
+			diff = append(diff, "- no "+key+" header")
+		}
+		for _, v := range w {
+			diff = append(diff, "- "+key+": "+v)
+		}
+		if len(g) == 0 {
+			diff = append(diff, "+ no "+key+" header")
+		}
+		for _, v := range g {
+			diff = append(diff, "+ "+key+": "+v)
+		}
+	}
+	if !bytes.Equal(want.body, got.body) {
+		diff = append(diff, "- body "+quoteBody(want.body), "+ body "+quoteBody(got.body))
+	}
+	return diff
+}
+
+// replay sends the request in each of -replay's fixtures to o's URL, in order, and compares what comes back with the
+// recorded response, printing whether each matched, and how it didn't, to stderr. it's an error if any didn't.
+func replay(ctx context.Context, env *cli.Env, o options) error {
+	paths, err := filepath.Glob(filepath.Join(o.replay, "*.fixture"))
+	if err != nil {
+		return err
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("-replay: no fixtures in %s", o.replay)
+	}
+	slices.Sort(paths)
+	c := newClient(o)
+	defer c.close()
+	ignore := append(slices.Clone(unreplayed), o.replayIgnore...)
+	failed := 0
+	for _, path := range paths {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		name := filepath.Base(path)
+		req, want, err := readFixture(path)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		var diff []string
+		got, err := c.doAuth(ctx, o.fromFixture(req))
+		if err == nil {
+			diff = diffResponses(want, got, ignore)
+		}
+		if err == nil && len(diff) == 0 {
+			fmt.Fprintf(env.Stderr, "ok    %s  %s %s\n", name, req.Method, req.Path)
+			continue
+		}
+		failed++
+		fmt.Fprintf(env.Stderr, "FAIL  %s  %s %s\n", name, req.Method, req.Path)
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "        %v\n", err)
+		}
+		for _, line := range diff {
+			fmt.Fprintf(env.Stderr, "        %s\n", line)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d fixtures don't match", failed, len(paths))
+	}
+	fmt.Fprintf(env.Stderr, "all %d fixtures match\n", len(paths))
+	return nil
+}
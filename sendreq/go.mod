@@ -1,3 +1,17 @@
 module github.com/ekediala/sendreq
 
 go 1.23.1
+
+require (
+	github.com/ekediala/cli v0.0.0
+	github.com/ekediala/httpwire v0.0.0
+	github.com/ekediala/pool v0.0.0
+	github.com/ekediala/retry v0.0.0
+)
+
+replace (
+	github.com/ekediala/cli => ../cli
+	github.com/ekediala/httpwire => ../httpwire
+	github.com/ekediala/pool => ../pool
+	github.com/ekediala/retry => ../retry
+)
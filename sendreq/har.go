@@ -0,0 +1,268 @@
+package sendreq
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+/* design note: a HAR file (HTTP Archive, http://www.softwareishard.com/blog/har-12-spec/) is what a browser's
+   devtools save from the network tab: a JSON log of every request and response, headers, bodies, and timings. it
+   goes the other way too: drop one on the network tab (or a HAR viewer) and the exchanges show up as if the browser
+   had made them, with the waterfall of where the time went.
+
+   HAR's timings are the phases of one request, in milliseconds, and they add up to its time: blocked (waiting for a
+   connection), dns, connect (which includes ssl, the TLS handshake, and says so separately too), send, wait (for the
+   first byte), and receive. we don't time writing the request, so send is 0 and it counts toward wait; a reused
+   connection had no dns or connect, which HAR writes as -1, "doesn't apply".
+
+   sizes come in two kinds. response.bodySize is the bytes that came over the wire, compressed if they were;
+   content.size is the body decoded, which is what content.text holds. a binary body goes in text as base64, and
+   content.encoding says so.
+*/
+
+// harLog is the top of a HAR file. the names are the spec's.
+type harLog struct {
+	Log struct {
+		Version string     `json:"version"`
+		Creator harCreator `json:"creator"`
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+	path string // where -har writes it
+}
+
+type harCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// harEntry is one request and its response.
+type harEntry struct {
+	StartedDateTime string      `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         harRequest  `json:"request"`
+	Response        harResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         harTimings  `json:"timings"`
+}
+
+type harRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	QueryString []harNameValue `json:"queryString"`
+	PostData    *harPostData   `json:"postData,omitempty"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+type harResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []harNameValue `json:"cookies"`
+	Headers     []harNameValue `json:"headers"`
+	Content     harContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int64          `json:"headersSize"`
+	BodySize    int64          `json:"bodySize"`
+}
+
+type harContent struct {
+	Size        int64  `json:"size"`
+	Compression int64  `json:"compression,omitempty"`
+	MimeType    string `json:"mimeType"`
+	Text        string `json:"text,omitempty"`
+	Encoding    string `json:"encoding,omitempty"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type harTimings struct {
+	Blocked float64 `json:"blocked"`
+	DNS     float64 `json:"dns"`
+	Connect float64 `json:"connect"`
+	SSL     float64 `json:"ssl"`
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+func newHAR(path string) *harLog {
+	h := &harLog{path: path}
+	h.Log.Version = "1.2"
+	h.Log.Creator = harCreator{Name: "sendreq", Version: "1.0"}
+	h.Log.Entries = []harEntry{}
+	return h
+}
+
+// add records o's request and resp, which started at start. wireSize is how many bytes the body was on the wire,
+// before resp.body was decoded.
+func (h *harLog) add(o options, resp *rawResponse, start time.Time, wireSize int) {
+	t := resp.timing
+	timings := harTimings{Blocked: -1, DNS: -1, Connect: -1, SSL: -1}
+	if !t.reused {
+		timings.DNS, timings.Connect = ms(t.dns), ms(t.connect+t.tls)
+		if o.tls {
+			timings.SSL = ms(t.tls)
+		}
+	}
+	timings.Wait = ms(t.ttfb - t.dns - t.connect - t.tls)
+	timings.Receive = ms(t.total - t.ttfb)
+
+	protocol, rest, _ := strings.Cut(resp.head[0], " ")
+	_, reason, _ := strings.Cut(rest, " ")
+	if reason == "" {
+		reason = http.StatusText(resp.status) // HTTP/2 has no reason phrases
+	}
+	r := harResponse{
+		Status:      resp.status,
+		StatusText:  reason,
+		HTTPVersion: protocol,
+		Cookies:     []harNameValue{},
+		Headers:     harHeaders(resp.headers),
+		Content:     harBody(resp.body, resp.headers.Get("Content-Type")),
+		RedirectURL: resp.headers.Get("Location"),
+		HeadersSize: -1,
+		BodySize:    int64(wireSize),
+	}
+	r.Content.Compression = int64(len(resp.body) - wireSize)
+	if protocol != "HTTP/2" {
+		// the status line and header lines, each with its CRLF, and the empty line after them.
+		r.HeadersSize = 2
+		for _, line := range resp.head {
+			r.HeadersSize += int64(len(line) + 2)
+		}
+	}
+	h.Log.Entries = append(h.Log.Entries, harEntry{
+		StartedDateTime: start.Format("2006-01-02T15:04:05.000Z07:00"),
+		Time:            ms(t.total),
+		Request:         o.harRequest(),
+		Response:        r,
+		Timings:         timings,
+	})
+}
+
+// harRequest records o's request as it was sent, as near as it can be told again: a -data-stdin body is gone by now.
+func (o options) harRequest() harRequest {
+	r := harRequest{
+		Method:      o.method,
+		URL:         o.url().String(),
+		HTTPVersion: "HTTP/1.1",
+		Cookies:     []harNameValue{},
+		QueryString: []harNameValue{},
+		HeadersSize: -1,
+		BodySize:    0,
+	}
+	var body io.ReadCloser
+	switch {
+	case o.dataStdin:
+		_, headers := o.requestHeaders()
+		r.Headers, r.BodySize = harHeaders(headers), -1
+	case o.http2:
+		r.HTTPVersion = "HTTP/2"
+		fields, b, _, err := o.http2Request()
+		if err != nil {
+			break
+		}
+		for _, f := range fields {
+			if !strings.HasPrefix(f.name, ":") {
+				r.Headers = append(r.Headers, harNameValue{f.name, f.value})
+			}
+		}
+		body = b
+	default:
+		if o.http10 {
+			r.HTTPVersion = "HTTP/1.0"
+		}
+		head, b, _, err := o.request()
+		if err != nil {
+			break
+		}
+		r.HeadersSize = int64(len(head))
+		lines := strings.Split(strings.TrimSuffix(head, "\r\n\r\n"), "\r\n")
+		for _, line := range lines[1:] {
+			k, v, _ := strings.Cut(line, ": ")
+			r.Headers = append(r.Headers, harNameValue{k, v})
+		}
+		body = b
+	}
+	if r.Headers == nil {
+		r.Headers = []harNameValue{}
+	}
+	if body != nil {
+		b, err := io.ReadAll(body)
+		body.Close()
+		if err == nil {
+			r.BodySize = int64(len(b))
+			r.PostData = &harPostData{Text: string(b)}
+			for _, h := range r.Headers {
+				if strings.EqualFold(h.Name, "Content-Type") {
+					r.PostData.MimeType = h.Value
+				}
+			}
+		}
+	}
+	// the query string, pair by pair in order, decoded.
+	if _, query, ok := strings.Cut(o.path, "?"); ok {
+		for _, pair := range strings.Split(query, "&") {
+			k, v, _ := strings.Cut(pair, "=")
+			if uk, err := url.QueryUnescape(k); err == nil {
+				k = uk
+			}
+			if uv, err := url.QueryUnescape(v); err == nil {
+				v = uv
+			}
+			r.QueryString = append(r.QueryString, harNameValue{k, v})
+		}
+	}
+	return r
+}
+
+func harHeaders(headers []Header) []harNameValue {
+	nv := make([]harNameValue, len(headers))
+	for i, h := range headers {
+		nv[i] = harNameValue{h.Key, h.Value}
+	}
+	return nv
+}
+
+// harBody is body as HAR content: as text if it's valid UTF-8, or else base64.
+func harBody(body []byte, mimeType string) harContent {
+	c := harContent{Size: int64(len(body)), MimeType: mimeType}
+	if utf8.Valid(body) {
+		c.Text = string(body)
+	} else {
+		c.Text, c.Encoding = base64.StdEncoding.EncodeToString(body), "base64"
+	}
+	return c
+}
+
+// save writes the log to its file.
+func (h *harLog) save() error {
+	b, err := json.MarshalIndent(h, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(h.path, append(b, '\n'), 0o644); err != nil {
+		return fmt.Errorf("-har: %w", err)
+	}
+	return nil
+}
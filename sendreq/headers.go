@@ -0,0 +1,86 @@
+package sendreq
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ekediala/httpwire"
+)
+
+// headerFlags collects repeated -H "Key: Value" flags, in order.
+type headerFlags []Header
+
+func (h *headerFlags) String() string {
+	s := make([]string, len(*h))
+	for i, hdr := range *h {
+		s[i] = hdr.Key + ": " + hdr.Value
+	}
+	return strings.Join(s, ", ")
+}
+
+func (h *headerFlags) Set(s string) error {
+	k, v, ok := strings.Cut(s, ":")
+	if !ok {
+		return fmt.Errorf("header %q should be of form 'Key: Value'", s)
+	}
+	k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+	if !isToken(k) {
+		return fmt.Errorf("header name %q: must be non-empty, with no spaces, control characters, or separators like ()<>@,;:\\\"/[]?={}", k)
+	}
+	// a CR or LF in the value would end the header line early and let the rest pass for another header (or a body):
+	// that's header injection, so refuse it here rather than write it to the wire.
+	if strings.ContainsAny(v, "\r\n\x00") {
+		return fmt.Errorf("header %s: value can't contain CR, LF, or NUL", k)
+	}
+	*h = append(*h, Header{Key: httpwire.AsTitle(k), Value: v})
+	return nil
+}
+
+// isToken reports whether s is a token (RFC 9110, section 5.6.2): one or more visible ASCII characters other than
+// the separators. header names and methods are both tokens.
+func isToken(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c <= ' ' || c >= 0x7f || strings.IndexByte(`"(),/:;<=>?@[\]{}`, c) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeHeaders returns defaults with extra merged in, curl-style: extra headers replace the default of the same name
+// in its place, an extra header with an empty value just removes the default, and the rest go at the end in the
+// order given.
+func mergeHeaders(defaults, extra []Header) []Header {
+	var merged []Header
+	for _, d := range defaults {
+		if indexHeader(extra, d.Key) < 0 {
+			merged = append(merged, d)
+			continue
+		}
+		for _, h := range extra {
+			if h.Key == d.Key && h.Value != "" {
+				merged = append(merged, h)
+			}
+		}
+	}
+	for _, h := range extra {
+		if indexHeader(defaults, h.Key) < 0 {
+			merged = append(merged, h)
+		}
+	}
+	return merged
+}
+
+// indexHeader returns the index of the first header named key (in title case), or -1.
+func indexHeader(headers []Header, key string) int {
+	for i, h := range headers {
+		if h.Key == key {
+			return i
+		}
+	}
+	return -1
+}
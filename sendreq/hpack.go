@@ -0,0 +1,285 @@
+package sendreq
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/* design note: HTTP/2 sends headers compressed with HPACK (RFC 7541). HTTP/1.1 sends the same long header lines
+   with every request on a connection; HPACK has both sides keep a table of header fields they've seen, so a repeat
+   can go as a single byte: an index into the table. there are two tables end to end. the static table, below, is 61
+   fields common enough to be fixed by the RFC, like ":method: GET" or "accept-encoding: gzip, deflate". the dynamic
+   table follows it, and holds whatever the encoder asks to add, newest first, up to a size limit; the oldest
+   entries fall off the end to make room.
+
+   each field in a header block is one of:
+
+	1xxxxxxx                  an index: the field is the table's entry there.
+	01xxxxxx <value>          a literal, added to the table: the name is the entry at the index, or a string
+	  (or 01000000 <name> <value>) after it if the index is 0.
+	0000xxxx / 0001xxxx       a literal, not added to the table; 0001 also asks proxies never to index it (say,
+	                          for a cookie).
+	001xxxxx                  not a field: a new size limit for the dynamic table.
+
+   the xs are an integer with an n-bit prefix: a value that doesn't fit in the prefix fills it with ones and goes on
+   in the bytes after, 7 bits at a time, low bits first. a string is a length, as a 7-bit-prefix integer, with the
+   top bit set if the bytes are Huffman-coded, and then the bytes.
+
+   the decoder has to do all of it, since the server picks. the encoder only has to produce something valid, and
+   ours sends every field as a literal that isn't indexed, with its name as a static table index when there is one:
+   one request per connection leaves nothing for a dynamic table to save.
+*/
+
+// hpackField is a header field as HTTP/2 has it: a lowercase name, and a value.
+type hpackField struct{ name, value string }
+
+// hpackStatic is the static table (RFC 7541, appendix A); index i is hpackStatic[i-1].
+var hpackStatic = [...]hpackField{
+	{":authority", ""}, {":method", "GET"}, {":method", "POST"}, {":path", "/"}, {":path", "/index.html"},
+	{":scheme", "http"}, {":scheme", "https"}, {":status", "200"}, {":status", "204"}, {":status", "206"},
+	{":status", "304"}, {":status", "400"}, {":status", "404"}, {":status", "500"}, {"accept-charset", ""},
+	{"accept-encoding", "gzip, deflate"}, {"accept-language", ""}, {"accept-ranges", ""}, {"accept", ""},
+	{"access-control-allow-origin", ""}, {"age", ""}, {"allow", ""}, {"authorization", ""}, {"cache-control", ""},
+	{"content-disposition", ""}, {"content-encoding", ""}, {"content-language", ""}, {"content-length", ""},
+	{"content-location", ""}, {"content-range", ""}, {"content-type", ""}, {"cookie", ""}, {"date", ""},
+	{"etag", ""}, {"expect", ""}, {"expires", ""}, {"from", ""}, {"host", ""}, {"if-match", ""},
+	{"if-modified-since", ""}, {"if-none-match", ""}, {"if-range", ""}, {"if-unmodified-since", ""},
+	{"last-modified", ""}, {"link", ""}, {"location", ""}, {"max-forwards", ""}, {"proxy-authenticate", ""},
+	{"proxy-authorization", ""}, {"range", ""}, {"referer", ""}, {"refresh", ""}, {"retry-after", ""},
+	{"server", ""}, {"set-cookie", ""}, {"strict-transport-security", ""}, {"transfer-encoding", ""},
+	{"user-agent", ""}, {"vary", ""}, {"via", ""}, {"www-authenticate", ""},
+}
+
+// hpackTableSize is the dynamic table size we allow the server's encoder: SETTINGS_HEADER_TABLE_SIZE's default,
+// which we leave alone.
+const hpackTableSize = 4096
+
+// errHPACK is wrapped by every decoding error. HTTP/2 calls it a COMPRESSION_ERROR: the two tables are out of step,
+// and nothing else on the connection can be decoded.
+var errHPACK = errors.New("hpack")
+
+// hpackDecoder decodes the header blocks of one connection, in the order they came.
+type hpackDecoder struct {
+	dynamic []hpackField // newest first
+	size    int          // the sum of the entries' sizes
+	maxSize int          // the limit, as the encoder last set it
+}
+
+func newHPACKDecoder() *hpackDecoder { return &hpackDecoder{maxSize: hpackTableSize} }
+
+// decode decodes a header block.
+func (d *hpackDecoder) decode(block []byte) ([]hpackField, error) {
+	var fields []hpackField
+	for len(block) > 0 {
+		var f hpackField
+		var err error
+		switch b := block[0]; {
+		case b&0x80 != 0:
+			var i uint64
+			if i, block, err = hpackInt(block, 7); err == nil {
+				f, err = d.entry(i)
+			}
+		case b&0xc0 == 0x40:
+			if f, block, err = d.literal(block, 6); err == nil {
+				d.add(f)
+			}
+		case b&0xe0 == 0x20:
+			var n uint64
+			if n, block, err = hpackInt(block, 5); err == nil && n > hpackTableSize {
+				err = fmt.Errorf("%w: table size %d is over the %d allowed", errHPACK, n, hpackTableSize)
+			}
+			if err == nil {
+				d.maxSize = int(n)
+				d.evict()
+			}
+			if err != nil {
+				return nil, err
+			}
+			continue
+		default:
+			f, block, err = d.literal(block, 4)
+		}
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// entry returns the field at index i of the two tables.
+func (d *hpackDecoder) entry(i uint64) (hpackField, error) {
+	switch {
+	case i == 0:
+		return hpackField{}, fmt.Errorf("%w: index 0", errHPACK)
+	case i <= uint64(len(hpackStatic)):
+		return hpackStatic[i-1], nil
+	case i-uint64(len(hpackStatic)) <= uint64(len(d.dynamic)):
+		return d.dynamic[i-uint64(len(hpackStatic))-1], nil
+	}
+	return hpackField{}, fmt.Errorf("%w: index %d is past the end of the table", errHPACK, i)
+}
+
+// literal decodes a literal field whose name index has an n-bit prefix.
+func (d *hpackDecoder) literal(b []byte, n uint) (f hpackField, rest []byte, err error) {
+	i, b, err := hpackInt(b, n)
+	if err != nil {
+		return f, nil, err
+	}
+	if i == 0 {
+		f.name, b, err = hpackString(b)
+	} else {
+		var e hpackField
+		e, err = d.entry(i)
+		f.name = e.name
+	}
+	if err != nil {
+		return f, nil, err
+	}
+	f.value, b, err = hpackString(b)
+	return f, b, err
+}
+
+// add puts f at the front of the dynamic table. an entry's size is its name and value plus 32 bytes, a guess at what
+// keeping it costs.
+func (d *hpackDecoder) add(f hpackField) {
+	d.dynamic = append([]hpackField{f}, d.dynamic...)
+	d.size += len(f.name) + len(f.value) + 32
+	d.evict()
+}
+
+// evict drops the oldest entries until the table fits its limit.
+func (d *hpackDecoder) evict() {
+	for d.size > d.maxSize {
+		last := d.dynamic[len(d.dynamic)-1]
+		d.dynamic = d.dynamic[:len(d.dynamic)-1]
+		d.size -= len(last.name) + len(last.value) + 32
+	}
+}
+
+// hpackInt decodes an integer with an n-bit prefix from the start of b.
+func hpackInt(b []byte, n uint) (v uint64, rest []byte, err error) {
+	if len(b) == 0 {
+		return 0, nil, fmt.Errorf("%w: truncated integer", errHPACK)
+	}
+	max := uint64(1)<<n - 1
+	v, b = uint64(b[0])&max, b[1:]
+	if v < max {
+		return v, b, nil
+	}
+	for shift := uint(0); shift < 63; shift += 7 {
+		if len(b) == 0 {
+			return 0, nil, fmt.Errorf("%w: truncated integer", errHPACK)
+		}
+		c := b[0]
+		b = b[1:]
+		v += uint64(c&0x7f) << shift
+		if c&0x80 == 0 {
+			return v, b, nil
+		}
+	}
+	return 0, nil, fmt.Errorf("%w: integer too large", errHPACK)
+}
+
+// hpackString decodes a string from the start of b.
+func hpackString(b []byte) (s string, rest []byte, err error) {
+	if len(b) == 0 {
+		return "", nil, fmt.Errorf("%w: truncated string", errHPACK)
+	}
+	huffman := b[0]&0x80 != 0
+	n, b, err := hpackInt(b, 7)
+	if err != nil {
+		return "", nil, err
+	}
+	if n > uint64(len(b)) {
+		return "", nil, fmt.Errorf("%w: string of %d bytes with only %d left", errHPACK, n, len(b))
+	}
+	if huffman {
+		s, err = huffmanDecode(b[:n])
+	} else {
+		s = string(b[:n])
+	}
+	return s, b[n:], err
+}
+
+// appendHPACK appends f to a header block as a literal that isn't indexed, naming it by its static table index if
+// it has one. strings go as they are, without Huffman coding.
+func appendHPACK(dst []byte, f hpackField) []byte {
+	i := 0
+	for j, e := range hpackStatic {
+		if e.name == f.name {
+			i = j + 1
+			break
+		}
+	}
+	dst = appendHPACKInt(dst, 0x00, 4, uint64(i))
+	if i == 0 {
+		dst = appendHPACKInt(dst, 0x00, 7, uint64(len(f.name)))
+		dst = append(dst, f.name...)
+	}
+	dst = appendHPACKInt(dst, 0x00, 7, uint64(len(f.value)))
+	return append(dst, f.value...)
+}
+
+// appendHPACKInt appends v as an integer with an n-bit prefix, in a first byte whose other bits are flags.
+func appendHPACKInt(dst []byte, flags byte, n uint, v uint64) []byte {
+	max := uint64(1)<<n - 1
+	if v < max {
+		return append(dst, flags|byte(v))
+	}
+	dst = append(dst, flags|byte(max))
+	for v -= max; v >= 0x80; v >>= 7 {
+		dst = append(dst, byte(v)|0x80)
+	}
+	return append(dst, byte(v))
+}
+
+// huffmanNode is a node of the tree huffmanDecode walks: a bit picks one of next, until a leaf gives a byte.
+type huffmanNode struct {
+	next [2]*huffmanNode
+	sym  byte
+	leaf bool
+}
+
+var huffmanRoot = func() *huffmanNode {
+	root := new(huffmanNode)
+	for sym, code := range huffmanCodes {
+		n := root
+		for i := int(huffmanCodeLens[sym]) - 1; i >= 0; i-- {
+			bit := code >> i & 1
+			if n.next[bit] == nil {
+				n.next[bit] = new(huffmanNode)
+			}
+			n = n.next[bit]
+		}
+		n.sym, n.leaf = byte(sym), true
+	}
+	return root
+}()
+
+// huffmanDecode decodes a Huffman-coded string. the last byte is padded out with the high bits of the end-of-string
+// code, which are all ones; anything else there, or more than 7 bits of it, is an error.
+func huffmanDecode(b []byte) (string, error) {
+	var s strings.Builder
+	n := huffmanRoot
+	pending, ones := 0, true // bits read since the last byte decoded, and whether they were all ones
+	for _, c := range b {
+		for i := 7; i >= 0; i-- {
+			bit := c >> i & 1
+			if n = n.next[bit]; n == nil {
+				return "", fmt.Errorf("%w: invalid Huffman code", errHPACK)
+			}
+			pending++
+			ones = ones && bit == 1
+			if n.leaf {
+				s.WriteByte(n.sym)
+				n, pending, ones = huffmanRoot, 0, true
+			}
+		}
+	}
+	if pending > 7 || !ones {
+		return "", fmt.Errorf("%w: bad Huffman padding", errHPACK)
+	}
+	return s.String(), nil
+}
@@ -0,0 +1,566 @@
+package sendreq
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekediala/httpwire"
+)
+
+/* design note: HTTP/2 (RFC 9113) means the same thing as HTTP/1.1 (a method, a path, headers, a body; a status,
+   headers, a body) but says it in binary frames instead of lines of text. every frame has a 9-byte header:
+
+	+-----------------------------------------------+
+	|                 Length (24)                   |
+	+---------------+---------------+---------------+
+	|   Type (8)    |   Flags (8)   |
+	+-+-------------+---------------+-------------------------------+
+	|R|                 Stream Identifier (31)                      |
+	+=+=============================================================+
+	|                   Frame Payload (Length bytes)              ...
+
+   a request is a "stream": a HEADERS frame with its header block (compressed with HPACK, see hpack.go), and DATA
+   frames with its body, all with the stream's number; the response comes back as frames on the same stream. the
+   request line is gone: the method, the path, and so on go in the header block as pseudo-headers, ":method" and
+   ":path", ":scheme" and ":authority" (the Host), and the status comes back as ":status". an END_STREAM flag on a
+   stream's last frame says that side is done, which is all the framing a body needs: no Content-Length or chunking.
+
+   the stream numbers are what let many requests share one connection at once, their frames interleaved, answered in
+   whatever order they finish; that's the head-of-line blocking pipelining had (see pipeline.go) gone. stream 0 is
+   for the connection itself: SETTINGS to say what each side allows, PING, GOAWAY to say it's closing.
+
+   the rest is flow control. each side says how many bytes of DATA it's ready to take, for the connection and each
+   stream, 65535 to begin with; the sender stops when that runs out, until a WINDOW_UPDATE gives it more. so a fast
+   sender can't bury a slow receiver, and one busy stream can't starve the others.
+
+   sendreq speaks just enough of it for one stream per connection. there are two ways to start: over TLS, the client
+   offers "h2" in the handshake's ALPN extension and the server picks it; in cleartext ("h2c"), the client just
+   starts talking HTTP/2, on "prior knowledge" that the server does. either way, the client's first bytes are the
+   preface, "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n", which an HTTP/1 server will reject as a bad request, and then a
+   SETTINGS frame. try it with -log-level debug to see every frame go by.
+*/
+
+// http2Preface is what an HTTP/2 client says first, to be sure the server speaks HTTP/2 too.
+const http2Preface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// frame types and flags (RFC 9113, section 6).
+const (
+	frameData         = 0x0
+	frameHeaders      = 0x1
+	framePriority     = 0x2
+	frameRSTStream    = 0x3
+	frameSettings     = 0x4
+	framePushPromise  = 0x5
+	framePing         = 0x6
+	frameGoAway       = 0x7
+	frameWindowUpdate = 0x8
+	frameContinuation = 0x9
+
+	flagEndStream  = 0x1
+	flagAck        = 0x1 // on SETTINGS and PING
+	flagEndHeaders = 0x4
+	flagPadded     = 0x8
+	flagPriority   = 0x20
+)
+
+var frameNames = [...]string{"DATA", "HEADERS", "PRIORITY", "RST_STREAM", "SETTINGS", "PUSH_PROMISE", "PING", "GOAWAY", "WINDOW_UPDATE", "CONTINUATION"}
+
+func frameName(typ byte) string {
+	if int(typ) < len(frameNames) {
+		return frameNames[typ]
+	}
+	return fmt.Sprintf("UNKNOWN(%#x)", typ)
+}
+
+// settings identifiers.
+const (
+	settingHeaderTableSize   = 0x1
+	settingEnablePush        = 0x2
+	settingInitialWindowSize = 0x4
+	settingMaxFrameSize      = 0x5
+)
+
+// http2Errors are the error codes of RST_STREAM and GOAWAY (RFC 9113, section 7).
+var http2Errors = [...]string{"NO_ERROR", "PROTOCOL_ERROR", "INTERNAL_ERROR", "FLOW_CONTROL_ERROR", "SETTINGS_TIMEOUT",
+	"STREAM_CLOSED", "FRAME_SIZE_ERROR", "REFUSED_STREAM", "CANCEL", "COMPRESSION_ERROR", "CONNECT_ERROR",
+	"ENHANCE_YOUR_CALM", "INADEQUATE_SECURITY", "HTTP_1_1_REQUIRED"}
+
+func http2Error(code uint32) string {
+	if int(code) < len(http2Errors) {
+		return http2Errors[code]
+	}
+	return fmt.Sprintf("error %#x", code)
+}
+
+const (
+	http2Stream        = 1     // the one stream we open: client streams are odd, starting at 1
+	http2MaxFrame      = 16384 // the largest frame either side may send until the other says otherwise
+	http2InitialWindow = 65535
+)
+
+// frame is one HTTP/2 frame.
+type frame struct {
+	typ, flags byte
+	stream     uint32
+	payload    []byte
+}
+
+// h2Conn is an HTTP/2 connection carrying a single request.
+type h2Conn struct {
+	ctx   context.Context
+	r     *bufio.Reader
+	w     *bufio.Writer
+	dec   *hpackDecoder
+	trace *tracer // for -v, which traces the frames rather than their bytes
+
+	maxFrame      uint32 // the largest DATA payload the server will take
+	initialWindow int64  // the server's initial stream window, to adjust ours by when it changes
+	connWindow    int64  // how much more DATA the server will take on the connection
+	streamWindow  int64  // and on our stream
+
+	block      []byte // a header block, until the CONTINUATION frame that ends it
+	blockEnds  bool   // the HEADERS frame that started it had END_STREAM
+	resp       *rawResponse
+	firstFrame time.Time // when the response's first HEADERS frame arrived
+	done       bool      // the server ended its side of the stream
+}
+
+// doHTTP2 sends o's request over HTTP/2 on a connection of its own, and reads the response.
+func (c *client) doHTTP2(ctx context.Context, o options) (*rawResponse, error) {
+	start := time.Now()
+	conn, t, err := dial(ctx, o)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	slog.InfoContext(ctx, "main", "message", fmt.Sprintf("connected to %s (@ %s)", o.host, conn.RemoteAddr()), "took", (t.dns + t.connect + t.tls).String())
+	// closing the connection on ^C ends any read or write below.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	if tc, ok := conn.(*traceConn); ok {
+		tc.frames = true
+	}
+	var r io.Reader = conn
+	if o.readTimeout > 0 {
+		r = timeoutReader{conn, o.readTimeout}
+	}
+	h := &h2Conn{
+		ctx:           ctx,
+		r:             bufio.NewReader(r),
+		w:             bufio.NewWriter(conn),
+		dec:           newHPACKDecoder(),
+		trace:         o.trace,
+		maxFrame:      http2MaxFrame,
+		initialWindow: http2InitialWindow,
+		connWindow:    http2InitialWindow,
+		streamWindow:  http2InitialWindow,
+	}
+	resp, err := h.roundTrip(o)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() && ctx.Err() == nil {
+		return nil, fmt.Errorf("no data from the server for %s (-read-timeout): %w", o.readTimeout, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// GOAWAY says we're done with the connection, and that the last stream we handled was 0: none of the server's.
+	h.writeFrame(frameGoAway, 0, 0, make([]byte, 8))
+	h.w.Flush()
+	resp.timing = timing{dialTiming: t, ttfb: h.firstFrame.Sub(start), total: time.Since(start)}
+	resp.tls = tlsState(conn)
+	return resp, nil
+}
+
+// roundTrip sends the preface and o's request, and reads frames until the response is complete.
+func (h *h2Conn) roundTrip(o options) (*rawResponse, error) {
+	fields, body, size, err := o.http2Request()
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		defer body.Close()
+	}
+
+	h.w.WriteString(http2Preface)
+	if h.trace != nil {
+		h.trace.lines("> ", new([]byte), []byte(http2Preface), true)
+	}
+	// we don't take pushed responses; otherwise the defaults will do.
+	h.writeFrame(frameSettings, 0, 0, []byte{0, settingEnablePush, 0, 0, 0, 0})
+	h.writeHeaders(fields, body == nil)
+	if err := h.w.Flush(); err != nil {
+		return nil, fmt.Errorf("%w: %w", errNoResponse, err)
+	}
+	slog.InfoContext(h.ctx, "main", "info", fmt.Sprintf("sent request:\n%s", formatFields(fields)))
+	if body != nil {
+		n, err := h.writeBody(body, o.trailers)
+		if err != nil {
+			return nil, err
+		}
+		slog.DebugContext(h.ctx, "main", "message", "sent request body", "bytes", n, "size", size)
+	}
+	for !h.done {
+		if err := h.next(); err != nil {
+			return nil, err
+		}
+	}
+	return h.resp, nil
+}
+
+// http2Request returns o's request as HTTP/2 header fields, pseudo-headers first, and opens its body, if it has one;
+// otherwise body is nil. size is the body's length, or -1 if it isn't known.
+func (o options) http2Request() (fields []hpackField, body io.ReadCloser, size int64, err error) {
+	target, headers := o.requestHeaders()
+	if o.signer != nil {
+		if headers, err = o.signer.sign(o, headers); err != nil {
+			return nil, nil, 0, err
+		}
+	}
+	scheme := "http"
+	if o.tls {
+		scheme = "https"
+	}
+	// the Host header becomes :authority, whatever -H made it.
+	fields = []hpackField{{":method", o.method}, {":scheme", scheme}, {":authority", headers.Get("Host")}, {":path", target}}
+	for _, h := range headers {
+		name := strings.ToLower(h.Key)
+		switch name {
+		case "host", "connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade":
+			// these are about an HTTP/1.1 connection; HTTP/2 forbids them.
+			continue
+		case "te":
+			if !strings.EqualFold(h.Value, "trailers") {
+				continue
+			}
+		}
+		fields = append(fields, hpackField{name, h.Value})
+	}
+	if o.hasBody {
+		if body, size, err = o.openBody(); err != nil {
+			return nil, nil, 0, fmt.Errorf("opening request body: %w", err)
+		}
+		if size >= 0 {
+			fields = append(fields, hpackField{"content-length", strconv.FormatInt(size, 10)})
+		}
+	}
+	return fields, body, size, nil
+}
+
+// formatFields prints header fields one per line, like "name: value", for the log.
+func formatFields(fields []hpackField) string {
+	var b strings.Builder
+	for _, f := range fields {
+		fmt.Fprintf(&b, "%s: %s\n", f.name, f.value)
+	}
+	return b.String()
+}
+
+// writeHeaders sends the request's header block: a HEADERS frame, and CONTINUATION frames for what doesn't fit in
+// one. endStream says there's no body to follow.
+func (h *h2Conn) writeHeaders(fields []hpackField, endStream bool) {
+	var block []byte
+	for _, f := range fields {
+		block = appendHPACK(block, f)
+	}
+	typ, flags := byte(frameHeaders), byte(0)
+	if endStream {
+		flags = flagEndStream
+	}
+	for {
+		chunk := block[:min(len(block), int(h.maxFrame))]
+		block = block[len(chunk):]
+		if len(block) == 0 {
+			flags |= flagEndHeaders
+		}
+		h.writeFrame(typ, flags, http2Stream, chunk)
+		if len(block) == 0 {
+			if h.trace != nil {
+				h.trace.fields("> ", fields)
+			}
+			return
+		}
+		typ, flags = frameContinuation, 0
+	}
+}
+
+// writeBody sends body as DATA frames, as fast as the server's flow control windows allow, and then the trailers, if
+// there are any, as a second HEADERS frame; END_STREAM goes on whichever is last. if the server answers in full
+// before it's all sent, the rest isn't.
+func (h *h2Conn) writeBody(body io.Reader, trailers []Header) (int64, error) {
+	buf := make([]byte, http2MaxFrame)
+	var sent int64
+	for {
+		// wait for room in both windows, handling what the server sends meanwhile.
+		for (h.connWindow <= 0 || h.streamWindow <= 0) && !h.done {
+			slog.DebugContext(h.ctx, "main", "message", "flow control window used up; waiting for WINDOW_UPDATE")
+			if err := h.next(); err != nil {
+				return sent, err
+			}
+		}
+		if h.done {
+			slog.InfoContext(h.ctx, "main", "message", "the server answered before the body was all sent; not sending the rest", "sent", sent)
+			return sent, nil
+		}
+		n, err := io.ReadFull(body, buf[:min(int64(len(buf)), int64(h.maxFrame), h.connWindow, h.streamWindow)])
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			if len(trailers) == 0 {
+				h.writeFrame(frameData, flagEndStream, http2Stream, buf[:n])
+				return sent + int64(n), h.flush()
+			}
+			h.writeFrame(frameData, 0, http2Stream, buf[:n])
+			fields := make([]hpackField, len(trailers))
+			for i, t := range trailers {
+				fields[i] = hpackField{strings.ToLower(t.Key), t.Value}
+			}
+			h.writeHeaders(fields, true)
+			return sent + int64(n), h.flush()
+		}
+		if err != nil {
+			return sent, fmt.Errorf("reading request body: %w", err)
+		}
+		h.writeFrame(frameData, 0, http2Stream, buf[:n])
+		if err := h.flush(); err != nil {
+			return sent, err
+		}
+		sent += int64(n)
+		h.connWindow -= int64(n)
+		h.streamWindow -= int64(n)
+	}
+}
+
+func (h *h2Conn) flush() error {
+	if err := h.w.Flush(); err != nil {
+		return fmt.Errorf("sending request body: %w", err)
+	}
+	return nil
+}
+
+// writeFrame buffers one frame.
+func (h *h2Conn) writeFrame(typ, flags byte, stream uint32, payload []byte) {
+	var head [9]byte
+	head[0], head[1], head[2] = byte(len(payload)>>16), byte(len(payload)>>8), byte(len(payload))
+	head[3], head[4] = typ, flags
+	binary.BigEndian.PutUint32(head[5:], stream)
+	h.w.Write(head[:])
+	h.w.Write(payload)
+	slog.DebugContext(h.ctx, "main", "message", "sent frame", "type", frameName(typ), "flags", fmt.Sprintf("%#x", flags), "stream", stream, "length", len(payload))
+	if h.trace != nil {
+		h.trace.frame("> ", frame{typ, flags, stream, payload})
+	}
+}
+
+// readFrame reads the next frame.
+func (h *h2Conn) readFrame() (frame, error) {
+	var head [9]byte
+	got, err := io.ReadFull(h.r, head[:])
+	if got >= 5 && string(head[:5]) == "HTTP/" {
+		// an HTTP/1 server's "400 Bad Request" for the preface.
+		return frame{}, errors.New("the server answered in HTTP/1: it doesn't speak HTTP/2 here")
+	}
+	if errors.Is(err, io.EOF) {
+		return frame{}, errNoResponse
+	}
+	if err != nil {
+		return frame{}, err
+	}
+	n := uint32(head[0])<<16 | uint32(head[1])<<8 | uint32(head[2])
+	if n > http2MaxFrame {
+		return frame{}, fmt.Errorf("the server sent a %d-byte frame; the most we allow is %d", n, http2MaxFrame)
+	}
+	f := frame{typ: head[3], flags: head[4], stream: binary.BigEndian.Uint32(head[5:]) & 0x7fffffff, payload: make([]byte, n)}
+	if _, err := io.ReadFull(h.r, f.payload); err != nil {
+		return frame{}, fmt.Errorf("reading %s frame: %w", frameName(f.typ), err)
+	}
+	slog.DebugContext(h.ctx, "main", "message", "received frame", "type", frameName(f.typ), "flags", fmt.Sprintf("%#x", f.flags), "stream", f.stream, "length", n)
+	if h.trace != nil {
+		h.trace.frame("< ", f)
+	}
+	return f, nil
+}
+
+// next reads the next frame and handles it.
+func (h *h2Conn) next() error {
+	f, err := h.readFrame()
+	if err != nil {
+		return err
+	}
+	if h.block != nil && f.typ != frameContinuation {
+		return fmt.Errorf("the server sent %s in the middle of a header block", frameName(f.typ))
+	}
+	switch f.typ {
+	case frameData:
+		return h.data(f)
+	case frameHeaders:
+		p, err := unpad(f)
+		if err != nil {
+			return err
+		}
+		if f.flags&flagPriority != 0 {
+			if len(p) < 5 {
+				return errors.New("HEADERS frame too short for its priority")
+			}
+			p = p[5:]
+		}
+		h.block, h.blockEnds = append([]byte{}, p...), f.flags&flagEndStream != 0
+		if f.flags&flagEndHeaders != 0 {
+			return h.headers()
+		}
+	case frameContinuation:
+		if h.block == nil {
+			return errors.New("the server sent CONTINUATION without HEADERS")
+		}
+		h.block = append(h.block, f.payload...)
+		if f.flags&flagEndHeaders != 0 {
+			return h.headers()
+		}
+	case frameSettings:
+		if f.flags&flagAck != 0 {
+			return nil
+		}
+		if len(f.payload)%6 != 0 {
+			return errors.New("malformed SETTINGS frame")
+		}
+		for p := f.payload; len(p) > 0; p = p[6:] {
+			id, v := binary.BigEndian.Uint16(p), binary.BigEndian.Uint32(p[2:])
+			slog.DebugContext(h.ctx, "main", "message", "server setting", "id", id, "value", v)
+			switch id {
+			case settingInitialWindowSize:
+				// the change applies to streams already open, like ours.
+				h.streamWindow += int64(v) - h.initialWindow
+				h.initialWindow = int64(v)
+			case settingMaxFrameSize:
+				h.maxFrame = v
+			}
+		}
+		h.writeFrame(frameSettings, flagAck, 0, nil)
+		return h.w.Flush()
+	case framePing:
+		if f.flags&flagAck == 0 {
+			h.writeFrame(framePing, flagAck, 0, f.payload)
+			return h.w.Flush()
+		}
+	case frameWindowUpdate:
+		if len(f.payload) != 4 {
+			return errors.New("malformed WINDOW_UPDATE frame")
+		}
+		inc := int64(binary.BigEndian.Uint32(f.payload) & 0x7fffffff)
+		if f.stream == 0 {
+			h.connWindow += inc
+		} else {
+			h.streamWindow += inc
+		}
+	case frameRSTStream:
+		if len(f.payload) != 4 {
+			return errors.New("malformed RST_STREAM frame")
+		}
+		code := binary.BigEndian.Uint32(f.payload)
+		if h.done && code == 0 {
+			return nil // the server has its answer, and doesn't need the rest of the body.
+		}
+		return fmt.Errorf("the server reset the stream: %s", http2Error(code))
+	case frameGoAway:
+		if len(f.payload) < 8 {
+			return errors.New("malformed GOAWAY frame")
+		}
+		last, code := binary.BigEndian.Uint32(f.payload)&0x7fffffff, binary.BigEndian.Uint32(f.payload[4:])
+		if code != 0 || last < http2Stream {
+			return fmt.Errorf("the server closed the connection: %s %s", http2Error(code), f.payload[8:])
+		}
+		slog.DebugContext(h.ctx, "main", "message", "the server is closing the connection after our stream")
+	case framePushPromise:
+		return errors.New("the server pushed a response, which we said not to")
+	}
+	// PRIORITY and unknown frames are ignored.
+	return nil
+}
+
+// data handles a DATA frame: the body, a piece at a time. every byte of it, padding too, used up some of our flow
+// control windows, so WINDOW_UPDATE frames give it back straight away.
+func (h *h2Conn) data(f frame) error {
+	if f.stream != http2Stream || h.resp == nil {
+		return errors.New("the server sent DATA before the response headers")
+	}
+	p, err := unpad(f)
+	if err != nil {
+		return err
+	}
+	h.resp.body = append(h.resp.body, p...)
+	h.done = f.flags&flagEndStream != 0
+	if len(f.payload) == 0 {
+		return nil
+	}
+	inc := binary.BigEndian.AppendUint32(nil, uint32(len(f.payload)))
+	h.writeFrame(frameWindowUpdate, 0, 0, inc)
+	if !h.done {
+		h.writeFrame(frameWindowUpdate, 0, http2Stream, inc)
+	}
+	return h.w.Flush()
+}
+
+// headers decodes a complete header block: the response's, an interim response's, or trailers.
+func (h *h2Conn) headers() error {
+	block, endStream := h.block, h.blockEnds
+	h.block = nil
+	// the block has to be decoded whatever it is, to keep the table in step with the server's.
+	fields, err := h.dec.decode(block)
+	if err != nil {
+		return err
+	}
+	if h.trace != nil {
+		h.trace.fields("< ", fields)
+	}
+	if h.resp != nil {
+		// a header block after the response's is its trailers, and ends the stream.
+		if !endStream {
+			return errors.New("the server sent a second header block without ending the stream")
+		}
+		for _, f := range fields {
+			h.resp.trailers = append(h.resp.trailers, Header{Key: httpwire.AsTitle(f.name), Value: f.value})
+		}
+		h.done = true
+		return nil
+	}
+	if len(fields) == 0 || fields[0].name != ":status" {
+		return errors.New("the server's response has no :status")
+	}
+	status, err := strconv.Atoi(fields[0].value)
+	if err != nil || len(fields[0].value) != 3 {
+		return fmt.Errorf("malformed :status %q", fields[0].value)
+	}
+	if h.firstFrame.IsZero() {
+		h.firstFrame = time.Now()
+	}
+	if status < 200 {
+		slog.DebugContext(h.ctx, "main", "message", "skipping interim response", "status", status)
+		return nil
+	}
+	// there's no status line or reason phrase in HTTP/2; this is how curl shows one.
+	resp := &rawResponse{head: []string{"HTTP/2 " + fields[0].value}, status: status}
+	for _, f := range fields[1:] {
+		resp.head = append(resp.head, f.name+": "+f.value)
+		resp.headers = append(resp.headers, Header{Key: httpwire.AsTitle(f.name), Value: f.value})
+	}
+	h.resp, h.done = resp, endStream
+	return nil
+}
+
+// unpad returns the payload of a DATA or HEADERS frame without its padding, if it has any.
+func unpad(f frame) ([]byte, error) {
+	if f.flags&flagPadded == 0 {
+		return f.payload, nil
+	}
+	if len(f.payload) == 0 || int(f.payload[0]) >= len(f.payload) {
+		return nil, fmt.Errorf("malformed padding in %s frame", frameName(f.typ))
+	}
+	return f.payload[1 : len(f.payload)-int(f.payload[0])], nil
+}
@@ -0,0 +1,168 @@
+package sendreq
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/* design note: most of the time, what a script wants from an API is one value: an ID to pass to the next request,
+   a status to check. -jsonpath picks it out of the response body, so the script doesn't need jq as well:
+
+	sendreq -jsonpath .data.items[0].id https://api.example.com/items
+
+   the path is a small subset of jq's: .name for an object's field, [N] for an array's Nth element (from 0), ["name"]
+   for a field whose name has dots or brackets in it, and . alone for the whole body. a string prints as it is,
+   without quotes, like jq -r, so it can go straight into a shell variable; anything else prints as JSON. numbers are
+   printed exactly as the server wrote them, not rounded through a float64. a path that isn't there is an error,
+   which says how far it got, so a typo doesn't quietly print nothing.
+*/
+
+// jsonPath is a parsed -jsonpath: the steps from the top of a JSON value down to the one to print.
+type jsonPath []jsonStep
+
+// jsonStep is one step: an object's field, or an array's element.
+type jsonStep struct {
+	field string
+	index int // when field is ""
+}
+
+// parseJSONPath parses a path like .data.items[0].id.
+func parseJSONPath(s string) (jsonPath, error) {
+	if !strings.HasPrefix(s, ".") && !strings.HasPrefix(s, "[") {
+		return nil, errors.New("a path starts with . or [, like .data.items[0].id")
+	}
+	p := jsonPath{}
+	if s == "." {
+		return p, nil
+	}
+	for rest := s; rest != ""; {
+		switch {
+		case strings.HasPrefix(rest, "[\""):
+			end := strings.Index(rest, "\"]")
+			if end < 0 {
+				return nil, fmt.Errorf("%q: unterminated [\"name\"]", s)
+			}
+			field, err := strconv.Unquote(rest[1 : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("%q: bad field name %s", s, rest[1:end+1])
+			}
+			p, rest = append(p, jsonStep{field: field}), rest[end+2:]
+		case strings.HasPrefix(rest, "["):
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("%q: unterminated [", s)
+			}
+			n, err := strconv.Atoi(rest[1:end])
+			if err != nil || n < 0 {
+				return nil, fmt.Errorf("%q: %q isn't an array index", s, rest[1:end])
+			}
+			p, rest = append(p, jsonStep{index: n}), rest[end+1:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			if end == 0 {
+				return nil, fmt.Errorf("%q: a . needs a field name after it", s)
+			}
+			p, rest = append(p, jsonStep{field: rest[:end]}), rest[end:]
+		default:
+			return nil, fmt.Errorf("%q: want . or [ before %q", s, rest)
+		}
+	}
+	return p, nil
+}
+
+func (p jsonPath) String() string {
+	if len(p) == 0 {
+		return "."
+	}
+	var b strings.Builder
+	for _, step := range p {
+		switch {
+		case step.field == "":
+			fmt.Fprintf(&b, "[%d]", step.index)
+		case strings.ContainsAny(step.field, ".[]\" "):
+			fmt.Fprintf(&b, "[%q]", step.field)
+		default:
+			b.WriteString("." + step.field)
+		}
+	}
+	return b.String()
+}
+
+// find follows p down from v, a value decoded with UseNumber, and returns what it comes to.
+func (p jsonPath) find(v any) (any, error) {
+	for i, step := range p {
+		at := p[:i].String()
+		switch x := v.(type) {
+		case map[string]any:
+			if step.field == "" {
+				return nil, fmt.Errorf("%s is an object, not an array, so it has no [%d]", at, step.index)
+			}
+			var ok bool
+			if v, ok = x[step.field]; !ok {
+				return nil, fmt.Errorf("%s has no field %q", at, step.field)
+			}
+		case []any:
+			if step.field != "" {
+				return nil, fmt.Errorf("%s is an array, not an object, so it has no field %q", at, step.field)
+			}
+			switch {
+			case len(x) == 1 && step.index > 0:
+				return nil, fmt.Errorf("%s has 1 element, so it has no [%d]", at, step.index)
+			case step.index >= len(x):
+				return nil, fmt.Errorf("%s has %d elements, so it has no [%d]", at, len(x), step.index)
+			}
+			v = x[step.index]
+		default:
+			return nil, fmt.Errorf("%s is %s, so there's nothing under it", at, jsonKind(v))
+		}
+	}
+	return v, nil
+}
+
+// jsonKind describes the kind of v, a decoded JSON value, for an error.
+func jsonKind(v any) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "a string"
+	case json.Number:
+		return "a number"
+	case bool:
+		return "a boolean"
+	}
+	return "a value"
+}
+
+// write parses body as JSON, and prints the value p selects from it: a string as it is, anything else as JSON.
+func (p jsonPath) write(w io.Writer, body []byte) error {
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+	var v any
+	if err := d.Decode(&v); err != nil {
+		return fmt.Errorf("-jsonpath: the response body isn't JSON: %w", err)
+	}
+	v, err := p.find(v)
+	if err != nil {
+		return fmt.Errorf("-jsonpath %s: %w", p, err)
+	}
+	if s, ok := v.(string); ok {
+		_, err := fmt.Fprintln(w, s)
+		return err
+	}
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,176 @@
+package sendreq
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ekediala/httpwire"
+)
+
+/* design note: a multipart/form-data body (RFC 7578) is what a browser sends for a form with a file input. it's
+   a list of parts, each with its own small header block, separated by a boundary line the body promises not to
+   contain anywhere else:
+
+	--<boundary>
+	Content-Disposition: form-data; name="comment"
+
+	looks good
+	--<boundary>
+	Content-Disposition: form-data; name="file"; filename="report.pdf"
+	Content-Type: application/pdf
+
+	<the file's bytes>
+	--<boundary>--
+
+   nothing is length-prefixed, so the boundary has to be unlikely to turn up in a file: a long random one is. and
+   since every byte of it is known up front, so is the body's length, as long as the files are regular files; the
+   files themselves are streamed from disk as the body is sent, never held in memory.
+*/
+
+// formPart is one -F field: name=value, name=@path to upload a file, or name=<path to send a file's contents as a
+// plain value. uploads can end with ;type=<content type> and ;filename=<name to send>.
+type formPart struct {
+	name        string
+	value       string // for name=value
+	path        string // for @ and <
+	upload      bool   // @: send it as a file, with a filename
+	filename    string
+	contentType string
+}
+
+// formFlags collects repeated -F flags, in order.
+type formFlags []formPart
+
+func (f *formFlags) String() string {
+	s := make([]string, len(*f))
+	for i, p := range *f {
+		switch {
+		case p.upload:
+			s[i] = p.name + "=@" + p.path
+		case p.path != "":
+			s[i] = p.name + "=<" + p.path
+		default:
+			s[i] = p.name + "=" + p.value
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+func (f *formFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("%q should be of form name=value, name=@file, or name=<file", s)
+	}
+	p := formPart{name: name}
+	switch {
+	case strings.HasPrefix(value, "@"):
+		p.upload = true
+		fields := strings.Split(value[1:], ";")
+		p.path = fields[0]
+		p.filename = filepath.Base(p.path)
+		for _, opt := range fields[1:] {
+			k, v, _ := strings.Cut(opt, "=")
+			switch k {
+			case "type":
+				p.contentType = v
+			case "filename":
+				p.filename = v
+			default:
+				return fmt.Errorf("unknown option %q in %q (want type= or filename=)", opt, s)
+			}
+		}
+		if p.path == "" {
+			return fmt.Errorf("missing file name after @ in %q", s)
+		}
+	case strings.HasPrefix(value, "<"):
+		if p.path = value[1:]; p.path == "" {
+			return fmt.Errorf("missing file name after < in %q", s)
+		}
+	default:
+		p.value = value
+	}
+	if strings.ContainsAny(p.name+p.filename+p.contentType, "\r\n") {
+		return fmt.Errorf("%q: names and types can't contain line breaks", s)
+	}
+	*f = append(*f, p)
+	return nil
+}
+
+// newBoundary returns a random multipart boundary.
+func newBoundary() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return "sendreq-" + hex.EncodeToString(b[:])
+}
+
+// quoteEscaper escapes a quoted-string in a part header, as Go's mime/multipart does.
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// open opens the multipart body for the parts, with the given boundary. the files are opened now, so a missing one
+// fails before anything is sent, and read as the body is. size is -1 if any of them isn't a regular file.
+func (f formFlags) open(boundary string) (body io.ReadCloser, size int64, err error) {
+	var readers []io.Reader
+	var files []*os.File
+	closeAll := func() error {
+		var err error
+		for _, f := range files {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}
+		return err
+	}
+	add := func(s string) {
+		readers = append(readers, strings.NewReader(s))
+		if size >= 0 {
+			size += int64(len(s))
+		}
+	}
+
+	for _, p := range f {
+		head := fmt.Sprintf("--%s\r\nContent-Disposition: form-data; name=\"%s\"", boundary, quoteEscaper.Replace(p.name))
+		if p.upload {
+			ct := p.contentType
+			if ct == "" {
+				if ct = httpwire.TypeByExtension(filepath.Ext(p.path)); ct == "" {
+					ct = "application/octet-stream"
+				}
+			}
+			head += fmt.Sprintf("; filename=\"%s\"\r\nContent-Type: %s", quoteEscaper.Replace(p.filename), ct)
+		}
+		add(head + "\r\n\r\n")
+
+		if p.path == "" {
+			add(p.value)
+		} else {
+			file, err := os.Open(p.path)
+			if err != nil {
+				closeAll()
+				return nil, 0, fmt.Errorf("-F %s: %w", p.name, err)
+			}
+			files = append(files, file)
+			readers = append(readers, file)
+			if n := fileSize(file); n < 0 {
+				size = -1
+			} else if size >= 0 {
+				size += n
+			}
+		}
+		add("\r\n")
+	}
+	add("--" + boundary + "--\r\n")
+	return readCloser{io.MultiReader(readers...), closeAll}, size, nil
+}
+
+// readCloser is a reader with a separate function to close whatever is behind it.
+type readCloser struct {
+	io.Reader
+	close func() error
+}
+
+func (r readCloser) Close() error { return r.close() }
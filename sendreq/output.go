@@ -0,0 +1,172 @@
+package sendreq
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// outputModes are the choices for -o.
+var outputModes = []string{"all", "headers", "body", "raw", "status"}
+
+// writeResponse prints resp the way -o (or -json, or -jsonpath) asks. last is false for the responses to redirects
+// that -L follows: for those only the modes that show the chain (all and headers, which print the status line and
+// headers of every hop, and raw) print anything, so body, status, -json, and -jsonpath print just the final answer.
+func writeResponse(w io.Writer, resp *rawResponse, o options, last bool) error {
+	if resp.streamed {
+		return nil // it's been printed already.
+	}
+	if o.json {
+		if !last {
+			return nil
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(newJSONResponse(o, resp))
+	}
+	if o.jsonPath != nil {
+		if !last {
+			return nil
+		}
+		return o.jsonPath.write(w, resp.body)
+	}
+	switch o.output {
+	case "headers":
+		writeHead(w, resp)
+		if last {
+			writeTrailers(w, resp)
+		}
+	case "body":
+		if last {
+			w.Write(resp.body)
+		}
+	case "raw":
+		w.Write(resp.raw)
+	case "status":
+		if last {
+			fmt.Fprintln(w, resp.status)
+		}
+	default:
+		// the status line and headers as the server sent them, then the body with any chunked framing taken off.
+		writeHead(w, resp)
+		if last {
+			w.Write(resp.body)
+			if len(resp.trailers) > 0 && len(resp.body) > 0 && resp.body[len(resp.body)-1] != '\n' {
+				fmt.Fprintln(w)
+			}
+			writeTrailers(w, resp)
+		}
+	}
+	return nil
+}
+
+// writeHead prints the status line and the header lines, one per line, and the empty line that ends them.
+func writeHead(w io.Writer, resp *rawResponse) {
+	for _, line := range resp.head {
+		fmt.Fprintf(w, "%s\n", line)
+	}
+	fmt.Fprintln(w)
+}
+
+// writeTrailers prints the trailer fields, if there are any, one per line like the headers, and an empty line after
+// them: on the wire they come after the body, so that's where they go here too.
+func writeTrailers(w io.Writer, resp *rawResponse) {
+	if len(resp.trailers) == 0 {
+		return
+	}
+	for _, h := range resp.trailers {
+		fmt.Fprintf(w, "%s: %s\n", h.Key, h.Value)
+	}
+	fmt.Fprintln(w)
+}
+
+// jsonResponse is what -json prints for each response.
+type jsonResponse struct {
+	URL      string              `json:"url"`
+	Protocol string              `json:"protocol"`
+	Status   int                 `json:"status"`
+	Reason   string              `json:"reason"`
+	Headers  map[string][]string `json:"headers"`            // by title-case name; a header sent more than once has several values
+	Trailers map[string][]string `json:"trailers,omitempty"` // the same, for fields sent after the body
+	// Body is the decoded body as text, if it's valid UTF-8. otherwise it's empty and BodyBase64 has the bytes.
+	Body       string     `json:"body"`
+	BodyBase64 string     `json:"body_base64,omitempty"`
+	Timing     jsonTiming `json:"timing"`
+	TLS        *jsonTLS   `json:"tls,omitempty"` // the connection's, if it was TLS
+}
+
+// jsonTiming has durations in milliseconds, which are easier on jq than Go's "1.5ms" strings. the connection phases
+// are zero when Reused is set.
+type jsonTiming struct {
+	Reused    bool    `json:"reused"`
+	DNSMS     float64 `json:"dns_ms"`
+	ConnectMS float64 `json:"connect_ms"`
+	TLSMS     float64 `json:"tls_ms"`
+	TTFBMS    float64 `json:"ttfb_ms"`
+	TotalMS   float64 `json:"total_ms"`
+}
+
+func newJSONResponse(o options, resp *rawResponse) jsonResponse {
+	// the status line is already known to be "<protocol> <code>[ <reason>]".
+	protocol, rest, _ := strings.Cut(resp.head[0], " ")
+	_, reason, _ := strings.Cut(rest, " ")
+	j := jsonResponse{
+		URL:      o.url().String(),
+		Protocol: protocol,
+		Status:   resp.status,
+		Reason:   reason,
+		Headers:  make(map[string][]string, len(resp.headers)),
+		Timing: jsonTiming{
+			Reused:    resp.timing.reused,
+			DNSMS:     ms(resp.timing.dns),
+			ConnectMS: ms(resp.timing.connect),
+			TLSMS:     ms(resp.timing.tls),
+			TTFBMS:    ms(resp.timing.ttfb),
+			TotalMS:   ms(resp.timing.total),
+		},
+	}
+	for _, h := range resp.headers {
+		j.Headers[h.Key] = append(j.Headers[h.Key], h.Value)
+	}
+	for _, h := range resp.trailers {
+		if j.Trailers == nil {
+			j.Trailers = map[string][]string{}
+		}
+		j.Trailers[h.Key] = append(j.Trailers[h.Key], h.Value)
+	}
+	if resp.tls != nil {
+		j.TLS = newJSONTLS(resp.tls)
+	}
+	if utf8.Valid(resp.body) {
+		j.Body = string(resp.body)
+	} else {
+		j.BodyBase64 = base64.StdEncoding.EncodeToString(resp.body)
+	}
+	return j
+}
+
+// writeTiming prints -timing's table for one request. the connection phases run one after another, and ttfb and
+// total both count from the start, so they include them, like curl's -w times.
+func writeTiming(w io.Writer, o options, t timing) {
+	fmt.Fprintf(w, "%s %s\n", o.method, o.url())
+	if t.reused {
+		fmt.Fprintf(w, "  %-8s (reused connection)\n", "connect")
+	} else {
+		fmt.Fprintf(w, "  %-8s %10s\n", "dns", round(t.dns))
+		fmt.Fprintf(w, "  %-8s %10s\n", "connect", round(t.connect))
+		if o.tls {
+			fmt.Fprintf(w, "  %-8s %10s\n", "tls", round(t.tls))
+		}
+	}
+	fmt.Fprintf(w, "  %-8s %10s\n", "ttfb", round(t.ttfb))
+	fmt.Fprintf(w, "  %-8s %10s\n", "total", round(t.total))
+}
+
+// round drops the nanoseconds nobody can read anyway.
+func round(d time.Duration) time.Duration { return d.Round(time.Microsecond) }
+
+func ms(d time.Duration) float64 { return float64(d.Microseconds()) / 1000 }
@@ -0,0 +1,141 @@
+package sendreq
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+/* design note: HTTP/1.1 lets a client send its next request before the last response has come back: "pipelining"
+   (RFC 9112, section 9.3.2). nothing in a response says which request it answers, so the server has to answer in
+   the order it was asked. that's the catch: if the first request is slow, the fast ones behind it wait for it, even
+   if the server finished them long ago. that's head-of-line blocking, and it's why browsers gave pipelining up, and
+   why HTTP/2 numbers its streams so responses can come back in any order.
+
+   net/http never pipelines: its transport sends a request on a connection only once the connection is free. here we
+   can just write them all and see what comes back. try "sendreq -pipeline http://host/slow /fast /fast" against a
+   server with a slow endpoint, and watch the fast ones arrive when the slow one does.
+*/
+
+// pipelined is how one pipelined response arrived.
+type pipelined struct {
+	request   string        // "GET /path"
+	firstByte time.Duration // since the start
+	done      time.Duration
+}
+
+// pipeline sends all o.count rounds of requests on one connection, back to back without waiting for any answers,
+// then reads the responses in order and prints them, with a table of when each arrived on stderr.
+func pipeline(ctx context.Context, env *cli.Env, o options, requests []options) error {
+	all := make([]options, o.count*len(requests))
+	for i := range all {
+		all[i] = requests[i%len(requests)]
+	}
+
+	c := newClient(o)
+	defer c.close()
+	start := time.Now()
+	conn, err := c.pool.Get(ctx, poolKey(o))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	bc := conn.Conn.(*bufConn)
+
+	// the requests are written from a goroutine while the responses are read: a server that answers each request
+	// as it reads it can fill the connection with answers before it's read them all, and then it waits for us to
+	// read some, while we'd be waiting for it to read the rest of the requests.
+	sent := make(chan error, 1)
+	go func() {
+		bw := bufio.NewWriter(conn)
+		for _, r := range all {
+			head, body, size, err := r.request()
+			if err != nil {
+				sent <- err
+				return
+			}
+			bw.WriteString(head)
+			if body != nil {
+				_, err = writeBody(bw, body, size, r.trailers)
+				body.Close()
+				if err != nil {
+					sent <- fmt.Errorf("sending request body: %w", err)
+					return
+				}
+			}
+		}
+		sent <- bw.Flush()
+	}()
+
+	w := bufio.NewWriter(env.Stdout)
+	defer w.Flush()
+	var arrivals []pipelined
+	defer func() { writePipelined(env.Stderr, arrivals, len(all)) }()
+	for i, r := range all {
+		err := awaitResponse(bc.r)
+		firstByte := time.Since(start)
+		if o.output == "raw" {
+			bc.rec.start(bc.r)
+		}
+		var resp *rawResponse
+		if err == nil {
+			resp, err = readResponse(bc.r, r.method)
+		}
+		if err != nil {
+			select {
+			case serr := <-sent:
+				if serr != nil {
+					return fmt.Errorf("sending the requests: %w", serr)
+				}
+			default:
+			}
+			if i > 0 && errors.Is(err, errNoResponse) {
+				return fmt.Errorf("the server closed the connection after %d of %d responses: %w", i, len(all), err)
+			}
+			return err
+		}
+		if o.output == "raw" {
+			resp.raw = bc.rec.stop(bc.r)
+		}
+		resp.timing = timing{reused: i > 0, ttfb: firstByte, total: time.Since(start)}
+		resp.tls = bc.tls
+		if i == 0 {
+			resp.timing.dialTiming = bc.dialed
+		}
+		arrivals = append(arrivals, pipelined{r.method + " " + r.path, resp.timing.ttfb, resp.timing.total})
+		if r.compressed && !r.compressedRaw {
+			if err := decodeBody(resp); err != nil {
+				return err
+			}
+		}
+		if err := writeResponse(w, resp, r, true); err != nil {
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if !resp.keepAlive() && i < len(all)-1 {
+			return fmt.Errorf("the server closed the connection after %d of %d responses; the rest of the requests went unanswered", i+1, len(all))
+		}
+	}
+	if err := <-sent; err != nil {
+		return fmt.Errorf("sending the requests: %w", err)
+	}
+	return nil
+}
+
+// writePipelined prints when each of n pipelined responses arrived, counting from the start, connecting included.
+func writePipelined(w io.Writer, arrivals []pipelined, n int) {
+	fmt.Fprintf(w, "%d requests pipelined on one connection; %d answered\n", n, len(arrivals))
+	fmt.Fprintf(w, "  %3s  %-32s %12s %12s\n", "#", "request", "first byte", "done")
+	for i, a := range arrivals {
+		fmt.Fprintf(w, "  %3d  %-32s %12s %12s\n", i+1, a.request, round(a.firstByte), round(a.done))
+	}
+}
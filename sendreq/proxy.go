@@ -0,0 +1,115 @@
+package sendreq
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultProxyPort is the port for a -proxy without one, as in curl.
+const defaultProxyPort = "1080"
+
+/* design note: an HTTP proxy gets requests in one of two shapes.
+
+   for plain http, the request goes to the proxy as is, except that the request line carries the whole URL (the
+   "absolute form", RFC 9112 section 3.2.2) instead of just the path: "GET http://example.com/a HTTP/1.1". the proxy
+   reads it, makes its own request to example.com, and relays the response. it sees everything.
+
+   for https that won't do: the proxy would have to see inside the TLS. so the client asks for a tunnel instead,
+   "CONNECT example.com:443 HTTP/1.1", and once the proxy answers 2xx, the connection is a raw pipe to example.com.
+   the TLS handshake and the request go through it end to end, and all the proxy sees is where they went.
+*/
+
+// parseProxy parses -proxy: http://[user:password@]host[:port], or just host[:port].
+func parseProxy(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" {
+		return nil, fmt.Errorf("want an http:// proxy; got %s://", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), defaultProxyPort)
+	}
+	return u, nil
+}
+
+// proxyAuth is the Proxy-Authorization header value for the proxy's credentials, or "" if it has none.
+func proxyAuth(proxy *url.URL) string {
+	if proxy.User == nil {
+		return ""
+	}
+	pass, _ := proxy.User.Password()
+	return basicAuth(proxy.User.Username() + ":" + pass)
+}
+
+// dialProxy connects to -proxy, adding to t. for a TLS target it also opens a CONNECT tunnel to the target, so the
+// connection it returns is ready for the TLS handshake; a plain http request goes straight to the proxy.
+func dialProxy(ctx context.Context, o options, t *dialTiming) (net.Conn, error) {
+	port, _ := strconv.Atoi(o.proxy.Port()) // parseProxy made sure there's one.
+	conn, err := dialTCP(ctx, o, o.proxy.Hostname(), port, t)
+	if err != nil {
+		return nil, fmt.Errorf("proxy %s: %w", o.proxy.Host, err)
+	}
+	if !o.tls {
+		return conn, nil
+	}
+
+	start := time.Now()
+	tunnel, err := connect(ctx, conn, o)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: %w", o.proxy.Host, err)
+	}
+	t.connect += time.Since(start)
+	return tunnel, nil
+}
+
+// connect asks the proxy on conn for a tunnel to o's host and port.
+func connect(ctx context.Context, conn net.Conn, o options) (net.Conn, error) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	target := net.JoinHostPort(o.host, strconv.Itoa(o.port))
+	req := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n", target, target)
+	if auth := proxyAuth(o.proxy); auth != "" {
+		req += "Proxy-Authorization: " + auth + "\r\n"
+	}
+	if _, err := conn.Write([]byte(req + "\r\n")); err != nil {
+		return nil, fmt.Errorf("sending CONNECT: %w", err)
+	}
+	// a 2xx to CONNECT has no body: the tunnel starts right after the head. anything else is the proxy refusing.
+	r := bufio.NewReader(conn)
+	resp, err := readHead(r, "CONNECT")
+	if err != nil {
+		return nil, fmt.Errorf("reading CONNECT response: %w", err)
+	}
+	if resp.status < 200 || resp.status > 299 {
+		return nil, fmt.Errorf("CONNECT %s refused: %s", target, resp.head[0])
+	}
+	if r.Buffered() > 0 {
+		// the target can't have said anything yet, since TLS has the client speak first, but don't drop it if so.
+		return &prefixConn{conn, r}, nil
+	}
+	return conn, nil
+}
+
+// prefixConn is a connection whose first reads come from r, which read ahead of what's been consumed.
+type prefixConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *prefixConn) Read(p []byte) (int, error) { return c.r.Read(p) }
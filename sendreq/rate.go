@@ -0,0 +1,120 @@
+package sendreq
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/* design note: a token bucket is the classic rate limiter. tokens drip into a bucket at a steady rate, up to the
+   bucket's size, and each request has to take one, waiting for the next drip if it's empty. the size is the burst:
+   how many requests can go back to back after a quiet spell. ours holds one token, so -rate 100/s sends a request
+   every 10ms, evenly spaced, rather than a hundred at the top of each second.
+*/
+
+// bucket is a token bucket.
+type bucket struct {
+	interval time.Duration // time for one token to drip in
+	burst    float64       // most tokens it holds
+	tokens   float64
+	last     time.Time // when tokens was last brought up to date
+}
+
+func newBucket(interval time.Duration, burst int) *bucket {
+	return &bucket{interval: interval, burst: float64(burst), tokens: float64(burst), last: time.Now()}
+}
+
+// wait takes a token, waiting for one to drip in if there isn't one. it's not safe for concurrent use.
+func (b *bucket) wait(ctx context.Context) error {
+	now := time.Now()
+	b.tokens = min(b.burst, b.tokens+float64(now.Sub(b.last))/float64(b.interval))
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return nil
+	}
+	delay := time.Duration((1 - b.tokens) * float64(b.interval))
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		b.tokens, b.last = 0, now.Add(delay) // the token that dripped in is spent.
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parseRate parses -rate: a count per period, like 100/s, 30/m, or 5/250ms; a bare count is per second. it
+// returns the time between requests.
+func parseRate(s string) (time.Duration, error) {
+	count, per, ok := strings.Cut(s, "/")
+	if !ok {
+		per = "s"
+	}
+	n, err := strconv.ParseFloat(count, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("want a positive number of requests; got %q", count)
+	}
+	// a unit on its own is one of it: "s" is "1s".
+	if per != "" && (per[0] < '0' || per[0] > '9') && per[0] != '.' {
+		per = "1" + per
+	}
+	d, err := time.ParseDuration(per)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("want a period like s, m, or 500ms; got %q", per)
+	}
+	interval := time.Duration(float64(d) / n)
+	if interval <= 0 {
+		return 0, fmt.Errorf("%s is too fast", s)
+	}
+	return interval, nil
+}
+
+// pacer says when to send each request: at most a certain number, no faster than -rate, and only until -duration is
+// up. it's not safe for concurrent use.
+type pacer struct {
+	left     int       // requests still to send; -1 for no limit
+	deadline time.Time // zero for none
+	bucket   *bucket   // nil for as fast as possible
+}
+
+// newPacer returns a pacer for o that allows n requests, or any number for n == 0.
+func (o options) newPacer(n int) *pacer {
+	p := &pacer{left: n}
+	if n == 0 {
+		p.left = -1
+	}
+	if o.duration > 0 {
+		p.deadline = time.Now().Add(o.duration)
+	}
+	if o.rate > 0 {
+		p.bucket = newBucket(o.rate, 1)
+	}
+	return p
+}
+
+// next waits until it's time for the next request and reports whether to send it: false once the requests are all
+// sent, the time is up, or ctx is done.
+func (p *pacer) next(ctx context.Context) bool {
+	if p.left == 0 {
+		return false
+	}
+	if !p.deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, p.deadline)
+		defer cancel()
+	}
+	if p.bucket != nil && p.bucket.wait(ctx) != nil {
+		return false
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	if p.left > 0 {
+		p.left--
+	}
+	return true
+}
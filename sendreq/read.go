@@ -0,0 +1,195 @@
+package sendreq
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/ekediala/httpwire"
+)
+
+// errNoResponse means the connection ended before any of the response arrived.
+var errNoResponse = errors.New("server closed the connection without responding")
+
+// rawResponse is a response as it came off the wire: the status line and header lines, as httpwire parsed them, and
+// the body with its transfer framing removed.
+type rawResponse struct {
+	head     []string // status line, then one entry per header line; no CRLFs
+	status   int
+	headers  Headers
+	body     []byte
+	trailers Headers              // fields sent after a chunked body (or, in HTTP/2, after the last DATA frame)
+	toEOF    bool                 // the body had no framing and ran until the server closed the connection
+	unsent   bool                 // the server answered before the request body went out, so it never did
+	raw      []byte               // the response exactly as it arrived, framing and all; only kept for -o raw
+	streamed bool                 // it was printed as it arrived, and body was never kept
+	tls      *tls.ConnectionState // the connection's, if it was TLS
+	timing   timing
+	wire     *Response // what httpwire read, with the body still to come through its BodyReader; nil for HTTP/2
+}
+
+// timing is how long a request took, phase by phase.
+type timing struct {
+	reused bool // the connection was already open, so there was no DNS lookup, connect, or TLS handshake
+	dialTiming
+	ttfb  time.Duration // time to first byte: from the start to the first byte of the response
+	total time.Duration // from the start to the end of the response
+}
+
+// recorder keeps a copy of what's read from a connection, for -o raw, between start and stop. it sits under the
+// connection's buffer, since that's what httpwire reads from, and the buffer reads ahead: on a keep-alive connection,
+// what it reads ahead is the next response. so stop takes what's still in the buffer back off the end, and start
+// puts what was already in it at the front.
+type recorder struct {
+	r   io.Reader
+	buf bytes.Buffer
+	on  bool
+}
+
+func (rec *recorder) Read(p []byte) (int, error) {
+	n, err := rec.r.Read(p)
+	if rec.on {
+		rec.buf.Write(p[:n])
+	}
+	return n, err
+}
+
+// start starts recording what's read from br, the buffer over rec.
+func (rec *recorder) start(br *bufio.Reader) {
+	b, _ := br.Peek(br.Buffered())
+	rec.buf.Reset()
+	rec.buf.Write(b)
+	rec.on = true
+}
+
+// stop stops recording, and returns what was read from br since start.
+func (rec *recorder) stop(br *bufio.Reader) []byte {
+	rec.on = false
+	return bytes.Clone(rec.buf.Bytes()[:rec.buf.Len()-br.Buffered()])
+}
+
+// readResponse reads one response to a method request from r, using the headers to find where its body ends: a
+// chunked body ends with its zero-length chunk, a Content-Length body after that many bytes, and anything else when
+// the server closes the connection. reading by framing rather than to EOF is what lets us stop when a keep-alive
+// server goes quiet.
+func readResponse(r *bufio.Reader, method string) (*rawResponse, error) {
+	resp, err := readFinalHead(r, method)
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.readBody(); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// readBody reads the body of resp into resp.body.
+func (resp *rawResponse) readBody() error {
+	body, _ := resp.bodyReader()
+	var err error
+	if resp.body, err = io.ReadAll(body); err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+	return nil
+}
+
+// bodyReader returns a reader for the body of resp, that ends where its framing says the body does, and puts any
+// trailers in resp.trailers when it gets there. size is the body's length, or -1 if it isn't known until the end.
+func (resp *rawResponse) bodyReader() (body io.Reader, size int64) {
+	return trailing{resp.wire.BodyReader, resp}, resp.wire.ContentLength
+}
+
+// trailing is a response's body, which copies the response's trailers, if it has any, once it's all been read.
+type trailing struct {
+	io.Reader
+	resp *rawResponse
+}
+
+func (t trailing) Read(p []byte) (int, error) {
+	n, err := t.Reader.Read(p)
+	if err == io.EOF {
+		t.resp.trailers = t.resp.wire.Trailers
+	}
+	return n, err
+}
+
+// bodiless reports whether a response with status to a method request has no body, whatever its headers say
+// (RFC 9112, section 6.3). a HEAD response has the headers a GET would, Content-Length included, but not the body
+// they describe; 1xx, 204, and 304 responses never have one.
+func bodiless(method string, status int) bool {
+	return method == "HEAD" || status < 200 || status == 204 || status == 304
+}
+
+// readFinalHead reads response heads from r until it gets to the final response's. 1xx responses other than 101
+// are interim: progress reports ahead of the real answer, like a late 100 Continue or 103 Early Hints, with no body.
+func readFinalHead(r *bufio.Reader, method string) (*rawResponse, error) {
+	for {
+		resp, err := readHead(r, method)
+		if err != nil || resp.status >= 200 || resp.status == 101 {
+			return resp, err
+		}
+	}
+}
+
+// awaitResponse waits for the first byte of a response on r, without taking it. bufio returns a Peek's error only
+// once, and the next read just tries again, under a fresh -read-timeout, so the error has to be kept from here.
+func awaitResponse(r *bufio.Reader) error {
+	if _, err := r.Peek(1); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET) {
+			return fmt.Errorf("%w: %w", errNoResponse, err)
+		}
+		return fmt.Errorf("reading response head: %w", err)
+	}
+	return nil
+}
+
+// readHead reads the head of a response to a method request, up to and including the empty line that ends it,
+// leaving the body on r, to be read through bodyReader.
+func readHead(r *bufio.Reader, method string) (*rawResponse, error) {
+	if err := awaitResponse(r); err != nil {
+		return nil, err
+	}
+	w, err := httpwire.ReadResponseHead(r, method)
+	if err != nil {
+		return nil, fmt.Errorf("reading response head: %w", err)
+	}
+	resp := &rawResponse{status: w.StatusCode, headers: w.Headers, wire: w}
+	// ReadResponseHead hands over r itself for a body with no framing, which runs until the server closes.
+	resp.toEOF = w.BodyReader == io.Reader(r)
+	status := w.Proto + " " + strconv.Itoa(w.StatusCode)
+	if w.Reason != "" {
+		status += " " + w.Reason
+	}
+	resp.head = append(resp.head, status)
+	for _, h := range w.Headers {
+		resp.head = append(resp.head, h.Key+": "+h.Value)
+	}
+	return resp, nil
+}
+
+// keepAlive reports whether the connection can carry another request after this response. HTTP/1.1 connections
+// stay open unless either side says "Connection: close"; HTTP/1.0 ones close unless the server says "keep-alive".
+// a body that ran to EOF used up the connection either way, and so did a request body that was never sent: the
+// server may still be waiting for it.
+func (resp *rawResponse) keepAlive() bool {
+	if resp.toEOF || resp.unsent {
+		return false
+	}
+	if strings.HasPrefix(resp.head[0], "HTTP/1.0") {
+		return resp.offersKeepAlive()
+	}
+	return !resp.headers.HasToken("Connection", "close")
+}
+
+// offersKeepAlive reports whether the response says "Connection: keep-alive", which is how HTTP/1.0 keeps a
+// connection open.
+func (resp *rawResponse) offersKeepAlive() bool {
+	return resp.headers.HasToken("Connection", "keep-alive")
+}
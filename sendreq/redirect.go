@@ -0,0 +1,108 @@
+package sendreq
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isRedirect reports whether status sends the client somewhere else with a Location header.
+// (300 Multiple Choices and 304 Not Modified are 3xx too, but neither says where to go next.)
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// url is the URL o requests.
+func (o options) url() *url.URL {
+	u := &url.URL{Scheme: "http", Host: o.host}
+	if o.tls {
+		u.Scheme = "https"
+	}
+	switch {
+	case (o.tls && o.port != 443) || (!o.tls && o.port != 80):
+		u.Host = net.JoinHostPort(o.host, strconv.Itoa(o.port))
+	case strings.Contains(o.host, ":"):
+		u.Host = "[" + o.host + "]" // an IPv6 address is bracketed, port or not, to keep its colons apart from one.
+	}
+	// path holds the path and query as they go on the request line; parse them so ResolveReference can use them.
+	if ref, err := url.ParseRequestURI(o.path); err == nil {
+		u.Path, u.RawPath, u.RawQuery = ref.Path, ref.RawPath, ref.RawQuery
+	}
+	return u
+}
+
+// redirect returns the options for following a redirect from o's request: a status response with a location header.
+// the location can be relative, so it's resolved against the URL we just asked for, and it can point at another
+// host, port, or scheme; the next request dials from scratch.
+func (o options) redirect(status int, location string) (options, error) {
+	ref, err := url.Parse(location)
+	if err != nil {
+		return o, fmt.Errorf("bad Location %q: %w", location, err)
+	}
+	next := o
+	// a URL overrides nothing from the flags here: they applied to the first request only.
+	if err := next.target([]string{o.url().ResolveReference(ref).String()}, nil); err != nil {
+		return o, fmt.Errorf("bad Location %q: %w", location, err)
+	}
+
+	/* design note: 307 and 308 were added because clients treated 301 and 302 inconsistently. they say "repeat the
+	   same request there", method and all. 303 says "GET the result there". and for 301 and 302, every browser turns
+	   a POST into a GET, so the spec (RFC 9110, section 15.4) now allows it, and we do the same.
+	*/
+	switch {
+	case status == http.StatusSeeOther && o.method != http.MethodHead:
+		next.method = http.MethodGet
+	case (status == http.StatusMovedPermanently || status == http.StatusFound) && o.method == http.MethodPost:
+		next.method = http.MethodGet
+	}
+	// credentials are for the server they were given for. a redirect elsewhere could be to anyone, so, as curl does,
+	// neither -u nor an Authorization header goes with it.
+	if next.host != o.host || next.port != o.port || next.tls != o.tls {
+		next.user = ""
+		next.headers = slices.DeleteFunc(slices.Clone(next.headers), func(h Header) bool { return h.Key == "Authorization" })
+	}
+	if next.method != o.method {
+		next.hasBody = false // the body went with the old method.
+	} else if o.hasBody && o.dataStdin {
+		return o, fmt.Errorf("%d redirect to %s: can't send the body again, stdin has already been read", status, next.url())
+	}
+	return next, nil
+}
+
+// hop is one request in a chain of redirects.
+type hop struct {
+	status      int
+	method, url string
+	next        string // where it redirected to; "" for the last
+	took        time.Duration
+}
+
+// writeChain prints the redirects -L followed to get the final response: each hop's status, request, how long it
+// took, and where it sent us next.
+func writeChain(w io.Writer, hops []hop) {
+	fmt.Fprintln(w, "redirect chain:")
+	var total time.Duration
+	for i, h := range hops {
+		fmt.Fprintf(w, "  %d. %d %s %s  %s\n", i+1, h.status, h.method, h.url, round(h.took))
+		if h.next != "" {
+			fmt.Fprintf(w, "       -> %s\n", h.next)
+		}
+		total += h.took
+	}
+	if len(hops) == 1 {
+		fmt.Fprintf(w, "  1 request in %s\n", round(total))
+	} else {
+		fmt.Fprintf(w, "  %d requests in %s\n", len(hops), round(total))
+	}
+}
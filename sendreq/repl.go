@@ -0,0 +1,310 @@
+package sendreq
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ekediala/cli"
+)
+
+/* design note: -i opens a session with a server, for poking at an API by hand: set a header once, then send request
+   after request, changing the method, path, or body in between, without retyping the command line. the requests go
+   over the client's kept-alive connections like any others, so the whole session is one connection for as long as
+   the server keeps it open (and a new one, dialed quietly, when it doesn't). each line is a command:
+
+	GET /items            a method, in capitals, with an optional path or URL: set them and send the request
+	method PUT            set the method
+	path /items/42        set the path and query, or, with a scheme and host, the whole URL
+	header X-Token: abc   set a header, replacing one of the same name; "header X-Token:" drops it
+	body {"n": 1}         set the body; "body @file" sends a file's contents; "body" on its own drops it
+	send                  send the request as it stands
+	info                  print the request as it stands, as it would go on the wire
+	history               list the requests sent so far; "!2" sends the second one again
+	show                  print the last exchange as it went on the wire: the request, then the response
+	help, quit
+
+   the command line's flags and URL are where the session starts. commands come from stdin, so a script piped in
+   works as well as typing, and stdin can't also be the body (-data-stdin). quit, ^D, or ^C ends the session; an
+   error, like a refused connection, just ends that command.
+*/
+
+// replHelp is what the help command prints.
+const replHelp = `GET /path             set the method (any, in capitals) and, optionally, the path or URL, and send
+method METHOD         set the method
+path PATH|URL         set the path and query, or the whole URL
+header Key: Value     set a header; "header Key:" drops it
+body TEXT|@FILE       set the body; "body" on its own drops it
+send                  send the request
+info                  print the request as it would be sent
+history               list the requests sent so far; !N sends the Nth again
+show                  print the last request and response as they went on the wire
+help, quit
+`
+
+// session is an -i session: the request as it stands, and what's been sent.
+type session struct {
+	o       options // the next request
+	c       *client
+	w       *bufio.Writer
+	stderr  io.Writer
+	history []options
+	last    *options     // the last request that got a response, for show
+	resp    *rawResponse // and its response
+}
+
+// repl runs an -i session, reading commands from in until it ends or ctx is done.
+func repl(ctx context.Context, env *cli.Env, o options, in io.Reader) error {
+	o.interactive = true // which keeps each response as it came, for show
+	s := &session{o: o, c: newClient(o), w: bufio.NewWriter(env.Stdout), stderr: env.Stderr}
+	defer s.c.close()
+	defer s.w.Flush()
+	s.c.progress = env.Stderr
+
+	// the prompt is for a person typing; a script piped in doesn't need one.
+	prompt := false
+	if f, ok := in.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			prompt = true
+			fmt.Fprintf(env.Stderr, "session with %s; \"help\" lists the commands\n", o.url())
+		}
+	}
+
+	// the read blocks, and ^C doesn't interrupt it (the context has the signal), so it goes on in the background.
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		r := bufio.NewReader(in)
+		for {
+			line, err := r.ReadString('\n')
+			if line != "" {
+				select {
+				case lines <- strings.TrimRight(line, "\r\n"):
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		if prompt {
+			fmt.Fprint(env.Stderr, "sendreq> ")
+		}
+		var line string
+		select {
+		case l, ok := <-lines:
+			if !ok {
+				if prompt {
+					fmt.Fprintln(env.Stderr)
+				}
+				return nil
+			}
+			line = l
+		case <-ctx.Done():
+			fmt.Fprintln(env.Stderr)
+			return nil
+		}
+		quit, err := s.exec(ctx, line)
+		if err := s.w.Flush(); err != nil {
+			return err
+		}
+		if err != nil {
+			fmt.Fprintf(env.Stderr, "error: %v\n", err)
+		}
+		if quit {
+			return nil
+		}
+	}
+}
+
+// exec runs one command line.
+func (s *session) exec(ctx context.Context, line string) (quit bool, err error) {
+	cmd, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+	switch {
+	case cmd == "" || strings.HasPrefix(cmd, "#"):
+	case cmd == "quit" || cmd == "exit":
+		return true, nil
+	case cmd == "help":
+		fmt.Fprint(s.w, replHelp)
+	case isMethod(cmd):
+		s.o.method = cmd
+		if arg != "" {
+			if err := s.setTarget(arg); err != nil {
+				return false, err
+			}
+		}
+		return false, s.send(ctx)
+	case cmd == "method":
+		if !isMethod(arg) {
+			return false, fmt.Errorf("%q isn't a method; methods are in capitals, like GET or POST", arg)
+		}
+		s.o.method = arg
+	case cmd == "path":
+		if arg == "" {
+			return false, errors.New("path needs a path or URL")
+		}
+		return false, s.setTarget(arg)
+	case cmd == "header":
+		var h headerFlags
+		if err := h.Set(arg); err != nil {
+			return false, err
+		}
+		s.setHeader(h[0])
+	case cmd == "body":
+		s.setBody(line)
+	case cmd == "send":
+		return false, s.send(ctx)
+	case cmd == "info":
+		return false, s.printRequest(s.o)
+	case cmd == "history":
+		for i, o := range s.history {
+			fmt.Fprintf(s.w, "%3d  %s %s\n", i+1, o.method, o.url())
+		}
+	case strings.HasPrefix(cmd, "!"):
+		n, err := strconv.Atoi(cmd[1:])
+		if err != nil || n < 1 || n > len(s.history) {
+			return false, fmt.Errorf("no request %s in the history", cmd[1:])
+		}
+		s.o = s.history[n-1]
+		return false, s.send(ctx)
+	case cmd == "show":
+		if s.last == nil {
+			return false, errors.New("nothing sent yet")
+		}
+		return false, s.show()
+	default:
+		return false, fmt.Errorf("unknown command %q; \"help\" lists them", cmd)
+	}
+	return false, nil
+}
+
+// isMethod reports whether s looks like a method: a token in capitals, like GET or PROPFIND.
+func isMethod(s string) bool {
+	return isToken(s) && strings.ToUpper(s) == s && strings.ToLower(s) != s
+}
+
+// setTarget points the request at target: a path, taken against the URL so far, or a whole URL.
+func (s *session) setTarget(target string) error {
+	ref, err := url.Parse(target)
+	if err != nil {
+		return fmt.Errorf("bad path %q: %w", target, err)
+	}
+	return s.o.target([]string{s.o.url().ResolveReference(ref).String()}, nil)
+}
+
+// setHeader sets h, replacing the session's header of the same name. an empty value drops it, or, for a header
+// sendreq sends by itself, like User-Agent, stops it being sent, as with -H.
+func (s *session) setHeader(h Header) {
+	headers := slices.DeleteFunc(slices.Clone(s.o.headers), func(x Header) bool { return x.Key == h.Key })
+	bare := s.o
+	bare.headers = nil
+	if _, defaults := bare.requestHeaders(); h.Value != "" || indexHeader(defaults, h.Key) >= 0 {
+		headers = append(headers, h)
+	}
+	s.o.headers = headers
+}
+
+// setBody sets the body from a body command line: the rest of the line, as it was typed, or a file's contents for
+// @file. with nothing after the command, the request has no body.
+func (s *session) setBody(line string) {
+	_, text, _ := strings.Cut(strings.TrimLeft(line, " \t"), " ")
+	s.o.data, s.o.dataFile, s.o.form, s.o.values = "", "", nil, nil
+	switch {
+	case strings.TrimSpace(text) == "":
+		s.o.hasBody = false
+	case strings.HasPrefix(text, "@"):
+		s.o.dataFile, s.o.hasBody = text[1:], true
+	default:
+		s.o.data, s.o.hasBody = text, true
+	}
+}
+
+// send sends the request as it stands and prints the response.
+func (s *session) send(ctx context.Context) error {
+	o := s.o
+	if o.hasBody && (indexHeader(o.headers, "Content-Length") >= 0 || indexHeader(o.headers, "Transfer-Encoding") >= 0) {
+		return errors.New("a request with a body can't set Content-Length or Transfer-Encoding; sendreq frames the body itself")
+	}
+	s.history = append(s.history, o)
+	resp, err := fetch(ctx, s.c, s.w, s.stderr, o)
+	if resp != nil {
+		s.last, s.resp = &o, resp
+	}
+	return err
+}
+
+// show prints the last exchange: the request as it went on the wire, then the response as it came back.
+func (s *session) show() error {
+	fmt.Fprintln(s.w, "--- sent")
+	if err := s.printRequest(*s.last); err != nil {
+		return err
+	}
+	fmt.Fprintln(s.w, "--- received")
+	if s.resp.raw == nil {
+		// HTTP/2's frames aren't kept as they came: the response as they said it will have to do.
+		var resp strings.Builder
+		writeResponse(&resp, s.resp, options{output: "all"}, true)
+		s.w.WriteString(endLine(resp.String()))
+		return nil
+	}
+	s.w.WriteString(endLine(string(s.resp.raw)))
+	return nil
+}
+
+// printRequest prints o's request as it goes on the wire, ending the line its body ends on, if it has to.
+func (s *session) printRequest(o options) error {
+	var req strings.Builder
+	if err := o.writeRequest(&req); err != nil {
+		return err
+	}
+	s.w.WriteString(endLine(req.String()))
+	return nil
+}
+
+// endLine returns s with a newline at the end, if it hasn't one, so what's printed next starts on a line of its own.
+func endLine(s string) string {
+	if s != "" && !strings.HasSuffix(s, "\n") {
+		s += "\n"
+	}
+	return s
+}
+
+// writeRequest writes o's request as it goes on the wire, body and all; for HTTP/2, its header fields, one per line,
+// then the body.
+func (o options) writeRequest(w io.Writer) error {
+	if o.http2 {
+		fields, body, _, err := o.http2Request()
+		if err != nil {
+			return err
+		}
+		io.WriteString(w, formatFields(fields))
+		if body != nil {
+			defer body.Close()
+			io.WriteString(w, "\n")
+			_, err = io.Copy(w, body)
+		}
+		return err
+	}
+	head, body, size, err := o.request()
+	if err != nil {
+		return err
+	}
+	io.WriteString(w, head)
+	if body != nil {
+		defer body.Close()
+		_, err = writeBody(w, body, size, o.trailers)
+	}
+	return err
+}
@@ -0,0 +1,116 @@
+package sendreq
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// resolve looks up host's IPv4 and IPv6 addresses (A and AAAA records), in the order to try them: see interleave.
+// a -resolve pin for host and port wins over DNS, and -resolver picks the DNS server. answers are cached, unless
+// -no-dns-cache says not to.
+func (o options) resolve(ctx context.Context, host string, port int) ([]net.IP, error) {
+	for _, p := range o.pins {
+		if strings.EqualFold(p.host, host) && p.port == port {
+			return interleave(p.addrs), nil
+		}
+	}
+	if o.dnsCache == nil || net.ParseIP(host) != nil {
+		return o.lookUp(ctx, host)
+	}
+	ips, cached, err := o.dnsCache.lookup(ctx, host, func(ctx context.Context) ([]net.IP, error) { return o.lookUp(ctx, host) })
+	if cached && err == nil && o.trace != nil {
+		o.trace.note("using the cached addresses for %s", host)
+	}
+	return ips, err
+}
+
+// lookUp asks DNS for host's addresses.
+func (o options) lookUp(ctx context.Context, host string) ([]net.IP, error) {
+	r := net.DefaultResolver
+	if o.resolver != nil {
+		r = o.resolver
+	}
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, len(addrs))
+	for i, a := range addrs {
+		ips[i] = a.IP
+	}
+	return interleave(ips), nil
+}
+
+// newResolver returns a resolver that asks the DNS server at addr (host[:port], port 53 by default) instead of the
+// ones in the system's configuration.
+func newResolver(addr string) (*net.Resolver, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	if net.ParseIP(host) == nil {
+		return nil, fmt.Errorf("%q is not an IP address", host) // a name would need a resolver of its own.
+	}
+	/* design note: Go's own resolver (PreferGo) reads /etc/resolv.conf to find a server, then dials it through
+	   Dial. replacing Dial swaps the server while keeping the rest: the UDP queries, the fallback to TCP for big
+	   answers, the A and AAAA lookups in parallel. the cgo resolver goes through the C library instead, and would
+	   ignore Dial entirely.
+	*/
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}, nil
+}
+
+// pin is a -resolve override: host:port goes to addrs, whatever DNS says.
+type pin struct {
+	host  string
+	port  int
+	addrs []net.IP
+}
+
+// pinFlags collects repeated -resolve host:port:addr[,addr...] flags, as in curl.
+type pinFlags []pin
+
+func (p *pinFlags) String() string {
+	s := make([]string, len(*p))
+	for i, pin := range *p {
+		addrs := make([]string, len(pin.addrs))
+		for j, a := range pin.addrs {
+			addrs[j] = a.String()
+			if a.To4() == nil {
+				addrs[j] = "[" + addrs[j] + "]"
+			}
+		}
+		s[i] = fmt.Sprintf("%s:%d:%s", pin.host, pin.port, strings.Join(addrs, ","))
+	}
+	return strings.Join(s, " ")
+}
+
+func (p *pinFlags) Set(s string) error {
+	// host and port can't have colons, but the addresses can (IPv6), so split off the first two fields only.
+	parts := strings.SplitN(s, ":", 3)
+	if len(parts) != 3 || parts[0] == "" {
+		return fmt.Errorf("%q should be of form host:port:addr", s)
+	}
+	port, err := strconv.Atoi(parts[1])
+	if err != nil || port < 1 || port > 65535 {
+		return fmt.Errorf("bad port %q in %q", parts[1], s)
+	}
+	pin := pin{host: parts[0], port: port}
+	for _, a := range strings.Split(parts[2], ",") {
+		ip := net.ParseIP(strings.Trim(a, "[]"))
+		if ip == nil {
+			return fmt.Errorf("bad address %q in %q", a, s)
+		}
+		pin.addrs = append(pin.addrs, ip)
+	}
+	*p = append(*p, pin)
+	return nil
+}
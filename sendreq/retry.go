@@ -0,0 +1,126 @@
+package sendreq
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekediala/httpwire"
+	"github.com/ekediala/retry"
+)
+
+// statusError is a 5xx or 429 response passed off as an error, so that -retry-5xx can retry it like a failed
+// request.
+type statusError struct{ resp *rawResponse }
+
+func (e *statusError) Error() string { return "server said " + e.resp.head[0] }
+
+/* design note: a 503 Service Unavailable or a 429 Too Many Requests can say when to come back, in a Retry-After
+   header: a number of seconds, "Retry-After: 120", or a date, "Retry-After: Fri, 16 Oct 2026 09:00:00 GMT". the
+   server knows better than our backoff does how long it'll be down, or how long a rate limit's window has left, and
+   a retry sooner than that is a wasted request, or one more strike against the client. so with -retry-5xx, a
+   Retry-After replaces the backoff's wait for that retry. a server that asks for more than maxRetryAfter doesn't get
+   waited for: its response is the answer, as if the retries had run out.
+*/
+
+// maxRetryAfter is the longest Retry-After that's waited out.
+const maxRetryAfter = 2 * time.Minute
+
+// doRetry is c.do with -retries: failed attempts are retried with jittered exponential backoff, starting at
+// -retry-backoff and doubling each time, or after as long as a Retry-After says. if the last attempt got a 5xx or a
+// 429 response, that response is returned.
+func (c *client) doRetry(ctx context.Context, o options) (*rawResponse, error) {
+	policy := retry.Policy{
+		MaxAttempts: o.retries + 1,
+		Backoff:     retry.Exponential{Base: o.retryBackoff, Max: 30 * time.Second, Jitter: 0.5},
+		Retryable:   o.retryable,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			args := []any{"url", o.url().String(), "attempt", attempt, "error", err.Error(), "retrying in", delay.String()}
+			if se := (*statusError)(nil); errors.As(err, &se) {
+				if after := se.resp.headers.Get("Retry-After"); after != "" {
+					args = append(args, "retry-after", after)
+				}
+			}
+			slog.WarnContext(ctx, "main", args...)
+		},
+	}
+	var resp *rawResponse
+	err := policy.Do(ctx, func(ctx context.Context) (err error) {
+		resp, err = c.do(ctx, o)
+		if err != nil || !o.retry5xx || resp.status < 500 && resp.status != http.StatusTooManyRequests {
+			return err
+		}
+		wait, ok := retryAfter(resp.headers, time.Now())
+		switch {
+		case !ok:
+			return &statusError{resp}
+		case wait > maxRetryAfter:
+			slog.WarnContext(ctx, "main", "url", o.url().String(), "message", "the server asks to wait longer than we will before retrying; giving up",
+				"retry-after", resp.headers.Get("Retry-After"), "wait", wait.Round(time.Second).String(), "max", maxRetryAfter.String())
+			return retry.Permanent(&statusError{resp})
+		default:
+			return retry.After(&statusError{resp}, wait)
+		}
+	})
+	if se := (*statusError)(nil); errors.As(err, &se) {
+		return se.resp, nil // out of attempts: the last 5xx is the answer.
+	}
+	return resp, err
+}
+
+// retryAfter returns how long headers' Retry-After says to wait, from now, if there's one that makes sense: a number
+// of seconds, or an HTTP date. a date in the past means now.
+func retryAfter(headers Headers, now time.Time) (time.Duration, bool) {
+	v := strings.TrimSpace(headers.Get("Retry-After"))
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.ParseUint(v, 10, 32); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	date, err := httpwire.ParseDate(v)
+	if err != nil {
+		return 0, false
+	}
+	return max(date.Sub(now), 0), true
+}
+
+// retryable reports whether a request that failed with err is worth sending again.
+func (o options) retryable(err error) bool {
+	var de *dialError
+	var se *statusError
+	var pe *printedError
+	switch {
+	case o.hasBody && o.dataStdin:
+		return false // stdin can't be read twice.
+	case errors.As(err, &pe):
+		return false // the response is partly printed already.
+	case errors.As(err, &de):
+		// nothing was sent, so any method is safe to try again. a certificate that doesn't verify isn't a network
+		// error at all, so retry.IsNetRetryable gives up on it right away.
+		return retry.IsNetRetryable(err)
+	case !idempotent(o.method):
+		/* design note: once a request has gone out, a timeout or a reset doesn't tell us whether the server acted on
+		   it. sending a GET twice is harmless; sending a POST twice might charge a card twice. so after the request
+		   has left, we only retry methods that are idempotent (RFC 9110, section 9.2.2), the way net/http does.
+		*/
+		return false
+	case errors.As(err, &se):
+		return true
+	default:
+		return retry.IsNetRetryable(err)
+	}
+}
+
+// idempotent reports whether sending a request with method twice has the same effect as sending it once.
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
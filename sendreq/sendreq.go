@@ -0,0 +1,775 @@
+// Package sendreq is the sendreq tool, which sends an HTTP request over a raw TCP connection and prints the raw
+// response. the request and response types it's built on are httpwire's.
+package sendreq
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekediala/cli"
+	"github.com/ekediala/httpwire"
+	"github.com/ekediala/pool"
+)
+
+// Header, Headers, Request, and Response are httpwire's: the wire types started out here, and moved there so other tools
+// could use them. the aliases keep code written against sendreq's working.
+type (
+	Header   = httpwire.Header
+	Headers  = httpwire.Headers
+	Request  = httpwire.Request
+	Response = httpwire.Response
+)
+
+// Command is the sendreq tool.
+var Command = &cli.Command{
+	Name:      "sendreq",
+	Summary:   "send an HTTP request over raw TCP and print the raw response",
+	Args:      "[<URL> [<PATH>...]]",
+	LogFormat: "json",
+	JSON:      true,
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		var o options
+		fs.StringVar(&o.method, "method", http.MethodGet, "http method to use")
+		fs.StringVar(&o.host, "host", "localhost", "host to connect to; overrides the URL's")
+		fs.StringVar(&o.path, "path", "/", "path (and query) to request; overrides the URL's")
+		var hostHeader string
+		fs.StringVar(&hostHeader, "host-header", "", "send this as the Host header (and HTTP/2's :authority) instead of the URL's host and port, while still connecting to the URL's host; for trying a virtual host on a server reached by IP")
+		fs.IntVar(&o.port, "port", 8080, "port to connect to (443 with -tls); overrides the URL's")
+		fs.BoolVar(&o.http10, "http1.0", false, "speak HTTP/1.0: say so on the request line, and send a body without chunking; each request gets a connection of its own unless the server offers keep-alive")
+		fs.BoolVar(&o.http2, "http2", false, "experimental: speak HTTP/2, one request per connection; over TLS if the server picks h2, otherwise in cleartext (h2c) on prior knowledge that the server speaks it")
+		fs.BoolVar(&o.tls, "tls", false, "use HTTPS: wrap the connection in TLS (on by default for https URLs and port 443)")
+		var sign string
+		fs.StringVar(&sign, "sign", "", "sign each request with a shared secret, as hmac-sha256:keyid:secret: an HMAC over the method, path, Date, and body hash goes in the Authorization header")
+		var sni string
+		fs.StringVar(&sni, "sni", "", "send this server name (SNI) in the TLS handshake instead of the host's, and check the certificate against it; for virtual hosts, CDNs, and SNI routing behind one address")
+		var caFile string
+		fs.StringVar(&caFile, "cacert", "", "trust the CAs in this PEM bundle, instead of the system's, to vouch for the server's certificate; for a dev server's self-signed certificate, or a private CA")
+		fs.BoolVar(&o.insecure, "insecure", false, "don't verify the server's certificate at all; the connection is encrypted, but could be to anyone")
+		fs.BoolVar(&o.insecure, "k", false, "same as -insecure")
+		var certFile, keyFile string
+		fs.StringVar(&certFile, "cert", "", "client certificate (PEM) to present if the server asks for one, for mutual TLS; the file can hold the private key too")
+		fs.StringVar(&keyFile, "key", "", "private key (PEM) for -cert's certificate, if it isn't in the same file")
+		var vars varFlags
+		fs.Var(&vars, "var", "value for {{key}} placeholders in the URL, paths, -host, -path, -H, -u, -d, -form, -F, and the -requests file, as key=value; repeatable")
+		fs.Var(&o.headers, "H", "extra request header, as \"Key: Value\"; repeatable. replaces a default header of the same name, or removes it if the value is empty")
+		var proxy string
+		fs.StringVar(&proxy, "proxy", "", "send requests through this HTTP proxy, as http://[user:password@]host[:port] (port 1080 by default); https goes through a CONNECT tunnel")
+		fs.StringVar(&o.unix, "unix", "", "connect to this unix socket instead of the host; the Host header still names the host")
+		var resolver string
+		fs.StringVar(&resolver, "resolver", "", "look host names up with the DNS server at this address (port 53 by default) instead of the system's")
+		var noDNSCache bool
+		fs.BoolVar(&noDNSCache, "no-dns-cache", false, "look the host up for every new connection, instead of reusing an answer for -dns-ttl; for testing DNS failover or round-robin names")
+		var dnsTTL time.Duration
+		fs.DurationVar(&dnsTTL, "dns-ttl", defaultDNSTTL, "how long to reuse a host's looked-up addresses for new connections")
+		fs.Var(&o.pins, "resolve", "connect to host:port at this address instead of looking it up, as host:port:addr[,addr...]; repeatable")
+		var socks string
+		fs.StringVar(&socks, "socks5", "", "connect through this SOCKS5 proxy, as [user:password@]host[:port] (port 1080 by default); it looks up the host names")
+		fs.StringVar(&o.user, "u", "", "send HTTP basic auth as \"user:password\"; with just a user, the password is asked for on the terminal")
+		fs.StringVar(&o.data, "d", "", "request body (makes the default method POST)")
+		fs.StringVar(&o.dataFile, "data-file", "", "send the contents of this file as the request body")
+		fs.BoolVar(&o.dataStdin, "data-stdin", false, "send stdin as the request body, chunked unless it's a redirected file")
+		fs.Var(&o.trailers, "trailer", "request trailer field to send after the body, as \"Key: Value\"; repeatable. the body goes chunked, and a Trailer header names the fields up front")
+		fs.Var(&o.values, "form", "URL-encoded form field, as key=value; repeatable. sends an application/x-www-form-urlencoded body")
+		fs.Var(&o.form, "F", "multipart form field, as name=value, name=@file to upload a file (with optional ;type= and ;filename=), or name=<file for a file's contents; repeatable")
+		fs.BoolVar(&o.expectContinue, "expect-continue", false, "send the body with Expect: 100-continue, and wait for the server's go-ahead before sending it; if the server answers with a final response instead, the body isn't sent")
+		fs.DurationVar(&o.expectTimeout, "expect-timeout", time.Second, "with -expect-continue, send the body anyway if the server hasn't answered in this long")
+		fs.BoolVar(&o.compressed, "compressed", false, "ask for a compressed response (Accept-Encoding: gzip, deflate) and decompress the body")
+		fs.BoolVar(&o.compressedRaw, "compressed-raw", false, "like -compressed, but print the body still compressed, as it arrived")
+		fs.StringVar(&o.download, "O", "", "save the response body to this file as it arrives, with a progress report on stderr, instead of printing it")
+		fs.StringVar(&o.download, "output", "", "same as -O")
+		var jsonPath string
+		fs.StringVar(&jsonPath, "jsonpath", "", "parse the response body as JSON and print only the value at this path, like .data.items[0].id: a string as it is, anything else as JSON")
+		fs.StringVar(&o.output, "o", "all", "what to print: all (status line, headers, and body), headers, body, raw (the bytes as received), or status (just the code)")
+		var rate string
+		fs.StringVar(&rate, "rate", "", "send requests no faster than this, like 100/s, 30/m, or 5/250ms, evenly spaced")
+		fs.DurationVar(&o.duration, "duration", 0, "keep sending requests until this much time has passed; -n, if given, still caps how many")
+		fs.BoolVar(&o.bench, "bench", false, "benchmark: send -n requests from -c workers at once, and report throughput, latency percentiles, status codes, and errors instead of the responses")
+		fs.IntVar(&o.concurrency, "c", 10, "with -bench or -urls, how many requests to have in flight at once")
+		fs.BoolVar(&o.pipeline, "pipeline", false, "write all the requests (the URL's, any extra paths', -n times over) on one connection before reading any responses, then read them in order, and report when each arrived")
+		var cache bool
+		fs.BoolVar(&cache, "cache", false, "remember each URL's ETag and Last-Modified, and send them back as If-None-Match and If-Modified-Since next time, so an unchanged resource comes back as a 304")
+		var cacheFile string
+		fs.StringVar(&cacheFile, "cache-file", "", "where -cache keeps what it remembers; implies -cache (default sendreq/validators.json in the user cache directory)")
+		var verbose bool
+		fs.BoolVar(&verbose, "v", false, "trace the connection to stderr like curl -v: every byte sent after \"> \", every byte received after \"< \", and what the TLS handshake settled on")
+		var har string
+		fs.StringVar(&har, "har", "", "record each request and response, with headers, bodies, sizes, and timings, in this HTTP Archive (HAR) file, for browser devtools or a HAR viewer")
+		fs.BoolVar(&o.websocket, "ws", false, "open a WebSocket: do the upgrade handshake, then send each line of stdin as a text message, and print each message the server sends; ^D closes it (on by default for ws:// and wss:// URLs)")
+		fs.BoolVar(&o.sse, "sse", false, "read a Server-Sent Events stream: print each event as it arrives (as JSON with -json), and reconnect with Last-Event-ID when the stream drops")
+		fs.BoolVar(&o.urls, "urls", false, "read URLs (or paths against the URL) from stdin, one per line, request them -c at a time, and print a line for each: status, size, time, and URL; for checking links or warming caches")
+		fs.BoolVar(&o.interactive, "i", false, "open a session: read commands from stdin to set the method, path, headers, and body, and send request after request over the same connection; \"help\" lists the commands")
+		var requestsFile string
+		fs.StringVar(&requestsFile, "requests", "", "run the requests in this file, one after another, and report how each went: stanzas of a request line (\"GET /path\"), headers, and a body, separated by ### lines")
+		fs.BoolVar(&o.tlsInfo, "tls-info", false, "for each new TLS connection, print what the handshake settled on (version, cipher suite, ALPN) and the server's certificate chain, with subjects, issuers, and expiry dates, to stderr; with -json, it's in each response's \"tls\" field")
+		fs.BoolVar(&o.timing, "timing", false, "print how long each phase of each request took (DNS, connect, TLS, first byte, total) to stderr")
+		var record string
+		fs.StringVar(&record, "record", "", "save each request and its response to this directory, as a numbered fixture file of raw HTTP, for -replay")
+		fs.StringVar(&o.replay, "replay", "", "send the request in each fixture in this directory to the URL again, in order, and compare the responses with the recorded ones: status, headers (but Date), and body")
+		fs.Var(&o.replayIgnore, "replay-ignore", "with -replay, don't compare this header, like one with a request ID or a timestamp; repeatable")
+		var expect expectations
+		var expectStatus string
+		fs.StringVar(&expectStatus, "expect-status", "", "fail (exit 1) unless each response's status is one of these, like 200, 200,204, or 2xx")
+		fs.Var(&expect.bodyContains, "expect-body-contains", "fail (exit 1) unless each response's body contains this text; repeatable")
+		fs.Var(&expect.headers, "expect-header", "fail (exit 1) unless each response has this header, as \"Key: Value\", with the value somewhere in the header's; with no value, the header just has to be there; repeatable")
+		fs.IntVar(&o.count, "n", 1, "send the request (and any extra paths) this many times, reusing the connection while the server keeps it open")
+		fs.DurationVar(&o.connectTimeout, "connect-timeout", 10*time.Second, "give up on connecting (DNS, TCP, and TLS) after this long, per attempt (0 means no limit)")
+		noDelay := true
+		fs.BoolVar(&noDelay, "nodelay", true, "set TCP_NODELAY on each connection, so small writes go out at once; -nodelay=false turns Nagle's algorithm back on, to see what it costs")
+		fs.DurationVar(&o.sock.keepAlive, "keepalive-interval", 15*time.Second, "send TCP keepalive probes on an idle connection this often (0 turns them off)")
+		var localAddr string
+		fs.StringVar(&localAddr, "local-addr", "", "bind each connection to this local IP address, or IP:port, or :port, to pick the interface or source port")
+		fs.DurationVar(&o.readTimeout, "read-timeout", 30*time.Second, "give up if the server sends nothing for this long while we wait for a response (0 means no limit)")
+		fs.IntVar(&o.retries, "retries", 4, "retry a request that failed to connect, timed out, or lost its connection this many times")
+		fs.DurationVar(&o.retryBackoff, "retry-backoff", 100*time.Millisecond, "wait before the first retry; doubles for each one after, with jitter")
+		fs.BoolVar(&o.retry5xx, "retry-5xx", false, "retry 5xx and 429 responses too (idempotent methods only), waiting as long as a Retry-After header says, if there is one")
+		fs.BoolVar(&o.fail, "fail", false, "exit 4 for a 4xx response, 5 for a 5xx, and 3 if there was no response at all, like curl -f; for health checks")
+		fs.IntVar(&o.maxIdle, "max-idle", pool.DefaultMaxIdle, "idle connections to keep open per host for later requests")
+		fs.DurationVar(&o.idleTimeout, "idle-timeout", 90*time.Second, "close a kept connection after it's been idle this long (0 means never)")
+		fs.BoolVar(&o.follow, "L", false, "follow redirects, and print the chain of them, with each hop's status and time, to stderr")
+		fs.IntVar(&o.maxRedirects, "max-redirects", 10, "with -L, the most redirects to follow; coming back to a request already made, the same method and URL, is an error too")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			set := map[string]bool{}
+			fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+			if len(vars) > 0 {
+				if err := vars.apply(&o, args); err != nil {
+					return cli.Usagef("%w", err)
+				}
+			}
+
+			// sendreq [<URL> [<PATH>...]]: the paths are requested after the URL, on the same connection.
+			var paths []string
+			if len(args) > 1 {
+				args, paths = args[:1], args[1:]
+			}
+			for _, path := range paths {
+				if !strings.HasPrefix(path, "/") {
+					return cli.Usagef("arguments after the URL are paths on the same host, and must start with /; got %q", path)
+				}
+			}
+			if err := o.target(args, set); err != nil {
+				return err
+			}
+			if sni != "" {
+				if !o.tls {
+					return cli.Usagef("-sni is the server name for the TLS handshake; use an https URL or -tls")
+				}
+				if strings.ContainsAny(sni, ":/ ") || net.ParseIP(sni) != nil {
+					return cli.Usagef("invalid -sni %q: want a host name, like example.com", sni)
+				}
+				o.sni = hostOverride{o.host, sni}
+			}
+			if hostHeader != "" {
+				switch {
+				case indexHeader(o.headers, "Host") >= 0:
+					return cli.Usagef("-host-header and -H 'Host: ...' both set the Host header; pick one")
+				case !validAuthority(hostHeader):
+					return cli.Usagef("invalid -host-header %q: want a host name, with an optional port, like example.com or example.com:8080", hostHeader)
+				}
+				o.hostHeader = hostOverride{o.host, hostHeader}
+			}
+			if localAddr != "" {
+				var err error
+				switch o.sock.local, err = parseLocalAddr(localAddr); {
+				case err != nil:
+					return cli.Usagef("invalid -local-addr %q: %v", localAddr, err)
+				case o.unix != "":
+					return cli.Usagef("-local-addr is for TCP connections, and -unix doesn't make one")
+				}
+			}
+			o.sock.nagle = !noDelay
+			if o.sock.keepAlive < 0 {
+				return cli.Usagef("-keepalive-interval can't be negative; 0 turns keepalive off")
+			}
+			if btoi(proxy != "")+btoi(socks != "")+btoi(o.unix != "") > 1 {
+				return cli.Usagef("-proxy, -socks5, and -unix are exclusive; pick one")
+			}
+			if proxy != "" {
+				var err error
+				if o.proxy, err = parseProxy(proxy); err != nil {
+					return cli.Usagef("invalid -proxy %q: %v", proxy, err)
+				}
+			}
+			if socks != "" {
+				var err error
+				if o.socks, err = parseSOCKS(socks); err != nil {
+					return cli.Usagef("invalid -socks5 %q: %v", socks, err)
+				}
+			}
+			switch {
+			case caFile != "" && o.insecure:
+				return cli.Usagef("-cacert says which CAs to trust, and -insecure says not to check; pick one")
+			case caFile != "":
+				var err error
+				if o.roots, err = loadCABundle(caFile); err != nil {
+					return cli.Usagef("-cacert: %v", err)
+				}
+			}
+			switch {
+			case keyFile != "" && certFile == "":
+				return cli.Usagef("-key is the private key for -cert's certificate; give the certificate with -cert")
+			case certFile != "":
+				var err error
+				if o.cert, err = loadClientCert(certFile, keyFile); err != nil {
+					return cli.Usagef("-cert: %v", err)
+				}
+			}
+			switch {
+			case dnsTTL <= 0:
+				return cli.Usagef("-dns-ttl must be positive; to look the host up every time, use -no-dns-cache")
+			case !noDNSCache:
+				o.dnsCache = newDNSCache(dnsTTL)
+			}
+			if resolver != "" {
+				var err error
+				if o.resolver, err = newResolver(resolver); err != nil {
+					return cli.Usagef("invalid -resolver: %v", err)
+				}
+			}
+			switch n := btoi(set["d"]) + btoi(set["data-file"]) + btoi(o.dataStdin) + btoi(len(o.form) > 0) + btoi(len(o.values) > 0); {
+			case n > 1:
+				return cli.Usagef("-d, -data-file, -data-stdin, -F, and -form are exclusive; pick one")
+			case n == 1:
+				o.hasBody = true // even for -d '': an empty body still gets a Content-Length: 0.
+				if len(o.form) > 0 {
+					o.boundary = newBoundary()
+				}
+				if !set["method"] {
+					o.method = http.MethodPost
+				}
+			}
+			if sign != "" {
+				if o.user != "" || indexHeader(o.headers, "Authorization") >= 0 || o.dataStdin {
+					return cli.Usagef("-sign puts its signature in the Authorization header, over a body it reads first: it doesn't go with -u, -H 'Authorization: ...', or -data-stdin")
+				}
+				var err error
+				if o.signer, err = parseSigner(sign); err != nil {
+					return cli.Usagef("invalid -sign: %v", err)
+				}
+			}
+			if o.expectContinue && !o.hasBody {
+				return cli.Usagef("-expect-continue needs a body to hold back; give one with -d, -data-file, -data-stdin, -F, or -form")
+			}
+			if o.hasBody && (indexHeader(o.headers, "Content-Length") >= 0 || indexHeader(o.headers, "Transfer-Encoding") >= 0) {
+				return cli.Usagef("-H can't set Content-Length or Transfer-Encoding along with a body; sendreq frames the body itself")
+			}
+			switch {
+			case o.duration < 0:
+				return cli.Usagef("-duration can't be negative")
+			case o.duration > 0 && !set["n"]:
+				o.count = 0 // as many as fit in -duration.
+			case o.count < 1:
+				return cli.Usagef("-n must be at least 1")
+			}
+			if rate != "" {
+				var err error
+				if o.rate, err = parseRate(rate); err != nil {
+					return cli.Usagef("invalid -rate %q: %v", rate, err)
+				}
+			}
+			o.json = env.JSON
+			o.compressed = o.compressed || o.compressedRaw
+			if !slices.Contains(outputModes, o.output) {
+				return cli.Usagef("-o must be one of %s; got %q", strings.Join(outputModes, ", "), o.output)
+			}
+			if o.download != "" {
+				switch {
+				case o.bench:
+					return cli.Usagef("-O doesn't go with -bench, which doesn't keep the responses")
+				case len(paths) > 0 || o.count != 1:
+					return cli.Usagef("-O saves one response; it can't be given extra paths, -n, or -duration")
+				case o.output == "raw":
+					return cli.Usagef("-O doesn't go with -o raw, which keeps the whole response")
+				}
+			}
+			if cache || cacheFile != "" {
+				if o.bench {
+					return cli.Usagef("-cache doesn't go with -bench")
+				}
+				var err error
+				if cacheFile == "" {
+					if cacheFile, err = defaultCachePath(); err != nil {
+						return fmt.Errorf("-cache: %w", err)
+					}
+				}
+				if o.cache, err = loadCache(cacheFile); err != nil {
+					return fmt.Errorf("-cache: %w", err)
+				}
+			}
+			if o.pipeline && (o.bench || o.follow || o.download != "" || o.rate > 0 || o.duration > 0 || o.dataStdin || o.expectContinue || o.cache != nil) {
+				return cli.Usagef("-pipeline doesn't go with -bench, -L, -O, -rate, -duration, -data-stdin, -expect-continue, or -cache")
+			}
+			if o.http10 && (o.expectContinue || o.pipeline) {
+				return cli.Usagef("-http1.0 doesn't go with -expect-continue or -pipeline, which are HTTP/1.1's")
+			}
+			if len(o.trailers) > 0 && (!o.hasBody || o.http10) {
+				return cli.Usagef("-trailer needs a request body, sent chunked, which -http1.0 can't do")
+			}
+			if o.http2 && (o.http10 || o.expectContinue || o.pipeline || o.download != "" || o.output == "raw") {
+				return cli.Usagef("-http2 doesn't go with -http1.0, -expect-continue, -pipeline, -O, or -o raw")
+			}
+			if o.http2 && o.proxy != nil && !o.tls {
+				return cli.Usagef("-http2 can't send plain http through -proxy; an https URL goes through a tunnel")
+			}
+			if verbose {
+				if o.bench {
+					return cli.Usagef("-v doesn't go with -bench: the traces of all the connections at once would be no use")
+				}
+				o.trace = newTracer(env.Stderr)
+			}
+			if har != "" {
+				if o.bench || o.pipeline {
+					return cli.Usagef("-har doesn't go with -bench or -pipeline")
+				}
+				o.har = newHAR(har)
+			}
+			if requestsFile != "" {
+				if len(paths) > 0 || o.hasBody || o.bench || o.pipeline || set["n"] || o.rate > 0 || o.duration > 0 || set["method"] {
+					return cli.Usagef("-requests runs each of the file's requests once, as the file says: it doesn't go with extra paths, a body, -method, -bench, -pipeline, -n, -rate, or -duration")
+				}
+				var err error
+				if o.batch, err = loadRequests(requestsFile, vars); err != nil {
+					return cli.Usagef("-requests: %w", err)
+				}
+			}
+			if o.interactive && (len(paths) > 0 || o.bench || o.pipeline || o.batch != nil || set["n"] || o.rate > 0 || o.duration > 0 || o.dataStdin || o.follow || o.download != "") {
+				return cli.Usagef("-i sends one request at a time, as its commands say: it doesn't go with extra paths, -bench, -pipeline, -requests, -n, -rate, -duration, -data-stdin (stdin is for the commands), -L, or -O")
+			}
+			if expectStatus != "" {
+				var err error
+				if expect.status, err = parseStatuses(expectStatus); err != nil {
+					return cli.Usagef("invalid -expect-status: %v", err)
+				}
+			}
+			if len(expect.status) > 0 || len(expect.bodyContains) > 0 || len(expect.headers) > 0 {
+				switch {
+				case o.bench || o.pipeline:
+					return cli.Usagef("-expect-* checks each response as it's printed; they don't go with -bench or -pipeline")
+				case len(expect.bodyContains) > 0 && o.download != "":
+					return cli.Usagef("-expect-body-contains doesn't go with -O, which saves the body instead of keeping it")
+				}
+				o.expect = &expect
+			}
+			if jsonPath != "" {
+				if o.json || set["o"] || o.download != "" || o.bench || o.pipeline || o.websocket || o.sse || o.replay != "" {
+					return cli.Usagef("-jsonpath prints one value from each response's body: it doesn't go with -json, -o, -O, -bench, -pipeline, -ws, -sse, or -replay")
+				}
+				var err error
+				if o.jsonPath, err = parseJSONPath(jsonPath); err != nil {
+					return cli.Usagef("invalid -jsonpath: %v", err)
+				}
+			}
+			if record != "" {
+				if o.bench || o.pipeline || o.http2 || o.dataStdin || o.websocket || o.sse || o.replay != "" {
+					return cli.Usagef("-record saves each request and response as HTTP/1.1 text: it doesn't go with -bench, -pipeline, -http2, -data-stdin (the body would be gone), -ws, -sse, or -replay")
+				}
+				var err error
+				if o.record, err = newFixtureDir(record); err != nil {
+					return cli.Usagef("-record: %w", err)
+				}
+			}
+			if o.replay != "" && (len(paths) > 0 || o.hasBody || set["method"] || o.batch != nil || o.interactive || o.websocket || o.sse || o.bench || o.pipeline || set["n"] || o.rate > 0 || o.duration > 0 || o.follow || o.download != "" || o.har != nil || o.cache != nil || o.expect != nil) {
+				return cli.Usagef("-replay sends each fixture's request once, as it was recorded: it doesn't go with extra paths, a body, -method, -requests, -i, -ws, -sse, -bench, -pipeline, -n, -rate, -duration, -L, -O, -har, -cache, or -expect-*")
+			}
+			if len(o.replayIgnore) > 0 && o.replay == "" {
+				return cli.Usagef("-replay-ignore is for -replay")
+			}
+			if o.websocket && (len(paths) > 0 || o.hasBody || set["method"] || o.http2 || o.http10 || o.bench || o.pipeline || o.batch != nil || o.interactive || set["n"] || o.rate > 0 || o.duration > 0 || o.follow || o.download != "" || o.har != nil || o.cache != nil || o.expect != nil) {
+				return cli.Usagef("-ws opens one WebSocket, with a GET, and talks over it until it closes: it doesn't go with extra paths, a body, -method, -http2, -http1.0, -bench, -pipeline, -requests, -i, -n, -rate, -duration, -L, -O, -har, -cache, or -expect-*")
+			}
+			if o.sse && (len(paths) > 0 || o.hasBody || set["method"] || o.websocket || o.http2 || o.http10 || o.bench || o.pipeline || o.batch != nil || o.interactive || set["n"] || o.rate > 0 || o.duration > 0 || o.follow || o.download != "" || o.har != nil || o.cache != nil || o.expect != nil || o.compressed) {
+				return cli.Usagef("-sse reads one event stream, with a GET, for as long as it lasts: it doesn't go with extra paths, a body, -method, -ws, -http2, -http1.0, -bench, -pipeline, -requests, -i, -n, -rate, -duration, -L, -O, -har, -cache, -expect-*, or -compressed")
+			}
+			if o.urls {
+				if len(paths) > 0 || o.dataStdin || o.bench || o.pipeline || o.batch != nil || o.interactive || o.websocket || o.sse || o.replay != "" || set["n"] || o.rate > 0 || o.duration > 0 || o.download != "" || o.jsonPath != nil || o.record != nil || o.har != nil || o.expect != nil {
+					return cli.Usagef("-urls requests each URL from stdin once, and prints a line for it: it doesn't go with extra paths, -data-stdin, -bench, -pipeline, -requests, -i, -ws, -sse, -replay, -n, -rate, -duration, -O, -jsonpath, -record, -har, or -expect-*")
+				}
+				if o.concurrency < 1 {
+					return cli.Usagef("-c must be at least 1")
+				}
+				if !set["max-idle"] {
+					o.maxIdle = o.concurrency
+				}
+				if !set["log-level"] {
+					env.Level.Set(slog.LevelWarn) // the result lines say how each went.
+				}
+			}
+			if o.fail {
+				switch {
+				case o.expect != nil && len(o.expect.status) > 0:
+					return cli.Usagef("-fail and -expect-status both decide which statuses are failures; pick one")
+				case o.bench || o.pipeline || o.batch != nil || o.urls || o.replay != "" || o.interactive || o.websocket || o.sse:
+					return cli.Usagef("-fail's exit code is for how a request went: it doesn't go with -bench, -pipeline, -requests, -urls, -replay, -i, -ws, or -sse")
+				}
+			}
+			if o.retries < 0 {
+				return cli.Usagef("-retries can't be negative")
+			}
+			if o.bench {
+				if o.concurrency < 1 {
+					return cli.Usagef("-c must be at least 1")
+				}
+				if o.dataStdin {
+					return cli.Usagef("-bench can't send -data-stdin: stdin can only be read once")
+				}
+				if !set["max-idle"] {
+					o.maxIdle = o.concurrency // a connection for each worker to come back to.
+				}
+				if !set["log-level"] {
+					env.Level.Set(slog.LevelWarn) // a log line per request would drown the report.
+				}
+			}
+			if o.user != "" && !strings.Contains(o.user, ":") {
+				pass, err := promptPassword(ctx, o.user)
+				if err != nil {
+					return err
+				}
+				o.user += ":" + pass
+			}
+			return run(ctx, env, o, paths)
+		}
+	},
+}
+
+type options struct {
+	host, path, method string
+	port               int
+	tls                bool
+	http10             bool        // -http1.0
+	http2              bool        // -http2
+	headers            headerFlags // -H
+	user               string      // -u or the URL's, as user:password
+	signer             *signer     // -sign; nil without it
+
+	data      string // -d
+	dataFile  string
+	dataStdin bool
+	form      formFlags   // -F
+	boundary  string      // for the -F body
+	values    formValues  // -form
+	hasBody   bool        // one of the five was given
+	trailers  headerFlags // -trailer: fields to send after the body
+
+	expectContinue bool
+	expectTimeout  time.Duration
+
+	proxy          *url.URL // -proxy
+	socks          *url.URL // -socks5
+	unix           string   // path to a unix socket, for -unix
+	resolver       *net.Resolver
+	dnsCache       *dnsCache // nil for -no-dns-cache
+	pins           pinFlags  // -resolve
+	sock           sockOpts
+	connectTimeout time.Duration
+	readTimeout    time.Duration
+
+	retries      int
+	retryBackoff time.Duration
+	retry5xx     bool
+	fail         bool // -fail
+
+	compressed    bool
+	compressedRaw bool // -compressed without the decompressing
+
+	cache      *validatorCache // for -cache; nil without it
+	validators validators      // to send, from the cache
+
+	cert         *tls.Certificate // -cert and -key: the client certificate for mutual TLS; nil without them
+	roots        *x509.CertPool   // -cacert: the CAs to trust; nil for the system's
+	insecure     bool             // -insecure: don't verify the server's certificate
+	sni          hostOverride     // -sni
+	hostHeader   hostOverride     // -host-header
+	trace        *tracer          // -v; nil without it
+	har          *harLog          // -har; nil without it
+	expect       *expectations    // -expect-*; nil without them
+	record       *fixtureDir      // -record; nil without it
+	replay       string           // -replay: the fixtures directory
+	replayIgnore stringFlags      // -replay-ignore
+	batch        []stanza         // -requests
+	interactive  bool             // -i
+	websocket    bool             // -ws, or a ws:// or wss:// URL
+	sse          bool             // -sse
+	urls         bool             // -urls
+	output       string           // -o
+	download     string           // -O: the file to save the body to
+	json         bool             // -json, which replaces -o
+	jsonPath     jsonPath         // -jsonpath, which replaces -o too; nil without it
+	timing       bool
+	tlsInfo      bool // -tls-info
+	bench        bool
+	pipeline     bool
+	concurrency  int           // -c
+	count        int           // -n; 0 for no limit
+	rate         time.Duration // between requests, for -rate
+	duration     time.Duration
+	maxIdle      int
+	idleTimeout  time.Duration
+	follow       bool // -L
+	maxRedirects int
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// target fills in where to send the request from the optional URL argument, like
+// https://example.com:8443/foo?bar=1. flags given explicitly (in set) override the URL's parts, so the old
+// -host/-port/-path invocations work unchanged.
+func (o *options) target(args []string, set map[string]bool) error {
+	// -host can be an IPv6 address, bracketed or not; o.host never is.
+	o.host = strings.TrimSuffix(strings.TrimPrefix(o.host, "["), "]")
+	switch len(args) {
+	case 0:
+	case 1:
+		u, err := url.Parse(args[0])
+		if err != nil {
+			return cli.Usagef("invalid URL: %w", err)
+		}
+		switch u.Scheme {
+		case "http", "https":
+		case "ws", "wss":
+			// a WebSocket URL is its http or https one, with -ws.
+			u.Scheme = strings.Replace(u.Scheme, "ws", "http", 1)
+			o.websocket = true
+		default:
+			return cli.Usagef("invalid URL %q: want an http://, https://, ws://, or wss:// URL", args[0])
+		}
+		if u.Hostname() == "" {
+			return cli.Usagef("invalid URL %q: missing host", args[0])
+		}
+		if !set["host"] {
+			o.host = u.Hostname()
+		}
+		if !set["tls"] {
+			o.tls = u.Scheme == "https"
+		}
+		if !set["port"] {
+			o.port = 80
+			if o.tls {
+				o.port = 443
+			}
+			if p := u.Port(); p != "" {
+				if o.port, err = strconv.Atoi(p); err != nil {
+					return cli.Usagef("invalid port in URL %q", args[0])
+				}
+			}
+		}
+		if !set["path"] {
+			o.path = u.RequestURI() // the path, escaped as given, and the query; "/" if there's neither.
+		}
+		if u.User != nil && !set["u"] {
+			// like curl, a user in the URL without a password gets an empty one, not a prompt.
+			pass, _ := u.User.Password()
+			o.user = u.User.Username() + ":" + pass
+		}
+		return nil
+	default:
+		return cli.Usagef("expected at most one URL; got %d arguments", len(args))
+	}
+
+	// no URL: -tls alone picks port 443, and port 443 alone means TLS.
+	switch {
+	case o.tls && !set["port"]:
+		o.port = 443
+	case o.port == 443 && !set["tls"]:
+		o.tls = true
+	}
+	return nil
+}
+
+/* design note: a health check in a script, or an orchestrator's probe, only gets the exit code, and 1 for every
+   kind of failure can't tell "the server said no" from "there's no server". -fail splits them, as curl -f does: a
+   4xx means the request was wrong (or the route is gone), a 5xx that the server is broken, and no response that it
+   couldn't be reached. 2 would be the obvious code for the last, but it's every tool's code for bad flags, and a
+   probe with a typo in it shouldn't look like a server that's down.
+*/
+
+// -fail's exit codes.
+const (
+	exitNoResponse  = 3 // the connection failed, or timed out, after any retries
+	exitClientError = 4 // a 4xx response
+	exitServerError = 5 // a 5xx response
+)
+
+// failCode is -fail's exit code for a response with status, 400 or more.
+func failCode(status int) int {
+	if status >= 500 {
+		return exitServerError
+	}
+	return exitClientError
+}
+
+func run(ctx context.Context, env *cli.Env, o options, paths []string) (err error) {
+	// the URL's request, then one for each extra path on the same host, all -n times over (or until -duration is up).
+	requests := []options{o}
+	for _, path := range paths {
+		next := o
+		next.path = path
+		requests = append(requests, next)
+	}
+	if o.bench {
+		return bench(ctx, env, o, requests)
+	}
+	if o.pipeline {
+		return pipeline(ctx, env, o, requests)
+	}
+
+	if o.har != nil {
+		// whatever was recorded is worth keeping, even if a request failed.
+		defer func() {
+			if herr := o.har.save(); err == nil {
+				err = herr
+			}
+		}()
+	}
+	if o.batch != nil {
+		return batch(ctx, env, o)
+	}
+	if o.replay != "" {
+		return replay(ctx, env, o)
+	}
+	if o.urls {
+		return fanOut(ctx, env, o, os.Stdin)
+	}
+	if o.interactive {
+		return repl(ctx, env, o, os.Stdin)
+	}
+	if o.websocket {
+		return websocket(ctx, env, o, os.Stdin)
+	}
+	if o.sse {
+		return sse(ctx, env, o)
+	}
+	w := bufio.NewWriter(env.Stdout)
+	defer w.Flush()
+	c := newClient(o)
+	defer c.close()
+	c.progress = env.Stderr
+	c.out = w
+	p := o.newPacer(o.count * len(requests))
+	for i := 0; p.next(ctx); i++ {
+		resp, err := fetch(ctx, c, w, env.Stderr, requests[i%len(requests)])
+		if err != nil {
+			if o.fail && resp == nil {
+				return cli.WithExitCode(exitNoResponse, err)
+			}
+			return err
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		if o.fail && resp.status >= 400 {
+			return cli.WithExitCode(failCode(resp.status), fmt.Errorf("server said %s", resp.head[0]))
+		}
+	}
+	if o.cache != nil {
+		if err := o.cache.save(); err != nil {
+			return fmt.Errorf("-cache: %w", err)
+		}
+	}
+	return nil
+}
+
+// fetch makes o's request and prints the response, following redirects with -L, checks the last response against
+// -expect-*, and returns it, if there was one. -timing tables, the chain of redirects, and unmet expectations go
+// to stderr.
+func fetch(ctx context.Context, c *client, w, stderr io.Writer, o options) (*rawResponse, error) {
+	// a redirect back to the same URL with another method isn't a loop: a POST answered by a 303 to itself, for the
+	// GET that shows the result, is the post/redirect/get pattern.
+	visited := map[string]bool{o.method + " " + o.url().String(): true}
+	var hops []hop
+	defer func() {
+		// a chain of one is no chain, unless it's a redirect we wouldn't follow, like one back to itself.
+		if len(hops) > 1 || len(hops) == 1 && hops[0].next != "" {
+			if f, ok := w.(*bufio.Writer); ok {
+				f.Flush()
+			}
+			writeChain(stderr, hops)
+		}
+	}()
+	for redirects := 0; ; redirects++ {
+		key := o.url().String()
+		if o.cache != nil {
+			o.validators = o.cache.get(key, o.method)
+		}
+		start := time.Now()
+		resp, err := c.doAuth(ctx, o)
+		if err != nil {
+			return nil, err
+		}
+		if o.follow {
+			hops = append(hops, hop{status: resp.status, method: o.method, url: key, took: time.Since(start)})
+		}
+		wireSize := len(resp.body)
+		if o.record != nil {
+			if err := o.record.save(o, resp); err != nil {
+				return resp, fmt.Errorf("-record: %w", err)
+			}
+		}
+		if o.cache != nil && conditional(o.method) {
+			if resp.status == http.StatusNotModified && o.validators != (validators{}) {
+				fmt.Fprintf(stderr, "%s: not modified since the cached response\n", key)
+			}
+			o.cache.update(key, resp)
+		}
+		if o.timing {
+			writeTiming(stderr, o, resp.timing)
+		}
+		if o.tlsInfo && !o.json && resp.tls != nil && !resp.timing.reused {
+			writeTLSInfo(stderr, o, resp.tls, time.Now())
+		}
+		if o.compressed && !o.compressedRaw && !resp.streamed {
+			if err := decodeBody(resp); err != nil {
+				return resp, err
+			}
+		}
+		if o.har != nil {
+			o.har.add(o, resp, start, wireSize)
+		}
+		location := resp.headers.Get("Location")
+		if !o.follow || !isRedirect(resp.status) || location == "" {
+			if err := writeResponse(w, resp, o, true); err != nil {
+				return resp, err
+			}
+			if f, ok := w.(*bufio.Writer); ok && o.expect != nil {
+				f.Flush() // so the response comes before what's wrong with it, on a terminal that shows both.
+			}
+			return resp, o.expect.check(stderr, o, resp)
+		}
+
+		if err := writeResponse(w, resp, o, false); err != nil {
+			return resp, err
+		}
+		next, err := o.redirect(resp.status, location)
+		if err != nil {
+			return resp, err
+		}
+		u := next.url().String()
+		hops[len(hops)-1].next = u
+		if redirects == o.maxRedirects {
+			return resp, fmt.Errorf("too many redirects (-max-redirects %d)", redirects)
+		}
+		if visited[next.method+" "+u] {
+			return resp, fmt.Errorf("redirect loop: %s %s was already requested", next.method, u)
+		}
+		visited[next.method+" "+u] = true
+		slog.InfoContext(ctx, "main", "message", "following redirect", "status", resp.status, "location", u, "method", next.method)
+		o = next
+	}
+}
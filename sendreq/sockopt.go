@@ -0,0 +1,90 @@
+package sendreq
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+/* design note: a TCP connection has knobs most clients never touch, and they show up in latency more than you'd
+   think. Nagle's algorithm (RFC 896) holds back a small write while an earlier one is still unacknowledged, to
+   bundle it with whatever comes next: good for a terminal sending a keystroke at a time, bad for a request whose
+   head and body go out in separate writes, since the body can sit waiting out the server's delayed ACK, 40ms or so.
+   Go turns it off (TCP_NODELAY) on every connection; -nodelay=false turns it back on, to see the difference.
+
+   keepalive probes are empty segments sent on a connection that's gone quiet, so a peer that vanished (a laptop
+   closed, a NAT that forgot the mapping) is noticed, and a middlebox sees traffic and keeps the connection open. Go
+   sends them every 15s; -keepalive-interval changes that, and 0 turns them off. -local-addr binds the connection's
+   end to an address (to pick the interface, or the source address a firewall expects) and, optionally, a port.
+
+   they apply to every TCP connection sendreq opens, the proxy's included. a fixed local port can only be used by one
+   connection at a time, and stays in TIME_WAIT for a while after it closes, so it's for one connection, not -n.
+*/
+
+// sockOpts are the socket options for the TCP connections sendreq opens.
+type sockOpts struct {
+	nagle     bool          // -nodelay=false: Nagle's algorithm back on
+	keepAlive time.Duration // -keepalive-interval; 0 for no probes
+	local     *net.TCPAddr  // -local-addr; nil to let the system pick
+}
+
+// dialer is a dialer for TCP connections with s's keepalive and local address.
+func (s sockOpts) dialer() *net.Dialer {
+	d := &net.Dialer{KeepAlive: s.keepAlive}
+	if s.keepAlive == 0 {
+		d.KeepAlive = -1 // net.Dialer's zero is its default, not off
+	}
+	if s.local != nil {
+		d.LocalAddr = s.local
+	}
+	return d
+}
+
+// apply sets the options that have to be set on conn after it's connected.
+func (s sockOpts) apply(conn net.Conn) error {
+	tc, ok := conn.(*net.TCPConn)
+	if !ok || !s.nagle {
+		return nil // Go has already set TCP_NODELAY
+	}
+	if err := tc.SetNoDelay(!s.nagle); err != nil {
+		return fmt.Errorf("setting TCP_NODELAY: %w", err)
+	}
+	return nil
+}
+
+// families keeps the ips a connection from s's local address can reach: the ones in its family, if it has an IP.
+func (s sockOpts) families(ips []net.IP) []net.IP {
+	if s.local == nil || s.local.IP == nil {
+		return ips
+	}
+	var kept []net.IP
+	for _, ip := range ips {
+		if (ip.To4() == nil) == (s.local.IP.To4() == nil) {
+			kept = append(kept, ip)
+		}
+	}
+	return kept
+}
+
+// parseLocalAddr parses -local-addr: an IP address, an IP address and port, or just a port, like 192.0.2.10,
+// [2001:db8::10]:50000, or :50000.
+func parseLocalAddr(s string) (*net.TCPAddr, error) {
+	if ip := net.ParseIP(s); ip != nil {
+		return &net.TCPAddr{IP: ip}, nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return nil, fmt.Errorf("want an IP address, with an optional port, like 192.0.2.10 or 192.0.2.10:50000")
+	}
+	a := &net.TCPAddr{}
+	if host != "" {
+		if a.IP = net.ParseIP(host); a.IP == nil {
+			return nil, fmt.Errorf("%q isn't an IP address", host)
+		}
+	}
+	if a.Port, err = strconv.Atoi(port); err != nil || a.Port < 0 || a.Port > 65535 {
+		return nil, fmt.Errorf("%q isn't a port number", port)
+	}
+	return a, nil
+}
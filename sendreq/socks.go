@@ -0,0 +1,187 @@
+package sendreq
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the client side of SOCKS5 (RFC 1928); the socksd tool is the server side, and its socks.go has the message
+// layouts. the client offers the authentication methods it can do, runs the one the server picks (RFC 1929 for
+// username/password), then asks for a connection to the target. once the server says it's connected, the socket is a
+// plain pipe to the target, and HTTP (or TLS) runs over it as if we'd dialed directly.
+const (
+	socksVersion   = 5
+	socksUserPass  = 1 // the username/password sub-negotiation's version
+	socksNoAuth    = 0x00
+	socksAuthUser  = 0x02
+	socksNoMethod  = 0xff
+	socksConnect   = 0x01
+	socksIPv4      = 0x01
+	socksDomain    = 0x03
+	socksIPv6      = 0x04
+	socksSucceeded = 0x00
+	socksMaxLen    = 255
+)
+
+// socksReplies are the messages for the server's reply codes.
+var socksReplies = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// parseSOCKS parses -socks5: [user:password@]host[:port], with an optional socks5:// in front.
+func parseSOCKS(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "socks5://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "socks5" && u.Scheme != "socks5h" {
+		return nil, fmt.Errorf("want a socks5:// proxy; got %s://", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), defaultProxyPort)
+	}
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		if len(u.User.Username()) > socksMaxLen || len(pass) > socksMaxLen {
+			return nil, fmt.Errorf("user name and password can be at most %d bytes each", socksMaxLen)
+		}
+	}
+	return u, nil
+}
+
+// dialSOCKS connects to the target through the -socks5 proxy, adding to t. dns and connect are the proxy's, and
+// connect includes the SOCKS handshake.
+func dialSOCKS(ctx context.Context, o options, t *dialTiming) (net.Conn, error) {
+	port, _ := strconv.Atoi(o.socks.Port()) // parseSOCKS made sure there's one.
+	conn, err := dialTCP(ctx, o, o.socks.Hostname(), port, t)
+	if err != nil {
+		return nil, fmt.Errorf("socks5 proxy %s: %w", o.socks.Host, err)
+	}
+
+	start := time.Now()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	if err := socksHandshake(conn, o.socks.User, o.host, o.port); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("socks5 proxy %s: %w", o.socks.Host, err)
+	}
+	t.connect += time.Since(start)
+	return conn, nil
+}
+
+// socksHandshake asks the SOCKS5 server on rw to connect to host:port, authenticating as user if it's set.
+func socksHandshake(rw io.ReadWriter, user *url.Userinfo, host string, port int) error {
+	// offer only what we can do: username/password when we have them, no authentication otherwise.
+	method := byte(socksNoAuth)
+	if user != nil {
+		method = socksAuthUser
+	}
+	if _, err := rw.Write([]byte{socksVersion, 1, method}); err != nil {
+		return fmt.Errorf("sending greeting: %w", err)
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(rw, reply[:]); err != nil {
+		return fmt.Errorf("reading method selection: %w", err)
+	}
+	switch {
+	case reply[0] != socksVersion:
+		return fmt.Errorf("not a SOCKS5 server (version %d)", reply[0])
+	case reply[1] == socksNoMethod && user == nil:
+		return fmt.Errorf("the server wants authentication; give a user:password")
+	case reply[1] != method:
+		return fmt.Errorf("the server refused the authentication method we offered")
+	}
+
+	if method == socksAuthUser {
+		// RFC 1929: VER ULEN UNAME PLEN PASSWD, answered with VER STATUS, where 0 is success.
+		pass, _ := user.Password()
+		b := []byte{socksUserPass, byte(len(user.Username()))}
+		b = append(b, user.Username()...)
+		b = append(b, byte(len(pass)))
+		b = append(b, pass...)
+		if _, err := rw.Write(b); err != nil {
+			return fmt.Errorf("sending credentials: %w", err)
+		}
+		if _, err := io.ReadFull(rw, reply[:]); err != nil {
+			return fmt.Errorf("reading authentication status: %w", err)
+		}
+		if reply[1] != 0 {
+			return fmt.Errorf("authentication failed for user %q", user.Username())
+		}
+	}
+
+	// the request: VER CMD RSV ATYP DST.ADDR DST.PORT. a name goes as is, so the proxy looks it up; that's what
+	// lets a Tor proxy reach .onion names, and keeps lookups from leaking around an ssh -D tunnel.
+	b := []byte{socksVersion, socksConnect, 0}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		if ip.Is4() {
+			b = append(b, socksIPv4)
+		} else {
+			b = append(b, socksIPv6)
+		}
+		b = append(b, ip.AsSlice()...)
+	} else {
+		if len(host) > socksMaxLen {
+			return fmt.Errorf("host name %q is too long for SOCKS", host)
+		}
+		b = append(b, socksDomain, byte(len(host)))
+		b = append(b, host...)
+	}
+	b = binary.BigEndian.AppendUint16(b, uint16(port))
+	if _, err := rw.Write(b); err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+
+	// the reply has the same layout, with a reply code for CMD and the proxy's own address, which we don't need.
+	var hdr [4]byte
+	if _, err := io.ReadFull(rw, hdr[:]); err != nil {
+		return fmt.Errorf("reading reply: %w", err)
+	}
+	if hdr[1] != socksSucceeded {
+		msg, ok := socksReplies[hdr[1]]
+		if !ok {
+			msg = fmt.Sprintf("reply code %d", hdr[1])
+		}
+		return fmt.Errorf("connecting to %s: %s", net.JoinHostPort(host, strconv.Itoa(port)), msg)
+	}
+	var skip int
+	switch hdr[3] {
+	case socksIPv4:
+		skip = 4
+	case socksIPv6:
+		skip = 16
+	case socksDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(rw, n[:]); err != nil {
+			return fmt.Errorf("reading reply: %w", err)
+		}
+		skip = int(n[0])
+	default:
+		return fmt.Errorf("bad address type %d in reply", hdr[3])
+	}
+	if _, err := io.CopyN(io.Discard, rw, int64(skip+2)); err != nil { // the address, then the port
+		return fmt.Errorf("reading reply: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+package sendreq
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ekediala/cli"
+	"github.com/ekediala/httpwire"
+)
+
+/* design note: Server-Sent Events (the WHATWG HTML standard's EventSource) are a response that never ends: the
+   server answers a GET with "Content-Type: text/event-stream", and keeps writing events to the body as things
+   happen, each a few "field: value" lines ended by an empty line:
+
+	: comment lines, like this one, are often sent every so often just to keep the connection busy
+	event: price
+	id: 42
+	data: {"symbol": "ACME", "price": 13.37}
+
+   event names the event ("message" if it's left out), data lines are joined with newlines into the event's data,
+   id is the event's ID, and retry tells the client how many milliseconds to wait before reconnecting. an event
+   with no data isn't delivered.
+
+   -sse reads the stream as it comes, and prints each event the moment its empty line arrives (with -json, as one
+   JSON object per line, for jq). when the stream drops, it reconnects, after the server's retry (3s unless the
+   server says otherwise), and sends the last ID it got as Last-Event-ID, so a server that keeps history can pick up
+   where it left off. a connection that fails, or a response that isn't an event stream, counts against -retries; one
+   that works resets the count. a 204 is the server saying there's nothing more: the stream ends, without an error.
+*/
+
+// sseDefaultRetry is how long to wait before reconnecting, until the server says otherwise.
+const sseDefaultRetry = 3 * time.Second
+
+// sseEvent is one event from an event stream.
+type sseEvent struct {
+	Event string `json:"event"`
+	ID    string `json:"id,omitempty"`
+	Data  string `json:"data"`
+}
+
+// sseStream is the state that lasts across an -sse session's connections.
+type sseStream struct {
+	lastID string        // sent as Last-Event-ID on reconnecting
+	retry  time.Duration // how long to wait before reconnecting
+}
+
+// errStreamDone is a 204: the server doesn't want us to reconnect.
+var errStreamDone = errors.New("the server ended the stream with 204 No Content")
+
+// sse runs an -sse session: it reads o's event stream, printing each event to stdout, and reconnects when the
+// stream drops, until ctx is done, the server sends a 204, or -retries connections in a row fail.
+func sse(ctx context.Context, env *cli.Env, o options) error {
+	w := bufio.NewWriter(env.Stdout)
+	defer w.Flush()
+	s := &sseStream{lastID: Headers(o.headers).Get("Last-Event-ID"), retry: sseDefaultRetry}
+	failures := 0
+	for {
+		connected, err := s.read(ctx, o, w)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.Is(err, errStreamDone):
+			slog.InfoContext(ctx, "main", "message", err.Error())
+			return nil
+		case connected:
+			failures = 0
+		default:
+			failures++
+			if failures > o.retries {
+				return err
+			}
+		}
+		args := []any{"url", o.url().String(), "retrying in", s.retry.String()}
+		if err != nil {
+			args = append(args, "error", err.Error())
+		}
+		if s.lastID != "" {
+			args = append(args, "last-event-id", s.lastID)
+		}
+		slog.WarnContext(ctx, "main", append([]any{"message", "event stream dropped; reconnecting"}, args...)...)
+		select {
+		case <-time.After(s.retry):
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// read connects, and reads events from the stream until it ends. connected says whether it got as far as an event
+// stream, whatever happened after.
+func (s *sseStream) read(ctx context.Context, o options, w *bufio.Writer) (connected bool, err error) {
+	conn, _, err := dial(ctx, o)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	o.method, o.hasBody = http.MethodGet, false
+	defaults := []Header{{Key: "Accept", Value: "text/event-stream"}, {Key: "Cache-Control", Value: "no-cache"}}
+	if s.lastID != "" {
+		defaults = append(defaults, Header{Key: httpwire.AsTitle("Last-Event-ID"), Value: s.lastID})
+	}
+	// the stream's Last-Event-ID is the one to send now, not the one -H started it with.
+	o.headers = mergeHeaders(defaults, slices.DeleteFunc(slices.Clone(o.headers), func(h Header) bool { return h.Key == httpwire.AsTitle("Last-Event-ID") }))
+	head, _, _, err := o.request()
+	if err != nil {
+		return false, err
+	}
+	if _, err := io.WriteString(conn, head); err != nil {
+		return false, fmt.Errorf("sending request: %w", err)
+	}
+	slog.InfoContext(ctx, "main", "info", fmt.Sprintf("sent request:\n%s", head))
+
+	r := bufio.NewReader(conn)
+	if o.readTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(o.readTimeout))
+	}
+	resp, err := readFinalHead(r, http.MethodGet)
+	if err != nil {
+		return false, fmt.Errorf("reading response: %w", err)
+	}
+	conn.SetReadDeadline(time.Time{}) // a stream can go quiet for as long as nothing happens.
+	mediaType, _, _ := mime.ParseMediaType(resp.headers.Get("Content-Type"))
+	switch {
+	case resp.status == http.StatusNoContent:
+		return false, errStreamDone
+	case resp.status != http.StatusOK:
+		return false, fmt.Errorf("want an event stream, got %s", resp.head[0])
+	case mediaType != "text/event-stream":
+		return false, fmt.Errorf("want an event stream, got a %q response", mediaType)
+	}
+	slog.InfoContext(ctx, "main", "message", "reading the event stream", "status", resp.head[0])
+	body, _ := resp.bodyReader()
+	return true, s.readEvents(body, func(e sseEvent) error {
+		if o.json {
+			b, _ := json.Marshal(e)
+			w.Write(append(b, '\n'))
+		} else {
+			writeEvent(w, e)
+		}
+		return w.Flush()
+	})
+}
+
+// readEvents parses the event stream in body, calling deliver with each event as its empty line arrives. it
+// returns when body ends: io.EOF, the server closing a stream it gave no length, is nil.
+func (s *sseStream) readEvents(body io.Reader, deliver func(sseEvent) error) error {
+	sc := bufio.NewScanner(body)
+	sc.Buffer(nil, 1<<20)
+	sc.Split(scanSSELines)
+	var e sseEvent
+	var data strings.Builder
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			// the empty line ends the event; one with no data lines isn't one.
+			if data.Len() > 0 {
+				e.Data = strings.TrimSuffix(data.String(), "\n")
+				if e.Event == "" {
+					e.Event = "message"
+				}
+				e.ID = s.lastID
+				if err := deliver(e); err != nil {
+					return err
+				}
+			}
+			e = sseEvent{}
+			data.Reset()
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "": // a comment
+		case "event":
+			e.Event = value
+		case "data":
+			data.WriteString(value)
+			data.WriteByte('\n')
+		case "id":
+			if !strings.Contains(value, "\x00") {
+				s.lastID = value
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(value, 10, 32); err == nil {
+				s.retry = time.Duration(ms) * time.Millisecond
+			}
+		}
+	}
+	return sc.Err()
+}
+
+// scanSSELines is a bufio.SplitFunc for an event stream's lines, which end in CRLF, LF, or CR alone.
+func scanSSELines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		if data[i] == '\n' {
+			return i + 1, data[:i], nil
+		}
+		switch {
+		case i+1 < len(data):
+			if data[i+1] == '\n' {
+				return i + 2, data[:i], nil
+			}
+			return i + 1, data[:i], nil
+		case atEOF:
+			return i + 1, data[:i], nil
+		}
+		return 0, nil, nil // a CR at the end: it might be half a CRLF.
+	}
+	if atEOF && len(data) > 0 {
+		return len(data), data, nil // a last line with no end; the event it's part of never ends either.
+	}
+	return 0, nil, nil
+}
+
+// writeEvent prints an event: its name and ID, then its data, then an empty line.
+func writeEvent(w io.Writer, e sseEvent) {
+	fmt.Fprintf(w, "event: %s\n", e.Event)
+	if e.ID != "" {
+		fmt.Fprintf(w, "id: %s\n", e.ID)
+	}
+	for _, line := range strings.Split(e.Data, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,145 @@
+package sendreq
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+/* design note: reading a whole response before printing any of it is simple, and fine for an API's JSON, but a
+   response's body can be any size: a 4GB download read that way takes 4GB of memory, and nothing shows up until the
+   last byte is in. so when a response is going straight to the output, its body is copied there as it comes off the
+   connection, a buffer at a time, and flushed as it goes, the way -O copies to a file: the status line and headers
+   first, then the body, then any trailers. the framing (Content-Length, chunked, or the server hanging up) still
+   decides where the body ends, so the connection can carry the next request after.
+
+   streaming is only for a response that's printed once and then done with. anything that needs the whole body keeps
+   reading it whole: -json, -jsonpath, -o raw, -har, -record, -i, -expect-body-contains, a redirect -L will follow,
+   HTTP/2. so does a response that might be sent for again, a 5xx with -retry-5xx or a 401 that -u answers with
+   digest auth: printing it, then printing the retry's answer, would print two responses for one request. and once a
+   body has been partly printed, a connection that drops doesn't get the request retried, for the same reason.
+*/
+
+// printedError is a failure partway through a body that's already partly printed. sending the request again would
+// print its response twice, so it isn't.
+type printedError struct{ err error }
+
+func (e *printedError) Error() string { return e.err.Error() }
+func (e *printedError) Unwrap() error { return e.err }
+
+// streams reports whether resp, the response to o's request whose head has just been read, is printed as its body
+// arrives, rather than after it's all in.
+func (o options) streams(resp *rawResponse) bool {
+	switch {
+	case o.json || o.jsonPath != nil || o.output == "raw" || o.interactive || o.har != nil || o.record != nil ||
+		o.expect != nil && len(o.expect.bodyContains) > 0:
+		return false // they need the whole body
+	case o.follow && isRedirect(resp.status) && resp.headers.Get("Location") != "":
+		return false // -L follows it, and only prints its head
+	case o.retry5xx && (resp.status >= 500 || resp.status == http.StatusTooManyRequests),
+		o.user != "" && resp.status == http.StatusUnauthorized,
+		o.expectContinue && resp.status == http.StatusExpectationFailed:
+		return false // it might be sent for again
+	}
+	return true
+}
+
+// stream reads a response from r, and if it's one to stream, prints it to c.out as it arrives: the head, then the
+// body, decoded for -compressed, then the trailers, as writeResponse would. otherwise its body is read whole, as
+// usual.
+func (c *client) stream(r *bufio.Reader, o options) (*rawResponse, error) {
+	out := c.out
+	resp, err := readFinalHead(r, o.method)
+	if err != nil {
+		return nil, err
+	}
+	if !o.streams(resp) {
+		return resp, resp.readBody()
+	}
+	resp.streamed = true
+	body, _ := resp.bodyReader()
+	if o.output == "all" || o.output == "headers" {
+		writeHead(out, resp)
+	}
+	// the body goes to the output for all and body, and is read off the connection, and dropped, for the others.
+	dst := io.Discard
+	if o.output == "all" || o.output == "body" {
+		dst = out
+	}
+	end := &lastByte{w: dst}
+	if err := copyBody(end, body, resp, o, out); err != nil {
+		return resp, &printedError{fmt.Errorf("reading response body: %w", err)}
+	}
+	switch o.output {
+	case "all":
+		if len(resp.trailers) > 0 && end.n > 0 && end.last != '\n' {
+			fmt.Fprintln(out)
+		}
+		writeTrailers(out, resp)
+	case "headers":
+		writeTrailers(out, resp)
+	case "status":
+		fmt.Fprintln(out, resp.status)
+	}
+	return resp, nil
+}
+
+// copyBody copies body, resp's as it comes off the wire, to w, decoding it for -compressed, and flushing out after
+// each piece, so it shows up as it arrives.
+func copyBody(w io.Writer, body io.Reader, resp *rawResponse, o options, out io.Writer) error {
+	wire := bufio.NewReader(body)
+	src := io.Reader(wire)
+	if o.compressed && !o.compressedRaw {
+		// an empty body has nothing to decode, and a decoder would call it a truncated stream.
+		if _, err := wire.Peek(1); err == nil {
+			d, err := decoder(resp, wire)
+			if err != nil {
+				return err
+			}
+			src = d
+		}
+	}
+	flusher, _ := out.(*bufio.Writer)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := src.Read(buf)
+		if n > 0 {
+			if _, werr := w.Write(buf[:n]); werr != nil {
+				return werr
+			}
+			if flusher != nil {
+				if ferr := flusher.Flush(); ferr != nil {
+					return ferr
+				}
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	// whatever follows the end of a compressed stream is still part of the response, and has to be read off the
+	// connection before it can carry another one.
+	_, err := io.Copy(io.Discard, wire)
+	return err
+}
+
+// lastByte is a writer that remembers the last byte written through it, and how many there were.
+type lastByte struct {
+	w    io.Writer
+	n    int64
+	last byte
+}
+
+func (l *lastByte) Write(p []byte) (int, error) {
+	n, err := l.w.Write(p)
+	if n > 0 {
+		l.n += int64(n)
+		l.last = p[n-1]
+	}
+	return n, err
+}
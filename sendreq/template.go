@@ -0,0 +1,102 @@
+package sendreq
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// varFlags collects -var key=value flags: values for the {{key}} placeholders in a request, so one command line can
+// be replayed against another host, ID, or token by changing a -var, not by editing quoted JSON in the middle of it.
+type varFlags map[string]string
+
+func (v *varFlags) String() string {
+	s := make([]string, 0, len(*v))
+	for k, val := range *v {
+		s = append(s, k+"="+val)
+	}
+	return strings.Join(s, ", ")
+}
+
+func (v *varFlags) Set(s string) error {
+	k, val, ok := strings.Cut(s, "=")
+	if k = strings.TrimSpace(k); !ok || k == "" || strings.ContainsAny(k, "{}") {
+		return fmt.Errorf("%q should be of form key=value", s)
+	}
+	if *v == nil {
+		*v = varFlags{}
+	}
+	(*v)[k] = val
+	return nil
+}
+
+// expand replaces each {{key}} in s, spaces inside the braces allowed, with key's value. a placeholder without a
+// -var is an error, rather than a request sent with the braces still in it. values go in as they are: a value
+// that looks like a placeholder itself isn't expanded again.
+func (v varFlags) expand(s string) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "{{")
+		if i < 0 {
+			break
+		}
+		j := strings.Index(s[i+2:], "}}")
+		if j < 0 {
+			break
+		}
+		key := strings.TrimSpace(s[i+2 : i+2+j])
+		val, ok := v[key]
+		if !ok {
+			return "", fmt.Errorf("no -var for {{%s}}", key)
+		}
+		b.WriteString(s[:i])
+		b.WriteString(val)
+		s = s[i+2+j+2:]
+	}
+	b.WriteString(s)
+	return b.String(), nil
+}
+
+// apply expands the placeholders in o's request, and in args, the URL and paths: the target, the headers, the
+// credentials, and the body, when it's on the command line. (a -data-file or -data-stdin body goes as it is.)
+func (v varFlags) apply(o *options, args []string) error {
+	fields := []*string{&o.host, &o.path, &o.user, &o.data}
+	for i := range args {
+		fields = append(fields, &args[i])
+	}
+	for i := range o.form {
+		fields = append(fields, &o.form[i].value, &o.form[i].path, &o.form[i].filename)
+	}
+	for _, f := range fields {
+		var err error
+		if *f, err = v.expand(*f); err != nil {
+			return err
+		}
+	}
+	for i, h := range o.headers {
+		value, err := v.expand(h.Value)
+		if err != nil {
+			return err
+		}
+		// -H checked the value it was given, but not what a -var put in it.
+		if strings.ContainsAny(value, "\r\n\x00") {
+			return fmt.Errorf("header %s: value can't contain CR, LF, or NUL, even from a -var", h.Key)
+		}
+		o.headers[i].Value = value
+	}
+	// -form keeps its fields escaped for the body, braces and all: expand them as they were given.
+	for i, kv := range o.values {
+		k, val, _ := strings.Cut(kv, "=")
+		k, _ = url.QueryUnescape(k)
+		val, _ = url.QueryUnescape(val)
+		var err error
+		if k, err = v.expand(k); err != nil {
+			return err
+		}
+		if val, err = v.expand(val); err != nil {
+			return err
+		}
+		o.values[i] = url.QueryEscape(k) + "=" + url.QueryEscape(val)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package sendreq
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+/* design note: -tls-info makes sendreq a quick TLS inspector, like "openssl s_client" without the wall of text: for
+   each new TLS connection it prints what the handshake settled on (the protocol version, the cipher suite, and the
+   application protocol ALPN picked, if any) and the certificate chain the server sent, leaf first, each with its
+   subject, issuer, and validity. "when does this certificate expire" is the usual question, so each says how long
+   it has left. the chain is as the server sent it, not as we verified it: a server that leaves out an intermediate,
+   or sends a stale one, shows it here. with -json, the same goes in each response's "tls" field instead.
+*/
+
+// tlsState returns conn's TLS state, or nil if it isn't a TLS connection.
+func tlsState(conn net.Conn) *tls.ConnectionState {
+	if tc, ok := conn.(*traceConn); ok {
+		conn = tc.Conn
+	}
+	tc, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := tc.ConnectionState()
+	return &state
+}
+
+// writeTLSInfo prints -tls-info's report on the TLS connection to o's host.
+func writeTLSInfo(w io.Writer, o options, state *tls.ConnectionState, now time.Time) {
+	alpn := state.NegotiatedProtocol
+	if alpn == "" {
+		alpn = "none"
+	}
+	fmt.Fprintf(w, "TLS with %s\n", o.url().Host)
+	fmt.Fprintf(w, "  version  %s\n", tls.VersionName(state.Version))
+	fmt.Fprintf(w, "  cipher   %s\n", tls.CipherSuiteName(state.CipherSuite))
+	fmt.Fprintf(w, "  ALPN     %s\n", alpn)
+	fmt.Fprintln(w, "  certificate chain, as the server sent it:")
+	for i, cert := range state.PeerCertificates {
+		fmt.Fprintf(w, "  %d  subject  %s\n", i, cert.Subject)
+		fmt.Fprintf(w, "     issuer   %s\n", cert.Issuer)
+		fmt.Fprintf(w, "     valid    %s to %s (%s)\n", cert.NotBefore.Format(time.DateOnly), cert.NotAfter.Format(time.DateOnly), expiry(cert.NotAfter, now))
+		if names := certNames(cert); len(names) > 0 {
+			fmt.Fprintf(w, "     names    %s\n", strings.Join(names, ", "))
+		}
+	}
+}
+
+// certNames is the DNS names and IP addresses cert is valid for.
+func certNames(cert *x509.Certificate) []string {
+	names := slices.Clone(cert.DNSNames)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	return names
+}
+
+// expiry says how long until notAfter, or how long since.
+func expiry(notAfter, now time.Time) string {
+	switch left := notAfter.Sub(now); {
+	case left < -24*time.Hour:
+		return fmt.Sprintf("EXPIRED %d days ago", int(-left.Hours()/24))
+	case left < 0:
+		return "EXPIRED today"
+	case left < 24*time.Hour:
+		return "expires within a day"
+	default:
+		return fmt.Sprintf("expires in %d days", int(left.Hours()/24))
+	}
+}
+
+// jsonTLS is a TLS connection's state in -json's output.
+type jsonTLS struct {
+	Version string     `json:"version"`
+	Cipher  string     `json:"cipher"`
+	ALPN    string     `json:"alpn,omitempty"`
+	Chain   []jsonCert `json:"chain"` // as the server sent it, leaf first
+}
+
+type jsonCert struct {
+	Subject   string   `json:"subject"`
+	Issuer    string   `json:"issuer"`
+	NotBefore string   `json:"not_before"` // RFC 3339
+	NotAfter  string   `json:"not_after"`
+	Names     []string `json:"names,omitempty"` // DNS names and IP addresses it's valid for
+}
+
+func newJSONTLS(state *tls.ConnectionState) *jsonTLS {
+	j := &jsonTLS{
+		Version: tls.VersionName(state.Version),
+		Cipher:  tls.CipherSuiteName(state.CipherSuite),
+		ALPN:    state.NegotiatedProtocol,
+		Chain:   []jsonCert{},
+	}
+	for _, cert := range state.PeerCertificates {
+		j.Chain = append(j.Chain, jsonCert{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			NotBefore: cert.NotBefore.UTC().Format(time.RFC3339),
+			NotAfter:  cert.NotAfter.UTC().Format(time.RFC3339),
+			Names:     certNames(cert),
+		})
+	}
+	return j
+}
@@ -0,0 +1,172 @@
+package sendreq
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+/* design note: -v shows the conversation the way curl -v does: every byte sent, a line at a time after "> ", every
+   byte received after "< ", and notes about the connection itself after "* ". it's taken from the connection the
+   requests are written to, after TLS if there is any, so it's the plaintext the two sides actually exchanged, bodies
+   and chunk framing and all, not a reconstruction of it. the TLS handshake itself is binary and encrypted past its
+   first few messages, so it gets a summary instead.
+
+   lines go out whole, so a pipelined request being written while a response is read can't land in the middle of one.
+   a line that isn't printable text, like a gzipped body, is quoted, with Go's escapes for the bytes that aren't.
+   HTTP/2's frames are binary through and through, so for -http2 the trace is of the frames instead: what each one
+   is, the header fields from the HPACK blocks, decoded, and the data from DATA frames.
+*/
+
+// tracer writes -v's trace. it's safe for concurrent use; connections share one.
+type tracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newTracer(w io.Writer) *tracer { return &tracer{w: w} }
+
+// note writes a line about the connection, rather than on it.
+func (t *tracer) note(format string, args ...any) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "* "+format+"\n", args...)
+}
+
+// lines adds data to *buf, then writes each complete line at its start after prefix, and leaves the rest, if any, in
+// *buf for more to complete. with flush, the rest goes too.
+func (t *tracer) lines(prefix string, buf *[]byte, data []byte, flush bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	b := append(*buf, data...)
+	for len(b) > 0 {
+		i := 0
+		for i < len(b) && b[i] != '\n' {
+			i++
+		}
+		if i == len(b) && !flush {
+			break
+		}
+		line := b[:i]
+		if i < len(b) {
+			b = b[i+1:]
+		} else {
+			b = nil
+		}
+		if n := len(line); n > 0 && line[n-1] == '\r' {
+			line = line[:n-1]
+		}
+		fmt.Fprintf(t.w, "%s%s\n", prefix, printable(line))
+	}
+	*buf = append((*buf)[:0], b...)
+}
+
+// printable returns line as it is if it's text, or quoted if it isn't.
+func printable(line []byte) string {
+	s := string(line)
+	if !utf8.ValidString(s) {
+		return strconv.Quote(s)
+	}
+	for _, r := range s {
+		if r != '\t' && !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+// traceConn is a connection that traces what goes through it.
+type traceConn struct {
+	net.Conn
+	t       *tracer
+	in, out []byte // partial lines, waiting for their ends; the tracer's lock guards them
+	frames  bool   // the bytes are HTTP/2 frames, which h2Conn traces itself
+	once    sync.Once
+}
+
+func (c *traceConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 && !c.frames {
+		c.t.lines("< ", &c.in, p[:n], false)
+	}
+	return n, err
+}
+
+// Write traces p as it goes. each write is something sent in one go, like a request head or a piece of its body, so
+// a partial line at the end of it is traced too, and so is one left from what was read before: the other side has
+// stopped sending for now, at least.
+func (c *traceConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	if n > 0 && !c.frames {
+		c.t.lines("< ", &c.in, nil, true)
+		c.t.lines("> ", &c.out, p[:n], true)
+	}
+	return n, err
+}
+
+// Close writes what's left of any partial lines before closing.
+func (c *traceConn) Close() error {
+	c.once.Do(func() {
+		c.t.lines("> ", &c.out, nil, true)
+		c.t.lines("< ", &c.in, nil, true)
+		c.t.note("closed the connection to %s", c.RemoteAddr())
+	})
+	return c.Conn.Close()
+}
+
+// traceHandshake notes what the TLS handshake settled on, and who the server proved to be.
+func (t *tracer) traceHandshake(state tls.ConnectionState) {
+	t.note("TLS handshake done: %s, %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if state.NegotiatedProtocol != "" {
+		t.note("ALPN: the server picked %s", state.NegotiatedProtocol)
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		t.note("server certificate: subject %q, issuer %q", cert.Subject.CommonName, cert.Issuer.CommonName)
+		t.note("  valid for %v, from %s until %s", cert.DNSNames, cert.NotBefore.Format("2006-01-02"), cert.NotAfter.Format("2006-01-02"))
+	}
+}
+
+// frame writes a line about an HTTP/2 frame, and, for DATA, the data in it.
+func (t *tracer) frame(prefix string, f frame) {
+	var flags []string
+	for _, fl := range []struct {
+		flag byte
+		name string
+	}{{flagEndStream, "END_STREAM"}, {flagEndHeaders, "END_HEADERS"}, {flagPadded, "PADDED"}, {flagPriority, "PRIORITY"}} {
+		switch {
+		case f.flags&fl.flag == 0:
+		case fl.flag == flagAck && (f.typ == frameSettings || f.typ == framePing):
+			flags = append(flags, "ACK")
+		case f.typ == frameData || f.typ == frameHeaders || f.typ == frameContinuation:
+			flags = append(flags, fl.name)
+		}
+	}
+	t.mu.Lock()
+	fmt.Fprintf(t.w, "%s[%s, stream %d, %d bytes", prefix, frameName(f.typ), f.stream, len(f.payload))
+	if len(flags) > 0 {
+		fmt.Fprintf(t.w, ", %s", strings.Join(flags, "|"))
+	}
+	fmt.Fprintln(t.w, "]")
+	t.mu.Unlock()
+	if f.typ == frameData {
+		if p, err := unpad(f); err == nil {
+			t.lines(prefix, new([]byte), p, true)
+		}
+	}
+}
+
+// fields writes the header fields of an HTTP/2 header block, one per line.
+func (t *tracer) fields(prefix string, fields []hpackField) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, f := range fields {
+		fmt.Fprintf(t.w, "%s%s: %s\n", prefix, f.name, f.value)
+	}
+}
@@ -0,0 +1,169 @@
+package sendreq
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+/* design note: -urls reads URLs from stdin, one per line, and requests them -c at a time, printing a line for each
+   as it finishes, so a list of links can be checked, or a cache warmed, without a shell loop:
+
+	grep -o 'https://[^"]*' page.html | sendreq -urls -c 20 -method HEAD
+
+   it's a worker pool like -bench's, sharing one client, so URLs on the same host share kept-alive connections. the
+   URLs are read as the workers get to them, so a slow producer upstream of the pipe is fine, and so is a list too
+   long to hold. a line can be a whole URL, or a path taken against the URL on the command line; blank lines and
+   lines starting with # are skipped. the lines come out in the order the requests finish, not the order they went
+   in: a slow URL doesn't hold up the rest.
+
+   each line is the status, the body's size as it came over the wire, the time it took, and the URL, and with -L,
+   where the redirects ended up. a URL that fails, or gets a 4xx or 5xx, is a failure; if there are any, sendreq
+   exits 1 after the last one, with an error saying how many.
+*/
+
+// urlResult is how one of -urls's requests went; it's what -json prints, one per line.
+type urlResult struct {
+	URL     string  `json:"url"`
+	Status  int     `json:"status,omitempty"`
+	Bytes   int     `json:"bytes"`
+	Latency float64 `json:"latency_ms"`
+	Final   string  `json:"final_url,omitempty"` // where -L's redirects ended up, if anywhere else
+	Error   string  `json:"error,omitempty"`
+	took    time.Duration
+}
+
+func (r urlResult) failed() bool { return r.Error != "" || r.Status >= 400 }
+
+// fanOut requests each URL read from in, o.concurrency at a time, printing a line for each to stdout as it's done.
+// it's an error, saying how many, if any failed.
+func fanOut(ctx context.Context, env *cli.Env, o options, in io.Reader) error {
+	c := newClient(o)
+	defer c.close()
+	w := bufio.NewWriter(env.Stdout)
+	defer w.Flush()
+
+	jobs := make(chan string)
+	var readErr error
+	go func() {
+		defer close(jobs)
+		sc := bufio.NewScanner(in)
+		for sc.Scan() {
+			line := strings.TrimSpace(sc.Text())
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			select {
+			case jobs <- line:
+			case <-ctx.Done():
+				return
+			}
+		}
+		readErr = sc.Err()
+	}()
+
+	results := make(chan urlResult, o.concurrency)
+	var wg sync.WaitGroup
+	for range o.concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for line := range jobs {
+				results <- c.check(ctx, o, line)
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	total, failed := 0, 0
+	for r := range results {
+		if r.Error != "" && ctx.Err() != nil {
+			continue // called off by ^C or -timeout.
+		}
+		total++
+		if r.failed() {
+			failed++
+		}
+		if o.json {
+			b, _ := json.Marshal(r)
+			w.Write(append(b, '\n'))
+		} else {
+			writeURLResult(w, r)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if readErr != nil {
+		return fmt.Errorf("reading URLs from stdin: %w", readErr)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d URLs failed", failed, total)
+	}
+	return ctx.Err()
+}
+
+// check requests line, a URL or a path against o's, following redirects with -L, and says how it went.
+func (c *client) check(ctx context.Context, o options, line string) (r urlResult) {
+	r.URL = line
+	start := time.Now()
+	defer func() { r.took = time.Since(start); r.Latency = ms(r.took) }()
+	ref, err := url.Parse(line)
+	if err == nil {
+		err = o.target([]string{o.url().ResolveReference(ref).String()}, nil)
+	}
+	if err != nil {
+		r.Error = fmt.Sprintf("bad URL: %v", err)
+		return r
+	}
+	r.URL = o.url().String()
+	for redirects := 0; ; redirects++ {
+		resp, err := c.doAuth(ctx, o)
+		if err != nil {
+			r.Error = err.Error()
+			return r
+		}
+		r.Status, r.Bytes = resp.status, len(resp.body)
+		location := resp.headers.Get("Location")
+		if !o.follow || !isRedirect(resp.status) || location == "" {
+			return r
+		}
+		if redirects == o.maxRedirects {
+			r.Error = fmt.Sprintf("too many redirects (-max-redirects %d)", redirects)
+			return r
+		}
+		if o, err = o.redirect(resp.status, location); err != nil {
+			r.Error = err.Error()
+			return r
+		}
+		r.Final = o.url().String()
+	}
+}
+
+// writeURLResult prints r as a line: the status (or ERR), size, latency, and URL, then where it ended up or what
+// went wrong.
+func writeURLResult(w io.Writer, r urlResult) {
+	status := fmt.Sprint(r.Status)
+	if r.Error != "" && r.Status == 0 {
+		status = "ERR"
+	}
+	fmt.Fprintf(w, "%-3s %9dB %10s  %s", status, r.Bytes, round(r.took), r.URL)
+	if r.Final != "" {
+		fmt.Fprintf(w, " -> %s", r.Final)
+	}
+	if r.Error != "" {
+		fmt.Fprintf(w, "  (%s)", r.Error)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,373 @@
+package sendreq
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ekediala/cli"
+	"github.com/ekediala/httpwire"
+)
+
+/* design note: a WebSocket (RFC 6455) starts life as an HTTP/1.1 request, and then stops being HTTP. the client asks
+   to switch protocols:
+
+	GET /chat HTTP/1.1
+	Upgrade: websocket
+	Connection: Upgrade
+	Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==
+	Sec-WebSocket-Version: 13
+
+   and a server that agrees answers "101 Switching Protocols", with Sec-WebSocket-Accept set to
+   base64(SHA-1(key + a GUID fixed by the RFC)). the key is random and the answer depends on it, which proves the
+   server read this request and actually speaks WebSocket, rather than being a cache replaying some old 101. after
+   the empty line ending the 101, the same TCP (or TLS) connection carries frames, both ways, whenever either side
+   likes:
+
+	 0               1               2               3
+	+-+-+-+-+-------+-+-------------+-------------------------------+
+	|F|R|R|R| opcode|M| payload len |   extended payload length     |
+	|I|S|S|S|  (4)  |A|     (7)     |          (16 or 64)           |
+	|N|V|V|V|       |S|             |                               |
+	+-+-+-+-+-------+-+-------------+-------------------------------+
+	|  masking key (4 bytes, client frames only)  |  payload ...    |
+	+---------------------------------------------+-----------------+
+
+   a message is one frame, or several with FIN on the last. text (1) and binary (2) frames carry messages; close (8),
+   ping (9), and pong (10) are control frames, which can come between a message's fragments. every frame a client
+   sends is masked, XORed with a random key, so that a naive proxy in the middle can't be tricked into reading a
+   client's payload as an HTTP request of its own and caching the answer. server frames aren't masked.
+
+   -ws does the handshake over a connection dialed like any other (proxies, TLS, -v traces and all), then each line
+   on stdin goes out as a text message, and each message that comes back is printed on a line of its own, binary
+   ones as a hex dump. pings are answered with pongs. ^D sends a close frame and waits for the server's; a close from
+   the server is answered and ends the session too. the connection never goes back to the pool: it isn't HTTP
+   anymore.
+*/
+
+// wsGUID is what RFC 6455 has the server append to the key before hashing it.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// wsMaxMessage is the largest message we'll put together, so a broken or hostile length can't make us allocate
+// gigabytes.
+const wsMaxMessage = 16 << 20
+
+// wsAccept is the Sec-WebSocket-Accept a server should answer key with.
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// websocket opens a -ws session: the upgrade handshake, then messages from in out, and messages from the server to
+// stdout, until one side closes it or ctx is done.
+func websocket(ctx context.Context, env *cli.Env, o options, in io.Reader) error {
+	conn, _, err := dial(ctx, o)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	slog.InfoContext(ctx, "main", "message", fmt.Sprintf("connected to %s (@ %s)", o.host, conn.RemoteAddr()))
+	// closing the connection on ^C ends whatever read or write is waiting on it.
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	r := bufio.NewReader(conn)
+	if err := o.wsHandshake(ctx, conn, r); err != nil {
+		return err
+	}
+	ws := &wsConn{w: conn, mask: true}
+
+	// the server's frames are read in the background; done closes when the server closes, or the connection goes.
+	done := make(chan error, 1)
+	go func() { done <- ws.readMessages(ctx, r, env.Stdout) }()
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		s := bufio.NewScanner(in)
+		s.Buffer(nil, wsMaxMessage)
+		for s.Scan() {
+			select {
+			case lines <- strings.TrimRight(s.Text(), "\r"):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				// ^D: say goodbye, and give the server a moment to say it back.
+				if err := ws.close(1000, ""); err != nil {
+					return err
+				}
+				select {
+				case err := <-done:
+					return err
+				case <-time.After(5 * time.Second):
+					return errors.New("the server didn't answer the close frame in 5s")
+				case <-ctx.Done():
+					return nil
+				}
+			}
+			if err := ws.write(wsText, []byte(line)); err != nil {
+				return fmt.Errorf("sending message: %w", err)
+			}
+		case err := <-done:
+			return err
+		case <-ctx.Done():
+			ws.close(1001, "going away")
+			return nil
+		}
+	}
+}
+
+// wsHandshake sends the upgrade request on conn, and reads and checks the server's answer from r.
+func (o options) wsHandshake(ctx context.Context, conn net.Conn, r *bufio.Reader) error {
+	b := make([]byte, 16)
+	rand.Read(b)
+	key := base64.StdEncoding.EncodeToString(b)
+	o.method, o.hasBody = "GET", false
+	// header names are kept title-cased, as -H's are, so that -H can override these.
+	o.headers = mergeHeaders([]Header{
+		{Key: "Upgrade", Value: "websocket"},
+		{Key: "Connection", Value: "Upgrade"},
+		{Key: httpwire.AsTitle("Sec-WebSocket-Key"), Value: key},
+		{Key: httpwire.AsTitle("Sec-WebSocket-Version"), Value: "13"},
+	}, o.headers)
+	key = Headers(o.headers).Get("Sec-WebSocket-Key") // -H's, if it gave one
+	head, _, _, err := o.request()
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(conn, head); err != nil {
+		return fmt.Errorf("sending the upgrade request: %w", err)
+	}
+	slog.InfoContext(ctx, "main", "info", fmt.Sprintf("sent request:\n%s", head))
+
+	if o.readTimeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(o.readTimeout))
+		defer conn.SetReadDeadline(time.Time{}) // once it's a WebSocket, a quiet server is nothing to worry about.
+	}
+	resp, err := readResponse(r, "GET")
+	if err != nil {
+		return fmt.Errorf("reading the upgrade response: %w", err)
+	}
+	slog.InfoContext(ctx, "main", "message", "upgrade response", "status", resp.head[0])
+	switch {
+	case resp.status != 101:
+		body := ""
+		if len(resp.body) > 0 {
+			body = ": " + quoteBody(resp.body)
+		}
+		return fmt.Errorf("the server didn't switch to WebSocket: %s%s", resp.head[0], body)
+	case !strings.EqualFold(resp.headers.Get("Upgrade"), "websocket"):
+		return fmt.Errorf("the server switched to %q, not websocket", resp.headers.Get("Upgrade"))
+	case !resp.headers.HasToken("Connection", "upgrade"):
+		return errors.New("the server's 101 doesn't say Connection: Upgrade")
+	case resp.headers.Get(httpwire.AsTitle("Sec-WebSocket-Accept")) != wsAccept(key):
+		return fmt.Errorf("the server's Sec-WebSocket-Accept is %q, want %q: it didn't answer our key", resp.headers.Get(httpwire.AsTitle("Sec-WebSocket-Accept")), wsAccept(key))
+	}
+	return nil
+}
+
+// wsConn writes frames. the session and the reader (answering pings and closes) both write, so writes take turns.
+type wsConn struct {
+	mu     sync.Mutex
+	w      io.Writer
+	mask   bool // a client's frames are masked; a server's aren't
+	closed bool // we've sent a close frame, and must send nothing after it
+}
+
+// write sends payload as one frame with opcode.
+func (ws *wsConn) write(opcode byte, payload []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closed {
+		return errors.New("the WebSocket is closing")
+	}
+	if opcode == wsClose {
+		ws.closed = true
+	}
+	_, err := ws.w.Write(wsFrame(opcode, payload, ws.mask))
+	return err
+}
+
+// close sends a close frame with code and reason, unless one's gone already.
+func (ws *wsConn) close(code uint16, reason string) error {
+	ws.mu.Lock()
+	closed := ws.closed
+	ws.mu.Unlock()
+	if closed {
+		return nil
+	}
+	payload := binary.BigEndian.AppendUint16(nil, code)
+	return ws.write(wsClose, append(payload, reason...))
+}
+
+// wsFrame is a frame, FIN set, carrying payload with opcode; masked with a random key if mask is set.
+func wsFrame(opcode byte, payload []byte, mask bool) []byte {
+	frame := []byte{0x80 | opcode, 0}
+	switch n := len(payload); {
+	case n < 126:
+		frame[1] = byte(n)
+	case n <= 0xffff:
+		frame[1] = 126
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame[1] = 127
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	if !mask {
+		return append(frame, payload...)
+	}
+	frame[1] |= 0x80
+	var key [4]byte
+	rand.Read(key[:])
+	frame = append(frame, key[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^key[i%4])
+	}
+	return frame
+}
+
+// wsReadFrame reads a frame from r, and unmasks its payload if it's masked.
+func wsReadFrame(r io.Reader) (fin bool, opcode byte, payload []byte, err error) {
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin, opcode = h[0]&0x80 != 0, h[0]&0x0f
+	if h[0]&0x70 != 0 {
+		return false, 0, nil, errors.New("frame has reserved bits set, and no extension was agreed on")
+	}
+	n := uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > wsMaxMessage {
+		return false, 0, nil, fmt.Errorf("frame of %d bytes is over the %d byte limit", n, wsMaxMessage)
+	}
+	var key [4]byte
+	masked := h[1]&0x80 != 0
+	if masked {
+		if _, err := io.ReadFull(r, key[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload = make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= key[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// readMessages reads frames from r until the server closes the WebSocket, printing each message to w, answering
+// pings, and answering the server's close. it returns nil for a clean close.
+func (ws *wsConn) readMessages(ctx context.Context, r io.Reader, w io.Writer) error {
+	var message []byte
+	var messageType byte // wsText or wsBinary while a fragmented message is coming in; 0 between messages
+	for {
+		fin, opcode, payload, err := wsReadFrame(r)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("reading frame: %w", err)
+		}
+		switch opcode {
+		case wsPing:
+			slog.DebugContext(ctx, "main", "message", "ping from the server; sending a pong", "bytes", len(payload))
+			ws.write(wsPong, payload)
+		case wsPong:
+			slog.DebugContext(ctx, "main", "message", "pong from the server", "bytes", len(payload))
+		case wsClose:
+			code, reason := wsCloseReason(payload)
+			slog.InfoContext(ctx, "main", "message", "the server closed the WebSocket", "code", code, "reason", reason)
+			// echo its code back, as RFC 6455 asks. (if we started the close, ours has gone, and this won't.)
+			ws.write(wsClose, payload[:min(2, len(payload))])
+			if code != 1000 && code != 1001 && code != 1005 {
+				return fmt.Errorf("the server closed the WebSocket with %d %s", code, reason)
+			}
+			return nil
+		case wsText, wsBinary, wsContinuation:
+			switch {
+			case opcode == wsContinuation && messageType == 0:
+				return errors.New("continuation frame with no message to continue")
+			case opcode != wsContinuation && messageType != 0:
+				return errors.New("new message before the last one's final fragment")
+			case opcode != wsContinuation:
+				messageType = opcode
+			}
+			if len(message)+len(payload) > wsMaxMessage {
+				return fmt.Errorf("message is over the %d byte limit", wsMaxMessage)
+			}
+			message = append(message, payload...)
+			if !fin {
+				continue
+			}
+			writeMessage(w, messageType, message)
+			message, messageType = nil, 0
+		default:
+			return fmt.Errorf("frame with unknown opcode %#x", opcode)
+		}
+	}
+}
+
+// wsCloseReason is a close frame's status code and reason; 1005, "no status", if it has none.
+func wsCloseReason(payload []byte) (uint16, string) {
+	if len(payload) < 2 {
+		return 1005, ""
+	}
+	return binary.BigEndian.Uint16(payload), string(payload[2:])
+}
+
+// writeMessage prints a message: a text one as it is, on a line of its own; a binary one as a hex dump.
+func writeMessage(w io.Writer, messageType byte, message []byte) {
+	if messageType == wsText && utf8.Valid(message) {
+		fmt.Fprintf(w, "%s\n", message)
+		return
+	}
+	fmt.Fprintf(w, "binary message, %d bytes\n%s", len(message), hex.Dump(message))
+}
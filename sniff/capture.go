@@ -0,0 +1,89 @@
+package sniff
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Config selects what to capture.
+type Config struct {
+	// Interface is the name of the interface to capture on, like "lo" or "eth0"; empty means all of them.
+	Interface string
+	// Port keeps only TCP segments to or from this port; zero keeps every frame.
+	Port int
+	// SnapLen caps how many bytes of each frame are kept; zero means DefaultSnapLen.
+	SnapLen int
+}
+
+// ErrUnsupported is returned by Open on platforms without a capture implementation.
+var ErrUnsupported = errors.New("sniff: packet capture is only supported on Linux")
+
+// Capture reads packets until ctx is done, calling fn for each. it returns nil when ctx is cancelled.
+func Capture(ctx context.Context, cfg Config, fn func(Packet) error) error {
+	h, err := Open(cfg)
+	if err != nil {
+		return err
+	}
+	defer h.Close()
+	return h.each(ctx, fn)
+}
+
+func (h *Handle) each(ctx context.Context, fn func(Packet) error) error {
+	for {
+		p, err := h.ReadPacket(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+}
+
+// CaptureFile captures to a pcap file in the background, for the servers' -capture flag. the capture socket is opened
+// before CaptureFile returns, so a missing permission (capturing needs root or CAP_NET_RAW) is reported up front.
+// call stop to end the capture and flush the file; it returns the first error the capture ran into.
+func CaptureFile(ctx context.Context, path string, cfg Config) (stop func() error, err error) {
+	h, err := Open(cfg)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	bw := bufio.NewWriter(f)
+	pw, err := NewPcapWriter(bw, cfg.SnapLen)
+	if err != nil {
+		h.Close()
+		f.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan error, 1)
+	go func() { done <- h.each(ctx, pw.WritePacket) }()
+
+	return func() error {
+		cancel()
+		err := <-done
+		h.Close()
+		if ferr := bw.Flush(); err == nil {
+			err = ferr
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return fmt.Errorf("sniff: capturing to %s: %w", path, err)
+		}
+		return nil
+	}, nil
+}
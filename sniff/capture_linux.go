@@ -0,0 +1,215 @@
+//go:build linux
+
+package sniff
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Linux hands raw frames to AF_PACKET sockets. with SOCK_RAW we get them complete with their link-layer header;
+// ETH_P_ALL asks for every protocol, not just IP.
+const ethPAll = 0x0003
+
+// link-layer header types (ARPHRD_*) from the socket address of each frame.
+const (
+	arphrdEther    = 1
+	arphrdLoopback = 772
+	arphrdNone     = 65534 // tun devices and the like: raw IP, no link header
+)
+
+// Handle is an open capture socket.
+type Handle struct {
+	fd      int
+	port    int
+	snapLen int
+	buf     []byte
+
+	loopback map[int]bool // interface index -> is it a loopback interface
+}
+
+// Open opens an AF_PACKET socket on cfg.Interface and, if cfg.Port is set, attaches a BPF filter for it.
+// it needs root or CAP_NET_RAW.
+func Open(cfg Config) (*Handle, error) {
+	snapLen := cfg.SnapLen
+	if snapLen <= 0 {
+		snapLen = DefaultSnapLen
+	}
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_RAW, int(htons(ethPAll)))
+	if err != nil {
+		return nil, fmt.Errorf("sniff: opening packet socket (needs root or CAP_NET_RAW): %w", err)
+	}
+	h := &Handle{fd: fd, port: cfg.Port, snapLen: snapLen, buf: make([]byte, snapLen), loopback: map[int]bool{}}
+
+	if cfg.Port != 0 {
+		// filter in the kernel, so the thousands of frames we don't care about never get copied to us.
+		// the socket may already have queued a few unfiltered frames; ReadPacket checks the port again.
+		if err := syscall.AttachLsf(fd, portFilter(uint16(cfg.Port), uint32(snapLen))); err != nil {
+			h.Close()
+			return nil, fmt.Errorf("sniff: attaching BPF filter: %w", err)
+		}
+	}
+
+	ifindex := 0 // all interfaces
+	if cfg.Interface != "" {
+		iface, err := net.InterfaceByName(cfg.Interface)
+		if err != nil {
+			h.Close()
+			return nil, fmt.Errorf("sniff: %w", err)
+		}
+		ifindex = iface.Index
+	}
+	if err := syscall.Bind(fd, &syscall.SockaddrLinklayer{Protocol: htons(ethPAll), Ifindex: ifindex}); err != nil {
+		h.Close()
+		return nil, fmt.Errorf("sniff: binding to interface: %w", err)
+	}
+
+	// wake up regularly so ReadPacket can notice a cancelled context; a blocked recvfrom ignores close(2).
+	tv := syscall.NsecToTimeval(int64(200 * time.Millisecond))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		h.Close()
+		return nil, fmt.Errorf("sniff: setting receive timeout: %w", err)
+	}
+	return h, nil
+}
+
+// ReadPacket blocks until a matching frame arrives or ctx is done. the returned Data is a fresh copy.
+func (h *Handle) ReadPacket(ctx context.Context) (Packet, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return Packet{}, err
+		}
+		// MSG_TRUNC makes recvfrom return the frame's real length even when it didn't fit in buf.
+		n, from, err := syscall.Recvfrom(h.fd, h.buf, syscall.MSG_TRUNC)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return Packet{}, fmt.Errorf("sniff: reading packet: %w", os.NewSyscallError("recvfrom", err))
+		}
+		ll, ok := from.(*syscall.SockaddrLinklayer)
+		if !ok {
+			continue
+		}
+		// on loopback every packet is seen twice, once going out and once coming back in; keep the incoming copy.
+		if ll.Pkttype == syscall.PACKET_OUTGOING && h.isLoopback(ll.Ifindex) {
+			continue
+		}
+
+		captured := min(n, len(h.buf))
+		var data []byte
+		switch ll.Hatype {
+		case arphrdEther, arphrdLoopback:
+			data = append([]byte(nil), h.buf[:captured]...)
+		case arphrdNone:
+			// give raw IP a fake Ethernet header, so everything downstream (and the pcap file) has one link type.
+			data, n = withEthernet(h.buf[:captured]), n+14
+		default:
+			continue
+		}
+
+		if h.port != 0 {
+			seg, err := Decode(data)
+			if err != nil || (int(seg.Src.Port()) != h.port && int(seg.Dst.Port()) != h.port) {
+				continue
+			}
+		}
+		return Packet{Time: time.Now(), Data: data, Len: n}, nil
+	}
+}
+
+// Close closes the capture socket.
+func (h *Handle) Close() error {
+	return syscall.Close(h.fd)
+}
+
+func (h *Handle) isLoopback(ifindex int) bool {
+	lo, ok := h.loopback[ifindex]
+	if !ok {
+		iface, err := net.InterfaceByIndex(ifindex)
+		lo = err == nil && iface.Flags&net.FlagLoopback != 0
+		h.loopback[ifindex] = lo
+	}
+	return lo
+}
+
+func withEthernet(ip []byte) []byte {
+	etherType := uint16(etherTypeIPv4)
+	if len(ip) > 0 && ip[0]>>4 == 6 {
+		etherType = etherTypeIPv6
+	}
+	frame := make([]byte, 14, 14+len(ip))
+	frame[12], frame[13] = byte(etherType>>8), byte(etherType)
+	return append(frame, ip...)
+}
+
+// htons converts to network byte order, which is what AF_PACKET wants for the protocol number.
+func htons(v uint16) uint16 { return v<<8 | v>>8 }
+
+// portFilter is classic BPF for "tcp port <port>" on Ethernet frames: the same program `tcpdump -d 'tcp port 8080'`
+// prints. the kernel runs it on every frame; returning 0 drops the frame, anything else is how many bytes to keep.
+// it assumes an Ethernet header, so on "all interfaces" frames from raw-IP devices (tun) won't match.
+//
+//	(000) ldh   [12]                      ; EtherType
+//	(001) jeq   #0x86dd      jt 2   jf 8  ; IPv6?
+//	(002) ldb   [20]                      ; IPv6 next header
+//	(003) jeq   #0x6         jt 4   jf 19 ; TCP?
+//	(004) ldh   [54]                      ; source port
+//	(005) jeq   #port        jt 18  jf 6
+//	(006) ldh   [56]                      ; destination port
+//	(007) jeq   #port        jt 18  jf 19
+//	(008) jeq   #0x800       jt 9   jf 19 ; IPv4?
+//	(009) ldb   [23]                      ; IPv4 protocol
+//	(010) jeq   #0x6         jt 11  jf 19 ; TCP?
+//	(011) ldh   [20]                      ; flags + fragment offset
+//	(012) jset  #0x1fff      jt 19  jf 13 ; a later fragment has no TCP header
+//	(013) ldxb  4*([14]&0xf)              ; X = IPv4 header length
+//	(014) ldh   [x + 14]                  ; source port
+//	(015) jeq   #port        jt 18  jf 16
+//	(016) ldh   [x + 16]                  ; destination port
+//	(017) jeq   #port        jt 18  jf 19
+//	(018) ret   #snaplen
+//	(019) ret   #0
+func portFilter(port uint16, snapLen uint32) []syscall.SockFilter {
+	p := uint32(port)
+	// jump targets are written as absolute instruction numbers, like the listing above, and made relative here.
+	prog := []struct {
+		code   uint16
+		jt, jf int
+		k      uint32
+	}{
+		{syscall.BPF_LD | syscall.BPF_H | syscall.BPF_ABS, 0, 0, 12},
+		{syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, 2, 8, etherTypeIPv6},
+		{syscall.BPF_LD | syscall.BPF_B | syscall.BPF_ABS, 0, 0, 20},
+		{syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, 4, 19, protoTCP},
+		{syscall.BPF_LD | syscall.BPF_H | syscall.BPF_ABS, 0, 0, 54},
+		{syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, 18, 6, p},
+		{syscall.BPF_LD | syscall.BPF_H | syscall.BPF_ABS, 0, 0, 56},
+		{syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, 18, 19, p},
+		{syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, 9, 19, etherTypeIPv4},
+		{syscall.BPF_LD | syscall.BPF_B | syscall.BPF_ABS, 0, 0, 23},
+		{syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, 11, 19, protoTCP},
+		{syscall.BPF_LD | syscall.BPF_H | syscall.BPF_ABS, 0, 0, 20},
+		{syscall.BPF_JMP | syscall.BPF_JSET | syscall.BPF_K, 19, 13, 0x1fff},
+		{syscall.BPF_LDX | syscall.BPF_B | syscall.BPF_MSH, 0, 0, 14},
+		{syscall.BPF_LD | syscall.BPF_H | syscall.BPF_IND, 0, 0, 14},
+		{syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, 18, 16, p},
+		{syscall.BPF_LD | syscall.BPF_H | syscall.BPF_IND, 0, 0, 16},
+		{syscall.BPF_JMP | syscall.BPF_JEQ | syscall.BPF_K, 18, 19, p},
+		{syscall.BPF_RET | syscall.BPF_K, 0, 0, snapLen},
+		{syscall.BPF_RET | syscall.BPF_K, 0, 0, 0},
+	}
+	out := make([]syscall.SockFilter, len(prog))
+	for i, ins := range prog {
+		out[i] = syscall.SockFilter{Code: ins.code, K: ins.k}
+		if ins.code&0x07 == syscall.BPF_JMP {
+			out[i].Jt, out[i].Jf = uint8(ins.jt-i-1), uint8(ins.jf-i-1)
+		}
+	}
+	return out
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package sniff
+
+import "context"
+
+// Handle is an open capture socket.
+type Handle struct{}
+
+// Open returns ErrUnsupported: on other platforms capturing means BPF devices (/dev/bpf*) on the BSDs and macOS,
+// or Npcap on Windows, neither of which is implemented here.
+func Open(cfg Config) (*Handle, error) { return nil, ErrUnsupported }
+
+// ReadPacket always returns ErrUnsupported.
+func (h *Handle) ReadPacket(ctx context.Context) (Packet, error) { return Packet{}, ErrUnsupported }
+
+// Close does nothing.
+func (h *Handle) Close() error { return nil }
@@ -0,0 +1,9 @@
+// Command sniff captures TCP traffic; see package sniff.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/sniff"
+)
+
+func main() { cli.Main(sniff.Command) }
@@ -0,0 +1,96 @@
+package sniff
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/ekediala/cli"
+)
+
+// Command is the sniff tool: a tiny tcpdump that prints TCP segments or writes them to a pcap file.
+var Command = &cli.Command{
+	Name:    "sniff",
+	Summary: "capture TCP segments and print them or write a pcap file",
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		iface := fs.String("i", "", "interface to capture on (default: all)")
+		port := fs.Int("p", 0, "only capture TCP traffic to or from this port (default: everything)")
+		write := fs.String("w", "", "write packets to this pcap file instead of printing them")
+		count := fs.Int("c", 0, "stop after this many packets (default: run until interrupted)")
+		snapLen := fs.Int("s", DefaultSnapLen, "bytes of each packet to keep")
+		ascii := fs.Bool("A", false, "print each segment's payload as text, like tcpdump -A")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			var pw *PcapWriter
+			if *write != "" {
+				f, err := os.Create(*write)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				bw := bufio.NewWriter(f)
+				defer bw.Flush()
+				if pw, err = NewPcapWriter(bw, *snapLen); err != nil {
+					return err
+				}
+			}
+
+			where := *iface
+			if where == "" {
+				where = "all interfaces"
+			}
+			slog.InfoContext(ctx, "main", "message", "capturing on "+where, "port", *port)
+
+			n := 0
+			errDone := errors.New("done")
+			err := Capture(ctx, Config{Interface: *iface, Port: *port, SnapLen: *snapLen}, func(p Packet) error {
+				n++
+				if pw != nil {
+					if err := pw.WritePacket(p); err != nil {
+						return err
+					}
+				} else {
+					printPacket(env.Stdout, p, *ascii)
+				}
+				if *count > 0 && n >= *count {
+					return errDone
+				}
+				return nil
+			})
+			if err != nil && !errors.Is(err, errDone) {
+				return err
+			}
+			slog.InfoContext(ctx, "main", "message", fmt.Sprintf("%d packets captured", n))
+			return nil
+		}
+	},
+}
+
+func printPacket(w io.Writer, p Packet, ascii bool) {
+	ts := p.Time.Format("15:04:05.000000")
+	seg, err := Decode(p.Data)
+	if err != nil {
+		// only possible without -p: the kernel filter only lets TCP through.
+		fmt.Fprintf(w, "%s %d bytes: %v\n", ts, p.Len, err)
+		return
+	}
+	fmt.Fprintf(w, "%s %s\n", ts, seg.Summary())
+	if ascii && len(seg.Payload) > 0 {
+		fmt.Fprintln(w, printable(seg.Payload))
+	}
+}
+
+// printable replaces non-printing bytes with '.', keeping newlines so HTTP and friends stay readable.
+func printable(p []byte) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\n' || (r >= ' ' && r <= '~') {
+			return r
+		}
+		return '.'
+	}, string(p))
+}
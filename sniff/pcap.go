@@ -0,0 +1,67 @@
+package sniff
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+)
+
+// the classic libpcap file format: a 24-byte global header, then for every packet a 16-byte record header
+// followed by the captured bytes. it's little-endian here; readers detect the byte order from the magic number.
+//
+//	global: magic (4) | version major (2) | minor (2) | timezone (4) | sigfigs (4) | snaplen (4) | link type (4)
+//	record: seconds (4) | microseconds (4) | captured length (4) | original length (4) | data
+const (
+	pcapMagic        = 0xa1b2c3d4 // microsecond timestamps
+	linkTypeEthernet = 1
+)
+
+// DefaultSnapLen is how much of each frame is kept by default: enough for any Ethernet frame.
+const DefaultSnapLen = 65535
+
+// PcapWriter writes packets to a pcap file. It is safe for concurrent use.
+type PcapWriter struct {
+	mu      sync.Mutex
+	w       io.Writer
+	snapLen int
+}
+
+// NewPcapWriter writes the pcap global header to w and returns a writer for the packets.
+// snapLen is recorded in the header; packets longer than it are truncated. zero means DefaultSnapLen.
+func NewPcapWriter(w io.Writer, snapLen int) (*PcapWriter, error) {
+	if snapLen <= 0 {
+		snapLen = DefaultSnapLen
+	}
+	var hdr [24]byte
+	binary.LittleEndian.PutUint32(hdr[0:], pcapMagic)
+	binary.LittleEndian.PutUint16(hdr[4:], 2)
+	binary.LittleEndian.PutUint16(hdr[6:], 4)
+	// timezone and sigfigs are always zero in practice.
+	binary.LittleEndian.PutUint32(hdr[16:], uint32(snapLen))
+	binary.LittleEndian.PutUint32(hdr[20:], linkTypeEthernet)
+	if _, err := w.Write(hdr[:]); err != nil {
+		return nil, err
+	}
+	return &PcapWriter{w: w, snapLen: snapLen}, nil
+}
+
+// WritePacket appends one packet record.
+func (pw *PcapWriter) WritePacket(p Packet) error {
+	data := p.Data
+	if len(data) > pw.snapLen {
+		data = data[:pw.snapLen]
+	}
+	orig := max(p.Len, len(data))
+
+	rec := make([]byte, 16, 16+len(data))
+	binary.LittleEndian.PutUint32(rec[0:], uint32(p.Time.Unix()))
+	binary.LittleEndian.PutUint32(rec[4:], uint32(p.Time.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(rec[8:], uint32(len(data)))
+	binary.LittleEndian.PutUint32(rec[12:], uint32(orig))
+	rec = append(rec, data...)
+
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	_, err := pw.w.Write(rec)
+	return err
+}
@@ -0,0 +1,183 @@
+// Package sniff captures TCP traffic off the wire and decodes it, for watching what the other tools in this repo
+// actually send.
+//
+// on Linux it opens an AF_PACKET socket, which hands us every frame the kernel sees (after a BPF filter, also run in
+// the kernel, throws away everything but the port we care about). each frame is a stack of headers:
+//
+//	+----------+-------------------+------------------+---------+
+//	| Ethernet | IPv4 or IPv6      | TCP              | payload |
+//	| 14 bytes | 20-60 / 40 bytes  | 20-60 bytes      |         |
+//	+----------+-------------------+------------------+---------+
+//
+// Decode peels them off one at a time; WritePcap saves raw frames in the format Wireshark and tcpdump read.
+package sniff
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// Packet is one captured frame, starting at its Ethernet header.
+type Packet struct {
+	Time time.Time
+	Data []byte // possibly truncated to the snap length
+	Len  int    // the frame's length on the wire
+}
+
+// EtherType values we decode.
+const (
+	etherTypeIPv4 = 0x0800
+	etherTypeIPv6 = 0x86dd
+	etherTypeVLAN = 0x8100
+)
+
+const protoTCP = 6
+
+// TCP flag bits.
+const (
+	FlagFIN = 1 << iota
+	FlagSYN
+	FlagRST
+	FlagPSH
+	FlagACK
+	FlagURG
+)
+
+// Segment is a decoded TCP segment.
+type Segment struct {
+	Src, Dst netip.AddrPort
+	Seq, Ack uint32
+	Flags    uint8
+	Window   uint16
+	Payload  []byte // what was captured of it; PayloadLen is how much was on the wire
+	// PayloadLen is computed from the IP header, so it's right even when the capture was truncated.
+	PayloadLen int
+}
+
+// ErrNotTCP is returned by Decode for frames that aren't TCP over IPv4 or IPv6.
+var ErrNotTCP = errors.New("sniff: not a TCP packet")
+
+// Decode parses an Ethernet frame down to its TCP segment.
+func Decode(frame []byte) (Segment, error) {
+	if len(frame) < 14 {
+		return Segment{}, fmt.Errorf("sniff: frame too short for Ethernet: %d bytes", len(frame))
+	}
+	// destination MAC (6), source MAC (6), EtherType (2). a VLAN tag inserts 4 bytes before the real EtherType.
+	etherType := binary.BigEndian.Uint16(frame[12:14])
+	ip := frame[14:]
+	if etherType == etherTypeVLAN && len(ip) >= 4 {
+		etherType = binary.BigEndian.Uint16(ip[2:4])
+		ip = ip[4:]
+	}
+
+	var seg Segment
+	var tcp []byte
+	var ipPayloadLen int
+	switch etherType {
+	case etherTypeIPv4:
+		// version+IHL (1), TOS (1), total length (2), ID (2), flags+fragment offset (2), TTL (1), protocol (1),
+		// checksum (2), source (4), destination (4), options (IHL*4 - 20).
+		if len(ip) < 20 {
+			return Segment{}, fmt.Errorf("sniff: truncated IPv4 header")
+		}
+		ihl := int(ip[0]&0x0f) * 4
+		if ip[9] != protoTCP {
+			return Segment{}, ErrNotTCP
+		}
+		if ihl < 20 || len(ip) < ihl {
+			return Segment{}, fmt.Errorf("sniff: bad IPv4 header length %d", ihl)
+		}
+		if binary.BigEndian.Uint16(ip[6:8])&0x1fff != 0 {
+			return Segment{}, ErrNotTCP // a later fragment: no TCP header in it
+		}
+		src, _ := netip.AddrFromSlice(ip[12:16])
+		dst, _ := netip.AddrFromSlice(ip[16:20])
+		seg.Src, seg.Dst = netip.AddrPortFrom(src, 0), netip.AddrPortFrom(dst, 0)
+		ipPayloadLen = int(binary.BigEndian.Uint16(ip[2:4])) - ihl
+		tcp = ip[ihl:]
+	case etherTypeIPv6:
+		// version/class/flow (4), payload length (2), next header (1), hop limit (1), source (16), destination (16).
+		// we don't walk extension headers; TCP has to come straight after.
+		if len(ip) < 40 {
+			return Segment{}, fmt.Errorf("sniff: truncated IPv6 header")
+		}
+		if ip[6] != protoTCP {
+			return Segment{}, ErrNotTCP
+		}
+		src, _ := netip.AddrFromSlice(ip[8:24])
+		dst, _ := netip.AddrFromSlice(ip[24:40])
+		seg.Src, seg.Dst = netip.AddrPortFrom(src, 0), netip.AddrPortFrom(dst, 0)
+		ipPayloadLen = int(binary.BigEndian.Uint16(ip[4:6]))
+		tcp = ip[40:]
+	default:
+		return Segment{}, ErrNotTCP
+	}
+
+	// source port (2), destination port (2), seq (4), ack (4), data offset (4 bits) + reserved, flags (1),
+	// window (2), checksum (2), urgent pointer (2), options.
+	if len(tcp) < 20 {
+		return Segment{}, fmt.Errorf("sniff: truncated TCP header")
+	}
+	off := int(tcp[12]>>4) * 4
+	if off < 20 {
+		return Segment{}, fmt.Errorf("sniff: bad TCP data offset %d", off)
+	}
+	seg.Src = netip.AddrPortFrom(seg.Src.Addr(), binary.BigEndian.Uint16(tcp[0:2]))
+	seg.Dst = netip.AddrPortFrom(seg.Dst.Addr(), binary.BigEndian.Uint16(tcp[2:4]))
+	seg.Seq = binary.BigEndian.Uint32(tcp[4:8])
+	seg.Ack = binary.BigEndian.Uint32(tcp[8:12])
+	seg.Flags = tcp[13] & 0x3f
+	seg.Window = binary.BigEndian.Uint16(tcp[14:16])
+	seg.PayloadLen = max(ipPayloadLen-off, 0)
+	if len(tcp) > off {
+		seg.Payload = tcp[off:min(len(tcp), off+seg.PayloadLen)]
+	}
+	return seg, nil
+}
+
+// Summary formats the segment like a line of tcpdump output (without the timestamp):
+//
+//	IP 127.0.0.1.54321 > 127.0.0.1.8080: Flags [P.], seq 1000:1005, ack 2000, win 512, length 5
+//
+// sequence numbers are absolute; tcpdump shows them relative to the start of the connection.
+func (s Segment) Summary() string {
+	proto := "IP"
+	if s.Src.Addr().Is6() {
+		proto = "IP6"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s %s.%d > %s.%d: Flags [%s]", proto, s.Src.Addr(), s.Src.Port(), s.Dst.Addr(), s.Dst.Port(), flagString(s.Flags))
+	if s.PayloadLen > 0 || s.Flags&(FlagSYN|FlagFIN|FlagRST) != 0 {
+		if s.PayloadLen > 0 {
+			fmt.Fprintf(&b, ", seq %d:%d", s.Seq, s.Seq+uint32(s.PayloadLen))
+		} else {
+			fmt.Fprintf(&b, ", seq %d", s.Seq)
+		}
+	}
+	if s.Flags&FlagACK != 0 {
+		fmt.Fprintf(&b, ", ack %d", s.Ack)
+	}
+	fmt.Fprintf(&b, ", win %d, length %d", s.Window, s.PayloadLen)
+	return b.String()
+}
+
+// flagString uses tcpdump's letters: S(YN), F(IN), P(USH), R(ST), U(RG), and "." for ACK.
+func flagString(f uint8) string {
+	var b strings.Builder
+	for _, fl := range []struct {
+		bit uint8
+		c   byte
+	}{{FlagSYN, 'S'}, {FlagFIN, 'F'}, {FlagPSH, 'P'}, {FlagRST, 'R'}, {FlagURG, 'U'}, {FlagACK, '.'}} {
+		if f&fl.bit != 0 {
+			b.WriteByte(fl.c)
+		}
+	}
+	if b.Len() == 0 {
+		return "none"
+	}
+	return b.String()
+}
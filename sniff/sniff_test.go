@@ -0,0 +1,101 @@
+package sniff
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// frame builds an Ethernet/IPv4/TCP frame from 10.0.0.1:40000 to 10.0.0.2:80.
+func frame(flags uint8, seq, ack uint32, payload []byte) []byte {
+	eth := make([]byte, 14)
+	binary.BigEndian.PutUint16(eth[12:], etherTypeIPv4)
+
+	ip := make([]byte, 20)
+	ip[0] = 0x45 // version 4, 5 words
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+20+len(payload)))
+	ip[9] = protoTCP
+	copy(ip[12:], []byte{10, 0, 0, 1})
+	copy(ip[16:], []byte{10, 0, 0, 2})
+
+	tcp := make([]byte, 20)
+	binary.BigEndian.PutUint16(tcp[0:], 40000)
+	binary.BigEndian.PutUint16(tcp[2:], 80)
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	binary.BigEndian.PutUint32(tcp[8:], ack)
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], 512)
+
+	return append(append(append(eth, ip...), tcp...), payload...)
+}
+
+func TestDecode(t *testing.T) {
+	seg, err := Decode(frame(FlagPSH|FlagACK, 1000, 2000, []byte("hello")))
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if want := netip.MustParseAddrPort("10.0.0.1:40000"); seg.Src != want {
+		t.Errorf("Src = %v, want %v", seg.Src, want)
+	}
+	if want := netip.MustParseAddrPort("10.0.0.2:80"); seg.Dst != want {
+		t.Errorf("Dst = %v, want %v", seg.Dst, want)
+	}
+	if string(seg.Payload) != "hello" || seg.PayloadLen != 5 {
+		t.Errorf("Payload = %q (length %d), want %q", seg.Payload, seg.PayloadLen, "hello")
+	}
+	want := "IP 10.0.0.1.40000 > 10.0.0.2.80: Flags [P.], seq 1000:1005, ack 2000, win 512, length 5"
+	if got := seg.Summary(); got != want {
+		t.Errorf("Summary() =\n%s\nwant\n%s", got, want)
+	}
+
+	syn, _ := Decode(frame(FlagSYN, 7, 0, nil))
+	if want := "IP 10.0.0.1.40000 > 10.0.0.2.80: Flags [S], seq 7, win 512, length 0"; syn.Summary() != want {
+		t.Errorf("Summary() =\n%s\nwant\n%s", syn.Summary(), want)
+	}
+
+	// truncated capture: the payload is cut short, but PayloadLen still comes from the IP header.
+	f := frame(FlagACK, 1, 1, []byte("0123456789"))
+	seg, err = Decode(f[:len(f)-6])
+	if err != nil || string(seg.Payload) != "0123" || seg.PayloadLen != 10 {
+		t.Errorf("Decode(truncated) = %q (length %d), %v; want %q (length 10)", seg.Payload, seg.PayloadLen, err, "0123")
+	}
+
+	udp := frame(0, 0, 0, nil)
+	udp[14+9] = 17
+	if _, err := Decode(udp); !errors.Is(err, ErrNotTCP) {
+		t.Errorf("Decode(udp) error = %v, want ErrNotTCP", err)
+	}
+	if _, err := Decode(f[:30]); err == nil {
+		t.Errorf("Decode(short) succeeded; want error")
+	}
+}
+
+func TestPcapWriter(t *testing.T) {
+	var buf bytes.Buffer
+	pw, err := NewPcapWriter(&buf, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := frame(FlagACK, 1, 1, nil)
+	ts := time.Unix(1700000000, 123456000)
+	if err := pw.WritePacket(Packet{Time: ts, Data: data, Len: len(data)}); err != nil {
+		t.Fatal(err)
+	}
+
+	b := buf.Bytes()
+	if len(b) != 24+16+16 {
+		t.Fatalf("pcap file is %d bytes, want %d", len(b), 24+16+16)
+	}
+	le := binary.LittleEndian
+	if le.Uint32(b[0:]) != pcapMagic || le.Uint32(b[16:]) != 16 || le.Uint32(b[20:]) != linkTypeEthernet {
+		t.Errorf("global header = %x", b[:24])
+	}
+	rec := b[24:]
+	if le.Uint32(rec[0:]) != 1700000000 || le.Uint32(rec[4:]) != 123456 || le.Uint32(rec[8:]) != 16 || le.Uint32(rec[12:]) != uint32(len(data)) {
+		t.Errorf("record header = %x", rec[:16])
+	}
+}
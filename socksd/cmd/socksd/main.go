@@ -0,0 +1,9 @@
+// Command socksd is a SOCKS5 proxy server; see package socksd.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/socksd"
+)
+
+func main() { cli.Main(socksd.Command) }
@@ -0,0 +1,259 @@
+package socksd
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"syscall"
+)
+
+// SOCKS5 (RFC 1928) is a small binary protocol for asking a proxy to open a connection on your behalf.
+// after the handshake the proxy just shovels bytes both ways, so it works for any TCP protocol, not only HTTP.
+//
+// the client speaks first, offering the authentication methods it supports:
+//
+//	+-----+----------+----------+
+//	| VER | NMETHODS | METHODS  |
+//	|  5  |    1     | 1 to 255 |
+//	+-----+----------+----------+
+//
+// the server picks one (or 0xFF: none acceptable), they run that method's sub-negotiation (RFC 1929 for
+// username/password), then the client sends its request:
+//
+//	+-----+-----+-------+------+----------+----------+
+//	| VER | CMD |  RSV  | ATYP | DST.ADDR | DST.PORT |
+//	|  5  |  1  |   0   |  1   | variable |    2     |
+//	+-----+-----+-------+------+----------+----------+
+//
+// and the server answers with the same layout, a reply code in place of CMD and the address it connected from
+// in place of the destination.
+
+const socksVersion = 5
+
+// authentication methods
+const (
+	methodNoAuth       = 0x00
+	methodUserPass     = 0x02
+	methodNoAcceptable = 0xff
+)
+
+// commands; we only implement CONNECT. BIND (for FTP-style active connections) and UDP ASSOCIATE are refused.
+const (
+	cmdConnect      = 0x01
+	cmdBind         = 0x02
+	cmdUDPAssociate = 0x03
+)
+
+// address types
+const (
+	atypIPv4   = 0x01
+	atypDomain = 0x03
+	atypIPv6   = 0x04
+)
+
+// reply codes
+const (
+	repSucceeded          = 0x00
+	repGeneralFailure     = 0x01
+	repNotAllowed         = 0x02
+	repNetworkUnreachable = 0x03
+	repHostUnreachable    = 0x04
+	repConnectionRefused  = 0x05
+	repTTLExpired         = 0x06
+	repCmdNotSupported    = 0x07
+	repAtypNotSupported   = 0x08
+)
+
+// the username/password sub-negotiation has its own version number.
+const userPassVersion = 0x01
+
+var errAuthFailed = errors.New("authentication failed")
+
+// negotiate runs the method selection and, if required, the username/password sub-negotiation.
+// with no users configured only "no authentication" is accepted; otherwise only username/password is.
+// it returns the authenticated user name ("" for no authentication).
+func negotiate(rw io.ReadWriter, users map[string]string) (string, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(rw, hdr[:]); err != nil {
+		return "", fmt.Errorf("reading greeting: %w", err)
+	}
+	if hdr[0] != socksVersion {
+		return "", fmt.Errorf("unsupported SOCKS version %d", hdr[0])
+	}
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(rw, methods); err != nil {
+		return "", fmt.Errorf("reading methods: %w", err)
+	}
+
+	want := byte(methodNoAuth)
+	if len(users) > 0 {
+		want = methodUserPass
+	}
+	chosen := byte(methodNoAcceptable)
+	for _, m := range methods {
+		if m == want {
+			chosen = want
+		}
+	}
+	if _, err := rw.Write([]byte{socksVersion, chosen}); err != nil {
+		return "", err
+	}
+	switch chosen {
+	case methodNoAuth:
+		return "", nil
+	case methodNoAcceptable:
+		return "", fmt.Errorf("no acceptable authentication method among %v", methods)
+	}
+
+	// RFC 1929: VER(1) ULEN UNAME PLEN PASSWD, answered with VER(1) STATUS (0 is success; anything else and we hang up).
+	user, pass, err := readUserPass(rw)
+	if err != nil {
+		return "", err
+	}
+	if expected, ok := users[user]; !ok || expected != pass {
+		rw.Write([]byte{userPassVersion, 0x01})
+		return user, fmt.Errorf("%w for user %q", errAuthFailed, user)
+	}
+	if _, err := rw.Write([]byte{userPassVersion, 0x00}); err != nil {
+		return user, err
+	}
+	return user, nil
+}
+
+func readUserPass(r io.Reader) (user, pass string, err error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return "", "", fmt.Errorf("reading credentials: %w", err)
+	}
+	if hdr[0] != userPassVersion {
+		return "", "", fmt.Errorf("unsupported username/password version %d", hdr[0])
+	}
+	u := make([]byte, hdr[1])
+	if _, err := io.ReadFull(r, u); err != nil {
+		return "", "", fmt.Errorf("reading credentials: %w", err)
+	}
+	var plen [1]byte
+	if _, err := io.ReadFull(r, plen[:]); err != nil {
+		return "", "", fmt.Errorf("reading credentials: %w", err)
+	}
+	p := make([]byte, plen[0])
+	if _, err := io.ReadFull(r, p); err != nil {
+		return "", "", fmt.Errorf("reading credentials: %w", err)
+	}
+	return string(u), string(p), nil
+}
+
+// requestError is a malformed or unsupported request, carrying the reply code to send back.
+type requestError struct {
+	code byte
+	msg  string
+}
+
+func (e *requestError) Error() string { return e.msg }
+
+// readRequest reads the client's request and returns the command and the destination as host:port.
+// domain names are passed through unresolved, so the proxy (not the client) does the DNS lookup.
+func readRequest(r io.Reader) (cmd byte, addr string, err error) {
+	var hdr [4]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return 0, "", fmt.Errorf("reading request: %w", err)
+	}
+	if hdr[0] != socksVersion {
+		return 0, "", &requestError{repGeneralFailure, fmt.Sprintf("unsupported SOCKS version %d in request", hdr[0])}
+	}
+	cmd = hdr[1]
+
+	var host string
+	switch hdr[3] {
+	case atypIPv4, atypIPv6:
+		ip := make([]byte, 4)
+		if hdr[3] == atypIPv6 {
+			ip = make([]byte, 16)
+		}
+		if _, err := io.ReadFull(r, ip); err != nil {
+			return 0, "", fmt.Errorf("reading address: %w", err)
+		}
+		a, _ := netip.AddrFromSlice(ip)
+		host = a.String()
+	case atypDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(r, n[:]); err != nil {
+			return 0, "", fmt.Errorf("reading address: %w", err)
+		}
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(r, name); err != nil {
+			return 0, "", fmt.Errorf("reading address: %w", err)
+		}
+		host = string(name)
+	default:
+		return cmd, "", &requestError{repAtypNotSupported, fmt.Sprintf("unsupported address type %d", hdr[3])}
+	}
+
+	var port [2]byte
+	if _, err := io.ReadFull(r, port[:]); err != nil {
+		return 0, "", fmt.Errorf("reading port: %w", err)
+	}
+	addr = net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:]))))
+
+	if cmd != cmdConnect {
+		return cmd, addr, &requestError{repCmdNotSupported, fmt.Sprintf("unsupported command %d", cmd)}
+	}
+	return cmd, addr, nil
+}
+
+// writeReply sends a reply with the given code. bound is the proxy's side of the outgoing connection;
+// for failures (or if it's unknown) it's sent as 0.0.0.0:0.
+func writeReply(w io.Writer, code byte, bound net.Addr) error {
+	ap := netip.AddrPortFrom(netip.IPv4Unspecified(), 0)
+	if tcp, ok := bound.(*net.TCPAddr); ok {
+		ap = tcp.AddrPort()
+	}
+	b := []byte{socksVersion, code, 0x00}
+	if a := ap.Addr().Unmap(); a.Is4() {
+		b = append(b, atypIPv4)
+		b = append(b, a.AsSlice()...)
+	} else {
+		b = append(b, atypIPv6)
+		b = append(b, a.AsSlice()...)
+	}
+	b = binary.BigEndian.AppendUint16(b, ap.Port())
+	_, err := w.Write(b)
+	return err
+}
+
+// replyCode maps a dial error to the closest SOCKS reply code, so the client can tell "nothing listening"
+// from "no route" from "no such host".
+func replyCode(err error) byte {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return repConnectionRefused
+	case errors.Is(err, syscall.ENETUNREACH):
+		return repNetworkUnreachable
+	case errors.Is(err, syscall.EHOSTUNREACH), errors.As(err, &dnsErr):
+		return repHostUnreachable
+	case errors.Is(err, os.ErrDeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return repTTLExpired // the closest thing SOCKS has to "timed out"
+	default:
+		return repGeneralFailure
+	}
+}
+
+func commandName(cmd byte) string {
+	switch cmd {
+	case cmdConnect:
+		return "CONNECT"
+	case cmdBind:
+		return "BIND"
+	case cmdUDPAssociate:
+		return "UDP ASSOCIATE"
+	default:
+		return fmt.Sprintf("CMD(%d)", cmd)
+	}
+}
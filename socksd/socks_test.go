@@ -0,0 +1,96 @@
+package socksd
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"testing"
+)
+
+// rw is a scripted client: reads come from in, writes go to out.
+type rw struct {
+	in  io.Reader
+	out bytes.Buffer
+}
+
+func (c *rw) Read(p []byte) (int, error)  { return c.in.Read(p) }
+func (c *rw) Write(p []byte) (int, error) { return c.out.Write(p) }
+
+func TestNegotiate(t *testing.T) {
+	users := map[string]string{"alice": "secret"}
+	tests := []struct {
+		name     string
+		users    map[string]string
+		in       []byte
+		wantUser string
+		wantErr  bool
+		wantOut  []byte
+	}{
+		{"no auth", nil, []byte{5, 1, methodNoAuth}, "", false, []byte{5, methodNoAuth}},
+		{"no auth offered when password required", users, []byte{5, 1, methodNoAuth}, "", true, []byte{5, methodNoAcceptable}},
+		{"password", users, []byte{5, 2, methodNoAuth, methodUserPass, 1, 5, 'a', 'l', 'i', 'c', 'e', 6, 's', 'e', 'c', 'r', 'e', 't'}, "alice", false, []byte{5, methodUserPass, 1, 0}},
+		{"wrong password", users, []byte{5, 1, methodUserPass, 1, 5, 'a', 'l', 'i', 'c', 'e', 1, 'x'}, "alice", true, []byte{5, methodUserPass, 1, 1}},
+		{"socks4", nil, []byte{4, 1, 0}, "", true, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &rw{in: bytes.NewReader(tt.in)}
+			user, err := negotiate(c, tt.users)
+			if (err != nil) != tt.wantErr || user != tt.wantUser {
+				t.Errorf("negotiate() = %q, %v; want %q, error %v", user, err, tt.wantUser, tt.wantErr)
+			}
+			if !bytes.Equal(c.out.Bytes(), tt.wantOut) {
+				t.Errorf("negotiate() wrote %v, want %v", c.out.Bytes(), tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestReadRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       []byte
+		wantAddr string
+		wantCode int // -1 for no error
+	}{
+		{"ipv4", []byte{5, cmdConnect, 0, atypIPv4, 127, 0, 0, 1, 0x1f, 0x90}, "127.0.0.1:8080", -1},
+		{"domain", []byte{5, cmdConnect, 0, atypDomain, 11, 'e', 'x', 'a', 'm', 'p', 'l', 'e', '.', 'c', 'o', 'm', 0, 80}, "example.com:80", -1},
+		{"ipv6", append(append([]byte{5, cmdConnect, 0, atypIPv6}, net.IPv6loopback...), 1, 187), "[::1]:443", -1},
+		{"bind", []byte{5, cmdBind, 0, atypIPv4, 127, 0, 0, 1, 0, 21}, "127.0.0.1:21", repCmdNotSupported},
+		{"bad address type", []byte{5, cmdConnect, 0, 9}, "", repAtypNotSupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, addr, err := readRequest(bytes.NewReader(tt.in))
+			if addr != tt.wantAddr {
+				t.Errorf("readRequest() addr = %q, want %q", addr, tt.wantAddr)
+			}
+			var reqErr *requestError
+			switch {
+			case tt.wantCode < 0 && err != nil:
+				t.Errorf("readRequest() error = %v", err)
+			case tt.wantCode >= 0 && (!errors.As(err, &reqErr) || reqErr.code != byte(tt.wantCode)):
+				t.Errorf("readRequest() error = %v, want reply code %d", err, tt.wantCode)
+			}
+		})
+	}
+
+	if _, _, err := readRequest(bytes.NewReader([]byte{5, cmdConnect, 0, atypIPv4, 127})); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("readRequest(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestWriteReply(t *testing.T) {
+	var buf bytes.Buffer
+	writeReply(&buf, repSucceeded, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 1080})
+	if want := []byte{5, 0, 0, atypIPv4, 10, 0, 0, 2, 0x04, 0x38}; !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("writeReply(ipv4) = %v, want %v", buf.Bytes(), want)
+	}
+
+	buf.Reset()
+	writeReply(&buf, repConnectionRefused, nil)
+	if want := []byte{5, repConnectionRefused, 0, atypIPv4, 0, 0, 0, 0, 0, 0}; !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("writeReply(failure) = %v, want %v", buf.Bytes(), want)
+	}
+}
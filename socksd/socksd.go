@@ -0,0 +1,368 @@
+// Package socksd is the socksd tool: a SOCKS5 proxy server (RFC 1928) with per-user bandwidth accounting.
+package socksd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ekediala/cli"
+	"github.com/ekediala/metrics"
+	"github.com/ekediala/sniff"
+	"github.com/ekediala/syslog"
+)
+
+// Command is the socksd tool.
+var Command = &cli.Command{
+	Name:    "socksd",
+	Summary: "SOCKS5 proxy server with per-user bandwidth accounting",
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		var o options
+		fs.IntVar(&o.port, "p", 1080, "port to listen on")
+		fs.StringVar(&o.usersFile, "users", "", "file of user:password lines; when set, username/password authentication is required")
+		fs.DurationVar(&o.dialTimeout, "dial-timeout", 10*time.Second, "timeout for connecting to a destination")
+		fs.DurationVar(&o.handshakeTimeout, "handshake-timeout", 10*time.Second, "time a client has to finish the SOCKS handshake")
+		fs.DurationVar(&o.statsInterval, "stats", 0, "log per-user bandwidth totals at this interval (0 logs them only at shutdown)")
+		fs.StringVar(&o.capture, "capture", "", "record client-side traffic to this pcap file (Linux only; needs root or CAP_NET_RAW)")
+		fs.StringVar(&o.syslog, "syslog", "", "also send logs to this syslog collector, like udp://localhost:514")
+		fs.StringVar(&o.metrics, "metrics", "", "send metrics to this StatsD or Graphite collector, like statsd://localhost:8125")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			return run(ctx, env, o)
+		}
+	},
+}
+
+type options struct {
+	port                                         int
+	usersFile                                    string
+	dialTimeout, handshakeTimeout, statsInterval time.Duration
+	capture, syslog, metrics                     string
+}
+
+func run(ctx context.Context, env *cli.Env, o options) error {
+	if o.syslog != "" {
+		h, w, err := syslog.Attach(env.Handler, o.syslog, &syslog.HandlerOptions{Facility: syslog.Daemon, AppName: env.Name})
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		slog.SetDefault(slog.New(h).With("app", env.Name))
+	}
+
+	var users map[string]string
+	if o.usersFile != "" {
+		var err error
+		if users, err = loadUsers(o.usersFile); err != nil {
+			return err
+		}
+	}
+
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{Port: o.port})
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	if o.capture != "" {
+		// only the listening port is captured: the client side of each session. the outgoing side goes to arbitrary ports.
+		stop, err := sniff.CaptureFile(ctx, o.capture, sniff.Config{Port: o.port})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := stop(); err != nil {
+				slog.ErrorContext(ctx, "main", "error", err.Error())
+			}
+		}()
+		slog.InfoContext(ctx, "main", "message", "capturing traffic", "file", o.capture)
+	}
+
+	// with no -metrics the registry stays nil, and the metrics it hands out ignore updates.
+	var reg *metrics.Registry
+	if o.metrics != "" {
+		format, addr, err := metrics.ParseAddr(o.metrics)
+		if err == nil {
+			reg, err = metrics.New(metrics.Config{Addr: addr, Format: format, Prefix: env.Name + "."})
+		}
+		if err != nil {
+			return err
+		}
+		defer reg.Close()
+		slog.InfoContext(ctx, "main", "message", "sending metrics", "collector", o.metrics)
+	}
+
+	s := &server{
+		metrics:          reg,
+		active:           reg.Gauge("sessions.active"),
+		connectTime:      reg.Timer("connect.latency"),
+		users:            users,
+		dialer:           net.Dialer{Timeout: o.dialTimeout},
+		handshakeTimeout: o.handshakeTimeout,
+		conns:            map[net.Conn]struct{}{},
+		usage:            map[string]*usage{},
+	}
+
+	go func() {
+		<-ctx.Done()
+		slog.InfoContext(ctx, "main", "message", "received shutdown signal")
+		listener.Close()
+	}()
+
+	if o.statsInterval > 0 {
+		go func() {
+			t := time.NewTicker(o.statsInterval)
+			defer t.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-t.C:
+					s.logUsage(ctx)
+				}
+			}
+		}()
+	}
+
+	auth := "none"
+	if users != nil {
+		auth = "username/password"
+	}
+	slog.InfoContext(ctx, "main", "message", "listening for connections", "port", o.port, "auth", auth)
+
+	var id atomic.Uint64
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			slog.ErrorContext(ctx, "main", "error", err.Error())
+			continue
+		}
+		// design note: unlike tcpupperecho's fixed worker pool, every session gets its own goroutine. proxied connections
+		// can stay open for hours, and a pool of N workers would let N idle sessions lock everyone else out.
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.serve(ctx, conn, id.Add(1))
+		}()
+	}
+
+	// hang up on sessions still in progress, then report the totals.
+	s.closeAll()
+	s.wg.Wait()
+	s.logUsage(ctx)
+	return nil
+}
+
+// loadUsers reads user:password lines. blank lines and lines starting with # are skipped.
+// credentials live in a file rather than a flag so they don't show up in ps.
+func loadUsers(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	users := map[string]string{}
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		user, pass, ok := strings.Cut(line, ":")
+		// RFC 1929 gives each field a one-byte length.
+		if !ok || user == "" || len(user) > 255 || len(pass) > 255 {
+			return nil, fmt.Errorf("%s:%d: expected user:password (each at most 255 bytes)", path, n)
+		}
+		users[user] = pass
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return nil, fmt.Errorf("%s: no users", path)
+	}
+	return users, nil
+}
+
+type server struct {
+	metrics     *metrics.Registry // nil without -metrics
+	active      *metrics.Gauge
+	connectTime *metrics.Timer
+
+	users            map[string]string
+	dialer           net.Dialer
+	handshakeTimeout time.Duration
+
+	wg sync.WaitGroup
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{} // open client connections, so shutdown can close them
+	usage map[string]*usage     // bandwidth per user ("-" for unauthenticated sessions)
+}
+
+// usage is cumulative bandwidth for one user.
+type usage struct {
+	sessions int
+	up, down int64 // client to destination, destination to client
+}
+
+func (s *server) track(conn net.Conn, open bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if open {
+		s.conns[conn] = struct{}{}
+	} else {
+		delete(s.conns, conn)
+	}
+}
+
+func (s *server) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+}
+
+func (s *server) account(user string, up, down int64) {
+	if user == "" {
+		user = "-"
+	}
+	// the same per-user totals as the log, but as counters: the collector can graph rates and alert on them.
+	s.metrics.Counter("bytes", "user", user, "direction", "up").Add(up)
+	s.metrics.Counter("bytes", "user", user, "direction", "down").Add(down)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.usage[user]
+	if !ok {
+		u = &usage{}
+		s.usage[user] = u
+	}
+	u.sessions++
+	u.up += up
+	u.down += down
+}
+
+func (s *server) logUsage(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	users := make([]string, 0, len(s.usage))
+	for user := range s.usage {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+	for _, user := range users {
+		u := s.usage[user]
+		slog.InfoContext(ctx, "usage", "user", user, "sessions", u.sessions, "bytes up", u.up, "bytes down", u.down)
+	}
+}
+
+// serve runs one SOCKS session: handshake, request, connect, then relay until either side hangs up.
+func (s *server) serve(ctx context.Context, conn net.Conn, id uint64) {
+	s.track(conn, true)
+	defer s.track(conn, false)
+	defer conn.Close()
+	s.active.Add(1)
+	defer s.active.Add(-1)
+
+	// every session ends up in exactly one result, so the counters add up to the sessions accepted.
+	result := "ok"
+	defer func() { s.metrics.Counter("sessions", "result", result).Inc() }()
+
+	log := slog.With("session", id, "client", conn.RemoteAddr().String())
+
+	// a client that connects and then says nothing shouldn't hold a goroutine and a file descriptor forever.
+	conn.SetDeadline(time.Now().Add(s.handshakeTimeout))
+
+	user, err := negotiate(conn, s.users)
+	if user != "" {
+		log = log.With("user", user)
+	}
+	if err != nil {
+		result = "handshake_failed"
+		log.WarnContext(ctx, "handshake", "error", err.Error())
+		return
+	}
+
+	cmd, addr, err := readRequest(conn)
+	if err != nil {
+		var reqErr *requestError
+		if errors.As(err, &reqErr) {
+			writeReply(conn, reqErr.code, nil)
+		}
+		result = "bad_request"
+		log.WarnContext(ctx, "request", "command", commandName(cmd), "target", addr, "error", err.Error())
+		return
+	}
+	log = log.With("target", addr)
+
+	dialStart := time.Now()
+	dst, err := s.dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		result = "connect_failed"
+		writeReply(conn, replyCode(err), nil)
+		log.WarnContext(ctx, "connect", "error", err.Error())
+		return
+	}
+	defer dst.Close()
+	s.connectTime.Since(dialStart)
+	if err := writeReply(conn, repSucceeded, dst.LocalAddr()); err != nil {
+		result = "connect_failed"
+		log.WarnContext(ctx, "connect", "error", err.Error())
+		return
+	}
+	conn.SetDeadline(time.Time{})
+	log.InfoContext(ctx, "connect", "message", "connected", "remote", dst.RemoteAddr().String())
+
+	start := time.Now()
+	up, down := relay(conn, dst)
+	s.account(user, up, down)
+	log.InfoContext(ctx, "close", "bytes up", up, "bytes down", down, "duration", time.Since(start).Round(time.Millisecond).String())
+}
+
+// relay copies both ways until both directions are done and returns the byte counts (client to destination,
+// destination to client). when one side finishes sending, the other side's write half is closed rather than the
+// whole connection, so a client that half-closes after its request still gets the full response. an error (like a
+// reset) tears down both connections instead; there's no one left to finish talking to.
+func relay(client, dst net.Conn) (up, down int64) {
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var err error
+		up, err = io.Copy(dst, client)
+		finish(dst, client, err)
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		down, err = io.Copy(client, dst)
+		finish(client, dst, err)
+	}()
+	wg.Wait()
+	return up, down
+}
+
+// finish ends one direction of the relay: to stopped sending cleanly (err == nil), or something broke.
+func finish(to, from net.Conn, err error) {
+	if tcp, ok := to.(*net.TCPConn); ok && err == nil {
+		tcp.CloseWrite()
+		return
+	}
+	to.Close()
+	from.Close()
+}
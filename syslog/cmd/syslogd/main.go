@@ -0,0 +1,9 @@
+// Command syslogd collects syslog messages; see package syslog.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/syslog"
+)
+
+func main() { cli.Main(syslog.Command) }
@@ -0,0 +1,209 @@
+package syslog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+// Command is syslogd, a small collector that writes every message it receives as one JSON record per line.
+var Command = &cli.Command{
+	Name:    "syslogd",
+	Summary: "syslog collector that prints messages as JSON lines",
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		udpAddr := fs.String("udp", ":514", `UDP address to listen on ("" to disable)`)
+		tcpAddr := fs.String("tcp", ":514", `TCP address to listen on ("" to disable)`)
+		output := fs.String("o", "", "append records to this file instead of writing them to stdout")
+		minSeverity := fs.String("severity", "debug", "drop messages less severe than this (emerg, alert, crit, err, warning, notice, info, debug)")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			if *udpAddr == "" && *tcpAddr == "" {
+				return cli.Usagef("nothing to listen on: both -udp and -tcp are empty")
+			}
+			threshold, err := parseSeverity(*minSeverity)
+			if err != nil {
+				return cli.Usagef("%v", err)
+			}
+			out := env.Stdout
+			if *output != "" {
+				f, err := os.OpenFile(*output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				out = f
+			}
+			c := &collector{enc: json.NewEncoder(out), threshold: threshold}
+
+			// open both sockets before serving either, so a bad address fails before anything is running.
+			var conn net.PacketConn
+			if *udpAddr != "" {
+				if conn, err = net.ListenPacket("udp", *udpAddr); err != nil {
+					return err
+				}
+				defer conn.Close()
+			}
+			var ln net.Listener
+			if *tcpAddr != "" {
+				if ln, err = net.Listen("tcp", *tcpAddr); err != nil {
+					return err
+				}
+				defer ln.Close()
+			}
+
+			var wg sync.WaitGroup
+			if conn != nil {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.serveUDP(ctx, conn)
+				}()
+				slog.InfoContext(ctx, "main", "message", "listening", "udp", conn.LocalAddr().String())
+			}
+			if ln != nil {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					c.serveTCP(ctx, ln)
+				}()
+				slog.InfoContext(ctx, "main", "message", "listening", "tcp", ln.Addr().String())
+			}
+
+			<-ctx.Done()
+			if conn != nil {
+				conn.Close()
+			}
+			if ln != nil {
+				ln.Close()
+			}
+			wg.Wait()
+			return nil
+		}
+	},
+}
+
+// collector turns received messages into JSON records, one per line.
+type collector struct {
+	threshold Severity // drop anything less severe (numerically greater)
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// record is what gets written for each message.
+type record struct {
+	Received  time.Time                    `json:"received"`
+	From      string                       `json:"from"`
+	Transport string                       `json:"transport"`
+	Format    string                       `json:"format,omitempty"`
+	Facility  string                       `json:"facility,omitempty"`
+	Severity  string                       `json:"severity,omitempty"`
+	Timestamp *time.Time                   `json:"timestamp,omitempty"`
+	Hostname  string                       `json:"hostname,omitempty"`
+	AppName   string                       `json:"app,omitempty"`
+	ProcID    string                       `json:"procid,omitempty"`
+	MsgID     string                       `json:"msgid,omitempty"`
+	Data      map[string]map[string]string `json:"data,omitempty"`
+	Msg       string                       `json:"msg,omitempty"`
+	// Error and Raw are set instead of the parsed fields when a message couldn't be parsed.
+	Error string `json:"error,omitempty"`
+	Raw   string `json:"raw,omitempty"`
+}
+
+func (c *collector) handle(raw []byte, from net.Addr, transport string) {
+	rec := record{Received: time.Now().UTC(), From: from.String(), Transport: transport}
+	m, err := Parse(raw)
+	if err != nil {
+		rec.Error, rec.Raw = err.Error(), string(raw)
+	} else {
+		if m.Severity > c.threshold {
+			return
+		}
+		rec.Format, rec.Facility, rec.Severity = m.Format.String(), m.Facility.String(), m.Severity.String()
+		if !m.Timestamp.IsZero() {
+			rec.Timestamp = &m.Timestamp
+		}
+		rec.Hostname, rec.AppName, rec.ProcID, rec.MsgID, rec.Msg = m.Hostname, m.AppName, m.ProcID, m.MsgID, m.Msg
+		for _, el := range m.Data {
+			if rec.Data == nil {
+				rec.Data = map[string]map[string]string{}
+			}
+			params := map[string]string{}
+			for _, p := range el.Params {
+				params[p.Name] = p.Value
+			}
+			rec.Data[el.ID] = params
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.enc.Encode(rec); err != nil {
+		slog.Error("output", "error", err.Error())
+	}
+}
+
+func (c *collector) serveUDP(ctx context.Context, conn net.PacketConn) {
+	buf := make([]byte, 64<<10) // the largest possible UDP payload
+	for {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.ErrorContext(ctx, "udp", "error", err.Error())
+			}
+			return
+		}
+		c.handle(buf[:n], from, "udp")
+	}
+}
+
+func (c *collector) serveTCP(ctx context.Context, ln net.Listener) {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.ErrorContext(ctx, "tcp", "error", err.Error())
+			}
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer conn.Close()
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+
+			r := NewReader(conn)
+			for {
+				frame, err := r.ReadFrame()
+				if err != nil {
+					if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+						slog.WarnContext(ctx, "tcp", "client", conn.RemoteAddr().String(), "error", err.Error())
+					}
+					return
+				}
+				c.handle(frame, conn.RemoteAddr(), "tcp")
+			}
+		}()
+	}
+}
+
+func parseSeverity(s string) (Severity, error) {
+	for sev := Emergency; sev <= Debug; sev++ {
+		if sev.String() == s {
+			return sev, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown severity %q", s)
+}
@@ -0,0 +1,95 @@
+package syslog
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// over UDP, one datagram is one message. over TCP there are no datagrams, so messages need framing; RFC 6587 documents
+// the two ways senders do it:
+//
+//	octet counting:   "71 <34>1 2003-10-11T22:14:15.003Z host app - - - hello"   (length, space, message)
+//	non-transparent:  "<34>Oct 11 22:14:15 host app: hello\n"                    (message, newline)
+//
+// octet counting is the robust one: a newline inside a message doesn't split it. receivers can tell the two apart
+// from the first byte of each frame, since a message always starts with '<' and a length always starts with a digit.
+
+// MaxFrameSize bounds one message on a stream, so a garbage length can't make the reader allocate gigabytes.
+const MaxFrameSize = 64 << 10
+
+// Reader reads messages from a TCP stream in either framing.
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader returns a Reader for the stream r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// ReadFrame returns the next message's bytes. it returns io.EOF at a clean end of stream.
+func (r *Reader) ReadFrame() ([]byte, error) {
+	first, err := r.r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] >= '1' && first[0] <= '9' {
+		return r.readCounted()
+	}
+	return r.readLine()
+}
+
+func (r *Reader) readCounted() ([]byte, error) {
+	prefix, err := r.r.ReadSlice(' ')
+	if err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("syslog: reading frame length: %w", err)
+	}
+	n, err := strconv.Atoi(string(prefix[:len(prefix)-1]))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("syslog: bad frame length %q", prefix)
+	}
+	if n > MaxFrameSize {
+		return nil, fmt.Errorf("syslog: frame of %d bytes exceeds %d", n, MaxFrameSize)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r.r, msg); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, fmt.Errorf("syslog: reading frame: %w", err)
+	}
+	return msg, nil
+}
+
+func (r *Reader) readLine() ([]byte, error) {
+	var line []byte
+	for {
+		frag, err := r.r.ReadSlice('\n')
+		line = append(line, frag...)
+		if len(line) > MaxFrameSize {
+			return nil, fmt.Errorf("syslog: line exceeds %d bytes", MaxFrameSize)
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && len(line) > 0:
+			return line, nil // the last message, without its newline
+		case err != nil:
+			return nil, err
+		}
+		return bytes.TrimRight(line, "\r\n"), nil
+	}
+}
+
+// AppendFrame appends msg to b with an octet-counting frame header.
+func AppendFrame(b, msg []byte) []byte {
+	b = strconv.AppendInt(b, int64(len(msg)), 10)
+	b = append(b, ' ')
+	return append(b, msg...)
+}
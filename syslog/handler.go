@@ -0,0 +1,201 @@
+package syslog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// sdID names the structured-data element that carries a record's attributes. IDs without an '@' are reserved for
+// IANA; private ones end in @<enterprise number>, and 32473 is the number set aside for documentation and examples.
+const sdID = "slog@32473"
+
+// HandlerOptions configures a Handler.
+type HandlerOptions struct {
+	// Level is the minimum level to send; nil means slog.LevelInfo.
+	Level slog.Leveler
+	// Facility goes into every message's priority; the zero value is Kern, so set it (User or Local0-7 are typical).
+	Facility Facility
+	// AppName identifies the program; empty means the executable's name.
+	AppName string
+	// Hostname empty means os.Hostname().
+	Hostname string
+}
+
+// Handler is a slog.Handler that sends records to a syslog collector as RFC 5424 messages. the record's message
+// becomes the syslog MSG; its attributes become structured data, so the collector gets them back as key/value
+// pairs instead of having to parse them out of text.
+type Handler struct {
+	w     *Writer
+	opts  HandlerOptions
+	pid   string
+	attrs []SDParam // from WithAttrs, already qualified by group
+	group string    // prefix from WithGroup, like "request."
+}
+
+// NewHandler returns a Handler that sends to w.
+func NewHandler(w *Writer, opts *HandlerOptions) *Handler {
+	h := &Handler{w: w, pid: strconv.Itoa(os.Getpid())}
+	if opts != nil {
+		h.opts = *opts
+	}
+	if h.opts.AppName == "" {
+		h.opts.AppName = appName()
+	}
+	if h.opts.Hostname == "" {
+		h.opts.Hostname, _ = os.Hostname()
+	}
+	return h
+}
+
+func appName() string {
+	exe := os.Args[0]
+	if i := strings.LastIndexAny(exe, `/\`); i >= 0 {
+		exe = exe[i+1:]
+	}
+	return exe
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return level >= min
+}
+
+// severity maps slog's open-ended levels onto syslog's eight.
+func severity(l slog.Level) Severity {
+	switch {
+	case l < slog.LevelInfo:
+		return Debug
+	case l < slog.LevelWarn:
+		return Informational
+	case l < slog.LevelError:
+		return Warning
+	case l == slog.LevelError:
+		return Error
+	default:
+		return Critical
+	}
+}
+
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	m := &Message{
+		Facility:  h.opts.Facility,
+		Severity:  severity(r.Level),
+		Timestamp: r.Time,
+		Hostname:  h.opts.Hostname,
+		AppName:   h.opts.AppName,
+		ProcID:    h.pid,
+		Msg:       r.Message,
+	}
+	params := append([]SDParam(nil), h.attrs...)
+	r.Attrs(func(a slog.Attr) bool {
+		params = appendAttr(params, h.group, a)
+		return true
+	})
+	if len(params) > 0 {
+		m.Data = []SDElement{{ID: sdID, Params: params}}
+	}
+	return h.w.Send(m)
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h2 := *h
+	h2.attrs = append([]SDParam(nil), h.attrs...)
+	for _, a := range attrs {
+		h2.attrs = appendAttr(h2.attrs, h.group, a)
+	}
+	return &h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.group = h.group + name + "."
+	return &h2
+}
+
+// appendAttr flattens a into name="value" params; groups become dotted names, like "request.method".
+func appendAttr(params []SDParam, prefix string, a slog.Attr) []SDParam {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return params
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "."
+		}
+		for _, ga := range a.Value.Group() {
+			params = appendAttr(params, prefix, ga)
+		}
+		return params
+	}
+	return append(params, SDParam{Name: prefix + a.Key, Value: a.Value.String()})
+}
+
+// Tee returns a handler that passes every record to all of hs, so a program can keep logging to stderr and ship the
+// same records to syslog. a record goes to each handler that's enabled for its level; errors are joined.
+func Tee(hs ...slog.Handler) slog.Handler {
+	return tee(hs)
+}
+
+type tee []slog.Handler
+
+func (t tee) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t tee) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			if err := h.Handle(ctx, r.Clone()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t tee) WithAttrs(attrs []slog.Attr) slog.Handler {
+	out := make(tee, len(t))
+	for i, h := range t {
+		out[i] = h.WithAttrs(attrs)
+	}
+	return out
+}
+
+func (t tee) WithGroup(name string) slog.Handler {
+	out := make(tee, len(t))
+	for i, h := range t {
+		out[i] = h.WithGroup(name)
+	}
+	return out
+}
+
+// Attach connects to the collector at target (see ParseAddr) and returns a handler that passes records to h and
+// sends a copy to the collector. it's how a tool with a -syslog flag keeps logging to stderr as well.
+// close the returned Writer when done.
+func Attach(h slog.Handler, target string, opts *HandlerOptions) (slog.Handler, *Writer, error) {
+	network, addr, err := ParseAddr(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	w, err := Dial(network, addr)
+	if err != nil {
+		return nil, nil, err
+	}
+	return Tee(h, NewHandler(w, opts)), w, nil
+}
@@ -0,0 +1,360 @@
+// Package syslog reads and writes syslog messages, ships slog records to a syslog collector, and frames
+// syslog over TCP.
+//
+// a syslog message starts with a priority in angle brackets, which packs two numbers into one: the facility (which
+// part of the system is talking: kernel, mail, local0...) and the severity (emergency down to debug), as
+// facility*8 + severity. what follows depends on the format:
+//
+//	RFC 3164 ("BSD"):  <34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8
+//	RFC 5424:          <165>1 2003-10-11T22:14:15.003Z mymachine myapp 1234 ID47 [exampleSDID@32473 iut="3"] An application event
+//
+// 3164 describes what old syslogd implementations did, so it's loose: no year, no time zone, and anything goes. 5424
+// is strict, adds a version, a real timestamp, and structured data: [id key="value" ...] blocks that carry
+// key/value pairs without inventing an ad-hoc format inside the message text.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Facility says which part of the system a message comes from.
+type Facility int
+
+const (
+	Kern Facility = iota
+	User
+	Mail
+	Daemon
+	Auth
+	Syslog
+	LPR
+	News
+	UUCP
+	Cron
+	AuthPriv
+	FTP
+	NTP
+	LogAudit
+	LogAlert
+	Clock
+	Local0
+	Local1
+	Local2
+	Local3
+	Local4
+	Local5
+	Local6
+	Local7
+)
+
+var facilityNames = []string{"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron",
+	"authpriv", "ftp", "ntp", "audit", "alert", "clock", "local0", "local1", "local2", "local3", "local4", "local5",
+	"local6", "local7"}
+
+func (f Facility) String() string {
+	if f >= 0 && int(f) < len(facilityNames) {
+		return facilityNames[f]
+	}
+	return fmt.Sprintf("facility(%d)", int(f))
+}
+
+// Severity says how bad it is; lower is worse.
+type Severity int
+
+const (
+	Emergency Severity = iota
+	Alert
+	Critical
+	Error
+	Warning
+	Notice
+	Informational
+	Debug
+)
+
+var severityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+func (s Severity) String() string {
+	if s >= 0 && int(s) < len(severityNames) {
+		return severityNames[s]
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// Priority packs a facility and a severity together the way the wire format does.
+func Priority(f Facility, s Severity) int { return int(f)*8 + int(s) }
+
+// Format is the wire format a message was parsed from or will be written in.
+type Format int
+
+const (
+	RFC5424 Format = iota
+	RFC3164
+)
+
+func (f Format) String() string {
+	if f == RFC3164 {
+		return "rfc3164"
+	}
+	return "rfc5424"
+}
+
+// SDElement is one structured-data block: [ID name="value" ...].
+type SDElement struct {
+	ID     string
+	Params []SDParam
+}
+
+// SDParam is one name="value" pair in an SDElement.
+type SDParam struct {
+	Name, Value string
+}
+
+// Message is a parsed syslog message. Fields the sender left out (the "-" NILVALUE in RFC 5424) are empty.
+type Message struct {
+	Format    Format
+	Facility  Facility
+	Severity  Severity
+	Timestamp time.Time // zero if absent
+	Hostname  string
+	AppName   string // the TAG in RFC 3164
+	ProcID    string
+	MsgID     string // RFC 5424 only
+	Data      []SDElement
+	Msg       string
+}
+
+// ErrMalformed is wrapped by every parse error.
+var ErrMalformed = errors.New("syslog: malformed message")
+
+func malformed(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrMalformed, fmt.Sprintf(format, args...))
+}
+
+// Parse parses a message in either format: RFC 5424 if a version number follows the priority, RFC 3164 otherwise.
+// 3164 parsing is lenient, as receivers are told to be; a message without a recognizable header is kept whole in Msg.
+func Parse(b []byte) (*Message, error) {
+	s := strings.TrimRight(string(b), "\r\n\x00")
+	if !strings.HasPrefix(s, "<") {
+		return nil, malformed("missing <PRI>")
+	}
+	end := strings.IndexByte(s, '>')
+	if end < 2 || end > 4 {
+		return nil, malformed("bad <PRI>")
+	}
+	pri, err := strconv.Atoi(s[1:end])
+	if err != nil || pri < 0 || pri > 191 {
+		return nil, malformed("bad priority %q", s[1:end])
+	}
+	m := &Message{Facility: Facility(pri / 8), Severity: Severity(pri % 8)}
+	rest := s[end+1:]
+
+	if strings.HasPrefix(rest, "1 ") {
+		return m, parse5424(m, rest[2:])
+	}
+	m.Format = RFC3164
+	parse3164(m, rest)
+	return m, nil
+}
+
+func parse5424(m *Message, s string) error {
+	m.Format = RFC5424
+	// TIMESTAMP HOSTNAME APP-NAME PROCID MSGID, each a single token or "-".
+	var fields [5]string
+	for i := range fields {
+		tok, rest, ok := strings.Cut(s, " ")
+		if !ok {
+			return malformed("truncated header")
+		}
+		if tok != "-" {
+			fields[i] = tok
+		}
+		s = rest
+	}
+	if fields[0] != "" {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return malformed("bad timestamp %q", fields[0])
+		}
+		m.Timestamp = ts
+	}
+	m.Hostname, m.AppName, m.ProcID, m.MsgID = fields[1], fields[2], fields[3], fields[4]
+
+	// STRUCTURED-DATA: "-", or one or more [...] blocks.
+	if strings.HasPrefix(s, "-") {
+		s = s[1:]
+	} else {
+		for strings.HasPrefix(s, "[") {
+			el, rest, err := parseSDElement(s)
+			if err != nil {
+				return err
+			}
+			m.Data = append(m.Data, el)
+			s = rest
+		}
+		if len(m.Data) == 0 {
+			return malformed("bad structured data")
+		}
+	}
+	if s != "" && s[0] != ' ' {
+		return malformed("no space after structured data")
+	}
+	// an RFC 5424 message may start with a UTF-8 byte order mark, which only says "this is UTF-8".
+	m.Msg = strings.TrimPrefix(strings.TrimPrefix(s, " "), "\ufeff")
+	return nil
+}
+
+// parseSDElement parses [id name="value" ...] at the start of s and returns what follows it.
+// inside values, '"', '\' and ']' are escaped with a backslash.
+func parseSDElement(s string) (SDElement, string, error) {
+	s = s[1:]
+	i := strings.IndexAny(s, " ]")
+	if i <= 0 {
+		return SDElement{}, "", malformed("bad SD-ID")
+	}
+	el := SDElement{ID: s[:i]}
+	s = s[i:]
+	for {
+		if strings.HasPrefix(s, "]") {
+			return el, s[1:], nil
+		}
+		if !strings.HasPrefix(s, " ") {
+			return SDElement{}, "", malformed("bad SD-PARAM in %q", el.ID)
+		}
+		name, rest, ok := strings.Cut(s[1:], `="`)
+		if !ok || name == "" {
+			return SDElement{}, "", malformed("bad SD-PARAM in %q", el.ID)
+		}
+		var val strings.Builder
+		closed := false
+		for j := 0; j < len(rest); j++ {
+			c := rest[j]
+			if c == '\\' && j+1 < len(rest) && strings.IndexByte(`"\]`, rest[j+1]) >= 0 {
+				val.WriteByte(rest[j+1])
+				j++
+				continue
+			}
+			if c == '"' {
+				s, closed = rest[j+1:], true
+				break
+			}
+			val.WriteByte(c)
+		}
+		if !closed {
+			return SDElement{}, "", malformed("unterminated SD-PARAM value in %q", el.ID)
+		}
+		el.Params = append(el.Params, SDParam{name, val.String()})
+	}
+}
+
+// parse3164 picks apart "Mmm dd hh:mm:ss HOSTNAME TAG[PID]: MSG" as far as it can.
+func parse3164(m *Message, s string) {
+	const stamp = "Jan _2 15:04:05"
+	if len(s) > len(stamp) && s[len(stamp)] == ' ' {
+		if ts, err := time.ParseInLocation(stamp, s[:len(stamp)], time.Local); err == nil {
+			// no year on the wire: assume this one, unless that puts it in the future (a December message read in January).
+			now := time.Now()
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.Add(24 * time.Hour)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			m.Timestamp = ts
+			s = s[len(stamp)+1:]
+			if host, rest, ok := strings.Cut(s, " "); ok {
+				m.Hostname, s = host, rest
+			}
+		}
+	}
+	// the TAG is up to 32 alphanumerics, optionally followed by [pid], ending at ':' or '['.
+	if i := strings.IndexAny(s, ":[ "); i > 0 && i <= 32 && s[i] != ' ' {
+		tag, rest := s[:i], s[i:]
+		if rest[0] == '[' {
+			if j := strings.IndexByte(rest, ']'); j > 0 {
+				m.ProcID, rest = rest[1:j], rest[j+1:]
+			}
+		}
+		if strings.HasPrefix(rest, ":") {
+			m.AppName, s = tag, strings.TrimPrefix(rest[1:], " ")
+		}
+	}
+	m.Msg = s
+}
+
+// AppendRFC5424 appends the message in RFC 5424 format (whatever format it was parsed from).
+func (m *Message) AppendRFC5424(b []byte) []byte {
+	b = fmt.Appendf(b, "<%d>1 ", Priority(m.Facility, m.Severity))
+	if m.Timestamp.IsZero() {
+		b = append(b, '-')
+	} else {
+		b = m.Timestamp.AppendFormat(b, "2006-01-02T15:04:05.000000Z07:00")
+	}
+	for _, f := range []struct {
+		v   string
+		max int
+	}{{m.Hostname, 255}, {m.AppName, 48}, {m.ProcID, 128}, {m.MsgID, 32}} {
+		b = append(b, ' ')
+		b = append(b, headerField(f.v, f.max)...)
+	}
+	b = append(b, ' ')
+	if len(m.Data) == 0 {
+		b = append(b, '-')
+	}
+	for _, el := range m.Data {
+		b = append(b, '[')
+		b = append(b, sdName(el.ID)...)
+		for _, p := range el.Params {
+			b = append(b, ' ')
+			b = append(b, sdName(p.Name)...)
+			b = append(b, '=', '"')
+			for i := 0; i < len(p.Value); i++ {
+				if c := p.Value[i]; c == '"' || c == '\\' || c == ']' {
+					b = append(b, '\\')
+				}
+				b = append(b, p.Value[i])
+			}
+			b = append(b, '"')
+		}
+		b = append(b, ']')
+	}
+	if m.Msg != "" {
+		b = append(b, ' ')
+		b = append(b, m.Msg...)
+	}
+	return b
+}
+
+// String returns the message in RFC 5424 format.
+func (m *Message) String() string { return string(m.AppendRFC5424(nil)) }
+
+// headerField makes v legal for a header field: printable ASCII with no spaces, at most max bytes, "-" if empty.
+func headerField(v string, max int) string {
+	if v == "" {
+		return "-"
+	}
+	b := []byte(v)
+	for i, c := range b {
+		if c <= ' ' || c > '~' {
+			b[i] = '_'
+		}
+	}
+	if len(b) > max {
+		b = b[:max]
+	}
+	return string(b)
+}
+
+// sdName makes an SD-ID or PARAM-NAME legal: like headerField, but '=', ']' and '"' are out too.
+func sdName(v string) string {
+	b := []byte(headerField(v, 32))
+	for i, c := range b {
+		if bytes.IndexByte([]byte(`="]`), c) >= 0 {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -0,0 +1,195 @@
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse5424(t *testing.T) {
+	// examples from RFC 5424 section 6.5.
+	m, err := Parse([]byte(`<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application" eventID="1011"][examplePriority@32473 class="high"] An application event log entry`))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if m.Format != RFC5424 || m.Facility != Local4 || m.Severity != Notice {
+		t.Errorf("format/facility/severity = %s/%s/%s, want rfc5424/local4/notice", m.Format, m.Facility, m.Severity)
+	}
+	if want := time.Date(2003, 10, 11, 22, 14, 15, 3e6, time.UTC); !m.Timestamp.Equal(want) {
+		t.Errorf("Timestamp = %s, want %s", m.Timestamp, want)
+	}
+	if m.Hostname != "mymachine.example.com" || m.AppName != "evntslog" || m.ProcID != "" || m.MsgID != "ID47" {
+		t.Errorf("header = %q %q %q %q", m.Hostname, m.AppName, m.ProcID, m.MsgID)
+	}
+	if len(m.Data) != 2 || m.Data[0].ID != "exampleSDID@32473" || len(m.Data[0].Params) != 3 || m.Data[1].Params[0] != (SDParam{"class", "high"}) {
+		t.Errorf("Data = %+v", m.Data)
+	}
+	if m.Msg != "An application event log entry" {
+		t.Errorf("Msg = %q", m.Msg)
+	}
+
+	// escapes in values, and no message at all.
+	m, err = Parse([]byte(`<14>1 - - - - - [a@1 v="say \"hi\" \] \\"]`))
+	if err != nil || m.Data[0].Params[0].Value != `say "hi" ] \` || m.Msg != "" {
+		t.Errorf("Parse(escapes) = %+v, %v", m, err)
+	}
+
+	for _, bad := range []string{"no priority", "<999>1 - - - - - -", "<14>1 - - -", "<14>1 - - - - - [unterminated", `<14>1 - - - - - [a@1 v="open]`} {
+		if _, err := Parse([]byte(bad)); !errors.Is(err, ErrMalformed) {
+			t.Errorf("Parse(%q) error = %v, want ErrMalformed", bad, err)
+		}
+	}
+}
+
+func TestParse3164(t *testing.T) {
+	m, err := Parse([]byte("<34>Oct 11 22:14:15 mymachine su[123]: 'su root' failed for lonvick on /dev/pts/8\n"))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if m.Format != RFC3164 || m.Facility != Auth || m.Severity != Critical {
+		t.Errorf("format/facility/severity = %s/%s/%s, want rfc3164/auth/crit", m.Format, m.Facility, m.Severity)
+	}
+	if m.Timestamp.Month() != time.October || m.Timestamp.Day() != 11 || m.Hostname != "mymachine" || m.AppName != "su" || m.ProcID != "123" {
+		t.Errorf("header = %s %q %q %q", m.Timestamp, m.Hostname, m.AppName, m.ProcID)
+	}
+	if m.Msg != "'su root' failed for lonvick on /dev/pts/8" {
+		t.Errorf("Msg = %q", m.Msg)
+	}
+
+	// no header at all: RFC 3164 says to keep the whole thing as the message.
+	m, err = Parse([]byte("<13>just some text"))
+	if err != nil || m.Msg != "just some text" || m.AppName != "" {
+		t.Errorf("Parse(bare) = %+v, %v", m, err)
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	m := &Message{
+		Facility:  Local0,
+		Severity:  Warning,
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC),
+		Hostname:  "host name", // the space isn't allowed in a header field
+		AppName:   "app",
+		ProcID:    "42",
+		Data:      []SDElement{{ID: "x@32473", Params: []SDParam{{"k", `a "quoted" ] value`}}}},
+		Msg:       "hello\nworld",
+	}
+	got, err := Parse(m.AppendRFC5424(nil))
+	if err != nil {
+		t.Fatalf("Parse(%q) error: %v", m.String(), err)
+	}
+	if got.Hostname != "host_name" || got.Data[0].Params[0].Value != m.Data[0].Params[0].Value || got.Msg != m.Msg || !got.Timestamp.Equal(m.Timestamp) {
+		t.Errorf("round trip of %q = %+v", m.String(), got)
+	}
+}
+
+func TestReader(t *testing.T) {
+	var stream []byte
+	stream = AppendFrame(stream, []byte("<14>1 - - - - - - multi\nline"))
+	stream = append(stream, "<14>old style\n"...)
+	stream = AppendFrame(stream, []byte("<14>1 - - - - - - last"))
+	stream = append(stream, "<14>no trailing newline"...)
+
+	r := NewReader(bytes.NewReader(stream))
+	for _, want := range []string{"<14>1 - - - - - - multi\nline", "<14>old style", "<14>1 - - - - - - last", "<14>no trailing newline"} {
+		got, err := r.ReadFrame()
+		if err != nil || string(got) != want {
+			t.Errorf("ReadFrame() = %q, %v; want %q", got, err, want)
+		}
+	}
+	if _, err := r.ReadFrame(); err != io.EOF {
+		t.Errorf("ReadFrame() at end = %v, want io.EOF", err)
+	}
+
+	if _, err := NewReader(strings.NewReader("99999999 x")).ReadFrame(); err == nil {
+		t.Errorf("ReadFrame(huge length) succeeded; want error")
+	}
+	if _, err := NewReader(strings.NewReader("10 short")).ReadFrame(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("ReadFrame(truncated) error = %v, want io.ErrUnexpectedEOF", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	for _, network := range []string{"udp", "tcp"} {
+		t.Run(network, func(t *testing.T) {
+			frames := make(chan []byte, 1)
+			var addr string
+			if network == "udp" {
+				conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer conn.Close()
+				addr = conn.LocalAddr().String()
+				go func() {
+					buf := make([]byte, 1500)
+					n, _, _ := conn.ReadFrom(buf)
+					frames <- buf[:n]
+				}()
+			} else {
+				ln, err := net.Listen("tcp", "127.0.0.1:0")
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer ln.Close()
+				addr = ln.Addr().String()
+				go func() {
+					conn, err := ln.Accept()
+					if err != nil {
+						return
+					}
+					defer conn.Close()
+					f, _ := NewReader(conn).ReadFrame()
+					frames <- f
+				}()
+			}
+
+			w, err := Dial(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer w.Close()
+			log := slog.New(NewHandler(w, &HandlerOptions{Facility: Local3, AppName: "test", Hostname: "h"}))
+			log.Debug("dropped: below the default level")
+			log.With("app", "x").WithGroup("req").Warn("slow request", "ms", 1500, slog.Group("client", "ip", "10.0.0.1"))
+
+			m, err := Parse(<-frames)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Facility != Local3 || m.Severity != Warning || m.AppName != "test" || m.Msg != "slow request" {
+				t.Errorf("got %+v", m)
+			}
+			want := []SDParam{{"app", "x"}, {"req.ms", "1500"}, {"req.client.ip", "10.0.0.1"}}
+			if len(m.Data) != 1 || m.Data[0].ID != sdID || len(m.Data[0].Params) != len(want) {
+				t.Fatalf("Data = %+v, want %v", m.Data, want)
+			}
+			for i, p := range want {
+				if m.Data[0].Params[i] != p {
+					t.Errorf("param %d = %+v, want %+v", i, m.Data[0].Params[i], p)
+				}
+			}
+		})
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	for in, want := range map[string][2]string{
+		"localhost":           {"udp", "localhost:514"},
+		"udp://10.0.0.1:5514": {"udp", "10.0.0.1:5514"},
+		"tcp://collector":     {"tcp", "collector:514"},
+	} {
+		network, addr, err := ParseAddr(in)
+		if err != nil || network != want[0] || addr != want[1] {
+			t.Errorf("ParseAddr(%q) = %q, %q, %v; want %q, %q", in, network, addr, err, want[0], want[1])
+		}
+	}
+	if _, _, err := ParseAddr("http://x"); err == nil {
+		t.Errorf("ParseAddr(http://) succeeded; want error")
+	}
+}
@@ -0,0 +1,102 @@
+package syslog
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Writer sends messages to a collector. It is safe for concurrent use.
+type Writer struct {
+	network, addr string
+
+	mu   sync.Mutex
+	conn net.Conn
+	buf  []byte
+}
+
+// Dial connects to a collector. network is "udp" or "tcp"; messages go out in RFC 5424 format, octet-counted on TCP.
+func Dial(network, addr string) (*Writer, error) {
+	switch network {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return nil, fmt.Errorf("syslog: unsupported network %q", network)
+	}
+	w := &Writer{network: network, addr: addr}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) connect() error {
+	conn, err := net.DialTimeout(w.network, w.addr, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("syslog: %w", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+func (w *Writer) stream() bool { return w.network[:3] == "tcp" }
+
+// Send writes one message. on TCP a failed write reconnects and tries once more, so a restarted collector doesn't
+// silence the sender for good; UDP has no connection to lose, and a lost datagram is just lost.
+func (w *Writer) Send(m *Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	b := m.AppendRFC5424(w.buf[:0])
+	if w.stream() {
+		b = AppendFrame(nil, b)
+	}
+	w.buf = b[:0]
+
+	if w.conn == nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+	}
+	_, err := w.conn.Write(b)
+	if err != nil && w.stream() {
+		w.conn.Close()
+		w.conn = nil
+		if err = w.connect(); err == nil {
+			_, err = w.conn.Write(b)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("syslog: sending to %s: %w", w.addr, err)
+	}
+	return nil
+}
+
+// Close closes the connection.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// ParseAddr splits a collector address like "udp://localhost:514" or "tcp://10.0.0.5:6514" into network and address
+// for Dial. a bare host:port means UDP, and a missing port means 514.
+func ParseAddr(s string) (network, addr string, err error) {
+	network = "udp"
+	if n, rest, ok := strings.Cut(s, "://"); ok {
+		network, s = n, rest
+	}
+	if network != "udp" && network != "tcp" {
+		return "", "", fmt.Errorf("syslog: unsupported scheme %q in %q (want udp or tcp)", network, s)
+	}
+	if _, _, err := net.SplitHostPort(s); err != nil {
+		s += ":514"
+	}
+	return network, s, nil
+}
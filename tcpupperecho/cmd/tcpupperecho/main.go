@@ -0,0 +1,9 @@
+// Command tcpupperecho is an uppercasing echo server; see package tcpupperecho.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/tcpupperecho"
+)
+
+func main() { cli.Main(tcpupperecho.Command) }
@@ -1,3 +1,17 @@
 module github.com/ekediala/tcpupperecho
 
 go 1.23.1
+
+require (
+	github.com/ekediala/cli v0.0.0
+	github.com/ekediala/metrics v0.0.0
+	github.com/ekediala/sniff v0.0.0
+	github.com/ekediala/syslog v0.0.0
+)
+
+replace (
+	github.com/ekediala/cli => ../cli
+	github.com/ekediala/metrics => ../metrics
+	github.com/ekediala/sniff => ../sniff
+	github.com/ekediala/syslog => ../syslog
+)
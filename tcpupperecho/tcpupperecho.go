@@ -0,0 +1,186 @@
+// Package tcpupperecho is the tcpupperecho tool: a TCP server that echoes back every line it receives in uppercase,
+// or put through some other transform, or a chain of them.
+package tcpupperecho
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/ekediala/cli"
+	"github.com/ekediala/metrics"
+	"github.com/ekediala/sniff"
+	"github.com/ekediala/syslog"
+)
+
+// Command is the tcpupperecho tool.
+var Command = &cli.Command{
+	Name:    "tcpupperecho",
+	Summary: "TCP server that echoes lines back in uppercase, or otherwise transformed",
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		var o options
+		fs.IntVar(&o.port, "p", 8080, "port to listen on")
+		fs.StringVar(&o.transform, "transform", "upper", "what to do to each line: upper, lower, reverse, rot13, or none, or several, comma-separated, applied in order, like reverse,upper")
+		fs.StringVar(&o.capture, "capture", "", "record the server's traffic to this pcap file (Linux only; needs root or CAP_NET_RAW)")
+		fs.StringVar(&o.syslog, "syslog", "", "also send logs to this syslog collector, like udp://localhost:514")
+		fs.StringVar(&o.metrics, "metrics", "", "send metrics to this StatsD or Graphite collector, like statsd://localhost:8125")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			return run(ctx, env, o)
+		}
+	},
+}
+
+type options struct {
+	port                     int
+	transform                string
+	capture, syslog, metrics string
+}
+
+func run(ctx context.Context, env *cli.Env, o options) error {
+	t, err := ParseTransform(o.transform)
+	if err != nil {
+		return fmt.Errorf("-transform: %w", err)
+	}
+
+	if o.syslog != "" {
+		h, w, err := syslog.Attach(env.Handler, o.syslog, &syslog.HandlerOptions{Facility: syslog.Daemon, AppName: env.Name})
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		slog.SetDefault(slog.New(h).With("app", env.Name))
+	}
+
+	if o.capture != "" {
+		stop, err := sniff.CaptureFile(ctx, o.capture, sniff.Config{Port: o.port})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if err := stop(); err != nil {
+				slog.ErrorContext(ctx, "main", "error", err.Error())
+			}
+		}()
+		slog.InfoContext(ctx, "main", "message", "capturing traffic", "file", o.capture)
+	}
+
+	// with no -metrics the registry stays nil, and nil metrics ignore updates.
+	var reg *metrics.Registry
+	if o.metrics != "" {
+		format, addr, err := metrics.ParseAddr(o.metrics)
+		if err != nil {
+			return err
+		}
+		reg, err = metrics.New(metrics.Config{Addr: addr, Format: format, Prefix: env.Name + ".", Tags: []string{"port", fmt.Sprint(o.port)}})
+		if err != nil {
+			return err
+		}
+		defer reg.Close()
+		slog.InfoContext(ctx, "main", "message", "sending metrics", "collector", o.metrics)
+	}
+	m := &instruments{
+		conns:   reg.Counter("connections"),
+		active:  reg.Gauge("connections.active"),
+		lines:   reg.Counter("lines"),
+		bytes:   reg.Counter("bytes"),
+		errors:  reg.Counter("errors"),
+		latency: reg.Timer("line.latency"),
+	}
+
+	// ListenTCP creates a TCP listener accepting connections on the given address.
+	// TCPAddr represents the address of a TCP end point; it has an IP, Port, and Zone, all of which are optional.
+	// Zone only matters for IPv6; we'll ignore it for now.
+	// If we omit the IP, it means we are listening on all available IP addresses; if we omit the Port, it means we are listening on a random port.
+	// We want to listen on a port specified by the user on the command-line.
+	// see https://golang.org/pkg/net/#ListenTCP and https://golang.org/pkg/net/#Dial for details.
+	listener, err := net.ListenTCP("tcp", &net.TCPAddr{Port: o.port})
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	numWorkers := runtime.NumCPU()
+	connChan := make(chan net.Conn, numWorkers)
+	wg := sync.WaitGroup{}
+	wg.Add(numWorkers)
+
+	go func() {
+		for range numWorkers {
+			go worker(ctx, connChan, &wg, t, m)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+		slog.InfoContext(ctx, "main", "message", "received shutdown signal")
+
+		close(connChan)
+		wg.Wait()
+
+		listener.Close()
+	}()
+
+	slog.InfoContext(ctx, "main", "message", "listening for connections", "port", o.port)
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				slog.InfoContext(ctx, "main", "message", "connection closed")
+				return nil
+			}
+			return err
+		}
+		connChan <- conn
+	}
+
+}
+
+// instruments are the server's metrics. they're all nil (and do nothing) without -metrics.
+type instruments struct {
+	conns   *metrics.Counter // connections accepted
+	active  *metrics.Gauge   // connections being served right now
+	lines   *metrics.Counter // lines echoed
+	bytes   *metrics.Counter // bytes echoed back
+	errors  *metrics.Counter // failed writes
+	latency *metrics.Timer   // time to transform and write back one line
+}
+
+func worker(ctx context.Context, connChan <-chan net.Conn, wg *sync.WaitGroup, t Transformer, m *instruments) {
+	defer wg.Done()
+
+	for conn := range connChan {
+		m.conns.Inc()
+		m.active.Add(1)
+		echo(ctx, conn, conn, t, m)
+		m.active.Add(-1)
+		conn.Close()
+	}
+
+}
+
+// echo writes each line read from r back to w, put through t.
+func echo(ctx context.Context, w io.Writer, r io.Reader, t Transformer, m *instruments) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		start := time.Now()
+		n, err := io.WriteString(w, t.Transform(scanner.Text())+"\n")
+		m.latency.Since(start)
+		m.lines.Inc()
+		m.bytes.Add(int64(n))
+		if err != nil {
+			m.errors.Inc()
+			slog.ErrorContext(ctx, "echo", "error", err.Error())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		slog.ErrorContext(ctx, "echo", "error", err.Error())
+	}
+}
@@ -0,0 +1,41 @@
+package tcpupperecho
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestParseTransform(t *testing.T) {
+	for spec, want := range map[string]string{
+		"upper":         "HELLO, WÖRLD",
+		"lower":         "hello, wörld",
+		"reverse":       "DLRöW ,olleH",
+		"rot13":         "Uryyb, JöEYQ",
+		"none":          "Hello, WöRLD",
+		"reverse,upper": "DLRÖW ,OLLEH",
+		"rot13, rot13":  "Hello, WöRLD",
+	} {
+		tr, err := ParseTransform(spec)
+		if err != nil {
+			t.Fatalf("ParseTransform(%q) returned error: %v", spec, err)
+		}
+		if got := tr.Transform("Hello, WöRLD"); got != want {
+			t.Errorf("ParseTransform(%q).Transform = %q, want %q", spec, got, want)
+		}
+	}
+	for _, spec := range []string{"", "shout", "upper,"} {
+		if _, err := ParseTransform(spec); err == nil {
+			t.Errorf("ParseTransform(%q) succeeded; want an error", spec)
+		}
+	}
+}
+
+func TestEcho(t *testing.T) {
+	var out strings.Builder
+	tr, _ := ParseTransform("upper")
+	echo(context.Background(), &out, strings.NewReader("one\n100% two\nthree"), tr, &instruments{})
+	if got, want := out.String(), "ONE\n100% TWO\nTHREE\n"; got != want {
+		t.Errorf("echo = %q, want %q", got, want)
+	}
+}
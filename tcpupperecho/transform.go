@@ -0,0 +1,88 @@
+package tcpupperecho
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+/* design note: uppercasing was the whole server once, which made it a fine first TCP server and not much else. a
+   Transformer is what's done to each line, so the server can be anything that answers a line with a line, and
+   -transform picks one by name. names separated by commas make a chain, applied left to right: reverse,upper
+   reverses the line, then uppercases it. the default is upper, so the server does what its name says unless told
+   otherwise.
+
+   a transform sees one line at a time, without its newline, and can't keep state between lines, or answer with
+   more than one line: that's all the echo loop needs, and it keeps every transform a plain function of a string.
+*/
+
+// Transformer turns a line into the line to echo back.
+type Transformer interface {
+	Transform(line string) string
+}
+
+// TransformFunc is a function that's a Transformer.
+type TransformFunc func(line string) string
+
+func (f TransformFunc) Transform(line string) string { return f(line) }
+
+// Chain is a Transformer that applies each of its transformers in turn.
+type Chain []Transformer
+
+func (c Chain) Transform(line string) string {
+	for _, t := range c {
+		line = t.Transform(line)
+	}
+	return line
+}
+
+// transforms are the transformers -transform can name.
+var transforms = map[string]Transformer{
+	"upper":   TransformFunc(strings.ToUpper),
+	"lower":   TransformFunc(strings.ToLower),
+	"reverse": TransformFunc(reverse),
+	"rot13":   TransformFunc(rot13),
+	"none":    TransformFunc(func(line string) string { return line }),
+}
+
+// ParseTransform returns the transformer for s: a name, like "upper", or comma-separated names, like
+// "reverse,upper", for a Chain of them.
+func ParseTransform(s string) (Transformer, error) {
+	var c Chain
+	for _, name := range strings.Split(s, ",") {
+		t, ok := transforms[strings.TrimSpace(name)]
+		if !ok {
+			names := make([]string, 0, len(transforms))
+			for k := range transforms {
+				names = append(names, k)
+			}
+			slices.Sort(names)
+			return nil, fmt.Errorf("unknown transform %q: want one of %s", name, strings.Join(names, ", "))
+		}
+		c = append(c, t)
+	}
+	if len(c) == 1 {
+		return c[0], nil
+	}
+	return c, nil
+}
+
+// reverse returns line backwards, a character, not a byte, at a time.
+func reverse(line string) string {
+	r := []rune(line)
+	slices.Reverse(r)
+	return string(r)
+}
+
+// rot13 moves each ASCII letter in line 13 places along the alphabet, so doing it twice gets line back.
+func rot13(line string) string {
+	return strings.Map(func(c rune) rune {
+		switch {
+		case c >= 'a' && c <= 'z':
+			return 'a' + (c-'a'+13)%26
+		case c >= 'A' && c <= 'Z':
+			return 'A' + (c-'A'+13)%26
+		}
+		return c
+	}, line)
+}
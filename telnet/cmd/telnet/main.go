@@ -0,0 +1,9 @@
+// Command telnet is a telnet client; see package telnet.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/telnet"
+)
+
+func main() { cli.Main(telnet.Command) }
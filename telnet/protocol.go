@@ -0,0 +1,261 @@
+package telnet
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// Telnet (RFC 854) is mostly a plain byte stream, with an escape byte, IAC ("interpret as command", 255),
+// that introduces in-band commands. the important ones are option negotiation: either side can offer to enable
+// an option on its end (WILL), ask the other side to enable one (DO), or refuse/disable (WONT/DONT).
+// for example a server that wants to do the echoing itself sends IAC WILL ECHO; the client agrees with IAC DO ECHO
+// and stops echoing locally.
+//
+// a literal 255 in the data is sent doubled (IAC IAC). subnegotiation (IAC SB <option> ... IAC SE) carries option
+// parameters like the terminal type; we don't enable any option that needs one, so we skip it.
+
+// commands
+const (
+	cmdSE   = 240 // end of subnegotiation
+	cmdNOP  = 241
+	cmdBRK  = 243 // break
+	cmdIP   = 244 // interrupt process
+	cmdAYT  = 246 // are you there
+	cmdGA   = 249 // go ahead
+	cmdSB   = 250 // start of subnegotiation
+	cmdWILL = 251
+	cmdWONT = 252
+	cmdDO   = 253
+	cmdDONT = 254
+	cmdIAC  = 255
+)
+
+// options
+const (
+	optEcho  = 1  // RFC 857
+	optSGA   = 3  // suppress go-ahead, RFC 858
+	optTType = 24 // terminal type, RFC 1091
+	optNAWS  = 31 // window size, RFC 1073
+	optLine  = 34 // linemode, RFC 1184
+)
+
+func commandName(c byte) string {
+	switch c {
+	case cmdWILL:
+		return "WILL"
+	case cmdWONT:
+		return "WONT"
+	case cmdDO:
+		return "DO"
+	case cmdDONT:
+		return "DONT"
+	default:
+		return fmt.Sprintf("CMD(%d)", c)
+	}
+}
+
+func optionName(o byte) string {
+	switch o {
+	case optEcho:
+		return "ECHO"
+	case optSGA:
+		return "SGA"
+	case optTType:
+		return "TERMINAL-TYPE"
+	case optNAWS:
+		return "NAWS"
+	case optLine:
+		return "LINEMODE"
+	default:
+		return fmt.Sprintf("OPTION(%d)", o)
+	}
+}
+
+// negotiator tracks which options are enabled on each side and answers the server's requests.
+//
+// the classic pitfall is negotiation loops: if both sides acknowledge every request, a WILL answered by DO answered
+// by WILL... goes on forever. RFC 854's rule avoids it: only reply to a request that would change the option's state.
+type negotiator struct {
+	w io.Writer // the connection; writes are serialized with mu
+
+	// accept holds the options we let the server enable on its side (it sends WILL, we answer DO).
+	// offer holds the options we're willing to enable on our side (it sends DO, we answer WILL).
+	// everything else is refused.
+	accept, offer map[byte]bool
+
+	mu     sync.Mutex
+	remote map[byte]bool // options enabled on the server's side
+	local  map[byte]bool // options enabled on our side
+
+	onChange func() // called (without mu held) whenever an option's state changes
+}
+
+func newNegotiator(w io.Writer, accept, offer []byte) *negotiator {
+	n := &negotiator{w: w, accept: map[byte]bool{}, offer: map[byte]bool{}, remote: map[byte]bool{}, local: map[byte]bool{}}
+	for _, o := range accept {
+		n.accept[o] = true
+	}
+	for _, o := range offer {
+		n.offer[o] = true
+	}
+	return n
+}
+
+// send writes raw bytes (already escaped) to the connection.
+func (n *negotiator) send(b ...byte) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err := n.w.Write(b)
+	return err
+}
+
+func (n *negotiator) reply(verb, opt byte) {
+	slog.Debug("negotiate", "sent", commandName(verb)+" "+optionName(opt))
+	n.w.Write([]byte{cmdIAC, verb, opt})
+}
+
+// handle answers one negotiation command from the server.
+func (n *negotiator) handle(verb, opt byte) {
+	slog.Debug("negotiate", "received", commandName(verb)+" "+optionName(opt))
+	n.mu.Lock()
+	changed := false
+	switch verb {
+	case cmdWILL:
+		switch {
+		case !n.accept[opt]:
+			n.reply(cmdDONT, opt)
+		case !n.remote[opt]:
+			n.remote[opt], changed = true, true
+			n.reply(cmdDO, opt)
+		}
+	case cmdWONT:
+		if n.remote[opt] {
+			n.remote[opt], changed = false, true
+			n.reply(cmdDONT, opt)
+		}
+	case cmdDO:
+		switch {
+		case !n.offer[opt]:
+			n.reply(cmdWONT, opt)
+		case !n.local[opt]:
+			n.local[opt], changed = true, true
+			n.reply(cmdWILL, opt)
+		}
+	case cmdDONT:
+		if n.local[opt] {
+			n.local[opt], changed = false, true
+			n.reply(cmdWONT, opt)
+		}
+	}
+	n.mu.Unlock()
+	if changed && n.onChange != nil {
+		n.onChange()
+	}
+}
+
+// remoteEnabled reports whether the server has opt enabled on its side.
+func (n *negotiator) remoteEnabled(opt byte) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.remote[opt]
+}
+
+// status describes the enabled options, for the escape prompt's status command.
+func (n *negotiator) status() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	s := "server options:"
+	for o, on := range n.remote {
+		if on {
+			s += " " + optionName(o)
+		}
+	}
+	s += "; client options:"
+	for o, on := range n.local {
+		if on {
+			s += " " + optionName(o)
+		}
+	}
+	return s
+}
+
+// parser states
+const (
+	stData = iota
+	stIAC  // saw IAC
+	stVerb // saw IAC WILL/WONT/DO/DONT, waiting for the option
+	stSB   // inside a subnegotiation
+	stSBIAC
+)
+
+// reader strips telnet commands out of the server's stream, handing negotiations to the negotiator
+// and returning only the data.
+type reader struct {
+	r     io.Reader
+	n     *negotiator
+	state int
+	verb  byte
+	buf   []byte
+}
+
+// Read reads from the server and returns the data bytes in it. it can return 0 bytes (and no error) if a read
+// contained only commands.
+func (r *reader) Read(p []byte) (int, error) {
+	if cap(r.buf) < len(p) {
+		r.buf = make([]byte, len(p))
+	}
+	m, err := r.r.Read(r.buf[:len(p)])
+	n := 0
+	for _, b := range r.buf[:m] {
+		switch r.state {
+		case stData:
+			if b == cmdIAC {
+				r.state = stIAC
+				continue
+			}
+			p[n] = b
+			n++
+		case stIAC:
+			switch b {
+			case cmdIAC: // an escaped literal 255
+				p[n] = b
+				n++
+				r.state = stData
+			case cmdWILL, cmdWONT, cmdDO, cmdDONT:
+				r.verb, r.state = b, stVerb
+			case cmdSB:
+				r.state = stSB
+			default: // NOP, GA, and friends carry no data; nothing to do.
+				r.state = stData
+			}
+		case stVerb:
+			r.n.handle(r.verb, b)
+			r.state = stData
+		case stSB:
+			if b == cmdIAC {
+				r.state = stSBIAC
+			}
+		case stSBIAC:
+			if b == cmdSE {
+				r.state = stData
+			} else {
+				r.state = stSB
+			}
+		}
+	}
+	return n, err
+}
+
+// escapeIAC doubles every IAC byte in user data so the server doesn't mistake it for a command.
+func escapeIAC(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+	for _, b := range p {
+		if b == cmdIAC {
+			out = append(out, cmdIAC)
+		}
+		out = append(out, b)
+	}
+	return out
+}
@@ -0,0 +1,72 @@
+package telnet
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestReader(t *testing.T) {
+	var sent bytes.Buffer
+	neg := newNegotiator(&sent, []byte{optEcho}, nil)
+
+	// data with an escaped 255, a NOP, a subnegotiation, and three negotiations mixed in.
+	in := []byte{'h', 'i', cmdIAC, cmdIAC, cmdIAC, cmdNOP,
+		cmdIAC, cmdWILL, optEcho, // accepted
+		cmdIAC, cmdDO, optTType, // refused: we don't offer it
+		cmdIAC, cmdSB, optTType, 1, cmdIAC, cmdSE,
+		cmdIAC, cmdWILL, optEcho, // already on: must not be acknowledged again
+		'!'}
+	got, err := io.ReadAll(&reader{r: bytes.NewReader(in), n: neg})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{'h', 'i', 255, '!'}; !bytes.Equal(got, want) {
+		t.Errorf("data = %v, want %v", got, want)
+	}
+	if want := []byte{cmdIAC, cmdDO, optEcho, cmdIAC, cmdWONT, optTType}; !bytes.Equal(sent.Bytes(), want) {
+		t.Errorf("replies = %v, want %v", sent.Bytes(), want)
+	}
+	if !neg.remoteEnabled(optEcho) {
+		t.Errorf("ECHO not enabled after WILL ECHO was accepted")
+	}
+}
+
+func TestNegotiateRefuseByDefault(t *testing.T) {
+	var sent bytes.Buffer
+	neg := newNegotiator(&sent, nil, nil)
+	neg.handle(cmdWILL, optEcho)
+	neg.handle(cmdDO, optSGA)
+	neg.handle(cmdWONT, optEcho) // already off: no reply
+	neg.handle(cmdDONT, optSGA)  // already off: no reply
+	if want := []byte{cmdIAC, cmdDONT, optEcho, cmdIAC, cmdWONT, optSGA}; !bytes.Equal(sent.Bytes(), want) {
+		t.Errorf("replies = %v, want %v", sent.Bytes(), want)
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	c := &client{}
+	for in, want := range map[string]string{
+		"ls\n":     "ls\r\n",
+		"ls\r":     "ls\r\n",
+		"ls\r\n":   "ls\r\n",
+		"a\xffb\n": "a\xff\xffb\r\n",
+		"no eol":   "no eol",
+	} {
+		c.lastCR = false
+		if got := string(c.translate([]byte(in))); got != want {
+			t.Errorf("translate(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestParseEscape(t *testing.T) {
+	for in, want := range map[string]int{"^]": 0x1d, "^C": 3, "~": '~', "none": -1} {
+		if got, err := parseEscape(in); err != nil || got != want {
+			t.Errorf("parseEscape(%q) = %d, %v; want %d", in, got, err, want)
+		}
+	}
+	if _, err := parseEscape("abc"); err == nil {
+		t.Errorf("parseEscape(%q) succeeded; want error", "abc")
+	}
+}
@@ -0,0 +1,289 @@
+// Package telnet is the telnet tool: a client that negotiates telnet options (RFC 854) instead of ignoring them.
+package telnet
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ekediala/cli"
+)
+
+// Command is the telnet tool.
+var Command = &cli.Command{
+	Name:    "telnet",
+	Summary: "telnet client with option negotiation and an escape prompt",
+	Args:    "<HOST> [<PORT>]",
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		var o options
+		fs.BoolVar(&o.acceptEcho, "echo", false, "let the server do the echoing (accept WILL ECHO), switching to character-at-a-time mode")
+		fs.BoolVar(&o.sga, "sga", false, "accept and offer SUPPRESS-GO-AHEAD")
+		fs.StringVar(&o.escape, "e", "^]", `escape character that opens the telnet> prompt; "^x" for control characters, "none" to disable`)
+		fs.BoolVar(&o.verbose, "v", false, "log option negotiation")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			return run(ctx, env, o, args)
+		}
+	},
+}
+
+type options struct {
+	acceptEcho, sga, verbose bool
+	escape                   string
+}
+
+func run(ctx context.Context, env *cli.Env, o options, args []string) error {
+	if o.verbose {
+		env.Level.Set(slog.LevelDebug)
+	}
+
+	if len(args) < 1 || len(args) > 2 {
+		return cli.Usagef("expected a host and an optional port")
+	}
+	port := "23"
+	if len(args) == 2 {
+		port = args[1]
+	}
+	escapeChar, err := parseEscape(o.escape)
+	if err != nil {
+		return cli.Usagef("%v", err)
+	}
+
+	addr := net.JoinHostPort(args[0], port)
+	d := net.Dialer{Timeout: 10 * time.Second}
+	conn, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+	defer conn.Close()
+	fmt.Fprintf(os.Stderr, "Connected to %s.\r\n", conn.RemoteAddr())
+	if escapeChar >= 0 {
+		fmt.Fprintf(os.Stderr, "Escape character is '%s'.\r\n", o.escape)
+	}
+
+	// everything is refused unless the user opted in.
+	var accept, offer []byte
+	if o.acceptEcho {
+		accept = append(accept, optEcho)
+	}
+	if o.sga {
+		accept, offer = append(accept, optSGA), append(offer, optSGA)
+	}
+	neg := newNegotiator(conn, accept, offer)
+
+	term := &terminal{fd: int(os.Stdin.Fd())}
+	// when the server echoes for us, there's no point buffering lines locally: switch to character mode.
+	neg.onChange = func() { term.setCharMode(neg.remoteEnabled(optEcho)) }
+
+	exit := func(code int) {
+		term.setCharMode(false)
+		conn.Close()
+		os.Exit(code)
+	}
+
+	// the main loop is stuck reading stdin, which nothing can interrupt; the way out is to exit from here.
+	go func() {
+		<-ctx.Done()
+		term.setCharMode(false)
+		conn.Close()
+		cli.Stop(ctx)
+	}()
+
+	go func() {
+		_, err := io.Copy(os.Stdout, &reader{r: conn, n: neg})
+		term.setCharMode(false)
+		if err != nil && !isClosed(err) {
+			slog.ErrorContext(ctx, "main", "error", err.Error())
+		}
+		fmt.Fprintf(os.Stderr, "\r\nConnection closed by foreign host.\r\n")
+		exit(cli.ExitOK)
+	}()
+
+	c := &client{neg: neg, term: term, escape: escapeChar, exit: exit}
+	buf := make([]byte, 1024)
+	for {
+		n, err := os.Stdin.Read(buf)
+		if n > 0 {
+			if err := c.input(buf[:n]); err != nil {
+				slog.ErrorContext(ctx, "main", "error", fmt.Sprintf("error writing to %s: %v", conn.RemoteAddr(), err))
+				exit(cli.ExitError)
+			}
+		}
+		if err != nil {
+			// stdin is done (e.g. a pipe ran dry); half-close so the server sees EOF but can still finish replying.
+			if tcp, ok := conn.(*net.TCPConn); ok {
+				tcp.CloseWrite()
+			}
+			select {} // the reader goroutine exits once the server closes its side.
+		}
+	}
+}
+
+// client turns keyboard input into what goes over the wire.
+type client struct {
+	neg    *negotiator
+	term   *terminal
+	escape int // -1 when disabled
+	exit   func(code int)
+
+	lastCR bool
+}
+
+// input sends p to the server, translating line endings, escaping IAC bytes, and diverting to the prompt on the escape character.
+func (c *client) input(p []byte) error {
+	for len(p) > 0 {
+		i := -1
+		if c.escape >= 0 {
+			i = bytes.IndexByte(p, byte(c.escape))
+		}
+		if i < 0 {
+			return c.neg.send(c.translate(p)...)
+		}
+		if err := c.neg.send(c.translate(p[:i])...); err != nil {
+			return err
+		}
+		c.prompt()
+		p = p[i+1:]
+		// in line mode the rest of the line (if any) was typed alongside the escape character; drop it.
+		if !c.term.charMode() {
+			return nil
+		}
+	}
+	return nil
+}
+
+// translate converts end-of-line to the network's CR LF (Enter is CR in raw mode and LF in cooked mode; a CR LF pair
+// from a pipe stays one line ending) and doubles IAC bytes.
+func (c *client) translate(p []byte) []byte {
+	out := make([]byte, 0, len(p)+2)
+	for _, b := range p {
+		switch {
+		case b == '\n' && c.lastCR:
+		case b == '\r' || b == '\n':
+			out = append(out, '\r', '\n')
+		default:
+			out = append(out, escapeIAC([]byte{b})...)
+		}
+		c.lastCR = b == '\r'
+	}
+	return out
+}
+
+// prompt runs the interactive escape prompt, like the classic telnet> one.
+func (c *client) prompt() {
+	wasChar := c.term.charMode()
+	c.term.setCharMode(false)
+	defer c.term.setCharMode(wasChar)
+
+	for {
+		fmt.Fprint(os.Stderr, "\r\ntelnet> ")
+		line, err := readLine(os.Stdin)
+		if err != nil {
+			c.exit(cli.ExitOK)
+		}
+		switch fields := strings.Fields(line); {
+		case len(fields) == 0 || fields[0] == "c" || fields[0] == "continue":
+			return
+		case fields[0] == "q" || fields[0] == "quit" || fields[0] == "close":
+			fmt.Fprintf(os.Stderr, "Connection closed.\r\n")
+			c.exit(cli.ExitOK)
+		case fields[0] == "status":
+			mode := "line"
+			if wasChar {
+				mode = "character"
+			}
+			fmt.Fprintf(os.Stderr, "%s mode; %s\r\n", mode, c.neg.status())
+		case fields[0] == "send" && len(fields) == 2:
+			cmd, ok := map[string]byte{"ayt": cmdAYT, "brk": cmdBRK, "ip": cmdIP, "nop": cmdNOP}[fields[1]]
+			if !ok {
+				fmt.Fprintf(os.Stderr, "unknown: send %s (try ayt, brk, ip, nop)\r\n", fields[1])
+				continue
+			}
+			c.neg.send(cmdIAC, cmd)
+			return
+		default:
+			fmt.Fprintf(os.Stderr, "commands: continue (or empty line), quit, status, send ayt|brk|ip|nop\r\n")
+		}
+	}
+}
+
+// readLine reads one line from r byte by byte, so nothing past the newline is consumed.
+func readLine(r io.Reader) (string, error) {
+	var line []byte
+	var b [1]byte
+	for {
+		if _, err := r.Read(b[:]); err != nil {
+			return string(line), err
+		}
+		if b[0] == '\n' {
+			return strings.TrimSuffix(string(line), "\r"), nil
+		}
+		line = append(line, b[0])
+	}
+}
+
+// parseEscape turns "^]" style notation (or a single literal character, or "none") into a byte, or -1 for none.
+func parseEscape(s string) (int, error) {
+	switch {
+	case s == "none":
+		return -1, nil
+	case len(s) == 2 && s[0] == '^':
+		if s[1] == '?' {
+			return 0x7f, nil
+		}
+		return int(s[1]) & 0x1f, nil
+	case len(s) == 1:
+		return int(s[0]), nil
+	default:
+		return 0, fmt.Errorf("invalid escape character %q: want a single character, ^x, or none", s)
+	}
+}
+
+func isClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed)
+}
+
+// terminal switches stdin between line mode (the kernel buffers and echoes a line at a time) and
+// character mode (raw: every key goes straight to the server, which does the echoing).
+type terminal struct {
+	fd int
+
+	mu      sync.Mutex
+	restore func() error // non-nil while in character mode
+}
+
+func (t *terminal) charMode() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.restore != nil
+}
+
+func (t *terminal) setCharMode(on bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch {
+	case on && t.restore == nil:
+		if !isTerminal(t.fd) {
+			return // nothing to switch when input is piped
+		}
+		restore, err := makeRaw(t.fd)
+		if err != nil {
+			slog.Warn("terminal", "error", fmt.Sprintf("staying in line mode: %v", err))
+			return
+		}
+		t.restore = restore
+		slog.Debug("terminal", "mode", "character")
+	case !on && t.restore != nil:
+		t.restore()
+		t.restore = nil
+		slog.Debug("terminal", "mode", "line")
+	}
+}
@@ -0,0 +1,42 @@
+//go:build linux
+
+package telnet
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts the terminal on fd into raw mode, so every keystroke is delivered immediately and isn't echoed
+// locally, and returns a function that restores the previous settings. this is what cfmakeraw(3) does.
+func makeRaw(fd int) (restore func() error, err error) {
+	var old syscall.Termios
+	if err := ioctl(fd, syscall.TCGETS, &old); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Oflag &^= syscall.OPOST
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cflag &^= syscall.CSIZE | syscall.PARENB
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN], raw.Cc[syscall.VTIME] = 1, 0
+	if err := ioctl(fd, syscall.TCSETS, &raw); err != nil {
+		return nil, err
+	}
+	return func() error { return ioctl(fd, syscall.TCSETS, &old) }, nil
+}
+
+// isTerminal reports whether fd is a terminal (as opposed to a pipe or file).
+func isTerminal(fd int) bool {
+	var t syscall.Termios
+	return ioctl(fd, syscall.TCGETS, &t) == nil
+}
+
+func ioctl(fd int, req uint, t *syscall.Termios) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), uintptr(req), uintptr(unsafe.Pointer(t))); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package telnet
+
+import "errors"
+
+// makeRaw is only implemented for Linux; elsewhere the client stays in line mode.
+func makeRaw(int) (func() error, error) {
+	return nil, errors.New("raw terminal mode is not supported on this platform")
+}
+
+func isTerminal(int) bool { return false }
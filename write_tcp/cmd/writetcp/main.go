@@ -0,0 +1,9 @@
+// Command writetcp forwards stdin to a TCP server; see package writetcp.
+package main
+
+import (
+	"github.com/ekediala/cli"
+	"github.com/ekediala/writetcp"
+)
+
+func main() { cli.Main(writetcp.Command) }
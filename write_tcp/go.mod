@@ -1,3 +1,13 @@
-module github.com/ekediala/dns
+module github.com/ekediala/writetcp
 
 go 1.23.1
+
+require (
+	github.com/ekediala/cli v0.0.0
+	github.com/ekediala/retry v0.0.0
+)
+
+replace (
+	github.com/ekediala/cli => ../cli
+	github.com/ekediala/retry => ../retry
+)
@@ -0,0 +1,101 @@
+// Package writetcp is the writetcp tool: it forwards stdin line by line to a TCP server and logs what comes back.
+package writetcp
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"time"
+
+	"github.com/ekediala/cli"
+	"github.com/ekediala/retry"
+)
+
+// Command is the writetcp tool.
+var Command = &cli.Command{
+	Name:    "writetcp",
+	Summary: "send stdin line by line to a TCP server on localhost",
+	Setup: func(fs *flag.FlagSet) cli.Runner {
+		port := fs.Int("p", 8080, "port to connect to")
+		retries := fs.Int("retries", retry.DefaultMaxAttempts, "connection attempts before giving up; negative means retry forever")
+		return func(ctx context.Context, env *cli.Env, args []string) error {
+			return run(ctx, *port, *retries)
+		}
+	},
+}
+
+func run(ctx context.Context, port, retries int) error {
+	policy := retry.Policy{
+		MaxAttempts: retries,
+		Backoff:     retry.Exponential{Base: 250 * time.Millisecond, Max: 10 * time.Second, Jitter: 0.5},
+		Retryable:   retry.IsNetRetryable,
+		OnRetry: func(attempt int, err error, delay time.Duration) {
+			slog.WarnContext(ctx, "connect", "attempt", attempt, "error", err.Error(), "retrying in", delay.String())
+		},
+	}
+
+	// connect dials the server (retrying transient failures) and spawns a goroutine to read incoming lines from it and print them.
+	// TCP is full-duplex, so we can read and write at the same time; we just need to spawn a goroutine to do the reading.
+	connect := func() (*net.TCPConn, error) {
+		var conn *net.TCPConn
+		err := policy.Do(ctx, func(ctx context.Context) (err error) {
+			conn, err = net.DialTCP("tcp", nil, &net.TCPAddr{Port: port})
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error connecting to localhost:%d: %w", port, err)
+		}
+		slog.InfoContext(ctx, "main", "info", fmt.Sprintf("connected to %s: will forward stdin", conn.RemoteAddr()))
+
+		go func() {
+			connScanner := bufio.NewScanner(conn)
+			for connScanner.Scan() {
+				slog.InfoContext(ctx, "connScanner", "server message", connScanner.Text())
+			}
+			if err := connScanner.Err(); err != nil {
+				slog.ErrorContext(ctx, "connScanner", "error", fmt.Sprintf("error reading from %s: %v", conn.RemoteAddr(), err))
+				return
+			}
+			slog.InfoContext(ctx, "connScanner", "info", fmt.Sprintf("%s closed the connection", conn.RemoteAddr()))
+		}()
+		return conn, nil
+	}
+
+	conn, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() { conn.Close() }()
+
+	go func() {
+		<-ctx.Done()
+		slog.InfoContext(ctx, "main", "info", "shutdown signal received.")
+		cli.Stop(ctx) // a read from stdin can't be interrupted; exiting closes whichever connection is current.
+	}()
+
+	for stdInScanner := bufio.NewScanner(os.Stdin); stdInScanner.Scan(); {
+		line := fmt.Appendf(stdInScanner.Bytes(), "\n")
+		if _, err := conn.Write(line); err != nil {
+			// the server probably went away: reconnect and try this line once more before moving on.
+			slog.WarnContext(ctx, "stdInScanner", "error", fmt.Sprintf("error writing to %s: %v; reconnecting", conn.RemoteAddr(), err))
+			conn.Close()
+			if conn, err = connect(); err != nil {
+				return err
+			}
+			if _, err := conn.Write(line); err != nil {
+				slog.ErrorContext(ctx, "stdInScanner", "error", fmt.Sprintf("error writing to %s: %v", conn.RemoteAddr(), err))
+				continue
+			}
+		}
+		slog.InfoContext(ctx, "stdInScanner", "info", fmt.Sprintf("sent: %s", stdInScanner.Text()))
+
+		if err := stdInScanner.Err(); err != nil {
+			return fmt.Errorf("error reading from stdin: %w", err)
+		}
+	}
+	return nil
+}